@@ -0,0 +1,355 @@
+package main
+
+import (
+	"acid/db"
+	"acid/internal/backfill"
+	"acid/internal/cache"
+	"acid/internal/export"
+	loggerUtils "acid/internal/logger"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"acid/internal/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// command is an acidctl subcommand; run receives the arguments after the subcommand name
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, logger *zap.Logger) error
+}
+
+var commands = []command{
+	{name: "backfill", summary: "Rebuild derived stores (cache, email keys) from the users table", run: runBackfill},
+	{name: "export", summary: "Export the users table to S3 or a directory as a logical backup", run: runExport},
+	{name: "restore", summary: "Import a logical backup written by export", run: runRestore},
+	{name: "indexes", summary: "Print or write the migration for declared indexes and views the cluster lacks", run: runIndexes},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+
+	logger, err := loggerUtils.InitLogger()
+	if err != nil {
+		panic("Failed to initialize logger: " + err.Error())
+	}
+	defer logger.Sync()
+	safego.Configure(nil, logger)
+
+	// Cancel on SIGINT/SIGTERM so long-running commands checkpoint and exit cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	safego.Go("acidctl.signals", func() {
+		<-utils.GracefulShutdown()
+		logger.Info("Interrupted, stopping...")
+		cancel()
+	})
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(ctx, os.Args[2:], logger); err != nil {
+				logger.Error("Command failed", zap.String("command", cmd.name), zap.Error(err))
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: acidctl <command> [flags]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'acidctl <command> -h' for command flags.")
+}
+
+func runBackfill(ctx context.Context, args []string, logger *zap.Logger) error {
+	defaults := backfill.DefaultConfig()
+
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	targetNames := fs.String("targets", "cache,email", "comma-separated derived stores to rebuild: cache, email, lookups")
+	checkpointPath := fs.String("checkpoint", "backfill.checkpoint.json", "progress file for resume (empty disables)")
+	ranges := fs.Int("ranges", defaults.Ranges, "token ranges to split the ring into (must match an existing checkpoint)")
+	concurrency := fs.Int("concurrency", defaults.Concurrency, "token ranges scanned in parallel")
+	ratePerSecond := fs.Float64("rate", defaults.RatePerSecond, "max rows per second across all workers (0 = unlimited)")
+	pageSize := fs.Int("page-size", defaults.PageSize, "driver page size for range scans")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	cacheManager, err := connectCache()
+	if err != nil {
+		return err
+	}
+	defer cacheManager.Close()
+
+	repo := userRepository(database)
+	var targets []backfill.Target
+	for _, name := range strings.Split(*targetNames, ",") {
+		switch strings.TrimSpace(name) {
+		case "cache":
+			targets = append(targets, backfill.NewCacheTarget(cacheManager))
+		case "email":
+			targets = append(targets, backfill.NewEmailTarget(cacheManager))
+		case "lookups":
+			targets = append(targets, backfill.NewLookupTarget(repo))
+		case "":
+		default:
+			return fmt.Errorf("unknown backfill target %q", name)
+		}
+	}
+	if len(targets) == 0 {
+		return errors.New("no backfill targets selected")
+	}
+
+	checkpoint, err := backfill.LoadCheckpoint(*checkpointPath, *ranges)
+	if err != nil {
+		return err
+	}
+
+	config := &backfill.Config{
+		Ranges:             *ranges,
+		Concurrency:        *concurrency,
+		RatePerSecond:      *ratePerSecond,
+		PageSize:           *pageSize,
+		CheckpointInterval: defaults.CheckpointInterval,
+	}
+	runner := backfill.NewRunner(repo, targets, checkpoint, config, logger)
+
+	_, err = runner.Run(ctx)
+	return err
+}
+
+func runExport(ctx context.Context, args []string, logger *zap.Logger) error {
+	defaults := export.DefaultExportConfig()
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dest := fs.String("dest", "", "backup location: s3://bucket/prefix or a directory (required)")
+	formatName := fs.String("format", defaults.Format.Name(), "part file format: "+strings.Join(export.FormatNames(), ", "))
+	parts := fs.Int("parts", defaults.Parts, "token ranges, one part file each")
+	concurrency := fs.Int("concurrency", defaults.Concurrency, "parts exported in parallel")
+	pageSize := fs.Int("page-size", defaults.PageSize, "driver page size for range scans")
+	overwrite := fs.Bool("overwrite", false, "replace an existing backup at -dest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dest == "" {
+		return errors.New("-dest is required")
+	}
+
+	format, err := export.FormatByName(*formatName)
+	if err != nil {
+		return err
+	}
+
+	store, err := export.OpenStore(ctx, *dest, s3Options())
+	if err != nil {
+		return err
+	}
+
+	database, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	exporter := export.NewExporter(userRepository(database), store, &export.ExportConfig{
+		Keyspace:    database.GetConfig().Keyspace,
+		Format:      format,
+		Parts:       *parts,
+		Concurrency: *concurrency,
+		PageSize:    *pageSize,
+		Overwrite:   *overwrite,
+	}, logger)
+
+	_, err = exporter.Export(ctx)
+	return err
+}
+
+func runRestore(ctx context.Context, args []string, logger *zap.Logger) error {
+	defaults := export.DefaultRestoreConfig()
+
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	src := fs.String("src", "", "backup location: s3://bucket/prefix or a directory (required)")
+	concurrency := fs.Int("concurrency", defaults.Concurrency, "parts restored in parallel")
+	batchSize := fs.Int("batch-size", defaults.BatchSize, "users written per CreateUsersBatch call")
+	verifyOnly := fs.Bool("verify-only", false, "check sizes, checksums and row counts without writing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *src == "" {
+		return errors.New("-src is required")
+	}
+
+	store, err := export.OpenStore(ctx, *src, s3Options())
+	if err != nil {
+		return err
+	}
+
+	database, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	restorer := export.NewRestorer(userRepository(database), store, &export.RestoreConfig{
+		Concurrency: *concurrency,
+		BatchSize:   *batchSize,
+		VerifyOnly:  *verifyOnly,
+	}, logger)
+
+	_, err = restorer.Restore(ctx)
+	return err
+}
+
+func runIndexes(ctx context.Context, args []string, logger *zap.Logger) error {
+	fs := flag.NewFlagSet("indexes", flag.ContinueOnError)
+	write := fs.String("write", "", "migration directory to add the next numbered up/down pair to (default: print the CQL)")
+	name := fs.String("name", "indexes", "migration name, after the version number")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connectDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	diff, err := database.CheckIndexes(repository.Indexes, repository.Views)
+	if err != nil {
+		return err
+	}
+	if diff.Empty() {
+		logger.Info("Every declared index and view exists",
+			zap.Int("indexes", len(repository.Indexes)), zap.Int("views", len(repository.Views)))
+		return nil
+	}
+	if *write == "" {
+		fmt.Print(diff.UpCQL())
+		return nil
+	}
+
+	up, err := writeMigration(*write, *name, diff.UpCQL(), diff.DownCQL())
+	if err != nil {
+		return err
+	}
+	logger.Info("Migration written, apply it with make migrateup", zap.String("file", up),
+		zap.Int("indexes", len(diff.Indexes)), zap.Int("views", len(diff.Views)))
+	return nil
+}
+
+// writeMigration adds a golang-migrate up/down pair numbered after the last migration in dir and
+// returns the path of the up file
+func writeMigration(dir, name, up, down string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations: %w", err)
+	}
+	version := 0
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if n, err := strconv.Atoi(prefix); ok && err == nil && n > version {
+			version = n
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", version+1, name))
+	if err := os.WriteFile(base+".up.sql", []byte(up), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".down.sql", []byte(down), 0o644); err != nil {
+		return "", err
+	}
+	return base + ".up.sql", nil
+}
+
+// s3Options reads S3_ENDPOINT / S3_PATH_STYLE for S3-compatible stores such as MinIO
+func s3Options() export.S3Options {
+	return export.S3Options{
+		Endpoint:  utils.GetEnv("S3_ENDPOINT", ""),
+		PathStyle: utils.GetEnvBool("S3_PATH_STYLE", false),
+	}
+}
+
+// connectDatabase connects with the same HOSTS/KEYSPACE/DB_CONSISTENCY/DB_*_TIMEOUT environment as the API;
+// replication expectations are left to the API
+func connectDatabase() (*db.ScyllaDB, error) {
+	config := db.DefaultConfig()
+	config.Hosts = strings.Split(utils.GetEnv("HOSTS", "localhost"), ",")
+	config.Keyspace = utils.GetEnv("KEYSPACE", "acid_data")
+	config.KeyspaceCheck = utils.GetEnv("DB_KEYSPACE_CHECK", config.KeyspaceCheck)
+	config.ReadTimeout = utils.GetEnvDuration("DB_READ_TIMEOUT", 0)
+	config.WriteTimeout = utils.GetEnvDuration("DB_WRITE_TIMEOUT", 0)
+	config.ScanTimeout = utils.GetEnvDuration("DB_SCAN_TIMEOUT", 0)
+	config.PoolCheckInterval = 0   // Commands are too short-lived for pool saturation warnings
+	config.HostRefreshInterval = 0 // or for contact points to move
+	if raw := utils.GetEnv("DB_CONSISTENCY", ""); raw != "" {
+		consistency, err := gocql.ParseConsistencyWrapper(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_CONSISTENCY: %w", err)
+		}
+		config.Consistency = consistency
+	}
+	return db.ConnectWithConfig(config)
+}
+
+// userRepository applies DB_READ_TIMEOUT / DB_WRITE_TIMEOUT / DB_SCAN_TIMEOUT and DB_MAX_PAGE_SIZE
+// like the API does
+func userRepository(database *db.ScyllaDB) *repository.UserRepository {
+	read, write, scan := database.GetConfig().StatementTimeouts()
+	userRepository := repository.NewUserRepository(database.Session)
+	userRepository.SetQueryTimeouts(&repository.QueryTimeouts{Read: read, Write: write, Scan: scan})
+	guardrails := repository.DefaultGuardrailConfig()
+	guardrails.MaxPageSize = utils.GetEnvInt("DB_MAX_PAGE_SIZE", guardrails.MaxPageSize)
+	userRepository.SetGuardrails(repository.NewGuardrails(guardrails))
+	return userRepository
+}
+
+// connectCache builds a Redis-only cache manager: a process-local tier is useless to a CLI,
+// and errors are surfaced instead of degraded so failed writes are counted
+func connectCache() (*cache.CacheManager, error) {
+	redisConfig := cache.DefaultRedisConfig()
+	redisConfig.Host = utils.GetEnv("REDIS_HOST", redisConfig.Host)
+	redisConfig.Port = utils.GetEnv("REDIS_PORT", redisConfig.Port)
+	redisConfig.Password = utils.GetEnv("REDIS_PASSWORD", redisConfig.Password)
+
+	redisClient, err := cache.NewRedisClient(redisConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	config := cache.DefaultCacheManagerConfig()
+	config.EnableLocalCache = false
+	config.GracefulDegradation = false
+	config.Name = "acidctl"
+	// Must match the API's layout or primed keys are never read
+	config.Keys.Namespace = utils.GetEnv("CACHE_KEY_NAMESPACE", config.Keys.Namespace)
+	config.Keys.Version = utils.GetEnvInt("CACHE_KEY_VERSION", config.Keys.Version)
+	config.Keys.Tenant = utils.GetEnv("CACHE_KEY_TENANT", config.Keys.Tenant)
+	return cache.NewCacheManager(nil, redisClient, config), nil
+}
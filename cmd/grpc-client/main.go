@@ -1,18 +1,38 @@
 package main
 
 import (
+	"acid/internal/grpccompress"
+	"acid/internal/utils"
+	"acid/pkg/client"
 	pb "acid/proto/acid"
 	"context"
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 func main() {
+	// Requests are compressed with GRPC_CLIENT_COMPRESSION (gzip, deflate or identity); the server
+	// answers with the same compressor unless it is configured otherwise
+	compressor, err := grpccompress.Parse(utils.GetEnv("GRPC_CLIENT_COMPRESSION", grpccompress.Gzip))
+	if err != nil {
+		log.Fatalf("Invalid GRPC_CLIENT_COMPRESSION: %v", err)
+	}
+	// The SDK's service config: deadlines, and retries with backoff for fetchUser and userExists
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(client.DefaultServiceConfig),
+	}
+	if compressor != "" {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.UseCompressor(compressor)))
+	}
+
 	// Connect to gRPC server
-	conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial("localhost:50051", options...)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -22,11 +42,22 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Servers reject anonymous calls unless GRPC_AUTH_REQUIRED=false: authenticate with an access token
+	// (or ADMIN_TOKEN) in GRPC_CLIENT_TOKEN, or a registered API key in GRPC_CLIENT_API_KEY
+	if token := utils.GetEnv("GRPC_CLIENT_TOKEN", ""); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	if apiKey := utils.GetEnv("GRPC_CLIENT_API_KEY", ""); apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", apiKey)
+	}
+
 	// Test CreateUser
 	log.Println("📝 Testing CreateUser...")
+	// request_id makes a retry of this call return its first result instead of a conflict
 	createResp, err := client.CreateUser(ctx, &pb.RegisterUserRequest{
-		Name:  "John Doe",
-		Email: "john.doe@example.com",
+		Name:      "John Doe",
+		Email:     "john.doe@example.com",
+		RequestId: uuid.NewString(),
 	})
 	if err != nil {
 		log.Fatalf("CreateUser failed: %v", err)
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"acid/internal/safego"
 	"context"
 	"fmt"
 	"log"
@@ -11,12 +12,33 @@ import (
 )
 
 type ScyllaDB struct {
-	Session gocqlx.Session
-	config  *Config
+	Session  gocqlx.Session
+	config   *Config
+	topology *Topology
+	pool     *poolState
+	standby  *standbyState  // nil without StandbyHosts
+	reads    gocqlx.Session // Zero unless reads are split (Config.SplitsReads)
+	resolver *hostResolver
+	stop     chan struct{}
 }
 
 type Config struct {
-	Hosts              []string
+	Hosts []string
+
+	// DiscoveryURL is an HTTP endpoint listing contact points as a JSON array of hosts, e.g. a
+	// service discovery proxy; they are used alongside Hosts, which may then be empty
+	DiscoveryURL string
+
+	// HostRefreshInterval is how often host names in Hosts are resolved again and DiscoveryURL
+	// fetched, so the driver reconnects to where the cluster is now rather than to the addresses
+	// it started with; 0 disables
+	HostRefreshInterval time.Duration
+
+	// StandbyHosts are the contact points of a fallback cluster holding the same keyspace, e.g. in
+	// another region. A warm session to it is kept open, and reads fail over to it (FailOver)
+	// while the primary is down; writes stay on the primary
+	StandbyHosts []string
+
 	Keyspace           string
 	Consistency        gocql.Consistency
 	Timeout            time.Duration
@@ -28,26 +50,89 @@ type Config struct {
 	ReconnectInterval  time.Duration
 	IgnorePeerAddr     bool
 	DisableInitialHost bool
+
+	// The scylladb/gocql fork (see the replace in go.mod) opens one connection per shard on
+	// Scylla nodes and routes each statement to the shard owning its token; NumConnections
+	// only sizes the per-host pool for non-Scylla nodes.
+	// ShardAwarePort dials Scylla's shard-aware port (19042) so each connection lands on the
+	// intended shard at first try; turn it off only if that port is unreachable
+	ShardAwarePort bool
+
+	// MaxRequestsPerConn caps in-flight requests on each (per-shard) connection; 0 keeps the driver default
+	MaxRequestsPerConn int
+
+	// ReadConsistency and ReadNumConnections move reads (ReadSession) to a second session with
+	// their own connections, e.g. LOCAL_ONE reads on a larger pool and LOCAL_QUORUM writes. The
+	// split is made when either is set: Any (the zero value) keeps Consistency and 0 keeps
+	// NumConnections. On Scylla nodes every session opens one connection per shard, so the split
+	// doubles those connections and reads no longer queue behind writes for streams
+	ReadConsistency    gocql.Consistency
+	ReadNumConnections int
+
+	// Speculative execution for idempotent reads: after SpeculativeDelay without a response,
+	// send the query to another replica, up to SpeculativeAttempts extra times (0 disables)
+	SpeculativeAttempts int
+	SpeculativeDelay    time.Duration
+
+	// Per-statement timeouts applied by the repositories through the query context; 0 falls back to
+	// Timeout. ScanTimeout bounds each page of a token-range scan, so it can exceed Timeout
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	ScanTimeout  time.Duration
+
+	// KeyspaceCheck verifies the keyspace's replication and the consistency level after connecting:
+	// KeyspaceCheckFail, KeyspaceCheckWarn or KeyspaceCheckOff
+	KeyspaceCheck string
+
+	// Expected replication, checked when set: a strategy such as "NetworkTopologyStrategy", and a
+	// replication factor every datacenter (or the whole cluster, for SimpleStrategy) must have
+	ExpectedReplicationStrategy string
+	ExpectedReplicationFactor   int
+
+	// SchemaCheck decides what ValidateSchema does when live tables don't match the compiled ones,
+	// with the same modes as KeyspaceCheck
+	SchemaCheck string
+
+	// PoolCheckInterval is how often the pool monitor turns statement counts into gauges and
+	// checks for saturation; 0 disables it. gocql sizes its pools when the session is created,
+	// so the monitor can only recommend changes, not apply them
+	PoolCheckInterval time.Duration
+
+	// PoolInFlightThreshold is the mean number of statements in flight at which the pool counts
+	// as saturated even before any fails for lack of a stream; 0 only watches for those failures
+	PoolInFlightThreshold int
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Consistency:        gocql.Quorum,
-		Timeout:            10 * time.Second,
-		ConnectTimeout:     10 * time.Second,
-		MaxRetries:         3,
-		RetryDelay:         2 * time.Second,
-		NumConnections:     50,
-		MaxWaitTime:        30 * time.Second,
-		ReconnectInterval:  60 * time.Second,
-		IgnorePeerAddr:     true,
-		DisableInitialHost: true,
+		Consistency:         gocql.Quorum,
+		Timeout:             10 * time.Second,
+		ConnectTimeout:      10 * time.Second,
+		MaxRetries:          3,
+		RetryDelay:          2 * time.Second,
+		NumConnections:      50,
+		MaxWaitTime:         30 * time.Second,
+		ReconnectInterval:   60 * time.Second,
+		IgnorePeerAddr:      true,
+		DisableInitialHost:  true,
+		ShardAwarePort:      true,
+		SpeculativeDelay:    100 * time.Millisecond,
+		KeyspaceCheck:       KeyspaceCheckFail,
+		SchemaCheck:         KeyspaceCheckFail,
+		PoolCheckInterval:   30 * time.Second,
+		HostRefreshInterval: 30 * time.Second,
 	}
 }
 
 func (c *Config) Validate() error {
-	if len(c.Hosts) == 0 {
-		return fmt.Errorf("at least one host must be specified")
+	if len(c.Hosts) == 0 && c.DiscoveryURL == "" {
+		return fmt.Errorf("at least one host or a discovery URL must be specified")
+	}
+	if c.HostRefreshInterval < 0 {
+		return fmt.Errorf("host refresh interval must not be negative")
+	}
+	if err := c.validateDiscovery(); err != nil {
+		return err
 	}
 	if c.Keyspace == "" {
 		return fmt.Errorf("keyspace must be specified")
@@ -58,10 +143,58 @@ func (c *Config) Validate() error {
 	if c.ConnectTimeout <= 0 {
 		return fmt.Errorf("connect timeout must be positive")
 	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.ScanTimeout < 0 {
+		return fmt.Errorf("query timeouts must not be negative")
+	}
 	if c.NumConnections <= 0 {
 		return fmt.Errorf("number of connections must be positive")
 	}
-	return nil
+	if c.MaxRequestsPerConn < 0 {
+		return fmt.Errorf("max requests per connection must not be negative")
+	}
+	if c.ReadNumConnections < 0 {
+		return fmt.Errorf("number of read connections must not be negative")
+	}
+	if c.SpeculativeAttempts < 0 {
+		return fmt.Errorf("speculative attempts must not be negative")
+	}
+	if c.SpeculativeAttempts > 0 && c.SpeculativeDelay <= 0 {
+		return fmt.Errorf("speculative delay must be positive when speculative execution is enabled")
+	}
+	switch c.KeyspaceCheck {
+	case "", KeyspaceCheckFail, KeyspaceCheckWarn, KeyspaceCheckOff:
+	default:
+		return fmt.Errorf("keyspace check must be %q, %q or %q", KeyspaceCheckFail, KeyspaceCheckWarn, KeyspaceCheckOff)
+	}
+	switch c.SchemaCheck {
+	case "", KeyspaceCheckFail, KeyspaceCheckWarn, KeyspaceCheckOff:
+	default:
+		return fmt.Errorf("schema check must be %q, %q or %q", KeyspaceCheckFail, KeyspaceCheckWarn, KeyspaceCheckOff)
+	}
+	if c.PoolCheckInterval < 0 || c.PoolInFlightThreshold < 0 {
+		return fmt.Errorf("pool check interval and in-flight threshold must not be negative")
+	}
+	if c.ExpectedReplicationFactor < 0 {
+		return fmt.Errorf("expected replication factor must not be negative")
+	}
+	if c.Consistency.IsSerial() {
+		return fmt.Errorf("consistency %s is only valid as a serial consistency", c.Consistency)
+	}
+	if c.ReadConsistency.IsSerial() {
+		return fmt.Errorf("read consistency %s is only valid as a serial consistency", c.ReadConsistency)
+	}
+	return c.validateStandby()
+}
+
+// StatementTimeouts returns the read, write and scan timeouts with unset ones defaulted to Timeout
+func (c *Config) StatementTimeouts() (read, write, scan time.Duration) {
+	orDefault := func(timeout time.Duration) time.Duration {
+		if timeout > 0 {
+			return timeout
+		}
+		return c.Timeout
+	}
+	return orDefault(c.ReadTimeout), orDefault(c.WriteTimeout), orDefault(c.ScanTimeout)
 }
 
 func Connect(hosts []string, keyspace string) (*ScyllaDB, error) {
@@ -86,20 +219,98 @@ func ConnectWithConfig(config *Config) (*ScyllaDB, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	cluster := gocql.NewCluster(config.Hosts...)
+	// Token-aware load balancing with round-robin fallback, observed for host up/down events
+	topology := newTopology()
+	hostPolicy := &topologyPolicy{
+		HostSelectionPolicy: gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy()),
+		topology:            topology,
+	}
+
+	// Statement observers feed the pool monitor and the per-request debug trace
+	pool := &poolState{}
+
+	// Resolve the contact points once up front, so discovered hosts are known to the driver and
+	// later refreshes have addresses to compare with
+	resolver := newHostResolver(config)
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	resolver.refresh(ctx)
+	cancel()
+
+	cluster := newCluster(config, hostPolicy, pool, resolver)
+	session, err := createSession(cluster, config)
+	if err != nil {
+		return nil, err
+	}
+
+	gocqlxSession := gocqlx.NewSession(session)
+
+	db := &ScyllaDB{
+		Session:  gocqlxSession,
+		config:   config,
+		topology: topology,
+		pool:     pool,
+		resolver: resolver,
+		stop:     make(chan struct{}),
+	}
+
+	log.Printf("✅ ScyllaDB connection established to keyspace '%s'", config.Keyspace)
+
+	if config.ShardAwarePort {
+		aware, total := db.ShardAwareHosts()
+		log.Printf("🔀 Shard-aware port advertised by %d/%d hosts", aware, total)
+	}
+
+	// Perform initial health check
+	if err := db.Health(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initial health check failed: %w", err)
+	}
+
+	// Catch a keyspace that can't serve the consistency level now, rather than as timeouts later
+	if err := db.validateKeyspace(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if config.SplitsReads() {
+		if err := db.openReadSession(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if config.PoolCheckInterval > 0 {
+		safego.Go("scylla.pool_monitor", db.monitorPool, safego.Restart(db.stop))
+	}
+
+	if config.HostRefreshInterval > 0 {
+		safego.Go("scylla.host_refresh", db.refreshHosts, safego.Restart(db.stop))
+	}
+
+	if len(config.StandbyHosts) > 0 {
+		db.openStandby()
+	}
+
+	return db, nil
+}
+
+// newCluster is the driver configuration of a session on config's hosts
+func newCluster(config *Config, hostPolicy gocql.HostSelectionPolicy, pool *poolState, resolver *hostResolver) *gocql.ClusterConfig {
+	cluster := gocql.NewCluster(config.contactPoints()...)
+	cluster.DNSResolver = resolver
 	cluster.Keyspace = config.Keyspace
 	cluster.Consistency = config.Consistency
-	cluster.Timeout = config.Timeout
+	// The connection timeout caps every request, so raise it to the longest statement timeout and
+	// let the statement contexts do the bounding
+	cluster.Timeout = max(config.Timeout, config.ReadTimeout, config.WriteTimeout, config.ScanTimeout)
 	cluster.ConnectTimeout = config.ConnectTimeout
 	cluster.NumConns = config.NumConnections
 	cluster.ReconnectInterval = config.ReconnectInterval
 	cluster.IgnorePeerAddr = config.IgnorePeerAddr
 	cluster.DisableInitialHostLookup = config.DisableInitialHost
-
-	// Token-aware load balancing with round-robin fallback
-	cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(
-		gocql.RoundRobinHostPolicy(),
-	)
+	cluster.DisableShardAwarePort = !config.ShardAwarePort
+	cluster.MaxRequestsPerConn = config.MaxRequestsPerConn
+	cluster.PoolConfig.HostSelectionPolicy = hostPolicy
 
 	// Retry policy for transient failures
 	cluster.RetryPolicy = &gocql.ExponentialBackoffRetryPolicy{
@@ -111,14 +322,20 @@ func ConnectWithConfig(config *Config) (*ScyllaDB, error) {
 	// Connection observer for monitoring
 	cluster.ConnectObserver = &connectObserver{}
 
+	cluster.QueryObserver = poolObserver{pool: pool}
+	cluster.BatchObserver = poolObserver{pool: pool}
+	return cluster
+}
+
+// createSession connects to the cluster, retrying with a growing delay
+func createSession(cluster *gocql.ClusterConfig, config *Config) (*gocql.Session, error) {
 	var session *gocql.Session
 	var err error
 
-	// Retry connection with exponential backoff
 	for attempt := 1; attempt <= config.MaxRetries; attempt++ {
 		session, err = cluster.CreateSession()
 		if err == nil {
-			break
+			return session, nil
 		}
 
 		if attempt < config.MaxRetries {
@@ -129,30 +346,24 @@ func ConnectWithConfig(config *Config) (*ScyllaDB, error) {
 		}
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to ScyllaDB after %d attempts: %w",
-			config.MaxRetries, err)
-	}
-
-	gocqlxSession := gocqlx.NewSession(session)
-
-	db := &ScyllaDB{
-		Session: gocqlxSession,
-		config:  config,
-	}
-
-	log.Printf("✅ ScyllaDB connection established to keyspace '%s'", config.Keyspace)
-
-	// Perform initial health check
-	if err := db.Health(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("initial health check failed: %w", err)
-	}
-
-	return db, nil
+	return nil, fmt.Errorf("failed to connect to ScyllaDB after %d attempts: %w",
+		config.MaxRetries, err)
 }
 
 func (db *ScyllaDB) Close() {
+	if db.stop != nil {
+		select {
+		case <-db.stop:
+		default:
+			close(db.stop)
+		}
+	}
+	if db.standby != nil {
+		db.standby.close()
+	}
+	if db.reads.Session != nil {
+		db.reads.Close()
+	}
 	if db.Session.Session != nil {
 		db.Session.Close()
 		log.Println("✅ ScyllaDB session closed gracefully")
@@ -164,6 +375,21 @@ func (db *ScyllaDB) Health() error {
 }
 
 func (db *ScyllaDB) HealthWithContext(ctx context.Context) error {
+	t, err := db.probe(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ Database health check passed at %v", t)
+	return nil
+}
+
+// PingContext runs the health query without logging, for periodic monitors
+func (db *ScyllaDB) PingContext(ctx context.Context) error {
+	_, err := db.probe(ctx)
+	return err
+}
+
+func (db *ScyllaDB) probe(ctx context.Context) (time.Time, error) {
 	type result struct {
 		t   time.Time
 		err error
@@ -171,24 +397,25 @@ func (db *ScyllaDB) HealthWithContext(ctx context.Context) error {
 
 	resultCh := make(chan result, 1)
 
-	go func() {
+	safego.Go("scylla.probe", func() {
 		query := db.Session.Query("SELECT now() FROM system.local", nil)
 		defer query.Release()
 
 		var t time.Time
 		err := query.Get(&t)
 		resultCh <- result{t: t, err: err}
-	}()
+	}, safego.OnPanic(func(r any) {
+		resultCh <- result{err: fmt.Errorf("probe panicked: %v", r)}
+	}))
 
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("health check cancelled: %w", ctx.Err())
+		return time.Time{}, fmt.Errorf("health check cancelled: %w", ctx.Err())
 	case res := <-resultCh:
 		if res.err != nil {
-			return fmt.Errorf("health check failed: %w", res.err)
+			return time.Time{}, fmt.Errorf("health check failed: %w", res.err)
 		}
-		log.Printf("✅ Database health check passed at %v", res.t)
-		return nil
+		return res.t, nil
 	}
 }
 
@@ -196,6 +423,35 @@ func (db *ScyllaDB) Ping() error {
 	return db.Health()
 }
 
+// ReadSpeculativePolicy returns the speculative execution policy for idempotent reads
+// gocql only speculates on queries marked Idempotent(true)
+func (db *ScyllaDB) ReadSpeculativePolicy() gocql.SpeculativeExecutionPolicy {
+	if db.config.SpeculativeAttempts <= 0 {
+		return &gocql.NonSpeculativeExecution{}
+	}
+	return &gocql.SimpleSpeculativeExecution{
+		NumAttempts:  db.config.SpeculativeAttempts,
+		TimeoutDelay: db.config.SpeculativeDelay,
+	}
+}
+
+// ShardAwareHosts reports how many known hosts advertise Scylla's shard-aware port
+// Hosts without it (older Scylla, Cassandra) still work, just without per-shard dialing
+func (db *ScyllaDB) ShardAwareHosts() (aware, total int) {
+	for _, host := range db.Session.GetHosts() {
+		total++
+		if host.ScyllaShardAwarePort() != 0 {
+			aware++
+		}
+	}
+	return aware, total
+}
+
+// Topology returns host state per datacenter as reported by the driver
+func (db *ScyllaDB) Topology() *Topology {
+	return db.topology
+}
+
 func (db *ScyllaDB) GetConfig() *Config {
 	return db.config
 }
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// discoveryHost is the contact point standing for the hosts DiscoveryURL lists; only the
+// resolver knows its addresses
+const discoveryHost = "scylla-discovery.invalid"
+
+// hostResolver is the driver's DNS resolver. gocql has no way to add contact points to a live
+// session: it resolves them when the session is created and again whenever its control
+// connection has lost every node it knew, which is when stale addresses hurt. The resolver
+// answers those lookups with the addresses of the last refresh when DNS fails, and with the
+// hosts of DiscoveryURL for discoveryHost
+type hostResolver struct {
+	config *Config
+	dns    gocql.DNSResolver
+	client *http.Client
+
+	mu        sync.Mutex
+	addresses map[string][]net.IP // Last good resolution per host name
+	unknown   int                 // Resolved addresses the driver didn't know at the last refresh
+
+	refreshes atomic.Int64
+	changes   atomic.Int64
+	failures  atomic.Int64
+}
+
+func newHostResolver(config *Config) *hostResolver {
+	return &hostResolver{
+		config:    config,
+		dns:       gocql.NewSimpleDNSResolver(os.Getenv("GOCQL_HOST_LOOKUP_PREFER_V4") == "true"),
+		client:    &http.Client{Timeout: config.ConnectTimeout},
+		addresses: make(map[string][]net.IP),
+	}
+}
+
+// contactPoints are the hosts handed to the driver
+func (c *Config) contactPoints() []string {
+	if c.DiscoveryURL == "" {
+		return c.Hosts
+	}
+	return append(slices.Clone(c.Hosts), discoveryHost)
+}
+
+// LookupIP resolves host live, falling back to the addresses of the last refresh
+func (r *hostResolver) LookupIP(host string) ([]net.IP, error) {
+	if host != discoveryHost {
+		ips, err := r.dns.LookupIP(host)
+		if err == nil && len(ips) > 0 {
+			return ips, nil
+		}
+	}
+
+	r.mu.Lock()
+	ips := r.addresses[host]
+	r.mu.Unlock()
+	if len(ips) == 0 {
+		// A DNS error makes the driver move on to the other contact points
+		return nil, &net.DNSError{Err: "no known addresses", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// refresh resolves every host name in Hosts and fetches DiscoveryURL, logging contact points
+// whose addresses changed. A host that can't be resolved keeps its last addresses
+func (r *hostResolver) refresh(ctx context.Context) {
+	r.refreshes.Add(1)
+	for _, host := range r.config.Hosts {
+		name := host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			name = h
+		}
+		if net.ParseIP(name) != nil {
+			continue
+		}
+		ips, err := r.dns.LookupIP(name)
+		if err == nil && len(ips) == 0 {
+			err = fmt.Errorf("no addresses")
+		}
+		if err != nil {
+			r.failures.Add(1)
+			log.Printf("⚠️ Failed to resolve ScyllaDB contact point %s, keeping its last addresses: %v", name, err)
+			continue
+		}
+		r.update(name, ips)
+	}
+
+	if r.config.DiscoveryURL == "" {
+		return
+	}
+	ips, err := r.discover(ctx)
+	if err != nil {
+		r.failures.Add(1)
+		log.Printf("⚠️ ScyllaDB discovery failed, keeping the last contact points: %v", err)
+		return
+	}
+	r.update(discoveryHost, ips)
+}
+
+// discover fetches DiscoveryURL, a JSON array of hosts (addresses or names), and resolves them
+func (r *hostResolver) discover(ctx context.Context) ([]net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.config.DiscoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint returned %s", resp.Status)
+	}
+
+	var hosts []string
+	if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
+		return nil, fmt.Errorf("invalid discovery response: %w", err)
+	}
+	var ips []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+			continue
+		}
+		resolved, err := r.dns.LookupIP(host)
+		if err != nil {
+			log.Printf("⚠️ Failed to resolve discovered ScyllaDB host %s: %v", host, err)
+			continue
+		}
+		ips = append(ips, resolved...)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("discovery endpoint listed no reachable hosts")
+	}
+	return ips, nil
+}
+
+// update stores the addresses of name, logging a change from the previous refresh
+func (r *hostResolver) update(name string, ips []net.IP) {
+	sort.Slice(ips, func(i, j int) bool { return ips[i].String() < ips[j].String() })
+
+	r.mu.Lock()
+	previous, known := r.addresses[name]
+	r.addresses[name] = ips
+	r.mu.Unlock()
+
+	if known && !slices.EqualFunc(previous, ips, net.IP.Equal) {
+		r.changes.Add(1)
+		if name == discoveryHost {
+			name = "discovery"
+		}
+		log.Printf("🔄 ScyllaDB contact point %s moved: %v -> %v", name, previous, ips)
+	}
+}
+
+// checkDriver counts the resolved addresses the driver has no host for. They usually belong to
+// nodes that joined or moved while the driver missed the topology event, and are picked up
+// when its control connection next falls back to the contact points
+func (r *hostResolver) checkDriver(hosts []*gocql.HostInfo) {
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[host.ConnectAddress().String()] = true
+	}
+
+	r.mu.Lock()
+	var unknown []string
+	for _, ips := range r.addresses {
+		for _, ip := range ips {
+			if !known[ip.String()] {
+				unknown = append(unknown, ip.String())
+			}
+		}
+	}
+	changed := len(unknown) != r.unknown
+	r.unknown = len(unknown)
+	r.mu.Unlock()
+
+	if changed && len(unknown) > 0 {
+		sort.Strings(unknown)
+		log.Printf("⚠️ ScyllaDB contact points %v are unknown to the driver; it adds them from topology events or when it reconnects", unknown)
+	}
+}
+
+// refreshHosts re-resolves the contact points every HostRefreshInterval
+func (db *ScyllaDB) refreshHosts() {
+	ticker := time.NewTicker(db.config.HostRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), db.config.ConnectTimeout)
+			db.resolver.refresh(ctx)
+			cancel()
+			db.resolver.checkDriver(db.Session.GetHosts())
+		}
+	}
+}
+
+// HostMetrics returns how often the contact points were refreshed, changed or failed to
+// resolve, how many addresses they resolve to and how many of those the driver doesn't know
+func (db *ScyllaDB) HostMetrics() map[string]int64 {
+	r := db.resolver
+	r.mu.Lock()
+	var addresses int
+	for _, ips := range r.addresses {
+		addresses += len(ips)
+	}
+	unknown := r.unknown
+	r.mu.Unlock()
+
+	return map[string]int64{
+		"refreshes":         r.refreshes.Load(),
+		"changes":           r.changes.Load(),
+		"failures":          r.failures.Load(),
+		"addresses":         int64(addresses),
+		"unknown_to_driver": int64(unknown),
+	}
+}
+
+// validateDiscovery checks DiscoveryURL is an http(s) URL
+func (c *Config) validateDiscovery() error {
+	if c.DiscoveryURL == "" {
+		return nil
+	}
+	if !strings.HasPrefix(c.DiscoveryURL, "http://") && !strings.HasPrefix(c.DiscoveryURL, "https://") {
+		return fmt.Errorf("discovery URL must be an http(s) URL")
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// IndexSchema is a secondary index the code queries through. Declaring it next to the tables
+// lets the startup check warn when it's missing and lets acidctl write its migration
+type IndexSchema struct {
+	Name  string
+	Table string
+
+	// Target is what the index covers as CREATE INDEX spells it: a column, keys(col), values(col),
+	// entries(col), or ((pk), col) for a local index
+	Target string
+
+	// Class makes a custom index (CREATE CUSTOM INDEX ... USING), such as SASI on clusters that
+	// support it; empty for a global or local secondary index. Options are only used with a class
+	Class   string
+	Options map[string]string
+}
+
+// CreateCQL is the statement that creates the index
+func (i IndexSchema) CreateCQL() string {
+	if i.Class == "" {
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s);", i.Name, i.Table, i.Target)
+	}
+	cql := fmt.Sprintf("CREATE CUSTOM INDEX IF NOT EXISTS %s ON %s (%s) USING '%s'", i.Name, i.Table, i.Target, i.Class)
+	if len(i.Options) > 0 {
+		cql += " WITH OPTIONS = " + cqlMap(i.Options)
+	}
+	return cql + ";"
+}
+
+// DropCQL is the statement that drops the index
+func (i IndexSchema) DropCQL() string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", i.Name)
+}
+
+// ViewSchema is a materialized view the code reads. Every primary key column of the view must be
+// restricted to IS NOT NULL, which CreateCQL adds
+type ViewSchema struct {
+	Name      string
+	BaseTable string
+	Columns   []string
+	PartKey   []string
+	SortKey   []string
+
+	// Descending lists the clustering columns sorted newest first
+	Descending []string
+}
+
+// CreateCQL is the statement that creates the view
+func (v ViewSchema) CreateCQL() string {
+	var where []string
+	for _, column := range append(append([]string{}, v.PartKey...), v.SortKey...) {
+		where = append(where, column+" IS NOT NULL")
+	}
+	key := "(" + strings.Join(v.PartKey, ", ") + ")"
+	if len(v.SortKey) > 0 {
+		key += ", " + strings.Join(v.SortKey, ", ")
+	}
+	cql := fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS\n    SELECT %s FROM %s\n    WHERE %s\n    PRIMARY KEY (%s)",
+		v.Name, strings.Join(v.Columns, ", "), v.BaseTable, strings.Join(where, " AND "), key)
+	if len(v.Descending) > 0 {
+		var order []string
+		for _, column := range v.SortKey {
+			direction := "ASC"
+			for _, desc := range v.Descending {
+				if desc == column {
+					direction = "DESC"
+				}
+			}
+			order = append(order, column+" "+direction)
+		}
+		cql += "\n    WITH CLUSTERING ORDER BY (" + strings.Join(order, ", ") + ")"
+	}
+	return cql + ";"
+}
+
+// DropCQL is the statement that drops the view
+func (v ViewSchema) DropCQL() string {
+	return fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;", v.Name)
+}
+
+// IndexDiff lists the declared indexes and views the keyspace lacks
+type IndexDiff struct {
+	Indexes []IndexSchema
+	Views   []ViewSchema
+}
+
+// Empty reports whether everything declared exists
+func (d *IndexDiff) Empty() bool {
+	return len(d.Indexes) == 0 && len(d.Views) == 0
+}
+
+// UpCQL creates everything missing; views come last since they may read indexed tables
+func (d *IndexDiff) UpCQL() string {
+	var statements []string
+	for _, index := range d.Indexes {
+		statements = append(statements, index.CreateCQL())
+	}
+	for _, view := range d.Views {
+		statements = append(statements, view.CreateCQL())
+	}
+	return strings.Join(statements, "\n\n") + "\n"
+}
+
+// DownCQL drops what UpCQL creates, in reverse order
+func (d *IndexDiff) DownCQL() string {
+	var statements []string
+	for i := len(d.Views) - 1; i >= 0; i-- {
+		statements = append(statements, d.Views[i].DropCQL())
+	}
+	for i := len(d.Indexes) - 1; i >= 0; i-- {
+		statements = append(statements, d.Indexes[i].DropCQL())
+	}
+	return strings.Join(statements, "\n") + "\n"
+}
+
+// CheckIndexes compares the declared indexes and views with the keyspace. Only existence is
+// checked: an index or view with the expected name is assumed to match its declaration
+func (db *ScyllaDB) CheckIndexes(indexes []IndexSchema, views []ViewSchema) (*IndexDiff, error) {
+	metadata, err := db.Session.KeyspaceMetadata(db.config.Keyspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyspace '%s' metadata: %w", db.config.Keyspace, err)
+	}
+
+	diff := &IndexDiff{}
+	for _, index := range indexes {
+		if _, ok := metadata.Indexes[index.Name]; !ok {
+			diff.Indexes = append(diff.Indexes, index)
+		}
+	}
+	for _, view := range views {
+		if _, ok := metadata.Views[view.Name]; !ok {
+			diff.Views = append(diff.Views, view)
+		}
+	}
+	return diff, nil
+}
+
+// WarnMissingIndexes logs each declared index or view the keyspace lacks. Unlike ValidateSchema it
+// never fails startup: queries through a missing index fail on their own, while an index being
+// built or dropped for maintenance shouldn't take the service down. SchemaCheck off disables it
+func (db *ScyllaDB) WarnMissingIndexes(indexes []IndexSchema, views []ViewSchema) {
+	if db.config.SchemaCheck == KeyspaceCheckOff || len(indexes)+len(views) == 0 {
+		return
+	}
+
+	diff, err := db.CheckIndexes(indexes, views)
+	if err != nil {
+		log.Printf("⚠️ Index check failed: %v", err)
+		return
+	}
+	if diff.Empty() {
+		log.Printf("✅ All %d index(es) and %d view(s) the code uses exist in '%s'", len(indexes), len(views), db.config.Keyspace)
+		return
+	}
+	for _, index := range diff.Indexes {
+		log.Printf("⚠️ Index %s on %s is missing in '%s', queries using it will fail; run `acidctl indexes -write db/migration`",
+			index.Name, index.Table, db.config.Keyspace)
+	}
+	for _, view := range diff.Views {
+		log.Printf("⚠️ Materialized view %s of %s is missing in '%s', reads from it will fail; run `acidctl indexes -write db/migration`",
+			view.Name, view.BaseTable, db.config.Keyspace)
+	}
+}
+
+func cqlMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = fmt.Sprintf("'%s': '%s'", key, m[key])
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
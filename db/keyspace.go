@@ -0,0 +1,269 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// Check modes: what ConnectWithConfig does when the keyspace doesn't fit the configuration, and
+// what ValidateSchema does when the tables don't
+const (
+	KeyspaceCheckFail = "fail" // Refuse to start on mismatches; only warn about nodes that are down
+	KeyspaceCheckWarn = "warn" // Log everything, start anyway
+	KeyspaceCheckOff  = "off"
+)
+
+// simpleStrategyGroup is the replica group name used for SimpleStrategy, which ignores datacenters
+const simpleStrategyGroup = "cluster"
+
+// Replication is a keyspace's replication settings
+type Replication struct {
+	// Strategy is the short class name, e.g. "SimpleStrategy" or "NetworkTopologyStrategy"
+	Strategy string
+
+	// Factors holds the replication factor per datacenter, or under "cluster" for SimpleStrategy
+	Factors map[string]int
+}
+
+// KeyspaceReport is the outcome of CheckKeyspace
+type KeyspaceReport struct {
+	Replication Replication
+	Consistency gocql.Consistency
+
+	// Nodes and NodesUp are per datacenter, as seen by the cluster
+	Nodes   map[string]int
+	NodesUp map[string]int
+
+	// Problems are mismatches that waiting won't fix: wrong strategy or factor, or a consistency
+	// level that needs more replicas than exist
+	Problems []string
+
+	// Warnings mean the consistency level can't be met with the nodes up right now
+	Warnings []string
+}
+
+// CheckKeyspace compares the keyspace's replication with the configured expectations and checks
+// that the configured consistency levels, of writes and of reads, can be met by the cluster's nodes
+func (db *ScyllaDB) CheckKeyspace() (*KeyspaceReport, error) {
+	metadata, err := db.Session.KeyspaceMetadata(db.config.Keyspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyspace '%s' metadata: %w", db.config.Keyspace, err)
+	}
+
+	replication, err := parseReplication(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &KeyspaceReport{
+		Replication: replication,
+		Consistency: db.config.Consistency,
+	}
+	report.Nodes, report.NodesUp = db.clusterNodes()
+
+	if expected := db.config.ExpectedReplicationStrategy; expected != "" && !strings.EqualFold(expected, replication.Strategy) {
+		report.Problems = append(report.Problems, fmt.Sprintf("replication strategy is %s, expected %s",
+			replication.Strategy, expected))
+	}
+	for _, group := range sortedGroups(replication.Factors) {
+		factor := replication.Factors[group]
+		if expected := db.config.ExpectedReplicationFactor; expected > 0 && factor != expected {
+			report.Problems = append(report.Problems, fmt.Sprintf("replication factor in %s is %d, expected %d",
+				group, factor, expected))
+		}
+		if nodes := report.nodesIn(group, report.Nodes); factor > nodes {
+			report.Problems = append(report.Problems, fmt.Sprintf("replication factor in %s is %d but it has %d node(s)",
+				group, factor, nodes))
+		}
+	}
+
+	report.checkConsistency()
+	if read := db.config.readConsistency(); read != report.Consistency {
+		reads := KeyspaceReport{Replication: replication, Consistency: read, Nodes: report.Nodes, NodesUp: report.NodesUp}
+		reads.checkConsistency()
+		report.Problems = append(report.Problems, reads.Problems...)
+		report.Warnings = append(report.Warnings, reads.Warnings...)
+	}
+	return report, nil
+}
+
+// checkConsistency compares the replicas each consistency level needs with the replicas that
+// exist (problems) and the replicas that are up (warnings)
+func (r *KeyspaceReport) checkConsistency() {
+	switch r.Consistency {
+	case gocql.Any:
+		return // Satisfied by hints alone
+	case gocql.LocalQuorum, gocql.EachQuorum, gocql.LocalOne:
+		// No DC-aware policy is configured, so any datacenter holding replicas may be "local"
+		for _, group := range sortedGroups(r.Replication.Factors) {
+			factor := r.Replication.Factors[group]
+			if factor == 0 {
+				continue
+			}
+			required := factor/2 + 1
+			if r.Consistency == gocql.LocalOne {
+				required = 1
+			}
+			r.compare(group, required, r.replicasIn(group, r.Nodes), r.replicasIn(group, r.NodesUp))
+		}
+	default:
+		var total, existing, up int
+		for group, factor := range r.Replication.Factors {
+			total += factor
+			existing += r.replicasIn(group, r.Nodes)
+			up += r.replicasIn(group, r.NodesUp)
+		}
+
+		var required int
+		switch r.Consistency {
+		case gocql.One:
+			required = 1
+		case gocql.Two:
+			required = 2
+		case gocql.Three:
+			required = 3
+		case gocql.Quorum:
+			required = total/2 + 1
+		case gocql.All:
+			required = total
+		default:
+			return
+		}
+		r.compare("the cluster", required, existing, up)
+	}
+}
+
+func (r *KeyspaceReport) compare(scope string, required, existing, up int) {
+	switch {
+	case required > existing:
+		r.Problems = append(r.Problems, fmt.Sprintf("consistency %s needs %d replica(s) in %s but only %d exist",
+			r.Consistency, required, scope, existing))
+	case required > up:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("consistency %s needs %d replica(s) in %s but only %d are up",
+			r.Consistency, required, scope, up))
+	}
+}
+
+// replicasIn is how many of a group's replicas can live on the given nodes: one per node at most
+func (r *KeyspaceReport) replicasIn(group string, nodes map[string]int) int {
+	return min(r.Replication.Factors[group], r.nodesIn(group, nodes))
+}
+
+func (r *KeyspaceReport) nodesIn(group string, nodes map[string]int) int {
+	if r.Replication.Strategy != "SimpleStrategy" {
+		return nodes[group]
+	}
+	var total int
+	for _, count := range nodes {
+		total += count
+	}
+	return total
+}
+
+// clusterNodes counts nodes per datacenter from Scylla's system.cluster_status, which sees every
+// member even when the driver was only given one host (DisableInitialHost); without that table
+// (older Scylla, Cassandra) it falls back to the hosts the driver knows
+func (db *ScyllaDB) clusterNodes() (nodes, up map[string]int) {
+	nodes, up = make(map[string]int), make(map[string]int)
+
+	iter := db.Session.Query("SELECT dc, up FROM system.cluster_status", nil).Iter()
+	var dc string
+	var isUp bool
+	for iter.Scan(&dc, &isUp) {
+		nodes[dc]++
+		if isUp {
+			up[dc]++
+		}
+	}
+	if err := iter.Close(); err == nil && len(nodes) > 0 {
+		return nodes, up
+	}
+
+	clear(nodes)
+	clear(up)
+	for _, host := range db.Session.GetHosts() {
+		nodes[host.DataCenter()]++
+		if host.IsUp() {
+			up[host.DataCenter()]++
+		}
+	}
+	return nodes, up
+}
+
+func parseReplication(metadata *gocql.KeyspaceMetadata) (Replication, error) {
+	class := metadata.StrategyClass
+	replication := Replication{
+		Strategy: class[strings.LastIndex(class, ".")+1:],
+		Factors:  make(map[string]int),
+	}
+
+	for option, value := range metadata.StrategyOptions {
+		factor, err := strconv.Atoi(fmt.Sprint(value))
+		if err != nil {
+			continue
+		}
+		// NetworkTopologyStrategy's "replication_factor" is only a shorthand expanded per datacenter
+		switch {
+		case replication.Strategy == "SimpleStrategy" && option == "replication_factor":
+			replication.Factors[simpleStrategyGroup] = factor
+		case replication.Strategy != "SimpleStrategy" && option != "replication_factor":
+			replication.Factors[option] = factor
+		}
+	}
+
+	if len(replication.Factors) == 0 {
+		return replication, fmt.Errorf("keyspace '%s' uses %s with no replication factor",
+			metadata.Name, replication.Strategy)
+	}
+	return replication, nil
+}
+
+func sortedGroups(factors map[string]int) []string {
+	groups := make([]string, 0, len(factors))
+	for group := range factors {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// validateKeyspace runs CheckKeyspace according to the configured mode; in fail mode problems
+// abort the connection, while replicas that are merely down are only logged
+func (db *ScyllaDB) validateKeyspace() error {
+	mode := db.config.KeyspaceCheck
+	if mode == "" || mode == KeyspaceCheckOff {
+		return nil
+	}
+
+	report, err := db.CheckKeyspace()
+	if err != nil {
+		if mode == KeyspaceCheckFail {
+			return fmt.Errorf("keyspace check failed: %w", err)
+		}
+		log.Printf("⚠️ Keyspace check failed: %v", err)
+		return nil
+	}
+
+	for _, warning := range report.Warnings {
+		log.Printf("⚠️ Keyspace '%s': %s", db.config.Keyspace, warning)
+	}
+	if len(report.Problems) == 0 {
+		log.Printf("✅ Keyspace '%s' uses %s %v, consistency %s is satisfiable",
+			db.config.Keyspace, report.Replication.Strategy, report.Replication.Factors, report.Consistency)
+		return nil
+	}
+
+	if mode == KeyspaceCheckFail {
+		return fmt.Errorf("keyspace '%s' does not match the configuration: %s",
+			db.config.Keyspace, strings.Join(report.Problems, "; "))
+	}
+	for _, problem := range report.Problems {
+		log.Printf("❌ Keyspace '%s': %s", db.config.Keyspace, problem)
+	}
+	return nil
+}
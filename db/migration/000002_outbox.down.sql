@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox_dlq;
+DROP TABLE IF EXISTS outbox;
\ No newline at end of file
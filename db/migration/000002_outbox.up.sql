@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    shard INT,
+    id TIMEUUID,
+    event_type TEXT,
+    aggregate_id TEXT,
+    payload TEXT,
+    attempts INT,
+    last_error TEXT,
+    created_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    PRIMARY KEY (shard, id)
+) WITH CLUSTERING ORDER BY (id ASC);
+
+CREATE TABLE IF NOT EXISTS outbox_dlq (
+    shard INT,
+    id TIMEUUID,
+    event_type TEXT,
+    aggregate_id TEXT,
+    payload TEXT,
+    attempts INT,
+    last_error TEXT,
+    created_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    PRIMARY KEY (shard, id)
+) WITH CLUSTERING ORDER BY (id ASC);
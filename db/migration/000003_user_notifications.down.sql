@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_notifications;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_notifications (
+    user_id UUID,
+    id TIMEUUID,
+    kind TEXT,
+    channel TEXT,
+    status TEXT,
+    attempts INT,
+    last_error TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (user_id, id)
+) WITH CLUSTERING ORDER BY (id DESC);
@@ -0,0 +1 @@
+ALTER TABLE users DROP preferences;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD preferences MAP<TEXT, BOOLEAN>;
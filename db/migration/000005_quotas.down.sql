@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS quota_limits;
+DROP TABLE IF EXISTS quota_usage;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS quota_usage (
+    subject TEXT,
+    bucket TEXT,
+    used COUNTER,
+    PRIMARY KEY (subject, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS quota_limits (
+    subject TEXT,
+    period TEXT,
+    quota BIGINT,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (subject, period)
+);
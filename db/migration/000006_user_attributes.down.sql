@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS attribute_schemas;
+ALTER TABLE users DROP attributes;
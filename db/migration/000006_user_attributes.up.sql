@@ -0,0 +1,9 @@
+ALTER TABLE users ADD attributes MAP<TEXT, TEXT>;
+
+CREATE TABLE IF NOT EXISTS attribute_schemas (
+    entity TEXT,
+    schema TEXT,
+    version INT,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (entity)
+);
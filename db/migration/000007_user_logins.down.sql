@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_logins;
+ALTER TABLE users DROP last_login_at;
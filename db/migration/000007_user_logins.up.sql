@@ -0,0 +1,7 @@
+ALTER TABLE users ADD last_login_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_logins (
+    id UUID,
+    login_count COUNTER,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cdc_checkpoints;
+ALTER TABLE users WITH cdc = {'enabled': false};
@@ -0,0 +1,8 @@
+ALTER TABLE users WITH cdc = {'enabled': true, 'preimage': true, 'ttl': 86400};
+
+CREATE TABLE IF NOT EXISTS cdc_checkpoints (
+    consumer TEXT,
+    position TIMESTAMP,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (consumer)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS users_by_username;
+DROP TABLE IF EXISTS users_by_email;
+DROP TABLE IF EXISTS users_by_created;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS users_by_created (
+    shard INT,
+    created_at TIMESTAMP,
+    id UUID,
+    PRIMARY KEY (shard, created_at, id)
+) WITH CLUSTERING ORDER BY (created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS users_by_email (
+    email TEXT,
+    id UUID,
+    PRIMARY KEY (email)
+);
+
+CREATE TABLE IF NOT EXISTS users_by_username (
+    username TEXT,
+    id UUID,
+    PRIMARY KEY (username)
+);
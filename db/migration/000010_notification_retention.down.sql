@@ -0,0 +1,3 @@
+ALTER TABLE user_notifications WITH compaction = {'class': 'SizeTieredCompactionStrategy'}
+    AND default_time_to_live = 0
+    AND gc_grace_seconds = 864000;
@@ -0,0 +1,3 @@
+ALTER TABLE user_notifications WITH compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': '3', 'compaction_window_unit': 'DAYS'}
+    AND default_time_to_live = 7776000
+    AND gc_grace_seconds = 86400;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sagas (
+    shard INT,
+    id TIMEUUID,
+    kind TEXT,
+    aggregate_id TEXT,
+    step TEXT,
+    status TEXT,
+    last_error TEXT,
+    started_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (shard, id)
+) WITH default_time_to_live = 2592000;
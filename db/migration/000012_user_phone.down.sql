@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS users_by_phone;
+ALTER TABLE users DROP phone;
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD phone TEXT;
+
+CREATE TABLE IF NOT EXISTS users_by_phone (
+    phone TEXT,
+    id UUID,
+    PRIMARY KEY (phone)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS user_aliases (
+    alias_id UUID,
+    user_id UUID,
+    merged_at TIMESTAMP,
+    PRIMARY KEY (alias_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_merges (
+    user_id UUID,
+    id TIMEUUID,
+    source_id UUID,
+    source_username TEXT,
+    source_email TEXT,
+    source_phone TEXT,
+    moved SET<TEXT>,
+    released SET<TEXT>,
+    reason TEXT,
+    merged_at TIMESTAMP,
+    PRIMARY KEY (user_id, id)
+) WITH CLUSTERING ORDER BY (id DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS identities_by_user;
+DROP TABLE IF EXISTS identities;
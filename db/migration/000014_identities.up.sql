@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS identities (
+    provider TEXT,
+    subject TEXT,
+    user_id UUID,
+    email TEXT,
+    linked_at TIMESTAMP,
+    PRIMARY KEY ((provider, subject))
+);
+
+CREATE TABLE IF NOT EXISTS identities_by_user (
+    user_id UUID,
+    provider TEXT,
+    subject TEXT,
+    email TEXT,
+    linked_at TIMESTAMP,
+    PRIMARY KEY (user_id, provider, subject)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id UUID PRIMARY KEY,
+    name TEXT,
+    description TEXT,
+    scopes SET<TEXT>,
+    secret_hash TEXT,
+    public_key TEXT,
+    disabled BOOLEAN,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+);
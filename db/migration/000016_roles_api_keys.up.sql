@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    permissions SET<TEXT>,
+    updated_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    key_hash TEXT,
+    name TEXT,
+    roles SET<TEXT>,
+    created_at TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tenant_limits;
+
+ALTER TABLE api_keys DROP tenant;
@@ -0,0 +1,8 @@
+ALTER TABLE api_keys ADD tenant TEXT;
+
+CREATE TABLE IF NOT EXISTS tenant_limits (
+    tenant TEXT PRIMARY KEY,
+    rate_limit BIGINT,
+    rate_window_ms BIGINT,
+    updated_at TIMESTAMP
+);
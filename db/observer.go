@@ -0,0 +1,47 @@
+package db
+
+import (
+	"acid/internal/debugtrace"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// traceObserver adds every statement attempt to the request's debug trace; requests outside debug
+// mode carry no trace and cost one context lookup per statement
+type traceObserver struct{}
+
+func (traceObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	trace := debugtrace.FromContext(ctx)
+	if trace == nil {
+		return
+	}
+	trace.AddStatement(observedStatement(q.Statement, q.Host, q.Attempt, q.Rows, q.End.Sub(q.Start), q.Err))
+}
+
+func (traceObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	trace := debugtrace.FromContext(ctx)
+	if trace == nil || len(b.Statements) == 0 {
+		return
+	}
+	statement := fmt.Sprintf("BATCH of %d: %s", len(b.Statements), b.Statements[0])
+	trace.AddStatement(observedStatement(statement, b.Host, b.Attempt, 0, b.End.Sub(b.Start), b.Err))
+}
+
+func observedStatement(statement string, host *gocql.HostInfo, attempt, rows int, latency time.Duration, err error) debugtrace.Statement {
+	observed := debugtrace.Statement{
+		Statement: statement,
+		Attempt:   attempt,
+		Rows:      rows,
+		Duration:  debugtrace.Milliseconds(latency),
+	}
+	if host != nil {
+		observed.Host = host.ConnectAddressAndPort()
+	}
+	if err != nil {
+		observed.Error = err.Error()
+	}
+	return observed
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// poolObserver counts statement attempts and the time they spend in flight for the pool monitor,
+// then hands them on to the debug trace
+type poolObserver struct {
+	traceObserver
+	pool *poolState
+}
+
+func (o poolObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	o.pool.observe(q.End.Sub(q.Start), q.Err)
+	o.traceObserver.ObserveQuery(ctx, q)
+}
+
+func (o poolObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	o.pool.observe(b.End.Sub(b.Start), b.Err)
+	o.traceObserver.ObserveBatch(ctx, b)
+}
+
+// poolState holds the counters the observer feeds and the gauges of the last check. gocql keeps
+// its pools unexported and has no wait queue to measure: a statement that finds every stream of
+// its connection taken fails at once with ErrNoStreams, so those errors are the saturation signal
+type poolState struct {
+	statements    atomic.Int64
+	busyNanos     atomic.Int64 // Sum of attempt latencies
+	noStreams     atomic.Int64
+	noConnections atomic.Int64
+
+	inFlight      atomic.Int64 // Mean statements in flight over the last interval
+	latencyMicros atomic.Int64 // Mean attempt latency over the last interval
+	alerts        atomic.Int64
+
+	// Only touched by the monitor goroutine
+	last      poolTotals
+	saturated bool
+}
+
+type poolTotals struct {
+	statements, busyNanos, noStreams, noConnections int64
+}
+
+func (p *poolState) observe(latency time.Duration, err error) {
+	p.statements.Add(1)
+	p.busyNanos.Add(int64(latency))
+	switch {
+	case err == nil:
+	case errors.Is(err, gocql.ErrNoStreams):
+		p.noStreams.Add(1)
+	case errors.Is(err, gocql.ErrNoConnections):
+		p.noConnections.Add(1)
+	}
+}
+
+func (db *ScyllaDB) monitorPool() {
+	ticker := time.NewTicker(db.config.PoolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.checkPool(db.config.PoolCheckInterval)
+		}
+	}
+}
+
+// checkPool turns the counters since the previous check into gauges and warns when the pool
+// becomes saturated: statements failed for lack of streams or connections, or more were in
+// flight on average than PoolInFlightThreshold
+func (db *ScyllaDB) checkPool(interval time.Duration) {
+	p := db.pool
+	now := poolTotals{
+		statements:    p.statements.Load(),
+		busyNanos:     p.busyNanos.Load(),
+		noStreams:     p.noStreams.Load(),
+		noConnections: p.noConnections.Load(),
+	}
+	last := p.last
+	p.last = now
+
+	statements := now.statements - last.statements
+	busy := time.Duration(now.busyNanos - last.busyNanos)
+	noStreams := now.noStreams - last.noStreams
+	noConnections := now.noConnections - last.noConnections
+
+	// Little's law: the mean number in flight is the busy time per unit of wall time
+	inFlight := int64(busy / interval)
+	var latency time.Duration
+	if statements > 0 {
+		latency = busy / time.Duration(statements)
+	}
+	p.inFlight.Store(inFlight)
+	p.latencyMicros.Store(latency.Microseconds())
+
+	threshold := int64(db.config.PoolInFlightThreshold)
+	saturated := noStreams > 0 || noConnections > 0 || (threshold > 0 && inFlight >= threshold)
+	if !saturated {
+		if p.saturated {
+			p.saturated = false
+			log.Printf("✅ ScyllaDB connection pool no longer saturated: %d statements in flight", inFlight)
+		}
+		return
+	}
+	if p.saturated {
+		return
+	}
+
+	p.saturated = true
+	p.alerts.Add(1)
+	log.Printf("⚠️ ScyllaDB connection pool saturated: %d statements in flight, mean latency %v, %d without a free stream and %d without a connection since the last check",
+		inFlight, latency, noStreams, noConnections)
+	if db.config.MaxRequestsPerConn > 0 {
+		log.Printf("⚠️ Consider raising DB_MAX_REQUESTS_PER_CONN (now %d) or DB_NUM_CONNECTIONS (now %d); both apply on restart",
+			db.config.MaxRequestsPerConn, db.config.NumConnections)
+	} else {
+		log.Printf("⚠️ Consider raising DB_NUM_CONNECTIONS (now %d, used for non-Scylla hosts) or adding nodes; it applies on restart",
+			db.config.NumConnections)
+	}
+}
+
+// PoolMetrics returns the pool gauges of the last check plus all-time statement and failure counts
+func (db *ScyllaDB) PoolMetrics() map[string]int64 {
+	return map[string]int64{
+		"statements":     db.pool.statements.Load(),
+		"no_streams":     db.pool.noStreams.Load(),
+		"no_connections": db.pool.noConnections.Load(),
+		"in_flight":      db.pool.inFlight.Load(),
+		"latency_us":     db.pool.latencyMicros.Load(),
+		"alerts":         db.pool.alerts.Load(),
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+)
+
+// SplitsReads reports whether reads get a session of their own (ReadConsistency,
+// ReadNumConnections)
+func (c *Config) SplitsReads() bool {
+	return c.ReadNumConnections > 0 || c.readConsistency() != c.Consistency
+}
+
+// readConsistency is the consistency of reads: ReadConsistency, or Consistency when unset
+func (c *Config) readConsistency() gocql.Consistency {
+	if c.ReadConsistency == gocql.Any {
+		return c.Consistency
+	}
+	return c.ReadConsistency
+}
+
+// readNumConnections is the per-host pool size of the read session
+func (c *Config) readNumConnections() int {
+	if c.ReadNumConnections > 0 {
+		return c.ReadNumConnections
+	}
+	return c.NumConnections
+}
+
+// openReadSession connects the read session. It shares the pool monitor's counters with the
+// primary session, and leaves host events to it so they are logged once
+func (db *ScyllaDB) openReadSession() error {
+	config := *db.config
+	config.Consistency = db.config.readConsistency()
+	config.NumConnections = db.config.readNumConnections()
+
+	cluster := newCluster(&config, gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy()), db.pool, db.resolver)
+	session, err := createSession(cluster, &config)
+	if err != nil {
+		return fmt.Errorf("read session: %w", err)
+	}
+	db.reads = gocqlx.NewSession(session)
+	log.Printf("✅ Read session established: consistency %s, %d connections per non-Scylla host",
+		config.Consistency, config.NumConnections)
+	return nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// TimeWindowCompaction is the compaction class of append-only tables with a Retention
+const TimeWindowCompaction = "TimeWindowCompactionStrategy"
+
+// Retention is how an append-only table ages out: rows expire after DefaultTTL and are compacted
+// into one SSTable per Window, so once a window has expired its SSTable is dropped whole instead
+// of being rewritten. Window should be about DefaultTTL/20-30, keeping the SSTable count bounded
+type Retention struct {
+	Window     time.Duration
+	DefaultTTL time.Duration
+
+	// GCGrace is how long tombstones are kept for repair. Expired cells become tombstones too, so
+	// the default of 10 days keeps every expired window on disk for that long after its TTL
+	GCGrace time.Duration
+}
+
+// OptionsCQL is the WITH clause of CREATE or ALTER TABLE that applies the retention, as written in
+// the table's migration
+func (r Retention) OptionsCQL() string {
+	unit, size := "DAYS", r.Window/(24*time.Hour)
+	switch {
+	case r.Window%(24*time.Hour) == 0:
+	case r.Window%time.Hour == 0:
+		unit, size = "HOURS", r.Window/time.Hour
+	default:
+		unit, size = "MINUTES", r.Window/time.Minute
+	}
+	compaction := cqlMap(map[string]string{
+		"class":                  TimeWindowCompaction,
+		"compaction_window_unit": unit,
+		"compaction_window_size": fmt.Sprint(int64(size)),
+	})
+	return fmt.Sprintf("compaction = %s\n    AND default_time_to_live = %d\n    AND gc_grace_seconds = %d",
+		compaction, int64(r.DefaultTTL/time.Second), int64(r.GCGrace/time.Second))
+}
+
+// compareRetention lists how a live table's options differ from its declared retention
+func compareRetention(name string, expected Retention, live gocql.TableMetadataOptions) []string {
+	var diff []string
+	// system_schema holds the full class name, e.g. org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy
+	if class := live.Compaction["class"]; !strings.HasSuffix(class, TimeWindowCompaction) {
+		diff = append(diff, fmt.Sprintf("%s compaction is %s, expected %s", name, class, TimeWindowCompaction))
+	}
+	if want := int(expected.DefaultTTL / time.Second); live.DefaultTimeToLive != want {
+		diff = append(diff, fmt.Sprintf("%s default_time_to_live is %d, expected %d", name, live.DefaultTimeToLive, want))
+	}
+	if want := int(expected.GCGrace / time.Second); live.GcGraceSeconds != want {
+		diff = append(diff, fmt.Sprintf("%s gc_grace_seconds is %d, expected %d", name, live.GcGraceSeconds, want))
+	}
+	return diff
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// TableSchema is a table as the compiled code reads and writes it: the gocqlx metadata the
+// repositories build statements from, plus the CQL type of every column
+type TableSchema struct {
+	Table table.Metadata
+
+	// Types holds each column's CQL type as system_schema spells it, e.g. "map<text, boolean>"
+	Types map[string]string
+
+	// Retention is checked against the table options of append-only tables when set
+	Retention *Retention
+}
+
+// CheckSchema compares the live tables with the expected ones and returns a line per difference:
+// a missing table or column, a column of another type, another primary key or retention. Columns
+// the code doesn't know about are ignored, so a migration can be applied ahead of the deploy that
+// uses it
+func (db *ScyllaDB) CheckSchema(tables []TableSchema) ([]string, error) {
+	metadata, err := db.Session.KeyspaceMetadata(db.config.Keyspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyspace '%s' metadata: %w", db.config.Keyspace, err)
+	}
+
+	var diff []string
+	for _, expected := range tables {
+		live, ok := metadata.Tables[expected.Table.Name]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("table %s is missing", expected.Table.Name))
+			continue
+		}
+		diff = append(diff, compareTable(expected, live)...)
+	}
+	return diff, nil
+}
+
+func compareTable(expected TableSchema, live *gocql.TableMetadata) []string {
+	name := expected.Table.Name
+	var diff []string
+
+	for _, column := range expected.Table.Columns {
+		want := expected.Types[column]
+		got, ok := live.Columns[column]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s.%s is missing, expected %s", name, column, want))
+		case want != "" && normalizeType(got.Type) != normalizeType(want):
+			diff = append(diff, fmt.Sprintf("%s.%s is %s, expected %s", name, column, got.Type, want))
+		}
+	}
+
+	if got := columnNames(live.PartitionKey); !slices.Equal(got, expected.Table.PartKey) {
+		diff = append(diff, fmt.Sprintf("%s partition key is (%s), expected (%s)",
+			name, strings.Join(got, ", "), strings.Join(expected.Table.PartKey, ", ")))
+	}
+	if got := columnNames(live.ClusteringColumns); !slices.Equal(got, expected.Table.SortKey) {
+		diff = append(diff, fmt.Sprintf("%s clustering columns are (%s), expected (%s)",
+			name, strings.Join(got, ", "), strings.Join(expected.Table.SortKey, ", ")))
+	}
+	if expected.Retention != nil {
+		diff = append(diff, compareRetention(name, *expected.Retention, live.Options)...)
+	}
+	return diff
+}
+
+func columnNames(columns []*gocql.ColumnMetadata) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// normalizeType makes type names comparable: case and spacing vary, and varchar is text
+func normalizeType(cqlType string) string {
+	cqlType = strings.ToLower(strings.ReplaceAll(cqlType, " ", ""))
+	return strings.ReplaceAll(cqlType, "varchar", "text")
+}
+
+// ValidateSchema runs CheckSchema according to the configured mode; in fail mode any difference
+// is returned as one error listing them all
+func (db *ScyllaDB) ValidateSchema(tables []TableSchema) error {
+	mode := db.config.SchemaCheck
+	if mode == "" || mode == KeyspaceCheckOff {
+		return nil
+	}
+
+	diff, err := db.CheckSchema(tables)
+	if err != nil {
+		if mode == KeyspaceCheckFail {
+			return fmt.Errorf("schema check failed: %w", err)
+		}
+		log.Printf("⚠️ Schema check failed: %v", err)
+		return nil
+	}
+
+	if len(diff) == 0 {
+		log.Printf("✅ Schema of %d table(s) in '%s' matches this build", len(tables), db.config.Keyspace)
+		return nil
+	}
+	if mode == KeyspaceCheckFail {
+		return fmt.Errorf("schema of keyspace '%s' does not match this build, is a migration missing?\n  %s",
+			db.config.Keyspace, strings.Join(diff, "\n  "))
+	}
+	for _, line := range diff {
+		log.Printf("❌ Schema mismatch in '%s': %s", db.config.Keyspace, line)
+	}
+	return nil
+}
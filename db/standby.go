@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+)
+
+// standbyState is the warm session to the fallback cluster (Config.StandbyHosts) and whether
+// reads use it
+type standbyState struct {
+	config *Config
+
+	mu      sync.Mutex // Serializes connecting
+	session atomic.Pointer[ScyllaDB]
+
+	active        atomic.Bool
+	since         atomic.Int64 // Unix nanos of the last switch
+	failovers     atomic.Int64
+	failbacks     atomic.Int64
+	connectErrors atomic.Int64
+}
+
+// standbyConfig is config pointed at the standby hosts. The standby only serves reads, so it takes
+// their consistency and pool size in a single session. It is connected to once per attempt, and
+// its keyspace is only warned about: it may be replicated differently
+func standbyConfig(config *Config) *Config {
+	standby := *config
+	standby.Hosts = config.StandbyHosts
+	standby.StandbyHosts = nil
+	standby.DiscoveryURL = ""
+	standby.Consistency = config.readConsistency()
+	standby.NumConnections = config.readNumConnections()
+	standby.ReadConsistency, standby.ReadNumConnections = gocql.Any, 0
+	standby.MaxRetries = 1
+	standby.PoolCheckInterval = 0
+	if standby.KeyspaceCheck == KeyspaceCheckFail {
+		standby.KeyspaceCheck = KeyspaceCheckWarn
+	}
+	return &standby
+}
+
+// connect returns the standby session, connecting to it if that hasn't succeeded yet
+func (s *standbyState) connect() (*ScyllaDB, error) {
+	if session := s.session.Load(); session != nil {
+		return session, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session := s.session.Load(); session != nil {
+		return session, nil
+	}
+
+	session, err := ConnectWithConfig(s.config)
+	if err != nil {
+		s.connectErrors.Add(1)
+		return nil, err
+	}
+	s.session.Store(session)
+	return session, nil
+}
+
+func (s *standbyState) close() {
+	if session := s.session.Load(); session != nil {
+		session.Close()
+	}
+}
+
+// ReadSession returns the session reads go through: the standby's while reads are failed over,
+// else the read session when reads are split, else Session. Writes always use Session
+func (db *ScyllaDB) ReadSession() gocqlx.Session {
+	if db.standby != nil && db.standby.active.Load() {
+		if session := db.standby.session.Load(); session != nil {
+			return session.Session
+		}
+	}
+	if db.reads.Session != nil {
+		return db.reads
+	}
+	return db.Session
+}
+
+// HasStandby reports whether a standby cluster is configured
+func (db *ScyllaDB) HasStandby() bool {
+	return db.standby != nil
+}
+
+// OnStandby reports whether reads are failed over to the standby cluster
+func (db *ScyllaDB) OnStandby() bool {
+	return db.standby != nil && db.standby.active.Load()
+}
+
+// FailOver switches reads to the standby cluster once it answers a probe, connecting first if the
+// warm session couldn't be opened at startup. It reports whether reads now go to the standby; with
+// no standby configured, or none reachable, they stay on the primary
+func (db *ScyllaDB) FailOver(ctx context.Context) bool {
+	if db.standby == nil {
+		return false
+	}
+	if db.standby.active.Load() {
+		return true
+	}
+
+	standby, err := db.standby.connect()
+	if err != nil {
+		log.Printf("❌ Primary cluster unavailable and the standby can't be connected: %v", err)
+		return false
+	}
+	if err := standby.PingContext(ctx); err != nil {
+		db.standby.connectErrors.Add(1)
+		log.Printf("❌ Primary cluster unavailable and the standby doesn't answer: %v", err)
+		return false
+	}
+
+	if db.standby.active.CompareAndSwap(false, true) {
+		db.standby.since.Store(time.Now().UnixNano())
+		db.standby.failovers.Add(1)
+		log.Printf("⚠️ Primary cluster unavailable, reads failed over to standby %v", db.config.StandbyHosts)
+	}
+	return true
+}
+
+// FailBack returns reads to the primary cluster
+func (db *ScyllaDB) FailBack() {
+	if db.standby == nil || !db.standby.active.CompareAndSwap(true, false) {
+		return
+	}
+	onStandby := time.Since(time.Unix(0, db.standby.since.Swap(time.Now().UnixNano())))
+	db.standby.failbacks.Add(1)
+	log.Printf("✅ Primary cluster recovered, reads back on the primary after %v on standby", onStandby.Round(time.Second))
+}
+
+// StandbyMetrics returns whether a standby is configured, connected and serving reads, and how
+// often reads failed over and back
+func (db *ScyllaDB) StandbyMetrics() map[string]int64 {
+	if db.standby == nil {
+		return map[string]int64{"configured": 0}
+	}
+	boolInt := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return map[string]int64{
+		"configured":     1,
+		"connected":      boolInt(db.standby.session.Load() != nil),
+		"active":         boolInt(db.standby.active.Load()),
+		"failovers":      db.standby.failovers.Load(),
+		"failbacks":      db.standby.failbacks.Load(),
+		"connect_errors": db.standby.connectErrors.Load(),
+	}
+}
+
+// openStandby opens the warm standby session. A standby that can't be reached is logged, not
+// fatal: the primary serves, and FailOver tries again
+func (db *ScyllaDB) openStandby() {
+	db.standby = &standbyState{config: standbyConfig(db.config)}
+	if _, err := db.standby.connect(); err != nil {
+		log.Printf("⚠️ Standby cluster %v unavailable, will retry on failover: %v", db.config.StandbyHosts, err)
+		return
+	}
+	log.Printf("✅ Warm standby session open to %v", db.config.StandbyHosts)
+}
+
+// validateStandby checks the standby hosts don't overlap the primary's
+func (c *Config) validateStandby() error {
+	primary := make(map[string]bool, len(c.Hosts))
+	for _, host := range c.Hosts {
+		primary[host] = true
+	}
+	for _, host := range c.StandbyHosts {
+		if primary[host] {
+			return fmt.Errorf("standby host %s is also a primary host", host)
+		}
+	}
+	return nil
+}
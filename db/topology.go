@@ -0,0 +1,167 @@
+package db
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// TopologyMetrics counts host events seen by the driver
+type TopologyMetrics struct {
+	HostsAdded   atomic.Int64
+	HostsRemoved atomic.Int64
+	HostUps      atomic.Int64
+	HostDowns    atomic.Int64
+}
+
+type hostState struct {
+	addr string
+	dc   string
+	up   bool
+}
+
+// Topology tracks cluster membership and host state per datacenter from driver events,
+// so node flaps show up in the app's logs and metrics instead of only in Scylla's
+type Topology struct {
+	metrics *TopologyMetrics
+
+	mu    sync.RWMutex
+	hosts map[string]*hostState // Keyed by host ID
+}
+
+func newTopology() *Topology {
+	return &Topology{
+		metrics: &TopologyMetrics{},
+		hosts:   make(map[string]*hostState),
+	}
+}
+
+func (t *Topology) addHost(host *gocql.HostInfo) {
+	t.metrics.HostsAdded.Add(1)
+	t.set(host, host.IsUp())
+	log.Printf("➕ Host added: %s (dc=%s, rack=%s)", host.ConnectAddressAndPort(), host.DataCenter(), host.Rack())
+}
+
+func (t *Topology) removeHost(host *gocql.HostInfo) {
+	t.metrics.HostsRemoved.Add(1)
+	t.mu.Lock()
+	delete(t.hosts, host.HostID())
+	t.mu.Unlock()
+	log.Printf("➖ Host removed: %s (dc=%s)", host.ConnectAddressAndPort(), host.DataCenter())
+}
+
+func (t *Topology) hostUp(host *gocql.HostInfo) {
+	t.metrics.HostUps.Add(1)
+	t.set(host, true)
+	log.Printf("✅ Host up: %s (dc=%s)", host.ConnectAddressAndPort(), host.DataCenter())
+}
+
+func (t *Topology) hostDown(host *gocql.HostInfo) {
+	t.metrics.HostDowns.Add(1)
+	t.set(host, false)
+	log.Printf("⚠️ Host down: %s (dc=%s)", host.ConnectAddressAndPort(), host.DataCenter())
+
+	if t.AliveHosts()[host.DataCenter()] == 0 {
+		log.Printf("❌ No alive hosts left in dc=%s", host.DataCenter())
+	}
+}
+
+func (t *Topology) set(host *gocql.HostInfo, up bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hosts[host.HostID()] = &hostState{
+		addr: host.ConnectAddressAndPort(),
+		dc:   host.DataCenter(),
+		up:   up,
+	}
+}
+
+// AliveHosts returns the number of hosts currently up in each datacenter
+func (t *Topology) AliveHosts() map[string]int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	alive := make(map[string]int)
+	for _, host := range t.hosts {
+		if _, ok := alive[host.dc]; !ok {
+			alive[host.dc] = 0
+		}
+		if host.up {
+			alive[host.dc]++
+		}
+	}
+	return alive
+}
+
+// GetMetrics returns event counters plus alive/down gauges per datacenter
+// ("alive_hosts:<dc>" and "down_hosts:<dc>")
+func (t *Topology) GetMetrics() map[string]int64 {
+	metrics := map[string]int64{
+		"hosts_added":   t.metrics.HostsAdded.Load(),
+		"hosts_removed": t.metrics.HostsRemoved.Load(),
+		"host_ups":      t.metrics.HostUps.Load(),
+		"host_downs":    t.metrics.HostDowns.Load(),
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, host := range t.hosts {
+		alive, down := "alive_hosts:"+host.dc, "down_hosts:"+host.dc
+		if _, ok := metrics[alive]; !ok {
+			// Report zero gauges too, so a fully-down DC is still visible
+			metrics[alive], metrics[down] = 0, 0
+		}
+		if host.up {
+			metrics[alive]++
+		} else {
+			metrics[down]++
+		}
+	}
+	return metrics
+}
+
+// topologyPolicy wraps the host selection policy to observe host state notifications
+// Routing decisions are left entirely to the wrapped policy
+type topologyPolicy struct {
+	gocql.HostSelectionPolicy
+	topology *Topology
+}
+
+func (p *topologyPolicy) AddHost(host *gocql.HostInfo) {
+	p.topology.addHost(host)
+	p.HostSelectionPolicy.AddHost(host)
+}
+
+// AddHosts keeps the wrapped policy's bulk path (token-aware builds its ring once)
+func (p *topologyPolicy) AddHosts(hosts []*gocql.HostInfo) {
+	for _, host := range hosts {
+		p.topology.addHost(host)
+	}
+
+	if bulk, ok := p.HostSelectionPolicy.(interface{ AddHosts([]*gocql.HostInfo) }); ok {
+		bulk.AddHosts(hosts)
+		return
+	}
+	for _, host := range hosts {
+		p.HostSelectionPolicy.AddHost(host)
+	}
+}
+
+func (p *topologyPolicy) RemoveHost(host *gocql.HostInfo) {
+	p.topology.removeHost(host)
+	p.HostSelectionPolicy.RemoveHost(host)
+}
+
+func (p *topologyPolicy) HostUp(host *gocql.HostInfo) {
+	p.topology.hostUp(host)
+	p.HostSelectionPolicy.HostUp(host)
+}
+
+func (p *topologyPolicy) HostDown(host *gocql.HostInfo) {
+	p.topology.hostDown(host)
+	p.HostSelectionPolicy.HostDown(host)
+}
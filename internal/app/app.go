@@ -0,0 +1,64 @@
+// Package app assembles the API server from fx modules. Each module provides one subsystem and
+// registers its start/stop work as lifecycle hooks, so startup order follows the dependency graph
+// and shutdown runs in reverse: servers stop taking traffic before the services, queues, cache
+// and database behind them are closed
+package app
+
+import (
+	"acid/internal/utils"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Modules lists every subsystem of the API server. A new subsystem (e.g. a CDC consumer) is a
+// module providing its components and appending its own hooks; scheduled jobs plug in by
+// providing a scheduler.Job into the scheduled jobs group
+var Modules = fx.Options(
+	fx.Provide(NewConfig, newClientIPResolver),
+	LoggerModule,
+	DatabaseModule,
+	CacheModule,
+	IPFilterModule,
+	CaptureModule,
+	JobsModule,
+	OutboxModule,
+	ServicesModule,
+	SchedulerModule,
+	QuotaModule,
+	AttributesModule,
+	OTPModule,
+	OAuthModule,
+	ServiceAccountModule,
+	AuthzModule,
+	TenantModule,
+	CDCModule,
+	SLOModule,
+	DeprecationModule,
+	WatchdogModule,
+	HTTPModule,
+	GRPCModule,
+)
+
+// New builds the API server; Run starts it, blocks until SIGINT/SIGTERM and stops it
+func New() *fx.App {
+	return fx.New(
+		fx.StartTimeout(utils.GetEnvDuration("STARTUP_TIMEOUT", 30*time.Second)),
+		fx.StopTimeout(utils.GetEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)),
+		fx.WithLogger(newFxLogger),
+		Modules,
+		// Root invokes run after every module's, so the servers start once all routes and
+		// services are registered, and stop first. drainHealth goes last to stop before them
+		fx.Invoke(serveGRPC, serveHTTP, drainHealth),
+	)
+}
+
+// newFxLogger routes fx's own events through zap; the per-constructor chatter is debug-level
+func newFxLogger(logger *zap.Logger) fxevent.Logger {
+	fxLogger := &fxevent.ZapLogger{Logger: logger}
+	fxLogger.UseLogLevel(zapcore.DebugLevel)
+	return fxLogger
+}
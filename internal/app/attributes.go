@@ -0,0 +1,45 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/attributes"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/services"
+	"acid/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// AttributesModule provides the user attribute schema registry and its admin API, and installs
+// the registry as the user service's attribute validator
+var AttributesModule = fx.Module("attributes",
+	fx.Provide(
+		newAttributeRegistry,
+		handlers.NewAttributesHandler,
+	),
+	fx.Invoke(registerAttributeRoutes),
+)
+
+func newAttributeRegistry(database *db.ScyllaDB, logger *zap.Logger) *attributes.Registry {
+	attributesConfig := attributes.DefaultConfig()
+	attributesConfig.CacheTTL = utils.GetEnvDuration("ATTRIBUTE_SCHEMA_CACHE_TTL", attributesConfig.CacheTTL)
+	return attributes.NewRegistry(attributes.NewRepository(database.Session), attributesConfig, logger)
+}
+
+type attributeRouteParams struct {
+	fx.In
+
+	Config            *Config
+	AdminRouter       *gin.Engine `name:"admin"`
+	AttributesHandler *handlers.AttributesHandler
+	Registry          *attributes.Registry
+	UserService       *services.UserService
+}
+
+func registerAttributeRoutes(p attributeRouteParams) {
+	p.UserService.SetAttributeValidator(p.Registry.Validate)
+	server.SetupAttributeRoutes(p.AdminRouter, p.AttributesHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// AuthzModule provides the permission policy the HTTP middleware and gRPC interceptors enforce,
+// the route permission annotations, and the admin API managing roles and API keys
+var AuthzModule = fx.Module("authz",
+	fx.Provide(
+		newAuthzPolicy,
+		handlers.NewAuthzHandler,
+	),
+	fx.Invoke(registerAuthzRoutes),
+)
+
+// newAuthzPolicy caches role and API key lookups for AUTHZ_CACHE_TTL. gRPC callers must
+// authenticate unless GRPC_AUTH_REQUIRED=false opts into anonymous calls, and HTTP writes unless
+// HTTP_WRITE_AUTH_REQUIRED=false does
+func newAuthzPolicy(database *db.ScyllaDB, cacheManager *cache.CacheManager, logger *zap.Logger) *authz.Policy {
+	authzConfig := authz.DefaultConfig()
+	authzConfig.CacheTTL = utils.GetEnvDuration("AUTHZ_CACHE_TTL", authzConfig.CacheTTL)
+	authzConfig.RequireGRPCAuth = utils.GetEnvBool("GRPC_AUTH_REQUIRED", authzConfig.RequireGRPCAuth)
+	if !authzConfig.RequireGRPCAuth {
+		logger.Warn("GRPC_AUTH_REQUIRED=false: gRPC serves unauthenticated calls anonymously")
+	}
+	authzConfig.RequireHTTPWriteAuth = utils.GetEnvBool("HTTP_WRITE_AUTH_REQUIRED", authzConfig.RequireHTTPWriteAuth)
+	if !authzConfig.RequireHTTPWriteAuth {
+		logger.Warn("HTTP_WRITE_AUTH_REQUIRED=false: the public API serves unauthenticated writes anonymously")
+	}
+	return authz.NewPolicy(authz.NewRepository(database.Session), cacheManager, authzConfig, logger)
+}
+
+type authzRouteParams struct {
+	fx.In
+
+	Config       *Config
+	AdminRouter  *gin.Engine `name:"admin"`
+	AuthzHandler *handlers.AuthzHandler
+}
+
+func registerAuthzRoutes(p authzRouteParams) {
+	authz.Annotate(server.RoutePermissions)
+	server.SetupAuthzRoutes(p.AdminRouter, p.AuthzHandler, p.Config.AdminToken)
+}
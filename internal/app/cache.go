@@ -0,0 +1,205 @@
+package app
+
+import (
+	"acid/internal/cache"
+	"acid/internal/safego"
+	"acid/internal/scheduler"
+	"acid/internal/utils"
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// CacheModule provides the multi-tier cache. It is optional: when it can't be set up the
+// manager is nil and the other modules run without cache
+var CacheModule = fx.Module("cache",
+	fx.Provide(
+		newCacheManager,
+		fx.Annotate(newCacheMetricsResetJob, fx.ResultTags(scheduledJobsGroup)),
+	),
+)
+
+func newCacheManager(lc fx.Lifecycle, logger *zap.Logger) *cache.CacheManager {
+	cacheManager, err := initializeCacheSystem(logger)
+	if err != nil {
+		// Continue without cache - graceful degradation
+		logger.Warn("Failed to initialize cache system, continuing without cache", zap.Error(err))
+		return nil
+	}
+	logger.Info("✅ Cache system initialized successfully")
+
+	lc.Append(fx.StopHook(func() error {
+		logger.Info("Shutting down cache system...")
+		if err := cacheManager.Close(); err != nil {
+			logger.Error("❌ Cache system shutdown error", zap.Error(err))
+			return err
+		}
+		logger.Info("✅ Cache system stopped gracefully")
+		return nil
+	}))
+
+	// Drop local entries other instances invalidate (admin evictions); stops before Close
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			safego.Go("cache.invalidations", func() { cacheManager.ListenInvalidations(listenCtx) }, safego.Restart(listenCtx.Done()))
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			stopListening()
+			return nil
+		},
+	})
+	return cacheManager
+}
+
+// newCacheMetricsResetJob resets cache counters daily; they are per instance, so every instance
+// resets its own
+func newCacheMetricsResetJob(cacheManager *cache.CacheManager) scheduler.Job {
+	return scheduler.Job{
+		Name:    "cache_metrics_reset",
+		Spec:    utils.GetEnv("CACHE_METRICS_RESET_SCHEDULE", "0 0 * * *"),
+		Timeout: 10 * time.Second,
+		Task: func(ctx context.Context) error {
+			if cacheManager != nil {
+				cacheManager.ResetMetrics()
+			}
+			return nil
+		},
+	}
+}
+
+// cacheKeyBuilder reads the key layout; bump CACHE_KEY_VERSION when a cached type changes shape
+func cacheKeyBuilder() cache.KeyBuilder {
+	keys := cache.DefaultKeyBuilder()
+	keys.Namespace = utils.GetEnv("CACHE_KEY_NAMESPACE", keys.Namespace)
+	keys.Version = utils.GetEnvInt("CACHE_KEY_VERSION", keys.Version)
+	keys.Tenant = utils.GetEnv("CACHE_KEY_TENANT", keys.Tenant)
+	return keys
+}
+
+func initializeCacheSystem(logger *zap.Logger) (*cache.CacheManager, error) {
+	// Read cache configuration from environment
+	redisHost := utils.GetEnv("REDIS_HOST", "localhost")
+	redisPort := utils.GetEnv("REDIS_PORT", "6379")
+	redisPassword := utils.GetEnv("REDIS_PASSWORD", "")
+	enableLocalCache := utils.GetEnv("ENABLE_LOCAL_CACHE", "true") == "true"
+	enableRedisCache := utils.GetEnv("ENABLE_REDIS_CACHE", "true") == "true"
+
+	logger.Info("Initializing cache system",
+		zap.String("redis_host", redisHost),
+		zap.String("redis_port", redisPort),
+		zap.Bool("local_cache", enableLocalCache),
+		zap.Bool("redis_cache", enableRedisCache),
+	)
+
+	var localCache *cache.LocalCache
+	var redisClient *cache.RedisClient
+
+	// Initialize local cache (BigCache)
+	if enableLocalCache {
+		localConfig := &cache.LocalCacheConfig{
+			Shards:             1024,
+			LifeWindow:         1 * time.Minute,
+			CleanWindow:        5 * time.Minute,
+			MaxEntriesInWindow: 600000, // 10K entries/sec * 60 sec
+			MaxEntrySize:       500,
+			HardMaxCacheSize:   utils.GetEnvInt("LOCAL_CACHE_MAX_SIZE_MB", 100),
+			Verbose:            false,
+
+			MemorySoftLimit:     utils.GetEnvFloat("LOCAL_CACHE_MEMORY_SOFT_LIMIT", 0.8),
+			MemoryCheckInterval: utils.GetEnvDuration("LOCAL_CACHE_MEMORY_CHECK_INTERVAL", 30*time.Second),
+			AutoTuneLifeWindow:  utils.GetEnvBool("LOCAL_CACHE_AUTOTUNE", false),
+			MinLifeWindow:       utils.GetEnvDuration("LOCAL_CACHE_MIN_LIFE_WINDOW", 10*time.Second),
+			RelaxAfter:          utils.GetEnvDuration("LOCAL_CACHE_RELAX_AFTER", 15*time.Minute),
+			Name:                "main",
+		}
+
+		var err error
+		localCache, err = cache.NewLocalCache(localConfig)
+		if err != nil {
+			logger.Warn("Failed to initialize local cache", zap.Error(err))
+			localCache = nil
+		} else {
+			logger.Info("✅ Local cache initialized")
+		}
+	}
+
+	// Initialize Redis cache
+	if enableRedisCache {
+		redisConfig := &cache.RedisConfig{
+			Host:         redisHost,
+			Port:         redisPort,
+			Password:     redisPassword,
+			DB:           0,
+			MaxRetries:   3,
+			PoolSize:     utils.GetEnvInt("REDIS_POOL_SIZE", 20),
+			MinIdleConns: utils.GetEnvInt("REDIS_MIN_IDLE_CONNS", 10),
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+			OpTimeout:    utils.GetEnvDuration("REDIS_OP_TIMEOUT", 2*time.Second),
+
+			PoolCheckInterval: utils.GetEnvDuration("REDIS_POOL_CHECK_INTERVAL", 30*time.Second),
+			PoolSaturation:    utils.GetEnvFloat("REDIS_POOL_SATURATION", 0.9),
+			PoolWaitThreshold: utils.GetEnvDuration("REDIS_POOL_WAIT_THRESHOLD", 5*time.Millisecond),
+			PoolAutoTune:      utils.GetEnvBool("REDIS_POOL_AUTOTUNE", false),
+			MaxPoolSize:       utils.GetEnvInt("REDIS_POOL_MAX_SIZE", 100),
+
+			PasswordFile:       utils.GetEnv("REDIS_PASSWORD_FILE", ""),
+			FailoverRetry:      utils.GetEnvBool("REDIS_FAILOVER_RETRY", true),
+			FailoverBackoff:    utils.GetEnvDuration("REDIS_FAILOVER_BACKOFF", 50*time.Millisecond),
+			FailoverMaxBackoff: utils.GetEnvDuration("REDIS_FAILOVER_MAX_BACKOFF", 500*time.Millisecond),
+			FailoverTimeout:    utils.GetEnvDuration("REDIS_FAILOVER_TIMEOUT", 5*time.Second),
+			ReconnectInterval:  utils.GetEnvDuration("REDIS_RECONNECT_INTERVAL", 1*time.Second),
+		}
+
+		var err error
+		redisClient, err = cache.NewRedisClient(redisConfig)
+		if err != nil {
+			logger.Warn("Failed to initialize Redis cache", zap.Error(err))
+			redisClient = nil
+		} else {
+			logger.Info("✅ Redis cache initialized")
+		}
+	}
+
+	// Create cache manager
+	cacheConfig := &cache.CacheManagerConfig{
+		LocalTTL:            1 * time.Minute,
+		RedisTTL:            10 * time.Minute,
+		EnableLocalCache:    localCache != nil,
+		EnableRedisCache:    redisClient != nil,
+		GracefulDegradation: true, // Continue even if Redis is down
+		WriteThrough:        true,
+		WriteBehind:         utils.GetEnvBool("CACHE_WRITE_BEHIND", false),
+		WriteBehindConfig: &cache.WriteBehindConfig{
+			QueueSize:     utils.GetEnvInt("CACHE_WRITE_BEHIND_QUEUE_SIZE", 10000),
+			Workers:       utils.GetEnvInt("CACHE_WRITE_BEHIND_WORKERS", 4),
+			BatchSize:     utils.GetEnvInt("CACHE_WRITE_BEHIND_BATCH_SIZE", 100),
+			FlushInterval: utils.GetEnvDuration("CACHE_WRITE_BEHIND_FLUSH_INTERVAL", 50*time.Millisecond),
+			Overflow:      cache.OverflowPolicy(utils.GetEnv("CACHE_WRITE_BEHIND_OVERFLOW", string(cache.OverflowDrop))),
+			FlushTimeout:  2 * time.Second,
+		},
+		TTLJitter:        utils.GetEnvFloat("CACHE_TTL_JITTER", 0.1),
+		EarlyRefresh:     utils.GetEnvBool("CACHE_EARLY_REFRESH", false),
+		EarlyRefreshBeta: utils.GetEnvFloat("CACHE_EARLY_REFRESH_BETA", 1.0),
+		ReadBudget:       utils.GetEnvDuration("CACHE_READ_BUDGET", 0),
+		StaleTTL:         utils.GetEnvDuration("CACHE_STALE_TTL", 0),
+		Keys:             cacheKeyBuilder(),
+		Name:             "main",
+	}
+
+	cacheManager := cache.NewCacheManager(localCache, redisClient, cacheConfig)
+
+	// Verify cache health
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health := cacheManager.HealthCheck(ctx)
+	logger.Info("Cache health check", zap.Any("health", health))
+
+	return cacheManager, nil
+}
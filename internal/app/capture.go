@@ -0,0 +1,55 @@
+package app
+
+import (
+	"acid/internal/capture"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// CaptureModule provides the opt-in request/response payload capture and its admin API. With
+// CAPTURE_ROUTES unset the recorder is nil, nothing is captured and the admin API answers 503
+var CaptureModule = fx.Module("capture",
+	fx.Provide(
+		newCaptureRecorder,
+		handlers.NewCaptureHandler,
+	),
+	fx.Invoke(registerCaptureRoutes),
+)
+
+func newCaptureRecorder(logger *zap.Logger) *capture.Recorder {
+	routes := utils.GetEnv("CAPTURE_ROUTES", "")
+	if routes == "" {
+		return nil
+	}
+
+	captureConfig := capture.DefaultConfig()
+	captureConfig.Routes = strings.Split(routes, ",")
+	captureConfig.MaxBodyBytes = utils.GetEnvInt("CAPTURE_MAX_BODY_BYTES", captureConfig.MaxBodyBytes)
+	captureConfig.BufferSize = utils.GetEnvInt("CAPTURE_BUFFER_SIZE", captureConfig.BufferSize)
+	// Adds to the default redacted fields rather than replacing them
+	if fields := utils.GetEnv("CAPTURE_REDACT_FIELDS", ""); fields != "" {
+		captureConfig.RedactFields = append(captureConfig.RedactFields, strings.Split(fields, ",")...)
+	}
+
+	recorder := capture.NewRecorder(captureConfig)
+	logger.Warn("⚠️ Payload capture enabled", zap.Strings("routes", recorder.Routes()))
+	return recorder
+}
+
+type captureRouteParams struct {
+	fx.In
+
+	Config         *Config
+	AdminRouter    *gin.Engine `name:"admin"`
+	CaptureHandler *handlers.CaptureHandler
+}
+
+func registerCaptureRoutes(p captureRouteParams) {
+	server.SetupCaptureRoutes(p.AdminRouter, p.CaptureHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/cdc"
+	"acid/internal/events"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/services"
+	"acid/internal/utils"
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// CDCModule provides the optional consumer of the users CDC log, which publishes users written
+// outside the API to the event bus, where their cached copies are purged, and its admin metrics.
+// With CDC_ENABLED unset the consumer is nil
+var CDCModule = fx.Module("cdc",
+	fx.Provide(
+		newCDCConsumer,
+		handlers.NewCDCHandler,
+	),
+	fx.Invoke(invalidateChangedUsers, registerCDCRoutes),
+)
+
+func newCDCConsumer(lc fx.Lifecycle, database *db.ScyllaDB, bus *events.Bus, logger *zap.Logger) *cdc.Consumer {
+	if !utils.GetEnvBool("CDC_ENABLED", false) {
+		return nil
+	}
+
+	cdcConfig := cdc.DefaultConfig()
+	cdcConfig.PollInterval = utils.GetEnvDuration("CDC_POLL_INTERVAL", cdcConfig.PollInterval)
+	cdcConfig.ConfirmationDelay = utils.GetEnvDuration("CDC_CONFIRMATION_DELAY", cdcConfig.ConfirmationDelay)
+	cdcConfig.Lookback = utils.GetEnvDuration("CDC_LOOKBACK", cdcConfig.Lookback)
+
+	consumer := cdc.NewConsumer(cdc.NewRepository(database.Session), cdc.PublishTo(bus), cdcConfig, logger)
+	lc.Append(fx.StartStopHook(consumer.Start, consumer.Stop))
+	return consumer
+}
+
+// invalidateChangedUsers purges every user CDC reports changed. Writes made through the API were
+// already purged by it; purging them again only costs one extra cache miss
+func invalidateChangedUsers(bus *events.Bus, userService *services.UserService) {
+	events.On(bus, "cache_invalidation", cdc.EventUserChanged, func(ctx context.Context, _ events.Event, change cdc.UserChange) error {
+		return userService.InvalidateUser(ctx, change.ID, change.Emails)
+	})
+}
+
+type cdcRouteParams struct {
+	fx.In
+
+	Config      *Config
+	AdminRouter *gin.Engine `name:"admin"`
+	CDCHandler  *handlers.CDCHandler
+}
+
+func registerCDCRoutes(p cdcRouteParams) {
+	server.SetupCDCRoutes(p.AdminRouter, p.CDCHandler, p.Config.AdminToken)
+}
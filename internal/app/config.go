@@ -0,0 +1,171 @@
+package app
+
+import (
+	"acid/internal/clientip"
+	"acid/internal/correlation"
+	"acid/internal/grpccompress"
+	"acid/internal/listener"
+	"acid/internal/middleware"
+	"acid/internal/utils"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+// Config holds the process-wide settings read by more than one module
+type Config struct {
+	HTTPPort string
+	GRPCPort string
+
+	// AdminPort serves health, admin, cache metrics and pprof separately from the public API so
+	// ingress can expose only the API; empty (or equal to HTTPPort) keeps them on HTTPPort, without pprof
+	AdminPort string
+
+	// ReadOnly replicas (e.g. pointed at a follower DC) serve reads and cache but reject writes
+	ReadOnly bool
+
+	// AdminToken guards /admin and /ws; admin routes are disabled while it is unset
+	AdminToken string
+
+	// AccessLog samples the access log of successful requests on both listeners
+	AccessLog *middleware.AccessLogConfig
+
+	// HTTPServer tunes the public and admin HTTP servers
+	HTTPServer *HTTPServerConfig
+
+	// GRPCServer tunes the gRPC server
+	GRPCServer *GRPCServerConfig
+
+	// Deadline bounds the deadlines upstream callers set for API requests over HTTP and gRPC
+	Deadline *correlation.DeadlineConfig
+}
+
+// HTTPServerConfig holds the http.Server limits and timeouts. Streaming handlers (SSE, GraphQL
+// subscriptions) lift the write deadline of their own connection, so WriteTimeout can stay short
+type HTTPServerConfig struct {
+	ReadTimeout       time.Duration // Whole request, body included; 0 is none
+	ReadHeaderTimeout time.Duration // Request headers, so slow clients can't hold connections open
+	WriteTimeout      time.Duration // From the end of the headers to the end of the response
+	IdleTimeout       time.Duration // Idle keep-alive connections are closed after it
+	MaxHeaderBytes    int
+
+	// KeepAlives lets clients reuse connections; off closes every connection after one request
+	KeepAlives bool
+
+	Listener *listener.Config
+}
+
+// GRPCServerConfig holds the gRPC server limits and HTTP/2 keep-alive settings; zero values keep
+// gRPC's defaults
+type GRPCServerConfig struct {
+	MaxConcurrentStreams uint32 // Per connection; 0 is unlimited
+	MaxRecvMsgSize       int
+	MaxSendMsgSize       int
+
+	// Keepalive pings idle clients and recycles connections by age, e.g. so clients rebalance
+	// onto new instances
+	Keepalive keepalive.ServerParameters
+
+	// KeepalivePolicy is how often clients may ping; faster ones are disconnected
+	KeepalivePolicy keepalive.EnforcementPolicy
+
+	// Compression picks the response compressor; gzip and deflate requests are always accepted
+	Compression *grpccompress.Config
+
+	Listener *listener.Config
+}
+
+func NewConfig() *Config {
+	return &Config{
+		HTTPPort:   utils.GetEnv("HTTP_PORT", "8000"),
+		GRPCPort:   utils.GetEnv("GRPC_PORT", "50051"),
+		AdminPort:  utils.GetEnv("ADMIN_PORT", "8001"),
+		ReadOnly:   utils.GetEnvBool("READ_ONLY", false),
+		AdminToken: utils.GetEnv("ADMIN_TOKEN", ""),
+		AccessLog:  newAccessLogConfig(),
+		HTTPServer: newHTTPServerConfig(),
+		GRPCServer: newGRPCServerConfig(),
+		Deadline:   newDeadlineConfig(),
+	}
+}
+
+func newDeadlineConfig() *correlation.DeadlineConfig {
+	deadlineConfig := correlation.DefaultDeadlineConfig()
+	deadlineConfig.Max = utils.GetEnvDuration("REQUEST_DEADLINE_MAX", deadlineConfig.Max)
+	deadlineConfig.Default = utils.GetEnvDuration("REQUEST_DEADLINE_DEFAULT", deadlineConfig.Default)
+	return deadlineConfig
+}
+
+func newHTTPServerConfig() *HTTPServerConfig {
+	return &HTTPServerConfig{
+		ReadTimeout:       utils.GetEnvDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		ReadHeaderTimeout: utils.GetEnvDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		WriteTimeout:      utils.GetEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       utils.GetEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    utils.GetEnvInt("HTTP_MAX_HEADER_BYTES", 1<<20),
+		KeepAlives:        utils.GetEnvBool("HTTP_KEEP_ALIVES", true),
+		Listener:          newListenerConfig("HTTP"),
+	}
+}
+
+func newGRPCServerConfig() *GRPCServerConfig {
+	return &GRPCServerConfig{
+		MaxConcurrentStreams: uint32(max(utils.GetEnvInt("GRPC_MAX_CONCURRENT_STREAMS", 0), 0)),
+		MaxRecvMsgSize:       utils.GetEnvInt("GRPC_MAX_RECV_MSG_SIZE", 4<<20),
+		MaxSendMsgSize:       utils.GetEnvInt("GRPC_MAX_SEND_MSG_SIZE", 0),
+		Keepalive: keepalive.ServerParameters{
+			Time:                  utils.GetEnvDuration("GRPC_KEEPALIVE_TIME", 2*time.Hour),
+			Timeout:               utils.GetEnvDuration("GRPC_KEEPALIVE_TIMEOUT", 20*time.Second),
+			MaxConnectionIdle:     utils.GetEnvDuration("GRPC_MAX_CONNECTION_IDLE", 0),
+			MaxConnectionAge:      utils.GetEnvDuration("GRPC_MAX_CONNECTION_AGE", 0),
+			MaxConnectionAgeGrace: utils.GetEnvDuration("GRPC_MAX_CONNECTION_AGE_GRACE", 0),
+		},
+		KeepalivePolicy: keepalive.EnforcementPolicy{
+			MinTime:             utils.GetEnvDuration("GRPC_KEEPALIVE_MIN_TIME", 5*time.Minute),
+			PermitWithoutStream: utils.GetEnvBool("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", false),
+		},
+		Compression: newGRPCCompressionConfig(),
+		Listener:    newListenerConfig("GRPC"),
+	}
+}
+
+func newGRPCCompressionConfig() *grpccompress.Config {
+	compressionConfig := grpccompress.DefaultConfig()
+	compressionConfig.Compressor = utils.GetEnv("GRPC_COMPRESSION", compressionConfig.Compressor)
+	compressionConfig.MinSize = utils.GetEnvInt("GRPC_COMPRESSION_MIN_SIZE", compressionConfig.MinSize)
+	return compressionConfig
+}
+
+// newListenerConfig reads <prefix>_LISTEN_BACKLOG and <prefix>_TCP_KEEPALIVE
+func newListenerConfig(prefix string) *listener.Config {
+	listenerConfig := listener.DefaultConfig()
+	listenerConfig.Backlog = utils.GetEnvInt(prefix+"_LISTEN_BACKLOG", listenerConfig.Backlog)
+	listenerConfig.KeepAlive = utils.GetEnvDuration(prefix+"_TCP_KEEPALIVE", listenerConfig.KeepAlive)
+	return listenerConfig
+}
+
+func newAccessLogConfig() *middleware.AccessLogConfig {
+	accessLog := middleware.DefaultAccessLogConfig()
+	accessLog.SampleRate = utils.GetEnvFloat("ACCESS_LOG_SAMPLE_RATE", accessLog.SampleRate)
+	// Replaces the default probe rates when set; "/livez=1,/readyz=1" logs every probe again
+	if raw := utils.GetEnv("ACCESS_LOG_PATH_SAMPLE_RATES", ""); raw != "" {
+		accessLog.PathSampleRates = middleware.ParsePathSampleRates(raw)
+	}
+	return accessLog
+}
+
+// newClientIPResolver trusts X-Forwarded-For only from TRUSTED_PROXIES (comma-separated CIDRs or
+// addresses of the load balancers in front); a malformed entry fails startup
+func newClientIPResolver() (*clientip.Resolver, error) {
+	trusted, err := clientip.ParsePrefixes(utils.GetEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	return clientip.NewResolver(trusted), nil
+}
+
+// SeparateAdminListener reports whether operational endpoints get their own port
+func (c *Config) SeparateAdminListener() bool {
+	return c.AdminPort != "" && c.AdminPort != c.HTTPPort
+}
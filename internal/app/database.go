@@ -0,0 +1,263 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/attributes"
+	"acid/internal/authz"
+	"acid/internal/cdc"
+	"acid/internal/health"
+	"acid/internal/oauth"
+	"acid/internal/outbox"
+	"acid/internal/quota"
+	"acid/internal/repository"
+	"acid/internal/saga"
+	"acid/internal/serviceaccount"
+	"acid/internal/tenant"
+	"acid/internal/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// DatabaseModule provides the ScyllaDB session, the repositories on top of it and the health
+// monitor that drives degraded mode
+var DatabaseModule = fx.Module("database",
+	fx.Provide(
+		newDatabase,
+		(*db.ScyllaDB).Topology,
+		newRetryer,
+		newGuardrails,
+		newReadRetryPolicy,
+		newTableTTLs,
+		newUserRepository,
+		newNotificationRepository,
+		newDBMonitor,
+	),
+	fx.Invoke(validateSchema, warnMissingIndexes),
+)
+
+func newDatabase(lc fx.Lifecycle) (*db.ScyllaDB, error) {
+	dbConfig := db.DefaultConfig()
+	// With a discovery endpoint HOSTS is optional; its names and the endpoint are re-read every
+	// DB_HOST_REFRESH_INTERVAL
+	dbConfig.DiscoveryURL = utils.GetEnv("DB_DISCOVERY_URL", "")
+	defaultHosts := "localhost"
+	if dbConfig.DiscoveryURL != "" {
+		defaultHosts = ""
+	}
+	if hosts := utils.GetEnv("HOSTS", defaultHosts); hosts != "" {
+		dbConfig.Hosts = strings.Split(hosts, ",")
+	}
+	dbConfig.HostRefreshInterval = utils.GetEnvDuration("DB_HOST_REFRESH_INTERVAL", dbConfig.HostRefreshInterval)
+	dbConfig.Keyspace = utils.GetEnv("KEYSPACE", "acid_data")
+	if standbyHosts := utils.GetEnv("STANDBY_HOSTS", ""); standbyHosts != "" {
+		for _, host := range strings.Split(standbyHosts, ",") {
+			dbConfig.StandbyHosts = append(dbConfig.StandbyHosts, strings.TrimSpace(host))
+		}
+	}
+	dbConfig.ShardAwarePort = utils.GetEnvBool("DB_SHARD_AWARE_PORT", dbConfig.ShardAwarePort)
+	dbConfig.NumConnections = utils.GetEnvInt("DB_NUM_CONNECTIONS", dbConfig.NumConnections)
+	dbConfig.MaxRequestsPerConn = utils.GetEnvInt("DB_MAX_REQUESTS_PER_CONN", 0)
+	dbConfig.ReadNumConnections = utils.GetEnvInt("DB_READ_NUM_CONNECTIONS", 0)
+	dbConfig.PoolCheckInterval = utils.GetEnvDuration("DB_POOL_CHECK_INTERVAL", dbConfig.PoolCheckInterval)
+	dbConfig.PoolInFlightThreshold = utils.GetEnvInt("DB_POOL_IN_FLIGHT_THRESHOLD", 0)
+	dbConfig.SpeculativeAttempts = utils.GetEnvInt("DB_SPECULATIVE_ATTEMPTS", 0)
+	dbConfig.SpeculativeDelay = utils.GetEnvDuration("DB_SPECULATIVE_DELAY", dbConfig.SpeculativeDelay)
+	dbConfig.ReadTimeout = utils.GetEnvDuration("DB_READ_TIMEOUT", 0)
+	dbConfig.WriteTimeout = utils.GetEnvDuration("DB_WRITE_TIMEOUT", 0)
+	dbConfig.ScanTimeout = utils.GetEnvDuration("DB_SCAN_TIMEOUT", 0)
+	if err := readKeyspaceConfig(dbConfig); err != nil {
+		return nil, err
+	}
+
+	database, err := db.ConnectWithConfig(dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := database.Health(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("health check failed: %w", err)
+	}
+	lc.Append(fx.StopHook(database.Close))
+	return database, nil
+}
+
+// readKeyspaceConfig reads the consistency levels and the keyspace expectations checked on connect;
+// acidctl reads the same variables
+func readKeyspaceConfig(dbConfig *db.Config) error {
+	if raw := utils.GetEnv("DB_CONSISTENCY", ""); raw != "" {
+		consistency, err := gocql.ParseConsistencyWrapper(raw)
+		if err != nil {
+			return fmt.Errorf("invalid DB_CONSISTENCY: %w", err)
+		}
+		dbConfig.Consistency = consistency
+	}
+	if raw := utils.GetEnv("DB_READ_CONSISTENCY", ""); raw != "" {
+		consistency, err := gocql.ParseConsistencyWrapper(raw)
+		if err != nil {
+			return fmt.Errorf("invalid DB_READ_CONSISTENCY: %w", err)
+		}
+		dbConfig.ReadConsistency = consistency
+	}
+	dbConfig.KeyspaceCheck = utils.GetEnv("DB_KEYSPACE_CHECK", dbConfig.KeyspaceCheck)
+	dbConfig.ExpectedReplicationStrategy = utils.GetEnv("DB_EXPECTED_REPLICATION_STRATEGY", "")
+	dbConfig.ExpectedReplicationFactor = utils.GetEnvInt("DB_EXPECTED_REPLICATION_FACTOR", 0)
+	dbConfig.SchemaCheck = utils.GetEnv("DB_SCHEMA_CHECK", dbConfig.SchemaCheck)
+	return nil
+}
+
+// validateSchema refuses to start when a table the repositories use is missing a migration, before
+// any request could fail to marshal a row
+func validateSchema(database *db.ScyllaDB) error {
+	return database.ValidateSchema([]db.TableSchema{
+		{Table: repository.UserTable.Metadata(), Types: repository.UserColumnTypes},
+		{Table: repository.UserLoginColumns, Types: repository.UserLoginColumnTypes},
+		{Table: repository.UserLoginsTable.Metadata(), Types: repository.UserLoginsColumnTypes},
+		{Table: repository.NotificationTable.Metadata(), Types: repository.NotificationColumnTypes, Retention: &repository.NotificationRetention},
+		{Table: repository.UsersByCreatedTable.Metadata(), Types: repository.UsersByCreatedColumnTypes},
+		{Table: repository.UsersByEmailTable.Metadata(), Types: repository.UsersByEmailColumnTypes},
+		{Table: repository.UsersByUsernameTable.Metadata(), Types: repository.UsersByUsernameColumnTypes},
+		{Table: repository.UsersByPhoneTable.Metadata(), Types: repository.UsersByPhoneColumnTypes},
+		{Table: repository.UserAliasesTable.Metadata(), Types: repository.UserAliasesColumnTypes},
+		{Table: repository.UserMergesTable.Metadata(), Types: repository.UserMergesColumnTypes},
+		{Table: oauth.IdentitiesTable.Metadata(), Types: oauth.ColumnTypes},
+		{Table: oauth.IdentitiesByUserTable.Metadata(), Types: oauth.ColumnTypes},
+		{Table: outbox.OutboxTable.Metadata(), Types: outbox.ColumnTypes},
+		{Table: outbox.DLQTable.Metadata(), Types: outbox.ColumnTypes},
+		{Table: quota.UsageTable.Metadata(), Types: quota.UsageColumnTypes},
+		{Table: quota.LimitsTable.Metadata(), Types: quota.LimitsColumnTypes},
+		{Table: attributes.SchemaTable.Metadata(), Types: attributes.SchemaColumnTypes},
+		{Table: cdc.CheckpointTable.Metadata(), Types: cdc.CheckpointColumnTypes},
+		{Table: saga.Table.Metadata(), Types: saga.ColumnTypes},
+		{Table: serviceaccount.Table.Metadata(), Types: serviceaccount.ColumnTypes},
+		{Table: authz.RolesTable.Metadata(), Types: authz.RoleColumnTypes},
+		{Table: authz.APIKeysTable.Metadata(), Types: authz.APIKeyColumnTypes},
+		{Table: tenant.LimitsTable.Metadata(), Types: tenant.LimitsColumnTypes},
+	})
+}
+
+// warnMissingIndexes logs declared secondary indexes and views the cluster lacks; unlike missing
+// tables they don't stop startup
+func warnMissingIndexes(database *db.ScyllaDB) {
+	database.WarnMissingIndexes(repository.Indexes, repository.Views)
+}
+
+// newRetryer is shared by all repositories; retries apply to idempotent statements only, on top
+// of the driver's retry policy
+func newRetryer() *repository.Retryer {
+	return repository.NewRetryer(&repository.RetryConfig{
+		MaxAttempts:    utils.GetEnvInt("DB_RETRY_MAX_ATTEMPTS", 3),
+		InitialBackoff: utils.GetEnvDuration("DB_RETRY_INITIAL_BACKOFF", 50*time.Millisecond),
+		MaxBackoff:     utils.GetEnvDuration("DB_RETRY_MAX_BACKOFF", 1*time.Second),
+	})
+}
+
+// newGuardrails is shared by all repositories like the retryer
+func newGuardrails() *repository.Guardrails {
+	defaults := repository.DefaultGuardrailConfig()
+	return repository.NewGuardrails(&repository.GuardrailConfig{
+		MaxPageSize:         utils.GetEnvInt("DB_MAX_PAGE_SIZE", defaults.MaxPageSize),
+		MaxPartitionRows:    utils.GetEnvInt("DB_MAX_PARTITION_ROWS", defaults.MaxPartitionRows),
+		DenyLargePartitions: utils.GetEnvBool("DB_DENY_LARGE_PARTITIONS", false),
+	})
+}
+
+// newReadRetryPolicy returns the consistency-downgrading policy for reads, or nil unless
+// DB_READ_DOWNGRADE is enabled
+func newReadRetryPolicy() (*repository.DowngradingRetryPolicy, error) {
+	if !utils.GetEnvBool("DB_READ_DOWNGRADE", false) {
+		return nil, nil
+	}
+
+	var levels []gocql.Consistency
+	for _, raw := range strings.Split(utils.GetEnv("DB_READ_DOWNGRADE_LEVELS", "LOCAL_ONE"), ",") {
+		level, err := gocql.ParseConsistencyWrapper(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_READ_DOWNGRADE_LEVELS: %w", err)
+		}
+		levels = append(levels, level)
+	}
+	return repository.NewDowngradingRetryPolicy(levels), nil
+}
+
+func queryTimeouts(database *db.ScyllaDB) *repository.QueryTimeouts {
+	read, write, scan := database.GetConfig().StatementTimeouts()
+	return &repository.QueryTimeouts{Read: read, Write: write, Scan: scan}
+}
+
+func newUserRepository(database *db.ScyllaDB, retryer *repository.Retryer, guard *repository.Guardrails, readRetry *repository.DowngradingRetryPolicy) (*repository.UserRepository, error) {
+	userRepository := repository.NewUserRepository(database.Session)
+	userRepository.SetReadSession(database.ReadSession)
+	userRepository.SetRetryer(retryer)
+	userRepository.SetGuardrails(guard)
+	userRepository.SetReadSpeculativeExecution(database.ReadSpeculativePolicy())
+	userRepository.SetQueryTimeouts(queryTimeouts(database))
+	if readRetry != nil {
+		userRepository.SetReadRetryPolicy(readRetry)
+	}
+	if err := userRepository.SetBatchConfig(&repository.BatchConfig{
+		Size:          utils.GetEnvInt("DB_BATCH_SIZE", 50),
+		Logged:        utils.GetEnvBool("DB_BATCH_LOGGED", false),
+		MaxPartitions: utils.GetEnvInt("DB_BATCH_MAX_PARTITIONS", 1),
+		Concurrency:   utils.GetEnvInt("DB_BATCH_CONCURRENCY", 4),
+	}); err != nil {
+		return nil, fmt.Errorf("invalid batch configuration: %w", err)
+	}
+	return userRepository, nil
+}
+
+// newTableTTLs reads the per-table TTL overrides of append-only tables, e.g.
+// DB_TABLE_TTLS=user_notifications=720h
+func newTableTTLs() (repository.TableTTLs, error) {
+	ttls, err := repository.ParseTableTTLs(utils.GetEnv("DB_TABLE_TTLS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_TABLE_TTLS: %w", err)
+	}
+	return ttls, nil
+}
+
+func newNotificationRepository(database *db.ScyllaDB, retryer *repository.Retryer, guard *repository.Guardrails, readRetry *repository.DowngradingRetryPolicy, ttls repository.TableTTLs) *repository.NotificationRepository {
+	notificationRepository := repository.NewNotificationRepository(database.Session)
+	notificationRepository.SetReadSession(database.ReadSession)
+	notificationRepository.SetTTL(ttls.For(repository.NotificationTable.Name()))
+	notificationRepository.SetRetryer(retryer)
+	notificationRepository.SetGuardrails(guard)
+	notificationRepository.SetReadSpeculativeExecution(database.ReadSpeculativePolicy())
+	notificationRepository.SetQueryTimeouts(queryTimeouts(database))
+	if readRetry != nil {
+		notificationRepository.SetReadRetryPolicy(readRetry)
+	}
+	return notificationRepository
+}
+
+// newDBMonitor probes ScyllaDB; after repeated failures readiness flips to not-ready, gRPC
+// health reports NOT_SERVING and user reads are served from cache only. With STANDBY_HOSTS set,
+// user reads fail over to the standby cluster instead and the instance stays ready
+func newDBMonitor(lc fx.Lifecycle, database *db.ScyllaDB, logger *zap.Logger) *health.Monitor {
+	monitor := health.NewMonitor("scylladb", database.PingContext, &health.MonitorConfig{
+		Interval:          utils.GetEnvDuration("DB_HEALTH_INTERVAL", 5*time.Second),
+		Timeout:           utils.GetEnvDuration("DB_HEALTH_TIMEOUT", 2*time.Second),
+		FailureThreshold:  utils.GetEnvInt("DB_HEALTH_FAILURE_THRESHOLD", 3),
+		RecoveryThreshold: utils.GetEnvInt("DB_HEALTH_RECOVERY_THRESHOLD", 2),
+	}, logger)
+	if database.HasStandby() {
+		// Registered first, so the listeners after it see where reads go
+		failoverTimeout := utils.GetEnvDuration("DB_STANDBY_FAILOVER_TIMEOUT", 10*time.Second)
+		monitor.OnChange(func(degraded bool) {
+			if !degraded {
+				database.FailBack()
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), failoverTimeout)
+			defer cancel()
+			database.FailOver(ctx)
+		})
+	}
+	lc.Append(fx.StartStopHook(monitor.Start, monitor.Stop))
+	return monitor
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"acid/internal/deprecation"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/utils"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// DeprecationModule provides the registry of deprecated routes and RPCs, consulted by the HTTP
+// middleware and the gRPC interceptors, and its traffic report
+var DeprecationModule = fx.Module("deprecation",
+	fx.Provide(
+		newDeprecationRegistry,
+		handlers.NewDeprecationHandler,
+	),
+	fx.Invoke(registerDeprecationRoutes),
+)
+
+// newDeprecationRegistry takes the notices declared in code; their schedule can be set per
+// deployment, e.g. DEPRECATION_CREATE_USER_V1_SUNSET and DEPRECATION_CREATE_USER_V1_MODE
+func newDeprecationRegistry(logger *zap.Logger) (*deprecation.Registry, error) {
+	deprecationConfig := deprecation.DefaultConfig()
+	deprecationConfig.WarnInterval = utils.GetEnvDuration("DEPRECATION_WARN_INTERVAL", deprecationConfig.WarnInterval)
+	deprecationConfig.Notices = append(deprecationConfig.Notices, server.LegacyNotices()...)
+	for i := range deprecationConfig.Notices {
+		notice := &deprecationConfig.Notices[i]
+		prefix := "DEPRECATION_" + strings.ToUpper(notice.Name) + "_"
+		notice.Since = utils.GetEnvTime(prefix+"SINCE", notice.Since)
+		notice.Sunset = utils.GetEnvTime(prefix+"SUNSET", notice.Sunset)
+		notice.Link = utils.GetEnv(prefix+"LINK", notice.Link)
+		if raw := utils.GetEnv(prefix+"MODE", ""); raw != "" {
+			mode, ok := deprecation.ParseMode(raw)
+			if !ok {
+				return nil, fmt.Errorf("invalid %sMODE %q, expected soft or hard", prefix, raw)
+			}
+			notice.Mode = mode
+		}
+	}
+	return deprecation.NewRegistry(deprecationConfig, logger), nil
+}
+
+type deprecationRouteParams struct {
+	fx.In
+
+	Config             *Config
+	AdminRouter        *gin.Engine `name:"admin"`
+	DeprecationHandler *handlers.DeprecationHandler
+}
+
+func registerDeprecationRoutes(p deprecationRouteParams) {
+	server.SetupDeprecationRoutes(p.AdminRouter, p.DeprecationHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/clientip"
+	"acid/internal/correlation"
+	"acid/internal/debugtrace"
+	"acid/internal/deprecation"
+	grpcServer "acid/internal/grpc"
+	"acid/internal/grpccompress"
+	"acid/internal/health"
+	"acid/internal/ipfilter"
+	"acid/internal/listener"
+	"acid/internal/safego"
+	"acid/internal/server"
+	"acid/internal/serviceaccount"
+	"acid/internal/slo"
+	"acid/internal/utils"
+	pb "acid/proto/acid"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpcHealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GRPCModule provides the gRPC server with the Acid and health services registered. The server
+// itself is started by serveGRPC
+var GRPCModule = fx.Module("grpc",
+	fx.Provide(
+		newGRPCServer,
+		newGRPCHealthServer,
+		newCreateUserIdempotency,
+		grpcServer.NewAcidServer,
+	),
+	fx.Invoke(registerAcidService),
+)
+
+func newGRPCServer(config *Config, resolver *clientip.Resolver, filter *ipfilter.Filter, accounts *serviceaccount.Manager, policy *authz.Policy, tracker *slo.Tracker, deprecations *deprecation.Registry) (*grpc.Server, error) {
+	tuning := config.GRPCServer
+	if _, err := grpccompress.Parse(tuning.Compression.Compressor); err != nil {
+		return nil, fmt.Errorf("invalid GRPC_COMPRESSION: %w", err)
+	}
+	options := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(tuning.MaxRecvMsgSize),
+		grpc.KeepaliveParams(tuning.Keepalive),
+		grpc.KeepaliveEnforcementPolicy(tuning.KeepalivePolicy),
+	}
+	if tuning.MaxConcurrentStreams > 0 {
+		options = append(options, grpc.MaxConcurrentStreams(tuning.MaxConcurrentStreams))
+	}
+	if tuning.MaxSendMsgSize > 0 {
+		options = append(options, grpc.MaxSendMsgSize(tuning.MaxSendMsgSize))
+	}
+	return grpc.NewServer(append(options,
+		grpc.ChainUnaryInterceptor(
+			safego.UnaryServerInterceptor(),
+			clientip.UnaryServerInterceptor(resolver),
+			ipfilter.UnaryServerInterceptor(filter),
+			serviceaccount.UnaryServerInterceptor(accounts, policy, grpcServer.MethodPermissions),
+			authz.UnaryServerInterceptor(policy, grpcServer.MethodPermissions),
+			authz.AuthenticateUnaryServerInterceptor(policy, config.AdminToken),
+			correlation.UnaryServerInterceptor(),
+			correlation.DeadlineUnaryServerInterceptor(config.Deadline),
+			slo.UnaryServerInterceptor(tracker),
+			deprecation.UnaryServerInterceptor(deprecations),
+			debugtrace.UnaryServerInterceptor(config.AdminToken),
+			grpccompress.UnaryServerInterceptor(tuning.Compression),
+		),
+		grpc.ChainStreamInterceptor(
+			safego.StreamServerInterceptor(),
+			clientip.StreamServerInterceptor(resolver),
+			ipfilter.StreamServerInterceptor(filter),
+			serviceaccount.StreamServerInterceptor(accounts, policy, grpcServer.MethodPermissions),
+			authz.StreamServerInterceptor(policy, grpcServer.MethodPermissions),
+			authz.AuthenticateStreamServerInterceptor(policy, config.AdminToken),
+			correlation.StreamServerInterceptor(),
+			deprecation.StreamServerInterceptor(deprecations),
+		),
+	)...), nil
+}
+
+// newCreateUserIdempotency deduplicates CreateUser calls by request_id; nil without a cache
+func newCreateUserIdempotency(cacheManager *cache.CacheManager) *cache.IdempotencyStore {
+	if cacheManager == nil {
+		return nil
+	}
+	return cacheManager.NewIdempotencyStore("grpc:createUser", &cache.IdempotencyConfig{
+		Window:     utils.GetEnvDuration("GRPC_IDEMPOTENCY_WINDOW", 24*time.Hour),
+		PendingTTL: utils.GetEnvDuration("GRPC_IDEMPOTENCY_PENDING_TTL", 30*time.Second),
+	})
+}
+
+// newGRPCHealthServer reports NOT_SERVING while the database monitor is degraded and reads
+// couldn't fail over to a standby cluster
+func newGRPCHealthServer(server *grpc.Server, dbMonitor *health.Monitor, database *db.ScyllaDB) *grpcHealth.Server {
+	healthServer := grpcHealth.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	dbMonitor.OnChange(func(degraded bool) {
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if degraded && !database.OnStandby() {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		healthServer.SetServingStatus("", servingStatus)
+		healthServer.SetServingStatus(pb.Acid_ServiceDesc.ServiceName, servingStatus)
+	})
+	healthServer.SetServingStatus(pb.Acid_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return healthServer
+}
+
+// registerAcidService refuses to start when the Acid service and the REST user routes have
+// drifted apart: an RPC without a route, or one requiring another permission than its route
+func registerAcidService(grpcSrv *grpc.Server, acidServer *grpcServer.AcidServer, logger *zap.Logger) error {
+	if err := server.CheckRPCs(&pb.Acid_ServiceDesc, grpcServer.MethodPermissions); err != nil {
+		return fmt.Errorf("gRPC service out of sync with the REST routes: %w", err)
+	}
+	pb.RegisterAcidServer(grpcSrv, acidServer)
+	logger.Info("✅ gRPC Acid service registered")
+	return nil
+}
+
+// serveGRPC binds the gRPC port on start and stops gracefully, falling back to a hard stop
+// when in-flight calls outlast the shutdown timeout
+func serveGRPC(lc fx.Lifecycle, shutdowner fx.Shutdowner, config *Config, server *grpc.Server, logger *zap.Logger) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ln, err := listener.Listen(ctx, ":"+config.GRPCPort, config.GRPCServer.Listener)
+			if err != nil {
+				return fmt.Errorf("failed to listen on port %s: %w", config.GRPCPort, err)
+			}
+			logger.Info("Starting gRPC server on port " + config.GRPCPort)
+			safego.Go("grpc.serve", func() {
+				if err := server.Serve(ln); err != nil {
+					logger.Error("Failed to serve gRPC server", zap.Error(err))
+					_ = shutdowner.Shutdown(fx.ExitCode(1))
+				}
+			}, shutdownOnPanic(shutdowner))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			safego.Go("grpc.graceful_stop", server.GracefulStop, safego.OnExit(func() { close(stopped) }))
+			select {
+			case <-stopped:
+				logger.Info("✅ gRPC Server stopped gracefully")
+			case <-ctx.Done():
+				server.Stop()
+				logger.Warn("gRPC graceful stop timed out, closed remaining connections")
+			}
+			return nil
+		},
+	})
+}
+
+// drainHealth is registered last so it stops first: health checks fail before the servers
+// close, letting load balancers stop routing new traffic here
+func drainHealth(lc fx.Lifecycle, healthServer *grpcHealth.Server, logger *zap.Logger) {
+	lc.Append(fx.StopHook(func() {
+		logger.Info("Shutting down servers...")
+		healthServer.Shutdown()
+	}))
+}
@@ -0,0 +1,307 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/capture"
+	"acid/internal/clientip"
+	"acid/internal/deprecation"
+	"acid/internal/events"
+	"acid/internal/graph"
+	"acid/internal/handlers"
+	"acid/internal/health"
+	"acid/internal/ipfilter"
+	"acid/internal/listener"
+	"acid/internal/middleware"
+	"acid/internal/quota"
+	"acid/internal/safego"
+	"acid/internal/server"
+	"acid/internal/serviceaccount"
+	"acid/internal/services"
+	"acid/internal/slo"
+	"acid/internal/tenant"
+	"acid/internal/utils"
+	"acid/internal/ws"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// HTTPModule provides the public gin router with its global middleware, the admin router for
+// operational endpoints, the REST, SSE, GraphQL and WebSocket handlers, and registers every route.
+// The listeners themselves are started by serveHTTP
+var HTTPModule = fx.Module("http",
+	fx.Provide(
+		newRouter,
+		fx.Annotate(newAdminRouter, fx.ResultTags(adminRouterTag)),
+		newResponseCache,
+		newWebSocketHub,
+		handlers.NewUserHandler,
+		newHealthHandler,
+		handlers.NewEventsHandler,
+		handlers.NewAdminHandler,
+	),
+	fx.Invoke(
+		invalidateResponseCache,
+		registerRoutes,
+		registerGraphQLRoutes,
+	),
+)
+
+// adminRouterTag names the router serving health, admin and pprof; it is the public router
+// itself unless Config.AdminPort selects a separate listener
+const adminRouterTag = `name:"admin"`
+
+// newEngine applies the middleware shared by both listeners; gin only applies middleware to
+// routes registered after it, so everything global is set up before any route
+func newEngine(config *Config, resolver *clientip.Resolver, filter *ipfilter.Filter, recorder *capture.Recorder, accounts *serviceaccount.Manager, policy *authz.Policy) (*gin.Engine, error) {
+	router := gin.New()
+	// Before any middleware reads ClientIP: gin trusts X-Forwarded-For from everyone by default
+	if err := resolver.Configure(router); err != nil {
+		return nil, err
+	}
+	router.Use(middleware.AccessLog(config.AccessLog), gin.Recovery())
+	router.Use(middleware.Correlation(), middleware.ClientIP())
+	// Opt-in payload capture wraps Problems so rendered error responses are captured too
+	if recorder != nil {
+		router.Use(middleware.Capture(recorder))
+	}
+	router.Use(middleware.Problems())
+	router.NoRoute(middleware.NoRoute)
+
+	// CIDR allow/deny lists, checked before anything does work for the request
+	router.Use(middleware.IPFilter(filter))
+
+	// Service account access tokens and registered API keys, checked before rate limits and
+	// quotas bill the caller. batchGet reads over POST, and unsubscribe links carry a signed token
+	router.Use(middleware.ServiceAuth(accounts, policy, "/api/v1/users:method", "/api/v2/users:method"))
+	router.Use(middleware.APIKeyAuth(policy, config.AdminToken,
+		[]string{"/api/v1/users/:id/unsubscribe", "/api/v2/users/:id/unsubscribe"},
+		"/api/v1/users:method", "/api/v2/users:method"))
+
+	if config.ReadOnly {
+		// batchGet reads over POST, and issuing a token writes nothing
+		router.Use(middleware.ReadOnly("/graphql", "/api/v1/users:method", "/api/v2/users:method", "/auth/token"))
+	}
+	return router, nil
+}
+
+func newRouter(config *Config, resolver *clientip.Resolver, filter *ipfilter.Filter, recorder *capture.Recorder, accounts *serviceaccount.Manager, policy *authz.Policy, cacheManager *cache.CacheManager, quotaManager *quota.Manager, tenants *tenant.Manager, tracker *slo.Tracker, deprecations *deprecation.Registry, logger *zap.Logger) (*gin.Engine, error) {
+	router, err := newEngine(config, resolver, filter, recorder, accounts, policy)
+	if err != nil {
+		return nil, err
+	}
+	if config.ReadOnly {
+		logger.Warn("⚠️ Starting in read-only mode, mutations will be rejected")
+	}
+
+	// Upstream deadlines bound the request context; requests arriving past theirs are refused
+	// before they count against the SLO
+	router.Use(middleware.Deadline(config.Deadline, "/api/v1/users/events", "/ws"))
+
+	// Latency and availability per route, measured before rate limiting so rejections count too
+	router.Use(middleware.SLO(tracker))
+
+	// Deprecation headers and per-caller counts for deprecated routes; retired ones answer 410
+	router.Use(middleware.Deprecations(deprecations))
+
+	// Debug traces for requests carrying the admin token in X-Debug-Token
+	router.Use(middleware.DebugTrace(config.AdminToken))
+
+	// The tenant a request is limited as: its API key's, else TENANT_HEADER's when a gateway sets it
+	router.Use(middleware.Tenant(utils.GetEnv("TENANT_HEADER", "")))
+
+	// Global per-client rate limit, disabled unless RATE_LIMIT_REQUESTS is set
+	if limit := utils.GetEnvInt("RATE_LIMIT_REQUESTS", 0); limit > 0 && cacheManager != nil {
+		limiter := cacheManager.NewRateLimiter(&cache.RateLimiterConfig{
+			Algorithm: cache.RateLimitAlgorithm(utils.GetEnv("RATE_LIMIT_ALGORITHM", string(cache.SlidingWindow))),
+			Prefix:    cacheManager.Keys().Prefix(cache.EntityRateLimit, "http"),
+			FailOpen:  true,
+			Name:      "http",
+		})
+		router.Use(middleware.RateLimit(limiter, int64(limit), utils.GetEnvDuration("RATE_LIMIT_WINDOW", 1*time.Minute), nil))
+	}
+
+	// Per-tenant rate limit across all of a tenant's callers, so one tenant can't starve the others
+	if cacheManager != nil {
+		tenantLimiter := cacheManager.NewRateLimiter(&cache.RateLimiterConfig{
+			Algorithm: cache.RateLimitAlgorithm(utils.GetEnv("RATE_LIMIT_ALGORITHM", string(cache.SlidingWindow))),
+			Prefix:    cacheManager.Keys().Prefix(cache.EntityRateLimit, "tenant"),
+			FailOpen:  true,
+			Name:      "tenant",
+		})
+		router.Use(middleware.TenantRateLimit(tenantLimiter, tenants))
+	}
+
+	// Daily/monthly quotas, metered after the rate limit so throttled requests aren't counted
+	if quotaManager != nil {
+		router.Use(middleware.Quota(quotaManager, nil))
+	}
+	return router, nil
+}
+
+// newAdminRouter builds the admin listener's engine (no client rate limit: its callers are probes,
+// scrapers and operators), or reuses the public router when both share a port
+func newAdminRouter(config *Config, resolver *clientip.Resolver, filter *ipfilter.Filter, recorder *capture.Recorder, accounts *serviceaccount.Manager, policy *authz.Policy, router *gin.Engine) (*gin.Engine, error) {
+	if !config.SeparateAdminListener() {
+		return router, nil
+	}
+	return newEngine(config, resolver, filter, recorder, accounts, policy)
+}
+
+// newResponseCache is the opt-in HTTP response cache for heavy GET routes
+func newResponseCache(cacheManager *cache.CacheManager) *middleware.ResponseCache {
+	return middleware.NewResponseCache(cacheManager, "http")
+}
+
+// invalidateResponseCache purges cached responses for a user on every write to it
+func invalidateResponseCache(userService *services.UserService, responseCache *middleware.ResponseCache, logger *zap.Logger) {
+	userService.RegisterInvalidationHook(func(ctx context.Context, userID string) {
+		if err := responseCache.Invalidate(ctx, middleware.ResourceScope("user", userID)); err != nil {
+			logger.Warn("Failed to invalidate response cache", zap.String("user_id", userID), zap.Error(err))
+		}
+	})
+}
+
+// newHealthHandler keeps readiness up while reads are failed over to the standby cluster
+func newHealthHandler(monitor *health.Monitor, database *db.ScyllaDB) *handlers.HealthHandler {
+	healthHandler := handlers.NewHealthHandler(monitor)
+	healthHandler.SetStandbyCheck(database.OnStandby)
+	return healthHandler
+}
+
+// newWebSocketHub serves real-time admin updates, fed by the same event bus as SSE
+func newWebSocketHub(lc fx.Lifecycle, bus *events.Bus, logger *zap.Logger) *ws.Hub {
+	wsConfig := ws.DefaultHubConfig()
+	if origins := utils.GetEnv("WS_ALLOWED_ORIGINS", ""); origins != "" {
+		wsConfig.AllowedOrigins = strings.Split(origins, ",")
+	}
+	wsConfig.SendBuffer = utils.GetEnvInt("WS_SEND_BUFFER", wsConfig.SendBuffer)
+	wsConfig.PingInterval = utils.GetEnvDuration("WS_PING_INTERVAL", wsConfig.PingInterval)
+	wsConfig.PongWait = utils.GetEnvDuration("WS_PONG_WAIT", wsConfig.PongWait)
+
+	hub := ws.NewHub(bus, wsConfig, logger)
+	lc.Append(fx.StartStopHook(hub.Start, hub.Stop))
+	return hub
+}
+
+type routeParams struct {
+	fx.In
+
+	Config        *Config
+	Router        *gin.Engine
+	AdminRouter   *gin.Engine `name:"admin"`
+	UserHandler   *handlers.UserHandler
+	HealthHandler *handlers.HealthHandler
+	EventsHandler *handlers.EventsHandler
+	AdminHandler  *handlers.AdminHandler
+	Hub           *ws.Hub
+}
+
+func registerRoutes(p routeParams) {
+	// /api/v1 is frozen; set API_V1_DEPRECATED_AT (and API_V1_SUNSET) to announce its retirement
+	server.SetupRoutes(p.Router, p.UserHandler, middleware.DeprecationConfig{
+		Since:  utils.GetEnvTime("API_V1_DEPRECATED_AT", time.Time{}),
+		Sunset: utils.GetEnvTime("API_V1_SUNSET", time.Time{}),
+		Link:   utils.GetEnv("API_V1_DEPRECATION_LINK", ""),
+	})
+	server.SetupV2Routes(p.Router, p.UserHandler)
+	server.SetupEventRoutes(p.Router, p.EventsHandler)
+	server.SetupWebSocketRoutes(p.Router, p.Hub, p.Config.AdminToken)
+
+	// Operational endpoints; pprof is unauthenticated, so it is never mounted on the public port
+	server.SetupHealthRoutes(p.AdminRouter, p.HealthHandler)
+	server.SetupAdminRoutes(p.AdminRouter, p.AdminHandler, p.Config.AdminToken)
+	if p.Config.SeparateAdminListener() && utils.GetEnvBool("PPROF_ENABLED", true) {
+		server.SetupProfilingRoutes(p.AdminRouter)
+	}
+}
+
+// registerGraphQLRoutes mounts the optional GraphQL API for the admin console
+func registerGraphQLRoutes(router *gin.Engine, userService *services.UserService, bus *events.Bus, logger *zap.Logger) error {
+	if !utils.GetEnvBool("GRAPHQL_ENABLED", false) {
+		return nil
+	}
+	graphHandler, err := graph.NewHandler(graph.NewResolver(userService, bus, logger), logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize GraphQL schema: %w", err)
+	}
+	server.SetupGraphQLRoutes(router, graphHandler)
+	logger.Info("✅ GraphQL endpoint enabled at /graphql")
+	return nil
+}
+
+type serveHTTPParams struct {
+	fx.In
+
+	Lifecycle   fx.Lifecycle
+	Shutdowner  fx.Shutdowner
+	Config      *Config
+	Router      *gin.Engine
+	AdminRouter *gin.Engine `name:"admin"`
+	Logger      *zap.Logger
+}
+
+// serveHTTP starts the public listener and, when configured, the admin listener. The admin one
+// is registered first so it stops last, keeping probes and metrics reachable while the API drains
+func serveHTTP(p serveHTTPParams) {
+	if p.Config.SeparateAdminListener() {
+		serveHandler(p.Lifecycle, p.Shutdowner, "Admin HTTP", p.Config.AdminPort, p.Config.HTTPServer, p.AdminRouter, p.Logger)
+	}
+	serveHandler(p.Lifecycle, p.Shutdowner, "HTTP", p.Config.HTTPPort, p.Config.HTTPServer, p.Router, p.Logger)
+}
+
+// shutdownOnPanic stops the process when a server's goroutine panics, as it can't serve without it
+func shutdownOnPanic(shutdowner fx.Shutdowner) safego.Option {
+	return safego.OnPanic(func(any) {
+		_ = shutdowner.Shutdown(fx.ExitCode(1))
+	})
+}
+
+// serveHandler binds port on start, so a taken port fails startup, and drains in-flight requests
+// on stop within the shutdown timeout
+func serveHandler(lc fx.Lifecycle, shutdowner fx.Shutdowner, name, port string, tuning *HTTPServerConfig, handler http.Handler, logger *zap.Logger) {
+	httpServer := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadTimeout:       tuning.ReadTimeout,
+		ReadHeaderTimeout: tuning.ReadHeaderTimeout,
+		WriteTimeout:      tuning.WriteTimeout,
+		IdleTimeout:       tuning.IdleTimeout,
+		MaxHeaderBytes:    tuning.MaxHeaderBytes,
+	}
+	httpServer.SetKeepAlivesEnabled(tuning.KeepAlives)
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ln, err := listener.Listen(ctx, httpServer.Addr, tuning.Listener)
+			if err != nil {
+				return fmt.Errorf("failed to listen on port %s: %w", port, err)
+			}
+			logger.Info("Starting " + name + " server on port " + port)
+			safego.Go("http.serve", func() {
+				if err := httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("Failed to serve "+name+" server", zap.Error(err))
+					_ = shutdowner.Shutdown(fx.ExitCode(1))
+				}
+			}, shutdownOnPanic(shutdowner))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := httpServer.Shutdown(ctx); err != nil {
+				logger.Error("❌ "+name+" server shutdown error", zap.Error(err))
+				return err
+			}
+			logger.Info("✅ " + name + " server stopped gracefully")
+			return nil
+		},
+	})
+}
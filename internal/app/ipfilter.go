@@ -0,0 +1,73 @@
+package app
+
+import (
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/ipfilter"
+	"acid/internal/server"
+	"acid/internal/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// IPFilterModule provides the IP allow/deny filter enforced by both HTTP listeners and the gRPC
+// server, and its admin API. With no lists configured every address is allowed
+var IPFilterModule = fx.Module("ipfilter",
+	fx.Provide(
+		newIPFilter,
+		handlers.NewIPRulesHandler,
+	),
+	fx.Invoke(registerIPRuleRoutes),
+)
+
+// ipFilterEnv names the static list variables of each scope
+var ipFilterEnv = map[ipfilter.Scope][2]string{
+	ipfilter.ScopeAdmin: {"IP_ALLOW_ADMIN", "IP_DENY_ADMIN"},
+	ipfilter.ScopeAPI:   {"IP_ALLOW_API", "IP_DENY_API"},
+	ipfilter.ScopeGRPC:  {"IP_ALLOW_GRPC", "IP_DENY_GRPC"},
+}
+
+// newIPFilter reads the static lists; a malformed CIDR fails startup rather than silently
+// leaving a list open
+func newIPFilter(lc fx.Lifecycle, cacheManager *cache.CacheManager, logger *zap.Logger) (*ipfilter.Filter, error) {
+	filterConfig := ipfilter.DefaultConfig()
+	for scope, env := range ipFilterEnv {
+		allow, err := ipfilter.ParsePrefixes(utils.GetEnv(env[0], ""))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env[0], err)
+		}
+		deny, err := ipfilter.ParsePrefixes(utils.GetEnv(env[1], ""))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env[1], err)
+		}
+		filterConfig.Static[scope] = ipfilter.Rules{Allow: allow, Deny: deny}
+	}
+	filterConfig.RefreshInterval = utils.GetEnvDuration("IP_RULES_REFRESH_INTERVAL", filterConfig.RefreshInterval)
+
+	// Without Redis only the static lists apply and the admin API can't change them
+	var store *cache.IPRuleStore
+	if cacheManager != nil {
+		store = cacheManager.NewIPRuleStore(cacheManager.Keys().Prefix(cache.EntityIPRules))
+	} else {
+		logger.Warn("IP filter running without cache, runtime IP rules are disabled")
+	}
+
+	filter := ipfilter.NewFilter(store, filterConfig, logger)
+	lc.Append(fx.StartStopHook(filter.Start, filter.Stop))
+	return filter, nil
+}
+
+type ipRuleRouteParams struct {
+	fx.In
+
+	Config         *Config
+	AdminRouter    *gin.Engine `name:"admin"`
+	IPRulesHandler *handlers.IPRulesHandler
+}
+
+func registerIPRuleRoutes(p ipRuleRouteParams) {
+	server.SetupIPRuleRoutes(p.AdminRouter, p.IPRulesHandler, p.Config.AdminToken)
+}
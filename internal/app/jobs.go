@@ -0,0 +1,28 @@
+package app
+
+import (
+	"acid/internal/jobs"
+	"acid/internal/utils"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// JobsModule provides the background job queue for side effects such as welcome emails
+var JobsModule = fx.Module("jobs",
+	fx.Provide(newJobQueue),
+)
+
+func newJobQueue(lc fx.Lifecycle, logger *zap.Logger) *jobs.Queue {
+	jobQueue := jobs.NewQueue(&jobs.QueueConfig{
+		Workers:        utils.GetEnvInt("JOB_WORKERS", 4),
+		Capacity:       utils.GetEnvInt("JOB_QUEUE_CAPACITY", 1000),
+		JobTimeout:     utils.GetEnvDuration("JOB_TIMEOUT", 30*time.Second),
+		MaxAttempts:    utils.GetEnvInt("JOB_MAX_ATTEMPTS", 5),
+		InitialBackoff: utils.GetEnvDuration("JOB_INITIAL_BACKOFF", 1*time.Second),
+		MaxBackoff:     utils.GetEnvDuration("JOB_MAX_BACKOFF", 1*time.Minute),
+	}, logger)
+	lc.Append(fx.StartStopHook(jobQueue.Start, jobQueue.Stop))
+	return jobQueue
+}
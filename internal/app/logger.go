@@ -0,0 +1,51 @@
+package app
+
+import (
+	loggerUtils "acid/internal/logger"
+	"acid/internal/safego"
+	"acid/internal/utils"
+	"fmt"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var LoggerModule = fx.Module("logger",
+	fx.Provide(newLogger),
+	fx.Invoke(configureGoroutines),
+)
+
+func newLogger(lc fx.Lifecycle) (*zap.Logger, error) {
+	loggerConfig := loggerUtils.DefaultConfig()
+	loggerConfig.SampleInitial = utils.GetEnvInt("LOG_SAMPLING_INITIAL", loggerConfig.SampleInitial)
+	loggerConfig.SampleThereafter = utils.GetEnvInt("LOG_SAMPLING_THEREAFTER", loggerConfig.SampleThereafter)
+	loggerConfig.Async = utils.GetEnvBool("LOG_ASYNC", loggerConfig.Async)
+	loggerConfig.AsyncQueueSize = utils.GetEnvInt("LOG_ASYNC_QUEUE_SIZE", loggerConfig.AsyncQueueSize)
+	overflow, err := loggerUtils.ParseOverflowPolicy(utils.GetEnv("LOG_ASYNC_OVERFLOW", string(loggerConfig.AsyncOverflow)))
+	if err != nil {
+		return nil, err
+	}
+	loggerConfig.AsyncOverflow = overflow
+
+	logger, err := loggerUtils.InitLoggerWithConfig(loggerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	// Flush buffered entries last, including those queued by LOG_ASYNC; syncing stderr fails on
+	// some platforms, which is harmless
+	lc.Append(fx.StopHook(func() {
+		_ = logger.Sync()
+	}))
+	return logger, nil
+}
+
+// configureGoroutines reports panics in background goroutines through the logger, and sets how
+// long restarted ones back off (GOROUTINE_RESTART_*). Goroutines started earlier, while the
+// logger was being built, report to stderr
+func configureGoroutines(logger *zap.Logger) {
+	safegoConfig := safego.DefaultConfig()
+	safegoConfig.InitialBackoff = utils.GetEnvDuration("GOROUTINE_RESTART_BACKOFF", safegoConfig.InitialBackoff)
+	safegoConfig.MaxBackoff = utils.GetEnvDuration("GOROUTINE_RESTART_MAX_BACKOFF", safegoConfig.MaxBackoff)
+	safegoConfig.StableAfter = utils.GetEnvDuration("GOROUTINE_RESTART_RESET_AFTER", safegoConfig.StableAfter)
+	safego.Configure(safegoConfig, logger)
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/oauth"
+	"acid/internal/server"
+	"acid/internal/services"
+	"acid/internal/utils"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// OAuthModule provides sign-in with Google and GitHub, linking provider identities to users and
+// provisioning users on first sign-in, and the identities admin API
+var OAuthModule = fx.Module("oauth",
+	fx.Provide(
+		newOAuthProviders,
+		newOAuthStates,
+		newOAuthService,
+		handlers.NewOAuthHandler,
+	),
+	fx.Invoke(registerOAuthRoutes),
+)
+
+// newOAuthProviders configures each provider whose OAUTH_<PROVIDER>_CLIENT_ID is set; with none,
+// every sign-in route answers 404
+func newOAuthProviders(logger *zap.Logger) ([]*oauth.Provider, error) {
+	var providers []*oauth.Provider
+	for _, known := range []struct {
+		name     string
+		defaults func() *oauth.ProviderConfig
+	}{
+		{oauth.ProviderGoogle, oauth.DefaultGoogleConfig},
+		{oauth.ProviderGitHub, oauth.DefaultGitHubConfig},
+	} {
+		name := known.name
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		providerConfig := known.defaults()
+		providerConfig.ClientID = utils.GetEnv(prefix+"CLIENT_ID", "")
+		if providerConfig.ClientID == "" {
+			continue
+		}
+		providerConfig.ClientSecret = utils.GetEnv(prefix+"CLIENT_SECRET", "")
+		providerConfig.RedirectURL = utils.GetEnv(prefix+"REDIRECT_URL", "")
+		if providerConfig.ClientSecret == "" || providerConfig.RedirectURL == "" {
+			return nil, fmt.Errorf("%sCLIENT_ID needs %sCLIENT_SECRET and %sREDIRECT_URL", prefix, prefix, prefix)
+		}
+		// Endpoint overrides point a provider at a mock in development
+		providerConfig.AuthURL = utils.GetEnv(prefix+"AUTH_URL", providerConfig.AuthURL)
+		providerConfig.TokenURL = utils.GetEnv(prefix+"TOKEN_URL", providerConfig.TokenURL)
+		providerConfig.UserInfoURL = utils.GetEnv(prefix+"USERINFO_URL", providerConfig.UserInfoURL)
+		providerConfig.EmailsURL = utils.GetEnv(prefix+"EMAILS_URL", providerConfig.EmailsURL)
+		providerConfig.Timeout = utils.GetEnvDuration("OAUTH_TIMEOUT", providerConfig.Timeout)
+
+		provider, err := oauth.NewProvider(name, providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+		logger.Info("Social sign-in enabled", zap.String("provider", name))
+	}
+	return providers, nil
+}
+
+// newOAuthStates keeps pending sign-ins in Redis; without cache there are none
+func newOAuthStates(cacheManager *cache.CacheManager) *cache.OAuthStateStore {
+	if cacheManager == nil {
+		return nil
+	}
+	return cacheManager.NewOAuthStateStore(utils.GetEnvDuration("OAUTH_STATE_TTL", cache.DefaultOAuthStateTTL))
+}
+
+func newOAuthService(users *services.UserService, database *db.ScyllaDB, states *cache.OAuthStateStore, providers []*oauth.Provider, logger *zap.Logger) *services.OAuthService {
+	oauthConfig := services.DefaultOAuthConfig()
+	oauthConfig.LinkVerifiedEmail = utils.GetEnvBool("OAUTH_LINK_VERIFIED_EMAIL", oauthConfig.LinkVerifiedEmail)
+	return services.NewOAuthService(users, oauth.NewRepository(database.Session), states, providers, oauthConfig, logger)
+}
+
+type oauthRouteParams struct {
+	fx.In
+
+	Config       *Config
+	Router       *gin.Engine
+	AdminRouter  *gin.Engine `name:"admin"`
+	OAuthHandler *handlers.OAuthHandler
+}
+
+func registerOAuthRoutes(p oauthRouteParams) {
+	server.SetupOAuthRoutes(p.Router, p.AdminRouter, p.OAuthHandler, p.Config.AdminToken)
+}
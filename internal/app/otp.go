@@ -0,0 +1,75 @@
+package app
+
+import (
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/services"
+	"acid/internal/sms"
+	"acid/internal/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// OTPModule provides SMS login codes, a second factor the authenticating service can offer, and
+// their admin API
+var OTPModule = fx.Module("otp",
+	fx.Provide(
+		newSMSSender,
+		newLoginCodes,
+		services.NewOTPService,
+		handlers.NewOTPHandler,
+	),
+	fx.Invoke(registerOTPRoutes),
+)
+
+// newSMSSender picks the SMS provider from SMS_PROVIDER: log (default) or twilio
+func newSMSSender(logger *zap.Logger) (sms.Sender, error) {
+	switch provider := utils.GetEnv("SMS_PROVIDER", "log"); provider {
+	case "log":
+		return sms.NewLogSender(logger), nil
+	case "twilio":
+		twilioConfig := sms.DefaultTwilioConfig()
+		twilioConfig.AccountSID = utils.GetEnv("TWILIO_ACCOUNT_SID", "")
+		twilioConfig.AuthToken = utils.GetEnv("TWILIO_AUTH_TOKEN", "")
+		twilioConfig.From = utils.GetEnv("TWILIO_FROM", "")
+		twilioConfig.MessagingServiceSID = utils.GetEnv("TWILIO_MESSAGING_SERVICE_SID", "")
+		twilioConfig.BaseURL = utils.GetEnv("TWILIO_BASE_URL", twilioConfig.BaseURL)
+		if twilioConfig.AccountSID == "" || twilioConfig.AuthToken == "" {
+			return nil, fmt.Errorf("SMS_PROVIDER=twilio needs TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN")
+		}
+		if twilioConfig.From == "" && twilioConfig.MessagingServiceSID == "" {
+			return nil, fmt.Errorf("SMS_PROVIDER=twilio needs TWILIO_FROM or TWILIO_MESSAGING_SERVICE_SID")
+		}
+		return sms.NewTwilioSender(twilioConfig), nil
+	default:
+		return nil, fmt.Errorf("SMS_PROVIDER: unknown provider %q, want log or twilio", provider)
+	}
+}
+
+// newLoginCodes keeps pending login codes in Redis; without cache there are none
+func newLoginCodes(cacheManager *cache.CacheManager) *cache.OTPStore {
+	if cacheManager == nil {
+		return nil
+	}
+	otpConfig := cache.DefaultOTPConfig()
+	otpConfig.TTL = utils.GetEnvDuration("OTP_TTL", otpConfig.TTL)
+	otpConfig.MaxAttempts = utils.GetEnvInt("OTP_MAX_ATTEMPTS", otpConfig.MaxAttempts)
+	otpConfig.ResendAfter = utils.GetEnvDuration("OTP_RESEND_AFTER", otpConfig.ResendAfter)
+	return cacheManager.NewOTPStore("login", otpConfig)
+}
+
+type otpRouteParams struct {
+	fx.In
+
+	Config      *Config
+	AdminRouter *gin.Engine `name:"admin"`
+	OTPHandler  *handlers.OTPHandler
+}
+
+func registerOTPRoutes(p otpRouteParams) {
+	server.SetupOTPRoutes(p.AdminRouter, p.OTPHandler, p.Config.AdminToken)
+}
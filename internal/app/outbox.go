@@ -0,0 +1,118 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/events"
+	"acid/internal/outbox"
+	"acid/internal/scheduler"
+	"acid/internal/utils"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// OutboxModule provides the event outbox, the relay publishing it and the event bus the published
+// events go through: to the EVENT_SINK, then to handlers and subscribers (SSE, GraphQL
+// subscriptions, WebSocket)
+var OutboxModule = fx.Module("outbox",
+	fx.Provide(
+		newOutboxRepository,
+		newEventBus,
+		newPublisher,
+		newRelay,
+		fx.Annotate(newOutboxDLQReportJob, fx.ResultTags(scheduledJobsGroup)),
+	),
+	fx.Invoke(runRelay),
+)
+
+func newOutboxRepository(database *db.ScyllaDB) *outbox.Repository {
+	return outbox.NewRepository(database.Session)
+}
+
+func newEventBus(logger *zap.Logger) *events.Bus {
+	return events.NewBus(&events.BusConfig{
+		SubscriberBuffer: utils.GetEnvInt("EVENT_BUS_BUFFER", 64),
+		HistorySize:      utils.GetEnvInt("EVENT_BUS_HISTORY", 256),
+	}, logger)
+}
+
+// newPublisher relays outbox events to the bus, whose durable backend is the EVENT_SINK
+func newPublisher(lc fx.Lifecycle, bus *events.Bus, logger *zap.Logger) (outbox.Publisher, error) {
+	sinkPublisher, err := initializeEventPublisher(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event publisher: %w", err)
+	}
+	bus.SetBackend(outbox.NewSinkBackend(sinkPublisher))
+	publisher := outbox.NewBusPublisher(bus)
+	lc.Append(fx.StopHook(publisher.Close))
+	return publisher, nil
+}
+
+func newRelay(repo *outbox.Repository, publisher outbox.Publisher, logger *zap.Logger) *outbox.Relay {
+	return outbox.NewRelay(repo, publisher, &outbox.RelayConfig{
+		PollInterval:   utils.GetEnvDuration("OUTBOX_POLL_INTERVAL", 1*time.Second),
+		BatchSize:      uint(utils.GetEnvInt("OUTBOX_BATCH_SIZE", 100)),
+		MaxAttempts:    utils.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 5),
+		PublishTimeout: utils.GetEnvDuration("OUTBOX_PUBLISH_TIMEOUT", 5*time.Second),
+	}, logger)
+}
+
+// runRelay starts the relay on writable instances only: marking events as published is a write
+func runRelay(lc fx.Lifecycle, config *Config, relay *outbox.Relay) {
+	if !config.ReadOnly {
+		lc.Append(fx.StartStopHook(relay.Start, relay.Stop))
+	}
+}
+
+// newOutboxDLQReportJob is a fleet-wide DLQ check; one instance is enough to raise the alarm
+func newOutboxDLQReportJob(relay *outbox.Relay, logger *zap.Logger) scheduler.Job {
+	return scheduler.Job{
+		Name:           "outbox_dlq_report",
+		Spec:           utils.GetEnv("OUTBOX_DLQ_REPORT_SCHEDULE", "@hourly"),
+		Timeout:        1 * time.Minute,
+		Jitter:         30 * time.Second,
+		SingleInstance: true,
+		Task: func(ctx context.Context) error {
+			if depth := relay.GetMetrics()["dlq_depth"]; depth > 0 {
+				logger.Warn("Outbox DLQ is not empty, replay via POST /admin/outbox/replay",
+					zap.Int64("dlq_depth", depth))
+			}
+			return nil
+		},
+	}
+}
+
+// initializeEventPublisher selects the outbox event sink from EVENT_SINK ("log" or "nats")
+func initializeEventPublisher(logger *zap.Logger) (outbox.Publisher, error) {
+	sink := utils.GetEnv("EVENT_SINK", "log")
+	logger.Info("Initializing event publisher", zap.String("sink", sink))
+
+	switch sink {
+	case "log":
+		return outbox.NewLogPublisher(logger), nil
+	case "nats":
+		natsConfig := outbox.DefaultNATSConfig()
+		natsConfig.URL = utils.GetEnv("NATS_URL", natsConfig.URL)
+		natsConfig.Stream = utils.GetEnv("NATS_STREAM", natsConfig.Stream)
+		natsConfig.SubjectPrefix = utils.GetEnv("NATS_SUBJECT_PREFIX", natsConfig.SubjectPrefix)
+		natsConfig.DuplicateWindow = utils.GetEnvDuration("NATS_DUPLICATE_WINDOW", natsConfig.DuplicateWindow)
+		natsConfig.Encoding = utils.GetEnv("EVENT_ENCODING", natsConfig.Encoding)
+		publisher, err := outbox.NewNATSPublisher(natsConfig, logger)
+		if err != nil {
+			return nil, err
+		}
+		if registryURL := utils.GetEnv("SCHEMA_REGISTRY_URL", ""); registryURL != "" && natsConfig.Encoding == outbox.EncodingProtobuf {
+			registryConfig := outbox.DefaultSchemaRegistryConfig()
+			registryConfig.URL = registryURL
+			registryConfig.Username = utils.GetEnv("SCHEMA_REGISTRY_USERNAME", "")
+			registryConfig.Password = utils.GetEnv("SCHEMA_REGISTRY_PASSWORD", "")
+			publisher.SetSchemaRegistry(outbox.NewSchemaRegistry(registryConfig))
+		}
+		return publisher, nil
+	default:
+		return nil, fmt.Errorf("unknown EVENT_SINK %q (expected \"log\" or \"nats\")", sink)
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/quota"
+	"acid/internal/server"
+	"acid/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// QuotaModule provides the optional daily/monthly quota manager and its admin API. With
+// QUOTA_ENABLED unset the manager is nil, no requests are metered and the admin API answers 503
+var QuotaModule = fx.Module("quota",
+	fx.Provide(
+		newQuotaManager,
+		handlers.NewQuotaHandler,
+	),
+	fx.Invoke(registerQuotaRoutes),
+)
+
+func newQuotaManager(lc fx.Lifecycle, database *db.ScyllaDB, cacheManager *cache.CacheManager, logger *zap.Logger) *quota.Manager {
+	if !utils.GetEnvBool("QUOTA_ENABLED", false) {
+		return nil
+	}
+
+	quotaConfig := quota.DefaultConfig()
+	quotaConfig.Defaults = quota.Limits{
+		Daily:   int64(utils.GetEnvInt("QUOTA_DAILY_LIMIT", 0)),
+		Monthly: int64(utils.GetEnvInt("QUOTA_MONTHLY_LIMIT", 0)),
+	}
+	quotaConfig.TenantDefaults = quota.Limits{
+		Daily:   int64(utils.GetEnvInt("QUOTA_TENANT_DAILY_LIMIT", 0)),
+		Monthly: int64(utils.GetEnvInt("QUOTA_TENANT_MONTHLY_LIMIT", 0)),
+	}
+	quotaConfig.FlushInterval = utils.GetEnvDuration("QUOTA_FLUSH_INTERVAL", quotaConfig.FlushInterval)
+	quotaConfig.LimitCacheTTL = utils.GetEnvDuration("QUOTA_LIMIT_CACHE_TTL", quotaConfig.LimitCacheTTL)
+
+	// Without Redis every check fails open; usage is still recorded in ScyllaDB
+	var counter *cache.QuotaCounter
+	if cacheManager != nil {
+		quotaConfig.Prefix = cacheManager.Keys().Prefix(cache.EntityQuota)
+		counter = cacheManager.NewQuotaCounter(quotaConfig.Prefix)
+	} else {
+		logger.Warn("Quotas enabled without cache, requests will be counted but not limited")
+	}
+
+	manager := quota.NewManager(quota.NewRepository(database.Session), counter, quotaConfig, logger)
+	lc.Append(fx.StartStopHook(manager.Start, manager.Stop))
+	return manager
+}
+
+type quotaRouteParams struct {
+	fx.In
+
+	Config       *Config
+	AdminRouter  *gin.Engine `name:"admin"`
+	QuotaHandler *handlers.QuotaHandler
+}
+
+func registerQuotaRoutes(p quotaRouteParams) {
+	server.SetupQuotaRoutes(p.AdminRouter, p.QuotaHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/authz"
+	"acid/internal/budget"
+	"acid/internal/cache"
+	"acid/internal/deprecation"
+	"acid/internal/jobs"
+	loggerUtils "acid/internal/logger"
+	"acid/internal/mailer"
+	"acid/internal/middleware"
+	"acid/internal/outbox"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"acid/internal/scheduler"
+	"acid/internal/serviceaccount"
+	"acid/internal/services"
+	"acid/internal/slo"
+	"acid/internal/tenant"
+	"acid/internal/utils"
+	"acid/internal/watchdog"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// scheduledJobsGroup collects the recurring jobs contributed by every module
+const scheduledJobsGroup = `group:"scheduled_jobs"`
+
+// SchedulerModule runs recurring maintenance jobs; single-instance jobs are guarded by a Redis lock
+var SchedulerModule = fx.Module("scheduler",
+	fx.Provide(
+		newScheduler,
+		fx.Annotate(newMetricsSnapshotJob, fx.ResultTags(scheduledJobsGroup)),
+	),
+	fx.Invoke(runScheduler),
+)
+
+type schedulerParams struct {
+	fx.In
+
+	Cache  *cache.CacheManager
+	Jobs   []scheduler.Job `group:"scheduled_jobs"`
+	Logger *zap.Logger
+}
+
+func newScheduler(p schedulerParams) (*scheduler.Scheduler, error) {
+	var schedulerLock scheduler.Locker
+	if p.Cache != nil {
+		schedulerLock = p.Cache
+	}
+	jobScheduler := scheduler.New(schedulerLock, p.Logger)
+	for _, job := range p.Jobs {
+		if err := jobScheduler.Register(job); err != nil {
+			return nil, fmt.Errorf("failed to register scheduled job %s: %w", job.Name, err)
+		}
+	}
+	return jobScheduler, nil
+}
+
+func runScheduler(lc fx.Lifecycle, jobScheduler *scheduler.Scheduler) {
+	if utils.GetEnvBool("SCHEDULER_ENABLED", true) {
+		lc.Append(fx.StartStopHook(jobScheduler.Start, jobScheduler.Stop))
+	}
+}
+
+type metricsSnapshotParams struct {
+	fx.In
+
+	Relay        *outbox.Relay
+	JobQueue     *jobs.Queue
+	Budgets      *budget.Budgets
+	Mailer       *mailer.Mailer
+	OTP          *services.OTPService
+	OAuth        *services.OAuthService
+	Accounts     *serviceaccount.Manager
+	Policy       *authz.Policy
+	Tenants      *tenant.Manager
+	Retryer      *repository.Retryer
+	Guardrails   *repository.Guardrails
+	Topology     *db.Topology
+	Database     *db.ScyllaDB
+	Downgrade    *repository.DowngradingRetryPolicy
+	Cache        *cache.CacheManager
+	SLO          *slo.Tracker
+	Deprecations *deprecation.Registry
+	Watchdog     *watchdog.Watchdog
+	Logger       *zap.Logger
+}
+
+// newMetricsSnapshotJob logs a periodic metrics snapshot for trend analysis
+func newMetricsSnapshotJob(p metricsSnapshotParams) scheduler.Job {
+	return scheduler.Job{
+		Name:    "metrics_snapshot",
+		Spec:    utils.GetEnv("METRICS_SNAPSHOT_SCHEDULE", "@every 5m"),
+		Timeout: 30 * time.Second,
+		Jitter:  10 * time.Second,
+		Task: func(ctx context.Context) error {
+			fields := []zap.Field{
+				zap.Any("outbox", p.Relay.GetMetrics()),
+				zap.Any("jobs", p.JobQueue.GetMetrics()),
+				zap.Any("db_retries", p.Retryer.GetMetrics()),
+				zap.Any("db_guardrails", p.Guardrails.GetMetrics()),
+				zap.Any("db_topology", p.Topology.GetMetrics()),
+				zap.Any("db_pool", p.Database.PoolMetrics()),
+				zap.Any("db_standby", p.Database.StandbyMetrics()),
+				zap.Any("db_hosts", p.Database.HostMetrics()),
+				zap.Int64("api_v1_deprecated_calls", middleware.DeprecatedCalls()),
+				zap.Any("deprecations", p.Deprecations.GetMetrics()),
+				zap.Any("goroutines", safego.GetMetrics()),
+				zap.Any("slo", p.SLO.Objectives()),
+				zap.Any("latency_budgets", p.Budgets.GetMetrics()),
+				zap.Any("mailer", p.Mailer.GetMetrics()),
+				zap.Any("login_codes", p.OTP.GetMetrics()),
+				zap.Any("oauth", p.OAuth.GetMetrics()),
+				zap.Any("service_accounts", p.Accounts.GetMetrics()),
+				zap.Any("authz", p.Policy.GetMetrics()),
+				zap.Any("tenant_limits", p.Tenants.GetMetrics()),
+			}
+			if p.Downgrade != nil {
+				fields = append(fields, zap.Any("db_read_downgrades", p.Downgrade.GetMetrics()))
+			}
+			if p.Cache != nil {
+				fields = append(fields, zap.Any("cache", p.Cache.GetMetrics()))
+			}
+			if p.Watchdog != nil {
+				fields = append(fields, zap.Any("runtime", p.Watchdog.GetMetrics()))
+			}
+			if asyncLog := loggerUtils.AsyncMetrics(); asyncLog != nil {
+				fields = append(fields, zap.Any("async_log", asyncLog))
+			}
+			p.Logger.Info("Metrics snapshot", fields...)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/serviceaccount"
+	"acid/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ServiceAccountModule provides machine identities for internal jobs: the token endpoint, the
+// verifier the HTTP middleware and gRPC interceptors authenticate with, and the admin API
+var ServiceAccountModule = fx.Module("serviceaccount",
+	fx.Provide(
+		newServiceAccountManager,
+		handlers.NewServiceAccountHandler,
+	),
+	fx.Invoke(registerServiceAccountRoutes),
+)
+
+// newServiceAccountManager reads the token signing key from SERVICE_TOKEN_SIGNING_KEY; without
+// one, accounts can still be managed but no token is issued or accepted
+func newServiceAccountManager(database *db.ScyllaDB, logger *zap.Logger) *serviceaccount.Manager {
+	accountsConfig := serviceaccount.DefaultConfig()
+	accountsConfig.SigningKey = []byte(utils.GetEnv("SERVICE_TOKEN_SIGNING_KEY", ""))
+	accountsConfig.Issuer = utils.GetEnv("SERVICE_TOKEN_ISSUER", accountsConfig.Issuer)
+	accountsConfig.TokenTTL = utils.GetEnvDuration("SERVICE_TOKEN_TTL", accountsConfig.TokenTTL)
+	accountsConfig.Audience = utils.GetEnv("SERVICE_TOKEN_AUDIENCE", accountsConfig.Audience)
+	accountsConfig.AssertionMaxAge = utils.GetEnvDuration("SERVICE_ASSERTION_MAX_AGE", accountsConfig.AssertionMaxAge)
+	accountsConfig.CacheTTL = utils.GetEnvDuration("SERVICE_ACCOUNT_CACHE_TTL", accountsConfig.CacheTTL)
+	if len(accountsConfig.SigningKey) == 0 {
+		logger.Info("SERVICE_TOKEN_SIGNING_KEY not set, service account tokens are disabled")
+	} else if len(accountsConfig.SigningKey) < 32 {
+		logger.Warn("SERVICE_TOKEN_SIGNING_KEY is shorter than 32 bytes")
+	}
+	return serviceaccount.NewManager(serviceaccount.NewRepository(database.Session), accountsConfig, logger)
+}
+
+type serviceAccountRouteParams struct {
+	fx.In
+
+	Config                *Config
+	Router                *gin.Engine
+	AdminRouter           *gin.Engine `name:"admin"`
+	ServiceAccountHandler *handlers.ServiceAccountHandler
+}
+
+func registerServiceAccountRoutes(p serviceAccountRouteParams) {
+	server.SetupServiceAccountRoutes(p.Router, p.AdminRouter, p.ServiceAccountHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/budget"
+	"acid/internal/cache"
+	"acid/internal/health"
+	"acid/internal/ids"
+	"acid/internal/jobs"
+	"acid/internal/mailer"
+	"acid/internal/outbox"
+	"acid/internal/repository"
+	"acid/internal/saga"
+	"acid/internal/services"
+	"acid/internal/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ServicesModule provides the business logic shared by the HTTP, GraphQL and gRPC APIs
+var ServicesModule = fx.Module("services",
+	fx.Provide(
+		newMailer,
+		newUnsubscribeLinks,
+		newNotificationService,
+		newIDGenerator,
+		newSagaRunner,
+		newLatencyBudgets,
+		newUserService,
+	),
+)
+
+// newMailer picks the email provider from MAILER: log (default), smtp, ses or dryrun. dryrun keeps
+// the last messages in memory instead of sending them
+func newMailer(logger *zap.Logger) (*mailer.Mailer, error) {
+	provider := utils.GetEnv("MAILER", "log")
+	var sender mailer.Sender
+	switch provider {
+	case "log":
+		sender = mailer.NewLogSender(logger)
+	case "smtp":
+		smtpConfig := mailer.DefaultSMTPConfig()
+		smtpConfig.Host = utils.GetEnv("SMTP_HOST", smtpConfig.Host)
+		smtpConfig.Port = utils.GetEnvInt("SMTP_PORT", smtpConfig.Port)
+		smtpConfig.Username = utils.GetEnv("SMTP_USERNAME", "")
+		smtpConfig.Password = utils.GetEnv("SMTP_PASSWORD", "")
+		smtpConfig.Timeout = utils.GetEnvDuration("SMTP_TIMEOUT", smtpConfig.Timeout)
+		sender = mailer.NewSMTPSender(smtpConfig)
+	case "ses":
+		sesSender, err := mailer.NewSESSender(context.Background(), &mailer.SESConfig{
+			Region:           utils.GetEnv("SES_REGION", ""),
+			ConfigurationSet: utils.GetEnv("SES_CONFIGURATION_SET", ""),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SES mailer: %w", err)
+		}
+		sender = sesSender
+	case "dryrun":
+		sender = mailer.NewDryRunSender(utils.GetEnvInt("MAILER_DRYRUN_KEEP", 100))
+	default:
+		return nil, fmt.Errorf("MAILER: unknown provider %q, want log, smtp, ses or dryrun", provider)
+	}
+
+	mailerConfig := mailer.DefaultConfig()
+	mailerConfig.From = utils.GetEnv("MAIL_FROM", mailerConfig.From)
+	mailerConfig.DefaultLocale = utils.GetEnv("MAIL_DEFAULT_LOCALE", mailerConfig.DefaultLocale)
+	emailMailer, err := mailer.New(sender, mailerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	logger.Info("Mailer configured", zap.String("provider", provider), zap.String("from", mailerConfig.From))
+	return emailMailer, nil
+}
+
+// newUnsubscribeLinks signs the unsubscribe links of emails with UNSUBSCRIBE_SIGNING_KEY, pointing
+// them at UNSUBSCRIBE_URL ({id} is the user ID). Without a key emails carry no link, and only
+// callers granted users:write can unsubscribe users
+func newUnsubscribeLinks(logger *zap.Logger) *services.UnsubscribeLinks {
+	key := []byte(utils.GetEnv("UNSUBSCRIBE_SIGNING_KEY", ""))
+	if len(key) == 0 {
+		logger.Info("UNSUBSCRIBE_SIGNING_KEY not set, emails carry no unsubscribe link")
+	} else if len(key) < 32 {
+		logger.Warn("UNSUBSCRIBE_SIGNING_KEY is shorter than 32 bytes")
+	}
+	return services.NewUnsubscribeLinks(key, utils.GetEnv("UNSUBSCRIBE_URL", "http://localhost:8000/api/v1/users/{id}/unsubscribe"))
+}
+
+func newNotificationService(repo *repository.NotificationRepository, users *repository.UserRepository, emailMailer *mailer.Mailer, queue *jobs.Queue, links *services.UnsubscribeLinks, logger *zap.Logger) *services.NotificationService {
+	notifications := services.NewNotificationService(repo, users, emailMailer, queue, logger)
+	notifications.SetUnsubscribeLinks(links)
+	return notifications
+}
+
+// newIDGenerator picks how new users' IDs are made from ID_STRATEGY: uuidv7 (default), uuidv4,
+// timeuuid or snowflake. Snowflake also needs ID_NODE (0-1023), unique per instance
+func newIDGenerator(logger *zap.Logger) (ids.Generator, error) {
+	strategy := ids.Strategy(utils.GetEnv("ID_STRATEGY", string(ids.UUIDv7)))
+	generator, err := ids.New(strategy, utils.GetEnvInt("ID_NODE", -1))
+	if err != nil {
+		return nil, fmt.Errorf("ID_STRATEGY: %w", err)
+	}
+	logger.Info("User ID strategy", zap.String("strategy", string(generator.Strategy())))
+	return generator, nil
+}
+
+// newSagaRunner records multi-step writes such as user creation in the sagas table
+func newSagaRunner(database *db.ScyllaDB, logger *zap.Logger) *saga.Runner {
+	return saga.NewRunner(saga.NewRepository(database.Session), &saga.Config{
+		StuckAfter: utils.GetEnvDuration("SAGA_STUCK_AFTER", 1*time.Minute),
+	}, logger)
+}
+
+// newLatencyBudgets reads the budget of each operation from BUDGET_<OPERATION>, e.g.
+// BUDGET_CREATE_USER="total=800ms,cache=20ms,db=500ms,events=100ms". An empty value leaves the
+// operation unbounded
+func newLatencyBudgets(logger *zap.Logger) (*budget.Budgets, error) {
+	defaults := map[string]string{
+		services.BudgetCreateUser: "total=800ms,cache=20ms,db=500ms,events=100ms",
+		services.BudgetFetchUser:  "total=500ms,db=400ms",
+	}
+	budgets := make(map[string]budget.Budget, len(services.BudgetStages))
+	for operation, stages := range services.BudgetStages {
+		key := "BUDGET_" + strings.ToUpper(operation)
+		parsed, err := budget.Parse(utils.GetEnv(key, defaults[operation]), stages...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		budgets[operation] = parsed
+		logger.Info("Latency budget", zap.String("operation", operation), zap.Stringer("budget", parsed))
+	}
+	return budget.New(budgets), nil
+}
+
+type userServiceParams struct {
+	fx.In
+
+	Config        *Config
+	Repo          *repository.UserRepository
+	Cache         *cache.CacheManager
+	Outbox        *outbox.Repository
+	Notifications *services.NotificationService
+	DBMonitor     *health.Monitor
+	Database      *db.ScyllaDB
+	IDs           ids.Generator
+	Sagas         *saga.Runner
+	Budgets       *budget.Budgets
+	Unsubscribe   *services.UnsubscribeLinks
+	Logger        *zap.Logger
+}
+
+func newUserService(p userServiceParams) *services.UserService {
+	userService := services.NewUserService(p.Repo, p.Logger, p.Cache, p.Outbox, p.Notifications)
+	userService.SetReadOnly(p.Config.ReadOnly)
+	userService.SetDegradedCheck(func() bool {
+		// Reads failed over to the standby cluster still reach a database
+		return p.DBMonitor.Degraded() && !p.Database.OnStandby()
+	})
+	userService.SetIDGenerator(p.IDs)
+	userService.SetSagaRunner(p.Sagas)
+	userService.SetBudgets(p.Budgets)
+	userService.SetUnsubscribeLinks(p.Unsubscribe)
+	return userService
+}
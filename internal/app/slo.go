@@ -0,0 +1,47 @@
+package app
+
+import (
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/slo"
+	"acid/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+)
+
+// SLOModule provides the latency/availability tracker fed by the HTTP middleware and the gRPC
+// interceptor, and its admin API
+var SLOModule = fx.Module("slo",
+	fx.Provide(
+		newSLOTracker,
+		handlers.NewSLOHandler,
+	),
+	fx.Invoke(registerSLORoutes),
+)
+
+func newSLOTracker() *slo.Tracker {
+	sloConfig := slo.DefaultConfig()
+	sloConfig.Window = utils.GetEnvDuration("SLO_WINDOW", sloConfig.Window)
+	for i := range sloConfig.Objectives {
+		objective := &sloConfig.Objectives[i]
+		// SLO_CREATE_USER_LATENCY, SLO_FETCH_USER_LATENCY
+		objective.LatencyThreshold = utils.GetEnvDuration("SLO_"+strings.ToUpper(objective.Name)+"_LATENCY", objective.LatencyThreshold)
+		objective.LatencyTarget = utils.GetEnvFloat("SLO_LATENCY_TARGET", objective.LatencyTarget)
+		objective.AvailabilityTarget = utils.GetEnvFloat("SLO_AVAILABILITY_TARGET", objective.AvailabilityTarget)
+	}
+	return slo.NewTracker(sloConfig)
+}
+
+type sloRouteParams struct {
+	fx.In
+
+	Config      *Config
+	AdminRouter *gin.Engine `name:"admin"`
+	SLOHandler  *handlers.SLOHandler
+}
+
+func registerSLORoutes(p sloRouteParams) {
+	server.SetupSLORoutes(p.AdminRouter, p.SLOHandler, p.Config.AdminToken)
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"acid/db"
+	"acid/internal/cache"
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/tenant"
+	"acid/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// TenantModule provides the per-tenant rate limits enforced by the public router, and their admin
+// API. Tenant quotas are part of QuotaModule
+var TenantModule = fx.Module("tenant",
+	fx.Provide(
+		newTenantManager,
+		handlers.NewTenantHandler,
+	),
+	fx.Invoke(registerTenantRoutes),
+)
+
+// newTenantManager reads the default tenant rate limit; with TENANT_RATE_LIMIT_REQUESTS unset
+// only tenants given an override are limited
+func newTenantManager(database *db.ScyllaDB, cacheManager *cache.CacheManager, logger *zap.Logger) *tenant.Manager {
+	tenantConfig := tenant.DefaultConfig()
+	tenantConfig.DefaultRateLimit.Requests = int64(utils.GetEnvInt("TENANT_RATE_LIMIT_REQUESTS", 0))
+	tenantConfig.DefaultRateLimit.Window = utils.GetEnvDuration("TENANT_RATE_LIMIT_WINDOW", tenantConfig.DefaultRateLimit.Window)
+	tenantConfig.CacheTTL = utils.GetEnvDuration("TENANT_LIMIT_CACHE_TTL", tenantConfig.CacheTTL)
+	return tenant.NewManager(tenant.NewRepository(database.Session), cacheManager, tenantConfig, logger)
+}
+
+type tenantRouteParams struct {
+	fx.In
+
+	Config        *Config
+	AdminRouter   *gin.Engine `name:"admin"`
+	TenantHandler *handlers.TenantHandler
+}
+
+func registerTenantRoutes(p tenantRouteParams) {
+	server.SetupTenantRoutes(p.AdminRouter, p.TenantHandler, p.Config.AdminToken)
+}
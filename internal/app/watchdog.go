@@ -0,0 +1,55 @@
+package app
+
+import (
+	"acid/internal/handlers"
+	"acid/internal/server"
+	"acid/internal/utils"
+	"acid/internal/watchdog"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// WatchdogModule provides the runtime watchdog sampling goroutines, open file descriptors and heap,
+// and its admin API. With WATCHDOG_ENABLED=false the watchdog is nil
+var WatchdogModule = fx.Module("watchdog",
+	fx.Provide(
+		newWatchdog,
+		handlers.NewRuntimeHandler,
+	),
+	fx.Invoke(registerRuntimeRoutes),
+)
+
+func newWatchdog(lc fx.Lifecycle, logger *zap.Logger) *watchdog.Watchdog {
+	if !utils.GetEnvBool("WATCHDOG_ENABLED", true) {
+		return nil
+	}
+
+	watchdogConfig := watchdog.DefaultConfig()
+	watchdogConfig.Interval = utils.GetEnvDuration("WATCHDOG_INTERVAL", watchdogConfig.Interval)
+	watchdogConfig.MaxGoroutines = utils.GetEnvInt("WATCHDOG_MAX_GOROUTINES", watchdogConfig.MaxGoroutines)
+	watchdogConfig.GrowthWindow = utils.GetEnvInt("WATCHDOG_GROWTH_WINDOW", watchdogConfig.GrowthWindow)
+	watchdogConfig.GrowthRatio = utils.GetEnvFloat("WATCHDOG_GROWTH_RATIO", watchdogConfig.GrowthRatio)
+	watchdogConfig.GrowthMin = utils.GetEnvInt("WATCHDOG_GROWTH_MIN", watchdogConfig.GrowthMin)
+	watchdogConfig.MaxOpenFDs = utils.GetEnvInt("WATCHDOG_MAX_OPEN_FDS", 0)
+	watchdogConfig.FDRatio = utils.GetEnvFloat("WATCHDOG_FD_RATIO", watchdogConfig.FDRatio)
+	watchdogConfig.MaxHeapBytes = uint64(utils.GetEnvInt("WATCHDOG_MAX_HEAP_MB", 0)) << 20
+	watchdogConfig.StackGroups = utils.GetEnvInt("WATCHDOG_STACK_GROUPS", watchdogConfig.StackGroups)
+
+	w := watchdog.NewWatchdog(watchdogConfig, logger)
+	lc.Append(fx.StartStopHook(w.Start, w.Stop))
+	return w
+}
+
+type runtimeRouteParams struct {
+	fx.In
+
+	Config         *Config
+	AdminRouter    *gin.Engine `name:"admin"`
+	RuntimeHandler *handlers.RuntimeHandler
+}
+
+func registerRuntimeRoutes(p runtimeRouteParams) {
+	server.SetupRuntimeRoutes(p.AdminRouter, p.RuntimeHandler, p.Config.AdminToken)
+}
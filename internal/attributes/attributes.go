@@ -0,0 +1,245 @@
+// Package attributes validates user attributes against a JSON Schema that admins manage at
+// runtime. The schema is stored in ScyllaDB and compiled once per instance, so product teams can
+// add fields by publishing a new schema instead of running a migration
+package attributes
+
+import (
+	"acid/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/zap"
+)
+
+// EntityUser is the entity whose attributes the user schema describes
+const EntityUser = "user"
+
+// schemaURL names the compiled schema in error locations; nothing is fetched from it
+const schemaURL = "urn:acid:attributes:user"
+
+// ErrNoSchema is returned by Validate before an admin has stored a schema
+var ErrNoSchema = errors.New("no attribute schema is configured")
+
+// ErrInvalidSchema is returned when a schema doesn't parse or compile
+var ErrInvalidSchema = errors.New("invalid attribute schema")
+
+// Config holds attribute schema configuration
+type Config struct {
+	// CacheTTL is how long the compiled schema is used before the stored version is re-read;
+	// a schema changed on another instance applies here within it
+	CacheTTL time.Duration
+}
+
+// DefaultConfig returns sensible defaults: a 30s schema cache
+func DefaultConfig() *Config {
+	return &Config{
+		CacheTTL: 30 * time.Second,
+	}
+}
+
+// Metrics tracks validations and schema loads
+type Metrics struct {
+	Validated   atomic.Int64
+	Rejected    atomic.Int64
+	SchemaLoads atomic.Int64
+	LoadErrors  atomic.Int64
+}
+
+type compiledSchema struct {
+	stored    *Schema
+	schema    *jsonschema.Schema
+	expiresAt time.Time
+}
+
+// Registry validates attributes against the stored schema, caching its compiled form
+type Registry struct {
+	repo   *Repository
+	config *Config
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	cached *compiledSchema
+
+	metrics Metrics
+}
+
+func NewRegistry(repo *Repository, config *Config, logger *zap.Logger) *Registry {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Registry{
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Validate checks attributes against the user schema. Rejections wrap models.ErrInvalidAttributes;
+// without a schema every attribute is rejected, so nothing is stored that no schema describes
+func (r *Registry) Validate(ctx context.Context, attributes models.Attributes) error {
+	compiled, err := r.current(ctx)
+	if err != nil {
+		return err
+	}
+	if compiled == nil {
+		r.metrics.Rejected.Add(1)
+		return fmt.Errorf("%w: %w", models.ErrInvalidAttributes, ErrNoSchema)
+	}
+
+	instance := make(map[string]any, len(attributes))
+	for name, value := range attributes {
+		decoded, err := jsonschema.UnmarshalJSON(strings.NewReader(value))
+		if err != nil {
+			r.metrics.Rejected.Add(1)
+			return fmt.Errorf("%w: attribute %q is not valid JSON", models.ErrInvalidAttributes, name)
+		}
+		instance[name] = decoded
+	}
+
+	if err := compiled.schema.Validate(instance); err != nil {
+		r.metrics.Rejected.Add(1)
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			return fmt.Errorf("%w: %s", models.ErrInvalidAttributes, describe(validationErr))
+		}
+		return fmt.Errorf("%w: %v", models.ErrInvalidAttributes, err)
+	}
+	r.metrics.Validated.Add(1)
+	return nil
+}
+
+// Schema returns the stored user schema, or nil when none was stored
+func (r *Registry) Schema(ctx context.Context) (*Schema, error) {
+	compiled, err := r.current(ctx)
+	if err != nil || compiled == nil {
+		return nil, err
+	}
+	return compiled.stored, nil
+}
+
+// SetSchema compiles and stores a new user schema based on version previous (0 for the first).
+// Existing attributes are not re-validated; they are checked against it when next written
+func (r *Registry) SetSchema(ctx context.Context, raw string, previous int) (*Schema, error) {
+	compiled, err := compile(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := r.repo.Put(ctx, EntityUser, raw, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cached = &compiledSchema{stored: stored, schema: compiled, expiresAt: time.Now().Add(r.config.CacheTTL)}
+	r.mu.Unlock()
+
+	r.logger.Info("Attribute schema updated", zap.String("entity", EntityUser), zap.Int("version", stored.Version))
+	return stored, nil
+}
+
+// GetMetrics returns current attribute validation metrics
+func (r *Registry) GetMetrics() map[string]int64 {
+	var version int64
+	r.mu.Lock()
+	if r.cached != nil && r.cached.stored != nil {
+		version = int64(r.cached.stored.Version)
+	}
+	r.mu.Unlock()
+
+	return map[string]int64{
+		"validated":      r.metrics.Validated.Load(),
+		"rejected":       r.metrics.Rejected.Load(),
+		"schema_loads":   r.metrics.SchemaLoads.Load(),
+		"load_errors":    r.metrics.LoadErrors.Load(),
+		"schema_version": version,
+	}
+}
+
+// current returns the cached schema, re-reading it once CacheTTL has passed. A schema that no
+// longer compiles (edited outside the API) is an error rather than "no schema", so writes fail
+// instead of going unchecked
+func (r *Registry) current(ctx context.Context) (*compiledSchema, error) {
+	r.mu.Lock()
+	cached := r.cached
+	r.mu.Unlock()
+	if cached != nil && time.Now().Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	r.metrics.SchemaLoads.Add(1)
+	stored, err := r.repo.Get(ctx, EntityUser)
+	if err != nil {
+		r.metrics.LoadErrors.Add(1)
+		return nil, fmt.Errorf("failed to load attribute schema: %w", err)
+	}
+
+	next := &compiledSchema{stored: stored, expiresAt: time.Now().Add(r.config.CacheTTL)}
+	if stored != nil {
+		// An unchanged version needs no recompiling
+		if cached != nil && cached.stored != nil && cached.stored.Version == stored.Version {
+			next.schema = cached.schema
+		} else if next.schema, err = compile(stored.Schema); err != nil {
+			r.metrics.LoadErrors.Add(1)
+			return nil, fmt.Errorf("stored attribute schema version %d: %w", stored.Version, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.cached = next
+	r.mu.Unlock()
+
+	if stored == nil {
+		return nil, nil
+	}
+	return next, nil
+}
+
+// compile parses and compiles a schema. Formats such as "email" are asserted, and $ref may only
+// point inside the schema: remote and file references are not loaded
+func compile(raw string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, fmt.Errorf("%w: schema must be a JSON object", ErrInvalidSchema)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+	compiler.AssertFormat()
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	schema, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return schema, nil
+}
+
+// describe flattens a validation error to "at '/plan': value must be one of ..." lines
+func describe(err *jsonschema.ValidationError) string {
+	var messages []string
+	for _, unit := range err.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		messages = append(messages, fmt.Sprintf("at '%s': %s", location, unit.Error))
+	}
+	if len(messages) == 0 {
+		return err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
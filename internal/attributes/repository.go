@@ -0,0 +1,94 @@
+package attributes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// SchemaTable holds one JSON Schema per entity, versioned so concurrent admin edits can't
+// silently overwrite each other
+var SchemaTable = table.New(table.Metadata{
+	Name:    "attribute_schemas",
+	Columns: []string{"entity", "schema", "version", "updated_at"},
+	PartKey: []string{"entity"},
+	SortKey: []string{},
+})
+
+// SchemaColumnTypes are the CQL types Schema is marshaled to
+var SchemaColumnTypes = map[string]string{
+	"entity":     "text",
+	"schema":     "text",
+	"version":    "int",
+	"updated_at": "timestamp",
+}
+
+// ErrSchemaConflict is returned when the stored schema is not the version the update was based on
+var ErrSchemaConflict = errors.New("attribute schema was changed concurrently")
+
+// Schema is a stored attribute schema
+type Schema struct {
+	Entity    string    `db:"entity" json:"entity"`
+	Schema    string    `db:"schema" json:"schema"`
+	Version   int       `db:"version" json:"version"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Repository stores attribute schemas in ScyllaDB
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Get returns an entity's schema, or nil when none was stored
+func (r *Repository) Get(ctx context.Context, entity string) (*Schema, error) {
+	var schema Schema
+	err := SchemaTable.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{
+		"entity": entity,
+	}).GetRelease(&schema)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Put stores schema as the version after previous (0 when there is none yet), failing with
+// ErrSchemaConflict if another version was stored in the meantime
+func (r *Repository) Put(ctx context.Context, entity, schema string, previous int) (*Schema, error) {
+	row := Schema{Entity: entity, Schema: schema, Version: previous + 1, UpdatedAt: time.Now()}
+
+	var q *gocqlx.Queryx
+	if previous == 0 {
+		stmt, names := qb.Insert(SchemaTable.Name()).Columns(SchemaTable.Metadata().Columns...).Unique().ToCql()
+		q = r.session.ContextQuery(ctx, stmt, names).BindStruct(row)
+	} else {
+		stmt, names := qb.Update(SchemaTable.Name()).
+			Set("schema", "version", "updated_at").
+			Where(qb.Eq("entity")).
+			If(qb.EqNamed("version", "previous")).
+			ToCql()
+		q = r.session.ContextQuery(ctx, stmt, names).BindStructMap(row, map[string]interface{}{
+			"previous": previous,
+		})
+	}
+
+	applied, err := q.ExecCASRelease()
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, ErrSchemaConflict
+	}
+	return &row, nil
+}
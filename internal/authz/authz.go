@@ -0,0 +1,94 @@
+// Package authz grants fine-grained permissions to callers. Service accounts carry permissions as
+// their scopes; API keys carry roles, named sets of permissions. Routes and RPCs are annotated
+// with the permission they require. Role and key lookups are cached through CacheManager so a
+// check costs no database read on the hot path
+package authz
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Permission is a capability, named <resource>:<action>
+type Permission = string
+
+// Permissions a caller can be granted
+const (
+	UsersRead  Permission = "users:read"  // Read users on the public HTTP and gRPC APIs
+	UsersWrite Permission = "users:write" // Create and change users on the public HTTP and gRPC APIs
+	CacheAdmin Permission = "cache:admin" // Inspect cache tiers, switch them and evict users
+	AdminRead  Permission = "admin:read"  // Read-only admin routes, e.g. for support staff
+	Admin      Permission = "admin"       // Everything, like ADMIN_TOKEN
+)
+
+// All lists every permission
+var All = []Permission{UsersRead, UsersWrite, CacheAdmin, AdminRead, Admin}
+
+// Valid reports whether p is a known permission
+func Valid(p Permission) bool {
+	return slices.Contains(All, p)
+}
+
+// Allows reports whether granted includes p; admin includes every permission
+func Allows(granted []Permission, p Permission) bool {
+	return slices.Contains(granted, p) || slices.Contains(granted, Admin)
+}
+
+// Grant is what an authenticated caller may do
+type Grant struct {
+	// Subject identifies the caller in logs, rate limits and quotas: "admin", "service:<id>" or
+	// "key:<id>"
+	Subject     string
+	Permissions []Permission
+
+	// Tenant is the customer the caller acts for, if its credential is bound to one
+	Tenant string
+}
+
+// Allows reports whether the grant includes p
+func (g *Grant) Allows(p Permission) bool {
+	return Allows(g.Permissions, p)
+}
+
+// AdminGrant is the grant of the admin token
+var AdminGrant = &Grant{Subject: "admin", Permissions: []Permission{Admin}}
+
+type grantKey struct{}
+
+// WithGrant returns a context carrying the caller's grant
+func WithGrant(ctx context.Context, grant *Grant) context.Context {
+	return context.WithValue(ctx, grantKey{}, grant)
+}
+
+// FromContext returns the grant of an authenticated caller, if any
+func FromContext(ctx context.Context) (*Grant, bool) {
+	grant, ok := ctx.Value(grantKey{}).(*Grant)
+	return grant, ok
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   = make(map[string]Permission)
+)
+
+// Annotate records the permissions routes require, keyed by "<METHOD> <path template>" (e.g.
+// "GET /admin/users/:id"). Must be called during startup, before routes are served
+func Annotate(annotations map[string]Permission) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	for route, p := range annotations {
+		routes[route] = p
+	}
+}
+
+// RoutePermission returns the permission annotated on a route; routes without an annotation
+// require admin, so a route nobody annotated is never opened up by accident
+func RoutePermission(method, path string) Permission {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	if p, ok := routes[method+" "+path]; ok {
+		return p
+	}
+	return Admin
+}
@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// healthServicePrefix is exempt so load balancer health checks never need a key
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// apiKeyMetadata carries the caller's API key, like the X-API-Key HTTP header
+const apiKeyMetadata = "x-api-key"
+
+// UnaryServerInterceptor authenticates calls carrying a registered API key in x-api-key metadata
+// and requires the permission methodPermissions maps their method to. Calls already authorized
+// by another interceptor, and calls with an unregistered key or none, pass on to
+// AuthenticateUnaryServerInterceptor
+func UnaryServerInterceptor(policy *Policy, methodPermissions map[string]Permission) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := policy.authorizeKeyCall(ctx, info.FullMethod, methodPermissions)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(policy *Policy, methodPermissions map[string]Permission) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := policy.authorizeKeyCall(ss.Context(), info.FullMethod, methodPermissions)
+		if err != nil {
+			return err
+		}
+		return handler(srv, StreamWithContext(ss, ctx))
+	}
+}
+
+// AuthenticateUnaryServerInterceptor runs after the service account and API key interceptors and
+// settles who the caller is. "authorization: Bearer <admin token>" is granted admin, and any other
+// bearer token they didn't accept fails with UNAUTHENTICATED. So does a call no credential
+// authenticated, unless RequireGRPCAuth is off and it is served anonymously. The grant is in the
+// handler's context (FromContext), so the service layer and its logs know the caller
+func AuthenticateUnaryServerInterceptor(policy *Policy, adminToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := policy.authenticateCall(ctx, info.FullMethod, adminToken)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthenticateStreamServerInterceptor is the streaming counterpart of
+// AuthenticateUnaryServerInterceptor
+func AuthenticateStreamServerInterceptor(policy *Policy, adminToken string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := policy.authenticateCall(ss.Context(), info.FullMethod, adminToken)
+		if err != nil {
+			return err
+		}
+		return handler(srv, StreamWithContext(ss, ctx))
+	}
+}
+
+// AuthorizeMethod checks that grant allows the permission methodPermissions maps method to;
+// methods missing from it require admin. It returns a PermissionDenied status otherwise
+func (p *Policy) AuthorizeMethod(grant *Grant, method string, methodPermissions map[string]Permission) error {
+	permission, ok := methodPermissions[method]
+	if !ok {
+		permission = Admin
+	}
+	if !p.Authorize(grant, permission) {
+		return status.Errorf(codes.PermissionDenied, "caller lacks permission %s", permission)
+	}
+	return nil
+}
+
+// StreamWithContext returns ss with its context replaced, for interceptors that add to it
+func StreamWithContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &contextStream{ServerStream: ss, ctx: ctx}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func (p *Policy) authorizeKeyCall(ctx context.Context, method string, methodPermissions map[string]Permission) (context.Context, error) {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return ctx, nil
+	}
+	if _, ok := FromContext(ctx); ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(apiKeyMetadata)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	grant, err := p.AuthenticateKey(ctx, values[0])
+	if err != nil {
+		return ctx, status.Error(codes.Unavailable, "failed to verify API key")
+	}
+	if grant == nil {
+		return ctx, nil
+	}
+	if err := p.AuthorizeMethod(grant, method, methodPermissions); err != nil {
+		return ctx, err
+	}
+	return WithGrant(ctx, grant), nil
+}
+
+func (p *Policy) authenticateCall(ctx context.Context, method, adminToken string) (context.Context, error) {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return ctx, nil
+	}
+	if _, ok := FromContext(ctx); ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ := strings.CutPrefix(values[0], "Bearer ")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			p.metrics.Unauthenticated.Add(1)
+			return ctx, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+		return WithGrant(ctx, AdminGrant), nil
+	}
+	if p.config.RequireGRPCAuth {
+		p.metrics.Unauthenticated.Add(1)
+		return ctx, status.Error(codes.Unauthenticated,
+			"authentication required: send a bearer token in authorization or a registered API key in x-api-key metadata")
+	}
+	p.metrics.Anonymous.Add(1)
+	return ctx, nil
+}
@@ -0,0 +1,361 @@
+package authz
+
+import (
+	"acid/internal/cache"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// keyPrefix marks generated API keys, so they are recognizable in leaked-credential scans
+const keyPrefix = "ak_"
+
+var (
+	// ErrRoleNotFound is returned for a role that doesn't exist
+	ErrRoleNotFound = errors.New("role not found")
+
+	// ErrAPIKeyNotFound is returned for an API key that isn't registered
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrInvalidPermission is returned for a permission that doesn't exist
+	ErrInvalidPermission = errors.New("invalid permission")
+)
+
+// Config holds policy settings
+type Config struct {
+	// CacheTTL is how long roles and API key lookups are cached. Changes made through the Policy
+	// are invalidated on every instance at once; the TTL bounds how long a missed invalidation
+	// (Redis down) is honored
+	CacheTTL time.Duration
+
+	// RequireGRPCAuth rejects gRPC calls made without a service account token, registered API key
+	// or the admin token. Turning it off serves them anonymously, an explicit opt-out for
+	// deployments whose clients can't authenticate yet
+	RequireGRPCAuth bool
+
+	// RequireHTTPWriteAuth rejects HTTP requests needing users:write that no access token,
+	// registered API key or admin token authenticated, so dropping a read-only key doesn't turn
+	// a refused write into an anonymous one. Turning it off serves them anonymously
+	RequireHTTPWriteAuth bool
+}
+
+// DefaultConfig returns sensible defaults: lookups cached for a minute, gRPC calls and HTTP
+// writes required to authenticate
+func DefaultConfig() *Config {
+	return &Config{CacheTTL: time.Minute, RequireGRPCAuth: true, RequireHTTPWriteAuth: true}
+}
+
+// Metrics tracks permission checks and lookups
+type Metrics struct {
+	Allowed     atomic.Int64
+	Denied      atomic.Int64
+	CacheHits   atomic.Int64
+	CacheMisses atomic.Int64
+	KeysMatched atomic.Int64 // Requests made with a registered API key
+
+	// gRPC calls rejected for missing or invalid credentials, and calls served anonymously
+	Unauthenticated atomic.Int64
+	Anonymous       atomic.Int64
+
+	// HTTP writes rejected for missing credentials
+	WritesUnauthenticated atomic.Int64
+}
+
+// cachedKey is the cached lookup of an API key ID; unregistered IDs are cached too, as most keys
+// seen are plain quota keys nobody registered
+type cachedKey struct {
+	Registered bool     `json:"registered"`
+	KeyHash    string   `json:"key_hash,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Tenant     string   `json:"tenant,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+}
+
+// Policy manages roles and API keys and evaluates what a caller may do
+type Policy struct {
+	repo   *Repository
+	cache  *cache.CacheManager
+	config *Config
+	logger *zap.Logger
+
+	metrics Metrics
+}
+
+// NewPolicy creates the policy evaluator; a nil CacheManager reads every lookup from ScyllaDB
+func NewPolicy(repo *Repository, cm *cache.CacheManager, config *Config, logger *zap.Logger) *Policy {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Policy{
+		repo:   repo,
+		cache:  cm,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Authorize reports whether grant allows p, counting the decision. A nil grant allows nothing
+func (p *Policy) Authorize(grant *Grant, permission Permission) bool {
+	if grant == nil || !grant.Allows(permission) {
+		p.metrics.Denied.Add(1)
+		return false
+	}
+	p.metrics.Allowed.Add(1)
+	return true
+}
+
+// AllowAnonymousWrite reports whether an HTTP request no credential authenticated may be served
+// when it needs users:write, counting the rejections
+func (p *Policy) AllowAnonymousWrite() bool {
+	if !p.config.RequireHTTPWriteAuth {
+		return true
+	}
+	p.metrics.WritesUnauthenticated.Add(1)
+	return false
+}
+
+// RolePermissions returns the union of the permissions of roles; roles deleted since they were
+// assigned grant nothing
+func (p *Policy) RolePermissions(ctx context.Context, roles []string) ([]Permission, error) {
+	var permissions []Permission
+	for _, name := range roles {
+		role, err := p.cachedRole(ctx, name)
+		if errors.Is(err, ErrRoleNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, role.Permissions...)
+	}
+	return normalize(permissions), nil
+}
+
+// AuthenticateKey returns the grant of a registered API key, or nil for a key nobody registered,
+// which stays a plain quota key
+func (p *Policy) AuthenticateKey(ctx context.Context, key string) (*Grant, error) {
+	id, hash := keyID(key)
+	var lookup cachedKey
+	if !p.getCached(ctx, cache.EntityAPIKey, id, &lookup) {
+		apiKey, err := p.repo.GetAPIKey(ctx, id)
+		switch {
+		case errors.Is(err, ErrAPIKeyNotFound):
+		case err != nil:
+			return nil, err
+		default:
+			lookup = cachedKey{Registered: true, KeyHash: apiKey.KeyHash, Name: apiKey.Name, Tenant: apiKey.Tenant, Roles: apiKey.Roles}
+		}
+		p.setCached(ctx, cache.EntityAPIKey, id, lookup)
+	}
+	if !lookup.Registered || subtle.ConstantTimeCompare([]byte(hash), []byte(lookup.KeyHash)) != 1 {
+		return nil, nil
+	}
+
+	permissions, err := p.RolePermissions(ctx, lookup.Roles)
+	if err != nil {
+		return nil, err
+	}
+	p.metrics.KeysMatched.Add(1)
+	return &Grant{Subject: "key:" + id, Permissions: permissions, Tenant: lookup.Tenant}, nil
+}
+
+// PutRole creates or replaces a role. API keys holding it get the new permissions on every
+// instance at once
+func (p *Policy) PutRole(ctx context.Context, name, description string, permissions []Permission) (*Role, error) {
+	for _, permission := range permissions {
+		if !Valid(permission) {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrInvalidPermission, permission)
+		}
+	}
+	role := &Role{
+		Name:        name,
+		Description: description,
+		Permissions: normalize(permissions),
+		UpdatedAt:   time.Now(),
+	}
+	if err := p.repo.PutRole(ctx, role); err != nil {
+		return nil, err
+	}
+	p.invalidate(ctx, cache.EntityRole, name)
+	p.logger.Info("Role stored", zap.String("role", name), zap.Strings("permissions", role.Permissions))
+	return role, nil
+}
+
+// GetRole returns a role, failing with ErrRoleNotFound
+func (p *Policy) GetRole(ctx context.Context, name string) (*Role, error) {
+	return p.repo.GetRole(ctx, name)
+}
+
+// ListRoles returns every role
+func (p *Policy) ListRoles(ctx context.Context) ([]Role, error) {
+	return p.repo.ListRoles(ctx)
+}
+
+// DeleteRole removes a role; API keys holding it lose its permissions
+func (p *Policy) DeleteRole(ctx context.Context, name string) error {
+	if _, err := p.repo.GetRole(ctx, name); err != nil {
+		return err
+	}
+	if err := p.repo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	p.invalidate(ctx, cache.EntityRole, name)
+	p.logger.Info("Role deleted", zap.String("role", name))
+	return nil
+}
+
+// CreateAPIKey registers a new API key holding roles and returns it with the key, which is only
+// shown now. Every role must exist. A key bound to a tenant has its requests limited as that
+// tenant's; tenant may be empty
+func (p *Policy) CreateAPIKey(ctx context.Context, name, tenant string, roles []string) (*APIKey, string, error) {
+	for _, role := range roles {
+		if _, err := p.repo.GetRole(ctx, role); err != nil {
+			if errors.Is(err, ErrRoleNotFound) {
+				return nil, "", fmt.Errorf("%w: %q", ErrRoleNotFound, role)
+			}
+			return nil, "", err
+		}
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	id, hash := keyID(key)
+
+	apiKey := &APIKey{
+		ID:        id,
+		KeyHash:   hash,
+		Name:      name,
+		Tenant:    tenant,
+		Roles:     normalize(roles),
+		CreatedAt: time.Now(),
+	}
+	if err := p.repo.InsertAPIKey(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+	p.logger.Info("API key created", zap.String("id", id), zap.String("name", name),
+		zap.String("tenant", tenant), zap.Strings("roles", apiKey.Roles))
+	return apiKey, key, nil
+}
+
+// ListAPIKeys returns every registered API key
+func (p *Policy) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return p.repo.ListAPIKeys(ctx)
+}
+
+// DeleteAPIKey unregisters an API key; it stops granting permissions on every instance at once,
+// and is a plain quota key from then on
+func (p *Policy) DeleteAPIKey(ctx context.Context, id string) error {
+	if _, err := p.repo.GetAPIKey(ctx, id); err != nil {
+		return err
+	}
+	if err := p.repo.DeleteAPIKey(ctx, id); err != nil {
+		return err
+	}
+	p.invalidate(ctx, cache.EntityAPIKey, id)
+	p.logger.Info("API key deleted", zap.String("id", id))
+	return nil
+}
+
+// GetMetrics returns permission check and lookup counts of this instance
+func (p *Policy) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"allowed":      p.metrics.Allowed.Load(),
+		"denied":       p.metrics.Denied.Load(),
+		"cache_hits":   p.metrics.CacheHits.Load(),
+		"cache_misses": p.metrics.CacheMisses.Load(),
+		"keys_matched": p.metrics.KeysMatched.Load(),
+
+		"grpc_unauthenticated": p.metrics.Unauthenticated.Load(),
+		"grpc_anonymous":       p.metrics.Anonymous.Load(),
+
+		"http_writes_unauthenticated": p.metrics.WritesUnauthenticated.Load(),
+	}
+}
+
+func (p *Policy) cachedRole(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	if p.getCached(ctx, cache.EntityRole, name, &role) {
+		if role.Name == "" {
+			return nil, ErrRoleNotFound
+		}
+		return &role, nil
+	}
+	stored, err := p.repo.GetRole(ctx, name)
+	if errors.Is(err, ErrRoleNotFound) {
+		// Cached as an empty role, so a key holding a deleted role doesn't read it on every request
+		p.setCached(ctx, cache.EntityRole, name, Role{})
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.setCached(ctx, cache.EntityRole, name, stored)
+	return stored, nil
+}
+
+func (p *Policy) getCached(ctx context.Context, entity, id string, dest any) bool {
+	if p.cache == nil {
+		return false
+	}
+	if _, err := p.cache.GetJSON(ctx, p.cache.Keys().Key(entity, id), dest); err != nil {
+		p.metrics.CacheMisses.Add(1)
+		return false
+	}
+	p.metrics.CacheHits.Add(1)
+	return true
+}
+
+func (p *Policy) setCached(ctx context.Context, entity, id string, value any) {
+	if p.cache == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := p.cache.SetWithTTL(ctx, p.cache.Keys().Key(entity, id), string(data), p.config.CacheTTL, p.config.CacheTTL); err != nil {
+		p.logger.Debug("Failed to cache authorization lookup", zap.String("entity", entity), zap.Error(err))
+	}
+}
+
+// invalidate drops a cached lookup here and tells other instances to drop their local copies
+func (p *Policy) invalidate(ctx context.Context, entity, id string) {
+	if p.cache == nil {
+		return
+	}
+	key := p.cache.Keys().Key(entity, id)
+	if err := p.cache.Delete(ctx, key); err != nil {
+		p.logger.Warn("Failed to invalidate authorization lookup", zap.String("key", key), zap.Error(err))
+	}
+	if err := p.cache.PublishInvalidation(ctx, key); err != nil {
+		p.logger.Warn("Failed to broadcast authorization invalidation", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// keyID returns the ID of an API key and the hash stored for it. The ID is the hash prefix quotas
+// bill the key under (key:<id>), so a key's permissions and its quota share a name
+func keyID(key string) (string, string) {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	return hash[:16], hash
+}
+
+// normalize sorts and deduplicates permissions or role names
+func normalize(values []string) []string {
+	normalized := slices.Clone(values)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
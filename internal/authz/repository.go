@@ -0,0 +1,155 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// RolesTable holds roles, one row each
+var RolesTable = table.New(table.Metadata{
+	Name:    "roles",
+	Columns: []string{"name", "description", "permissions", "updated_at"},
+	PartKey: []string{"name"},
+	SortKey: []string{},
+})
+
+// APIKeysTable holds registered API keys by ID, the hash prefix quotas bill them under
+var APIKeysTable = table.New(table.Metadata{
+	Name:    "api_keys",
+	Columns: []string{"id", "key_hash", "name", "tenant", "roles", "created_at"},
+	PartKey: []string{"id"},
+	SortKey: []string{},
+})
+
+// RoleColumnTypes are the CQL types Role is marshaled to
+var RoleColumnTypes = map[string]string{
+	"name":        "text",
+	"description": "text",
+	"permissions": "set<text>",
+	"updated_at":  "timestamp",
+}
+
+// APIKeyColumnTypes are the CQL types APIKey is marshaled to
+var APIKeyColumnTypes = map[string]string{
+	"id":         "text",
+	"key_hash":   "text",
+	"name":       "text",
+	"tenant":     "text",
+	"roles":      "set<text>",
+	"created_at": "timestamp",
+}
+
+// maxListed bounds List: roles and keys are a handful, read with a full table scan
+const maxListed = 1000
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string       `db:"name" json:"name"`
+	Description string       `db:"description" json:"description,omitempty"`
+	Permissions []Permission `db:"permissions" json:"permissions"`
+	UpdatedAt   time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+// APIKey is a registered API key. The key itself is never stored: KeyHash verifies it
+type APIKey struct {
+	ID        string    `db:"id" json:"id"`
+	KeyHash   string    `db:"key_hash" json:"-"`
+	Name      string    `db:"name" json:"name"`
+	Tenant    string    `db:"tenant" json:"tenant,omitempty"`
+	Roles     []string  `db:"roles" json:"roles"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Repository stores roles and API keys in ScyllaDB
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// GetRole returns a role, failing with ErrRoleNotFound
+func (r *Repository) GetRole(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	err := RolesTable.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{"name": name}).GetRelease(&role)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role: %w", err)
+	}
+	return &role, nil
+}
+
+// PutRole creates or replaces a role
+func (r *Repository) PutRole(ctx context.Context, role *Role) error {
+	if err := RolesTable.InsertQueryContext(ctx, r.session).BindStruct(role).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to store role: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole removes a role
+func (r *Repository) DeleteRole(ctx context.Context, name string) error {
+	if err := RolesTable.DeleteQueryContext(ctx, r.session).BindMap(map[string]interface{}{"name": name}).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return nil
+}
+
+// ListRoles returns every role, up to maxListed
+func (r *Repository) ListRoles(ctx context.Context) ([]Role, error) {
+	stmt, names := qb.Select(RolesTable.Name()).Limit(maxListed).ToCql()
+	var roles []Role
+	if err := r.session.ContextQuery(ctx, stmt, names).SelectRelease(&roles); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetAPIKey returns a registered API key, failing with ErrAPIKeyNotFound
+func (r *Repository) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	err := APIKeysTable.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{"id": id}).GetRelease(&key)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key: %w", err)
+	}
+	return &key, nil
+}
+
+// InsertAPIKey stores a new API key
+func (r *Repository) InsertAPIKey(ctx context.Context, key *APIKey) error {
+	if err := APIKeysTable.InsertQueryContext(ctx, r.session).BindStruct(key).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey removes an API key
+func (r *Repository) DeleteAPIKey(ctx context.Context, id string) error {
+	if err := APIKeysTable.DeleteQueryContext(ctx, r.session).BindMap(map[string]interface{}{"id": id}).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeys returns every API key, up to maxListed
+func (r *Repository) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	stmt, names := qb.Select(APIKeysTable.Name()).Limit(maxListed).ToCql()
+	var keys []APIKey
+	if err := r.session.ContextQuery(ctx, stmt, names).SelectRelease(&keys); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,264 @@
+package backfill
+
+import (
+	"acid/internal/cache"
+	"acid/internal/models"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"acid/internal/workerpool"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Target is a derived store rebuilt from the users table
+// Apply must be idempotent: after a resume, rows since the last checkpoint are applied again
+type Target interface {
+	Name() string
+	Apply(ctx context.Context, user *models.User) error
+}
+
+// CacheTarget primes the user cache entries read by GetUser
+type CacheTarget struct {
+	cache *cache.CacheManager
+}
+
+func NewCacheTarget(cacheManager *cache.CacheManager) *CacheTarget {
+	return &CacheTarget{cache: cacheManager}
+}
+
+func (t *CacheTarget) Name() string { return "cache" }
+
+func (t *CacheTarget) Apply(ctx context.Context, user *models.User) error {
+	return t.cache.Set(ctx, t.cache.Keys().Key(cache.EntityUser, user.ID.String()), user)
+}
+
+// EmailTarget rebuilds the email uniqueness keys checked by gRPC CreateUser
+type EmailTarget struct {
+	cache *cache.CacheManager
+}
+
+func NewEmailTarget(cacheManager *cache.CacheManager) *EmailTarget {
+	return &EmailTarget{cache: cacheManager}
+}
+
+func (t *EmailTarget) Name() string { return "email" }
+
+func (t *EmailTarget) Apply(ctx context.Context, user *models.User) error {
+	if user.Email == "" {
+		return nil
+	}
+	return t.cache.Set(ctx, t.cache.Keys().Key(cache.EntityEmail, user.Email), user.ID.String())
+}
+
+// LookupTarget rebuilds the users_by_created, users_by_email, users_by_username and users_by_phone
+// rows read by user listings
+type LookupTarget struct {
+	repo *repository.UserRepository
+}
+
+func NewLookupTarget(repo *repository.UserRepository) *LookupTarget {
+	return &LookupTarget{repo: repo}
+}
+
+func (t *LookupTarget) Name() string { return "lookups" }
+
+func (t *LookupTarget) Apply(ctx context.Context, user *models.User) error {
+	return t.repo.IndexUser(ctx, user)
+}
+
+// Config holds backfill configuration
+type Config struct {
+	// Ranges is the number of token ranges the ring is split into; fixed for a checkpoint's lifetime
+	Ranges int
+
+	// Concurrency is the number of ranges scanned in parallel
+	Concurrency int
+
+	// RatePerSecond caps rows applied per second across all workers (0 means unlimited)
+	RatePerSecond float64
+
+	// PageSize is the driver page size for range scans
+	PageSize int
+
+	// CheckpointInterval is how often progress is flushed to the checkpoint file
+	CheckpointInterval time.Duration
+}
+
+// DefaultConfig returns conservative defaults that won't starve live traffic
+func DefaultConfig() *Config {
+	return &Config{
+		Ranges:             256,
+		Concurrency:        4,
+		RatePerSecond:      1000,
+		PageSize:           500,
+		CheckpointInterval: 5 * time.Second,
+	}
+}
+
+// Stats summarises a backfill run
+type Stats struct {
+	Scanned int64
+	Applied map[string]int64
+	Failed  map[string]int64
+}
+
+// Runner scans the users table range by range and feeds each row to every target
+type Runner struct {
+	repo       *repository.UserRepository
+	targets    []Target
+	checkpoint *Checkpoint
+	config     *Config
+	limiter    *rate.Limiter
+	logger     *zap.Logger
+
+	scanned atomic.Int64
+	applied []atomic.Int64 // Indexed like targets
+	failed  []atomic.Int64
+}
+
+func NewRunner(repo *repository.UserRepository, targets []Target, checkpoint *Checkpoint, config *Config, logger *zap.Logger) *Runner {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	limit := rate.Inf
+	if config.RatePerSecond > 0 {
+		limit = rate.Limit(config.RatePerSecond)
+	}
+
+	return &Runner{
+		repo:       repo,
+		targets:    targets,
+		checkpoint: checkpoint,
+		config:     config,
+		limiter:    rate.NewLimiter(limit, max(int(config.RatePerSecond), 1)),
+		logger:     logger,
+		applied:    make([]atomic.Int64, len(targets)),
+		failed:     make([]atomic.Int64, len(targets)),
+	}
+}
+
+// Run processes every unfinished range and returns when all are done, ctx is cancelled or a
+// scan fails. Progress is checkpointed periodically and on return, so Run can simply be
+// called again to resume
+func (r *Runner) Run(ctx context.Context) (*Stats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	remaining := r.checkpoint.remaining()
+	r.logger.Info("Backfill started",
+		zap.Int("ranges", len(r.checkpoint.Ranges)),
+		zap.Int("remaining", remaining),
+		zap.Int64("already_processed", r.checkpoint.Processed))
+
+	// Periodic checkpoint flush
+	flushDone := make(chan struct{})
+	safego.Go("backfill.checkpoint", func() {
+		ticker := time.NewTicker(r.config.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.checkpoint.Save(); err != nil {
+					r.logger.Warn("Failed to save backfill checkpoint", zap.Error(err))
+				}
+				r.logger.Info("Backfill progress",
+					zap.Int64("scanned", r.scanned.Load()),
+					zap.Int("ranges_remaining", r.checkpoint.remaining()))
+			}
+		}
+	}, safego.Restart(ctx.Done()), safego.OnExit(func() { close(flushDone) }))
+
+	pool := workerpool.New(ctx, &workerpool.Config{Workers: r.config.Concurrency, StopOnError: true})
+	for i := range r.checkpoint.Ranges {
+		if pool.Submit(func(ctx context.Context) error {
+			return r.runRange(ctx, i)
+		}) != nil {
+			break
+		}
+	}
+	// A failed range is reported as is; a cancelled run is reported below as interrupted
+	var firstErr error
+	var failures *workerpool.Errors
+	if err := pool.Wait(); errors.As(err, &failures) {
+		firstErr = failures.Errs[0]
+	}
+	cancel()
+	<-flushDone
+
+	if err := r.checkpoint.Save(); err != nil {
+		r.logger.Warn("Failed to save backfill checkpoint", zap.Error(err))
+	}
+
+	stats := r.stats()
+	if firstErr == nil && r.checkpoint.remaining() > 0 {
+		firstErr = fmt.Errorf("backfill interrupted with %d ranges remaining: %w", r.checkpoint.remaining(), context.Canceled)
+	}
+	if firstErr != nil {
+		return stats, firstErr
+	}
+
+	r.logger.Info("Backfill finished",
+		zap.Int64("scanned", stats.Scanned),
+		zap.Any("applied", stats.Applied),
+		zap.Any("failed", stats.Failed))
+	return stats, nil
+}
+
+// runRange scans one range from its resume point and marks it done on success
+func (r *Runner) runRange(ctx context.Context, i int) error {
+	rng, ok := r.checkpoint.resumeRange(i)
+	if !ok {
+		return nil
+	}
+
+	err := r.repo.ScanUsers(ctx, rng, r.config.PageSize, func(token int64, user *models.User) error {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		r.scanned.Add(1)
+		for t, target := range r.targets {
+			// A failing row is counted and skipped; one bad record must not stall the whole job
+			if err := target.Apply(ctx, user); err != nil {
+				r.failed[t].Add(1)
+				r.logger.Warn("Backfill target failed",
+					zap.String("target", target.Name()),
+					zap.String("user_id", user.ID.String()),
+					zap.Error(err))
+				continue
+			}
+			r.applied[t].Add(1)
+		}
+
+		r.checkpoint.advance(i, token, 1)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.checkpoint.complete(i)
+	return nil
+}
+
+func (r *Runner) stats() *Stats {
+	stats := &Stats{
+		Scanned: r.scanned.Load(),
+		Applied: make(map[string]int64, len(r.targets)),
+		Failed:  make(map[string]int64, len(r.targets)),
+	}
+	for t, target := range r.targets {
+		stats.Applied[target.Name()] = r.applied[t].Load()
+		stats.Failed[target.Name()] = r.failed[t].Load()
+	}
+	return stats
+}
@@ -0,0 +1,129 @@
+package backfill
+
+import (
+	"acid/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rangeProgress is the resume point of one token range
+type rangeProgress struct {
+	repository.TokenRange
+	LastToken *int64 `json:"last_token,omitempty"` // Last token fully applied; nil when not started
+	Done      bool   `json:"done"`
+}
+
+// Checkpoint records per-range progress in a JSON file so an interrupted backfill resumes
+// where it stopped instead of rescanning the whole table
+type Checkpoint struct {
+	path string
+
+	mu        sync.Mutex
+	Ranges    []rangeProgress `json:"ranges"`
+	Processed int64           `json:"processed"`
+}
+
+// LoadCheckpoint reads the checkpoint at path, or starts a fresh one split into n ranges
+// An existing checkpoint with a different range count is rejected: its tokens wouldn't line up
+func LoadCheckpoint(path string, n int) (*Checkpoint, error) {
+	cp := &Checkpoint{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || path == "" {
+		for _, rng := range repository.SplitTokenRing(n) {
+			cp.Ranges = append(cp.Ranges, rangeProgress{TokenRange: rng})
+		}
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if len(cp.Ranges) != n {
+		return nil, fmt.Errorf("checkpoint %s has %d ranges, expected %d (delete it to start over)", path, len(cp.Ranges), n)
+	}
+	return cp, nil
+}
+
+// resumeRange returns the range still to scan for index i and whether there is anything left
+func (cp *Checkpoint) resumeRange(i int) (repository.TokenRange, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	progress := cp.Ranges[i]
+	if progress.Done {
+		return repository.TokenRange{}, false
+	}
+
+	rng := progress.TokenRange
+	if progress.LastToken != nil {
+		rng.Start = *progress.LastToken
+	}
+	return rng, true
+}
+
+// advance records that every row up to token in range i has been applied
+func (cp *Checkpoint) advance(i int, token int64, rows int64) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.Ranges[i].LastToken = &token
+	cp.Processed += rows
+}
+
+func (cp *Checkpoint) complete(i int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.Ranges[i].Done = true
+}
+
+// remaining returns the number of ranges not finished yet
+func (cp *Checkpoint) remaining() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	n := 0
+	for _, progress := range cp.Ranges {
+		if !progress.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// Save writes the checkpoint atomically (temp file + rename); no-op without a path
+func (cp *Checkpoint) Save() error {
+	if cp.path == "" {
+		return nil
+	}
+
+	cp.mu.Lock()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	cp.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cp.path), filepath.Base(cp.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), cp.path)
+}
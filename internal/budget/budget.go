@@ -0,0 +1,205 @@
+// Package budget splits the latency an operation may take between its stages. The service layer
+// starts a run per operation and gives each stage a context whose deadline is the stage's share,
+// capped by what is left of the total, instead of every layer picking a timeout of its own
+package budget
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Budget is the latency allowed to one operation. A zero Total or an absent stage is unbounded
+type Budget struct {
+	Total  time.Duration
+	Stages map[string]time.Duration
+}
+
+// Parse reads a budget written as "total=800ms,cache=20ms,db=500ms,events=100ms", where every
+// name other than total must be one of stages. An empty string is no budget
+func Parse(raw string, stages ...string) (Budget, error) {
+	budget := Budget{Stages: make(map[string]time.Duration)}
+	for _, entry := range strings.Split(raw, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return Budget{}, fmt.Errorf("%q is not stage=duration", entry)
+		}
+		limit, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return Budget{}, fmt.Errorf("invalid budget of %s: %w", name, err)
+		}
+		if limit <= 0 {
+			return Budget{}, fmt.Errorf("budget of %s must be positive", name)
+		}
+		switch {
+		case name == "total":
+			budget.Total = limit
+		case slices.Contains(stages, name):
+			budget.Stages[name] = limit
+		default:
+			return Budget{}, fmt.Errorf("unknown stage %s, want total or one of %s", name, strings.Join(stages, ", "))
+		}
+	}
+	for name, limit := range budget.Stages {
+		if budget.Total > 0 && limit > budget.Total {
+			return Budget{}, fmt.Errorf("budget of %s (%s) exceeds the total (%s)", name, limit, budget.Total)
+		}
+	}
+	return budget, nil
+}
+
+// operation holds the budget of one operation and its overrun counts
+type operation struct {
+	budget   Budget
+	runs     atomic.Int64
+	overruns atomic.Int64
+	stages   map[string]*atomic.Int64 // Overruns per stage; keys are fixed at construction
+}
+
+// Budgets holds the budget of every operation. A nil *Budgets, or an operation without a budget,
+// runs unbounded
+type Budgets struct {
+	operations map[string]*operation
+}
+
+// New creates budgets for the given operations, keyed by operation name
+func New(budgets map[string]Budget) *Budgets {
+	b := &Budgets{operations: make(map[string]*operation, len(budgets))}
+	for name, budget := range budgets {
+		op := &operation{budget: budget, stages: make(map[string]*atomic.Int64, len(budget.Stages))}
+		for stage := range budget.Stages {
+			op.stages[stage] = &atomic.Int64{}
+		}
+		b.operations[name] = op
+	}
+	return b
+}
+
+// Run is one execution of an operation. A nil *Run is valid and bounds nothing
+type Run struct {
+	op      *operation
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+// Start begins a run of the named operation, returning a context bounded by its total. End must
+// be called when the operation returns
+func (b *Budgets) Start(ctx context.Context, name string) (context.Context, *Run) {
+	if b == nil {
+		return ctx, nil
+	}
+	op, ok := b.operations[name]
+	if !ok {
+		return ctx, nil
+	}
+	op.runs.Add(1)
+	run := &Run{op: op, started: time.Now()}
+	ctx, run.cancel = withLimit(ctx, op.budget.Total)
+	return ctx, run
+}
+
+// Stage bounds one stage of the run by its share of the budget; the total's deadline still
+// applies when sooner. done must be called when the stage returns; a stage that took longer than
+// its share is counted as an overrun, including one that ignored its context
+func (r *Run) Stage(ctx context.Context, stage string) (context.Context, func()) {
+	if r == nil {
+		return ctx, func() {}
+	}
+	overruns, ok := r.op.stages[stage]
+	if !ok {
+		return ctx, func() {}
+	}
+	limit := r.op.budget.Stages[stage]
+	started := time.Now()
+	stageCtx, cancel := withLimit(ctx, limit)
+	return stageCtx, func() {
+		cancel()
+		if time.Since(started) > limit {
+			overruns.Add(1)
+		}
+	}
+}
+
+// End releases the run's context and counts it as an overrun when it took longer than the total
+func (r *Run) End() {
+	if r == nil {
+		return
+	}
+	r.cancel()
+	if r.op.budget.Total > 0 && time.Since(r.started) > r.op.budget.Total {
+		r.op.overruns.Add(1)
+	}
+}
+
+// deadlineKey carries the deadline of the innermost budget in a context
+type deadlineKey struct{}
+
+// Deadline returns the budget deadline ctx carries
+func Deadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(deadlineKey{}).(time.Time)
+	return deadline, ok
+}
+
+// Detach returns ctx without the caller's cancellation but bounded by its budget deadline, if
+// any. Writes use it so a client hanging up can't abandon them while they stay within budget
+func Detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := Deadline(ctx)
+	if !ok {
+		return context.WithoutCancel(ctx), func() {}
+	}
+	return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+}
+
+// withLimit bounds ctx by limit and records the resulting deadline for Deadline
+func withLimit(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		return context.WithCancel(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	deadline, _ := ctx.Deadline()
+	return context.WithValue(ctx, deadlineKey{}, deadline), cancel
+}
+
+// GetMetrics returns per operation the number of runs and overruns, in total and per stage
+func (b *Budgets) GetMetrics() map[string]map[string]int64 {
+	metrics := make(map[string]map[string]int64)
+	if b == nil {
+		return metrics
+	}
+	for name, op := range b.operations {
+		counts := map[string]int64{
+			"runs":     op.runs.Load(),
+			"overruns": op.overruns.Load(),
+		}
+		for stage, overruns := range op.stages {
+			counts[stage+"_overruns"] = overruns.Load()
+		}
+		metrics[name] = counts
+	}
+	return metrics
+}
+
+// String describes the budget, e.g. "total=800ms cache=20ms db=500ms", for startup logs
+func (b Budget) String() string {
+	parts := make([]string, 0, len(b.Stages)+1)
+	if b.Total > 0 {
+		parts = append(parts, "total="+b.Total.String())
+	}
+	stages := make([]string, 0, len(b.Stages))
+	for stage := range b.Stages {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		parts = append(parts, stage+"="+b.Stages[stage].String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,20 +1,40 @@
 package cache
 
 import (
+	"acid/internal/debugtrace"
+	"acid/internal/jsoncodec"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 // CacheManager orchestrates multi-tier caching with intelligent fallback
 // Architecture: L1 (Local BigCache) → L2 (Redis) → L3 (Database/Source)
 type CacheManager struct {
-	local  *LocalCache
-	redis  *RedisClient
-	config *CacheManagerConfig
+	local       *LocalCache
+	redis       *RedisClient
+	config      *CacheManagerConfig
+	writeBehind *writeBehindQueue // nil unless WriteBehind is enabled
+	instanceID  string            // Tells this instance's invalidations apart from other instances'
+
+	// Tiers can be switched off at runtime (see SetTierEnabled); both start from the config
+	localEnabled atomic.Bool
+	redisEnabled atomic.Bool
+	tierSwitches atomic.Int64
+
+	earlyRefreshes     atomic.Int64
+	readBudgetExceeded atomic.Int64
+	staleServed        atomic.Int64
+	staleMisses        atomic.Int64
+
+	invalidationsSent     atomic.Int64
+	invalidationsReceived atomic.Int64
 }
 
 // CacheManagerConfig holds cache manager configuration
@@ -37,6 +57,36 @@ type CacheManagerConfig struct {
 	// WriteThrough writes to all cache tiers simultaneously
 	WriteThrough bool
 
+	// WriteBehind queues Redis writes and deletes for background batched flushing instead of
+	// waiting on Redis; local cache writes stay synchronous
+	WriteBehind bool
+
+	// WriteBehindConfig tunes the write-behind queue (defaults when nil)
+	WriteBehindConfig *WriteBehindConfig
+
+	// TTLJitter randomizes Redis TTLs by ±this fraction (0.1 = ±10%) so keys written together
+	// don't expire together
+	TTLJitter float64
+
+	// EarlyRefresh enables probabilistic early recomputation (XFetch) in GetOrSet/GetOrSetJSON
+	EarlyRefresh bool
+
+	// EarlyRefreshBeta scales how eagerly keys are refreshed before expiry (1.0 = standard XFetch)
+	EarlyRefreshBeta float64
+
+	// ReadBudget caps the time a lookup may spend on Redis; when it runs out the lookup counts as
+	// a miss so the caller falls through to the source instead of waiting on a slow cache.
+	// 0 disables the budget (Redis calls are still bounded by the caller's deadline and OpTimeout)
+	ReadBudget time.Duration
+
+	// StaleTTL keeps a copy of every value GetOrSetJSON loads for this long (well past RedisTTL);
+	// when a later fetch fails with ErrSourceUnavailable the copy is served instead of the error.
+	// 0 disables second-chance serving
+	StaleTTL time.Duration
+
+	// Keys lays out the keys built by the manager and its callers (DefaultKeyBuilder when unset)
+	Keys KeyBuilder
+
 	// Name for logging
 	Name string
 }
@@ -50,6 +100,10 @@ func DefaultCacheManagerConfig() *CacheManagerConfig {
 		EnableRedisCache:    true,
 		GracefulDegradation: true, // Don't fail if Redis is down
 		WriteThrough:        true, // Write to all tiers
+		TTLJitter:           0.1,  // ±10%
+		EarlyRefresh:        false,
+		EarlyRefreshBeta:    1.0,
+		Keys:                DefaultKeyBuilder(),
 		Name:                "default",
 	}
 }
@@ -59,22 +113,41 @@ func NewCacheManager(local *LocalCache, redis *RedisClient, config *CacheManager
 	if config == nil {
 		config = DefaultCacheManagerConfig()
 	}
+	if config.Keys.Namespace == "" {
+		config.Keys = DefaultKeyBuilder()
+	}
 
-	log.Printf("[CacheManager:%s] Initialized - Local: %v, Redis: %v, Graceful: %v",
-		config.Name, config.EnableLocalCache, config.EnableRedisCache, config.GracefulDegradation)
+	log.Printf("[CacheManager:%s] Initialized - Local: %v, Redis: %v, Graceful: %v, WriteBehind: %v",
+		config.Name, config.EnableLocalCache, config.EnableRedisCache, config.GracefulDegradation, config.WriteBehind)
+
+	cm := &CacheManager{
+		local:      local,
+		redis:      redis,
+		config:     config,
+		instanceID: uuid.NewString(),
+	}
+	cm.localEnabled.Store(config.EnableLocalCache)
+	cm.redisEnabled.Store(config.EnableRedisCache)
 
-	return &CacheManager{
-		local:  local,
-		redis:  redis,
-		config: config,
+	if config.WriteBehind && config.EnableRedisCache && redis != nil {
+		cm.writeBehind = newWriteBehindQueue(redis, config.WriteBehindConfig, config.Name)
 	}
+
+	return cm
 }
 
 // Get retrieves a value from cache with automatic tier fallback
 // Returns (value, source, error) where source is "local", "redis", or "miss"
 func (cm *CacheManager) Get(ctx context.Context, key string) (string, string, error) {
+	start := time.Now()
+	value, source, err := cm.get(ctx, key)
+	debugtrace.FromContext(ctx).AddCacheLookup("get", key, source, start)
+	return value, source, err
+}
+
+func (cm *CacheManager) get(ctx context.Context, key string) (string, string, error) {
 	// L1: Check local cache first (fastest - ~0.001ms)
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		value, err := cm.local.GetString(key)
 		if err == nil {
 			return value, "local", nil
@@ -87,11 +160,14 @@ func (cm *CacheManager) Get(ctx context.Context, key string) (string, string, er
 	}
 
 	// L2: Check Redis cache (~0.5-2ms)
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		value, err := cm.redis.Get(ctx, key)
+	if cm.redisActive() {
+		readCtx, cancel := cm.readContext(ctx)
+		value, err := cm.redis.Get(readCtx, key)
+		overBudget := err != nil && cm.overBudget(ctx, readCtx)
+		cancel()
 		if err == nil {
 			// Found in Redis - populate local cache (write-back)
-			if cm.config.EnableLocalCache && cm.local != nil {
+			if cm.localActive() {
 				if setErr := cm.local.SetString(key, value); setErr != nil {
 					log.Printf("[CacheManager:%s] Failed to write-back to local cache: %v", cm.config.Name, setErr)
 				}
@@ -100,7 +176,7 @@ func (cm *CacheManager) Get(ctx context.Context, key string) (string, string, er
 		}
 
 		// Check if it's a cache miss or actual error
-		if errors.Is(err, ErrCacheMiss) {
+		if errors.Is(err, ErrCacheMiss) || overBudget {
 			return "", "miss", ErrCacheMiss
 		}
 
@@ -117,6 +193,57 @@ func (cm *CacheManager) Get(ctx context.Context, key string) (string, string, er
 	return "", "miss", ErrCacheMiss
 }
 
+// MGet retrieves several keys, reading Redis once for every key the local cache missed
+// Returns the values found keyed by key; keys missing from all tiers are left out. A Redis
+// failure is only returned without GracefulDegradation, otherwise its keys count as misses
+func (cm *CacheManager) MGet(ctx context.Context, keys []string) (map[string]string, error) {
+	start := time.Now()
+	trace := debugtrace.FromContext(ctx)
+	found := make(map[string]string, len(keys))
+
+	remaining := keys
+	if cm.localActive() {
+		remaining = make([]string, 0, len(keys))
+		for _, key := range keys {
+			value, err := cm.local.GetString(key)
+			if err != nil {
+				if !errors.Is(err, ErrCacheMiss) {
+					log.Printf("[CacheManager:%s] Local cache error for key '%s': %v", cm.config.Name, key, err)
+				}
+				remaining = append(remaining, key)
+				continue
+			}
+			found[key] = value
+			trace.AddCacheLookup("mget", key, "local", start)
+		}
+	}
+
+	if len(remaining) > 0 && cm.redisActive() {
+		readCtx, cancel := cm.readContext(ctx)
+		values, err := cm.redis.MGet(readCtx, remaining...)
+		overBudget := err != nil && cm.overBudget(ctx, readCtx)
+		cancel()
+		if err != nil && !overBudget {
+			if !cm.config.GracefulDegradation {
+				return nil, err
+			}
+			log.Printf("[CacheManager:%s] Redis unavailable, continuing without cache: %v", cm.config.Name, err)
+		}
+		for key, value := range values {
+			// Found in Redis - populate local cache (write-back)
+			if cm.localActive() {
+				if setErr := cm.local.SetString(key, value); setErr != nil {
+					log.Printf("[CacheManager:%s] Failed to write-back to local cache: %v", cm.config.Name, setErr)
+				}
+			}
+			found[key] = value
+			trace.AddCacheLookup("mget", key, "redis", start)
+		}
+	}
+
+	return found, nil
+}
+
 // Set stores a value in cache (write-through to all enabled tiers)
 func (cm *CacheManager) Set(ctx context.Context, key string, value any) error {
 	var localErr, redisErr error
@@ -129,15 +256,15 @@ func (cm *CacheManager) Set(ctx context.Context, key string, value any) error {
 		jsonString = v
 	default:
 		// Marshal to JSON
-		jsonData, err := json.Marshal(value)
+		encoded, err := jsoncodec.MarshalString(value)
 		if err != nil {
 			return fmt.Errorf("failed to marshal value to JSON: %w", err)
 		}
-		jsonString = string(jsonData)
+		jsonString = encoded
 	}
 
 	// Write to local cache (as string to avoid double serialization)
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		localErr = cm.local.SetString(key, jsonString)
 		if localErr != nil {
 			log.Printf("[CacheManager:%s] Failed to set in local cache: %v", cm.config.Name, localErr)
@@ -145,8 +272,8 @@ func (cm *CacheManager) Set(ctx context.Context, key string, value any) error {
 	}
 
 	// Write to Redis cache (as string to avoid double serialization)
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		redisErr = cm.redis.Set(ctx, key, jsonString, cm.config.RedisTTL)
+	if cm.redisActive() {
+		redisErr = cm.redisSet(ctx, key, jsonString, cm.jitterTTL(cm.config.RedisTTL))
 		if redisErr != nil {
 			log.Printf("[CacheManager:%s] Failed to set in Redis: %v", cm.config.Name, redisErr)
 
@@ -164,13 +291,69 @@ func (cm *CacheManager) Set(ctx context.Context, key string, value any) error {
 	return nil
 }
 
-// SetWithTTL stores a value with custom TTLs for each tier
+// SetMany stores several values at once (write-through to all enabled tiers)
+// The Redis writes are sent as one MULTI/EXEC so related keys (e.g. user + email) appear together
+func (cm *CacheManager) SetMany(ctx context.Context, values map[string]any) error {
+	encoded := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			encoded[key] = v
+		default:
+			jsonString, err := jsoncodec.MarshalString(value)
+			if err != nil {
+				return fmt.Errorf("failed to marshal value for key '%s' to JSON: %w", key, err)
+			}
+			encoded[key] = jsonString
+		}
+	}
+
+	if cm.localActive() {
+		for key, value := range encoded {
+			if err := cm.local.SetString(key, value); err != nil {
+				log.Printf("[CacheManager:%s] Failed to set '%s' in local cache: %v", cm.config.Name, key, err)
+			}
+		}
+	}
+
+	if cm.redisActive() && cm.writeBehind != nil {
+		// Write-behind trades the MULTI/EXEC guarantee for not waiting on Redis
+		for key, value := range encoded {
+			if err := cm.redisSet(ctx, key, value, cm.jitterTTL(cm.config.RedisTTL)); err != nil {
+				log.Printf("[CacheManager:%s] Failed to queue '%s' for Redis: %v", cm.config.Name, key, err)
+			}
+		}
+	} else if cm.redisActive() {
+		_, err := cm.redis.TxPipeline(ctx, func(pipe redis.Pipeliner) error {
+			for key, value := range encoded {
+				pipe.Set(ctx, key, value, cm.jitterTTL(cm.config.RedisTTL))
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("[CacheManager:%s] Failed to set %d keys in Redis: %v", cm.config.Name, len(encoded), err)
+
+			if !cm.config.GracefulDegradation {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetWithTTL stores a value with custom TTLs for each tier (redisTTL is jittered by TTLJitter)
 func (cm *CacheManager) SetWithTTL(ctx context.Context, key string, value string, localTTL, redisTTL time.Duration) error {
+	return cm.setWithTTL(ctx, key, value, cm.jitterTTL(redisTTL))
+}
+
+// setWithTTL writes value to all tiers with an exact Redis TTL
+func (cm *CacheManager) setWithTTL(ctx context.Context, key string, value string, redisTTL time.Duration) error {
 	var localErr, redisErr error
 
 	// Note: BigCache doesn't support per-key TTL, uses global LifeWindow
 	// So localTTL is ignored, but keeping parameter for API consistency
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		localErr = cm.local.SetString(key, value)
 		if localErr != nil {
 			log.Printf("[CacheManager:%s] Failed to set in local cache: %v", cm.config.Name, localErr)
@@ -178,8 +361,8 @@ func (cm *CacheManager) SetWithTTL(ctx context.Context, key string, value string
 	}
 
 	// Write to Redis with custom TTL (value should already be a string/JSON)
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		redisErr = cm.redis.Set(ctx, key, value, redisTTL)
+	if cm.redisActive() {
+		redisErr = cm.redisSet(ctx, key, value, redisTTL)
 		if redisErr != nil {
 			log.Printf("[CacheManager:%s] Failed to set in Redis: %v", cm.config.Name, redisErr)
 
@@ -201,19 +384,25 @@ func (cm *CacheManager) Delete(ctx context.Context, key string) error {
 	var localErr, redisErr error
 
 	// Delete from local cache
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		localErr = cm.local.Delete(key)
 		if localErr != nil {
 			log.Printf("[CacheManager:%s] Failed to delete from local cache: %v", cm.config.Name, localErr)
 		}
 	}
 
-	// Delete from Redis
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		redisErr = cm.redis.Delete(ctx, key)
+	// Delete from Redis, including the stale copy so a deleted or updated entry isn't served
+	// again during a source outage
+	if cm.redisActive() {
+		redisErr = cm.redisDelete(ctx, key)
 		if redisErr != nil {
 			log.Printf("[CacheManager:%s] Failed to delete from Redis: %v", cm.config.Name, redisErr)
 		}
+		if cm.config.StaleTTL > 0 {
+			if err := cm.redisDelete(ctx, stalePrefix+key); err != nil {
+				log.Printf("[CacheManager:%s] Failed to delete stale copy from Redis: %v", cm.config.Name, err)
+			}
+		}
 	}
 
 	// Best effort - only error if both failed
@@ -227,17 +416,20 @@ func (cm *CacheManager) Delete(ctx context.Context, key string) error {
 // Exists checks if a key exists in any cache tier
 func (cm *CacheManager) Exists(ctx context.Context, key string) (bool, error) {
 	// Check local cache first
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		if cm.local.Exists(key) {
 			return true, nil
 		}
 	}
 
 	// Check Redis
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		exists, err := cm.redis.Exists(ctx, key)
+	if cm.redisActive() {
+		readCtx, cancel := cm.readContext(ctx)
+		exists, err := cm.redis.Exists(readCtx, key)
+		overBudget := err != nil && cm.overBudget(ctx, readCtx)
+		cancel()
 		if err != nil {
-			if cm.config.GracefulDegradation {
+			if cm.config.GracefulDegradation || overBudget {
 				log.Printf("[CacheManager:%s] Redis exists check failed, assuming not exists: %v", cm.config.Name, err)
 				return false, nil
 			}
@@ -250,30 +442,89 @@ func (cm *CacheManager) Exists(ctx context.Context, key string) (bool, error) {
 	return false, nil
 }
 
+// Locate reports which tier holds key without reading or deserializing its value
+// Returns "local" or "redis", or "miss" with ErrCacheMiss; a Redis hit is not written back locally
+func (cm *CacheManager) Locate(ctx context.Context, key string) (string, error) {
+	start := time.Now()
+	source, err := cm.locate(ctx, key)
+	debugtrace.FromContext(ctx).AddCacheLookup("locate", key, source, start)
+	return source, err
+}
+
+func (cm *CacheManager) locate(ctx context.Context, key string) (string, error) {
+	if cm.localActive() && cm.local.Exists(key) {
+		return "local", nil
+	}
+
+	if cm.redisActive() {
+		readCtx, cancel := cm.readContext(ctx)
+		exists, err := cm.redis.Exists(readCtx, key)
+		overBudget := err != nil && cm.overBudget(ctx, readCtx)
+		cancel()
+		if err != nil {
+			if cm.config.GracefulDegradation || overBudget {
+				log.Printf("[CacheManager:%s] Redis exists check failed, treating as miss: %v", cm.config.Name, err)
+				return "miss", ErrCacheMiss
+			}
+			return "error", err
+		}
+		if exists {
+			return "redis", nil
+		}
+	}
+
+	return "miss", ErrCacheMiss
+}
+
+// readContext bounds a Redis read by ReadBudget; the caller's own deadline still applies when sooner
+func (cm *CacheManager) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cm.config.ReadBudget <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, cm.config.ReadBudget)
+}
+
+// overBudget reports (and counts) a read cut short by ReadBudget rather than by the caller's
+// own deadline or cancellation, which callers should see as errors
+func (cm *CacheManager) overBudget(ctx, readCtx context.Context) bool {
+	if cm.config.ReadBudget <= 0 || !errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	cm.readBudgetExceeded.Add(1)
+	return true
+}
+
 // GetOrSet retrieves a value from cache, or sets it using the provided function
 // This is the most common pattern: check cache, if miss, fetch from source and cache
 func (cm *CacheManager) GetOrSet(ctx context.Context, key string, fetchFunc func() (string, error)) (string, error) {
 	// Try to get from cache
 	value, source, err := cm.Get(ctx, key)
-	if err == nil {
+	if err == nil && !cm.shouldRefreshEarly(ctx, key) {
 		log.Printf("[CacheManager:%s] Cache hit for key '%s' from %s", cm.config.Name, key, source)
 		return value, nil
 	}
 
-	// Only fetch if it's a cache miss
-	if !errors.Is(err, ErrCacheMiss) {
+	// Only fetch if it's a cache miss (or an early refresh)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
 		return "", fmt.Errorf("cache error: %w", err)
 	}
 
 	// Cache miss - fetch from source
 	log.Printf("[CacheManager:%s] Cache miss for key '%s', fetching from source", cm.config.Name, key)
+	start := time.Now()
 	value, err = fetchFunc()
 	if err != nil {
 		return "", fmt.Errorf("fetch function failed: %w", err)
 	}
 
 	// Store in cache for next time
-	if setErr := cm.Set(ctx, key, value); setErr != nil {
+	if setErr := cm.setRecomputed(ctx, key, value, time.Since(start)); setErr != nil {
 		log.Printf("[CacheManager:%s] Failed to cache fetched value: %v", cm.config.Name, setErr)
 		// Don't fail the request, we have the value
 	}
@@ -282,9 +533,9 @@ func (cm *CacheManager) GetOrSet(ctx context.Context, key string, fetchFunc func
 }
 
 // InvalidatePattern invalidates all keys matching a pattern (Redis only)
-// Pattern examples: "user:*", "session:*", "email:*"
+// Build patterns from the key layout, e.g. cm.Keys().Prefix(EntityUser) + "*"
 func (cm *CacheManager) InvalidatePattern(ctx context.Context, pattern string) error {
-	if !cm.config.EnableRedisCache || cm.redis == nil {
+	if !cm.redisActive() {
 		return fmt.Errorf("redis cache is not enabled")
 	}
 
@@ -297,18 +548,54 @@ func (cm *CacheManager) InvalidatePattern(ctx context.Context, pattern string) e
 	return fmt.Errorf("pattern invalidation not implemented - use specific key deletion")
 }
 
+// Keys returns the key layout shared by everything that reads or writes through this manager
+func (cm *CacheManager) Keys() KeyBuilder {
+	return cm.config.Keys
+}
+
 // GetMetrics returns combined metrics from all cache tiers
 func (cm *CacheManager) GetMetrics() map[string]interface{} {
 	metrics := make(map[string]interface{})
 
-	if cm.config.EnableLocalCache && cm.local != nil {
-		metrics["local"] = cm.local.GetMetrics()
-		metrics["local_hit_rate"] = cm.local.GetHitRate()
+	// Tiers switched off at runtime keep reporting; topology says which ones are serving
+	// Snapshots render like the per-tier maps did, in one allocation each
+	if cm.local != nil {
+		local := &LocalCacheSnapshot{}
+		cm.local.Snapshot(local)
+		metrics["local"] = local
+		metrics["local_hit_rate"] = local.HitRate()
+	}
+
+	if cm.redis != nil {
+		redis := &RedisSnapshot{}
+		cm.redis.Snapshot(redis)
+		metrics["redis"] = redis
+		metrics["redis_hit_rate"] = redis.HitRate()
+	}
+
+	metrics["topology"] = cm.Topology()
+	metrics["tier_switches"] = cm.tierSwitches.Load()
+
+	if cm.writeBehind != nil {
+		metrics["write_behind"] = cm.writeBehind.getMetrics()
+	}
+
+	if cm.config.EarlyRefresh {
+		metrics["early_refreshes"] = cm.earlyRefreshes.Load()
 	}
 
-	if cm.config.EnableRedisCache && cm.redis != nil {
-		metrics["redis"] = cm.redis.GetMetrics()
-		metrics["redis_hit_rate"] = cm.redis.GetHitRate()
+	if cm.config.ReadBudget > 0 {
+		metrics["read_budget_exceeded"] = cm.readBudgetExceeded.Load()
+	}
+
+	if cm.config.StaleTTL > 0 {
+		metrics["stale_served"] = cm.staleServed.Load()
+		metrics["stale_misses"] = cm.staleMisses.Load()
+	}
+
+	if cm.local != nil && cm.redis != nil {
+		metrics["invalidations_sent"] = cm.invalidationsSent.Load()
+		metrics["invalidations_received"] = cm.invalidationsReceived.Load()
 	}
 
 	return metrics
@@ -330,7 +617,7 @@ func (cm *CacheManager) GetJSON(ctx context.Context, key string, dest interface{
 	}
 
 	// Unmarshal JSON
-	if err := json.Unmarshal([]byte(jsonString), dest); err != nil {
+	if err := jsoncodec.Unmarshal([]byte(jsonString), dest); err != nil {
 		return source, fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
@@ -341,13 +628,13 @@ func (cm *CacheManager) GetJSON(ctx context.Context, key string, dest interface{
 func (cm *CacheManager) GetOrSetJSON(ctx context.Context, key string, dest interface{}, fetchFunc func() (interface{}, error)) (string, error) {
 	// Try to get from cache
 	source, err := cm.GetJSON(ctx, key, dest)
-	if err == nil {
+	if err == nil && !cm.shouldRefreshEarly(ctx, key) {
 		log.Printf("[CacheManager:%s] JSON cache hit for key '%s' from %s", cm.config.Name, key, source)
 		return source, nil
 	}
 
-	// Only fetch if it's a cache miss
-	if !errors.Is(err, ErrCacheMiss) {
+	// Only fetch if it's a cache miss (or an early refresh)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
 		// Check if it's just unmarshal error, might be corrupted cache
 		if errors.Is(err, ErrCacheUnavailable) {
 			log.Printf("[CacheManager:%s] Cache unavailable for key '%s', fetching from source", cm.config.Name, key)
@@ -358,9 +645,13 @@ func (cm *CacheManager) GetOrSetJSON(ctx context.Context, key string, dest inter
 
 	// Cache miss - fetch from source
 	log.Printf("[CacheManager:%s] JSON cache miss for key '%s', fetching from source", cm.config.Name, key)
+	start := time.Now()
 	value, err := fetchFunc()
 	if err != nil {
 		log.Printf("[CacheManager:%s] Fetch function failed for key '%s': %v", cm.config.Name, key, err)
+		if cm.serveStale(ctx, key, dest, err) {
+			return SourceStale, nil
+		}
 		return "", fmt.Errorf("fetch function failed: %w", err)
 	}
 
@@ -371,23 +662,25 @@ func (cm *CacheManager) GetOrSetJSON(ctx context.Context, key string, dest inter
 	}
 
 	// Store in cache as JSON
-	if setErr := cm.SetJSON(ctx, key, value); setErr != nil {
+	if setErr := cm.setRecomputed(ctx, key, value, time.Since(start)); setErr != nil {
 		log.Printf("[CacheManager:%s] Failed to cache JSON for key '%s': %v", cm.config.Name, key, setErr)
 		// Don't fail the request
 	}
 
 	// Populate the destination with the fetched value
 	// Handle both pointer and non-pointer cases
-	jsonData, marshalErr := json.Marshal(value)
-	if marshalErr != nil {
+	buf := jsoncodec.AcquireBuffer()
+	defer jsoncodec.ReleaseBuffer(buf)
+	if marshalErr := jsoncodec.MarshalTo(buf, value); marshalErr != nil {
 		log.Printf("[CacheManager:%s] Failed to marshal fetched value: %v", cm.config.Name, marshalErr)
 		return "", fmt.Errorf("failed to marshal fetched value: %w", marshalErr)
 	}
 
-	if unmarshalErr := json.Unmarshal(jsonData, dest); unmarshalErr != nil {
+	if unmarshalErr := jsoncodec.Unmarshal(buf.Bytes(), dest); unmarshalErr != nil {
 		log.Printf("[CacheManager:%s] Failed to unmarshal into destination: %v", cm.config.Name, unmarshalErr)
 		return "", fmt.Errorf("failed to unmarshal into destination: %w", unmarshalErr)
 	}
+	cm.setStale(ctx, key, buf.String())
 
 	return "database", nil
 }
@@ -397,7 +690,7 @@ func (cm *CacheManager) HealthCheck(ctx context.Context) map[string]string {
 	health := make(map[string]string)
 
 	// Check local cache
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		health["local"] = "healthy"
 		health["local_entries"] = fmt.Sprintf("%d", cm.local.Len())
 	} else {
@@ -405,7 +698,7 @@ func (cm *CacheManager) HealthCheck(ctx context.Context) map[string]string {
 	}
 
 	// Check Redis
-	if cm.config.EnableRedisCache && cm.redis != nil {
+	if cm.redisActive() {
 		if err := cm.redis.HealthCheck(ctx); err != nil {
 			health["redis"] = fmt.Sprintf("unhealthy: %v", err)
 		} else {
@@ -424,6 +717,11 @@ func (cm *CacheManager) Close() error {
 
 	var localErr, redisErr error
 
+	// Drain queued writes while Redis is still open
+	if cm.writeBehind != nil {
+		cm.writeBehind.close()
+	}
+
 	if cm.local != nil {
 		localErr = cm.local.Close()
 	}
@@ -440,22 +738,39 @@ func (cm *CacheManager) Close() error {
 	return nil
 }
 
+// redisSet writes to Redis directly, or queues the write in write-behind mode
+func (cm *CacheManager) redisSet(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if cm.writeBehind != nil {
+		return cm.writeBehind.enqueue(ctx, writeOp{key: key, value: value, ttl: ttl})
+	}
+	return cm.redis.Set(ctx, key, value, ttl)
+}
+
+// redisDelete deletes from Redis directly, or queues the delete in write-behind mode so it is
+// applied after any earlier queued write of the same key
+func (cm *CacheManager) redisDelete(ctx context.Context, key string) error {
+	if cm.writeBehind != nil {
+		return cm.writeBehind.enqueue(ctx, writeOp{key: key, delete: true})
+	}
+	return cm.redis.Delete(ctx, key)
+}
+
 // --- Helper Functions for Common Patterns ---
 
 // CacheEmailExists checks if an email exists using atomic SetNX (Redis only)
 // Returns true if email was successfully reserved, false if already exists
 func (cm *CacheManager) CacheEmailExists(ctx context.Context, email string, userID string, ttl time.Duration) (bool, error) {
-	key := "email:" + email
+	key := cm.config.Keys.Key(EntityEmail, email)
 
 	// Check local cache first (fast path)
-	if cm.config.EnableLocalCache && cm.local != nil {
+	if cm.localActive() {
 		if cm.local.Exists(key) {
 			return false, nil // Email exists
 		}
 	}
 
 	// Use Redis SetNX for atomic check-and-set
-	if cm.config.EnableRedisCache && cm.redis != nil {
+	if cm.redisActive() {
 		reserved, err := cm.redis.SetNX(ctx, key, userID, ttl)
 		if err != nil {
 			if cm.config.GracefulDegradation {
@@ -466,7 +781,7 @@ func (cm *CacheManager) CacheEmailExists(ctx context.Context, email string, user
 		}
 
 		// Update local cache if reserved
-		if reserved && cm.config.EnableLocalCache && cm.local != nil {
+		if reserved && cm.localActive() {
 			cm.local.SetString(key, userID)
 		}
 
@@ -477,6 +792,45 @@ func (cm *CacheManager) CacheEmailExists(ctx context.Context, email string, user
 	return true, nil
 }
 
+// TryLock acquires a distributed lock key for ttl using Redis SetNX
+// Without Redis there is nothing to coordinate with, so the lock is always granted
+func (cm *CacheManager) TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	if !cm.redisActive() {
+		return true, nil
+	}
+
+	acquired, err := cm.redis.SetNX(ctx, cm.config.Keys.Key(EntityLock, key), owner, ttl)
+	if err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
+
+// NewRateLimiter creates a rate limiter on this manager's Redis tier
+// With Redis disabled every call follows the limiter's FailOpen policy
+func (cm *CacheManager) NewRateLimiter(config *RateLimiterConfig) *RateLimiter {
+	if config == nil {
+		config = DefaultRateLimiterConfig()
+		config.Prefix = cm.config.Keys.Prefix(EntityRateLimit)
+	}
+	var redisClient *RedisClient
+	if cm.config.EnableRedisCache {
+		redisClient = cm.redis
+	}
+	return NewRateLimiter(redisClient, config)
+}
+
+// ResetMetrics zeroes hit/miss counters on all tiers (e.g. at the start of a reporting window)
+func (cm *CacheManager) ResetMetrics() {
+	if cm.local != nil {
+		cm.local.ResetMetrics()
+	}
+	if cm.redis != nil {
+		cm.redis.ResetMetrics()
+	}
+	log.Printf("[CacheManager:%s] Metrics reset", cm.config.Name)
+}
+
 // GetWithStats returns value and detailed stats about cache performance
 func (cm *CacheManager) GetWithStats(ctx context.Context, key string) (value string, stats CacheStats, err error) {
 	start := time.Now()
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// counterShards is the number of slots a Counter spreads its adds over. A power of two, so a
+// shard is picked with a mask; 32 keeps collisions rare up to a few dozen cores
+const counterShards = 32
+
+// counterShard is one slot of a Counter, padded to a cache line so adds on neighboring shards
+// don't bounce the same line between cores
+type counterShard struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// Counter is a monotonic counter for hot paths. A single atomic.Int64 hit by every request
+// serializes all cores on one cache line; Counter spreads adds over padded shards picked by the
+// runtime's per-thread random source, which keeps goroutines on different Ps apart like per-P
+// slots would, and sums them when read. Reads are a scrape-time cost, adds stay uncontended.
+// The zero value is ready to use; a Counter must not be copied after first use
+type Counter struct {
+	shards [counterShards]counterShard
+}
+
+// Add adds delta to the counter
+func (c *Counter) Add(delta int64) {
+	c.shards[rand.Uint32()&(counterShards-1)].n.Add(delta)
+}
+
+// Load returns the sum of all shards. Adds racing with it may or may not be counted
+func (c *Counter) Load() int64 {
+	var sum int64
+	for i := range c.shards {
+		sum += c.shards[i].n.Load()
+	}
+	return sum
+}
+
+// Reset zeroes the counter. Adds racing with it may survive the reset
+func (c *Counter) Reset() {
+	for i := range c.shards {
+		c.shards[i].n.Store(0)
+	}
+}
+
+// hitRate returns hits as a percentage of lookups, 0 before the first one
+func hitRate(hits, misses int64) float64 {
+	total := hits + misses
+	if total == 0 {
+		return 0.0
+	}
+	return float64(hits) / float64(total) * 100.0
+}
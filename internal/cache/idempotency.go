@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRequestInProgress is returned by IdempotencyStore.Begin while the first request with the same
+// key is still running
+var ErrRequestInProgress = errors.New("a request with this idempotency key is in progress")
+
+// ErrIdempotencyKeyReused is returned by IdempotencyStore.Begin for a key first used with a
+// different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+
+// IdempotencyConfig holds the duplicate-request detection settings
+type IdempotencyConfig struct {
+	// Window is how long a completed request's result is replayed to retries with its key
+	Window time.Duration
+
+	// PendingTTL bounds how long a key stays claimed by a request that never completes (e.g. its
+	// instance crashed); retries are refused as in progress until then
+	PendingTTL time.Duration
+}
+
+// DefaultIdempotencyConfig returns sensible defaults: results are kept for a day
+func DefaultIdempotencyConfig() *IdempotencyConfig {
+	return &IdempotencyConfig{
+		Window:     24 * time.Hour,
+		PendingTTL: 30 * time.Second,
+	}
+}
+
+// idempotencyRecord is stored under a key: pending until the request completes, then its result.
+// Fingerprint identifies the request the key was first used with
+type idempotencyRecord struct {
+	Fingerprint string          `json:"f"`
+	Done        bool            `json:"d,omitempty"`
+	Result      json.RawMessage `json:"r,omitempty"`
+}
+
+// IdempotencyStore deduplicates retried requests by a client-supplied key. The first request
+// claims the key with SET NX and stores its result under it; a retry within the window gets that
+// result back instead of running again. Without Redis every request runs
+type IdempotencyStore struct {
+	cm     *CacheManager
+	scope  string
+	config *IdempotencyConfig
+}
+
+// NewIdempotencyStore creates a store for one operation; scope keeps keys of different operations
+// apart, e.g. "grpc:createUser"
+func (cm *CacheManager) NewIdempotencyStore(scope string, config *IdempotencyConfig) *IdempotencyStore {
+	if config == nil {
+		config = DefaultIdempotencyConfig()
+	}
+	return &IdempotencyStore{cm: cm, scope: scope, config: config}
+}
+
+// Begin claims key for a request identified by fingerprint. When the key completed before with the
+// same fingerprint its stored result is decoded into result and replayed is true; the caller
+// returns it without running the request. Otherwise the caller runs the request and calls
+// Complete, or Release when the failure is worth retrying. A Redis error is returned with the
+// request unclaimed; callers may run it without deduplication
+func (s *IdempotencyStore) Begin(ctx context.Context, key, fingerprint string, result any) (replayed bool, err error) {
+	if !s.cm.redisActive() {
+		return false, nil
+	}
+
+	redisKey := s.redisKey(key)
+	pending, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return false, err
+	}
+
+	// A second try covers a record that expired between SET NX and GET
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := s.cm.redis.SetNX(ctx, redisKey, pending, s.config.PendingTTL)
+		if err != nil || claimed {
+			return false, err
+		}
+
+		raw, err := s.cm.redis.Get(ctx, redisKey)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		var record idempotencyRecord
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			return false, fmt.Errorf("invalid idempotency record: %w", err)
+		}
+		switch {
+		case record.Fingerprint != fingerprint:
+			return false, ErrIdempotencyKeyReused
+		case !record.Done:
+			return false, ErrRequestInProgress
+		}
+		if err := json.Unmarshal(record.Result, result); err != nil {
+			return false, fmt.Errorf("invalid idempotency result: %w", err)
+		}
+		return true, nil
+	}
+	return false, ErrRequestInProgress
+}
+
+// Complete stores the result of a request begun with key, replayed to retries for the window
+func (s *IdempotencyStore) Complete(ctx context.Context, key, fingerprint string, result any) error {
+	if !s.cm.redisActive() {
+		return nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	record, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Done: true, Result: encoded})
+	if err != nil {
+		return err
+	}
+	return s.cm.redis.Set(ctx, s.redisKey(key), record, s.config.Window)
+}
+
+// Release frees key without a result, so a retry runs the request again
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	if !s.cm.redisActive() {
+		return nil
+	}
+	return s.cm.redis.Delete(ctx, s.redisKey(key))
+}
+
+// Window returns how long results are replayed
+func (s *IdempotencyStore) Window() time.Duration {
+	return s.config.Window
+}
+
+func (s *IdempotencyStore) redisKey(key string) string {
+	return s.cm.config.Keys.Key(EntityIdempotency, s.scope, key)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"acid/internal/jsoncodec"
+	"context"
+	"fmt"
+	"log"
+)
+
+// invalidationMessage is published on the invalidation channel; Origin lets an instance skip its own
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// PublishInvalidation asks every other instance sharing Redis to drop keys from its local tier.
+// Delete only reaches this instance's local tier and Redis, so without it other instances keep
+// serving their local copy until LocalTTL expires. Needs the Redis tier active
+func (cm *CacheManager) PublishInvalidation(ctx context.Context, keys ...string) error {
+	if !cm.redisActive() {
+		return fmt.Errorf("%w: redis tier is not active", ErrCacheUnavailable)
+	}
+
+	message, err := jsoncodec.Marshal(invalidationMessage{Origin: cm.instanceID, Keys: keys})
+	if err != nil {
+		return fmt.Errorf("failed to encode invalidation: %w", err)
+	}
+	if err := cm.redis.Publish(ctx, cm.invalidationChannel(), message); err != nil {
+		return err
+	}
+
+	cm.invalidationsSent.Add(1)
+	return nil
+}
+
+// ListenInvalidations drops the keys published by other instances from the local tier until ctx
+// is done. Messages published while the subscription is reconnecting are lost; those entries
+// expire on LocalTTL as before
+func (cm *CacheManager) ListenInvalidations(ctx context.Context) {
+	if cm.local == nil || cm.redis == nil {
+		return
+	}
+
+	sub := cm.redis.Subscribe(ctx, cm.invalidationChannel())
+	defer sub.Close()
+
+	log.Printf("[CacheManager:%s] Listening for invalidations on '%s'", cm.config.Name, cm.invalidationChannel())
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			cm.applyInvalidation(msg.Payload)
+		}
+	}
+}
+
+func (cm *CacheManager) applyInvalidation(payload string) {
+	var message invalidationMessage
+	if err := jsoncodec.Unmarshal([]byte(payload), &message); err != nil {
+		log.Printf("[CacheManager:%s] Ignoring malformed invalidation: %v", cm.config.Name, err)
+		return
+	}
+	if message.Origin == cm.instanceID {
+		return
+	}
+
+	// The local tier is cleared when re-enabled, so a disabled one needs no special case
+	for _, key := range message.Keys {
+		if err := cm.local.Delete(key); err != nil {
+			log.Printf("[CacheManager:%s] Failed to apply invalidation of '%s': %v", cm.config.Name, key, err)
+		}
+	}
+	cm.invalidationsReceived.Add(1)
+}
+
+func (cm *CacheManager) invalidationChannel() string {
+	return cm.config.Keys.Key(EntityInvalidation)
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IPRuleStore keeps runtime IP rules as Redis sets, one per scope and list (e.g. "admin:allow"),
+// so every instance sharing Redis enforces the same rules
+type IPRuleStore struct {
+	redis  *RedisClient
+	prefix string
+}
+
+// NewIPRuleStore creates a rule store on this manager's Redis tier; with Redis disabled Load
+// returns no rules and changes fail with ErrCacheUnavailable
+func (cm *CacheManager) NewIPRuleStore(prefix string) *IPRuleStore {
+	var redisClient *RedisClient
+	if cm.config.EnableRedisCache {
+		redisClient = cm.redis
+	}
+	return &IPRuleStore{redis: redisClient, prefix: prefix}
+}
+
+// Load returns the members of every set in one round trip; missing sets are empty
+func (s *IPRuleStore) Load(ctx context.Context, sets []string) (map[string][]string, error) {
+	rules := make(map[string][]string, len(sets))
+	if s == nil || s.redis == nil {
+		return rules, nil
+	}
+
+	cmds, err := s.redis.Pipeline(ctx, func(pipe redis.Pipeliner) error {
+		for _, set := range sets {
+			pipe.SMembers(ctx, s.prefix+set)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IP rules: %w", err)
+	}
+	for i, cmd := range cmds {
+		members, err := cmd.(*redis.StringSliceCmd).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load IP rules: %w", err)
+		}
+		rules[sets[i]] = members
+	}
+	return rules, nil
+}
+
+// Add adds member to set
+func (s *IPRuleStore) Add(ctx context.Context, set, member string) error {
+	if s == nil || s.redis == nil {
+		return fmt.Errorf("%w: redis not configured", ErrCacheUnavailable)
+	}
+	return s.redis.SAdd(ctx, s.prefix+set, member)
+}
+
+// Remove removes member from set
+func (s *IPRuleStore) Remove(ctx context.Context, set, member string) error {
+	if s == nil || s.redis == nil {
+		return fmt.Errorf("%w: redis not configured", ErrCacheUnavailable)
+	}
+	return s.redis.SRem(ctx, s.prefix+set, member)
+}
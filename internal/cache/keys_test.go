@@ -0,0 +1,53 @@
+package cache
+
+import "testing"
+
+const benchUserID = "0192b7a4-3c1e-7d2a-9f4b-6a8e1c5d2f30"
+
+func TestAppendKeyMatchesKey(t *testing.T) {
+	b := DefaultKeyBuilder().WithTenant("acme")
+	for _, parts := range [][]string{nil, {benchUserID}, {"GET", "/api/v1/users", "deadbeef"}} {
+		key := b.Key(EntityUser, parts...)
+		if appended := string(b.AppendKey(nil, EntityUser, parts...)); appended != key {
+			t.Errorf("AppendKey(%q) = %q, Key = %q", parts, appended, key)
+		}
+	}
+	if key, want := DefaultKeyBuilder().Key(EntityUser, benchUserID), "acid:v1:user:default:"+benchUserID; key != want {
+		t.Errorf("Key = %q, want %q", key, want)
+	}
+}
+
+func TestKeyAllocations(t *testing.T) {
+	b := DefaultKeyBuilder()
+	if allocs := testing.AllocsPerRun(100, func() {
+		_ = b.Key(EntityUser, benchUserID)
+	}); allocs > 1 {
+		t.Errorf("Key allocates %v times, want 1", allocs)
+	}
+
+	buf := AcquireKeyBuffer()
+	defer ReleaseKeyBuffer(buf)
+	if allocs := testing.AllocsPerRun(100, func() {
+		*buf = b.AppendKey((*buf)[:0], EntityUser, benchUserID)
+	}); allocs > 0 {
+		t.Errorf("AppendKey into a pooled buffer allocates %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkKey(b *testing.B) {
+	builder := DefaultKeyBuilder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = builder.Key(EntityUser, benchUserID)
+	}
+}
+
+func BenchmarkAppendKey(b *testing.B) {
+	builder := DefaultKeyBuilder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := AcquireKeyBuffer()
+		*buf = builder.AppendKey(*buf, EntityUser, benchUserID)
+		ReleaseKeyBuffer(buf)
+	}
+}
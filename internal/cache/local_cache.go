@@ -1,8 +1,9 @@
 package cache
 
 import (
+	"acid/internal/jsoncodec"
+	"acid/internal/safego"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -15,17 +16,52 @@ import (
 // LocalCache provides an in-memory cache with zero GC overhead
 // Uses BigCache - optimized for high-throughput, low-latency scenarios
 type LocalCache struct {
-	cache   *bigcache.BigCache
+	cache   atomic.Pointer[bigcache.BigCache] // Swapped when the LifeWindow is auto-tuned
 	metrics *LocalCacheMetrics
 	name    string
+	config  LocalCacheConfig
+	memory  memoryState
+	stop    chan struct{}
 }
 
-// LocalCacheMetrics tracks local cache performance
+// LocalCacheMetrics tracks local cache performance. Counters are sharded, as every cache read
+// bumps one of them
 type LocalCacheMetrics struct {
-	Hits   atomic.Int64
-	Misses atomic.Int64
-	Sets   atomic.Int64
-	Errors atomic.Int64
+	Hits   Counter
+	Misses Counter
+	Sets   Counter
+	Errors Counter
+
+	// Evictions by BigCache itself; NoSpace means the cache is full at HardMaxCacheSize
+	EvictedExpired Counter
+	EvictedNoSpace Counter
+}
+
+// LocalCacheSnapshot is a point-in-time copy of the local cache metrics. Fill one with Snapshot;
+// a scraper can reuse the same struct across scrapes instead of allocating a map per scrape
+type LocalCacheSnapshot struct {
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Sets       int64 `json:"sets"`
+	Errors     int64 `json:"errors"`
+	Entries    int64 `json:"entries"`
+	Capacity   int64 `json:"capacity"`
+	Collisions int64 `json:"collisions"`
+	DelHits    int64 `json:"del_hits"`
+	DelMisses  int64 `json:"del_misses"`
+
+	EvictedExpired    int64 `json:"evicted_expired"`
+	EvictedNoSpace    int64 `json:"evicted_no_space"`
+	UsedBytes         int64 `json:"used_bytes"`
+	CapacityLimit     int64 `json:"capacity_limit"`
+	LifeWindowSeconds int64 `json:"life_window_seconds"`
+	MemoryAlerts      int64 `json:"memory_alerts"`
+	LifeWindowChanges int64 `json:"life_window_changes"`
+}
+
+// HitRate returns the hit rate of the snapshot as a percentage
+func (s *LocalCacheSnapshot) HitRate() float64 {
+	return hitRate(s.Hits, s.Misses)
 }
 
 // LocalCacheConfig holds configuration for local cache
@@ -53,6 +89,25 @@ type LocalCacheConfig struct {
 	// Verbose enables logging
 	Verbose bool
 
+	// MemorySoftLimit is the share of HardMaxCacheSize (0.8 = 80%) above which the cache is under
+	// memory pressure: a warning is logged and, with AutoTuneLifeWindow, LifeWindow shrinks.
+	// 0, or no HardMaxCacheSize, disables the check
+	MemorySoftLimit float64
+
+	// MemoryCheckInterval is how often usage is compared with the soft limit
+	MemoryCheckInterval time.Duration
+
+	// AutoTuneLifeWindow rebuilds the cache with half the LifeWindow, down to MinLifeWindow, while
+	// under pressure, and doubles it back once usage has stayed below the soft limit for RelaxAfter.
+	// Every change starts the cache empty
+	AutoTuneLifeWindow bool
+
+	// MinLifeWindow is the shortest LifeWindow auto-tuning goes to
+	MinLifeWindow time.Duration
+
+	// RelaxAfter is how long usage must stay below the soft limit before LifeWindow grows back
+	RelaxAfter time.Duration
+
 	// Name for identification
 	Name string
 }
@@ -61,14 +116,19 @@ type LocalCacheConfig struct {
 // Optimized for ~10K items with 1 minute TTL
 func DefaultLocalCacheConfig() *LocalCacheConfig {
 	return &LocalCacheConfig{
-		Shards:             1024,            // Good parallelism
-		LifeWindow:         1 * time.Minute, // 1 min TTL
-		CleanWindow:        5 * time.Minute, // Clean every 5 min
-		MaxEntriesInWindow: 10000 * 60,      // 10K entries/sec * 60 sec
-		MaxEntrySize:       500,             // 500 bytes per entry
-		HardMaxCacheSize:   0,               // No hard limit
-		Verbose:            false,
-		Name:               "default",
+		Shards:              1024,            // Good parallelism
+		LifeWindow:          1 * time.Minute, // 1 min TTL
+		CleanWindow:         5 * time.Minute, // Clean every 5 min
+		MaxEntriesInWindow:  10000 * 60,      // 10K entries/sec * 60 sec
+		MaxEntrySize:        500,             // 500 bytes per entry
+		HardMaxCacheSize:    0,               // No hard limit
+		Verbose:             false,
+		MemorySoftLimit:     0.8,
+		MemoryCheckInterval: 30 * time.Second,
+		AutoTuneLifeWindow:  false,
+		MinLifeWindow:       10 * time.Second,
+		RelaxAfter:          15 * time.Minute,
+		Name:                "default",
 	}
 }
 
@@ -78,6 +138,31 @@ func NewLocalCache(config *LocalCacheConfig) (*LocalCache, error) {
 		config = DefaultLocalCacheConfig()
 	}
 
+	l := &LocalCache{
+		metrics: &LocalCacheMetrics{},
+		name:    config.Name,
+		config:  *config,
+		stop:    make(chan struct{}),
+	}
+	l.memory.lifeWindow.Store(int64(config.LifeWindow))
+
+	cache, err := l.newBigCache(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local cache: %w", err)
+	}
+	l.cache.Store(cache)
+
+	log.Printf("[LocalCache:%s] Initialized - Shards: %d, LifeWindow: %v, MaxEntries: %d",
+		config.Name, config.Shards, config.LifeWindow, config.MaxEntriesInWindow)
+
+	if l.memoryLimit() > 0 && config.MemorySoftLimit > 0 && config.MemoryCheckInterval > 0 {
+		safego.Go("local_cache.memory", l.monitorMemory, safego.Restart(l.stop))
+	}
+
+	return l, nil
+}
+
+func (l *LocalCache) newBigCache(config *LocalCacheConfig) (*bigcache.BigCache, error) {
 	// Build BigCache config
 	bigCacheConfig := bigcache.Config{
 		Shards:             config.Shards,
@@ -88,40 +173,30 @@ func NewLocalCache(config *LocalCacheConfig) (*LocalCache, error) {
 		HardMaxCacheSize:   config.HardMaxCacheSize,
 		Verbose:            config.Verbose,
 
-		// OnRemove callback for tracking evictions
-		OnRemove: func(key string, entry []byte) {
-			// Could track evictions here if needed
-		},
-
-		// OnRemoveWithReason for detailed eviction tracking
+		// OnRemoveWithReason for detailed eviction tracking; BigCache ignores it when OnRemove is set
 		OnRemoveWithReason: func(key string, entry []byte, reason bigcache.RemoveReason) {
 			// Expired, NoSpace, Deleted
+			switch reason {
+			case bigcache.Expired:
+				l.metrics.EvictedExpired.Add(1)
+			case bigcache.NoSpace:
+				l.metrics.EvictedNoSpace.Add(1)
+			}
 			if config.Verbose {
 				log.Printf("[LocalCache:%s] Key '%s' removed: %v", config.Name, key, reason)
 			}
 		},
 	}
 
-	cache, err := bigcache.New(context.Background(), bigCacheConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create local cache: %w", err)
-	}
-
-	log.Printf("[LocalCache:%s] Initialized - Shards: %d, LifeWindow: %v, MaxEntries: %d",
-		config.Name, config.Shards, config.LifeWindow, config.MaxEntriesInWindow)
-
-	return &LocalCache{
-		cache:   cache,
-		metrics: &LocalCacheMetrics{},
-		name:    config.Name,
-	}, nil
+	return bigcache.New(context.Background(), bigCacheConfig)
 }
 
 // Set stores a byte slice value
 func (l *LocalCache) Set(key string, value []byte) error {
 	l.metrics.Sets.Add(1)
+	l.memory.written(key, value)
 
-	err := l.cache.Set(key, value)
+	err := l.cache.Load().Set(key, value)
 	if err != nil {
 		l.metrics.Errors.Add(1)
 		return fmt.Errorf("cache set failed: %w", err)
@@ -137,7 +212,7 @@ func (l *LocalCache) SetString(key string, value string) error {
 
 // SetJSON stores any value as JSON
 func (l *LocalCache) SetJSON(key string, value interface{}) error {
-	data, err := json.Marshal(value)
+	data, err := jsoncodec.Marshal(value)
 	if err != nil {
 		l.metrics.Errors.Add(1)
 		return fmt.Errorf("failed to marshal value: %w", err)
@@ -148,7 +223,7 @@ func (l *LocalCache) SetJSON(key string, value interface{}) error {
 
 // Get retrieves a value from cache as []byte
 func (l *LocalCache) Get(key string) ([]byte, error) {
-	value, err := l.cache.Get(key)
+	value, err := l.cache.Load().Get(key)
 	if err != nil {
 		if errors.Is(err, bigcache.ErrEntryNotFound) {
 			l.metrics.Misses.Add(1)
@@ -178,7 +253,7 @@ func (l *LocalCache) GetJSON(key string, dest interface{}) error {
 		return err
 	}
 
-	if err := json.Unmarshal(value, dest); err != nil {
+	if err := jsoncodec.Unmarshal(value, dest); err != nil {
 		l.metrics.Errors.Add(1)
 		return fmt.Errorf("failed to unmarshal value: %w", err)
 	}
@@ -188,7 +263,7 @@ func (l *LocalCache) GetJSON(key string, dest interface{}) error {
 
 // Exists checks if a key exists in cache
 func (l *LocalCache) Exists(key string) bool {
-	_, err := l.cache.Get(key)
+	_, err := l.cache.Load().Get(key)
 	if err != nil {
 		l.metrics.Misses.Add(1)
 		return false
@@ -199,7 +274,7 @@ func (l *LocalCache) Exists(key string) bool {
 
 // Delete removes a key from cache
 func (l *LocalCache) Delete(key string) error {
-	err := l.cache.Delete(key)
+	err := l.cache.Load().Delete(key)
 	if err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
 		l.metrics.Errors.Add(1)
 		return fmt.Errorf("cache delete failed: %w", err)
@@ -209,7 +284,7 @@ func (l *LocalCache) Delete(key string) error {
 
 // Reset removes all items from cache
 func (l *LocalCache) Reset() error {
-	err := l.cache.Reset()
+	err := l.cache.Load().Reset()
 	if err != nil {
 		l.metrics.Errors.Add(1)
 		return fmt.Errorf("cache reset failed: %w", err)
@@ -220,58 +295,96 @@ func (l *LocalCache) Reset() error {
 
 // Len returns the number of items in cache
 func (l *LocalCache) Len() int {
-	return l.cache.Len()
+	return l.cache.Load().Len()
 }
 
 // Capacity returns cache capacity in bytes
 func (l *LocalCache) Capacity() int {
-	return l.cache.Capacity()
+	return l.cache.Load().Capacity()
 }
 
-// GetMetrics returns current cache performance metrics
-func (l *LocalCache) GetMetrics() map[string]int64 {
+// Snapshot fills s with the current cache performance metrics
+func (l *LocalCache) Snapshot(s *LocalCacheSnapshot) {
 	// Get BigCache's internal stats
-	stats := l.cache.Stats()
+	cache := l.cache.Load()
+	stats := cache.Stats()
+
+	*s = LocalCacheSnapshot{
+		Hits:       l.metrics.Hits.Load(),
+		Misses:     l.metrics.Misses.Load(),
+		Sets:       l.metrics.Sets.Load(),
+		Errors:     l.metrics.Errors.Load(),
+		Entries:    int64(cache.Len()),
+		Capacity:   int64(cache.Capacity()),
+		Collisions: int64(stats.Collisions),
+		DelHits:    int64(stats.DelHits),
+		DelMisses:  int64(stats.DelMisses),
+
+		EvictedExpired:    l.metrics.EvictedExpired.Load(),
+		EvictedNoSpace:    l.metrics.EvictedNoSpace.Load(),
+		UsedBytes:         l.UsedBytes(),
+		CapacityLimit:     l.memoryLimit(),
+		LifeWindowSeconds: int64(l.LifeWindow().Seconds()),
+		MemoryAlerts:      l.memory.alerts.Load(),
+		LifeWindowChanges: l.memory.changes.Load(),
+	}
+}
+
+// GetMetrics returns current cache performance metrics as a map. Prefer Snapshot on hot scrape
+// paths; this allocates
+func (l *LocalCache) GetMetrics() map[string]int64 {
+	var s LocalCacheSnapshot
+	l.Snapshot(&s)
 
 	return map[string]int64{
-		"hits":       l.metrics.Hits.Load(),
-		"misses":     l.metrics.Misses.Load(),
-		"sets":       l.metrics.Sets.Load(),
-		"errors":     l.metrics.Errors.Load(),
-		"entries":    int64(l.cache.Len()),
-		"capacity":   int64(l.cache.Capacity()),
-		"collisions": int64(stats.Collisions),
-		"del_hits":   int64(stats.DelHits),
-		"del_misses": int64(stats.DelMisses),
+		"hits":       s.Hits,
+		"misses":     s.Misses,
+		"sets":       s.Sets,
+		"errors":     s.Errors,
+		"entries":    s.Entries,
+		"capacity":   s.Capacity,
+		"collisions": s.Collisions,
+		"del_hits":   s.DelHits,
+		"del_misses": s.DelMisses,
+
+		"evicted_expired":     s.EvictedExpired,
+		"evicted_no_space":    s.EvictedNoSpace,
+		"used_bytes":          s.UsedBytes,
+		"capacity_limit":      s.CapacityLimit,
+		"life_window_seconds": s.LifeWindowSeconds,
+		"memory_alerts":       s.MemoryAlerts,
+		"life_window_changes": s.LifeWindowChanges,
 	}
 }
 
+// ResetMetrics zeroes all counters (BigCache internal stats are not affected)
+func (l *LocalCache) ResetMetrics() {
+	l.metrics.Hits.Reset()
+	l.metrics.Misses.Reset()
+	l.metrics.Sets.Reset()
+	l.metrics.Errors.Reset()
+}
+
 // GetHitRate calculates cache hit rate as percentage
 func (l *LocalCache) GetHitRate() float64 {
-	hits := l.metrics.Hits.Load()
-	misses := l.metrics.Misses.Load()
-	total := hits + misses
-
-	if total == 0 {
-		return 0.0
-	}
-
-	return float64(hits) / float64(total) * 100.0
+	return hitRate(l.metrics.Hits.Load(), l.metrics.Misses.Load())
 }
 
 // GetStats returns BigCache internal statistics
 func (l *LocalCache) GetStats() bigcache.Stats {
-	return l.cache.Stats()
+	return l.cache.Load().Stats()
 }
 
 // Close gracefully closes the cache with final stats
 func (l *LocalCache) Close() error {
-	metrics := l.GetMetrics()
+	var s LocalCacheSnapshot
+	l.Snapshot(&s)
 
 	log.Printf("[LocalCache:%s] Closing. Stats - Hits: %d, Misses: %d, Entries: %d, Hit Rate: %.2f%%",
-		l.name, metrics["hits"], metrics["misses"], metrics["entries"], l.GetHitRate())
+		l.name, s.Hits, s.Misses, s.Entries, s.HitRate())
 
-	return l.cache.Close()
+	close(l.stop)
+	return l.cache.Load().Close()
 }
 
 // --- Multi-Tier Cache Helper ---
@@ -340,10 +453,15 @@ func (m *MultiTierCache) Delete(ctx context.Context, key string) error {
 
 // GetMetrics returns combined metrics from both tiers
 func (m *MultiTierCache) GetMetrics() map[string]interface{} {
+	local := &LocalCacheSnapshot{}
+	m.local.Snapshot(local)
+	redis := &RedisSnapshot{}
+	m.redis.Snapshot(redis)
+
 	return map[string]interface{}{
-		"local":          m.local.GetMetrics(),
-		"redis":          m.redis.GetMetrics(),
-		"local_hit_rate": m.local.GetHitRate(),
-		"redis_hit_rate": m.redis.GetHitRate(),
+		"local":          local,
+		"redis":          redis,
+		"local_hit_rate": local.HitRate(),
+		"redis_hit_rate": redis.HitRate(),
 	}
 }
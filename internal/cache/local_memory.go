@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// entryOverhead approximates BigCache's per-entry header (timestamp, hash, key length) plus the
+// queue's length prefix
+const entryOverhead = 20
+
+// memoryState tracks local cache usage against HardMaxCacheSize for the soft limit and auto-tuning
+type memoryState struct {
+	// writtenEntries and writtenBytes give the average entry size; unlike the hit/miss metrics
+	// they are never reset
+	writtenEntries atomic.Int64
+	writtenBytes   atomic.Int64
+
+	lifeWindow atomic.Int64 // Current LifeWindow in nanoseconds
+	alerts     atomic.Int64 // Times usage crossed the soft limit
+	changes    atomic.Int64 // LifeWindow changes made by auto-tuning
+
+	// Only touched by the monitor goroutine
+	underPressure bool
+	calmSince     time.Time
+}
+
+func (m *memoryState) written(key string, value []byte) {
+	m.writtenEntries.Add(1)
+	m.writtenBytes.Add(int64(len(key) + len(value) + entryOverhead))
+}
+
+// UsedBytes estimates the bytes held by live entries: entry count times the average entry
+// written. BigCache preallocates up to HardMaxCacheSize, so Capacity alone says little about
+// what is used; entries deleted or overwritten but not yet reclaimed are not counted
+func (l *LocalCache) UsedBytes() int64 {
+	entries := l.memory.writtenEntries.Load()
+	if entries == 0 {
+		return 0
+	}
+	return int64(l.Len()) * (l.memory.writtenBytes.Load() / entries)
+}
+
+// LifeWindow returns the LifeWindow in effect, shorter than configured while auto-tuned
+func (l *LocalCache) LifeWindow() time.Duration {
+	return time.Duration(l.memory.lifeWindow.Load())
+}
+
+// memoryLimit is HardMaxCacheSize in bytes, 0 when unlimited
+func (l *LocalCache) memoryLimit() int64 {
+	return int64(l.config.HardMaxCacheSize) * 1024 * 1024
+}
+
+func (l *LocalCache) monitorMemory() {
+	ticker := time.NewTicker(l.config.MemoryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			l.checkMemory(now)
+		}
+	}
+}
+
+// checkMemory compares usage with the soft limit, logging when it is crossed either way and, with
+// AutoTuneLifeWindow, shrinking or relaxing the LifeWindow
+func (l *LocalCache) checkMemory(now time.Time) {
+	used, limit := l.UsedBytes(), l.memoryLimit()
+	usage := float64(used) / float64(limit)
+
+	if usage >= l.config.MemorySoftLimit {
+		l.memory.calmSince = time.Time{}
+		if !l.memory.underPressure {
+			l.memory.underPressure = true
+			l.memory.alerts.Add(1)
+			log.Printf("[LocalCache:%s] Warning: memory usage ~%dMB is %.0f%% of the %dMB limit (soft limit %.0f%%)",
+				l.name, used/(1024*1024), usage*100, l.config.HardMaxCacheSize, l.config.MemorySoftLimit*100)
+		}
+		if l.config.AutoTuneLifeWindow {
+			if window := max(l.LifeWindow()/2, l.config.MinLifeWindow); window < l.LifeWindow() {
+				l.retune(window, "memory pressure")
+			}
+		}
+		return
+	}
+
+	if l.memory.underPressure {
+		l.memory.underPressure = false
+		log.Printf("[LocalCache:%s] Memory usage back below the soft limit: ~%dMB (%.0f%%)",
+			l.name, used/(1024*1024), usage*100)
+	}
+	if l.memory.calmSince.IsZero() {
+		l.memory.calmSince = now
+	}
+
+	if l.config.AutoTuneLifeWindow && l.LifeWindow() < l.config.LifeWindow &&
+		now.Sub(l.memory.calmSince) >= l.config.RelaxAfter {
+		l.retune(min(l.LifeWindow()*2, l.config.LifeWindow), "usage below the soft limit")
+		l.memory.calmSince = now
+	}
+}
+
+// retune swaps in an empty BigCache with the given LifeWindow; BigCache can't change it in place.
+// Entries are reloaded from Redis or the source as they are read again
+func (l *LocalCache) retune(lifeWindow time.Duration, reason string) {
+	if err := l.rebuild(lifeWindow); err != nil {
+		l.metrics.Errors.Add(1)
+		log.Printf("[LocalCache:%s] Failed to change LifeWindow to %v: %v", l.name, lifeWindow, err)
+		return
+	}
+	l.memory.changes.Add(1)
+	log.Printf("[LocalCache:%s] LifeWindow changed to %v (configured %v) due to %s; cache cleared",
+		l.name, lifeWindow, l.config.LifeWindow, reason)
+}
+
+func (l *LocalCache) rebuild(lifeWindow time.Duration) error {
+	config := l.config
+	config.LifeWindow = lifeWindow
+	config.CleanWindow = min(config.CleanWindow, lifeWindow)
+	// MaxEntriesInWindow only sizes the initial allocation; scale it with the window
+	config.MaxEntriesInWindow = max(int(int64(config.MaxEntriesInWindow)*int64(lifeWindow)/int64(l.config.LifeWindow)), 1)
+
+	next, err := l.newBigCache(&config)
+	if err != nil {
+		return fmt.Errorf("failed to create local cache: %w", err)
+	}
+
+	previous := l.cache.Swap(next)
+	l.memory.lifeWindow.Store(int64(lifeWindow))
+	return previous.Close()
+}
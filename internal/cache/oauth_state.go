@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOAuthStateUnavailable is returned by OAuthStateStore while Redis is off: the callback may
+// reach another instance than the one that started the sign-in
+var ErrOAuthStateUnavailable = errors.New("sign-in state needs Redis, which is unavailable")
+
+// ErrOAuthStateNotFound is returned by OAuthStateStore.Take for a state that expired, was used
+// already or was never issued
+var ErrOAuthStateNotFound = errors.New("unknown or expired sign-in state")
+
+// DefaultOAuthStateTTL is how long a user has to sign in at the provider by default
+const DefaultOAuthStateTTL = 10 * time.Minute
+
+// oauthTakeScript: KEYS[1]=key. Returns the value and deletes it, or "" when there is none
+var oauthTakeScript = NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+  return ''
+end
+redis.call('DEL', KEYS[1])
+return value
+`)
+
+// OAuthStateStore keeps what a sign-in started with (PKCE verifier, provider) under its state
+// parameter until the callback takes it, once, or it expires
+type OAuthStateStore struct {
+	cm  *CacheManager
+	ttl time.Duration
+}
+
+// NewOAuthStateStore creates a store whose states expire after ttl, the time a user has to sign
+// in at the provider
+func (cm *CacheManager) NewOAuthStateStore(ttl time.Duration) *OAuthStateStore {
+	return &OAuthStateStore{cm: cm, ttl: ttl}
+}
+
+// Save stores value under state
+func (s *OAuthStateStore) Save(ctx context.Context, state, value string) error {
+	if s.cm == nil || !s.cm.redisActive() {
+		return ErrOAuthStateUnavailable
+	}
+	if err := s.cm.redis.Set(ctx, s.redisKey(state), value, s.ttl); err != nil {
+		return fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+	return nil
+}
+
+// Take returns the value stored under state and deletes it, so a callback can't be replayed. It
+// fails with ErrOAuthStateNotFound when there is none
+func (s *OAuthStateStore) Take(ctx context.Context, state string) (string, error) {
+	if s.cm == nil || !s.cm.redisActive() {
+		return "", ErrOAuthStateUnavailable
+	}
+	result, err := s.cm.redis.RunScript(ctx, oauthTakeScript, []string{s.redisKey(state)})
+	if err != nil {
+		return "", fmt.Errorf("failed to read sign-in state: %w", err)
+	}
+	value, _ := result.(string)
+	if value == "" {
+		return "", ErrOAuthStateNotFound
+	}
+	return value, nil
+}
+
+// TTL returns how long a sign-in may take
+func (s *OAuthStateStore) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *OAuthStateStore) redisKey(state string) string {
+	return s.cm.config.Keys.Key(EntityOAuthState, state)
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOTPUnavailable is returned by OTPStore while Redis is off: codes must be shared by every
+// instance, so none are issued from a local tier
+var ErrOTPUnavailable = errors.New("one-time codes need Redis, which is unavailable")
+
+// ErrOTPResendTooSoon is returned by OTPStore.Issue while the last code is too recent to replace
+var ErrOTPResendTooSoon = errors.New("a code was sent recently")
+
+// ErrOTPInvalid is returned by OTPStore.Verify for a wrong code; the code stays valid until its
+// attempts run out
+var ErrOTPInvalid = errors.New("invalid code")
+
+// ErrOTPExpired is returned by OTPStore.Verify when no code is pending: it expired, was used, or
+// too many wrong codes were tried
+var ErrOTPExpired = errors.New("no valid code, request a new one")
+
+// OTPConfig holds one-time code settings
+type OTPConfig struct {
+	// TTL is how long a code can be used
+	TTL time.Duration
+
+	// MaxAttempts is how many wrong codes revoke the pending one
+	MaxAttempts int
+
+	// ResendAfter is how long a subject waits before a new code replaces the pending one
+	ResendAfter time.Duration
+}
+
+// DefaultOTPConfig returns sensible defaults: 5 attempts at a 6-digit code give a 1 in 200,000
+// chance to guess it
+func DefaultOTPConfig() *OTPConfig {
+	return &OTPConfig{
+		TTL:         5 * time.Minute,
+		MaxAttempts: 5,
+		ResendAfter: 30 * time.Second,
+	}
+}
+
+// otpIssueScript: KEYS[1]=key ARGV[1]=code hash ARGV[2]=ttl_ms ARGV[3]=resend_after_ms
+// Returns 0 when issued, otherwise the milliseconds until a new code may be issued
+var otpIssueScript = NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+local age = tonumber(ARGV[2]) - ttl
+if ttl > 0 and age < tonumber(ARGV[3]) then
+  return tonumber(ARGV[3]) - age
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'h', ARGV[1], 'a', 0)
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 0
+`)
+
+// otpVerifyScript: KEYS[1]=key ARGV[1]=code hash ARGV[2]=max attempts
+// Returns 1 for a match (the code is consumed), 0 for a wrong code, -1 when none is pending
+var otpVerifyScript = NewScript(`
+local stored = redis.call('HGET', KEYS[1], 'h')
+if not stored then
+  return -1
+end
+if stored == ARGV[1] then
+  redis.call('DEL', KEYS[1])
+  return 1
+end
+if redis.call('HINCRBY', KEYS[1], 'a', 1) >= tonumber(ARGV[2]) then
+  redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// OTPStore keeps one pending one-time code per subject in Redis, hashed, for TTL. A correct code is
+// consumed by the check that accepts it, and MaxAttempts wrong ones revoke it
+type OTPStore struct {
+	cm     *CacheManager
+	scope  string
+	config *OTPConfig
+}
+
+// NewOTPStore creates a store for one purpose; scope keeps codes of different purposes apart,
+// e.g. "login"
+func (cm *CacheManager) NewOTPStore(scope string, config *OTPConfig) *OTPStore {
+	if config == nil {
+		config = DefaultOTPConfig()
+	}
+	return &OTPStore{cm: cm, scope: scope, config: config}
+}
+
+// Issue makes code the pending code of subject, replacing any earlier one. Within ResendAfter of
+// the last one it fails with ErrOTPResendTooSoon and returns how long to wait
+func (s *OTPStore) Issue(ctx context.Context, subject, code string) (time.Duration, error) {
+	if s.cm == nil || !s.cm.redisActive() {
+		return 0, ErrOTPUnavailable
+	}
+	result, err := s.cm.redis.RunScript(ctx, otpIssueScript, []string{s.redisKey(subject)},
+		s.hash(subject, code), s.config.TTL.Milliseconds(), s.config.ResendAfter.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to store code: %w", err)
+	}
+	if wait := toInt64(result); wait > 0 {
+		return time.Duration(wait) * time.Millisecond, ErrOTPResendTooSoon
+	}
+	return 0, nil
+}
+
+// Verify consumes the pending code of subject if it is code. It fails with ErrOTPInvalid for a
+// wrong code and ErrOTPExpired when none is pending
+func (s *OTPStore) Verify(ctx context.Context, subject, code string) error {
+	if s.cm == nil || !s.cm.redisActive() {
+		return ErrOTPUnavailable
+	}
+	result, err := s.cm.redis.RunScript(ctx, otpVerifyScript, []string{s.redisKey(subject)},
+		s.hash(subject, code), s.config.MaxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to check code: %w", err)
+	}
+	switch toInt64(result) {
+	case 1:
+		return nil
+	case 0:
+		return ErrOTPInvalid
+	default:
+		return ErrOTPExpired
+	}
+}
+
+// Revoke drops the pending code of subject, e.g. when it couldn't be delivered
+func (s *OTPStore) Revoke(ctx context.Context, subject string) error {
+	if s.cm == nil || !s.cm.redisActive() {
+		return nil
+	}
+	return s.cm.redis.Delete(ctx, s.redisKey(subject))
+}
+
+// TTL returns how long codes can be used
+func (s *OTPStore) TTL() time.Duration {
+	return s.config.TTL
+}
+
+// hash keeps codes out of Redis in plain text; the subject salts it so equal codes differ
+func (s *OTPStore) hash(subject, code string) string {
+	sum := sha256.Sum256([]byte(subject + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *OTPStore) redisKey(subject string) string {
+	return s.cm.config.Keys.Key(EntityOTP, s.scope, subject)
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQuotaSeedRequired is returned by QuotaCounter.Consume when a counter is missing from Redis
+// (new period, eviction, Redis restart) and no seed was given; retry with the durable usage
+var ErrQuotaSeedRequired = errors.New("quota counter needs a seed")
+
+// quotaConsumeScript: KEYS[i]=counter, ARGV[3i-2]=limit ARGV[3i-1]=expire_at_ms ARGV[3i]=seed
+// Counters missing from Redis start at their seed; a negative seed means unknown and the script
+// returns {-1} without changing anything. The request is counted in every window only if it fits
+// all of them. Returns {allowed, used_1, ..., used_n}
+var quotaConsumeScript = NewScript(`
+local used = {}
+for i, key in ipairs(KEYS) do
+  local current = redis.call('GET', key)
+  if current then
+    used[i] = tonumber(current)
+  else
+    local seed = tonumber(ARGV[3 * i])
+    if seed < 0 then
+      return {-1}
+    end
+    redis.call('SET', key, seed)
+    redis.call('PEXPIREAT', key, ARGV[3 * i - 1])
+    used[i] = seed
+  end
+end
+local allowed = 1
+for i = 1, #KEYS do
+  if used[i] >= tonumber(ARGV[3 * i - 2]) then
+    allowed = 0
+  end
+end
+if allowed == 1 then
+  for i, key in ipairs(KEYS) do
+    used[i] = redis.call('INCR', key)
+  end
+end
+local reply = {allowed}
+for i = 1, #KEYS do
+  reply[i + 1] = used[i]
+end
+return reply
+`)
+
+// QuotaWindow is one quota counter checked by QuotaCounter.Consume
+type QuotaWindow struct {
+	// Key identifies the subject and period bucket, e.g. "{key:ab12}:day:2024-06-01"; windows
+	// consumed together must share a hash tag on Redis Cluster
+	Key   string
+	Limit int64
+	// ExpireAt is when the bucket ends; the counter is dropped from Redis after it
+	ExpireAt time.Time
+	// Seed is the durable usage a missing counter starts from; negative means unknown
+	Seed int64
+}
+
+// QuotaCounterMetrics tracks quota decisions made in Redis
+type QuotaCounterMetrics struct {
+	Allowed atomic.Int64
+	Denied  atomic.Int64
+	Seeded  atomic.Int64
+	Errors  atomic.Int64
+}
+
+// QuotaCounter is the fast path for long-window quotas (daily, monthly): usage counters live in
+// Redis and every check-and-increment is one atomic script call. It is not the source of truth;
+// callers seed counters from durable storage and record usage there themselves
+type QuotaCounter struct {
+	redis   *RedisClient
+	prefix  string
+	metrics *QuotaCounterMetrics
+}
+
+// NewQuotaCounter creates a quota counter on this manager's Redis tier; with Redis disabled every
+// call returns ErrCacheUnavailable
+func (cm *CacheManager) NewQuotaCounter(prefix string) *QuotaCounter {
+	var redisClient *RedisClient
+	if cm.config.EnableRedisCache {
+		redisClient = cm.redis
+	}
+	return &QuotaCounter{
+		redis:   redisClient,
+		prefix:  prefix,
+		metrics: &QuotaCounterMetrics{},
+	}
+}
+
+// Consume counts one request against every window if it fits all of them, and returns the usage
+// of each window after the call
+func (q *QuotaCounter) Consume(ctx context.Context, windows []QuotaWindow) (bool, []int64, error) {
+	if q == nil || q.redis == nil {
+		return false, nil, fmt.Errorf("%w: redis not configured", ErrCacheUnavailable)
+	}
+
+	keys := make([]string, len(windows))
+	args := make([]any, 0, 3*len(windows))
+	for i, window := range windows {
+		keys[i] = q.prefix + window.Key
+		args = append(args,
+			strconv.FormatInt(window.Limit, 10),
+			strconv.FormatInt(window.ExpireAt.UnixMilli(), 10),
+			strconv.FormatInt(window.Seed, 10))
+	}
+
+	result, err := q.redis.RunScript(ctx, quotaConsumeScript, keys, args...)
+	if err != nil {
+		q.metrics.Errors.Add(1)
+		log.Printf("[QuotaCounter] Script failed for %v: %v", keys, err)
+		return false, nil, err
+	}
+
+	values, ok := result.([]any)
+	if !ok || len(values) == 0 {
+		q.metrics.Errors.Add(1)
+		return false, nil, fmt.Errorf("unexpected quota script result: %v", result)
+	}
+	if toInt64(values[0]) < 0 {
+		q.metrics.Seeded.Add(1)
+		return false, nil, ErrQuotaSeedRequired
+	}
+	if len(values) != len(windows)+1 {
+		q.metrics.Errors.Add(1)
+		return false, nil, fmt.Errorf("unexpected quota script result: %v", result)
+	}
+
+	used := make([]int64, len(windows))
+	for i := range windows {
+		used[i] = toInt64(values[i+1])
+	}
+
+	allowed := toInt64(values[0]) == 1
+	if allowed {
+		q.metrics.Allowed.Add(1)
+	} else {
+		q.metrics.Denied.Add(1)
+	}
+	return allowed, used, nil
+}
+
+// Reset drops counters so they are re-seeded from durable storage on the next Consume
+func (q *QuotaCounter) Reset(ctx context.Context, keys ...string) error {
+	if q == nil || q.redis == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if err := q.redis.Delete(ctx, q.prefix+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMetrics returns current quota counter metrics
+func (q *QuotaCounter) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"allowed": q.metrics.Allowed.Load(),
+		"denied":  q.metrics.Denied.Load(),
+		"seeded":  q.metrics.Seeded.Load(),
+		"errors":  q.metrics.Errors.Load(),
+	}
+}
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// RateLimitAlgorithm selects how requests are counted
+type RateLimitAlgorithm string
+
+const (
+	// FixedWindow counts requests per window aligned to the Redis clock (cheapest, allows bursts at
+	// window edges)
+	FixedWindow RateLimitAlgorithm = "fixed_window"
+	// SlidingWindow counts requests in the trailing window using a sorted-set log (exact)
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+	// TokenBucket refills limit tokens per window and allows bursts up to limit
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+)
+
+// All scripts read the clock from Redis (TIME) so instances with skewed clocks agree
+
+// fixedWindowScript: KEYS[1]=key ARGV[1]=limit ARGV[2]=window_ms
+// Windows start at multiples of window_ms since the epoch: the counter expires at the end of the
+// window it was created in, so every instance resets it at the same instant. A counter left
+// without a TTL (e.g. a lost PEXPIRE) is re-armed rather than counting forever
+// Returns {allowed, remaining, retry_after_ms}
+var fixedWindowScript = NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local current = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+  ttl = window - now % window
+  redis.call('PEXPIRE', KEYS[1], ttl)
+end
+if current <= limit then
+  return {1, limit - current, 0}
+end
+return {0, 0, ttl}
+`)
+
+// slidingWindowScript: KEYS[1]=key ARGV[1]=limit ARGV[2]=window_ms ARGV[3]=unique member
+var slidingWindowScript = NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[3])
+  redis.call('PEXPIRE', KEYS[1], window)
+  return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, window - (now - tonumber(oldest[2]))}
+`)
+
+// tokenBucketScript: KEYS[1]=key ARGV[1]=capacity ARGV[2]=window_ms (time to refill capacity)
+var tokenBucketScript = NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local rate = capacity / window
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// RateLimitResult describes the outcome of an Allow call
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	RetryAfter time.Duration // Zero when allowed
+}
+
+// RateLimiterConfig holds rate limiter configuration
+type RateLimiterConfig struct {
+	// Algorithm selects the counting strategy
+	Algorithm RateLimitAlgorithm
+
+	// Prefix namespaces limiter keys in Redis
+	Prefix string
+
+	// FailOpen allows requests when Redis is unavailable instead of rejecting them
+	FailOpen bool
+
+	// Name for logging
+	Name string
+}
+
+// DefaultRateLimiterConfig returns sensible production defaults
+func DefaultRateLimiterConfig() *RateLimiterConfig {
+	return &RateLimiterConfig{
+		Algorithm: SlidingWindow,
+		Prefix:    "ratelimit:",
+		FailOpen:  true, // Consistent with cache graceful degradation
+		Name:      "default",
+	}
+}
+
+// RateLimiterMetrics tracks limiter decisions; sharded, as every request makes one
+type RateLimiterMetrics struct {
+	Allowed Counter
+	Denied  Counter
+	Errors  Counter
+}
+
+// RateLimiter is a Redis-backed distributed rate limiter shared by HTTP, gRPC and auth code
+// Each decision is a single atomic Lua script call
+type RateLimiter struct {
+	redis   *RedisClient
+	config  *RateLimiterConfig
+	metrics *RateLimiterMetrics
+
+	keyPrefix string // Prefix and algorithm of every key, joined once instead of per call
+}
+
+// NewRateLimiter creates a rate limiter; a nil Redis client applies the FailOpen policy to every call
+func NewRateLimiter(redisClient *RedisClient, config *RateLimiterConfig) *RateLimiter {
+	if config == nil {
+		config = DefaultRateLimiterConfig()
+	}
+
+	log.Printf("[RateLimiter:%s] Initialized - Algorithm: %s, FailOpen: %v",
+		config.Name, config.Algorithm, config.FailOpen)
+
+	return &RateLimiter{
+		redis:     redisClient,
+		config:    config,
+		metrics:   &RateLimiterMetrics{},
+		keyPrefix: config.Prefix + string(config.Algorithm) + ":",
+	}
+}
+
+// Allow records one request for key and reports whether it fits in limit per window
+func (rl *RateLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*RateLimitResult, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, fmt.Errorf("rate limit and window must be positive")
+	}
+
+	if rl.redis == nil {
+		return rl.unavailable(limit, fmt.Errorf("%w: redis not configured", ErrCacheUnavailable))
+	}
+
+	ctx, cancel := rl.redis.withTimeout(ctx)
+	defer cancel()
+
+	redisKey := rl.keyPrefix + key
+	windowMs := strconv.FormatInt(window.Milliseconds(), 10)
+	limitArg := strconv.FormatInt(limit, 10)
+
+	var values []any
+	var err error
+
+	switch rl.config.Algorithm {
+	case FixedWindow:
+		values, err = rl.run(ctx, fixedWindowScript, []string{redisKey}, limitArg, windowMs)
+	case SlidingWindow:
+		member := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+		values, err = rl.run(ctx, slidingWindowScript, []string{redisKey}, limitArg, windowMs, member)
+	case TokenBucket:
+		values, err = rl.run(ctx, tokenBucketScript, []string{redisKey}, limitArg, windowMs)
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", rl.config.Algorithm)
+	}
+
+	if err != nil {
+		log.Printf("[RateLimiter:%s] Script failed for key '%s': %v", rl.config.Name, key, err)
+		return rl.unavailable(limit, err)
+	}
+
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	result := &RateLimitResult{
+		Allowed:    toInt64(values[0]) == 1,
+		Limit:      limit,
+		Remaining:  toInt64(values[1]),
+		RetryAfter: time.Duration(toInt64(values[2])) * time.Millisecond,
+	}
+
+	if result.Allowed {
+		rl.metrics.Allowed.Add(1)
+	} else {
+		rl.metrics.Denied.Add(1)
+	}
+
+	return result, nil
+}
+
+// run executes a limiter script and expects a {allowed, remaining, retry_after_ms} reply
+func (rl *RateLimiter) run(ctx context.Context, script *Script, keys []string, args ...any) ([]any, error) {
+	result, err := rl.redis.RunScript(ctx, script, keys, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+	return values, nil
+}
+
+// unavailable applies the fail-open/fail-closed policy when Redis can't decide
+func (rl *RateLimiter) unavailable(limit int64, err error) (*RateLimitResult, error) {
+	rl.metrics.Errors.Add(1)
+
+	if rl.config.FailOpen {
+		rl.metrics.Allowed.Add(1)
+		return &RateLimitResult{Allowed: true, Limit: limit, Remaining: limit}, nil
+	}
+
+	rl.metrics.Denied.Add(1)
+	return &RateLimitResult{Allowed: false, Limit: limit}, err
+}
+
+// GetMetrics returns current limiter metrics
+func (rl *RateLimiter) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"allowed": rl.metrics.Allowed.Load(),
+		"denied":  rl.metrics.Denied.Load(),
+		"errors":  rl.metrics.Errors.Load(),
+	}
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case string:
+		parsed, _ := strconv.ParseInt(v, 10, 64)
+		return parsed
+	default:
+		return 0
+	}
+}
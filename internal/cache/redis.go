@@ -1,10 +1,12 @@
 package cache
 
 import (
+	"acid/internal/safego"
 	"context"
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,15 +21,51 @@ var (
 )
 
 type RedisClient struct {
-	client  *redis.Client
-	metrics *CacheMetrics
+	client    atomic.Pointer[redis.Client] // Swapped by pool auto-tuning and failover reconnects
+	pubsub    *redis.Client                // The first client; subscriptions stay on it
+	options   *redis.Options
+	config    *RedisConfig
+	metrics   *CacheMetrics
+	scripts   sync.Map // sha -> source, used to re-register scripts on NOSCRIPT
+	opTimeout time.Duration
+	pool      poolState
+	failover  failoverState
+	stop      chan struct{}
 }
 
 // CacheMetrics tracks cache performance for observability
 type CacheMetrics struct {
-	Hits   atomic.Int64
-	Misses atomic.Int64
-	Errors atomic.Int64
+	Hits   Counter
+	Misses Counter
+	Errors Counter
+}
+
+// RedisSnapshot is a point-in-time copy of the Redis client metrics, filled by Snapshot
+type RedisSnapshot struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+
+	// Connection pool gauges, as of the last pool check
+	PoolSize        int64 `json:"pool_size"`
+	PoolConns       int64 `json:"pool_conns"`
+	PoolIdleConns   int64 `json:"pool_idle_conns"`
+	PoolUtilization int64 `json:"pool_utilization_pct"` // Connections in use, percent of PoolSize
+	PoolWaitMicros  int64 `json:"pool_wait_us"`         // Mean wait of calls that waited for a connection
+	PoolTimeouts    int64 `json:"pool_timeouts"`
+	PoolAlerts      int64 `json:"pool_alerts"`
+	PoolResizes     int64 `json:"pool_resizes"`
+
+	FailoverErrors    int64 `json:"failover_errors"` // Commands still failing with a failover error after go-redis's retries
+	FailoverRetries   int64 `json:"failover_retries"`
+	FailoverRecovered int64 `json:"failover_recovered"`
+	Reconnects        int64 `json:"reconnects"`
+	DialErrors        int64 `json:"dial_errors"`
+}
+
+// HitRate returns the hit rate of the snapshot as a percentage
+func (s *RedisSnapshot) HitRate() float64 {
+	return hitRate(s.Hits, s.Misses)
 }
 
 // RedisConfig holds production-ready Redis configuration
@@ -42,6 +80,39 @@ type RedisConfig struct {
 	DialTimeout  time.Duration // Timeout for establishing connections
 	ReadTimeout  time.Duration // Timeout for socket reads
 	WriteTimeout time.Duration // Timeout for socket writes
+	OpTimeout    time.Duration // Upper bound per operation; the caller's deadline applies when sooner
+
+	// PoolCheckInterval is how often pool utilization and wait times are sampled; 0 disables
+	// the pool monitor
+	PoolCheckInterval time.Duration
+
+	// PoolSaturation is the share of PoolSize in use above which the pool counts as saturated
+	// and a warning is logged
+	PoolSaturation float64
+
+	// PoolWaitThreshold is the mean wait for a connection above which the pool counts as
+	// saturated even below PoolSaturation
+	PoolWaitThreshold time.Duration
+
+	// PoolAutoTune grows PoolSize by half while the pool is saturated, up to MaxPoolSize
+	PoolAutoTune bool
+	MaxPoolSize  int
+
+	// PasswordFile, when set, is read for the password at every new connection, so a rotated
+	// secret is used once a failover or NOAUTH reconnects; Password is the fallback
+	PasswordFile string
+
+	// FailoverRetry retries idempotent commands failing with failover errors (READONLY, LOADING,
+	// MASTERDOWN, ...) after go-redis's own retries, backing off from FailoverBackoff up to
+	// FailoverMaxBackoff for at most FailoverTimeout; the operation's deadline still applies
+	FailoverRetry      bool
+	FailoverBackoff    time.Duration
+	FailoverMaxBackoff time.Duration
+	FailoverTimeout    time.Duration
+
+	// ReconnectInterval is the least time between two reconnects triggered by errors that new
+	// connections fix (READONLY, MOVED, NOAUTH)
+	ReconnectInterval time.Duration
 }
 
 // DefaultRedisConfig returns sensible production defaults
@@ -57,6 +128,18 @@ func DefaultRedisConfig() *RedisConfig {
 		DialTimeout:  5 * time.Second,
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 3 * time.Second,
+		OpTimeout:    2 * time.Second,
+
+		PoolCheckInterval: 30 * time.Second,
+		PoolSaturation:    0.9,
+		PoolWaitThreshold: 5 * time.Millisecond,
+		MaxPoolSize:       100,
+
+		FailoverRetry:      true,
+		FailoverBackoff:    50 * time.Millisecond,
+		FailoverMaxBackoff: 500 * time.Millisecond,
+		FailoverTimeout:    5 * time.Second,
+		ReconnectInterval:  1 * time.Second,
 	}
 }
 
@@ -67,7 +150,7 @@ func NewRedisClient(config *RedisConfig) (*RedisClient, error) {
 	}
 
 	// Create Redis client with production settings
-	client := redis.NewClient(&redis.Options{
+	options := &redis.Options{
 		Addr:         config.Host + ":" + config.Port,
 		Password:     config.Password,
 		DB:           config.DB,
@@ -78,10 +161,25 @@ func NewRedisClient(config *RedisConfig) (*RedisClient, error) {
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
 
+		// Socket deadlines follow the context, so a caller's deadline cuts a slow read short
+		// instead of waiting out ReadTimeout (and the retries behind it)
+		ContextTimeoutEnabled: true,
+
 		// Production optimizations
 		PoolTimeout:  4 * time.Second,
 		MaxIdleConns: 5,
-	})
+	}
+	if config.PasswordFile != "" {
+		options.CredentialsProvider = passwordFromFile(config.PasswordFile, config.Password)
+	}
+
+	r := &RedisClient{
+		options: options,
+		config:  config,
+		metrics: &CacheMetrics{},
+		stop:    make(chan struct{}),
+	}
+	client := r.newClient(options)
 
 	// CRITICAL: Validate connection before returning (fail fast)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -96,22 +194,47 @@ func NewRedisClient(config *RedisConfig) (*RedisClient, error) {
 	log.Printf("[Redis] Successfully connected to %s:%s (DB: %d)",
 		config.Host, config.Port, config.DB)
 
-	return &RedisClient{
-		client:  client,
-		metrics: &CacheMetrics{},
-	}, nil
+	opTimeout := config.OpTimeout
+	if opTimeout <= 0 {
+		opTimeout = DefaultRedisConfig().OpTimeout
+	}
+
+	r.pubsub, r.opTimeout = client, opTimeout
+	r.client.Store(client)
+	r.pool.size.Store(int64(config.PoolSize))
+	if config.PoolCheckInterval > 0 {
+		safego.Go("redis.pool_monitor", r.monitorPool, safego.Restart(r.stop))
+	}
+	return r, nil
 }
 
-// Set stores a value with TTL - accepts context for proper timeout/cancellation
-func (r *RedisClient) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
-	// Ensure we have a context with timeout
+// newClient creates a client with the failover hook
+func (r *RedisClient) newClient(options *redis.Options) *redis.Client {
+	client := redis.NewClient(options)
+	client.AddHook(failoverHook{r: r})
+	return client
+}
+
+// rdb returns the client commands go through
+func (r *RedisClient) rdb() *redis.Client {
+	return r.client.Load()
+}
+
+// withTimeout bounds one operation by OpTimeout or the caller's remaining deadline, whichever
+// is sooner; a nil ctx gets OpTimeout alone
+func (r *RedisClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+		ctx = context.Background()
 	}
+	return context.WithTimeout(ctx, r.opTimeout)
+}
+
+// Set stores a value with TTL - accepts context for proper timeout/cancellation
+func (r *RedisClient) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	err := r.client.Set(ctx, key, value, ttl).Err()
+	err := r.rdb().Set(ctx, key, value, ttl).Err()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] SET failed for key '%s': %v", key, err)
@@ -123,14 +246,10 @@ func (r *RedisClient) Set(ctx context.Context, key string, value any, ttl time.D
 
 // Get retrieves a value - properly distinguishes cache miss from errors
 func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	// Ensure we have a context with timeout
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	val, err := r.client.Get(ctx, key).Result()
+	val, err := r.rdb().Get(ctx, key).Result()
 	if err != nil {
 		// Cache miss is NOT an error - it's an expected case
 		if errors.Is(err, redis.Nil) {
@@ -148,15 +267,35 @@ func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
 	return val, nil
 }
 
+// MGet retrieves several values in one round trip; missing keys are left out of the result
+func (r *RedisClient) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	vals, err := r.rdb().MGet(ctx, keys...).Result()
+	if err != nil {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] MGET of %d keys failed: %v", len(keys), err)
+		return nil, fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	found := make(map[string]string, len(keys))
+	for i, val := range vals {
+		if s, ok := val.(string); ok {
+			found[keys[i]] = s
+		}
+	}
+	r.metrics.Hits.Add(int64(len(found)))
+	r.metrics.Misses.Add(int64(len(keys) - len(found)))
+	return found, nil
+}
+
 // Exists checks if a key exists - useful for email uniqueness checks
 func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	count, err := r.client.Exists(ctx, key).Result()
+	count, err := r.rdb().Exists(ctx, key).Result()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] EXISTS failed for key '%s': %v", key, err)
@@ -176,13 +315,10 @@ func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
 // Returns true if key was set, false if it already existed
 // PERFECT for email uniqueness - no race conditions!
 func (r *RedisClient) SetNX(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	success, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	success, err := r.rdb().SetNX(ctx, key, value, ttl).Result()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] SETNX failed for key '%s': %v", key, err)
@@ -200,13 +336,10 @@ func (r *RedisClient) SetNX(ctx context.Context, key string, value any, ttl time
 
 // Delete removes a key from cache
 func (r *RedisClient) Delete(ctx context.Context, key string) error {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	err := r.client.Del(ctx, key).Err()
+	err := r.rdb().Del(ctx, key).Err()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] DELETE failed for key '%s': %v", key, err)
@@ -218,13 +351,10 @@ func (r *RedisClient) Delete(ctx context.Context, key string) error {
 
 // Incr atomically increments a counter - useful for rate limiting
 func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	val, err := r.client.Incr(ctx, key).Result()
+	val, err := r.rdb().Incr(ctx, key).Result()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] INCR failed for key '%s': %v", key, err)
@@ -236,13 +366,10 @@ func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
 
 // Expire sets a timeout on a key - useful with Incr for rate limiting
 func (r *RedisClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	err := r.client.Expire(ctx, key, ttl).Err()
+	err := r.rdb().Expire(ctx, key, ttl).Err()
 	if err != nil {
 		r.metrics.Errors.Add(1)
 		log.Printf("[Redis] EXPIRE failed for key '%s': %v", key, err)
@@ -252,37 +379,151 @@ func (r *RedisClient) Expire(ctx context.Context, key string, ttl time.Duration)
 	return nil
 }
 
-// GetMetrics returns current cache performance metrics
+// SAdd adds members to a set
+func (r *RedisClient) SAdd(ctx context.Context, key string, members ...any) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := r.rdb().SAdd(ctx, key, members...).Err(); err != nil {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] SADD failed for key '%s': %v", key, err)
+		return fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+	return nil
+}
+
+// SRem removes members from a set
+func (r *RedisClient) SRem(ctx context.Context, key string, members ...any) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := r.rdb().SRem(ctx, key, members...).Err(); err != nil {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] SREM failed for key '%s': %v", key, err)
+		return fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+	return nil
+}
+
+// Pipeline queues the commands issued in fn and sends them in a single round trip
+// Commands are not atomic - use TxPipeline when all-or-nothing semantics are required
+func (r *RedisClient) Pipeline(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cmds, err := r.rdb().Pipelined(ctx, fn)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] PIPELINE of %d commands failed: %v", len(cmds), err)
+		return cmds, fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	return cmds, nil
+}
+
+// TxPipeline queues the commands issued in fn and executes them atomically with MULTI/EXEC
+func (r *RedisClient) TxPipeline(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	cmds, err := r.rdb().TxPipelined(ctx, fn)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] MULTI/EXEC of %d commands failed: %v", len(cmds), err)
+		return cmds, fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	return cmds, nil
+}
+
+// Publish sends message to every subscriber of channel
+func (r *RedisClient) Publish(ctx context.Context, channel string, message any) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := r.rdb().Publish(ctx, channel, message).Err(); err != nil {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] PUBLISH to '%s' failed: %v", channel, err)
+		return fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+	return nil
+}
+
+// Subscribe listens on channel; the subscription reconnects by itself until closed
+func (r *RedisClient) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	return r.pubsub.Subscribe(ctx, channel)
+}
+
+// Snapshot fills s with the current cache performance metrics
+func (r *RedisClient) Snapshot(s *RedisSnapshot) {
+	*s = RedisSnapshot{
+		Hits:   r.metrics.Hits.Load(),
+		Misses: r.metrics.Misses.Load(),
+		Errors: r.metrics.Errors.Load(),
+
+		PoolSize:        r.pool.size.Load(),
+		PoolConns:       r.pool.conns.Load(),
+		PoolIdleConns:   r.pool.idle.Load(),
+		PoolUtilization: r.pool.utilization.Load(),
+		PoolWaitMicros:  r.pool.waitMicros.Load(),
+		PoolTimeouts:    r.pool.timeouts.Load(),
+		PoolAlerts:      r.pool.alerts.Load(),
+		PoolResizes:     r.pool.resizes.Load(),
+
+		FailoverErrors:    r.failover.errors.Load(),
+		FailoverRetries:   r.failover.retries.Load(),
+		FailoverRecovered: r.failover.recovered.Load(),
+		Reconnects:        r.failover.reconnects.Load(),
+		DialErrors:        r.failover.dialErrors.Load(),
+	}
+}
+
+// GetMetrics returns current cache performance metrics as a map. Prefer Snapshot on hot scrape
+// paths; this allocates
 func (r *RedisClient) GetMetrics() map[string]int64 {
+	var s RedisSnapshot
+	r.Snapshot(&s)
+
 	return map[string]int64{
-		"hits":   r.metrics.Hits.Load(),
-		"misses": r.metrics.Misses.Load(),
-		"errors": r.metrics.Errors.Load(),
+		"hits":   s.Hits,
+		"misses": s.Misses,
+		"errors": s.Errors,
+
+		"pool_size":            s.PoolSize,
+		"pool_conns":           s.PoolConns,
+		"pool_idle_conns":      s.PoolIdleConns,
+		"pool_utilization_pct": s.PoolUtilization,
+		"pool_wait_us":         s.PoolWaitMicros,
+		"pool_timeouts":        s.PoolTimeouts,
+		"pool_alerts":          s.PoolAlerts,
+		"pool_resizes":         s.PoolResizes,
+
+		"failover_errors":    s.FailoverErrors,
+		"failover_retries":   s.FailoverRetries,
+		"failover_recovered": s.FailoverRecovered,
+		"reconnects":         s.Reconnects,
+		"dial_errors":        s.DialErrors,
 	}
 }
 
+// ResetMetrics zeroes all counters
+func (r *RedisClient) ResetMetrics() {
+	r.metrics.Hits.Reset()
+	r.metrics.Misses.Reset()
+	r.metrics.Errors.Reset()
+}
+
 // GetHitRate calculates cache hit rate as a percentage
 func (r *RedisClient) GetHitRate() float64 {
-	hits := r.metrics.Hits.Load()
-	misses := r.metrics.Misses.Load()
-	total := hits + misses
-
-	if total == 0 {
-		return 0.0
-	}
-
-	return float64(hits) / float64(total) * 100.0
+	return hitRate(r.metrics.Hits.Load(), r.metrics.Misses.Load())
 }
 
 // HealthCheck verifies Redis is responsive - critical for health endpoints
 func (r *RedisClient) HealthCheck(ctx context.Context) error {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	if err := r.client.Ping(ctx).Err(); err != nil {
+	if err := r.rdb().Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("redis health check failed: %w", err)
 	}
 
@@ -291,17 +532,21 @@ func (r *RedisClient) HealthCheck(ctx context.Context) error {
 
 // GetPoolStats returns connection pool statistics for monitoring
 func (r *RedisClient) GetPoolStats() *redis.PoolStats {
-	return r.client.PoolStats()
+	return r.rdb().PoolStats()
 }
 
 // Close gracefully closes the Redis connection with final stats logging
 func (r *RedisClient) Close() error {
-	hits := r.metrics.Hits.Load()
-	misses := r.metrics.Misses.Load()
-	errors := r.metrics.Errors.Load()
+	var s RedisSnapshot
+	r.Snapshot(&s)
 
 	log.Printf("[Redis] Closing connection. Final stats - Hits: %d, Misses: %d, Errors: %d, Hit Rate: %.2f%%",
-		hits, misses, errors, r.GetHitRate())
+		s.Hits, s.Misses, s.Errors, s.HitRate())
 
-	return r.client.Close()
+	close(r.stop)
+	client := r.rdb()
+	if client != r.pubsub {
+		_ = client.Close()
+	}
+	return r.pubsub.Close()
 }
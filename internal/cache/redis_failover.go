@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// failoverState counts failover errors and what was done about them
+type failoverState struct {
+	errors        atomic.Int64 // Commands that failed with a failover error after go-redis's own retries
+	retries       atomic.Int64
+	recovered     atomic.Int64 // Commands a retry completed
+	reconnects    atomic.Int64
+	dialErrors    atomic.Int64
+	lastReconnect atomic.Int64 // Unix nanos
+}
+
+// failoverKind classifies an error returned while a primary fails over
+type failoverKind int
+
+const (
+	notFailover failoverKind = iota
+
+	// failoverWait: the server is mid-transition and will answer once it is done (LOADING,
+	// MASTERDOWN, TRYAGAIN, CLUSTERDOWN); waiting is enough
+	failoverWait
+
+	// failoverReconnect: the connections reach the wrong node or lost their authentication
+	// (READONLY, MOVED, NOAUTH, WRONGPASS); only new connections help
+	failoverReconnect
+)
+
+func classifyFailover(err error) failoverKind {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		return notFailover
+	}
+	msg := redisErr.Error()
+	for _, prefix := range []string{"LOADING ", "MASTERDOWN ", "TRYAGAIN ", "CLUSTERDOWN "} {
+		if strings.HasPrefix(msg, prefix) {
+			return failoverWait
+		}
+	}
+	for _, prefix := range []string{"READONLY ", "MOVED ", "NOAUTH ", "WRONGPASS "} {
+		if strings.HasPrefix(msg, prefix) {
+			return failoverReconnect
+		}
+	}
+	return notFailover
+}
+
+// idempotentCommands can run twice with the same effect as once. Only they are retried: the
+// failover errors mean the server refused the command, but a retry racing a recovering primary
+// must not apply an increment or a script twice
+var idempotentCommands = map[string]bool{
+	"ping": true, "echo": true,
+	"get": true, "mget": true, "exists": true, "ttl": true, "pttl": true, "strlen": true, "type": true,
+	"del": true, "unlink": true, "expire": true, "pexpire": true, "persist": true,
+	"hget": true, "hmget": true, "hgetall": true, "hexists": true, "hset": true, "hdel": true,
+	"sadd": true, "srem": true, "smembers": true, "sismember": true, "scard": true,
+	"zscore": true, "zcard": true, "zrange": true, "zrem": true,
+	"evalsha_ro": true, "eval_ro": true,
+}
+
+// isIdempotent reports whether cmd is safe to retry. SET is, unless NX/XX/GET make its result
+// depend on the previous value
+func isIdempotent(cmd redis.Cmder) bool {
+	name := cmd.Name()
+	if name != "set" {
+		return idempotentCommands[name]
+	}
+	args := cmd.Args()
+	for _, arg := range args[min(3, len(args)):] {
+		if option, ok := arg.(string); ok {
+			switch strings.ToLower(option) {
+			case "nx", "xx", "get":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// retryingKey marks a context whose command is already being retried, so the retry doesn't
+// nest another one when it goes through a freshly connected client
+type retryingKey struct{}
+
+// failoverHook rides out a primary failover. go-redis already retries these errors a few times
+// within a second, on the same connections; a failover takes longer and often needs new ones.
+// The hook counts dials, and for a command that still fails with a failover error it
+// reconnects when the connections are at fault, then retries idempotent commands with backoff
+// until FailoverTimeout or the command's deadline. Pipelines are not retried, as their commands
+// may have partly run; they only trigger the reconnect
+type failoverHook struct {
+	r *RedisClient
+}
+
+func (h failoverHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			h.r.failover.dialErrors.Add(1)
+		}
+		return conn, err
+	}
+}
+
+func (h failoverHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		kind := classifyFailover(err)
+		if kind == notFailover || ctx.Value(retryingKey{}) != nil {
+			return err
+		}
+
+		h.r.failover.errors.Add(1)
+		if kind == failoverReconnect {
+			h.r.reconnect(err)
+		}
+		if !h.r.config.FailoverRetry || !isIdempotent(cmd) {
+			return err
+		}
+		return h.r.retryFailover(ctx, cmd, err)
+	}
+}
+
+func (h failoverHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			if kind := classifyFailover(cmd.Err()); kind != notFailover {
+				h.r.failover.errors.Add(1)
+				if kind == failoverReconnect {
+					h.r.reconnect(cmd.Err())
+				}
+				break
+			}
+		}
+		return err
+	}
+}
+
+// retryFailover re-runs cmd on the current client with exponential backoff while it keeps
+// failing with failover errors
+func (r *RedisClient) retryFailover(ctx context.Context, cmd redis.Cmder, err error) error {
+	deadline := time.Now().Add(r.config.FailoverTimeout)
+	ctx = context.WithValue(ctx, retryingKey{}, true)
+	backoff := max(r.config.FailoverBackoff, time.Millisecond)
+
+	for {
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		r.failover.retries.Add(1)
+		cmd.SetErr(nil)
+		err = r.rdb().Process(ctx, cmd)
+		kind := classifyFailover(err)
+		if kind == notFailover {
+			if err == nil || errors.Is(err, redis.Nil) {
+				r.failover.recovered.Add(1)
+			}
+			return err
+		}
+		if kind == failoverReconnect {
+			r.reconnect(err)
+		}
+		backoff = min(backoff*2, r.config.FailoverMaxBackoff)
+	}
+}
+
+// reconnect replaces the client so every command gets a new connection, dialed to wherever the
+// address now resolves and authenticated with the current credentials. Concurrent failures
+// reconnect once per ReconnectInterval
+func (r *RedisClient) reconnect(cause error) {
+	now := time.Now().UnixNano()
+	last := r.failover.lastReconnect.Load()
+	if now-last < int64(r.config.ReconnectInterval) || !r.failover.lastReconnect.CompareAndSwap(last, now) {
+		return
+	}
+
+	r.failover.reconnects.Add(1)
+	r.replaceClient(int(r.pool.size.Load()))
+	log.Printf("[Redis] Reconnecting after %v", cause)
+}
+
+// passwordFromFile returns a credentials provider reading the password from path at every dial,
+// so a rotated secret is picked up by the next connection; fallback is used when it can't be read
+func passwordFromFile(path, fallback string) func() (string, string) {
+	return func() (string, string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[Redis] Failed to read the password file, using the configured password: %v", err)
+			return "", fallback
+		}
+		return "", strings.TrimSpace(string(data))
+	}
+}
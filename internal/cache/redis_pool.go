@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// poolState holds the pool gauges of the last check and what the monitor needs between checks
+type poolState struct {
+	size        atomic.Int64 // PoolSize of the client in use
+	conns       atomic.Int64
+	idle        atomic.Int64
+	utilization atomic.Int64 // Connections in use, percent of size
+	waitMicros  atomic.Int64 // Mean wait of the calls that waited since the previous check
+	timeouts    atomic.Int64 // Calls that gave up waiting for a connection, all time
+	alerts      atomic.Int64 // Times the pool became saturated
+	resizes     atomic.Int64 // Pool growths made by auto-tuning
+
+	// Only touched by the monitor goroutine
+	last      redis.PoolStats // Cumulative stats at the previous check, for the deltas
+	client    *redis.Client   // The client last was read from; a replaced one starts from zero
+	saturated bool
+}
+
+func (r *RedisClient) monitorPool() {
+	ticker := time.NewTicker(r.config.PoolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkPool()
+		}
+	}
+}
+
+// checkPool samples the pool, warning when it becomes saturated and, with PoolAutoTune, growing it.
+// The pool counts as saturated when most connections are in use, when calls waited too long for
+// one on average, or when any call timed out waiting
+func (r *RedisClient) checkPool() {
+	client := r.rdb()
+	stats := client.PoolStats()
+	last := r.pool.last
+	if client != r.pool.client {
+		last = redis.PoolStats{}
+	}
+	r.pool.last, r.pool.client = *stats, client
+
+	size := r.pool.size.Load()
+	inUse := int64(stats.TotalConns) - int64(stats.IdleConns)
+	waits := int64(stats.WaitCount - last.WaitCount)
+	timeouts := int64(stats.Timeouts - last.Timeouts)
+	var wait time.Duration
+	if waits > 0 {
+		wait = time.Duration((stats.WaitDurationNs - last.WaitDurationNs) / waits)
+	}
+
+	r.pool.conns.Store(int64(stats.TotalConns))
+	r.pool.idle.Store(int64(stats.IdleConns))
+	r.pool.utilization.Store(inUse * 100 / max(size, 1))
+	r.pool.waitMicros.Store(wait.Microseconds())
+	r.pool.timeouts.Add(timeouts)
+
+	saturated := float64(inUse) >= r.config.PoolSaturation*float64(size) ||
+		(waits > 0 && wait >= r.config.PoolWaitThreshold) || timeouts > 0
+	if !saturated {
+		if r.pool.saturated {
+			r.pool.saturated = false
+			log.Printf("[Redis] Connection pool no longer saturated: %d/%d connections in use", inUse, size)
+		}
+		return
+	}
+
+	if !r.pool.saturated {
+		r.pool.saturated = true
+		r.pool.alerts.Add(1)
+		log.Printf("[Redis] Warning: connection pool saturated: %d/%d connections in use, mean wait %v, %d timeouts since the last check",
+			inUse, size, wait, timeouts)
+		switch {
+		case !r.config.PoolAutoTune:
+			log.Printf("[Redis] Consider raising the pool size (REDIS_POOL_SIZE, now %d) or enabling REDIS_POOL_AUTOTUNE", size)
+		case size >= int64(r.config.MaxPoolSize):
+			log.Printf("[Redis] Pool is at its auto-tuning maximum of %d; consider raising REDIS_POOL_MAX_SIZE", size)
+		}
+	}
+
+	if r.config.PoolAutoTune && size < int64(r.config.MaxPoolSize) {
+		r.resizePool(int(min(size+max(size/2, 1), int64(r.config.MaxPoolSize))))
+	}
+}
+
+// resizePool swaps in a client with a pool of size connections; go-redis can't resize a pool in
+// place
+func (r *RedisClient) resizePool(size int) {
+	r.replaceClient(size)
+	r.pool.resizes.Add(1)
+	log.Printf("[Redis] Connection pool grown to %d connections (configured %d, max %d)",
+		size, r.config.PoolSize, r.config.MaxPoolSize)
+}
+
+// replaceClient swaps in a new client with a pool of size connections. Commands already running
+// finish on the previous client, which is closed once they can't be waiting anymore. The first
+// client is kept, as subscriptions live on it
+func (r *RedisClient) replaceClient(size int) {
+	options := *r.options
+	options.PoolSize = size
+	previous := r.client.Swap(r.newClient(&options))
+	r.pool.size.Store(int64(size))
+
+	if previous != r.pubsub {
+		drain := options.PoolTimeout + options.ReadTimeout + options.WriteTimeout
+		time.AfterFunc(drain, func() {
+			if err := previous.Close(); err != nil {
+				log.Printf("[Redis] Failed to close the previous connection pool: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Script is a Lua script identified by its SHA1, reusable across RedisClients
+type Script struct {
+	src string
+	sha string
+}
+
+// NewScript computes the script's SHA1 locally; nothing is sent to Redis until first use
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{
+		src: src,
+		sha: hex.EncodeToString(sum[:]),
+	}
+}
+
+// SHA returns the script's SHA1 as used by EVALSHA
+func (s *Script) SHA() string {
+	return s.sha
+}
+
+// compareAndDeleteScript deletes KEYS[1] only if it still holds ARGV[1]
+var compareAndDeleteScript = NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Eval runs a Lua script source and registers it so later EvalSha calls can self-heal
+func (r *RedisClient) Eval(ctx context.Context, src string, keys []string, args ...any) (any, error) {
+	return r.RunScript(ctx, NewScript(src), keys, args...)
+}
+
+// EvalSha runs a previously loaded script by SHA1
+// If Redis lost the script (restart, failover, SCRIPT FLUSH) and the source is known to this
+// client, it is re-registered and the call retried once
+func (r *RedisClient) EvalSha(ctx context.Context, sha string, keys []string, args ...any) (any, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.rdb().EvalSha(ctx, sha, keys, args...).Result()
+	if err != nil && isNoScript(err) {
+		src, known := r.scripts.Load(sha)
+		if !known {
+			r.metrics.Errors.Add(1)
+			return nil, fmt.Errorf("script %s not loaded and source unknown: %w", sha, err)
+		}
+
+		log.Printf("[Redis] NOSCRIPT for %s, re-registering script", sha)
+		if _, loadErr := r.LoadScript(ctx, src.(string)); loadErr != nil {
+			return nil, loadErr
+		}
+		result, err = r.rdb().EvalSha(ctx, sha, keys, args...).Result()
+	}
+
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] EVALSHA failed for script %s: %v", sha, err)
+		return nil, fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	return result, nil
+}
+
+// LoadScript uploads a script to Redis (SCRIPT LOAD) and remembers its source for NOSCRIPT recovery
+func (r *RedisClient) LoadScript(ctx context.Context, src string) (string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	sha, err := r.rdb().ScriptLoad(ctx, src).Result()
+	if err != nil {
+		r.metrics.Errors.Add(1)
+		log.Printf("[Redis] SCRIPT LOAD failed: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrCacheUnavailable, err)
+	}
+
+	r.scripts.Store(sha, src)
+	return sha, nil
+}
+
+// RunScript executes a Script via EVALSHA, loading it on first use or after NOSCRIPT
+func (r *RedisClient) RunScript(ctx context.Context, script *Script, keys []string, args ...any) (any, error) {
+	r.scripts.LoadOrStore(script.sha, script.src)
+	return r.EvalSha(ctx, script.sha, keys, args...)
+}
+
+// CompareAndDelete deletes key only if its value equals expected (safe lock release)
+// Returns true if the key was deleted
+func (r *RedisClient) CompareAndDelete(ctx context.Context, key string, expected string) (bool, error) {
+	result, err := r.RunScript(ctx, compareAndDeleteScript, []string{key}, expected)
+	if err != nil {
+		return false, err
+	}
+	return toInt64(result) == 1, nil
+}
+
+func isNoScript(err error) bool {
+	return strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"acid/internal/jsoncodec"
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceStale is the source reported when GetOrSetJSON answers from the last known copy because
+// the source was unavailable
+const SourceStale = "stale"
+
+// ErrSourceUnavailable marks fetch errors that mean the source could not be reached (timeouts,
+// no replicas), as opposed to a missing row. Fetch functions wrap it to allow a stale answer
+var ErrSourceUnavailable = errors.New("source unavailable")
+
+// stalePrefix marks the long-lived copies kept for second-chance serving; like the XFetch
+// sidecars they are derived from the entry key
+const stalePrefix = "stale:"
+
+type staleReportKey struct{}
+
+// StaleReport tells the caller that a lookup was answered from the last known copy
+type StaleReport struct {
+	// Served is set when GetOrSetJSON answered from the stale copy
+	Served bool
+
+	// StoredAt is when that copy was loaded from the source
+	StoredAt time.Time
+}
+
+// Age is how old the stale copy was when served
+func (r *StaleReport) Age() time.Duration {
+	return time.Since(r.StoredAt)
+}
+
+// WithStaleReport returns a context in which GetOrSetJSON records stale answers in the returned
+// report, so handlers can tell clients how old the data is
+func WithStaleReport(ctx context.Context) (context.Context, *StaleReport) {
+	report := &StaleReport{}
+	return context.WithValue(ctx, staleReportKey{}, report), report
+}
+
+// setStale keeps a copy of a freshly fetched value for StaleTTL, stamped with its load time
+func (cm *CacheManager) setStale(ctx context.Context, key string, encoded string) {
+	if cm.config.StaleTTL <= 0 || !cm.redisActive() {
+		return
+	}
+
+	stamped := strconv.FormatInt(time.Now().UnixMilli(), 10) + ":" + encoded
+	if err := cm.redisSet(ctx, stalePrefix+key, stamped, cm.config.StaleTTL); err != nil {
+		log.Printf("[CacheManager:%s] Failed to keep stale copy for key '%s': %v", cm.config.Name, key, err)
+	}
+}
+
+// serveStale answers a lookup whose source failed with cause from the stale copy of key; it
+// reports false when second-chance serving doesn't apply or no readable copy is left
+func (cm *CacheManager) serveStale(ctx context.Context, key string, dest interface{}, cause error) bool {
+	if cm.config.StaleTTL <= 0 || !cm.redisActive() || !errors.Is(cause, ErrSourceUnavailable) {
+		return false
+	}
+
+	readCtx, cancel := cm.readContext(ctx)
+	raw, err := cm.redis.Get(readCtx, stalePrefix+key)
+	cancel()
+	if err != nil {
+		cm.staleMisses.Add(1)
+		return false
+	}
+
+	storedPart, value, found := strings.Cut(raw, ":")
+	storedMs, parseErr := strconv.ParseInt(storedPart, 10, 64)
+	if !found || parseErr != nil {
+		cm.staleMisses.Add(1)
+		return false
+	}
+	if err := jsoncodec.Unmarshal([]byte(value), dest); err != nil {
+		log.Printf("[CacheManager:%s] Stale copy of key '%s' unreadable: %v", cm.config.Name, key, err)
+		cm.staleMisses.Add(1)
+		return false
+	}
+
+	storedAt := time.UnixMilli(storedMs)
+	if report, ok := ctx.Value(staleReportKey{}).(*StaleReport); ok {
+		report.Served = true
+		report.StoredAt = storedAt
+	}
+
+	cm.staleServed.Add(1)
+	log.Printf("[CacheManager:%s] Source unavailable, serving stale copy of key '%s' (age %s): %v",
+		cm.config.Name, key, time.Since(storedAt).Round(time.Second), cause)
+	return true
+}
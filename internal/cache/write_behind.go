@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"acid/internal/safego"
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OverflowPolicy decides what happens when the write-behind queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDrop discards the write (the key simply stays stale/missing in Redis)
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock waits for room, bounded by the caller's context
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// ErrWriteBehindFull is returned when a write is dropped because the queue is full
+var ErrWriteBehindFull = errors.New("write-behind queue full")
+
+// WriteBehindConfig holds write-behind queue configuration
+type WriteBehindConfig struct {
+	// QueueSize is the total number of pending writes across all workers
+	QueueSize int
+
+	// Workers is the number of flush goroutines; keys are pinned to a worker so per-key order is kept
+	Workers int
+
+	// BatchSize is the max number of writes sent in one pipeline
+	BatchSize int
+
+	// FlushInterval is the longest a write waits for its batch to fill
+	FlushInterval time.Duration
+
+	// Overflow selects drop or block when the queue is full
+	Overflow OverflowPolicy
+
+	// FlushTimeout bounds a single pipeline flush
+	FlushTimeout time.Duration
+}
+
+// DefaultWriteBehindConfig returns sensible production defaults
+func DefaultWriteBehindConfig() *WriteBehindConfig {
+	return &WriteBehindConfig{
+		QueueSize:     10000,
+		Workers:       4,
+		BatchSize:     100,
+		FlushInterval: 50 * time.Millisecond,
+		Overflow:      OverflowDrop,
+		FlushTimeout:  2 * time.Second,
+	}
+}
+
+// WriteBehindMetrics tracks queue throughput and losses
+type WriteBehindMetrics struct {
+	Enqueued    atomic.Int64
+	Flushed     atomic.Int64
+	Dropped     atomic.Int64
+	FlushErrors atomic.Int64
+	Batches     atomic.Int64
+}
+
+// writeOp is a pending Redis write (set or delete)
+type writeOp struct {
+	key    string
+	value  string
+	ttl    time.Duration
+	delete bool
+}
+
+// writeBehindQueue buffers Redis writes and flushes them in pipelined batches
+type writeBehindQueue struct {
+	redis   *RedisClient
+	config  *WriteBehindConfig
+	name    string
+	metrics *WriteBehindMetrics
+	shards  []chan writeOp
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newWriteBehindQueue(redisClient *RedisClient, config *WriteBehindConfig, name string) *writeBehindQueue {
+	if config == nil {
+		config = DefaultWriteBehindConfig()
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+
+	q := &writeBehindQueue{
+		redis:   redisClient,
+		config:  config,
+		name:    name,
+		metrics: &WriteBehindMetrics{},
+		shards:  make([]chan writeOp, config.Workers),
+	}
+
+	perShard := max(config.QueueSize/config.Workers, 1)
+	for i := range q.shards {
+		q.shards[i] = make(chan writeOp, perShard)
+		q.wg.Add(1)
+		shard := q.shards[i]
+		safego.Go("write_behind.worker", func() { q.worker(shard) }, safego.Restart(nil), safego.OnExit(q.wg.Done))
+	}
+
+	log.Printf("[WriteBehind:%s] Started - Workers: %d, QueueSize: %d, BatchSize: %d, Overflow: %s",
+		name, config.Workers, config.QueueSize, config.BatchSize, config.Overflow)
+
+	return q
+}
+
+// enqueue queues a write according to the overflow policy
+func (q *writeBehindQueue) enqueue(ctx context.Context, op writeOp) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrCacheUnavailable
+	}
+
+	shard := q.shards[shardIndex(op.key, len(q.shards))]
+
+	select {
+	case shard <- op:
+		q.metrics.Enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	if q.config.Overflow == OverflowBlock && ctx != nil {
+		select {
+		case shard <- op:
+			q.metrics.Enqueued.Add(1)
+			return nil
+		case <-ctx.Done():
+		}
+	}
+
+	q.metrics.Dropped.Add(1)
+	return ErrWriteBehindFull
+}
+
+func (q *writeBehindQueue) worker(ops chan writeOp) {
+	ticker := time.NewTicker(q.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]writeOp, 0, q.config.BatchSize)
+	for {
+		select {
+		case op, ok := <-ops:
+			if !ok {
+				q.flush(batch)
+				return
+			}
+			batch = append(batch, op)
+			if len(batch) >= q.config.BatchSize {
+				q.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				q.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush sends a batch in one pipeline; failed writes are dropped (the cache is best effort)
+func (q *writeBehindQueue) flush(batch []writeOp) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.config.FlushTimeout)
+	defer cancel()
+
+	_, err := q.redis.Pipeline(ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range batch {
+			if op.delete {
+				pipe.Del(ctx, op.key)
+			} else {
+				pipe.Set(ctx, op.key, op.value, op.ttl)
+			}
+		}
+		return nil
+	})
+
+	q.metrics.Batches.Add(1)
+	if err != nil {
+		q.metrics.FlushErrors.Add(1)
+		q.metrics.Dropped.Add(int64(len(batch)))
+		log.Printf("[WriteBehind:%s] Flush of %d writes failed: %v", q.name, len(batch), err)
+		return
+	}
+	q.metrics.Flushed.Add(int64(len(batch)))
+}
+
+// depth returns the number of writes waiting in the queue
+func (q *writeBehindQueue) depth() int {
+	total := 0
+	for _, shard := range q.shards {
+		total += len(shard)
+	}
+	return total
+}
+
+// close stops accepting writes and drains everything already queued
+func (q *writeBehindQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	for _, shard := range q.shards {
+		close(shard)
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	log.Printf("[WriteBehind:%s] Drained - Flushed: %d, Dropped: %d",
+		q.name, q.metrics.Flushed.Load(), q.metrics.Dropped.Load())
+}
+
+func (q *writeBehindQueue) getMetrics() map[string]int64 {
+	return map[string]int64{
+		"enqueued":     q.metrics.Enqueued.Load(),
+		"flushed":      q.metrics.Flushed.Load(),
+		"dropped":      q.metrics.Dropped.Load(),
+		"flush_errors": q.metrics.FlushErrors.Load(),
+		"batches":      q.metrics.Batches.Load(),
+		"depth":        int64(q.depth()),
+		"capacity":     int64(q.config.QueueSize),
+	}
+}
+
+// shardIndex pins a key to one worker so its writes are applied in order
+func shardIndex(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
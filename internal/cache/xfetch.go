@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"acid/internal/jsoncodec"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xfetchPrefix marks the sidecar keys holding XFetch recompute metadata; they are derived from
+// the entry key, which already carries the namespace and version
+const xfetchPrefix = "xfetch:"
+
+// jitterTTL spreads ttl uniformly over ±TTLJitter so keys warmed together don't expire together
+func (cm *CacheManager) jitterTTL(ttl time.Duration) time.Duration {
+	jitter := cm.config.TTLJitter
+	if jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+
+	factor := 1 + jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(ttl) * factor)
+}
+
+// shouldRefreshEarly implements XFetch (Vattani et al.): a hit is treated as a miss with a
+// probability that rises as expiry approaches, scaled by how long the value took to compute,
+// so one request refreshes a hot key before it expires instead of all of them at once
+func (cm *CacheManager) shouldRefreshEarly(ctx context.Context, key string) bool {
+	if !cm.config.EarlyRefresh || !cm.redisActive() {
+		return false
+	}
+
+	// The sidecar read shares the lookup's budget; running out just skips the early refresh
+	ctx, cancelBudget := cm.readContext(ctx)
+	defer cancelBudget()
+	ctx, cancel := cm.redis.withTimeout(ctx)
+	defer cancel()
+
+	// Read the sidecar directly so it doesn't count towards cache hit/miss metrics
+	raw, err := cm.redis.rdb().Get(ctx, xfetchPrefix+key).Result()
+	if err != nil {
+		return false
+	}
+
+	deltaMs, expiryMs, ok := parseXFetch(raw)
+	if !ok {
+		return false
+	}
+
+	beta := cm.config.EarlyRefreshBeta
+	if beta <= 0 {
+		beta = 1.0
+	}
+
+	now := float64(time.Now().UnixMilli())
+	gap := -float64(deltaMs) * beta * math.Log(1-rand.Float64()) // 1-rand avoids log(0)
+	if now+gap < float64(expiryMs) {
+		return false
+	}
+
+	cm.earlyRefreshes.Add(1)
+	log.Printf("[CacheManager:%s] Early refresh for key '%s' (expires in %dms)",
+		cm.config.Name, key, expiryMs-int64(now))
+	return true
+}
+
+// setRecomputed stores a freshly fetched value; with EarlyRefresh it also records how long
+// the fetch took and when the Redis copy expires, which XFetch needs on later hits
+func (cm *CacheManager) setRecomputed(ctx context.Context, key string, value any, delta time.Duration) error {
+	if !cm.config.EarlyRefresh || !cm.redisActive() {
+		return cm.Set(ctx, key, value)
+	}
+
+	var encoded string
+	switch v := value.(type) {
+	case string:
+		encoded = v
+	default:
+		jsonString, err := jsoncodec.MarshalString(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value to JSON: %w", err)
+		}
+		encoded = jsonString
+	}
+
+	ttl := cm.jitterTTL(cm.config.RedisTTL)
+	if err := cm.setWithTTL(ctx, key, encoded, ttl); err != nil {
+		return err
+	}
+
+	expiry := time.Now().Add(ttl).UnixMilli()
+	sidecar := strconv.FormatInt(delta.Milliseconds(), 10) + ":" + strconv.FormatInt(expiry, 10)
+	if err := cm.redisSet(ctx, xfetchPrefix+key, sidecar, ttl); err != nil {
+		log.Printf("[CacheManager:%s] Failed to record XFetch metadata for key '%s': %v", cm.config.Name, key, err)
+	}
+	return nil
+}
+
+func parseXFetch(raw string) (deltaMs, expiryMs int64, ok bool) {
+	deltaPart, expiryPart, found := strings.Cut(raw, ":")
+	if !found {
+		return 0, 0, false
+	}
+
+	deltaMs, err := strconv.ParseInt(deltaPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	expiryMs, err = strconv.ParseInt(expiryPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return deltaMs, expiryMs, true
+}
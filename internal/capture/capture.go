@@ -0,0 +1,257 @@
+// Package capture keeps the request and response bodies of selected routes in an in-memory ring
+// buffer, so support can see exactly what a customer sent and got back when they report an API
+// discrepancy. Bodies are size-capped and personal data is redacted before anything is stored
+package capture
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config holds payload capture configuration
+type Config struct {
+	// Routes are the captured routes as "METHOD /route/template" (e.g. "POST /api/v2/users"), or
+	// a bare template for every method; nothing is captured while it is empty
+	Routes []string
+
+	// MaxBodyBytes caps each stored body; larger bodies are recorded by size only, since a cut
+	// JSON document can't be redacted reliably
+	MaxBodyBytes int
+
+	// BufferSize is how many exchanges are kept; the oldest are dropped first
+	BufferSize int
+
+	// RedactFields are JSON object keys and query parameters whose values are replaced, matched
+	// case-insensitively at any depth
+	RedactFields []string
+}
+
+// DefaultConfig returns sensible defaults: 8KB bodies, the last 200 exchanges, and user
+// identifying fields and credentials redacted
+func DefaultConfig() *Config {
+	return &Config{
+		MaxBodyBytes: 8 << 10,
+		BufferSize:   200,
+		RedactFields: []string{"email", "username", "password", "token", "access_token", "refresh_token", "secret", "authorization", "attributes"},
+	}
+}
+
+// Body is a captured request or response body
+type Body struct {
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+
+	// Content is the redacted body; empty when the body was empty, too large or not JSON
+	Content json.RawMessage `json:"content,omitempty"`
+
+	// Omitted says why Content is missing when the body wasn't empty: "too_large" or "not_json"
+	Omitted string `json:"omitted,omitempty"`
+}
+
+// Exchange is one captured request and its response
+type Exchange struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	RequestID  string    `json:"request_id,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	Request    Body      `json:"request"`
+	Response   Body      `json:"response"`
+}
+
+// Metrics tracks captured exchanges
+type Metrics struct {
+	Captured  atomic.Int64
+	Evicted   atomic.Int64
+	Oversized atomic.Int64
+}
+
+// Recorder holds the captured exchanges of this instance
+type Recorder struct {
+	config *Config
+	routes map[string]bool
+	redact map[string]bool
+
+	mu      sync.Mutex
+	ring    []Exchange
+	next    int
+	wrapped bool
+
+	metrics Metrics
+}
+
+func NewRecorder(config *Config) *Recorder {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	r := &Recorder{
+		config: config,
+		routes: make(map[string]bool, len(config.Routes)),
+		redact: make(map[string]bool, len(config.RedactFields)),
+		ring:   make([]Exchange, max(config.BufferSize, 1)),
+	}
+	for _, route := range config.Routes {
+		if route = strings.TrimSpace(route); route != "" {
+			r.routes[route] = true
+		}
+	}
+	for _, field := range config.RedactFields {
+		if field = strings.TrimSpace(field); field != "" {
+			r.redact[strings.ToLower(field)] = true
+		}
+	}
+	return r
+}
+
+// Captures reports whether requests to route (a gin route template) with method are captured
+func (r *Recorder) Captures(method, route string) bool {
+	return route != "" && (r.routes[method+" "+route] || r.routes[route])
+}
+
+// MaxBodyBytes is the largest body kept
+func (r *Recorder) MaxBodyBytes() int {
+	return r.config.MaxBodyBytes
+}
+
+// NewBody builds a captured body from raw, which holds up to MaxBodyBytes+1 bytes of a body of
+// size bytes in total. Only JSON is kept, redacted
+func (r *Recorder) NewBody(contentType string, raw []byte, size int) Body {
+	body := Body{ContentType: contentType, Size: size}
+	switch {
+	case size == 0:
+	case size > r.config.MaxBodyBytes || len(raw) < size:
+		body.Omitted = "too_large"
+		r.metrics.Oversized.Add(1)
+	default:
+		if content, ok := redactJSON(raw, r.redact); ok {
+			body.Content = json.RawMessage(content)
+		} else {
+			body.Omitted = "not_json"
+		}
+	}
+	return body
+}
+
+// RedactQuery redacts the values of sensitive parameters in a raw query string
+func (r *Recorder) RedactQuery(rawQuery string) string {
+	return redactQuery(rawQuery, r.redact)
+}
+
+// Record stores an exchange, assigning its ID
+func (r *Recorder) Record(exchange Exchange) {
+	exchange.ID = uuid.NewString()
+
+	r.mu.Lock()
+	if r.wrapped {
+		r.metrics.Evicted.Add(1)
+	}
+	r.ring[r.next] = exchange
+	r.next++
+	if r.next == len(r.ring) {
+		r.next = 0
+		r.wrapped = true
+	}
+	r.mu.Unlock()
+
+	r.metrics.Captured.Add(1)
+}
+
+// List returns up to limit exchanges, newest first, optionally only those of route, continuing
+// after the exchange with ID after when set. more reports whether older matching exchanges
+// remain and total counts every buffered match. An evicted after means the rest of the listing
+// was evicted too, since the ring drops the oldest first
+func (r *Recorder) List(route, after string, limit int) (exchanges []Exchange, more bool, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exchanges = make([]Exchange, 0, min(limit, len(r.ring)))
+	skipping := after != ""
+	for _, exchange := range r.newestFirst() {
+		if route != "" && exchange.Route != route {
+			continue
+		}
+		total++
+		if skipping {
+			skipping = exchange.ID != after
+			continue
+		}
+		if len(exchanges) == limit {
+			more = true
+			continue
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, more, total
+}
+
+// Get returns the exchange with id, if it is still buffered
+func (r *Recorder) Get(id string) (Exchange, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, exchange := range r.ring {
+		if exchange.ID != "" && exchange.ID == id {
+			return exchange, true
+		}
+	}
+	return Exchange{}, false
+}
+
+// Clear drops every buffered exchange
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	clear(r.ring)
+	r.next, r.wrapped = 0, false
+	r.mu.Unlock()
+}
+
+// Routes returns the captured routes
+func (r *Recorder) Routes() []string {
+	routes := make([]string, 0, len(r.routes))
+	for route := range r.routes {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// GetMetrics returns current capture metrics
+func (r *Recorder) GetMetrics() map[string]int64 {
+	r.mu.Lock()
+	buffered := r.next
+	if r.wrapped {
+		buffered = len(r.ring)
+	}
+	r.mu.Unlock()
+
+	return map[string]int64{
+		"captured":  r.metrics.Captured.Load(),
+		"evicted":   r.metrics.Evicted.Load(),
+		"oversized": r.metrics.Oversized.Load(),
+		"buffered":  int64(buffered),
+	}
+}
+
+// newestFirst returns the buffered exchanges, newest first; callers hold mu
+func (r *Recorder) newestFirst() []Exchange {
+	count := r.next
+	if r.wrapped {
+		count = len(r.ring)
+	}
+	exchanges := make([]Exchange, 0, count)
+	for i := 1; i <= count; i++ {
+		exchanges = append(exchanges, r.ring[(r.next-i+len(r.ring))%len(r.ring)])
+	}
+	return exchanges
+}
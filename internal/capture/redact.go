@@ -0,0 +1,65 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces sensitive values
+const redactedValue = "[REDACTED]"
+
+// redactJSON returns raw with the values of sensitive keys replaced, re-encoded compactly; ok is
+// false when raw isn't JSON
+func redactJSON(raw []byte, fields map[string]bool) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber() // Keep numbers exactly as sent
+	var doc any
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return "", false
+	}
+
+	redacted, err := json.Marshal(redactValue(doc, fields))
+	if err != nil {
+		return "", false
+	}
+	return string(redacted), true
+}
+
+func redactValue(value any, fields map[string]bool) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, nested := range typed {
+			if fields[strings.ToLower(key)] {
+				typed[key] = redactedValue
+				continue
+			}
+			typed[key] = redactValue(nested, fields)
+		}
+	case []any:
+		for i, nested := range typed {
+			typed[i] = redactValue(nested, fields)
+		}
+	}
+	return value
+}
+
+// redactQuery replaces the values of sensitive parameters; a query that doesn't parse is dropped
+func redactQuery(rawQuery string, fields map[string]bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redactedValue
+	}
+	for key, list := range values {
+		if fields[strings.ToLower(key)] {
+			for i := range list {
+				list[i] = redactedValue
+			}
+		}
+	}
+	return values.Encode()
+}
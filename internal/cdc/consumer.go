@@ -0,0 +1,285 @@
+// Package cdc consumes the ScyllaDB CDC log of the users table, so writes that bypass the API
+// (Spark jobs, cqlsh fixes) reach the service as changes it can react to, e.g. by purging caches
+package cdc
+
+import (
+	"acid/internal/events"
+	"acid/internal/safego"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config holds CDC consumer configuration
+type Config struct {
+	// Name identifies the consumer's checkpoint; consumers sharing a name share progress
+	Name string
+
+	// LogTable is the CDC log table read, <table>_scylla_cdc_log
+	LogTable string
+
+	// PollInterval is how often the log is read
+	PollInterval time.Duration
+
+	// ConfirmationDelay keeps the newest part of the log unread: rows are ordered by the writer's
+	// clock, so a write from a client running late can land behind a window already read
+	ConfirmationDelay time.Duration
+
+	// Lookback is where a consumer without a checkpoint starts reading, before now
+	Lookback time.Duration
+
+	// MaxWindow bounds the time range read per poll, so catching up after downtime is done in steps
+	MaxWindow time.Duration
+
+	// StreamBatch is how many streams are read per query
+	StreamBatch int
+}
+
+// DefaultConfig returns sensible defaults: a 5s poll reading up to 15s behind the present
+func DefaultConfig() *Config {
+	return &Config{
+		Name:              "cache-invalidation",
+		LogTable:          "users_scylla_cdc_log",
+		PollInterval:      5 * time.Second,
+		ConfirmationDelay: 15 * time.Second,
+		Lookback:          1 * time.Minute,
+		MaxWindow:         1 * time.Minute,
+		StreamBatch:       100,
+	}
+}
+
+// Handler processes the changes of one window; an error makes the window be read again
+type Handler func(ctx context.Context, changes []Change) error
+
+// Metrics tracks consumer progress
+type Metrics struct {
+	Polls     atomic.Int64
+	Changes   atomic.Int64
+	Errors    atomic.Int64
+	LagMillis atomic.Int64 // How far behind the present the checkpoint is
+}
+
+// Consumer reads the CDC log in consecutive time windows and hands each window's changes to a
+// handler. Delivery is at-least-once: a window is read again after a failure or a restart from
+// an older checkpoint, so the handler must be idempotent
+type Consumer struct {
+	repo    *Repository
+	handler Handler
+	config  *Config
+	logger  *zap.Logger
+
+	position time.Time
+	streams  map[time.Time][][]byte // Per generation; generations never change once created
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	metrics Metrics
+}
+
+func NewConsumer(repo *Repository, handler Handler, config *Config, logger *zap.Logger) *Consumer {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Consumer{
+		repo:    repo,
+		handler: handler,
+		config:  config,
+		logger:  logger,
+		streams: make(map[time.Time][][]byte),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start resumes from the checkpoint, or Lookback before now without one, and polls in the
+// background until Stop
+func (c *Consumer) Start(ctx context.Context) error {
+	position, err := c.repo.Checkpoint(ctx, c.config.Name)
+	if err != nil {
+		return fmt.Errorf("failed to load CDC checkpoint: %w", err)
+	}
+	if position.IsZero() {
+		position = time.Now().Add(-c.config.Lookback)
+	}
+	c.position = position
+
+	c.logger.Info("CDC consumer started",
+		zap.String("consumer", c.config.Name),
+		zap.String("table", c.config.LogTable),
+		zap.Time("position", position))
+	safego.Go("cdc.consumer", c.run, safego.Restart(c.stop), safego.OnExit(func() { close(c.done) }))
+	return nil
+}
+
+// Stop halts polling after the current window
+func (c *Consumer) Stop() {
+	c.once.Do(func() {
+		close(c.stop)
+		<-c.done
+	})
+}
+
+// GetMetrics returns current consumer metrics
+func (c *Consumer) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"polls":      c.metrics.Polls.Load(),
+		"changes":    c.metrics.Changes.Load(),
+		"errors":     c.metrics.Errors.Load(),
+		"lag_millis": c.metrics.LagMillis.Load(),
+	}
+}
+
+func (c *Consumer) run() {
+	ticker := time.NewTicker(c.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.config.PollInterval+30*time.Second)
+			if err := c.poll(ctx); err != nil {
+				c.metrics.Errors.Add(1)
+				c.logger.Warn("CDC poll failed, retrying the window", zap.Time("position", c.position), zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// poll reads the next window and advances the checkpoint once the handler accepted it
+func (c *Consumer) poll(ctx context.Context) error {
+	c.metrics.Polls.Add(1)
+	to := time.Now().Add(-c.config.ConfirmationDelay)
+	if window := c.position.Add(c.config.MaxWindow); to.After(window) {
+		to = window
+	}
+	if !to.After(c.position) {
+		return nil
+	}
+
+	changes, err := c.read(ctx, c.position, to)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		if err := c.handler(ctx, changes); err != nil {
+			return fmt.Errorf("handler failed on %d change(s): %w", len(changes), err)
+		}
+		c.metrics.Changes.Add(int64(len(changes)))
+	}
+
+	c.position = to
+	c.metrics.LagMillis.Store(time.Since(to).Milliseconds())
+	// A lost checkpoint only means re-reading windows after a restart
+	if err := c.repo.SaveCheckpoint(ctx, c.config.Name, to); err != nil {
+		c.logger.Warn("Failed to save CDC checkpoint", zap.Time("position", to), zap.Error(err))
+	}
+	return nil
+}
+
+// read returns the changes of [from, to) across the generations active in it
+func (c *Consumer) read(ctx context.Context, from, to time.Time) ([]Change, error) {
+	generations, err := c.repo.Generations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for i, start := range generations {
+		end := to
+		if i+1 < len(generations) && generations[i+1].Before(to) {
+			end = generations[i+1]
+		}
+		windowFrom := from
+		if start.After(windowFrom) {
+			windowFrom = start
+		}
+		if !end.After(windowFrom) {
+			continue
+		}
+
+		streams, err := c.generationStreams(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+		for batch := 0; batch < len(streams); batch += c.config.StreamBatch {
+			batchChanges, err := c.repo.Changes(ctx, c.config.LogTable, streams[batch:min(batch+c.config.StreamBatch, len(streams))], windowFrom, end)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, batchChanges...)
+		}
+	}
+	return changes, nil
+}
+
+func (c *Consumer) generationStreams(ctx context.Context, start time.Time) ([][]byte, error) {
+	if streams, ok := c.streams[start]; ok {
+		return streams, nil
+	}
+	streams, err := c.repo.Streams(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	c.streams[start] = streams
+	return streams, nil
+}
+
+// EventUserChanged is the internal bus event published for every user a window changed
+const EventUserChanged = "user.changed_externally"
+
+// UserChange is the payload of EventUserChanged
+type UserChange struct {
+	ID     string   `json:"id"`
+	Emails []string `json:"emails,omitempty"`
+}
+
+// PublishTo returns a Handler publishing an EventUserChanged per affected user to bus, for its
+// handlers (e.g. cache invalidation) to react to. A failing handler makes the window be read again
+func PublishTo(bus *events.Bus) Handler {
+	return func(ctx context.Context, changes []Change) error {
+		for userID, emails := range AffectedUsers(changes) {
+			event, err := events.NewEvent(EventUserChanged, userID, UserChange{ID: userID, Emails: emails})
+			if err != nil {
+				return err
+			}
+			event.Internal = true
+			if err := bus.Publish(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AffectedUsers groups changes by user, with every email any of them carried: the preimage's
+// (the email before the write) and the new one
+func AffectedUsers(changes []Change) map[string][]string {
+	emails := make(map[string]map[string]bool)
+	for _, change := range changes {
+		if emails[change.UserID] == nil {
+			emails[change.UserID] = make(map[string]bool)
+		}
+		if change.Email != "" {
+			emails[change.UserID][change.Email] = true
+		}
+	}
+
+	affected := make(map[string][]string, len(emails))
+	for userID, set := range emails {
+		list := make([]string, 0, len(set))
+		for email := range set {
+			list = append(list, email)
+		}
+		affected[userID] = list
+	}
+	return affected
+}
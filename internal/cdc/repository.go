@@ -0,0 +1,141 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// CheckpointTable records how far each consumer has read, so a restart resumes where it stopped
+var CheckpointTable = table.New(table.Metadata{
+	Name:    "cdc_checkpoints",
+	Columns: []string{"consumer", "position", "updated_at"},
+	PartKey: []string{"consumer"},
+	SortKey: []string{},
+})
+
+// CheckpointColumnTypes are the CQL types of cdc_checkpoints
+var CheckpointColumnTypes = map[string]string{
+	"consumer":   "text",
+	"position":   "timestamp",
+	"updated_at": "timestamp",
+}
+
+// Operation is a CDC log row's cdc$operation
+type Operation int8
+
+// Operations that affect a users row; range deletes can't happen on a table without clustering
+const (
+	OpPreimage        Operation = 0
+	OpUpdate          Operation = 1
+	OpInsert          Operation = 2
+	OpRowDelete       Operation = 3
+	OpPartitionDelete Operation = 4
+)
+
+// Repository reads the users CDC log and the consumer checkpoints
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Checkpoint returns the position a consumer stopped at, or the zero time when it never ran
+func (r *Repository) Checkpoint(ctx context.Context, consumer string) (time.Time, error) {
+	var position time.Time
+	stmt, names := qb.Select(CheckpointTable.Name()).Columns("position").Where(qb.Eq("consumer")).ToCql()
+	err := r.session.ContextQuery(ctx, stmt, names).BindMap(map[string]interface{}{
+		"consumer": consumer,
+	}).Scan(&position)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	return position, err
+}
+
+// SaveCheckpoint records that everything before position was handled
+func (r *Repository) SaveCheckpoint(ctx context.Context, consumer string, position time.Time) error {
+	return CheckpointTable.InsertQueryContext(ctx, r.session).BindMap(map[string]interface{}{
+		"consumer":   consumer,
+		"position":   position,
+		"updated_at": time.Now(),
+	}).ExecRelease()
+}
+
+// Generations returns every CDC stream generation, oldest first, without their streams
+func (r *Repository) Generations(ctx context.Context) ([]time.Time, error) {
+	iter := r.session.ContextQuery(ctx,
+		`SELECT time FROM system_distributed.cdc_generation_timestamps WHERE key = 'timestamps'`, nil).Iter()
+
+	var times []time.Time
+	var at time.Time
+	for iter.Scan(&at) {
+		times = append(times, at)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to list CDC generations: %w", err)
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	return times, nil
+}
+
+// Streams returns the stream IDs of the generation starting at at
+func (r *Repository) Streams(ctx context.Context, at time.Time) ([][]byte, error) {
+	iter := r.session.ContextQuery(ctx,
+		`SELECT streams FROM system_distributed.cdc_streams_descriptions_v2 WHERE time = ?`, nil).
+		Bind(at).Iter()
+
+	var streams [][]byte
+	var vnodeStreams [][]byte
+	for iter.Scan(&vnodeStreams) {
+		streams = append(streams, vnodeStreams...)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read CDC streams of generation %s: %w", at.Format(time.RFC3339), err)
+	}
+	return streams, nil
+}
+
+// Change is one CDC log row of the users table
+type Change struct {
+	Time      time.Time
+	Operation Operation
+	UserID    string
+	Email     string // Empty unless the row carries it
+}
+
+// Changes reads the log rows of streams written in [from, to)
+func (r *Repository) Changes(ctx context.Context, logTable string, streams [][]byte, from, to time.Time) ([]Change, error) {
+	stmt := fmt.Sprintf(`SELECT "cdc$time", "cdc$operation", id, email FROM %s `+
+		`WHERE "cdc$stream_id" IN ? AND "cdc$time" >= minTimeuuid(?) AND "cdc$time" < minTimeuuid(?)`, logTable)
+	iter := r.session.ContextQuery(ctx, stmt, nil).Bind(streams, from, to).Iter()
+
+	var changes []Change
+	var (
+		changeTime gocql.UUID
+		operation  int8
+		id         gocql.UUID
+		email      string
+	)
+	for iter.Scan(&changeTime, &operation, &id, &email) {
+		changes = append(changes, Change{
+			Time:      changeTime.Time(),
+			Operation: Operation(operation),
+			UserID:    id.String(),
+			Email:     email,
+		})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", logTable, err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,139 @@
+// Package clientip resolves the address of the client behind a request. X-Forwarded-For is only
+// believed from configured trusted proxies, so a client can't pick its own address (and with it
+// its rate limit bucket, quota subject or IP filter decision) by sending the header itself
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderForwardedFor is the only header read for the client address; X-Real-IP is ignored
+const HeaderForwardedFor = "X-Forwarded-For"
+
+// Resolver resolves client addresses the same way for HTTP (through gin's ClientIP) and gRPC
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver creates a resolver trusting the given proxies; with none, the connection's peer is
+// always the client
+func NewResolver(trusted []netip.Prefix) *Resolver {
+	return &Resolver{trusted: trusted}
+}
+
+// TrustedProxies returns the trusted proxy prefixes
+func (r *Resolver) TrustedProxies() []netip.Prefix {
+	return r.trusted
+}
+
+// Resolve returns the client address of a connection from remoteAddr ("ip:port" or a bare IP)
+// and the X-Forwarded-For value it sent. Unless the peer is a trusted proxy the header is ignored;
+// otherwise it is walked right to left and the first untrusted address is the client. This is
+// gin's algorithm, so HTTP and gRPC agree
+func (r *Resolver) Resolve(remoteAddr, forwardedFor string) netip.Addr {
+	remote := ParseAddr(remoteAddr)
+	if !remote.IsValid() || !r.trusts(remote) || forwardedFor == "" {
+		return remote
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := ParseAddr(strings.TrimSpace(hops[i]))
+		if !hop.IsValid() {
+			break
+		}
+		if i == 0 || !r.trusts(hop) {
+			return hop
+		}
+	}
+	return remote
+}
+
+// Configure makes gin's Context.ClientIP (used by its access log) resolve addresses like Resolve.
+// gin otherwise trusts X-Forwarded-For and X-Real-IP from any peer
+func (r *Resolver) Configure(engine *gin.Engine) error {
+	proxies := make([]string, len(r.trusted))
+	for i, prefix := range r.trusted {
+		proxies[i] = prefix.String()
+	}
+	engine.ForwardedByClientIP = true
+	engine.RemoteIPHeaders = []string{HeaderForwardedFor}
+	if err := engine.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+	return nil
+}
+
+func (r *Resolver) trusts(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the resolved client address
+func NewContext(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, contextKey{}, addr)
+}
+
+// FromContext returns the client address stored in ctx, or the zero (invalid) address
+func FromContext(ctx context.Context) netip.Addr {
+	addr, _ := ctx.Value(contextKey{}).(netip.Addr)
+	return addr
+}
+
+// ParsePrefix parses a CIDR, or a single address as a /32 (/128) prefix
+func ParsePrefix(raw string) (netip.Prefix, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "/") {
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%q is not an IP address or CIDR", raw)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(raw)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is not an IP address or CIDR", raw)
+	}
+	return prefix.Masked(), nil
+}
+
+// ParsePrefixes parses a comma-separated list of CIDRs or addresses
+func ParsePrefixes(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		prefix, err := ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// ParseAddr parses "ip:port" or a bare IP, unmapping IPv4-mapped IPv6 addresses; it returns the
+// zero address when raw is neither
+func ParseAddr(raw string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(raw); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
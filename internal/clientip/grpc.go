@@ -0,0 +1,45 @@
+package clientip
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryServerInterceptor resolves the caller's address from the peer and x-forwarded-for
+// metadata and stores it in the context; it goes first so later interceptors can use it
+func UnaryServerInterceptor(resolver *Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(resolver.incomingContext(ctx), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(resolver *Resolver) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &resolvedStream{ServerStream: ss, ctx: resolver.incomingContext(ss.Context())})
+	}
+}
+
+type resolvedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *resolvedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (r *Resolver) incomingContext(ctx context.Context) context.Context {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	// Repeated values are joined as one header would be, in arrival order
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwardedFor := strings.Join(md.Get(strings.ToLower(HeaderForwardedFor)), ",")
+	return NewContext(ctx, r.Resolve(remoteAddr, forwardedFor))
+}
@@ -0,0 +1,58 @@
+// Package correlation carries request-scoped metadata (request ID, trace context, auth) from
+// inbound requests to outbound HTTP and gRPC calls made while serving them
+package correlation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTP headers; gRPC metadata uses the same names lower-cased
+const (
+	HeaderRequestID     = "X-Request-ID"
+	HeaderTraceParent   = "Traceparent"
+	HeaderTraceState    = "Tracestate"
+	HeaderAuthorization = "Authorization"
+)
+
+// Metadata is the correlation data propagated to downstream services
+type Metadata struct {
+	RequestID     string
+	TraceParent   string // W3C trace context, forwarded verbatim
+	TraceState    string
+	Authorization string // Only forwarded when the outbound client is configured to
+}
+
+type contextKey struct{}
+
+// WithMetadata returns a context carrying md. Unless the context already has an active
+// OpenTelemetry span, the caller's traceparent also becomes its remote span context, so
+// OTel-aware code (the context logger, a tracer added later) continues the caller's trace
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	if md.TraceParent != "" && !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+			"traceparent": md.TraceParent,
+			"tracestate":  md.TraceState,
+		})
+	}
+	return context.WithValue(ctx, contextKey{}, md)
+}
+
+// FromContext returns the metadata stored in ctx, or the zero value
+func FromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(contextKey{}).(Metadata)
+	return md
+}
+
+// RequestID returns the request ID stored in ctx, or ""
+func RequestID(ctx context.Context) string {
+	return FromContext(ctx).RequestID
+}
+
+// NewRequestID generates a request ID for requests that arrive without one
+func NewRequestID() string {
+	return uuid.NewString()
+}
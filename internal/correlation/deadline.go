@@ -0,0 +1,120 @@
+package correlation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// HeaderDeadline is the absolute time by which an upstream gateway needs the response, as RFC 3339
+// or Unix milliseconds. Unlike HeaderTimeout it doesn't shrink in transit, but it trusts the
+// caller's clock
+const HeaderDeadline = "X-Request-Deadline"
+
+// DeadlineConfig bounds the deadlines callers set for their requests
+type DeadlineConfig struct {
+	// Max caps the time a caller may grant a request; 0 leaves caller deadlines unbounded
+	Max time.Duration
+
+	// Default is the time given to requests arriving without a deadline; 0 gives them none
+	Default time.Duration
+}
+
+// DefaultDeadlineConfig honors caller deadlines up to 30s and sets none otherwise
+func DefaultDeadlineConfig() *DeadlineConfig {
+	return &DeadlineConfig{
+		Max: 30 * time.Second,
+	}
+}
+
+// ParseDeadline parses a HeaderDeadline value
+func ParseDeadline(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// Resolve returns the deadline of a request arriving at now: the earliest of the caller's deadline
+// already on the request (zero when none) and the HeaderDeadline and HeaderTimeout values found
+// with header, else now+Default, clamped to now+Max. ok is false when the request gets none
+func (c *DeadlineConfig) Resolve(now, caller time.Time, header func(key string) string) (deadline time.Time, ok bool, err error) {
+	deadline = caller
+	earliest := func(t time.Time) {
+		if deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+
+	if value := header(HeaderDeadline); value != "" {
+		t, err := ParseDeadline(value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s header, expected RFC 3339 or Unix milliseconds", HeaderDeadline)
+		}
+		earliest(t)
+	}
+	if value := header(HeaderTimeout); value != "" {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms < 0 {
+			return time.Time{}, false, fmt.Errorf("invalid %s header, expected milliseconds", HeaderTimeout)
+		}
+		earliest(now.Add(time.Duration(ms) * time.Millisecond))
+	}
+
+	if deadline.IsZero() {
+		if c.Default <= 0 {
+			return time.Time{}, false, nil
+		}
+		deadline = now.Add(c.Default)
+	}
+	if c.Max > 0 && deadline.After(now.Add(c.Max)) {
+		deadline = now.Add(c.Max)
+	}
+	return deadline, true, nil
+}
+
+// DeadlineUnaryServerInterceptor clamps the deadline gRPC derived from grpc-timeout to config, also
+// honoring the x-request-deadline and x-request-timeout-ms metadata gateways forward from HTTP.
+// There is no stream counterpart: streams (health Watch) are long-lived by design
+func DeadlineUnaryServerInterceptor(config *DeadlineConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel, err := withIncomingDeadline(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func withIncomingDeadline(ctx context.Context, config *DeadlineConfig) (context.Context, context.CancelFunc, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	header := func(key string) string {
+		if values := incoming.Get(strings.ToLower(key)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	now := time.Now()
+	caller, _ := ctx.Deadline()
+	deadline, ok, err := config.Resolve(now, caller, header)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !ok || deadline.Equal(caller) {
+		return ctx, func() {}, nil
+	}
+	if !deadline.After(now) {
+		return nil, nil, status.Error(codes.DeadlineExceeded, "the request deadline has already passed")
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
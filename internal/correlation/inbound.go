@@ -0,0 +1,58 @@
+package correlation
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor extracts correlation metadata from incoming gRPC calls,
+// generating a request ID when the caller didn't send one
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = incomingContext(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(HeaderRequestID), RequestID(ctx)))
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := incomingContext(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(strings.ToLower(HeaderRequestID), RequestID(ctx)))
+		return handler(srv, &correlatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type correlatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *correlatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func incomingContext(ctx context.Context) context.Context {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := incoming.Get(strings.ToLower(key)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	md := Metadata{
+		RequestID:     first(HeaderRequestID),
+		TraceParent:   first(HeaderTraceParent),
+		TraceState:    first(HeaderTraceState),
+		Authorization: first(HeaderAuthorization),
+	}
+	if md.RequestID == "" {
+		md.RequestID = NewRequestID()
+	}
+	return WithMetadata(ctx, md)
+}
@@ -0,0 +1,124 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// HeaderTimeout tells HTTP downstreams how much of the caller's deadline is left, in milliseconds
+// (gRPC carries deadlines natively via grpc-timeout)
+const HeaderTimeout = "X-Request-Timeout-Ms"
+
+// OutboundConfig controls what is propagated to downstream services
+type OutboundConfig struct {
+	// PropagateAuth forwards the inbound Authorization credential; enable only for trusted internal services
+	PropagateAuth bool
+}
+
+// DefaultOutboundConfig returns defaults for calls to internal services
+func DefaultOutboundConfig() *OutboundConfig {
+	return &OutboundConfig{
+		PropagateAuth: true,
+	}
+}
+
+// Transport is an http.RoundTripper that adds correlation headers from the request context
+type Transport struct {
+	Base   http.RoundTripper
+	config *OutboundConfig
+}
+
+// NewTransport wraps base (http.DefaultTransport when nil)
+func NewTransport(base http.RoundTripper, config *OutboundConfig) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if config == nil {
+		config = DefaultOutboundConfig()
+	}
+	return &Transport{Base: base, config: config}
+}
+
+// NewHTTPClient returns an HTTP client that propagates correlation metadata and deadlines
+// Requests must be built with http.NewRequestWithContext using the inbound request's context
+func NewHTTPClient(timeout time.Duration, config *OutboundConfig) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(nil, config),
+		Timeout:   timeout,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	md := FromContext(ctx)
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(ctx)
+	setIfMissing(req.Header, HeaderRequestID, md.RequestID)
+	setIfMissing(req.Header, HeaderTraceParent, md.TraceParent)
+	setIfMissing(req.Header, HeaderTraceState, md.TraceState)
+	if t.config.PropagateAuth {
+		setIfMissing(req.Header, HeaderAuthorization, md.Authorization)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 {
+			req.Header.Set(HeaderTimeout, strconv.FormatInt(remaining.Milliseconds(), 10))
+		}
+	}
+
+	return t.Base.RoundTrip(req)
+}
+
+// DialOptions returns gRPC dial options that propagate correlation metadata on every call
+func DialOptions(config *OutboundConfig) []grpc.DialOption {
+	if config == nil {
+		config = DefaultOutboundConfig()
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(outgoingContext(ctx, config), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(outgoingContext(ctx, config), desc, cc, method, opts...)
+		}),
+	}
+}
+
+// outgoingContext copies correlation metadata into outgoing gRPC metadata
+func outgoingContext(ctx context.Context, config *OutboundConfig) context.Context {
+	md := FromContext(ctx)
+	existing, _ := metadata.FromOutgoingContext(ctx)
+
+	var pairs []string
+	add := func(key, value string) {
+		key = strings.ToLower(key)
+		if value != "" && len(existing.Get(key)) == 0 {
+			pairs = append(pairs, key, value)
+		}
+	}
+
+	add(HeaderRequestID, md.RequestID)
+	add(HeaderTraceParent, md.TraceParent)
+	add(HeaderTraceState, md.TraceState)
+	if config.PropagateAuth {
+		add(HeaderAuthorization, md.Authorization)
+	}
+
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+func setIfMissing(header http.Header, key, value string) {
+	if value != "" && header.Get(key) == "" {
+		header.Set(key, value)
+	}
+}
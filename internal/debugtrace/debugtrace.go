@@ -0,0 +1,136 @@
+// Package debugtrace collects what one request did inside the service (cache lookups, CQL
+// statements, repository retries) so the admin-only debug mode can return it with the response
+package debugtrace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxEntries caps the lookups and statements kept per request; the rest are only counted
+const maxEntries = 50
+
+type traceKey struct{}
+
+// Trace is one request's internals; safe for concurrent use
+type Trace struct {
+	start time.Time
+
+	mu         sync.Mutex
+	cache      []CacheLookup
+	statements []Statement
+	retries    int
+	dropped    int
+}
+
+// CacheLookup is one read against the cache tiers
+type CacheLookup struct {
+	Op       string  `json:"op"`
+	Key      string  `json:"key"`
+	Tier     string  `json:"tier"` // "local", "redis", "miss" or "error"
+	Duration float64 `json:"ms"`
+}
+
+// Statement is one attempt at a CQL statement or batch, as seen by the driver
+type Statement struct {
+	Statement string  `json:"statement"`
+	Host      string  `json:"host,omitempty"`
+	Attempt   int     `json:"attempt"` // Driver retry number, 0 for the first try
+	Rows      int     `json:"rows"`
+	Duration  float64 `json:"ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Summary is the trace as returned to the client
+type Summary struct {
+	TotalMs    float64       `json:"total_ms"`
+	CacheMs    float64       `json:"cache_ms"`
+	CQLMs      float64       `json:"cql_ms"`
+	Cache      []CacheLookup `json:"cache"`
+	Statements []Statement   `json:"statements"`
+	Retries    int           `json:"retries"` // Repository-level retries; driver retries show as attempts
+	Dropped    int           `json:"dropped,omitempty"`
+}
+
+// WithTrace starts a trace and returns a context that carries it
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{start: time.Now()}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+// FromContext returns the request's trace, or nil outside debug mode; all methods accept nil
+func FromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// AddCacheLookup records a cache read that took since-start
+func (t *Trace) AddCacheLookup(op, key, tier string, start time.Time) {
+	if t == nil {
+		return
+	}
+	lookup := CacheLookup{Op: op, Key: key, Tier: tier, Duration: Milliseconds(time.Since(start))}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.cache) >= maxEntries {
+		t.dropped++
+		return
+	}
+	t.cache = append(t.cache, lookup)
+}
+
+// AddStatement records one statement attempt
+func (t *Trace) AddStatement(statement Statement) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.statements) >= maxEntries {
+		t.dropped++
+		return
+	}
+	t.statements = append(t.statements, statement)
+}
+
+// AddRetry counts a repository-level retry
+func (t *Trace) AddRetry() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.retries++
+	t.mu.Unlock()
+}
+
+// Summary snapshots the trace with totals
+func (t *Trace) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := Summary{
+		TotalMs:    Milliseconds(time.Since(t.start)),
+		Cache:      append([]CacheLookup{}, t.cache...),
+		Statements: append([]Statement{}, t.statements...),
+		Retries:    t.retries,
+		Dropped:    t.dropped,
+	}
+	for _, lookup := range t.cache {
+		summary.CacheMs += lookup.Duration
+	}
+	for _, statement := range t.statements {
+		summary.CQLMs += statement.Duration
+	}
+	return summary
+}
+
+// Milliseconds converts a duration for the trace's JSON
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,37 @@
+package debugtrace
+
+import (
+	"acid/internal/jsoncodec"
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// TokenMetadataKey turns on debug mode for one call; its value must be the admin token
+	TokenMetadataKey = "x-debug-token"
+
+	// TraceTrailerKey carries the call's Summary as JSON
+	TraceTrailerKey = "x-debug-trace"
+)
+
+// UnaryServerInterceptor traces unary calls whose x-debug-token metadata matches the admin token
+// and returns the trace in the x-debug-trace trailer; an empty token disables debug mode
+func UnaryServerInterceptor(adminToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		provided := md.Get(TokenMetadataKey)
+		if adminToken == "" || len(provided) == 0 || subtle.ConstantTimeCompare([]byte(provided[0]), []byte(adminToken)) != 1 {
+			return handler(ctx, req)
+		}
+
+		ctx, trace := WithTrace(ctx)
+		resp, err := handler(ctx, req)
+		if encoded, marshalErr := jsoncodec.Marshal(trace.Summary()); marshalErr == nil {
+			grpc.SetTrailer(ctx, metadata.Pairs(TraceTrailerKey, string(encoded)))
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,267 @@
+// Package deprecation marks HTTP routes and gRPC methods deprecated in code. Callers of a
+// deprecated endpoint get Deprecation and Sunset headers, each call is counted per endpoint and
+// caller and logged as a warning, and /admin/deprecations reports who still calls what, so an
+// endpoint can be retired once its traffic is gone
+package deprecation
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Mode decides what happens to calls made after a notice's Sunset
+type Mode string
+
+const (
+	// Soft keeps serving the endpoint past its sunset, with the headers and warnings
+	Soft Mode = "soft"
+
+	// Hard rejects calls after the sunset: HTTP 410 Gone, gRPC UNIMPLEMENTED
+	Hard Mode = "hard"
+)
+
+// ParseMode parses "soft" or "hard"
+func ParseMode(s string) (Mode, bool) {
+	switch mode := Mode(s); mode {
+	case Soft, Hard:
+		return mode, true
+	}
+	return "", false
+}
+
+// maxCallers bounds the callers tracked per endpoint; the rest are counted under "other"
+const maxCallers = 100
+
+// Notice deprecates one or more endpoints (HTTP "METHOD /route" or gRPC full method names)
+type Notice struct {
+	Name      string
+	Endpoints []string
+
+	// Since is when the endpoints were deprecated (Deprecation header, RFC 9745)
+	Since time.Time
+
+	// Sunset is when they stop being served (Sunset header, RFC 8594); zero means not scheduled
+	Sunset time.Time
+
+	// Link points at migration docs (Link rel="deprecation")
+	Link string
+
+	// Replacement names what callers should move to, for the warnings and the report
+	Replacement string
+
+	Mode Mode
+}
+
+// Retired reports whether the notice rejects calls made at now
+func (n *Notice) Retired(now time.Time) bool {
+	return n.Mode == Hard && !n.Sunset.IsZero() && !now.Before(n.Sunset)
+}
+
+// SetHeaders calls set with each response header announcing the deprecation
+func (n *Notice) SetHeaders(set func(name, value string)) {
+	set("Deprecation", "@"+strconv.FormatInt(n.Since.Unix(), 10))
+	if !n.Sunset.IsZero() {
+		set("Sunset", n.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if n.Link != "" {
+		set("Link", "<"+n.Link+`>; rel="deprecation"; type="text/html"`)
+	}
+}
+
+// Config holds the deprecated endpoints and how often their calls are logged
+type Config struct {
+	// WarnInterval is the least time between two warnings about the same endpoint
+	WarnInterval time.Duration
+
+	Notices []Notice
+}
+
+// DefaultConfig warns about each deprecated endpoint at most once a minute. It declares no
+// notices: the deprecated aliases of user routes come with the routes (server.LegacyNotices),
+// and other endpoints are deprecated by adding a notice here
+func DefaultConfig() *Config {
+	return &Config{
+		WarnInterval: 1 * time.Minute,
+	}
+}
+
+// Registry looks up the notice of an endpoint and counts its calls
+type Registry struct {
+	config    *Config
+	logger    *zap.Logger
+	endpoints map[string]*endpointStats
+	order     []string
+}
+
+type endpointStats struct {
+	notice   *Notice
+	calls    atomic.Int64
+	rejected atomic.Int64
+	lastCall atomic.Int64 // Unix nanos
+	lastWarn atomic.Int64 // Unix nanos
+
+	mu      sync.Mutex
+	callers map[string]int64
+}
+
+// NewRegistry indexes the notices by endpoint; an endpoint listed twice keeps its first notice
+func NewRegistry(config *Config, logger *zap.Logger) *Registry {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	r := &Registry{
+		config:    config,
+		logger:    logger,
+		endpoints: make(map[string]*endpointStats),
+	}
+	for i := range config.Notices {
+		notice := &config.Notices[i]
+		if notice.Mode == "" {
+			notice.Mode = Soft
+		}
+		for _, endpoint := range notice.Endpoints {
+			if _, ok := r.endpoints[endpoint]; ok {
+				continue
+			}
+			r.endpoints[endpoint] = &endpointStats{notice: notice, callers: make(map[string]int64)}
+			r.order = append(r.order, endpoint)
+		}
+	}
+	return r
+}
+
+// Lookup returns the notice deprecating endpoint, if any
+func (r *Registry) Lookup(endpoint string) (*Notice, bool) {
+	stats, ok := r.endpoints[endpoint]
+	if !ok {
+		return nil, false
+	}
+	return stats.notice, true
+}
+
+// Record counts a call to a deprecated endpoint by caller and reports whether it must be
+// rejected. The first call, and then one per WarnInterval, is logged as a warning
+func (r *Registry) Record(endpoint, caller string) (rejected bool) {
+	stats, ok := r.endpoints[endpoint]
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	rejected = stats.notice.Retired(now)
+	calls := stats.calls.Add(1)
+	if rejected {
+		stats.rejected.Add(1)
+	}
+	stats.lastCall.Store(now.UnixNano())
+
+	stats.mu.Lock()
+	if _, tracked := stats.callers[caller]; !tracked && len(stats.callers) >= maxCallers {
+		caller = "other"
+	}
+	stats.callers[caller]++
+	stats.mu.Unlock()
+
+	last := stats.lastWarn.Load()
+	if now.UnixNano()-last >= int64(r.config.WarnInterval) && stats.lastWarn.CompareAndSwap(last, now.UnixNano()) {
+		fields := []zap.Field{
+			zap.String("endpoint", endpoint),
+			zap.String("notice", stats.notice.Name),
+			zap.String("caller", caller),
+			zap.Int64("calls", calls),
+			zap.String("replacement", stats.notice.Replacement),
+			zap.Bool("rejected", rejected),
+		}
+		if !stats.notice.Sunset.IsZero() {
+			fields = append(fields, zap.Time("sunset", stats.notice.Sunset))
+		}
+		r.logger.Warn("Deprecated endpoint called", fields...)
+	}
+	return rejected
+}
+
+// EndpointReport is the traffic of one deprecated endpoint since start
+type EndpointReport struct {
+	Endpoint    string         `json:"endpoint"`
+	Notice      string         `json:"notice"`
+	Mode        Mode           `json:"mode"`
+	Since       time.Time      `json:"since"`
+	Sunset      *time.Time     `json:"sunset,omitempty"`
+	Replacement string         `json:"replacement,omitempty"`
+	Link        string         `json:"link,omitempty"`
+	Retired     bool           `json:"retired"`
+	Calls       int64          `json:"calls"`
+	Rejected    int64          `json:"rejected"`
+	LastCall    *time.Time     `json:"last_call,omitempty"`
+	Callers     []CallerReport `json:"callers"`
+}
+
+// CallerReport counts one caller's calls: "subject:<name>" when authenticated, else "ip:<addr>"
+type CallerReport struct {
+	Caller string `json:"caller"`
+	Calls  int64  `json:"calls"`
+}
+
+// Report lists every deprecated endpoint in declaration order, callers busiest first
+func (r *Registry) Report() []EndpointReport {
+	now := time.Now()
+	report := make([]EndpointReport, 0, len(r.order))
+	for _, endpoint := range r.order {
+		stats := r.endpoints[endpoint]
+		notice := stats.notice
+		entry := EndpointReport{
+			Endpoint:    endpoint,
+			Notice:      notice.Name,
+			Mode:        notice.Mode,
+			Since:       notice.Since,
+			Replacement: notice.Replacement,
+			Link:        notice.Link,
+			Retired:     notice.Retired(now),
+			Calls:       stats.calls.Load(),
+			Rejected:    stats.rejected.Load(),
+		}
+		if !notice.Sunset.IsZero() {
+			sunset := notice.Sunset
+			entry.Sunset = &sunset
+		}
+		if last := stats.lastCall.Load(); last > 0 {
+			lastCall := time.Unix(0, last)
+			entry.LastCall = &lastCall
+		}
+
+		stats.mu.Lock()
+		entry.Callers = make([]CallerReport, 0, len(stats.callers))
+		for caller, calls := range stats.callers {
+			entry.Callers = append(entry.Callers, CallerReport{Caller: caller, Calls: calls})
+		}
+		stats.mu.Unlock()
+		sort.Slice(entry.Callers, func(i, j int) bool {
+			if entry.Callers[i].Calls != entry.Callers[j].Calls {
+				return entry.Callers[i].Calls > entry.Callers[j].Calls
+			}
+			return entry.Callers[i].Caller < entry.Callers[j].Caller
+		})
+		report = append(report, entry)
+	}
+	return report
+}
+
+// GetMetrics returns the number of deprecated endpoints and their calls and rejections
+func (r *Registry) GetMetrics() map[string]int64 {
+	var calls, rejected int64
+	for _, stats := range r.endpoints {
+		calls += stats.calls.Load()
+		rejected += stats.rejected.Load()
+	}
+	return map[string]int64{
+		"endpoints": int64(len(r.endpoints)),
+		"calls":     calls,
+		"rejected":  rejected,
+	}
+}
@@ -0,0 +1,67 @@
+package deprecation
+
+import (
+	"acid/internal/authz"
+	"acid/internal/clientip"
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor records calls to deprecated methods, announcing the deprecation in the
+// response header metadata, and rejects retired ones with UNIMPLEMENTED
+func UnaryServerInterceptor(registry *Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := registry.checkRPC(ctx, info.FullMethod, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) }); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming methods
+func StreamServerInterceptor(registry *Registry) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := registry.checkRPC(ss.Context(), info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (r *Registry) checkRPC(ctx context.Context, method string, setHeader func(metadata.MD) error) error {
+	notice, ok := r.Lookup(method)
+	if !ok {
+		return nil
+	}
+
+	if r.Record(method, rpcCaller(ctx)) {
+		return status.Errorf(codes.Unimplemented, "%s was retired on %s%s",
+			method, notice.Sunset.UTC().Format(time.RFC3339), replacementHint(notice))
+	}
+	md := metadata.MD{}
+	notice.SetHeaders(func(name, value string) { md.Set(strings.ToLower(name), value) })
+	_ = setHeader(md)
+	return nil
+}
+
+// rpcCaller identifies the caller by its grant's subject, else its address
+func rpcCaller(ctx context.Context) string {
+	if grant, ok := authz.FromContext(ctx); ok {
+		return "subject:" + grant.Subject
+	}
+	return "ip:" + clientip.FromContext(ctx).String()
+}
+
+// replacementHint completes a rejection message with what to call instead
+func replacementHint(notice *Notice) string {
+	if notice.Replacement == "" {
+		return ""
+	}
+	return "; use " + notice.Replacement
+}
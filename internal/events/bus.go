@@ -0,0 +1,314 @@
+// Package events is the in-process event bus. Producers (the outbox relay for service-layer
+// events, the CDC consumer) publish to it; consumers either register a synchronous Handler, whose
+// error goes back to the producer to retry, or take a Subscription, a buffered channel that drops
+// events it can't keep up with (SSE, GraphQL subscriptions, WebSocket). A Backend, when set, carries
+// events beyond this process before any local delivery
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// Event is a user lifecycle change delivered to in-process subscribers
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+
+	// Internal events only reach handlers: they skip the backend, the history and subscriptions,
+	// so they are never streamed to clients
+	Internal bool `json:"-"`
+}
+
+// NewEvent builds an event with a time-based ID and a JSON-encoded payload
+func NewEvent(eventType, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	return Event{
+		ID:          gocql.TimeUUID().String(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     data,
+		OccurredAt:  time.Now(),
+	}, nil
+}
+
+// Handler consumes events synchronously during Publish; its error is returned to the producer
+type Handler func(ctx context.Context, event Event) error
+
+// On registers a handler decoding the payload of eventType events into T. A payload that doesn't
+// decode is an error like any other. Must be called during startup
+func On[T any](b *Bus, name, eventType string, handle func(ctx context.Context, event Event, payload T) error) {
+	b.Handle(name, func(ctx context.Context, event Event) error {
+		var payload T
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		return handle(ctx, event, payload)
+	}, eventType)
+}
+
+// Backend carries events beyond this process, e.g. to NATS JetStream, so consumers elsewhere get
+// them durably
+type Backend interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// BusConfig holds event bus configuration
+type BusConfig struct {
+	// SubscriberBuffer is the per-subscriber channel size; a full buffer drops events
+	SubscriberBuffer int
+
+	// HistorySize is how many recent events are retained for Last-Event-ID resume
+	HistorySize int
+}
+
+// DefaultBusConfig returns sensible production defaults
+func DefaultBusConfig() *BusConfig {
+	return &BusConfig{
+		SubscriberBuffer: 64,
+		HistorySize:      256,
+	}
+}
+
+// BusMetrics tracks fan-out for observability
+type BusMetrics struct {
+	Published       atomic.Int64
+	Delivered       atomic.Int64
+	Dropped         atomic.Int64
+	BackendFailures atomic.Int64
+	HandlerFailures atomic.Int64
+}
+
+// handler is a registered Handler and the event types it takes (all when nil)
+type handler struct {
+	name   string
+	types  map[string]bool
+	handle Handler
+}
+
+// Bus fans events out to handlers and in-process subscribers (GraphQL, SSE, WebSocket)
+// Subscriptions never block Publish: a subscriber that can't keep up loses events instead of
+// stalling the publisher
+type Bus struct {
+	config   *BusConfig
+	logger   *zap.Logger
+	metrics  *BusMetrics
+	backend  Backend
+	handlers []handler
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	history     []Event // Oldest first, at most HistorySize entries
+}
+
+func NewBus(config *BusConfig, logger *zap.Logger) *Bus {
+	if config == nil {
+		config = DefaultBusConfig()
+	}
+
+	return &Bus{
+		config:      config,
+		logger:      logger,
+		metrics:     &BusMetrics{},
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// SetBackend sets the durable backend events are published to before local delivery; without
+// one events stay in this process. Must be called during startup
+func (b *Bus) SetBackend(backend Backend) {
+	b.backend = backend
+}
+
+// Handle registers a synchronous handler for the given event types, all when none. Handlers run
+// in registration order. Must be called during startup
+func (b *Bus) Handle(name string, handle Handler, types ...string) {
+	h := handler{name: name, handle: handle}
+	if len(types) > 0 {
+		h.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			h.types[t] = true
+		}
+	}
+	b.handlers = append(b.handlers, h)
+}
+
+// Close closes the backend
+func (b *Bus) Close() error {
+	if b.backend == nil {
+		return nil
+	}
+	return b.backend.Close()
+}
+
+// Subscription receives events of the requested types until Close is called
+type Subscription struct {
+	bus    *Bus
+	types  map[string]bool
+	events chan Event
+	once   sync.Once
+}
+
+// Subscribe registers a subscriber; no types means every event type
+func (b *Bus) Subscribe(types ...string) *Subscription {
+	sub := b.newSubscription(types)
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// SubscribeFrom registers a subscriber and returns the retained events published after lastEventID,
+// with no gap or overlap between the backlog and the live channel
+// If lastEventID is no longer retained the whole history is returned and resumed is false
+func (b *Bus) SubscribeFrom(lastEventID string, types ...string) (sub *Subscription, backlog []Event, resumed bool) {
+	sub = b.newSubscription(types)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := 0
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID == lastEventID {
+			start = i + 1
+			resumed = true
+			break
+		}
+	}
+
+	for _, event := range b.history[start:] {
+		if sub.matches(event.Type) {
+			backlog = append(backlog, event)
+		}
+	}
+
+	b.subscribers[sub] = struct{}{}
+	return sub, backlog, resumed
+}
+
+func (b *Bus) newSubscription(types []string) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		events: make(chan Event, b.config.SubscriberBuffer),
+	}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	return sub
+}
+
+// Publish delivers an event: to the backend, then to every matching handler, then to matching
+// subscriptions without blocking. A backend failure stops delivery and is returned; handler
+// failures don't stop the other handlers and are returned joined. Either way the producer should
+// retry, so handlers must tolerate an event delivered more than once
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.metrics.Published.Add(1)
+
+	if b.backend != nil && !event.Internal {
+		if err := b.backend.Publish(ctx, event); err != nil {
+			b.metrics.BackendFailures.Add(1)
+			return err
+		}
+	}
+
+	var errs []error
+	for _, h := range b.handlers {
+		if h.types != nil && !h.types[event.Type] {
+			continue
+		}
+		if err := h.handle(ctx, event); err != nil {
+			b.metrics.HandlerFailures.Add(1)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+
+	if !event.Internal {
+		b.fanOut(event)
+	}
+	return errors.Join(errs...)
+}
+
+// fanOut appends event to the history and offers it to every matching subscription
+func (b *Bus) fanOut(event Event) {
+	// Exclusive lock: history append and fan-out must be atomic with respect to SubscribeFrom
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.HistorySize > 0 {
+		if len(b.history) >= b.config.HistorySize {
+			b.history = append(b.history[:0], b.history[len(b.history)-b.config.HistorySize+1:]...)
+		}
+		b.history = append(b.history, event)
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(event.Type) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+			b.metrics.Delivered.Add(1)
+		default:
+			b.metrics.Dropped.Add(1)
+			b.logger.Warn("Event bus subscriber is full, dropping event",
+				zap.String("event_id", event.ID),
+				zap.String("event_type", event.Type))
+		}
+	}
+}
+
+// GetMetrics returns current bus metrics
+func (b *Bus) GetMetrics() map[string]int64 {
+	b.mu.RLock()
+	subscribers := len(b.subscribers)
+	b.mu.RUnlock()
+
+	return map[string]int64{
+		"published":        b.metrics.Published.Load(),
+		"delivered":        b.metrics.Delivered.Load(),
+		"dropped":          b.metrics.Dropped.Load(),
+		"backend_failures": b.metrics.BackendFailures.Load(),
+		"handler_failures": b.metrics.HandlerFailures.Load(),
+		"handlers":         int64(len(b.handlers)),
+		"subscribers":      int64(subscribers),
+	}
+}
+
+// Events returns the channel events are delivered on; it is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unregisters the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers, s)
+		s.bus.mu.Unlock()
+		close(s.events)
+	})
+}
+
+func (s *Subscription) matches(eventType string) bool {
+	return s.types == nil || s.types[eventType]
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"acid/internal/models"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Record is the stable, versioned shape of an exported user, independent of the Go model
+type Record struct {
+	ID          string            `json:"id"`
+	Username    string            `json:"username"`
+	Email       string            `json:"email"`
+	Phone       string            `json:"phone,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Preferences map[string]bool   `json:"preferences,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"` // Each value is JSON text
+}
+
+func RecordFromUser(user *models.User) *Record {
+	return &Record{
+		ID:          user.ID.String(),
+		Username:    user.Username,
+		Email:       user.Email,
+		Phone:       user.Phone,
+		CreatedAt:   user.CreatedAt,
+		Preferences: user.Preferences,
+		Attributes:  user.Attributes,
+	}
+}
+
+func (r *Record) ToUser() (*models.User, error) {
+	id, err := gocql.ParseUUID(r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id %q: %w", r.ID, err)
+	}
+	return &models.User{
+		ID:          id,
+		Username:    r.Username,
+		Email:       r.Email,
+		Phone:       r.Phone,
+		CreatedAt:   r.CreatedAt,
+		Preferences: r.Preferences,
+		Attributes:  r.Attributes,
+	}, nil
+}
+
+// Format encodes records into part files
+type Format interface {
+	// Name is recorded in the manifest and selects the format on restore
+	Name() string
+	// Extension is appended to part file names
+	Extension() string
+	NewWriter(w io.Writer) (RecordWriter, error)
+	// NewReader reads a downloaded part; a file allows formats that need random access
+	NewReader(f *os.File) (RecordReader, error)
+}
+
+type RecordWriter interface {
+	Write(record *Record) error
+	// Close flushes buffered data; it does not close the underlying writer
+	Close() error
+}
+
+type RecordReader interface {
+	// Read returns io.EOF after the last record
+	Read() (*Record, error)
+	Close() error
+}
+
+// formats lists the formats available for export and restore
+var formats = map[string]Format{
+	"ndjson": NDJSON{},
+}
+
+// FormatNames lists the registered formats, sorted
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatByName returns a registered format
+func FormatByName(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q (available: %s)", name, strings.Join(FormatNames(), ", "))
+	}
+	return format, nil
+}
+
+// NDJSON writes one JSON record per line, gzip-compressed
+type NDJSON struct{}
+
+func (NDJSON) Name() string      { return "ndjson" }
+func (NDJSON) Extension() string { return ".ndjson.gz" }
+
+func (NDJSON) NewWriter(w io.Writer) (RecordWriter, error) {
+	gz := gzip.NewWriter(w)
+	return &ndjsonWriter{gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+func (NDJSON) NewReader(f *os.File) (RecordReader, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	return &ndjsonReader{gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+type ndjsonWriter struct {
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(record *Record) error {
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.gz.Close()
+}
+
+type ndjsonReader struct {
+	gz  *gzip.Reader
+	dec *json.Decoder
+}
+
+func (r *ndjsonReader) Read() (*Record, error) {
+	var record Record
+	if err := r.dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *ndjsonReader) Close() error {
+	return r.gz.Close()
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"acid/internal/repository"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ManifestKey is written last, so a backup without it is incomplete
+const ManifestKey = "manifest.json"
+
+// manifestVersion is bumped when the manifest or record layout changes incompatibly
+const manifestVersion = 1
+
+// Manifest describes a logical backup: where each part is, what it holds and its checksum
+type Manifest struct {
+	Version   int       `json:"version"`
+	Keyspace  string    `json:"keyspace"`
+	Table     string    `json:"table"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	TotalRows int64     `json:"total_rows"`
+	Parts     []Part    `json:"parts"`
+}
+
+// Part is one exported token range
+type Part struct {
+	Key        string                `json:"key"`
+	TokenRange repository.TokenRange `json:"token_range"`
+	Rows       int64                 `json:"rows"`
+	Bytes      int64                 `json:"bytes"`
+	SHA256     string                `json:"sha256"` // Hex digest of the stored (compressed) file
+}
+
+func decodeManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d (expected %d)", manifest.Version, manifestVersion)
+	}
+	return &manifest, nil
+}
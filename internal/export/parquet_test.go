@@ -0,0 +1,78 @@
+//go:build parquet
+
+package export
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParquetRoundTrip(t *testing.T) {
+	records := []*Record{
+		{
+			ID:          "0192b7a4-3c1e-7d2a-9f4b-6a8e1c5d2f30",
+			Username:    "ada",
+			Email:       "ada@example.com",
+			CreatedAt:   time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+			Preferences: map[string]bool{"marketing": false, "digest": true},
+		},
+		{
+			ID:        "0192b7a4-3c1e-7d2a-9f4b-6a8e1c5d2f31",
+			Username:  "grace",
+			Email:     "grace@example.com",
+			CreatedAt: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "part-0"+Parquet{}.Extension())
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Parquet{}.NewWriter(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close writer: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	r, err := Parquet{}.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i, want := range records {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read record %d: %v", i, err)
+		}
+		if len(got.Preferences) == 0 && len(want.Preferences) == 0 {
+			got.Preferences = want.Preferences
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read after the last record = %v, want io.EOF", err)
+	}
+}
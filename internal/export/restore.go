@@ -0,0 +1,208 @@
+package export
+
+import (
+	"acid/internal/models"
+	"acid/internal/repository"
+	"acid/internal/workerpool"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// ErrChecksumMismatch is returned when a downloaded part doesn't match the manifest
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// RestoreConfig holds restore configuration
+type RestoreConfig struct {
+	// Concurrency is the number of parts downloaded and imported in parallel
+	Concurrency int
+
+	// BatchSize is the number of users handed to CreateUsersBatch at a time
+	BatchSize int
+
+	// VerifyOnly downloads and checks every part without writing to the database
+	VerifyOnly bool
+}
+
+// DefaultRestoreConfig returns sensible defaults
+func DefaultRestoreConfig() *RestoreConfig {
+	return &RestoreConfig{
+		Concurrency: 4,
+		BatchSize:   500,
+	}
+}
+
+// RestoreStats summarises a restore run
+type RestoreStats struct {
+	Parts    int
+	Rows     int64
+	Restored int64
+}
+
+// Restorer imports a backup written by Exporter back into the users table
+// Rows are upserted, so restoring over existing data overwrites matching users
+type Restorer struct {
+	repo   *repository.UserRepository
+	store  Store
+	config *RestoreConfig
+	logger *zap.Logger
+}
+
+func NewRestorer(repo *repository.UserRepository, store Store, config *RestoreConfig, logger *zap.Logger) *Restorer {
+	if config == nil {
+		config = DefaultRestoreConfig()
+	}
+
+	return &Restorer{
+		repo:   repo,
+		store:  store,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Restore reads the manifest, verifies each part's size and checksum and imports its rows
+func (r *Restorer) Restore(ctx context.Context) (*RestoreStats, error) {
+	manifest, err := r.readManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := FormatByName(manifest.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Restore started",
+		zap.String("source", r.store.URL(ManifestKey)),
+		zap.String("format", manifest.Format),
+		zap.Int("parts", len(manifest.Parts)),
+		zap.Int64("rows", manifest.TotalRows),
+		zap.Bool("verify_only", r.config.VerifyOnly))
+
+	var rows, restored atomic.Int64
+	pool := workerpool.New(ctx, &workerpool.Config{Workers: r.config.Concurrency, StopOnError: true})
+	for _, part := range manifest.Parts {
+		if pool.Submit(func(ctx context.Context) error {
+			read, written, err := r.restorePart(ctx, format, part)
+			rows.Add(read)
+			restored.Add(written)
+			if err != nil {
+				return fmt.Errorf("%s: %w", part.Key, err)
+			}
+			return nil
+		}) != nil {
+			break
+		}
+	}
+	err = pool.Wait()
+
+	stats := &RestoreStats{Parts: len(manifest.Parts), Rows: rows.Load(), Restored: restored.Load()}
+	if err != nil {
+		return stats, err
+	}
+
+	r.logger.Info("Restore finished",
+		zap.Int64("rows", stats.Rows),
+		zap.Int64("restored", stats.Restored))
+	return stats, nil
+}
+
+func (r *Restorer) readManifest(ctx context.Context) (*Manifest, error) {
+	body, err := r.store.Get(ctx, ManifestKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return decodeManifest(data)
+}
+
+// restorePart downloads a part to a temp file, verifies it and imports it in batches
+func (r *Restorer) restorePart(ctx context.Context, format Format, part Part) (rows, restored int64, err error) {
+	tmp, err := os.CreateTemp("", "acid-restore-*"+format.Extension())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	body, err := r.store.Get(ctx, part.Key)
+	if err != nil {
+		return 0, 0, err
+	}
+	hw := newHashingWriter(tmp)
+	_, err = io.Copy(hw, body)
+	body.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("download failed: %w", err)
+	}
+
+	if hw.n != part.Bytes || hw.sum() != part.SHA256 {
+		return 0, 0, fmt.Errorf("%w: got %d bytes sha256 %s, manifest has %d bytes sha256 %s",
+			ErrChecksumMismatch, hw.n, hw.sum(), part.Bytes, part.SHA256)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	reader, err := format.NewReader(tmp)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+
+	batch := make([]*models.User, 0, r.config.BatchSize)
+	flush := func() error {
+		if r.config.VerifyOnly || len(batch) == 0 {
+			batch = batch[:0]
+			return nil
+		}
+		n, err := r.repo.CreateUsersBatch(ctx, batch)
+		restored += int64(n)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, restored, fmt.Errorf("failed to decode record %d: %w", rows+1, err)
+		}
+
+		user, err := record.ToUser()
+		if err != nil {
+			return rows, restored, err
+		}
+		rows++
+
+		batch = append(batch, user)
+		if len(batch) >= r.config.BatchSize {
+			if err := flush(); err != nil {
+				return rows, restored, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return rows, restored, err
+	}
+
+	if rows != part.Rows {
+		return rows, restored, fmt.Errorf("row count mismatch: read %d, manifest has %d", rows, part.Rows)
+	}
+
+	r.logger.Info("Restore part done", zap.String("key", part.Key), zap.Int64("rows", rows))
+	return rows, restored, nil
+}
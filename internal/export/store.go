@@ -0,0 +1,174 @@
+package export
+
+import (
+	"acid/internal/httpclient"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrObjectNotFound is returned by Store.Get for missing keys
+var ErrObjectNotFound = errors.New("object not found")
+
+// Store is where backups are written; keys are relative, "/"-separated paths
+type Store interface {
+	// Put uploads the file at localPath under key
+	Put(ctx context.Context, key string, localPath string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// URL returns a human-readable location for key, for logs
+	URL(key string) string
+}
+
+// S3Options tweaks the S3 client for S3-compatible stores such as MinIO
+type S3Options struct {
+	// Endpoint overrides the AWS endpoint (empty uses the region's default)
+	Endpoint string
+	// PathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint
+	PathStyle bool
+}
+
+// OpenStore returns a Store for s3://bucket/prefix or file:///dir (a plain path is a directory)
+// S3 credentials and region come from the standard AWS environment/config chain
+func OpenStore(ctx context.Context, location string, opts S3Options) (Store, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid store location %q: %w", location, err)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid store location %q: missing bucket", location)
+		}
+		return newS3Store(ctx, u.Host, strings.Trim(u.Path, "/"), opts)
+	case "file":
+		return &FileStore{dir: u.Path}, nil
+	case "":
+		return &FileStore{dir: location}, nil
+	default:
+		return nil, fmt.Errorf("unsupported store scheme %q (expected s3:// or file://)", u.Scheme)
+	}
+}
+
+// FileStore keeps backups in a local directory, for development and restores from a copied backup
+type FileStore struct {
+	dir string
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, localPath string) error {
+	dest := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return f, err
+}
+
+func (s *FileStore) URL(key string) string {
+	return "file://" + filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// S3Store keeps backups in an S3 bucket under a prefix
+type S3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Store(ctx context.Context, bucket, prefix string, opts S3Options) (*S3Store, error) {
+	// The SDK retries by itself, and backups stream for longer than any per-attempt timeout
+	clientConfig := httpclient.DefaultConfig("s3")
+	clientConfig.MaxAttempts = 1
+	clientConfig.Timeout = 0
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithHTTPClient(httpclient.New(clientConfig).StandardClient()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+		o.UsePathStyle = opts.PathStyle
+	})
+
+	return &S3Store{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *S3Store) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return path.Join(s.prefix, key)
+}
+
+// Put uploads from a file so the SDK can seek for signing and retries
+func (s *S3Store) Put(ctx context.Context, key string, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   f,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", s.URL(key), err)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, s.URL(key))
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", s.URL(key), err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3Store) URL(key string) string {
+	return "s3://" + s.bucket + "/" + s.objectKey(key)
+}
@@ -0,0 +1,70 @@
+// Package fieldmask trims responses to the fields a caller asked for (?fields= on REST,
+// google.protobuf.FieldMask on gRPC), so consumers that need a couple of fields don't pay for
+// serializing and transferring the rest
+package fieldmask
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Parse splits a comma-separated field list, dropping blanks and duplicates
+func Parse(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	fields := make([]string, 0, strings.Count(raw, ",")+1)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Validate checks that every path names a top-level field of msg; nested paths are not supported
+func Validate(msg proto.Message, paths []string) error {
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	for _, path := range paths {
+		if fields.ByName(protoreflect.Name(path)) == nil {
+			return fmt.Errorf("unknown field %q", path)
+		}
+	}
+	return nil
+}
+
+// Prune clears every field of msg not named in paths; empty paths leave msg whole
+func Prune(msg proto.Message, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if err := Validate(msg, paths); err != nil {
+		return err
+	}
+
+	keep := make(map[protoreflect.Name]bool, len(paths))
+	for _, path := range paths {
+		keep[protoreflect.Name(path)] = true
+	}
+
+	m := msg.ProtoReflect()
+	var unselected []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !keep[fd.Name()] {
+			unselected = append(unselected, fd)
+		}
+		return true
+	})
+	for _, fd := range unselected {
+		m.Clear(fd)
+	}
+	return nil
+}
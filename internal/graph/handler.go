@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"acid/internal/jsoncodec"
+	"acid/internal/problem"
+	_ "embed"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxQueryDepth bounds nesting to keep malicious queries cheap to reject
+const maxQueryDepth = 10
+
+// Handler serves GraphQL over HTTP
+// Queries and mutations are plain JSON POSTs; subscriptions are streamed as server-sent
+// events (graphql-sse "distinct connections" mode) when the client sends Accept: text/event-stream
+type Handler struct {
+	schema *graphql.Schema
+	logger *zap.Logger
+}
+
+// Request is a standard GraphQL-over-HTTP request body
+type Request struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func NewHandler(resolver *Resolver, logger *zap.Logger) (*Handler, error) {
+	schema, err := graphql.ParseSchema(schemaSDL, resolver, graphql.MaxDepth(maxQueryDepth))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		schema: schema,
+		logger: logger,
+	}, nil
+}
+
+// Serve executes a GraphQL request
+func (h *Handler) Serve(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.subscribe(c, &req)
+		return
+	}
+
+	response := h.schema.Exec(c.Request.Context(), req.Query, req.OperationName, req.Variables)
+	c.JSON(200, response)
+}
+
+// subscribe streams each subscription result as a "next" event and ends with "complete"
+func (h *Handler) subscribe(c *gin.Context, req *Request) {
+	ctx := c.Request.Context()
+
+	responses, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// Streams outlive the server's WriteTimeout; lift the deadline for this connection only
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("Failed to clear write deadline for GraphQL subscription", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(200)
+
+	c.Stream(func(w io.Writer) bool {
+		response, ok := <-responses
+		if !ok {
+			c.SSEvent("complete", "")
+			return false
+		}
+
+		data, err := jsoncodec.Marshal(response)
+		if err != nil {
+			h.logger.Error("Failed to encode GraphQL subscription response", zap.Error(err))
+			return false
+		}
+		c.SSEvent("next", string(data))
+		return true
+	})
+}
@@ -0,0 +1,250 @@
+package graph
+
+import (
+	"acid/internal/authz"
+	"acid/internal/events"
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"acid/internal/services"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gocql/gocql"
+	graphql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+)
+
+// Resolver is the root resolver for queries, mutations and subscriptions
+// It shares UserService and the REST DTOs so every transport applies the same rules
+type Resolver struct {
+	service *services.UserService
+	bus     *events.Bus
+	logger  *zap.Logger
+}
+
+func NewResolver(service *services.UserService, bus *events.Bus, logger *zap.Logger) *Resolver {
+	return &Resolver{
+		service: service,
+		bus:     bus,
+		logger:  logger,
+	}
+}
+
+// --- Query ---
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	log := logger.For(ctx, r.logger)
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, source, err := r.service.GetUser(ctx, id.String())
+	if err != nil {
+		log.Warn("GraphQL user lookup failed", zap.String("id", id.String()), zap.Error(err))
+		return nil, nil // Unknown users resolve to null, matching REST's 404
+	}
+
+	log.Info("GraphQL user resolved", zap.String("id", id.String()), zap.String("source", source))
+	return &userResolver{user: user}, nil
+}
+
+// --- Mutation ---
+
+type createUserInput struct {
+	ID       *graphql.ID
+	Username string
+	Email    string
+}
+
+func (r *Resolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userResolver, error) {
+	log := logger.For(ctx, r.logger)
+	req := models.UserRequest{
+		Username: args.Input.Username,
+		Email:    args.Input.Email,
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, err
+	}
+
+	user, err := models.NewUser(req.Username, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user")
+	}
+	create := r.service.CreateUser
+	if args.Input.ID != nil {
+		if user.ID, err = models.ParseClientUserID(string(*args.Input.ID)); err != nil {
+			return nil, err
+		}
+		create = r.service.CreateUserWithID
+	}
+
+	if err := create(ctx, user); err != nil {
+		if errors.Is(err, services.ErrReadOnly) || errors.Is(err, repository.ErrUserExists) ||
+			errors.Is(err, repository.ErrEmailTaken) || errors.Is(err, repository.ErrUsernameTaken) {
+			return nil, err
+		}
+		log.Error("Failed to save user to database", zap.Error(err))
+		return nil, fmt.Errorf("failed to save user")
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+type emailPreferenceInput struct {
+	Category string
+	Enabled  bool
+}
+
+func (r *Resolver) UpdatePreferences(ctx context.Context, args struct {
+	ID    graphql.ID
+	Email []emailPreferenceInput
+}) ([]*emailPreferenceResolver, error) {
+	// Like PATCH /users/:id/preferences, even where anonymous writes are served
+	if grant, ok := authz.FromContext(ctx); !ok || !grant.Allows(authz.UsersWrite) {
+		return nil, fmt.Errorf("updatePreferences requires permission %s", authz.UsersWrite)
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := models.PreferencesRequest{Email: make(map[string]bool, len(args.Email))}
+	for _, pref := range args.Email {
+		req.Email[pref.Category] = pref.Enabled
+	}
+
+	preferences, err := r.service.UpdatePreferences(ctx, id, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEmailPreferenceResolvers(*preferences), nil
+}
+
+// --- Subscription ---
+
+func (r *Resolver) UserEvents(ctx context.Context, args struct{ Types *[]string }) (<-chan *userEventResolver, error) {
+	if r.bus == nil {
+		return nil, fmt.Errorf("user events are not available")
+	}
+
+	var types []string
+	if args.Types != nil {
+		types = *args.Types
+	}
+
+	sub := r.bus.Subscribe(types...)
+	out := make(chan *userEventResolver)
+
+	safego.Go("graphql.subscription", func() {
+		defer close(out)
+		defer sub.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				select {
+				case out <- &userEventResolver{event: event}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	})
+
+	return out, nil
+}
+
+// --- Types ---
+
+type userResolver struct {
+	user *models.User
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(u.user.ID.String())
+}
+
+func (u *userResolver) Username() string {
+	return u.user.Username
+}
+
+func (u *userResolver) Email() string {
+	return u.user.Email
+}
+
+func (u *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: u.user.CreatedAt}
+}
+
+func (u *userResolver) Preferences() []*emailPreferenceResolver {
+	return newEmailPreferenceResolvers(u.user.EffectivePreferences())
+}
+
+type emailPreferenceResolver struct {
+	category string
+	enabled  bool
+}
+
+func (p *emailPreferenceResolver) Category() string {
+	return p.category
+}
+
+func (p *emailPreferenceResolver) Enabled() bool {
+	return p.enabled
+}
+
+// newEmailPreferenceResolvers lists preferences sorted by category for stable output
+func newEmailPreferenceResolvers(preferences models.PreferencesResponse) []*emailPreferenceResolver {
+	resolvers := make([]*emailPreferenceResolver, 0, len(preferences.Email))
+	for category, enabled := range preferences.Email {
+		resolvers = append(resolvers, &emailPreferenceResolver{category: category, enabled: enabled})
+	}
+	sort.Slice(resolvers, func(i, j int) bool {
+		return resolvers[i].category < resolvers[j].category
+	})
+	return resolvers
+}
+
+type userEventResolver struct {
+	event events.Event
+}
+
+func (e *userEventResolver) ID() graphql.ID {
+	return graphql.ID(e.event.ID)
+}
+
+func (e *userEventResolver) Type() string {
+	return e.event.Type
+}
+
+func (e *userEventResolver) AggregateID() graphql.ID {
+	return graphql.ID(e.event.AggregateID)
+}
+
+func (e *userEventResolver) Payload() string {
+	return string(e.event.Payload)
+}
+
+func (e *userEventResolver) OccurredAt() graphql.Time {
+	return graphql.Time{Time: e.event.OccurredAt}
+}
+
+func parseID(id graphql.ID) (gocql.UUID, error) {
+	parsed, err := gocql.ParseUUID(string(id))
+	if err != nil {
+		return gocql.UUID{}, fmt.Errorf("invalid id %q: must be a UUID", id)
+	}
+	return parsed, nil
+}
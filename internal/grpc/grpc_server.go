@@ -1,40 +1,152 @@
 package grpc
 
 import (
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/fieldmask"
+	"acid/internal/logger"
 	"acid/internal/models"
+	"acid/internal/repository"
 	"acid/internal/services"
 	pb "acid/proto/acid"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
 
+	"github.com/gocql/gocql"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+// MethodPermissions annotate the Acid methods with the permission they require of callers
+// presenting an access token or a registered API key; methods missing here require admin
+var MethodPermissions = map[string]authz.Permission{
+	pb.Acid_CreateUser_FullMethodName: authz.UsersWrite,
+	pb.Acid_FetchUser_FullMethodName:  authz.UsersRead,
+	pb.Acid_UserExists_FullMethodName: authz.UsersRead,
+}
+
 // AcidServer implements the gRPC Acid service
 type AcidServer struct {
 	pb.UnimplementedAcidServer
 	userService *services.UserService
+	idempotency *cache.IdempotencyStore
 	logger      *zap.Logger
 }
 
-// NewAcidServer creates a new gRPC server instance
-func NewAcidServer(userService *services.UserService, logger *zap.Logger) *AcidServer {
+// NewAcidServer creates a new gRPC server instance. A nil idempotency store (no cache) runs
+// every CreateUser, request_id or not
+func NewAcidServer(userService *services.UserService, idempotency *cache.IdempotencyStore, logger *zap.Logger) *AcidServer {
 	return &AcidServer{
 		userService: userService,
+		idempotency: idempotency,
 		logger:      logger,
 	}
 }
 
-// CreateUser implements the createUser RPC method
+// maxRequestIDLength bounds the client-supplied request_id stored in Redis keys
+const maxRequestIDLength = 128
+
+// createUserResult is what a CreateUser with a request_id stores for its retries
+type createUserResult struct {
+	Response pb.RegisterUserResponse_Status `json:"response"`
+	Code     codes.Code                     `json:"code"`
+	Message  string                         `json:"message,omitempty"`
+}
+
+// CreateUser implements the createUser RPC method. A call carrying a request_id runs once per
+// idempotency window: retries with the same ID and request get the first call's result back,
+// marked with idempotent-replayed metadata
 func (s *AcidServer) CreateUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
-	s.logger.Info("gRPC CreateUser called",
+	if req.RequestId == "" || s.idempotency == nil {
+		return s.createUser(ctx, req)
+	}
+	if len(req.RequestId) > maxRequestIDLength {
+		return &pb.RegisterUserResponse{
+			Response: pb.RegisterUserResponse_FAILURE,
+		}, status.Errorf(codes.InvalidArgument, "request_id must be at most %d characters", maxRequestIDLength)
+	}
+
+	log := logger.For(ctx, s.logger).With(zap.String("request_id", req.RequestId))
+	fingerprint, err := createUserFingerprint(req)
+	if err != nil {
+		log.Error("Failed to fingerprint CreateUser request", zap.Error(err))
+		return &pb.RegisterUserResponse{
+			Response: pb.RegisterUserResponse_FAILURE,
+		}, status.Error(codes.Internal, "failed to create user")
+	}
+
+	var replay createUserResult
+	replayed, err := s.idempotency.Begin(ctx, req.RequestId, fingerprint, &replay)
+	switch {
+	case errors.Is(err, cache.ErrRequestInProgress):
+		return &pb.RegisterUserResponse{
+			Response: pb.RegisterUserResponse_FAILURE,
+		}, status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, cache.ErrIdempotencyKeyReused):
+		return &pb.RegisterUserResponse{
+			Response: pb.RegisterUserResponse_FAILURE,
+		}, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		log.Warn("Failed to check request_id, creating without deduplication", zap.Error(err))
+		return s.createUser(ctx, req)
+	case replayed:
+		log.Info("Replaying CreateUser result", zap.Stringer("code", replay.Code))
+		if err := grpc.SetHeader(ctx, metadata.Pairs("idempotent-replayed", "true")); err != nil {
+			log.Warn("Failed to set idempotent-replayed header", zap.Error(err))
+		}
+		return &pb.RegisterUserResponse{Response: replay.Response}, status.Error(replay.Code, replay.Message)
+	}
+
+	resp, err := s.createUser(ctx, req)
+	result := createUserResult{Response: resp.GetResponse(), Code: status.Code(err)}
+	if st, ok := status.FromError(err); ok && err != nil {
+		result.Message = st.Message()
+	}
+
+	// Only outcomes a retry would repeat are stored; anything else may succeed when retried
+	done := context.WithoutCancel(ctx)
+	switch result.Code {
+	case codes.OK, codes.InvalidArgument, codes.AlreadyExists:
+		if err := s.idempotency.Complete(done, req.RequestId, fingerprint, result); err != nil {
+			log.Warn("Failed to store CreateUser result for retries", zap.Error(err))
+		}
+	default:
+		if err := s.idempotency.Release(done, req.RequestId); err != nil {
+			log.Warn("Failed to release request_id", zap.Error(err))
+		}
+	}
+	return resp, err
+}
+
+// createUserFingerprint identifies a request by its fields other than request_id, so reusing an
+// ID for a different user is refused instead of replaying someone else's result
+func createUserFingerprint(req *pb.RegisterUserRequest) (string, error) {
+	body := proto.Clone(req).(*pb.RegisterUserRequest)
+	body.RequestId = ""
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *AcidServer) createUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.RegisterUserResponse, error) {
+	log := logger.For(ctx, s.logger)
+	log.Info("gRPC CreateUser called",
 		zap.String("name", req.Name),
 		zap.String("email", req.Email))
 
 	// Validate input
 	if req.Name == "" || req.Email == "" {
-		s.logger.Warn("Invalid input for CreateUser",
+		log.Warn("Invalid input for CreateUser",
 			zap.String("name", req.Name),
 			zap.String("email", req.Email))
 		return &pb.RegisterUserResponse{
@@ -45,28 +157,40 @@ func (s *AcidServer) CreateUser(ctx context.Context, req *pb.RegisterUserRequest
 	// Create user model
 	user, err := models.NewUser(req.Name, req.Email)
 	if err != nil {
-		s.logger.Error("Failed to create user model", zap.Error(err))
+		log.Error("Failed to create user model", zap.Error(err))
 		return &pb.RegisterUserResponse{
 			Response: pb.RegisterUserResponse_FAILURE,
 		}, status.Error(codes.Internal, "failed to create user")
 	}
 
+	if s.userService.ReadOnly() {
+		return &pb.RegisterUserResponse{
+			Response: pb.RegisterUserResponse_FAILURE,
+		}, status.Error(codes.FailedPrecondition, services.ErrReadOnly.Error())
+	}
+
 	// Check if email already exists (using cache)
-	emailKey := "email:" + req.Email
+	keys := s.userService.CacheManager.Keys()
+	emailKey := keys.Key(cache.EntityEmail, req.Email)
 	exists, err := s.userService.CacheManager.Exists(ctx, emailKey)
 	if err != nil {
-		s.logger.Warn("Failed to check email in cache", zap.Error(err))
+		log.Warn("Failed to check email in cache", zap.Error(err))
 		// Continue without cache check (graceful degradation)
 	} else if exists {
-		s.logger.Warn("Email already exists", zap.String("email", req.Email))
+		log.Warn("Email already exists", zap.String("email", req.Email))
 		return &pb.RegisterUserResponse{
 			Response: pb.RegisterUserResponse_FAILURE,
 		}, status.Error(codes.AlreadyExists, "email already registered")
 	}
 
-	// Save to database
-	if err := s.userService.Repo.CreateUser(user); err != nil {
-		s.logger.Error("Failed to save user to database",
+	// Save to database; the cache check above misses emails registered over HTTP or evicted
+	if err := s.userService.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) || errors.Is(err, repository.ErrUsernameTaken) {
+			return &pb.RegisterUserResponse{
+				Response: pb.RegisterUserResponse_FAILURE,
+			}, status.Error(codes.AlreadyExists, err.Error())
+		}
+		log.Error("Failed to save user to database",
 			zap.String("email", req.Email),
 			zap.Error(err))
 		return &pb.RegisterUserResponse{
@@ -74,17 +198,17 @@ func (s *AcidServer) CreateUser(ctx context.Context, req *pb.RegisterUserRequest
 		}, status.Error(codes.Internal, "failed to save user")
 	}
 
-	// Cache the email for uniqueness check (stores user_id as string)
-	// Reuse emailKey from above
-	if err := s.userService.CacheManager.Set(ctx, emailKey, user.ID.String()); err != nil {
-		s.logger.Warn("Failed to cache email", zap.Error(err))
+	// Cache the email for uniqueness check (stores user_id as string) and prime the
+	// user object in the same MULTI/EXEC round trip. Reuse emailKey from above
+	if err := s.userService.CacheManager.SetMany(ctx, map[string]any{
+		emailKey: user.ID.String(),
+		keys.Key(cache.EntityUser, user.ID.String()): user,
+	}); err != nil {
+		log.Warn("Failed to cache email", zap.Error(err))
 		// Don't fail the request, user is already created
 	}
 
-	// Note: We don't cache the user object here. It will be cached automatically
-	// when FetchUser is called via GetOrSetJSON pattern.
-
-	s.logger.Info("User created successfully via gRPC",
+	log.Info("User created successfully via gRPC",
 		zap.String("id", user.ID.String()),
 		zap.String("email", req.Email))
 
@@ -95,40 +219,132 @@ func (s *AcidServer) CreateUser(ctx context.Context, req *pb.RegisterUserRequest
 
 // FetchUser implements the fetchUser RPC method
 func (s *AcidServer) FetchUser(ctx context.Context, req *pb.FetchUserRequest) (*pb.FetchUserResponse, error) {
-	s.logger.Info("gRPC FetchUser called", zap.String("user_id", req.UserId))
+	log := logger.For(ctx, s.logger)
+	log.Info("gRPC FetchUser called", zap.String("user_id", req.UserId))
 
 	// Validate input
 	if req.UserId == "" {
-		s.logger.Warn("Empty user_id provided")
+		log.Warn("Empty user_id provided")
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
+	paths := req.GetFieldMask().GetPaths()
+	if err := fieldmask.Validate(&pb.FetchUserResponse{}, paths); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid field_mask: "+err.Error())
+	}
 
-	var user models.User
-
-	// Try to get from cache or database
-	source, err := s.userService.CacheManager.GetOrSetJSON(
-		ctx,
-		"user:"+req.UserId,
-		&user,
-		func() (interface{}, error) {
-			s.logger.Info("Fetching user from database", zap.String("user_id", req.UserId))
-			return s.userService.Repo.GetUserByID(req.UserId)
-		},
-	)
+	// The protobuf entry answers without decoding JSON or mapping the model again
+	protoKey := s.userService.CacheManager.Keys().Key(cache.EntityUserProto, req.UserId)
+	if resp, source, ok := s.cachedFetchUser(ctx, protoKey); ok {
+		if s.userService.Degraded() {
+			if err := grpc.SetHeader(ctx, metadata.Pairs("x-served-from", services.SourceCacheDegraded)); err != nil {
+				log.Warn("Failed to set x-served-from header", zap.Error(err))
+			}
+		}
+		log.Info("User fetched successfully via gRPC",
+			zap.String("user_id", req.UserId),
+			zap.String("source", source))
+		if err := fieldmask.Prune(resp, paths); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid field_mask: "+err.Error())
+		}
+		return resp, nil
+	}
 
+	// Try to get from cache or database (cache only while the database is degraded)
+	ctx, stale := cache.WithStaleReport(ctx)
+	user, source, err := s.userService.GetUser(ctx, req.UserId)
+	if errors.Is(err, services.ErrDegraded) {
+		log.Warn("User not cached while database is degraded", zap.String("user_id", req.UserId))
+		return nil, status.Error(codes.Unavailable, "service degraded, user not available from cache")
+	}
 	if err != nil {
-		s.logger.Error("Failed to fetch user",
+		log.Error("Failed to fetch user",
 			zap.String("user_id", req.UserId),
 			zap.Error(err))
 		return nil, status.Error(codes.NotFound, "user not found")
 	}
 
-	s.logger.Info("User fetched successfully via gRPC",
+	if source == services.SourceCacheDegraded {
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-served-from", source)); err != nil {
+			log.Warn("Failed to set x-served-from header", zap.Error(err))
+		}
+	}
+	if stale.Served {
+		age := strconv.Itoa(int(stale.Age().Seconds()))
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-served-from", source, "x-stale-age", age)); err != nil {
+			log.Warn("Failed to set x-served-from header", zap.Error(err))
+		}
+	}
+
+	log.Info("User fetched successfully via gRPC",
 		zap.String("user_id", req.UserId),
 		zap.String("source", source))
 
-	return &pb.FetchUserResponse{
+	resp := &pb.FetchUserResponse{
 		Name:  user.Username,
 		Email: user.Email,
+		Id:    user.ID.String(),
+	}
+	// Fallback answers are not stored: they must not outlive the outage that produced them. Nor is
+	// the answer for a merged ID, which purges of the user it resolved to don't reach
+	if source != services.SourceCacheDegraded && !stale.Served && user.ID.String() == req.UserId {
+		s.cacheFetchUser(ctx, protoKey, resp)
+	}
+	if err := fieldmask.Prune(resp, paths); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid field_mask: "+err.Error())
+	}
+	return resp, nil
+}
+
+// cachedFetchUser returns the cached protobuf FetchUser response and the tier it came from
+func (s *AcidServer) cachedFetchUser(ctx context.Context, key string) (*pb.FetchUserResponse, string, bool) {
+	log := logger.For(ctx, s.logger)
+	raw, source, err := s.userService.CacheManager.Get(ctx, key)
+	if err != nil {
+		return nil, "", false
+	}
+
+	resp := &pb.FetchUserResponse{}
+	if err := proto.Unmarshal([]byte(raw), resp); err != nil {
+		log.Warn("Ignoring unreadable cached FetchUser response", zap.String("key", key), zap.Error(err))
+		return nil, "", false
+	}
+	return resp, source, true
+}
+
+// cacheFetchUser stores the full (unmasked) response; UserService purges it with the JSON entry
+func (s *AcidServer) cacheFetchUser(ctx context.Context, key string, resp *pb.FetchUserResponse) {
+	log := logger.For(ctx, s.logger)
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		log.Warn("Failed to encode FetchUser response for cache", zap.Error(err))
+		return
+	}
+	if err := s.userService.CacheManager.Set(ctx, key, string(data)); err != nil {
+		log.Warn("Failed to cache FetchUser response", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// UserExists implements the userExists RPC method; it answers from the cache when it can and
+// never loads the full user
+func (s *AcidServer) UserExists(ctx context.Context, req *pb.UserExistsRequest) (*pb.UserExistsResponse, error) {
+	log := logger.For(ctx, s.logger)
+	if _, err := gocql.ParseUUID(req.UserId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "user_id must be a valid UUID")
+	}
+
+	exists, source, err := s.userService.UserExists(ctx, req.UserId)
+	if errors.Is(err, services.ErrDegraded) {
+		return nil, status.Error(codes.Unavailable, "service degraded, user not available from cache")
+	}
+	if err != nil {
+		log.Error("Failed to check user existence",
+			zap.String("user_id", req.UserId),
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to check user existence")
+	}
+
+	return &pb.UserExistsResponse{
+		Exists: exists,
+		Source: source,
 	}, nil
 }
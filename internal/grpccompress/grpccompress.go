@@ -0,0 +1,115 @@
+// Package grpccompress registers the gzip and deflate gRPC compressors and picks which one the
+// server compresses responses with. Importing it is enough for a client or server to accept
+// either encoding; grpc-go only ships gzip
+package grpccompress
+
+import (
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+)
+
+// Compressor names, as sent in grpc-encoding
+const (
+	Gzip     = gzip.Name
+	Deflate  = "deflate"
+	Identity = encoding.Identity
+)
+
+// Parse checks a compressor name; "" is returned as is and means gRPC's default
+func Parse(name string) (string, error) {
+	switch name {
+	case "", Gzip, Deflate, Identity:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown gRPC compressor %q, expected gzip, deflate or identity", name)
+}
+
+// Config selects how the server compresses responses
+type Config struct {
+	// Compressor compresses every response large enough to the clients that accept it. Empty
+	// keeps gRPC's default: responses use the compressor of the request, if any
+	Compressor string
+
+	// MinSize is the smallest response, in bytes, worth compressing; smaller ones are sent
+	// uncompressed even to clients that compressed their request
+	MinSize int
+}
+
+// DefaultConfig mirrors the client's compressor, for responses of 1KiB and more
+func DefaultConfig() *Config {
+	return &Config{MinSize: 1024}
+}
+
+// UnaryServerInterceptor picks the response compressor once the response is known. Streams keep
+// gRPC's default, as their messages are not known when the headers go out
+func UnaryServerInterceptor(config *Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		message, ok := resp.(proto.Message)
+		if err != nil || !ok {
+			return resp, err
+		}
+
+		if proto.Size(message) < config.MinSize {
+			_ = grpc.SetSendCompressor(ctx, Identity)
+			return resp, err
+		}
+		if config.Compressor != "" {
+			if accepted, _ := grpc.ClientSupportedCompressors(ctx); slices.Contains(accepted, config.Compressor) {
+				_ = grpc.SetSendCompressor(ctx, config.Compressor)
+			}
+		}
+		return resp, err
+	}
+}
+
+func init() {
+	encoding.RegisterCompressor(&deflateCompressor{})
+}
+
+// deflateCompressor is raw DEFLATE (RFC 1951), with pooled writers as flate.NewWriter allocates
+// several hundred KiB
+type deflateCompressor struct {
+	writers sync.Pool
+}
+
+type deflateWriter struct {
+	*flate.Writer
+	pool *sync.Pool
+}
+
+func (c *deflateCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if dw, ok := c.writers.Get().(*deflateWriter); ok {
+		dw.Reset(w)
+		return dw, nil
+	}
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return &deflateWriter{Writer: fw, pool: &c.writers}, nil
+}
+
+// Close flushes the stream and returns the writer to the pool
+func (w *deflateWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w)
+	return err
+}
+
+func (c *deflateCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return flate.NewReader(r), nil
+}
+
+func (c *deflateCompressor) Name() string {
+	return Deflate
+}
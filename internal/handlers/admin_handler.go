@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"acid/internal/cache"
+	"acid/internal/ids"
+	"acid/internal/models"
+	"acid/internal/outbox"
+	"acid/internal/pagination"
+	"acid/internal/problem"
+	"acid/internal/repository"
+	"acid/internal/saga"
+	"acid/internal/scheduler"
+	"acid/internal/services"
+	"acid/internal/utils"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+const defaultAdminListLimit = 100
+
+// dlqPagination pages GET /admin/outbox/dlq
+var dlqPagination = pagination.Config{DefaultLimit: defaultAdminListLimit, MaxLimit: 1000}
+
+// sagaPagination pages GET /admin/sagas
+var sagaPagination = pagination.Config{DefaultLimit: defaultAdminListLimit, MaxLimit: 1000}
+
+type AdminHandler struct {
+	relay        *outbox.Relay
+	sagas        *saga.Runner
+	scheduler    *scheduler.Scheduler
+	cacheManager *cache.CacheManager
+	userService  *services.UserService
+	logger       *zap.Logger
+}
+
+// NewAdminHandler creates the admin API handler; cacheManager may be nil when running without cache
+func NewAdminHandler(relay *outbox.Relay, sagas *saga.Runner, scheduler *scheduler.Scheduler, cacheManager *cache.CacheManager, userService *services.UserService, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		relay:        relay,
+		sagas:        sagas,
+		scheduler:    scheduler,
+		cacheManager: cacheManager,
+		userService:  userService,
+		logger:       logger,
+	}
+}
+
+// SetCacheTierRequest switches a cache tier on or off
+type SetCacheTierRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// ReplayRequest selects dead-lettered events to re-queue; empty IDs replays up to Limit events
+type ReplayRequest struct {
+	IDs   []string `json:"ids"`
+	Limit int      `json:"limit"`
+}
+
+// ListOutboxDLQ returns dead-lettered outbox events a page at a time, shard by shard
+func (h *AdminHandler) ListOutboxDLQ(c *gin.Context) {
+	var from outbox.DLQPosition
+	page, ok := pagination.FromQuery(c, dlqPagination, &from)
+	if !ok {
+		return
+	}
+	if from.Shard < 0 || from.Shard >= outbox.NumShards {
+		problem.Abort(c, problem.FieldProblem(pagination.CursorParam, pagination.ErrInvalidCursor.Error()))
+		return
+	}
+
+	events, next, err := h.relay.ListDLQPage(from, page.Limit)
+	if err != nil {
+		h.logger.Error("Failed to list outbox DLQ", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list outbox DLQ"))
+		return
+	}
+
+	meta := pagination.NewMeta(len(events), next, pagination.Total(h.relay.DLQDepth()))
+	pagination.SetLink(c, page, meta)
+	c.JSON(200, gin.H{
+		"events": events,
+		"count":  len(events),
+		"meta":   meta,
+	})
+}
+
+// ReplayOutbox moves dead-lettered events back to the outbox for another publish attempt
+func (h *AdminHandler) ReplayOutbox(c *gin.Context) {
+	var req ReplayRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.FromBindError(err))
+			return
+		}
+	}
+
+	ids := make([]gocql.UUID, 0, len(req.IDs))
+	for _, raw := range req.IDs {
+		id, err := gocql.ParseUUID(raw)
+		if err != nil {
+			problem.Abort(c, problem.FieldProblem("ids", "invalid event id: "+raw))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAdminListLimit
+	}
+
+	replayed, err := h.relay.Replay(ids, limit)
+	if err != nil {
+		h.logger.Error("Failed to replay outbox DLQ", zap.Int("replayed", replayed), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to replay outbox DLQ").
+			With("replayed", replayed))
+		return
+	}
+
+	c.JSON(200, gin.H{"replayed": replayed})
+}
+
+// GetOutboxMetrics returns relay throughput, lag and DLQ depth
+func (h *AdminHandler) GetOutboxMetrics(c *gin.Context) {
+	c.JSON(200, gin.H{"metrics": h.relay.GetMetrics()})
+}
+
+// ListStuckSagas returns sagas that failed to compensate or have been running for longer than
+// SAGA_STUCK_AFTER, such as a user creation interrupted by a crash, a page at a time
+func (h *AdminHandler) ListStuckSagas(c *gin.Context) {
+	var from saga.Position
+	page, ok := pagination.FromQuery(c, sagaPagination, &from)
+	if !ok {
+		return
+	}
+	if from.Shard < 0 || from.Shard >= saga.NumShards {
+		problem.Abort(c, problem.FieldProblem(pagination.CursorParam, pagination.ErrInvalidCursor.Error()))
+		return
+	}
+
+	sagas, next, err := h.sagas.ListStuck(c.Request.Context(), from, page.Limit)
+	if err != nil {
+		h.logger.Error("Failed to list stuck sagas", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list stuck sagas"))
+		return
+	}
+
+	meta := pagination.NewMeta(len(sagas), next, nil)
+	pagination.SetLink(c, page, meta)
+	c.JSON(200, gin.H{
+		"sagas":       sagas,
+		"count":       len(sagas),
+		"stuck_after": h.sagas.StuckAfter().String(),
+		"metrics":     h.sagas.GetMetrics(),
+		"meta":        meta,
+	})
+}
+
+// GetCacheMetrics returns per-tier cache metrics and health
+func (h *AdminHandler) GetCacheMetrics(c *gin.Context) {
+	if h.cacheManager == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "cache is disabled on this instance"))
+		return
+	}
+	c.JSON(200, gin.H{
+		"metrics": h.cacheManager.GetMetrics(),
+		"health":  h.cacheManager.HealthCheck(c.Request.Context()),
+	})
+}
+
+// GetCacheTiers reports which cache tiers are serving on this instance
+func (h *AdminHandler) GetCacheTiers(c *gin.Context) {
+	if h.cacheManager == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "cache is disabled on this instance"))
+		return
+	}
+	c.JSON(200, gin.H{"topology": h.cacheManager.Topology()})
+}
+
+// SetCacheTier switches the local or Redis tier on or off on this instance without a restart
+func (h *AdminHandler) SetCacheTier(c *gin.Context) {
+	if h.cacheManager == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "cache is disabled on this instance"))
+		return
+	}
+
+	tier := cache.Tier(c.Param("tier"))
+	if tier != cache.TierLocal && tier != cache.TierRedis {
+		problem.Abort(c, problem.New(http.StatusNotFound, "unknown cache tier, expected local or redis"))
+		return
+	}
+
+	var req SetCacheTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	if err := h.cacheManager.SetTierEnabled(tier, *req.Enabled); err != nil {
+		if errors.Is(err, cache.ErrTierNotConfigured) {
+			problem.Abort(c, problem.New(http.StatusConflict, "cache tier is not configured on this instance"))
+			return
+		}
+		h.logger.Error("Failed to switch cache tier", zap.String("tier", string(tier)), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to switch cache tier"))
+		return
+	}
+
+	h.logger.Info("Cache tier switched", zap.String("tier", string(tier)), zap.Bool("enabled", *req.Enabled))
+	c.JSON(200, gin.H{"topology": h.cacheManager.Topology()})
+}
+
+// EvictUser drops a user from every cache tier on every instance, for profiles served stale; the
+// response lists the keys evicted and the tier each was found in
+func (h *AdminHandler) EvictUser(c *gin.Context) {
+	if h.cacheManager == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "cache is disabled on this instance"))
+		return
+	}
+
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	eviction, err := h.userService.EvictUser(c.Request.Context(), id.String())
+	if err != nil {
+		h.logger.Error("Failed to evict user", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to evict user"))
+		return
+	}
+
+	c.JSON(200, eviction)
+}
+
+// RecordLoginRequest reports a successful login; Method is free-form ("password", "sso", ...)
+type RecordLoginRequest struct {
+	Method string `json:"method"`
+}
+
+// GetUser is the admin view of a user: the v2 representation plus login stats
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	user, source, err := h.userService.GetUser(c.Request.Context(), id.String())
+	if err != nil {
+		h.logger.Warn("Failed to get user", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return
+	}
+	// A merged ID resolves to the user it was merged into, whose stats are the ones kept
+	logins, err := h.userService.LoginStats(c.Request.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to load login stats", zap.String("id", user.ID.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to load login stats"))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"user":        models.NewUserV2(user),
+		"id_strategy": ids.Detect(id),
+		"source":      source,
+		"logins":      logins,
+	})
+}
+
+// RecordLogin is called by the authenticating service after a successful login; it updates the
+// user's login stats and emits a user.logged_in event
+func (h *AdminHandler) RecordLogin(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	var req RecordLoginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.FromBindError(err))
+			return
+		}
+	}
+
+	stats, err := h.userService.RecordLogin(c.Request.Context(), id, req.Method)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		h.logger.Error("Failed to record login", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to record login"))
+		return
+	}
+
+	c.JSON(200, gin.H{"user_id": id.String(), "logins": stats})
+}
+
+// MergeUserRequest names the duplicate folded into the user of the path
+type MergeUserRequest struct {
+	DuplicateID string `json:"duplicate_id" binding:"required"`
+	Reason      string `json:"reason" binding:"max=500"`
+}
+
+// MergeUser folds a duplicate account into the user: its lookups the user lacks move over, its
+// ID becomes an alias of the user's, and the merge is recorded
+func (h *AdminHandler) MergeUser(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+	var req MergeUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	duplicateID, err := gocql.ParseUUID(req.DuplicateID)
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("duplicate_id", "must be a valid UUID"))
+		return
+	}
+
+	user, merge, err := h.userService.MergeUsers(c.Request.Context(), id, duplicateID, req.Reason)
+	switch {
+	case errors.Is(err, services.ErrMergeSelf):
+		problem.Abort(c, problem.FieldProblem("duplicate_id", "must be another user"))
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return
+	case errors.Is(err, repository.ErrAlreadyMerged):
+		problem.Abort(c, problem.New(http.StatusConflict, "duplicate was already merged into a user"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		h.logger.Error("Failed to merge users", zap.String("id", id.String()),
+			zap.String("duplicate_id", duplicateID.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to merge users"))
+		return
+	}
+
+	c.JSON(200, gin.H{"user": models.NewUserV2(user), "merge": merge})
+}
+
+// ListMerges returns the latest merges into a user, newest first
+func (h *AdminHandler) ListMerges(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	merges, err := h.userService.ListMerges(c.Request.Context(), id, defaultAdminListLimit)
+	if err != nil {
+		h.logger.Error("Failed to list merges", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list merges"))
+		return
+	}
+
+	c.JSON(200, gin.H{"merges": merges, "count": len(merges)})
+}
+
+// ListJobs returns the status of every scheduled job on this instance
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	jobs := h.scheduler.Status()
+	c.JSON(200, gin.H{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// GetConfig returns the configuration this instance is running with: every setting it has read,
+// the value in effect after defaults and parse fallbacks, and where that value came from.
+// Passwords, tokens and keys are redacted
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	hostname, _ := os.Hostname()
+	settings := utils.EffectiveConfig()
+	c.JSON(200, gin.H{
+		"instance": hostname,
+		"settings": settings,
+		"count":    len(settings),
+	})
+}
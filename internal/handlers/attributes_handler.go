@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"acid/internal/attributes"
+	"acid/internal/problem"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AttributesHandler struct {
+	registry *attributes.Registry
+	logger   *zap.Logger
+}
+
+func NewAttributesHandler(registry *attributes.Registry, logger *zap.Logger) *AttributesHandler {
+	return &AttributesHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// UpdateAttributeSchemaRequest replaces the user attribute schema. Version is the version the
+// change was based on, 0 for the first schema; a stale version gets a 409
+type UpdateAttributeSchemaRequest struct {
+	Schema  json.RawMessage `json:"schema" binding:"required"`
+	Version *int            `json:"version" binding:"required,min=0"`
+}
+
+// attributeSchemaResponse renders the stored schema as JSON rather than the text it is stored as
+type attributeSchemaResponse struct {
+	Entity    string          `json:"entity"`
+	Schema    json.RawMessage `json:"schema"`
+	Version   int             `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// GetAttributeSchema returns the user attribute schema
+func (h *AttributesHandler) GetAttributeSchema(c *gin.Context) {
+	schema, err := h.registry.Schema(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to load attribute schema", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to load attribute schema"))
+		return
+	}
+	if schema == nil {
+		problem.Abort(c, problem.New(http.StatusNotFound, "no attribute schema is configured"))
+		return
+	}
+	h.writeSchema(c, schema)
+}
+
+// UpdateAttributeSchema compiles and stores a new user attribute schema
+func (h *AttributesHandler) UpdateAttributeSchema(c *gin.Context) {
+	var req UpdateAttributeSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	schema, err := h.registry.SetSchema(c.Request.Context(), string(req.Schema), *req.Version)
+	switch {
+	case errors.Is(err, attributes.ErrInvalidSchema):
+		problem.Abort(c, problem.FieldProblem("schema", err.Error()))
+		return
+	case errors.Is(err, attributes.ErrSchemaConflict):
+		problem.Abort(c, problem.New(http.StatusConflict, "attribute schema was changed since version given, reload it and retry"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to store attribute schema", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to store attribute schema"))
+		return
+	}
+	h.writeSchema(c, schema)
+}
+
+// GetAttributeMetrics returns attribute validation counts for this instance
+func (h *AttributesHandler) GetAttributeMetrics(c *gin.Context) {
+	c.JSON(200, gin.H{"metrics": h.registry.GetMetrics()})
+}
+
+func (h *AttributesHandler) writeSchema(c *gin.Context, schema *attributes.Schema) {
+	c.JSON(200, attributeSchemaResponse{
+		Entity:    schema.Entity,
+		Schema:    json.RawMessage(schema.Schema),
+		Version:   schema.Version,
+		UpdatedAt: schema.UpdatedAt,
+	})
+}
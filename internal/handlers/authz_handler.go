@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"acid/internal/authz"
+	"acid/internal/problem"
+	"acid/internal/tenant"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AuthzHandler serves the admin API managing roles and API keys
+type AuthzHandler struct {
+	policy *authz.Policy
+	logger *zap.Logger
+}
+
+func NewAuthzHandler(policy *authz.Policy, logger *zap.Logger) *AuthzHandler {
+	return &AuthzHandler{
+		policy: policy,
+		logger: logger,
+	}
+}
+
+// PutRoleRequest sets a role's permissions
+type PutRoleRequest struct {
+	Description string   `json:"description" binding:"max=500"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+// CreateAPIKeyRequest names a new API key, the roles it holds and optionally the tenant it is
+// bound to
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Tenant string   `json:"tenant"`
+	Roles  []string `json:"roles" binding:"required,min=1"`
+}
+
+// ListRoles returns every role
+func (h *AuthzHandler) ListRoles(c *gin.Context) {
+	roles, err := h.policy.ListRoles(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list roles", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list roles"))
+		return
+	}
+
+	c.JSON(200, gin.H{"roles": roles, "count": len(roles), "permissions": authz.All})
+}
+
+// PutRole creates or replaces a role
+func (h *AuthzHandler) PutRole(c *gin.Context) {
+	var req PutRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	role, err := h.policy.PutRole(c.Request.Context(), c.Param("name"), req.Description, req.Permissions)
+	if errors.Is(err, authz.ErrInvalidPermission) {
+		problem.Abort(c, problem.FieldProblem("permissions", err.Error()))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to store role", zap.String("role", c.Param("name")), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to store role"))
+		return
+	}
+
+	c.JSON(200, role)
+}
+
+// DeleteRole removes a role; API keys holding it lose its permissions
+func (h *AuthzHandler) DeleteRole(c *gin.Context) {
+	err := h.policy.DeleteRole(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, authz.ErrRoleNotFound) {
+		problem.Abort(c, problem.New(http.StatusNotFound, "Role not found"))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete role", zap.String("role", c.Param("name")), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to delete role"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateAPIKey registers a new API key; the key is only returned here
+func (h *AuthzHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	if req.Tenant != "" && !tenant.Valid(req.Tenant) {
+		problem.Abort(c, problem.FieldProblem("tenant", "must be lowercase letters, digits, - or _, up to 64 characters"))
+		return
+	}
+
+	apiKey, key, err := h.policy.CreateAPIKey(c.Request.Context(), req.Name, req.Tenant, req.Roles)
+	if errors.Is(err, authz.ErrRoleNotFound) {
+		problem.Abort(c, problem.FieldProblem("roles", err.Error()))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create API key", zap.String("name", req.Name), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to create API key"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"api_key": apiKey, "key": key})
+}
+
+// ListAPIKeys returns every registered API key
+func (h *AuthzHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.policy.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list API keys", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list API keys"))
+		return
+	}
+
+	c.JSON(200, gin.H{"api_keys": keys, "count": len(keys)})
+}
+
+// DeleteAPIKey unregisters an API key; it keeps working as a plain quota key
+func (h *AuthzHandler) DeleteAPIKey(c *gin.Context) {
+	err := h.policy.DeleteAPIKey(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, authz.ErrAPIKeyNotFound) {
+		problem.Abort(c, problem.New(http.StatusNotFound, "API key not found"))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete API key", zap.String("id", c.Param("id")), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to delete API key"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetAuthzMetrics returns permission check counts of this instance
+func (h *AuthzHandler) GetAuthzMetrics(c *gin.Context) {
+	c.JSON(200, h.policy.GetMetrics())
+}
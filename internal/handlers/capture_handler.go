@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"acid/internal/capture"
+	"acid/internal/pagination"
+	"acid/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CaptureHandler struct {
+	recorder *capture.Recorder
+}
+
+// NewCaptureHandler creates the payload capture admin handler; recorder is nil when capture is off
+func NewCaptureHandler(recorder *capture.Recorder) *CaptureHandler {
+	return &CaptureHandler{recorder: recorder}
+}
+
+// capturePagination pages GET /admin/captures
+var capturePagination = pagination.Config{DefaultLimit: 50, MaxLimit: 500}
+
+// capturePosition is a captures cursor: the last exchange of the previous page
+type capturePosition struct {
+	After string `json:"a"`
+}
+
+// ListCaptures returns the newest captured exchanges of this instance a page at a time, only
+// those of one route template with ?route=
+func (h *CaptureHandler) ListCaptures(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	var from capturePosition
+	page, ok := pagination.FromQuery(c, capturePagination, &from)
+	if !ok {
+		return
+	}
+
+	exchanges, more, total := h.recorder.List(c.Query("route"), from.After, page.Limit)
+	var next *capturePosition
+	if more {
+		next = &capturePosition{After: exchanges[len(exchanges)-1].ID}
+	}
+	meta := pagination.NewMeta(len(exchanges), next, pagination.Total(int64(total)))
+	pagination.SetLink(c, page, meta)
+	c.JSON(200, gin.H{
+		"routes":    h.recorder.Routes(),
+		"exchanges": exchanges,
+		"count":     len(exchanges),
+		"meta":      meta,
+	})
+}
+
+// GetCapture returns one captured exchange
+func (h *CaptureHandler) GetCapture(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	exchange, ok := h.recorder.Get(c.Param("id"))
+	if !ok {
+		problem.Abort(c, problem.New(http.StatusNotFound, "capture not found, it may have been evicted"))
+		return
+	}
+	c.JSON(200, exchange)
+}
+
+// ClearCaptures drops every captured exchange on this instance
+func (h *CaptureHandler) ClearCaptures(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	h.recorder.Clear()
+	c.Status(http.StatusNoContent)
+}
+
+// GetCaptureMetrics returns payload capture counts for this instance
+func (h *CaptureHandler) GetCaptureMetrics(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(200, gin.H{"metrics": h.recorder.GetMetrics()})
+}
+
+func (h *CaptureHandler) enabled(c *gin.Context) bool {
+	if h.recorder == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "payload capture is disabled, set CAPTURE_ROUTES to enable it"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"acid/internal/cdc"
+	"acid/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CDCHandler struct {
+	consumer *cdc.Consumer
+}
+
+// NewCDCHandler creates the CDC admin handler; consumer is nil when CDC is disabled
+func NewCDCHandler(consumer *cdc.Consumer) *CDCHandler {
+	return &CDCHandler{consumer: consumer}
+}
+
+// GetCDCMetrics returns the CDC consumer's polls, changes, errors and lag on this instance
+func (h *CDCHandler) GetCDCMetrics(c *gin.Context) {
+	if h.consumer == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "CDC consumer is disabled on this instance"))
+		return
+	}
+	c.JSON(200, gin.H{"metrics": h.consumer.GetMetrics()})
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"acid/internal/deprecation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeprecationHandler struct {
+	registry *deprecation.Registry
+}
+
+func NewDeprecationHandler(registry *deprecation.Registry) *DeprecationHandler {
+	return &DeprecationHandler{registry: registry}
+}
+
+// GetDeprecations lists every deprecated route and RPC with its schedule and the calls it still
+// gets since start, by caller, to tell when an endpoint can be retired
+func (h *DeprecationHandler) GetDeprecations(c *gin.Context) {
+	c.JSON(200, gin.H{"endpoints": h.registry.Report()})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"acid/internal/events"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// sseKeepAlive is how often a comment is sent on idle streams so proxies don't drop them
+const sseKeepAlive = 15 * time.Second
+
+type EventsHandler struct {
+	bus    *events.Bus
+	logger *zap.Logger
+}
+
+func NewEventsHandler(bus *events.Bus, logger *zap.Logger) *EventsHandler {
+	return &EventsHandler{
+		bus:    bus,
+		logger: logger,
+	}
+}
+
+// StreamUserEvents streams user lifecycle events as server-sent events
+// ?types=user.created,user.updated filters by type; Last-Event-ID resumes from the bus history
+func (h *EventsHandler) StreamUserEvents(c *gin.Context) {
+	var types []string
+	if raw := c.Query("types"); raw != "" {
+		types = strings.Split(raw, ",")
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+
+	var sub *events.Subscription
+	var backlog []events.Event
+	if lastEventID != "" {
+		var resumed bool
+		sub, backlog, resumed = h.bus.SubscribeFrom(lastEventID, types...)
+		if !resumed {
+			h.logger.Info("SSE resume point no longer retained, replaying available history",
+				zap.String("last_event_id", lastEventID),
+				zap.Int("replayed", len(backlog)))
+		}
+	} else {
+		sub = h.bus.Subscribe(types...)
+	}
+	defer sub.Close()
+
+	// Streams outlive the server's WriteTimeout; lift the deadline for this connection only
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("Failed to clear write deadline for SSE stream", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+	c.Status(200)
+
+	for _, event := range backlog {
+		renderEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			renderEvent(c, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
+
+func renderEvent(c *gin.Context, event events.Event) {
+	c.Render(-1, sse.Event{
+		Id:    event.ID,
+		Event: event.Type,
+		Data:  event,
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"acid/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderServedFrom tells clients and load balancers that a response came from a fallback path
+const HeaderServedFrom = "X-Served-From"
+
+type HealthHandler struct {
+	monitor   *health.Monitor
+	onStandby func() bool
+}
+
+func NewHealthHandler(monitor *health.Monitor) *HealthHandler {
+	return &HealthHandler{
+		monitor: monitor,
+	}
+}
+
+// SetStandbyCheck installs the check of reads being failed over to a standby cluster, which
+// keeps the instance ready while the primary is degraded
+func (h *HealthHandler) SetStandbyCheck(onStandby func() bool) {
+	h.onStandby = onStandby
+}
+
+// Livez reports that the process is up; it never depends on the database so a DB outage
+// doesn't get healthy pods restarted
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "alive"})
+}
+
+// Readyz returns 503 while the database is degraded so load balancers shift traffic away, unless
+// reads are served by the standby cluster
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	status := h.monitor.Status()
+	if h.monitor.Degraded() && h.onStandby != nil && h.onStandby() {
+		c.JSON(200, gin.H{"status": "standby", "database": status})
+		return
+	}
+	if h.monitor.Degraded() {
+		c.JSON(503, gin.H{"status": "degraded", "database": status})
+		return
+	}
+	c.JSON(200, gin.H{"status": "ready", "database": status})
+}
@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/logger"
+	"acid/internal/middleware"
 	"acid/internal/models"
+	"acid/internal/problem"
+	"acid/internal/repository"
 	"acid/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
 	"go.uber.org/zap"
 )
 
@@ -25,80 +35,236 @@ func (h *UserHandler) HealthCheck(c *gin.Context) {
 }
 
 func (h *UserHandler) CreateUser(c *gin.Context) {
-	// Logic to create a user goes here
-	var userRequest models.UserRequest
-	if err := c.ShouldBindJSON(&userRequest); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	user, ok := h.createUser(c)
+	if !ok {
 		return
 	}
+	c.JSON(201, gin.H{
+		"message": "User created successfully",
+		"user":    userV1(user),
+	})
+}
+
+// createUser binds and saves a new user; on failure it aborts with a problem and returns false
+func (h *UserHandler) createUser(c *gin.Context) (*models.User, bool) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	userRequest := models.AcquireUserRequest()
+	defer models.ReleaseUserRequest(userRequest)
+	if err := c.ShouldBindJSON(userRequest); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return nil, false
+	}
 	user, err := models.NewUser(userRequest.Username, userRequest.Email)
 
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create user"})
-		return
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to create user"))
+		return nil, false
+	}
+	create := h.service.CreateUser
+	if userRequest.ID != "" {
+		id, err := models.ParseClientUserID(userRequest.ID)
+		if err != nil {
+			problem.Abort(c, problem.FieldProblem("id", err.Error()))
+			return nil, false
+		}
+		user.ID = id
+		create = h.service.CreateUserWithID
+	}
+	user.Attributes = userRequest.Attributes
+	if userRequest.Phone != "" {
+		phone, err := models.NormalizePhone(userRequest.Phone)
+		if err != nil {
+			problem.Abort(c, problem.FieldProblem("phone", err.Error()))
+			return nil, false
+		}
+		user.Phone = phone
 	}
 
-	h.service.Logger.Info("Creating user", zap.String("username", user.Username))
-	if err := h.service.Repo.CreateUser(user); err != nil {
-		h.service.Logger.Error("Failed to save user to database", zap.Error(err))
-		c.JSON(500, gin.H{"error": "Failed to save user to database"})
-		return
+	log.Info("Creating user", zap.String("username", user.Username))
+	if err := create(c.Request.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			problem.Abort(c, problem.New(http.StatusConflict, "a user with this id already exists"))
+			return nil, false
+		}
+		if errors.Is(err, repository.ErrEmailTaken) {
+			problem.Abort(c, problem.New(http.StatusConflict, "a user with this email already exists"))
+			return nil, false
+		}
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			problem.Abort(c, problem.New(http.StatusConflict, "a user with this username already exists"))
+			return nil, false
+		}
+		if errors.Is(err, repository.ErrPhoneTaken) {
+			problem.Abort(c, problem.New(http.StatusConflict, "a user with this phone number already exists"))
+			return nil, false
+		}
+		if errors.Is(err, services.ErrReadOnly) {
+			problem.Abort(c, readOnlyProblem())
+			return nil, false
+		}
+		if errors.Is(err, models.ErrInvalidAttributes) {
+			problem.Abort(c, problem.FieldProblem("attributes", err.Error()))
+			return nil, false
+		}
+		log.Error("Failed to save user to database", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to save user to database"))
+		return nil, false
 	}
-	// Here you would typically call h.service to save the user to the database
-	c.JSON(201, gin.H{
-		"message": "User created successfully",
-		"user":    user,
-	})
+	return user, true
 }
 
 func (h *UserHandler) GetUser(c *gin.Context) {
+	user, source, ok := h.getUser(c)
+	if !ok {
+		return
+	}
+	defer models.ReleaseUser(user)
+	respond(c, 200, gin.H{
+		"user":   userV1(user),
+		"source": source,
+	}, userMessage(user, source))
+}
+
+// getUser loads the :id user through the cache tiers; on failure it aborts with a problem. The
+// user comes from models.AcquireUser: release it once the response is written
+func (h *UserHandler) getUser(c *gin.Context) (*models.User, string, bool) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
 	id := c.Param("id")
 
-	h.service.Logger.Info("Getting user", zap.String("id", id))
-
-	var user models.User
-
-	// Try to get from cache using GetOrSetJSON
-	source, err := h.service.CacheManager.GetOrSetJSON(
-		c.Request.Context(),
-		"user:"+id,
-		&user,
-		func() (interface{}, error) {
-			// This function is only called on cache miss
-			h.service.Logger.Info("Fetching user from database", zap.String("id", id))
-			fetchedUser, dbErr := h.service.Repo.GetUserByID(id)
-			if dbErr != nil {
-				h.service.Logger.Error("Database fetch failed",
-					zap.String("id", id),
-					zap.Error(dbErr))
-				return nil, dbErr
-			}
-			h.service.Logger.Info("User fetched from database successfully",
-				zap.String("id", id),
-				zap.String("username", fetchedUser.Username))
-			return fetchedUser, nil
-		},
-	)
+	log.Info("Getting user", zap.String("id", id))
 
+	ctx, stale := cache.WithStaleReport(c.Request.Context())
+	user := models.AcquireUser()
+	source, err := h.service.GetUserInto(ctx, id, user)
 	if err != nil {
-		h.service.Logger.Error("Failed to get user",
+		models.ReleaseUser(user)
+	}
+	if errors.Is(err, services.ErrDegraded) {
+		log.Warn("User not cached while database is degraded", zap.String("id", id))
+		c.Header("Retry-After", "5")
+		problem.Abort(c, problem.Typed(http.StatusServiceUnavailable, problem.TypeDegraded,
+			"Service degraded", "user not available from cache while the database is unreachable"))
+		return nil, "", false
+	}
+	if errors.Is(err, gocql.ErrNotFound) {
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return nil, "", false
+	}
+	if err != nil {
+		log.Error("Failed to get user",
 			zap.String("id", id),
 			zap.Error(err))
-		c.JSON(404, gin.H{"error": "User not found"})
-		return
+		problem.Abort(c, lookupProblem(c, err, "Failed to get user"))
+		return nil, "", false
 	}
 
-	h.service.Logger.Info("User retrieved successfully",
+	log.Info("User retrieved successfully",
 		zap.String("id", id),
 		zap.String("username", user.Username),
 		zap.String("source", source))
 
+	if source == services.SourceCacheDegraded {
+		c.Header(HeaderServedFrom, source)
+	}
+	if stale.Served {
+		// Second-chance answer while ScyllaDB is unreachable: say how old it is
+		c.Header(HeaderServedFrom, source)
+		c.Header("Age", strconv.Itoa(int(stale.Age().Seconds())))
+		c.Header("Warning", `110 - "Response is Stale"`)
+	}
+	return user, source, true
+}
+
+// HeadUser answers 200 or 404 without a body, for callers that only validate an ID; it never
+// loads or serializes the user
+func (h *UserHandler) HeadUser(c *gin.Context) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	id := c.Param("id")
+
+	exists, source, err := h.service.UserExists(c.Request.Context(), id)
+	if errors.Is(err, services.ErrDegraded) {
+		c.Header("Retry-After", "5")
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to check user existence", zap.String("id", id), zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	if source == services.SourceCacheDegraded {
+		c.Header(HeaderServedFrom, source)
+	}
+	if !exists {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// BatchGetUsers reads up to 500 users in one call. It answers 200 even when some IDs fail:
+// found users, unknown IDs and per-ID errors are listed separately so callers retry only the
+// failures
+func (h *UserHandler) BatchGetUsers(c *gin.Context) {
+	batch, ok := h.batchGetUsers(c)
+	if !ok {
+		return
+	}
+	users := make([]*models.User, 0, len(batch.Users))
+	for _, user := range batch.Users {
+		users = append(users, userV1(user))
+	}
 	c.JSON(200, gin.H{
-		"user":   user,
-		"source": source,
+		"users":     users,
+		"not_found": nonNil(batch.NotFound),
+		"errors":    batchGetErrors(batch.Failed),
 	})
 }
 
+// batchGetUsers binds a batchGet request and loads its users; on a bad request it aborts with
+// a problem
+func (h *UserHandler) batchGetUsers(c *gin.Context) (*services.UserBatch, bool) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	var req models.BatchGetUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return nil, false
+	}
+
+	batch := h.service.GetUsers(c.Request.Context(), req.IDs)
+	log.Info("Users batch retrieved",
+		zap.Int("requested", len(req.IDs)),
+		zap.Int("found", len(batch.Users)),
+		zap.Int("not_found", len(batch.NotFound)),
+		zap.Int("failed", len(batch.Failed)))
+	return batch, true
+}
+
+// batchGetErrors describes each failed ID without leaking database errors to the client
+func batchGetErrors(failed []services.UserLookupError) []models.BatchGetError {
+	errs := make([]models.BatchGetError, 0, len(failed))
+	for _, f := range failed {
+		message := "temporarily unavailable, retry"
+		switch {
+		case errors.Is(f.Err, services.ErrInvalidUserID):
+			message = "invalid UUID"
+		case errors.Is(f.Err, services.ErrDegraded):
+			message = "not available from cache while the database is unreachable"
+		}
+		errs = append(errs, models.BatchGetError{ID: f.ID, Error: message})
+	}
+	return errs
+}
+
+// nonNil renders an empty list as [] rather than null
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
 // GetCacheMetrics returns cache performance metrics
 func (h *UserHandler) GetCacheMetrics(c *gin.Context) {
 	metrics := h.service.CacheManager.GetMetrics()
@@ -109,3 +275,127 @@ func (h *UserHandler) GetCacheMetrics(c *gin.Context) {
 		"health":  health,
 	})
 }
+
+// GetPreferences returns the user's effective notification preferences
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	if preferences, ok := h.getPreferences(c); ok {
+		respond(c, 200, gin.H{"preferences": preferences}, nil)
+	}
+}
+
+// UpdatePreferences merges the given category toggles into the user's preferences
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+	if preferences, ok := h.updatePreferences(c); ok {
+		c.JSON(200, gin.H{"preferences": preferences})
+	}
+}
+
+// Unsubscribe opts the user out of one email category (?category=) or all optional ones. Callers
+// without users:write prove the request comes from the user's email with the link's ?token=
+func (h *UserHandler) Unsubscribe(c *gin.Context) {
+	if preferences, ok := h.unsubscribe(c); ok {
+		c.JSON(200, gin.H{"preferences": preferences})
+	}
+}
+
+func (h *UserHandler) getPreferences(c *gin.Context) (*models.PreferencesResponse, bool) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	id, _ := middleware.UUIDParam(c, "id")
+
+	preferences, err := h.service.GetPreferences(c.Request.Context(), id)
+	if errors.Is(err, gocql.ErrNotFound) {
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Failed to get preferences", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, lookupProblem(c, err, "Failed to get preferences"))
+		return nil, false
+	}
+	return preferences, true
+}
+
+func (h *UserHandler) updatePreferences(c *gin.Context) (*models.PreferencesResponse, bool) {
+	id, _ := middleware.UUIDParam(c, "id")
+
+	var req models.PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return nil, false
+	}
+
+	return h.savePreferences(c, id, &req)
+}
+
+func (h *UserHandler) unsubscribe(c *gin.Context) (*models.PreferencesResponse, bool) {
+	id, _ := middleware.UUIDParam(c, "id")
+	category := c.Query("category")
+
+	if grant, ok := authz.FromContext(c.Request.Context()); !ok || !grant.Allows(authz.UsersWrite) {
+		if err := h.service.CheckUnsubscribeToken(id, category, c.Query("token")); err != nil {
+			problem.Abort(c, problem.New(http.StatusForbidden, err.Error()))
+			return nil, false
+		}
+	}
+
+	categories := models.OptionalEmailCategories()
+	if category != "" {
+		categories = []string{category}
+	}
+
+	req := models.PreferencesRequest{Email: make(map[string]bool, len(categories))}
+	for _, category := range categories {
+		req.Email[category] = false
+	}
+
+	return h.savePreferences(c, id, &req)
+}
+
+func (h *UserHandler) savePreferences(c *gin.Context, id gocql.UUID, req *models.PreferencesRequest) (*models.PreferencesResponse, bool) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	preferences, err := h.service.UpdatePreferences(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+			return nil, false
+		}
+		if errors.Is(err, models.ErrInvalidPreferences) {
+			problem.Abort(c, problem.FieldProblem("email", err.Error()))
+			return nil, false
+		}
+		if errors.Is(err, services.ErrReadOnly) {
+			problem.Abort(c, readOnlyProblem())
+			return nil, false
+		}
+		log.Error("Failed to update preferences", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to update preferences"))
+		return nil, false
+	}
+
+	log.Info("Preferences updated", zap.String("id", id.String()))
+	return preferences, true
+}
+
+// userV1 is the frozen v1 shape of a user: attributes and phone numbers are only served by v2
+func userV1(user *models.User) *models.User {
+	v1 := *user
+	v1.Attributes = nil
+	v1.Phone = ""
+	return &v1
+}
+
+// lookupProblem answers a read that failed for a reason other than a missing user: 503 when
+// ScyllaDB couldn't answer, so clients retry instead of concluding the user doesn't exist, else 500
+func lookupProblem(c *gin.Context, err error, title string) *problem.Problem {
+	if repository.IsUnavailable(err) {
+		c.Header("Retry-After", "5")
+		return problem.New(http.StatusServiceUnavailable, title+": database unavailable")
+	}
+	return problem.New(http.StatusInternalServerError, title)
+}
+
+// readOnlyProblem matches the body middleware.ReadOnly returns for writes the service rejects
+func readOnlyProblem() *problem.Problem {
+	return problem.Typed(http.StatusServiceUnavailable, problem.TypeReadOnly,
+		"Instance is read-only", "this instance does not accept writes")
+}
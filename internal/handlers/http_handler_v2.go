@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"acid/internal/listquery"
+	"acid/internal/logger"
+	"acid/internal/middleware"
+	"acid/internal/models"
+	"acid/internal/pagination"
+	"acid/internal/problem"
+	"acid/internal/repository"
+	"acid/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// v2 handlers share lookup and error mapping with v1 and differ only in the response shape:
+// resources are wrapped in models.Envelope and users are rendered as models.UserV2
+
+// CreateUserV2 creates a user and returns it with a Location header
+func (h *UserHandler) CreateUserV2(c *gin.Context) {
+	user, ok := h.createUser(c)
+	if !ok {
+		return
+	}
+	c.Header("Location", "/api/v2/users/"+user.ID.String())
+	renderV2(c, 201, models.NewUserV2(user), "", nil)
+}
+
+// GetUserV2 returns a user; ?fields=id,username limits the response to those fields
+func (h *UserHandler) GetUserV2(c *gin.Context) {
+	fields, ok := fieldsParam(c, models.UserV2Fields)
+	if !ok {
+		return
+	}
+	user, source, ok := h.getUser(c)
+	if !ok {
+		return
+	}
+	defer models.ReleaseUser(user)
+
+	var data any = models.NewUserV2(user)
+	if len(fields) > 0 {
+		data = models.NewUserV2(user).Select(fields)
+	}
+	renderV2(c, 200, data, source, partialUserMessage(user, source, fields))
+}
+
+// BatchGetUsersV2 reads up to 500 users in one call, with the partial-failure semantics of v1
+func (h *UserHandler) BatchGetUsersV2(c *gin.Context) {
+	batch, ok := h.batchGetUsers(c)
+	if !ok {
+		return
+	}
+	users := make([]*models.UserV2, 0, len(batch.Users))
+	for _, user := range batch.Users {
+		users = append(users, models.NewUserV2(user))
+	}
+	renderV2(c, 200, models.BatchGetUsersV2{
+		Users:    users,
+		NotFound: nonNil(batch.NotFound),
+		Errors:   batchGetErrors(batch.Failed),
+	}, "", nil)
+}
+
+// ListUsersV2 pages through users, optionally sorted (?sort=created_at:desc) or filtered
+// (?filter=email:a@example.com) by what an index answers; other combinations are rejected
+func (h *UserHandler) ListUsersV2(c *gin.Context) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	plan, ok := listquery.FromQuery(c, services.UserListSchema)
+	if !ok {
+		return
+	}
+	var from services.UserListPosition
+	page, ok := pagination.FromQuery(c, userListPagination, &from)
+	if !ok {
+		return
+	}
+
+	users, next, err := h.service.ListUsers(c.Request.Context(), plan, from, page.Limit)
+	switch {
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		problem.Abort(c, problem.FieldProblem(pagination.CursorParam, "cursor belongs to a different sort or filter"))
+		return
+	case errors.Is(err, services.ErrDegraded):
+		c.Header("Retry-After", "5")
+		problem.Abort(c, problem.Typed(http.StatusServiceUnavailable, problem.TypeDegraded,
+			"Service degraded", "listing users needs the database, which is unreachable"))
+		return
+	case err != nil:
+		log.Error("Failed to list users", zap.String("index", plan.Index.Name), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list users"))
+		return
+	}
+
+	data := make([]*models.UserV2, 0, len(users))
+	for _, user := range users {
+		data = append(data, models.NewUserV2(user))
+	}
+	renderV2Page(c, data, page, pagination.NewMeta(len(data), next, nil))
+}
+
+// userListPagination pages GET /api/v2/users
+var userListPagination = pagination.Config{DefaultLimit: 50, MaxLimit: 200}
+
+func (h *UserHandler) GetPreferencesV2(c *gin.Context) {
+	if preferences, ok := h.getPreferences(c); ok {
+		renderV2(c, 200, preferences, "", nil)
+	}
+}
+
+func (h *UserHandler) UpdatePreferencesV2(c *gin.Context) {
+	if preferences, ok := h.updatePreferences(c); ok {
+		renderV2(c, 200, preferences, "", nil)
+	}
+}
+
+// UpdateAttributesV2 applies a JSON merge patch to the user's attributes: each key replaces the
+// attribute and null removes it. The result must satisfy the attribute schema
+func (h *UserHandler) UpdateAttributesV2(c *gin.Context) {
+	log := logger.For(c.Request.Context(), h.service.Logger)
+	id, _ := middleware.UUIDParam(c, "id")
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	attributes, err := h.service.UpdateAttributes(c.Request.Context(), id, patch)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return
+	case errors.Is(err, models.ErrInvalidAttributes):
+		problem.Abort(c, problem.FieldProblem("attributes", err.Error()))
+		return
+	case errors.Is(err, repository.ErrAttributesChanged):
+		problem.Abort(c, problem.New(http.StatusConflict, "attributes are being updated concurrently, retry"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		log.Error("Failed to update attributes", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to update attributes"))
+		return
+	}
+
+	log.Info("Attributes updated", zap.String("id", id.String()))
+	renderV2(c, 200, gin.H{"attributes": attributes}, "", nil)
+}
+
+func (h *UserHandler) UnsubscribeV2(c *gin.Context) {
+	if preferences, ok := h.unsubscribe(c); ok {
+		renderV2(c, 200, preferences, "", nil)
+	}
+}
+
+// renderV2 writes data in the v2 envelope, echoing the vendor media type when it was negotiated.
+// Read routes may negotiate msgpack (same envelope) or protobuf (the bare message, no envelope)
+func renderV2(c *gin.Context, status int, data any, source string, message func() proto.Message) {
+	renderEnvelope(c, status, models.Envelope{
+		Data: data,
+		Meta: &models.Meta{
+			RequestID: c.GetString(middleware.RequestIDKey),
+			Source:    source,
+		},
+	}, message)
+}
+
+// renderV2Page writes one page of a list in the v2 envelope, with the pagination fields in meta
+// and a Link header to the next page
+func renderV2Page(c *gin.Context, data any, page pagination.Page, meta pagination.Meta) {
+	pagination.SetLink(c, page, meta)
+	renderEnvelope(c, 200, models.Envelope{
+		Data: data,
+		Meta: &models.Meta{
+			RequestID: c.GetString(middleware.RequestIDKey),
+			Meta:      &meta,
+		},
+	}, nil)
+}
+
+func renderEnvelope(c *gin.Context, status int, envelope models.Envelope, message func() proto.Message) {
+	if middleware.ResponseEncoding(c) != middleware.EncodingJSON {
+		respond(c, status, envelope, message)
+		return
+	}
+	if middleware.AcceptsV2(c) {
+		c.Header("Content-Type", middleware.MediaTypeV2)
+	}
+	c.Render(status, pooledJSON{Data: envelope})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"acid/internal/cache"
+	"acid/internal/ipfilter"
+	"acid/internal/middleware"
+	"acid/internal/problem"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type IPRulesHandler struct {
+	filter *ipfilter.Filter
+	logger *zap.Logger
+}
+
+func NewIPRulesHandler(filter *ipfilter.Filter, logger *zap.Logger) *IPRulesHandler {
+	return &IPRulesHandler{
+		filter: filter,
+		logger: logger,
+	}
+}
+
+// IPRuleRequest names one CIDR (or single address) to add to or remove from a list
+type IPRuleRequest struct {
+	CIDR string `json:"cidr" binding:"required"`
+}
+
+// ipRulesResponse lists the rules in effect per scope and the runtime part of them
+type ipRulesResponse struct {
+	Effective map[ipfilter.Scope]ipfilter.Rules `json:"effective"`
+	Runtime   map[ipfilter.Scope]ipfilter.Rules `json:"runtime"`
+}
+
+// ListIPRules returns the rules of every scope
+func (h *IPRulesHandler) ListIPRules(c *gin.Context) {
+	effective := make(map[ipfilter.Scope]ipfilter.Rules, len(ipfilter.Scopes))
+	for _, scope := range ipfilter.Scopes {
+		effective[scope] = h.filter.Rules(scope)
+	}
+	c.JSON(200, ipRulesResponse{Effective: effective, Runtime: h.filter.Runtime()})
+}
+
+// AddIPRule adds a CIDR to a runtime list; a change locking the caller out of the admin scope
+// is rejected with 409 unless ?force=true
+func (h *IPRulesHandler) AddIPRule(c *gin.Context) {
+	var req IPRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	h.change(c, req.CIDR, true)
+}
+
+// RemoveIPRule removes the CIDR given in ?cidr= from a runtime list; static rules can't be removed
+func (h *IPRulesHandler) RemoveIPRule(c *gin.Context) {
+	cidr := c.Query("cidr")
+	if cidr == "" {
+		problem.Abort(c, problem.FieldProblem("cidr", "cidr query parameter is required"))
+		return
+	}
+	h.change(c, cidr, false)
+}
+
+// GetIPFilterMetrics returns IP filter counts for this instance
+func (h *IPRulesHandler) GetIPFilterMetrics(c *gin.Context) {
+	c.JSON(200, gin.H{"metrics": h.filter.GetMetrics()})
+}
+
+func (h *IPRulesHandler) change(c *gin.Context, cidr string, add bool) {
+	scope, list := ipfilter.Scope(c.Param("scope")), ipfilter.List(c.Param("list"))
+	caller := middleware.ClientAddr(c)
+
+	prefix, err := h.filter.Change(c.Request.Context(), scope, list, cidr, add, caller, c.Query("force") == "true")
+	switch {
+	case errors.Is(err, ipfilter.ErrInvalidRule):
+		problem.Abort(c, problem.FieldProblem("cidr", err.Error()))
+		return
+	case errors.Is(err, ipfilter.ErrLockout):
+		problem.Abort(c, problem.New(http.StatusConflict, "change would block your own address ("+caller.String()+") from the admin API, retry with ?force=true to apply it anyway"))
+		return
+	case errors.Is(err, cache.ErrCacheUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "runtime IP rules need Redis, which is unavailable"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to change IP rule", zap.String("scope", string(scope)), zap.String("list", string(list)), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to change IP rule"))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"scope": scope,
+		"list":  list,
+		"cidr":  prefix.String(),
+		"rules": h.filter.Runtime()[scope],
+	})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"acid/internal/cache"
+	"acid/internal/models"
+	"acid/internal/oauth"
+	"acid/internal/problem"
+	"acid/internal/repository"
+	"acid/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// oauthStateCookie binds a sign-in to the browser that started it, so a callback URL handed to
+// someone else can't sign them in as the person who started it
+const oauthStateCookie = "oauth_state"
+
+// OAuthHandler serves social sign-in redirects and callbacks, and the admin API over linked
+// identities
+type OAuthHandler struct {
+	oauth  *services.OAuthService
+	logger *zap.Logger
+}
+
+func NewOAuthHandler(oauth *services.OAuthService, logger *zap.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauth:  oauth,
+		logger: logger,
+	}
+}
+
+// BeginSignIn redirects to the provider's sign-in page
+func (h *OAuthHandler) BeginSignIn(c *gin.Context) {
+	provider := c.Param("provider")
+
+	start, err := h.oauth.Begin(c.Request.Context(), provider)
+	switch {
+	case errors.Is(err, oauth.ErrUnknownProvider):
+		problem.Abort(c, problem.New(http.StatusNotFound, "Unknown sign-in provider"))
+		return
+	case errors.Is(err, cache.ErrOAuthStateUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "social sign-in is unavailable while the cache is down"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to start sign-in", zap.String("provider", provider), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to start sign-in"))
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    start.State,
+		Path:     "/auth/" + provider,
+		MaxAge:   int(h.oauth.States.TTL().Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		// Lax still sends the cookie on the provider's top-level redirect back
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, start.URL)
+}
+
+// CompleteSignIn handles the provider's redirect back: it signs the user in, linking or
+// provisioning them on their first sign-in, and returns who signed in
+func (h *OAuthHandler) CompleteSignIn(c *gin.Context) {
+	provider := c.Param("provider")
+	state := c.Query("state")
+
+	cookie, _ := c.Cookie(oauthStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{Name: oauthStateCookie, Path: "/auth/" + provider, MaxAge: -1})
+	if state == "" || cookie != state {
+		problem.Abort(c, problem.New(http.StatusBadRequest, "sign-in was not started in this browser"))
+		return
+	}
+	// The user declined, or the provider refused the request
+	if reason := c.Query("error"); reason != "" {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, "sign-in was not completed: "+reason))
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		problem.Abort(c, problem.FieldProblem("code", "is required"))
+		return
+	}
+
+	login, err := h.oauth.Complete(c.Request.Context(), provider, state, code)
+	switch {
+	case errors.Is(err, oauth.ErrUnknownProvider):
+		problem.Abort(c, problem.New(http.StatusNotFound, "Unknown sign-in provider"))
+		return
+	case errors.Is(err, cache.ErrOAuthStateNotFound):
+		problem.Abort(c, problem.New(http.StatusBadRequest, "sign-in expired or was completed already"))
+		return
+	case errors.Is(err, oauth.ErrExchangeFailed):
+		h.logger.Warn("Provider refused sign-in", zap.String("provider", provider), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusUnauthorized, "the provider did not confirm the sign-in"))
+		return
+	case errors.Is(err, services.ErrNoVerifiedEmail):
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, "the provider account has no verified email"))
+		return
+	case errors.Is(err, repository.ErrEmailTaken):
+		problem.Abort(c, problem.New(http.StatusConflict, "email belongs to an account not linked to this sign-in"))
+		return
+	case errors.Is(err, oauth.ErrIdentityLinked):
+		problem.Abort(c, problem.New(http.StatusConflict, "sign-in completed concurrently, try again"))
+		return
+	case errors.Is(err, cache.ErrOAuthStateUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "social sign-in is unavailable while the cache is down"))
+		return
+	case errors.Is(err, services.ErrDegraded):
+		c.Header("Retry-After", "5")
+		problem.Abort(c, problem.Typed(http.StatusServiceUnavailable, problem.TypeDegraded,
+			"Service degraded", "user not available from cache while the database is unreachable"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		h.logger.Error("Failed to complete sign-in", zap.String("provider", provider), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to complete sign-in"))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"user":     models.NewUserV2(login.User),
+		"identity": login.Identity,
+		"method":   services.LoginMethodOAuthPrefix + provider,
+		"created":  login.Created,
+		"linked":   login.Linked,
+		"logins":   login.Logins,
+	})
+}
+
+// ListIdentities returns the provider identities linked to a user
+func (h *OAuthHandler) ListIdentities(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	identities, err := h.oauth.ListIdentities(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to list identities", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list identities"))
+		return
+	}
+
+	c.JSON(200, gin.H{"identities": identities, "count": len(identities)})
+}
+
+// UnlinkIdentity removes a provider identity from a user; signing in with it again provisions or
+// links a user anew
+func (h *OAuthHandler) UnlinkIdentity(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+	provider, subject := c.Param("provider"), c.Param("subject")
+
+	err = h.oauth.UnlinkIdentity(c.Request.Context(), id, provider, subject)
+	switch {
+	case errors.Is(err, services.ErrIdentityNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "Identity not found"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		h.logger.Error("Failed to unlink identity", zap.String("id", id.String()),
+			zap.String("provider", provider), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to unlink identity"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetOAuthMetrics returns social sign-in counts and the configured providers
+func (h *OAuthHandler) GetOAuthMetrics(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"providers": h.oauth.Providers(),
+		"metrics":   h.oauth.GetMetrics(),
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"acid/internal/cache"
+	"acid/internal/problem"
+	"acid/internal/repository"
+	"acid/internal/services"
+	"acid/internal/sms"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// OTPHandler serves SMS login codes to the authenticating service
+type OTPHandler struct {
+	otp    *services.OTPService
+	logger *zap.Logger
+}
+
+func NewOTPHandler(otp *services.OTPService, logger *zap.Logger) *OTPHandler {
+	return &OTPHandler{
+		otp:    otp,
+		logger: logger,
+	}
+}
+
+// VerifyOTPRequest is the code the user typed in
+type VerifyOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+// SendLoginCode texts a login code to the user's phone number
+func (h *OTPHandler) SendLoginCode(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+
+	challenge, wait, err := h.otp.SendLoginCode(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, cache.ErrOTPResendTooSoon):
+		retryAfter := int64(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		problem.Abort(c, problem.Typed(http.StatusTooManyRequests, problem.TypeRateLimited,
+			"Code sent recently", "wait before requesting another code").With("retry_after", retryAfter))
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "User not found"))
+		return
+	case errors.Is(err, services.ErrDegraded):
+		c.Header("Retry-After", "5")
+		problem.Abort(c, problem.Typed(http.StatusServiceUnavailable, problem.TypeDegraded,
+			"Service degraded", "user not available from cache while the database is unreachable"))
+		return
+	case errors.Is(err, services.ErrNoPhone):
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, "user has no phone number"))
+		return
+	case errors.Is(err, sms.ErrInvalidRecipient):
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, "the user's phone number cannot receive text messages"))
+		return
+	case errors.Is(err, cache.ErrOTPUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "login codes are unavailable while the cache is down"))
+		return
+	case errors.Is(err, services.ErrCodeNotDelivered):
+		h.logger.Warn("Failed to deliver login code", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusBadGateway, "Failed to send login code"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to send login code", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to send login code"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, challenge)
+}
+
+// VerifyLoginCode checks a code sent by SendLoginCode and records the login on success
+func (h *OTPHandler) VerifyLoginCode(c *gin.Context) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return
+	}
+	var req VerifyOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	stats, err := h.otp.VerifyLoginCode(c.Request.Context(), id, req.Code)
+	switch {
+	case errors.Is(err, cache.ErrOTPInvalid), errors.Is(err, cache.ErrOTPExpired):
+		problem.Abort(c, problem.New(http.StatusUnauthorized, err.Error()))
+		return
+	case errors.Is(err, cache.ErrOTPUnavailable):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "login codes are unavailable while the cache is down"))
+		return
+	case errors.Is(err, services.ErrReadOnly):
+		problem.Abort(c, readOnlyProblem())
+		return
+	case err != nil:
+		h.logger.Error("Failed to verify login code", zap.String("id", id.String()), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to verify login code"))
+		return
+	}
+
+	c.JSON(200, gin.H{"user_id": id.String(), "method": services.LoginMethodSMSOTP, "logins": stats})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"acid/internal/problem"
+	"acid/internal/quota"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type QuotaHandler struct {
+	manager *quota.Manager
+	logger  *zap.Logger
+}
+
+// NewQuotaHandler creates the quota admin handler; manager is nil when quotas are disabled
+func NewQuotaHandler(manager *quota.Manager, logger *zap.Logger) *QuotaHandler {
+	return &QuotaHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// UpdateQuotaRequest overrides a subject's limits; omitted periods keep their current limit and
+// zero means unlimited
+type UpdateQuotaRequest struct {
+	Daily   *int64 `json:"daily" binding:"omitempty,min=0"`
+	Monthly *int64 `json:"monthly" binding:"omitempty,min=0"`
+}
+
+// GetQuota returns a subject's limits and its usage in the current day and month
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	h.writeQuota(c, c.Param("subject"))
+}
+
+// UpdateQuota sets per-subject limit overrides
+func (h *QuotaHandler) UpdateQuota(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	var req UpdateQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	subject := c.Param("subject")
+	updates := map[quota.Period]*int64{quota.Daily: req.Daily, quota.Monthly: req.Monthly}
+	for _, period := range quota.Periods {
+		if updates[period] == nil {
+			continue
+		}
+		if err := h.manager.SetLimit(c.Request.Context(), subject, period, *updates[period]); err != nil {
+			h.logger.Error("Failed to set quota", zap.String("subject", subject), zap.Error(err))
+			problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to set quota"))
+			return
+		}
+	}
+
+	h.logger.Info("Quota updated", zap.String("subject", subject))
+	h.writeQuota(c, subject)
+}
+
+// ClearQuotaLimits removes a subject's overrides so the defaults apply again
+func (h *QuotaHandler) ClearQuotaLimits(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	subject := c.Param("subject")
+	if err := h.manager.ClearLimits(c.Request.Context(), subject); err != nil {
+		h.logger.Error("Failed to clear quota limits", zap.String("subject", subject), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to clear quota limits"))
+		return
+	}
+
+	h.logger.Info("Quota limits cleared", zap.String("subject", subject))
+	h.writeQuota(c, subject)
+}
+
+// ResetQuotaUsage zeroes a subject's usage in the current day and month
+func (h *QuotaHandler) ResetQuotaUsage(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	subject := c.Param("subject")
+	if err := h.manager.ResetUsage(c.Request.Context(), subject); err != nil {
+		h.logger.Error("Failed to reset quota usage", zap.String("subject", subject), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to reset quota usage"))
+		return
+	}
+
+	h.logger.Info("Quota usage reset", zap.String("subject", subject))
+	h.writeQuota(c, subject)
+}
+
+// GetQuotaMetrics returns quota decisions and durable write counts for this instance
+func (h *QuotaHandler) GetQuotaMetrics(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(200, gin.H{"metrics": h.manager.GetMetrics()})
+}
+
+func (h *QuotaHandler) writeQuota(c *gin.Context, subject string) {
+	limits, usage, err := h.manager.Usage(c.Request.Context(), subject)
+	if err != nil {
+		h.logger.Error("Failed to load quota usage", zap.String("subject", subject), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to load quota usage"))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"subject": subject,
+		"limits":  limits,
+		"usage":   usage,
+	})
+}
+
+func (h *QuotaHandler) enabled(c *gin.Context) bool {
+	if h.manager == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "quotas are disabled on this instance"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"acid/internal/fieldmask"
+	"acid/internal/jsoncodec"
+	"acid/internal/middleware"
+	"acid/internal/models"
+	"acid/internal/problem"
+	pb "acid/proto/acid"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/proto"
+)
+
+// respond renders body in the encoding chosen by middleware.NegotiateEncoding. message builds
+// the protobuf form; resources without one pass nil and answer protobuf-only clients with 406
+func respond(c *gin.Context, status int, body any, message func() proto.Message) {
+	switch middleware.ResponseEncoding(c) {
+	case middleware.EncodingMsgPack:
+		c.Header("Content-Type", middleware.MediaTypeMsgPack)
+		c.Render(status, render.MsgPack{Data: body})
+	case middleware.EncodingProtobuf:
+		if message == nil {
+			problem.Abort(c, problem.New(http.StatusNotAcceptable, "protobuf encoding is only available for user resources"))
+			return
+		}
+		c.Render(status, render.ProtoBuf{Data: message()})
+	default:
+		c.Render(status, pooledJSON{Data: body})
+	}
+}
+
+// pooledJSON renders like render.JSON, encoding into a pooled buffer instead of a slice allocated
+// per response; the hot user reads go through it
+type pooledJSON struct {
+	Data any
+}
+
+func (r pooledJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	buf := jsoncodec.AcquireBuffer()
+	defer jsoncodec.ReleaseBuffer(buf)
+	if err := jsoncodec.MarshalTo(buf, r.Data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (r pooledJSON) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); len(header["Content-Type"]) == 0 {
+		header["Content-Type"] = []string{"application/json; charset=utf-8"}
+	}
+}
+
+// userMessage returns the protobuf builder for a user read from source
+func userMessage(user *models.User, source string) func() proto.Message {
+	return func() proto.Message {
+		return &pb.UserResponse{
+			Id:              user.ID.String(),
+			Username:        user.Username,
+			Email:           user.Email,
+			CreatedAtUnixMs: user.CreatedAt.UnixMilli(),
+			Source:          source,
+			Preferences:     user.Preferences,
+		}
+	}
+}
+
+// userMessageFields maps ?fields= names to UserResponse fields
+var userMessageFields = map[string]string{
+	"id":         "id",
+	"username":   "username",
+	"email":      "email",
+	"created_at": "created_at_unix_ms",
+}
+
+// partialUserMessage is userMessage trimmed to fields; source is kept since it isn't user data
+func partialUserMessage(user *models.User, source string, fields []string) func() proto.Message {
+	if len(fields) == 0 {
+		return userMessage(user, source)
+	}
+	return func() proto.Message {
+		paths := []string{"source"}
+		for _, field := range fields {
+			// Attributes have no protobuf field yet
+			if path, ok := userMessageFields[field]; ok {
+				paths = append(paths, path)
+			}
+		}
+		message := userMessage(user, source)()
+		_ = fieldmask.Prune(message, paths) // Paths come from userMessageFields, so they are valid
+		return message
+	}
+}
+
+// fieldsParam parses ?fields=a,b for partial responses; names outside allowed abort with a 400
+func fieldsParam(c *gin.Context, allowed []string) ([]string, bool) {
+	fields := fieldmask.Parse(c.Query("fields"))
+	for _, field := range fields {
+		if !slices.Contains(allowed, field) {
+			problem.Abort(c, problem.FieldProblem("fields",
+				"unknown field "+field+", expected one of "+strings.Join(allowed, ", ")))
+			return nil, false
+		}
+	}
+	return fields, true
+}
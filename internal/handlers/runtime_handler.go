@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"acid/internal/problem"
+	"acid/internal/safego"
+	"acid/internal/watchdog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RuntimeHandler struct {
+	watchdog *watchdog.Watchdog
+}
+
+// NewRuntimeHandler creates the runtime watchdog admin handler; watchdog is nil when it is disabled
+func NewRuntimeHandler(watchdog *watchdog.Watchdog) *RuntimeHandler {
+	return &RuntimeHandler{watchdog: watchdog}
+}
+
+// GetRuntime returns this instance's goroutine, file descriptor and heap gauges, alert counts and
+// the stack groups logged with the last goroutine alert
+func (h *RuntimeHandler) GetRuntime(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(200, h.watchdog.Status())
+}
+
+// GetGoroutines groups the goroutines running now by stack, the ?limit= largest groups (default 20)
+func (h *RuntimeHandler) GetGoroutines(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		problem.Abort(c, problem.FieldProblem("limit", "must be a positive integer"))
+		return
+	}
+	c.JSON(200, gin.H{"groups": h.watchdog.Stacks(limit)})
+}
+
+// GetSupervised lists the background goroutines started through safego by name: how many run,
+// their panics and restarts, and the last panic with its stack. It works without the watchdog
+func (h *RuntimeHandler) GetSupervised(c *gin.Context) {
+	c.JSON(200, gin.H{"goroutines": safego.Statuses(), "totals": safego.GetMetrics()})
+}
+
+func (h *RuntimeHandler) enabled(c *gin.Context) bool {
+	if h.watchdog == nil {
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "runtime watchdog is disabled, set WATCHDOG_ENABLED=true to enable it"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"acid/internal/problem"
+	"acid/internal/serviceaccount"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// clientAssertionType is the only client_assertion_type accepted (RFC 7523)
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ServiceAccountHandler serves the token endpoint and the service account admin API
+type ServiceAccountHandler struct {
+	manager *serviceaccount.Manager
+	logger  *zap.Logger
+}
+
+func NewServiceAccountHandler(manager *serviceaccount.Manager, logger *zap.Logger) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// CreateServiceAccountRequest names a new service account and its scopes
+type CreateServiceAccountRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Scopes      []string `json:"scopes" binding:"required,min=1"`
+}
+
+// UpdateServiceAccountRequest changes a service account; omitted fields are kept
+type UpdateServiceAccountRequest struct {
+	Description *string  `json:"description" binding:"omitempty,max=500"`
+	Scopes      []string `json:"scopes" binding:"omitempty,min=1"`
+	Disabled    *bool    `json:"disabled"`
+}
+
+// SetPublicKeyRequest registers the key a service account signs client assertions with
+type SetPublicKeyRequest struct {
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// IssueToken is the OAuth2 token endpoint for the client credentials grant. The client
+// authenticates with HTTP Basic or client_id/client_secret form fields, or with a signed
+// client_assertion
+func (h *ServiceAccountHandler) IssueToken(c *gin.Context) {
+	// Tokens must not be cached by anything between the client and us (RFC 6749 5.1)
+	c.Header("Cache-Control", "no-store")
+
+	if grantType := c.PostForm("grant_type"); grantType != "client_credentials" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, "grant_type must be client_credentials").
+			With("error", "unsupported_grant_type"))
+		return
+	}
+	req := serviceaccount.TokenRequest{
+		ClientID:     c.PostForm("client_id"),
+		ClientSecret: c.PostForm("client_secret"),
+		Scopes:       strings.Fields(c.PostForm("scope")),
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	if assertion := c.PostForm("client_assertion"); assertion != "" {
+		if c.PostForm("client_assertion_type") != clientAssertionType {
+			problem.Abort(c, problem.New(http.StatusBadRequest, "client_assertion_type must be "+clientAssertionType).
+				With("error", "invalid_request"))
+			return
+		}
+		req.Assertion = assertion
+	} else if req.ClientID == "" || req.ClientSecret == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, "client credentials are required").
+			With("error", "invalid_request"))
+		return
+	}
+
+	token, err := h.manager.IssueToken(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, serviceaccount.ErrInvalidClient):
+		c.Header("WWW-Authenticate", `Basic realm="acid"`)
+		problem.Abort(c, problem.New(http.StatusUnauthorized, err.Error()).With("error", "invalid_client"))
+		return
+	case errors.Is(err, serviceaccount.ErrInvalidScope):
+		problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()).With("error", "invalid_scope"))
+		return
+	case errors.Is(err, serviceaccount.ErrTokensDisabled):
+		problem.Abort(c, problem.New(http.StatusServiceUnavailable, "service account tokens are not configured"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to issue token", zap.String("client_id", req.ClientID), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to issue token"))
+		return
+	}
+
+	c.JSON(200, token)
+}
+
+// CreateServiceAccount creates a service account; its client secret is only returned here
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	account, secret, err := h.manager.Create(c.Request.Context(), req.Name, req.Description, req.Scopes)
+	if errors.Is(err, serviceaccount.ErrInvalidScope) {
+		problem.Abort(c, problem.FieldProblem("scopes", err.Error()))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create service account", zap.String("name", req.Name), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to create service account"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"account":       account,
+		"client_id":     account.ID.String(),
+		"client_secret": secret,
+	})
+}
+
+// ListServiceAccounts returns every service account
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	accounts, err := h.manager.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list service accounts", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list service accounts"))
+		return
+	}
+
+	c.JSON(200, gin.H{"accounts": accounts, "count": len(accounts)})
+}
+
+// GetServiceAccount returns one service account
+func (h *ServiceAccountHandler) GetServiceAccount(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.manager.Get(c.Request.Context(), id)
+	if !h.check(c, err, "Failed to get service account") {
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// UpdateServiceAccount changes a service account's description, scopes or disabled flag
+func (h *ServiceAccountHandler) UpdateServiceAccount(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+	var req UpdateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	account, err := h.manager.Update(c.Request.Context(), id, serviceaccount.AccountUpdate{
+		Description: req.Description,
+		Scopes:      req.Scopes,
+		Disabled:    req.Disabled,
+	})
+	if errors.Is(err, serviceaccount.ErrInvalidScope) {
+		problem.Abort(c, problem.FieldProblem("scopes", err.Error()))
+		return
+	}
+	if !h.check(c, err, "Failed to update service account") {
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// RotateServiceAccountSecret replaces a service account's client secret and returns the new one
+func (h *ServiceAccountHandler) RotateServiceAccountSecret(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	secret, err := h.manager.RotateSecret(c.Request.Context(), id)
+	if !h.check(c, err, "Failed to rotate client secret") {
+		return
+	}
+
+	c.JSON(200, gin.H{"client_id": id.String(), "client_secret": secret})
+}
+
+// SetServiceAccountKey registers the public key a service account signs client assertions with
+func (h *ServiceAccountHandler) SetServiceAccountKey(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+	var req SetPublicKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	account, err := h.manager.SetPublicKey(c.Request.Context(), id, req.PublicKey)
+	if errors.Is(err, serviceaccount.ErrInvalidPublicKey) {
+		problem.Abort(c, problem.FieldProblem("public_key", err.Error()))
+		return
+	}
+	if !h.check(c, err, "Failed to set public key") {
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// DeleteServiceAccountKey removes a service account's public key, so only its secret works
+func (h *ServiceAccountHandler) DeleteServiceAccountKey(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.manager.SetPublicKey(c.Request.Context(), id, "")
+	if !h.check(c, err, "Failed to remove public key") {
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// DeleteServiceAccount removes a service account; its tokens stop working
+func (h *ServiceAccountHandler) DeleteServiceAccount(c *gin.Context) {
+	id, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	err := h.manager.Delete(c.Request.Context(), id)
+	if !h.check(c, err, "Failed to delete service account") {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetServiceAccountMetrics returns token counts of this instance
+func (h *ServiceAccountHandler) GetServiceAccountMetrics(c *gin.Context) {
+	c.JSON(200, h.manager.GetMetrics())
+}
+
+func serviceAccountID(c *gin.Context) (gocql.UUID, bool) {
+	id, err := gocql.ParseUUID(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.FieldProblem("id", "must be a valid UUID"))
+		return id, false
+	}
+	return id, true
+}
+
+// check answers 404 or 500 for err and reports whether the request may go on
+func (h *ServiceAccountHandler) check(c *gin.Context, err error, message string) bool {
+	switch {
+	case errors.Is(err, serviceaccount.ErrNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, "Service account not found"))
+		return false
+	case err != nil:
+		h.logger.Error(message, zap.String("id", c.Param("id")), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, message))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"acid/internal/slo"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SLOHandler struct {
+	tracker *slo.Tracker
+}
+
+func NewSLOHandler(tracker *slo.Tracker) *SLOHandler {
+	return &SLOHandler{tracker: tracker}
+}
+
+// GetSLO returns each objective's availability, latency and remaining error budget over the
+// window, plus latency quantiles and availability of every endpoint since start
+func (h *SLOHandler) GetSLO(c *gin.Context) {
+	c.JSON(200, h.tracker.Summary())
+}
+
+// GetSLOHistograms returns the raw latency histogram of every endpoint, for export to a metrics backend
+func (h *SLOHandler) GetSLOHistograms(c *gin.Context) {
+	c.JSON(200, gin.H{"histograms": h.tracker.Histograms()})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"acid/internal/problem"
+	"acid/internal/quota"
+	"acid/internal/tenant"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TenantHandler serves the admin API managing per-tenant rate limits
+type TenantHandler struct {
+	manager *tenant.Manager
+	logger  *zap.Logger
+}
+
+func NewTenantHandler(manager *tenant.Manager, logger *zap.Logger) *TenantHandler {
+	return &TenantHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// SetTenantRateLimitRequest overrides a tenant's rate limit; zero requests means unlimited
+type SetTenantRateLimitRequest struct {
+	Requests *int64 `json:"requests" binding:"required,min=0"`
+	Window   string `json:"window" binding:"required"`
+}
+
+// rateLimitView renders a rate limit with a readable window
+func rateLimitView(limit tenant.RateLimit) gin.H {
+	return gin.H{"requests": limit.Requests, "window": limit.Window.String()}
+}
+
+// ListTenants returns every tenant with a rate limit override
+func (h *TenantHandler) ListTenants(c *gin.Context) {
+	overrides, err := h.manager.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list tenant limits", zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to list tenant limits"))
+		return
+	}
+
+	tenants := make([]gin.H, len(overrides))
+	for i, o := range overrides {
+		tenants[i] = gin.H{"tenant": o.Tenant, "rate_limit": rateLimitView(o.RateLimit), "updated_at": o.UpdatedAt}
+	}
+	c.JSON(200, gin.H{
+		"tenants":            tenants,
+		"count":              len(tenants),
+		"default_rate_limit": rateLimitView(h.manager.DefaultRateLimit()),
+	})
+}
+
+// GetTenant returns the rate limit in effect for a tenant and whether it is an override
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	limit := h.manager.DefaultRateLimit()
+	overrides, err := h.manager.Get(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, tenant.ErrNotFound) {
+		h.logger.Error("Failed to get tenant limits", zap.String("tenant", id), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to get tenant limits"))
+		return
+	}
+	if overrides != nil {
+		limit = overrides.RateLimit
+	}
+
+	c.JSON(200, gin.H{
+		"tenant":        id,
+		"rate_limit":    rateLimitView(limit),
+		"overridden":    overrides != nil,
+		"quota_subject": quota.TenantSubject(id),
+	})
+}
+
+// SetTenantRateLimit overrides a tenant's rate limit on every instance
+func (h *TenantHandler) SetTenantRateLimit(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+	var req SetTenantRateLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+	window, err := time.ParseDuration(req.Window)
+	if err != nil || window < time.Second {
+		problem.Abort(c, problem.FieldProblem("window", "must be a duration of at least 1s, e.g. 1m"))
+		return
+	}
+
+	overrides, err := h.manager.SetRateLimit(c.Request.Context(), id, tenant.RateLimit{Requests: *req.Requests, Window: window})
+	if err != nil {
+		h.logger.Error("Failed to set tenant rate limit", zap.String("tenant", id), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to set tenant rate limit"))
+		return
+	}
+
+	c.JSON(200, gin.H{"tenant": id, "rate_limit": rateLimitView(overrides.RateLimit), "updated_at": overrides.UpdatedAt})
+}
+
+// DeleteTenantRateLimit drops a tenant's override so the default applies again
+func (h *TenantHandler) DeleteTenantRateLimit(c *gin.Context) {
+	id, ok := tenantID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.DeleteRateLimit(c.Request.Context(), id); err != nil {
+		h.logger.Error("Failed to clear tenant rate limit", zap.String("tenant", id), zap.Error(err))
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "Failed to clear tenant rate limit"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTenantMetrics returns tenant limit lookup counts of this instance
+func (h *TenantHandler) GetTenantMetrics(c *gin.Context) {
+	c.JSON(200, h.manager.GetMetrics())
+}
+
+func tenantID(c *gin.Context) (string, bool) {
+	id := c.Param("tenant")
+	if !tenant.Valid(id) {
+		problem.Abort(c, problem.FieldProblem("tenant", "must be lowercase letters, digits, - or _, up to 64 characters"))
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,224 @@
+package health
+
+import (
+	"acid/internal/safego"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Checker probes a dependency; a non-nil error counts as one failed check
+type Checker func(ctx context.Context) error
+
+// MonitorConfig holds degraded mode thresholds
+type MonitorConfig struct {
+	// Interval between checks
+	Interval time.Duration
+
+	// Timeout bounds a single check
+	Timeout time.Duration
+
+	// FailureThreshold is the number of consecutive failures that enter degraded mode
+	FailureThreshold int
+
+	// RecoveryThreshold is the number of consecutive successes that leave degraded mode
+	RecoveryThreshold int
+}
+
+// DefaultMonitorConfig returns sensible production defaults
+func DefaultMonitorConfig() *MonitorConfig {
+	return &MonitorConfig{
+		Interval:          5 * time.Second,
+		Timeout:           2 * time.Second,
+		FailureThreshold:  3,
+		RecoveryThreshold: 2,
+	}
+}
+
+// MonitorMetrics tracks check outcomes and state changes
+type MonitorMetrics struct {
+	Checks      atomic.Int64
+	Failures    atomic.Int64
+	Transitions atomic.Int64
+}
+
+// Monitor runs a Checker in the background and flips into degraded mode after repeated failures
+// Consecutive-run thresholds keep a single slow check from flapping readiness
+type Monitor struct {
+	name    string
+	check   Checker
+	config  *MonitorConfig
+	logger  *zap.Logger
+	metrics *MonitorMetrics
+
+	degraded atomic.Bool
+
+	mu        sync.Mutex
+	failures  int // Consecutive failures while healthy
+	successes int // Consecutive successes while degraded
+	lastErr   error
+	since     time.Time
+	listeners []func(degraded bool)
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewMonitor(name string, check Checker, config *MonitorConfig, logger *zap.Logger) *Monitor {
+	if config == nil {
+		config = DefaultMonitorConfig()
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 1
+	}
+	if config.RecoveryThreshold <= 0 {
+		config.RecoveryThreshold = 1
+	}
+
+	return &Monitor{
+		name:    name,
+		check:   check,
+		config:  config,
+		logger:  logger,
+		metrics: &MonitorMetrics{},
+		since:   time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// OnChange registers a listener called with the new state on every transition
+// Listeners must be registered during startup, before Start
+func (m *Monitor) OnChange(listener func(degraded bool)) {
+	m.listeners = append(m.listeners, listener)
+}
+
+// Start runs checks every Interval until Stop is called
+func (m *Monitor) Start() {
+	safego.Go("health.monitor", m.run, safego.Restart(m.stop), safego.OnExit(func() { close(m.done) }))
+	m.logger.Info("Health monitor started",
+		zap.String("dependency", m.name),
+		zap.Duration("interval", m.config.Interval),
+		zap.Int("failure_threshold", m.config.FailureThreshold),
+		zap.Int("recovery_threshold", m.config.RecoveryThreshold))
+}
+
+// Stop halts the check loop and waits for an in-flight check to finish
+func (m *Monitor) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+		<-m.done
+		m.logger.Info("Health monitor stopped", zap.String("dependency", m.name))
+	})
+}
+
+// Degraded reports whether the dependency is currently considered down
+func (m *Monitor) Degraded() bool {
+	return m.degraded.Load()
+}
+
+// Status returns a snapshot for readiness endpoints
+func (m *Monitor) Status() map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := map[string]any{
+		"dependency": m.name,
+		"degraded":   m.degraded.Load(),
+		"since":      m.since,
+	}
+	if m.lastErr != nil {
+		status["last_error"] = m.lastErr.Error()
+	}
+	return status
+}
+
+func (m *Monitor) GetMetrics() map[string]int64 {
+	degraded := int64(0)
+	if m.degraded.Load() {
+		degraded = 1
+	}
+	return map[string]int64{
+		"checks":      m.metrics.Checks.Load(),
+		"failures":    m.metrics.Failures.Load(),
+		"transitions": m.metrics.Transitions.Load(),
+		"degraded":    degraded,
+	}
+}
+
+func (m *Monitor) run() {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.runCheck()
+		}
+	}
+}
+
+func (m *Monitor) runCheck() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+	err := m.check(ctx)
+	cancel()
+
+	m.metrics.Checks.Add(1)
+	if err != nil {
+		m.metrics.Failures.Add(1)
+	}
+
+	m.mu.Lock()
+	m.lastErr = err
+	changed := false
+	degraded := m.degraded.Load()
+
+	if err != nil {
+		m.successes = 0
+		if !degraded {
+			m.failures++
+			if m.failures >= m.config.FailureThreshold {
+				changed, degraded = true, true
+			}
+		}
+	} else {
+		m.failures = 0
+		if degraded {
+			m.successes++
+			if m.successes >= m.config.RecoveryThreshold {
+				changed, degraded = true, false
+			}
+		}
+	}
+
+	if changed {
+		m.failures, m.successes = 0, 0
+		m.since = time.Now()
+		m.degraded.Store(degraded)
+		m.metrics.Transitions.Add(1)
+	}
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if degraded {
+		m.logger.Error("Dependency unhealthy, entering degraded mode",
+			zap.String("dependency", m.name),
+			zap.Int("consecutive_failures", m.config.FailureThreshold),
+			zap.Error(err))
+	} else {
+		m.logger.Info("Dependency recovered, leaving degraded mode", zap.String("dependency", m.name))
+	}
+
+	for _, listener := range m.listeners {
+		listener(degraded)
+	}
+}
@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker stops calls to a host after threshold failures in a row. Once cooldown has passed it
+// lets one call through: success closes it, failure keeps it open for another cooldown
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go out, claiming the probe of a breaker whose cooldown ended
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record counts the outcome of a call
+func (b *breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.threshold > 0 && b.failures >= b.threshold
+}
+
+// breakers holds one breaker per host, so one failing endpoint doesn't block the others
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{threshold: threshold, cooldown: cooldown, hosts: make(map[string]*breaker)}
+}
+
+func (b *breakers) forHost(host string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hostBreaker, ok := b.hosts[host]
+	if !ok {
+		hostBreaker = &breaker{threshold: b.threshold, cooldown: b.cooldown}
+		b.hosts[host] = hostBreaker
+	}
+	return hostBreaker
+}
+
+// open returns how many hosts currently have an open breaker
+func (b *breakers) open() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	open := 0
+	for _, hostBreaker := range b.hosts {
+		if hostBreaker.isOpen() {
+			open++
+		}
+	}
+	return open
+}
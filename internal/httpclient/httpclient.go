@@ -0,0 +1,275 @@
+// Package httpclient builds the client every outbound HTTP integration uses (schema registry,
+// webhooks, mailer or storage APIs), so timeouts, retries, circuit breaking, trace propagation and
+// the User-Agent are set once instead of per call site with http.DefaultClient
+package httpclient
+
+import (
+	"acid/internal/correlation"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Config holds outbound client configuration
+type Config struct {
+	// Name identifies the integration in the User-Agent and logs, e.g. "schema-registry"
+	Name string
+
+	// Timeout bounds each attempt, including reading the response body
+	Timeout time.Duration
+
+	// MaxAttempts includes the first try; 1 disables retries
+	MaxAttempts int
+
+	// InitialBackoff is the base delay before the first retry, doubled on each further retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts, including one a Retry-After header asks for
+	MaxBackoff time.Duration
+
+	// BreakerThreshold is how many failures in a row open the breaker of a host; 0 disables it
+	BreakerThreshold int
+
+	// BreakerCooldown is how long an open breaker rejects calls before letting one through
+	BreakerCooldown time.Duration
+
+	// UserAgent is sent unless the request sets one
+	UserAgent string
+
+	// Outbound controls the correlation metadata forwarded. Third parties must not get the
+	// inbound Authorization, so it is off unless set
+	Outbound *correlation.OutboundConfig
+}
+
+// DefaultConfig returns sensible defaults for a third-party integration
+func DefaultConfig(name string) *Config {
+	return &Config{
+		Name:             name,
+		Timeout:          10 * time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   100 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		UserAgent:        "acid/" + name,
+		Outbound:         &correlation.OutboundConfig{PropagateAuth: false},
+	}
+}
+
+// ErrCircuitOpen is returned without calling a host whose breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Metrics tracks outbound calls for observability
+type Metrics struct {
+	Attempts  atomic.Int64 // Every request sent, including retries
+	Retries   atomic.Int64 // Attempts after the first
+	Failures  atomic.Int64 // Calls that failed after their last attempt
+	Rejected  atomic.Int64 // Calls refused by an open breaker
+	Recovered atomic.Int64 // Calls that succeeded after at least one retry
+}
+
+// Client is the outbound HTTP client; it is safe for concurrent use
+type Client struct {
+	config   *Config
+	http     *http.Client
+	breakers *breakers
+	metrics  *Metrics
+}
+
+func New(config *Config) *Client {
+	if config == nil {
+		config = DefaultConfig("acid")
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	if config.Outbound == nil {
+		config.Outbound = &correlation.OutboundConfig{PropagateAuth: false}
+	}
+
+	c := &Client{
+		config:   config,
+		breakers: newBreakers(config.BreakerThreshold, config.BreakerCooldown),
+		metrics:  &Metrics{},
+	}
+	// Redirects are followed by http.Client inside one attempt
+	c.http = &http.Client{Transport: &transport{client: c, base: correlation.NewTransport(nil, config.Outbound)}}
+	return c
+}
+
+// Do sends req. Requests must be built with http.NewRequestWithContext; the context's deadline
+// bounds all attempts together
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.http.Do(req)
+}
+
+// StandardClient returns an *http.Client with the same behavior, for libraries that take one
+func (c *Client) StandardClient() *http.Client {
+	return c.http
+}
+
+// GetMetrics returns call counts of this client
+func (c *Client) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"attempts":  c.metrics.Attempts.Load(),
+		"retries":   c.metrics.Retries.Load(),
+		"failures":  c.metrics.Failures.Load(),
+		"rejected":  c.metrics.Rejected.Load(),
+		"recovered": c.metrics.Recovered.Load(),
+		"open":      int64(c.breakers.open()),
+	}
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks the requests made with ctx as safe to retry even when their method isn't,
+// e.g. a POST registering a schema the server deduplicates
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// transport applies the breaker, retries and per-attempt timeout around the correlation transport
+type transport struct {
+	client *Client
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.client
+	breaker := c.breakers.forHost(req.URL.Host)
+	if !breaker.allow() {
+		c.metrics.Rejected.Add(1)
+		return nil, fmt.Errorf("%s %s: %w", c.config.Name, req.URL.Host, ErrCircuitOpen)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	if req.Header.Get("User-Agent") == "" && c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	// An active OTel span wins over the traceparent merely forwarded from the inbound request
+	propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+
+	retryable := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			c.metrics.Retries.Add(1)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		c.metrics.Attempts.Add(1)
+		resp, err := t.attempt(req)
+		failed := err != nil || resp.StatusCode >= 500
+		breaker.record(!failed)
+
+		if !retryable || attempt >= c.config.MaxAttempts || !shouldRetry(req.Context(), resp, err) {
+			switch {
+			case err != nil || resp.StatusCode >= 500:
+				c.metrics.Failures.Add(1)
+			case attempt > 1:
+				c.metrics.Recovered.Add(1)
+			}
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			// Drain so the connection is reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			c.metrics.Failures.Add(1)
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		if !breaker.allow() {
+			c.metrics.Rejected.Add(1)
+			return nil, fmt.Errorf("%s %s: %w", c.config.Name, req.URL.Host, ErrCircuitOpen)
+		}
+	}
+}
+
+// attempt sends req once within Timeout; the timeout keeps running while the body is read
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.client.config.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.client.config.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay before retry number attempt: jittered exponential, or what a
+// Retry-After header asks for, capped by MaxBackoff either way
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.config.MaxBackoff)
+		}
+	}
+	delay := min(c.config.InitialBackoff<<(attempt-1), c.config.MaxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	// Full jitter spreads retries from many callers
+	return rand.N(delay) + 1
+}
+
+// isIdempotent reports whether repeating req can't apply it twice (RFC 9110 methods), or the
+// caller said so with Idempotent or an Idempotency-Key header
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if marked, _ := req.Context().Value(idempotentKey{}).(bool); marked {
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// shouldRetry reports whether a failed attempt is transient: a network error while the caller
+// still waits, or a status asking to come back later
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cancelOnClose releases an attempt's timeout once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
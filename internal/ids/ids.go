@@ -0,0 +1,180 @@
+// Package ids generates user IDs. The strategy is chosen per deployment; every strategy yields a
+// UUID so the users.id column and every client that parses IDs stay unchanged, and IDs of
+// different strategies coexist in one table
+package ids
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/uuid"
+)
+
+// Strategy names an ID generation scheme
+type Strategy string
+
+const (
+	// TimeUUID is a version 1 UUID: timestamp and clock sequence, the original scheme
+	TimeUUID Strategy = "timeuuid"
+
+	// UUIDv4 is fully random
+	UUIDv4 Strategy = "uuidv4"
+
+	// UUIDv7 leads with a millisecond Unix timestamp, so IDs sort by creation time
+	UUIDv7 Strategy = "uuidv7"
+
+	// Snowflake is a 64-bit time/node/sequence ID carried in a version 8 UUID, for systems that
+	// need a compact, sortable integer (see SnowflakeFromUUID)
+	Snowflake Strategy = "snowflake"
+)
+
+// Strategies lists every supported strategy
+var Strategies = []Strategy{TimeUUID, UUIDv4, UUIDv7, Snowflake}
+
+// Generator produces new IDs; implementations are safe for concurrent use
+type Generator interface {
+	NewID() gocql.UUID
+	Strategy() Strategy
+}
+
+// New returns the generator of strategy; node identifies this instance for Snowflake (0-1023,
+// unique across instances) and is ignored otherwise
+func New(strategy Strategy, node int) (Generator, error) {
+	switch Strategy(strings.ToLower(string(strategy))) {
+	case TimeUUID:
+		return timeUUIDGenerator{}, nil
+	case UUIDv4:
+		return uuidV4Generator{}, nil
+	case UUIDv7:
+		return uuidV7Generator{}, nil
+	case Snowflake:
+		return NewSnowflakeGenerator(node)
+	}
+	return nil, fmt.Errorf("unknown ID strategy %q, want one of %v", strategy, Strategies)
+}
+
+type timeUUIDGenerator struct{}
+
+func (timeUUIDGenerator) NewID() gocql.UUID  { return gocql.TimeUUID() }
+func (timeUUIDGenerator) Strategy() Strategy { return TimeUUID }
+
+type uuidV4Generator struct{}
+
+func (uuidV4Generator) NewID() gocql.UUID  { return gocql.UUID(uuid.New()) }
+func (uuidV4Generator) Strategy() Strategy { return UUIDv4 }
+
+type uuidV7Generator struct{}
+
+// NewID panics only if the system's random source fails, like uuid.New
+func (uuidV7Generator) NewID() gocql.UUID  { return gocql.UUID(uuid.Must(uuid.NewV7())) }
+func (uuidV7Generator) Strategy() Strategy { return UUIDv7 }
+
+// Snowflake layout: 41 bits of milliseconds since SnowflakeEpoch, 10 bits of node and 12 bits
+// of per-millisecond sequence
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+
+	// MaxSnowflakeNode is the highest node number
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+)
+
+// SnowflakeEpoch is the zero time of Snowflake timestamps
+var SnowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator issues up to 4096 IDs per millisecond per node; a burst beyond that waits
+// for the next millisecond. A clock stepping backwards keeps the last timestamp instead of
+// reissuing IDs
+type SnowflakeGenerator struct {
+	node int64
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+func NewSnowflakeGenerator(node int) (*SnowflakeGenerator, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %d out of range 0-%d", node, MaxSnowflakeNode)
+	}
+	return &SnowflakeGenerator{node: int64(node)}, nil
+}
+
+func (g *SnowflakeGenerator) Strategy() Strategy { return Snowflake }
+
+// NewID returns the next Snowflake as a UUID
+func (g *SnowflakeGenerator) NewID() gocql.UUID {
+	return SnowflakeUUID(g.Next())
+}
+
+// Next returns the next Snowflake
+func (g *SnowflakeGenerator) Next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := max(time.Since(SnowflakeEpoch).Milliseconds(), g.last)
+	if now == g.last {
+		g.sequence = (g.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if g.sequence == 0 {
+			for now <= g.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(SnowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = now
+
+	return now<<(snowflakeNodeBits+snowflakeSequenceBits) | g.node<<snowflakeSequenceBits | g.sequence
+}
+
+// SnowflakeUUID carries a Snowflake in a version 8 UUID: its 64 bits fill the first 9 bytes
+// around the version and variant bits, most significant first, so the UUIDs sort like the
+// Snowflakes. The remaining bytes are zero
+func SnowflakeUUID(id int64) gocql.UUID {
+	var u gocql.UUID
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], uint64(id))
+
+	copy(u[0:6], raw[0:6])       // bits 63-16
+	u[6] = 0x80 | raw[6]>>4      // version 8, bits 15-12
+	u[7] = raw[6]<<4 | raw[7]>>4 // bits 11-4
+	u[8] = 0x80 | raw[7]&0x0f    // variant, bits 3-0
+	return u
+}
+
+// SnowflakeFromUUID extracts the Snowflake from an ID made by SnowflakeUUID
+func SnowflakeFromUUID(u gocql.UUID) (int64, bool) {
+	if u.Version() != 8 || u.Variant() != gocql.VariantIETF {
+		return 0, false
+	}
+	var raw [8]byte
+	copy(raw[0:6], u[0:6])
+	raw[6] = u[6]<<4 | u[7]>>4
+	raw[7] = u[7]<<4 | u[8]&0x0f
+	return int64(binary.BigEndian.Uint64(raw[:])), true
+}
+
+// Detect reports which strategy an existing ID was made with, e.g. to tell TimeUUID rows from
+// newer ones after switching strategy; it returns "" for an ID no strategy here produces
+func Detect(u gocql.UUID) Strategy {
+	if u.Variant() != gocql.VariantIETF {
+		return ""
+	}
+	switch u.Version() {
+	case 1:
+		return TimeUUID
+	case 4:
+		return UUIDv4
+	case 7:
+		return UUIDv7
+	case 8:
+		return Snowflake
+	}
+	return ""
+}
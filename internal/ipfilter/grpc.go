@@ -0,0 +1,46 @@
+package ipfilter
+
+import (
+	"acid/internal/clientip"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// healthServicePrefix is exempt so load balancer health checks pass whatever the lists say
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// UnaryServerInterceptor rejects unary calls from addresses the grpc scope doesn't allow with
+// PermissionDenied. It reads the address clientip's interceptor resolved, so it must run after it
+func UnaryServerInterceptor(filter *Filter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := filter.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(filter *Filter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := filter.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (f *Filter) check(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return nil
+	}
+
+	if f.Allowed(ScopeGRPC, clientip.FromContext(ctx)) {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "client address is not allowed")
+}
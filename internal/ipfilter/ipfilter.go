@@ -0,0 +1,316 @@
+// Package ipfilter enforces CIDR allow and deny lists per scope (admin routes, the public API,
+// gRPC). Static lists come from the environment; runtime entries are kept in Redis so admins can
+// block or admit networks on every instance without a redeploy
+package ipfilter
+
+import (
+	"acid/internal/cache"
+	"acid/internal/clientip"
+	"acid/internal/safego"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scope is a group of endpoints sharing one pair of lists
+type Scope string
+
+const (
+	ScopeAdmin Scope = "admin" // /admin and /debug routes
+	ScopeAPI   Scope = "api"   // Every other HTTP route
+	ScopeGRPC  Scope = "grpc"
+)
+
+// Scopes lists every scope
+var Scopes = []Scope{ScopeAdmin, ScopeAPI, ScopeGRPC}
+
+// List is either the allow or the deny list of a scope
+type List string
+
+const (
+	Allow List = "allow"
+	Deny  List = "deny"
+)
+
+// ErrInvalidRule is returned for unknown scopes or lists and unparsable CIDRs
+var ErrInvalidRule = errors.New("invalid IP rule")
+
+// ErrLockout is returned when a change would deny the admin making it
+var ErrLockout = errors.New("change would block the caller's own address")
+
+// Rules are the allow and deny lists of one scope. A denied address is always rejected; when the
+// allow list is not empty, only addresses it contains are accepted
+type Rules struct {
+	Allow []netip.Prefix `json:"allow"`
+	Deny  []netip.Prefix `json:"deny"`
+}
+
+// Allows reports whether addr passes the rules
+func (r Rules) Allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if contains(r.Deny, addr) {
+		return false
+	}
+	return len(r.Allow) == 0 || contains(r.Allow, addr)
+}
+
+func (r Rules) merge(other Rules) Rules {
+	return Rules{
+		Allow: append(append(make([]netip.Prefix, 0, len(r.Allow)+len(other.Allow)), r.Allow...), other.Allow...),
+		Deny:  append(append(make([]netip.Prefix, 0, len(r.Deny)+len(other.Deny)), r.Deny...), other.Deny...),
+	}
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config holds IP filter configuration
+type Config struct {
+	// Static rules per scope, from the environment; they can't be changed at runtime
+	Static map[Scope]Rules
+
+	// RefreshInterval is how often runtime rules are re-read from Redis; a change made on
+	// another instance applies here within it
+	RefreshInterval time.Duration
+}
+
+// DefaultConfig returns sensible defaults: no rules, runtime rules refreshed every 10s
+func DefaultConfig() *Config {
+	return &Config{
+		Static:          map[Scope]Rules{},
+		RefreshInterval: 10 * time.Second,
+	}
+}
+
+// Metrics tracks filter decisions and refreshes
+type Metrics struct {
+	Denied        atomic.Int64
+	Refreshes     atomic.Int64
+	RefreshErrors atomic.Int64
+}
+
+// Filter decides whether a client address may reach a scope
+type Filter struct {
+	store  *cache.IPRuleStore
+	config *Config
+	logger *zap.Logger
+
+	// runtime holds the rules last loaded from Redis
+	runtime atomic.Pointer[map[Scope]Rules]
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	metrics Metrics
+}
+
+// NewFilter creates an IP filter; a nil store leaves only the static rules
+func NewFilter(store *cache.IPRuleStore, config *Config, logger *zap.Logger) *Filter {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	f := &Filter{
+		store:  store,
+		config: config,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	f.runtime.Store(&map[Scope]Rules{})
+	return f
+}
+
+// Start loads the runtime rules and keeps refreshing them. A failed first load is logged, not
+// returned: the static rules still apply
+func (f *Filter) Start(ctx context.Context) error {
+	if err := f.Refresh(ctx); err != nil {
+		f.logger.Warn("Failed to load runtime IP rules, enforcing static rules only", zap.Error(err))
+	}
+	safego.Go("ipfilter.refresh", f.run, safego.Restart(f.stop), safego.OnExit(func() { close(f.done) }))
+	return nil
+}
+
+// Stop halts the refresh loop
+func (f *Filter) Stop() {
+	f.once.Do(func() {
+		close(f.stop)
+		<-f.done
+	})
+}
+
+// Allowed reports whether addr may reach scope
+func (f *Filter) Allowed(scope Scope, addr netip.Addr) bool {
+	if f.Rules(scope).Allows(addr) {
+		return true
+	}
+	f.metrics.Denied.Add(1)
+	return false
+}
+
+// Rules returns the rules in effect for scope: static and runtime combined
+func (f *Filter) Rules(scope Scope) Rules {
+	return f.config.Static[scope].merge((*f.runtime.Load())[scope])
+}
+
+// Runtime returns the runtime rules of every scope
+func (f *Filter) Runtime() map[Scope]Rules {
+	return *f.runtime.Load()
+}
+
+// Change adds (or removes) cidr to the runtime list of scope, then reloads the rules; if that
+// reload fails the next refresh applies the change. A change to the admin scope that would block
+// caller fails with ErrLockout unless force is set
+func (f *Filter) Change(ctx context.Context, scope Scope, list List, cidr string, add bool, caller netip.Addr, force bool) (netip.Prefix, error) {
+	if !slices.Contains(Scopes, scope) {
+		return netip.Prefix{}, fmt.Errorf("%w: unknown scope %q", ErrInvalidRule, scope)
+	}
+	if list != Allow && list != Deny {
+		return netip.Prefix{}, fmt.Errorf("%w: unknown list %q", ErrInvalidRule, list)
+	}
+	prefix, err := ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if scope == ScopeAdmin && !force && caller.IsValid() {
+		next := f.Rules(scope)
+		target := &next.Allow
+		if list == Deny {
+			target = &next.Deny
+		}
+		if add {
+			*target = append(*target, prefix)
+		} else {
+			*target = slices.DeleteFunc(slices.Clone(*target), func(p netip.Prefix) bool { return p == prefix })
+		}
+		if !next.Allows(caller) {
+			return prefix, ErrLockout
+		}
+	}
+
+	set := setName(scope, list)
+	if add {
+		err = f.store.Add(ctx, set, prefix.String())
+	} else {
+		err = f.store.Remove(ctx, set, prefix.String())
+	}
+	if err != nil {
+		return prefix, err
+	}
+
+	f.logger.Info("IP rule changed",
+		zap.String("scope", string(scope)), zap.String("list", string(list)),
+		zap.String("cidr", prefix.String()), zap.Bool("added", add))
+	if err := f.Refresh(ctx); err != nil {
+		f.logger.Warn("Failed to reload IP rules after a change", zap.Error(err))
+	}
+	return prefix, nil
+}
+
+// Refresh re-reads the runtime rules from Redis; on error the previous rules stay in effect.
+// Entries that don't parse are skipped
+func (f *Filter) Refresh(ctx context.Context) error {
+	sets := make([]string, 0, 2*len(Scopes))
+	for _, scope := range Scopes {
+		sets = append(sets, setName(scope, Allow), setName(scope, Deny))
+	}
+
+	f.metrics.Refreshes.Add(1)
+	loaded, err := f.store.Load(ctx, sets)
+	if err != nil {
+		f.metrics.RefreshErrors.Add(1)
+		return err
+	}
+
+	runtime := make(map[Scope]Rules, len(Scopes))
+	for _, scope := range Scopes {
+		runtime[scope] = Rules{
+			Allow: f.parseAll(loaded[setName(scope, Allow)]),
+			Deny:  f.parseAll(loaded[setName(scope, Deny)]),
+		}
+	}
+	f.runtime.Store(&runtime)
+	return nil
+}
+
+// GetMetrics returns current IP filter metrics
+func (f *Filter) GetMetrics() map[string]int64 {
+	var rules int64
+	for _, scope := range Scopes {
+		scopeRules := f.Rules(scope)
+		rules += int64(len(scopeRules.Allow) + len(scopeRules.Deny))
+	}
+	return map[string]int64{
+		"denied":         f.metrics.Denied.Load(),
+		"refreshes":      f.metrics.Refreshes.Load(),
+		"refresh_errors": f.metrics.RefreshErrors.Load(),
+		"rules":          rules,
+	}
+}
+
+// ParsePrefix parses a CIDR, or a single address as a /32 (/128) prefix
+func ParsePrefix(raw string) (netip.Prefix, error) {
+	prefix, err := clientip.ParsePrefix(raw)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return prefix, nil
+}
+
+// ParsePrefixes parses a comma-separated list of CIDRs or addresses
+func ParsePrefixes(raw string) ([]netip.Prefix, error) {
+	prefixes, err := clientip.ParsePrefixes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return prefixes, nil
+}
+
+func (f *Filter) parseAll(members []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(members))
+	for _, member := range members {
+		prefix, err := ParsePrefix(member)
+		if err != nil {
+			f.logger.Warn("Skipping invalid runtime IP rule", zap.String("rule", member), zap.Error(err))
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+func (f *Filter) run() {
+	ticker := time.NewTicker(f.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := f.Refresh(ctx); err != nil {
+				f.logger.Warn("Failed to refresh runtime IP rules, keeping the previous ones", zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+func setName(scope Scope, list List) string {
+	return string(scope) + ":" + string(list)
+}
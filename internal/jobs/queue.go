@@ -0,0 +1,256 @@
+package jobs
+
+import (
+	"acid/internal/safego"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQueueFull is returned when the queue is at capacity
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrQueueClosed is returned when enqueueing after Stop
+	ErrQueueClosed = errors.New("job queue is closed")
+)
+
+// Job is a unit of background work retried with exponential backoff
+type Job struct {
+	// Name identifies the job type in logs and metrics
+	Name string
+
+	// MaxAttempts overrides the queue default when positive
+	MaxAttempts int
+
+	// Run executes the job; attempt starts at 1
+	Run func(ctx context.Context, attempt int) error
+
+	// OnFailure is called once when all attempts are exhausted (optional)
+	OnFailure func(err error)
+}
+
+// QueueConfig holds background queue configuration
+type QueueConfig struct {
+	// Workers is the number of concurrent job workers
+	Workers int
+
+	// Capacity is the max number of jobs waiting to run
+	Capacity int
+
+	// JobTimeout bounds a single attempt
+	JobTimeout time.Duration
+
+	// MaxAttempts is the default attempt budget per job
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry; doubled on every retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the retry delay
+	MaxBackoff time.Duration
+}
+
+// DefaultQueueConfig returns sensible production defaults
+func DefaultQueueConfig() *QueueConfig {
+	return &QueueConfig{
+		Workers:        4,
+		Capacity:       1000,
+		JobTimeout:     30 * time.Second,
+		MaxAttempts:    5,
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     1 * time.Minute,
+	}
+}
+
+// QueueMetrics tracks queue throughput for observability
+type QueueMetrics struct {
+	Enqueued  atomic.Int64
+	Succeeded atomic.Int64
+	Retried   atomic.Int64
+	Failed    atomic.Int64
+	Dropped   atomic.Int64
+}
+
+type queuedJob struct {
+	job     Job
+	attempt int
+}
+
+// Queue is an in-process background job queue with bounded capacity
+type Queue struct {
+	jobs    chan *queuedJob
+	config  *QueueConfig
+	logger  *zap.Logger
+	metrics *QueueMetrics
+
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewQueue(config *QueueConfig, logger *zap.Logger) *Queue {
+	if config == nil {
+		config = DefaultQueueConfig()
+	}
+
+	return &Queue{
+		jobs:    make(chan *queuedJob, config.Capacity),
+		config:  config,
+		logger:  logger,
+		metrics: &QueueMetrics{},
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start launches the queue workers
+func (q *Queue) Start() {
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		safego.Go("jobs.worker", q.worker, safego.Restart(q.stop), safego.OnExit(q.wg.Done))
+	}
+
+	q.logger.Info("Job queue started",
+		zap.Int("workers", q.config.Workers),
+		zap.Int("capacity", q.config.Capacity))
+}
+
+// Stop rejects new jobs, drains the jobs already queued and waits for workers
+// Retries still waiting on their backoff timer are dropped
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	close(q.stop)
+	q.wg.Wait()
+
+	q.logger.Info("Job queue stopped",
+		zap.Int64("succeeded", q.metrics.Succeeded.Load()),
+		zap.Int64("failed", q.metrics.Failed.Load()),
+		zap.Int64("dropped", q.metrics.Dropped.Load()))
+}
+
+// Enqueue schedules a job for immediate execution
+func (q *Queue) Enqueue(job Job) error {
+	if err := q.push(&queuedJob{job: job, attempt: 1}); err != nil {
+		return err
+	}
+	q.metrics.Enqueued.Add(1)
+	return nil
+}
+
+func (q *Queue) push(item *queuedJob) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- item:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) worker() {
+	for {
+		select {
+		case item := <-q.jobs:
+			q.execute(item)
+		case <-q.stop:
+			// Drain what is already queued before exiting
+			for {
+				select {
+				case item := <-q.jobs:
+					q.execute(item)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) execute(item *queuedJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.config.JobTimeout)
+	err := item.job.Run(ctx, item.attempt)
+	cancel()
+
+	if err == nil {
+		q.metrics.Succeeded.Add(1)
+		return
+	}
+
+	maxAttempts := item.job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.config.MaxAttempts
+	}
+
+	if item.attempt >= maxAttempts {
+		q.metrics.Failed.Add(1)
+		q.logger.Error("Job failed permanently",
+			zap.String("job", item.job.Name),
+			zap.Int("attempts", item.attempt),
+			zap.Error(err))
+		if item.job.OnFailure != nil {
+			item.job.OnFailure(err)
+		}
+		return
+	}
+
+	delay := q.backoff(item.attempt)
+	q.metrics.Retried.Add(1)
+	q.logger.Warn("Job failed, retrying",
+		zap.String("job", item.job.Name),
+		zap.Int("attempt", item.attempt),
+		zap.Duration("retry_in", delay),
+		zap.Error(err))
+
+	next := &queuedJob{job: item.job, attempt: item.attempt + 1}
+	time.AfterFunc(delay, func() {
+		if pushErr := q.push(next); pushErr != nil {
+			q.metrics.Dropped.Add(1)
+			q.logger.Error("Failed to re-enqueue job",
+				zap.String("job", next.job.Name),
+				zap.Int("attempt", next.attempt),
+				zap.Error(pushErr))
+		}
+	})
+}
+
+// backoff returns the exponential delay before the given attempt's retry
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Depth returns the number of jobs waiting to run
+func (q *Queue) Depth() int {
+	return len(q.jobs)
+}
+
+// GetMetrics returns current queue metrics
+func (q *Queue) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"enqueued":  q.metrics.Enqueued.Load(),
+		"succeeded": q.metrics.Succeeded.Load(),
+		"retried":   q.metrics.Retried.Load(),
+		"failed":    q.metrics.Failed.Load(),
+		"dropped":   q.metrics.Dropped.Load(),
+		"depth":     int64(q.Depth()),
+	}
+}
@@ -0,0 +1,120 @@
+package jsoncodec_test
+
+import (
+	"acid/internal/jsoncodec"
+	"acid/internal/models"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// These compare the engine selected by build tags with encoding/json on the payloads the hot
+// paths encode: cached users, v1/v2 user responses and list-sized bodies. Without a tag both
+// sides are encoding/json, which only checks the baseline:
+//
+//	go test -run '^$' -bench Engine ./internal/jsoncodec
+//	go test -tags go_json -run '^$' -bench Engine ./internal/jsoncodec
+
+type payload struct {
+	name  string
+	value any
+	// newDest returns a pointer to decode into
+	newDest func() any
+}
+
+type codec struct {
+	name      string
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+}
+
+var (
+	stdCodec    = codec{name: "std", marshal: json.Marshal, unmarshal: json.Unmarshal}
+	engineCodec = codec{name: "engine", marshal: jsoncodec.Marshal, unmarshal: jsoncodec.Unmarshal}
+)
+
+// listSize is the number of users in the list payload
+const listSize = 100
+
+func payloads() []payload {
+	user := sampleUser(0)
+	users := make([]*models.User, listSize)
+	for i := range users {
+		users[i] = sampleUser(i)
+	}
+
+	return []payload{
+		{name: "user", value: user, newDest: func() any { return new(models.User) }},
+		{name: "v1_response", value: map[string]any{"user": user, "source": "redis"}, newDest: func() any { return new(map[string]any) }},
+		{name: "v2_envelope", value: models.Envelope{
+			Data: models.NewUserV2(user),
+			Meta: &models.Meta{RequestID: "4f0c2a7e9b1d4c3a", Source: "redis"},
+		}, newDest: func() any { return new(map[string]any) }},
+		{name: fmt.Sprintf("%d_users", listSize), value: users, newDest: func() any { return new([]*models.User) }},
+	}
+}
+
+// TestEngineRoundTrip checks the engine decodes what encoding/json wrote and vice versa, so
+// instances built with different tags can share a cache
+func TestEngineRoundTrip(t *testing.T) {
+	for _, p := range payloads() {
+		t.Run(p.name, func(t *testing.T) {
+			stdData, err := stdCodec.marshal(p.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromStd := p.newDest()
+			if err := engineCodec.unmarshal(stdData, fromStd); err != nil {
+				t.Fatalf("%s can't decode encoding/json output: %v", jsoncodec.Engine, err)
+			}
+
+			engineData, err := engineCodec.marshal(p.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromEngine := p.newDest()
+			if err := stdCodec.unmarshal(engineData, fromEngine); err != nil {
+				t.Fatalf("encoding/json can't decode %s output: %v", jsoncodec.Engine, err)
+			}
+
+			if !reflect.DeepEqual(fromStd, fromEngine) {
+				t.Errorf("%s and encoding/json disagree on the decoded value", jsoncodec.Engine)
+			}
+		})
+	}
+}
+
+func BenchmarkEngineMarshal(b *testing.B) {
+	for _, p := range payloads() {
+		for _, c := range []codec{stdCodec, engineCodec} {
+			b.Run(p.name+"/"+c.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := c.marshal(p.value); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEngineUnmarshal(b *testing.B) {
+	for _, p := range payloads() {
+		data, err := stdCodec.marshal(p.value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, c := range []codec{stdCodec, engineCodec} {
+			b.Run(p.name+"/"+c.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := c.unmarshal(data, p.newDest()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
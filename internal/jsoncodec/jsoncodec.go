@@ -0,0 +1,95 @@
+// Package jsoncodec is the JSON engine used on hot paths: HTTP rendering and binding (through
+// gin), the cache tiers, the response cache and real-time fan-out. It delegates to gin's codec,
+// so one build tag switches every one of them:
+//
+//	(none)    encoding/json
+//	go_json   github.com/goccy/go-json
+//	sonic     github.com/bytedance/sonic (linux, darwin, windows on amd64/arm64)
+//	jsoniter  github.com/json-iterator/go
+//
+// All engines produce standard JSON, so instances built with different tags can share a Redis
+// cache. Run `make bench-json` (optionally with TAGS=sonic) to compare an engine with encoding/json
+package jsoncodec
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	ginjson "github.com/gin-gonic/gin/codec/json"
+)
+
+// Engine is the import path of the JSON library compiled in
+const Engine = ginjson.Package
+
+type (
+	Encoder = ginjson.Encoder
+	Decoder = ginjson.Decoder
+)
+
+func Marshal(v any) ([]byte, error) {
+	return ginjson.API.Marshal(v)
+}
+
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return ginjson.API.MarshalIndent(v, prefix, indent)
+}
+
+func Unmarshal(data []byte, v any) error {
+	return ginjson.API.Unmarshal(data, v)
+}
+
+func NewEncoder(w io.Writer) Encoder {
+	return ginjson.API.NewEncoder(w)
+}
+
+func NewDecoder(r io.Reader) Decoder {
+	return ginjson.API.NewDecoder(r)
+}
+
+// maxPooledBuffer caps the buffers kept for reuse, so one huge document doesn't pin its buffer
+const maxPooledBuffer = 64 << 10
+
+var buffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// AcquireBuffer returns an empty pooled buffer for MarshalTo. Hand it back with ReleaseBuffer
+// once nothing refers to its bytes
+func AcquireBuffer() *bytes.Buffer {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// ReleaseBuffer returns a buffer to the pool
+func ReleaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buffers.Put(buf)
+}
+
+// MarshalTo appends the JSON encoding of v to buf, byte for byte what Marshal returns. Encoding
+// into a pooled buffer skips the slice Marshal allocates per call
+func MarshalTo(buf *bytes.Buffer, v any) error {
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	// Encoders terminate each value with a newline that Marshal doesn't add
+	if n := buf.Len(); n > 0 && buf.Bytes()[n-1] == '\n' {
+		buf.Truncate(n - 1)
+	}
+	return nil
+}
+
+// MarshalString is Marshal for callers that store the result as a string, e.g. the cache tiers;
+// it copies the encoding once instead of twice
+func MarshalString(v any) (string, error) {
+	buf := AcquireBuffer()
+	defer ReleaseBuffer(buf)
+	if err := MarshalTo(buf, v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,85 @@
+package jsoncodec_test
+
+import (
+	"acid/internal/jsoncodec"
+	"acid/internal/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// marshalStringBudget is the most allocations MarshalString may make for a cached user with
+// encoding/json; other engines allocate differently and only report
+const marshalStringBudget = 8
+
+func sampleUser(i int) *models.User {
+	return &models.User{
+		ID:        gocql.TimeUUID(),
+		Username:  fmt.Sprintf("user_%06d", i),
+		Email:     fmt.Sprintf("user_%06d@example.com", i),
+		CreatedAt: time.Date(2025, 10, 22, 8, 15, 47, 123000000, time.UTC),
+		Preferences: map[string]bool{
+			"email.marketing":       false,
+			"email.product_updates": true,
+		},
+	}
+}
+
+func TestMarshalToMatchesMarshal(t *testing.T) {
+	user := sampleUser(0)
+	want, err := jsoncodec.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := jsoncodec.AcquireBuffer()
+	defer jsoncodec.ReleaseBuffer(buf)
+	if err := jsoncodec.MarshalTo(buf, user); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("MarshalTo = %s, Marshal = %s", buf.String(), want)
+	}
+
+	got, err := jsoncodec.MarshalString(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("MarshalString = %s, Marshal = %s", got, want)
+	}
+}
+
+func TestMarshalStringAllocations(t *testing.T) {
+	if jsoncodec.Engine != "encoding/json" {
+		t.Skipf("allocation budget is set for encoding/json, not %s", jsoncodec.Engine)
+	}
+	user := sampleUser(0)
+	if allocs := testing.AllocsPerRun(100, func() {
+		_, _ = jsoncodec.MarshalString(user)
+	}); allocs > marshalStringBudget {
+		t.Errorf("MarshalString allocates %v times, budget %d", allocs, marshalStringBudget)
+	}
+}
+
+// Sink keeps the compiler from optimizing the benchmarked work away
+var sink string
+
+func BenchmarkMarshalAndString(b *testing.B) {
+	user := sampleUser(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ := jsoncodec.Marshal(user)
+		sink = string(data)
+	}
+}
+
+func BenchmarkMarshalString(b *testing.B) {
+	user := sampleUser(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sink, _ = jsoncodec.MarshalString(user)
+	}
+}
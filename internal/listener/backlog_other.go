@@ -0,0 +1,10 @@
+//go:build !unix
+
+package listener
+
+import "net"
+
+// setBacklog is a no-op where the backlog can't be resized: the OS default applies
+func setBacklog(ln *net.TCPListener, backlog int) error {
+	return nil
+}
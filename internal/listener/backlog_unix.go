@@ -0,0 +1,24 @@
+//go:build unix
+
+package listener
+
+import (
+	"net"
+	"syscall"
+)
+
+// setBacklog calls listen(2) again on the listening socket: Go listens with the OS default
+// backlog and offers no option for it, and listening again only resizes the accept queue
+func setBacklog(ln *net.TCPListener, backlog int) error {
+	raw, err := ln.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var listenErr error
+	if err := raw.Control(func(fd uintptr) {
+		listenErr = syscall.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	return listenErr
+}
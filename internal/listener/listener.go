@@ -0,0 +1,45 @@
+// Package listener opens the TCP listeners of the HTTP and gRPC servers with tunable socket
+// options: the accept backlog and the keep-alive period of accepted connections
+package listener
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Config holds socket options of a listener
+type Config struct {
+	// Backlog is the accept queue length; 0 keeps the OS default (net.core.somaxconn on Linux),
+	// which also caps larger values. Only applied on Unix
+	Backlog int
+
+	// KeepAlive is the TCP keep-alive period of accepted connections; 0 keeps Go's default
+	// (15s) and a negative value disables keep-alive probes
+	KeepAlive time.Duration
+}
+
+// DefaultConfig keeps the OS backlog and Go's keep-alive period
+func DefaultConfig() *Config {
+	return &Config{}
+}
+
+// Listen announces on the TCP address addr, e.g. ":8000"
+func Listen(ctx context.Context, addr string, config *Config) (net.Listener, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	listenConfig := net.ListenConfig{KeepAlive: config.KeepAlive}
+	ln, err := listenConfig.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config.Backlog > 0 {
+		if err := setBacklog(ln.(*net.TCPListener), config.Backlog); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set listen backlog: %w", err)
+		}
+	}
+	return ln, nil
+}
@@ -0,0 +1,284 @@
+// Package listquery reads the ?sort= and ?filter= parameters of list endpoints, e.g.
+// ?sort=created_at:desc&filter=email:a@example.com, and picks the index (a table or lookup table)
+// that answers them. A combination no index covers is rejected with a message naming the
+// supported ones: lists never fall back to ALLOW FILTERING
+package listquery
+
+import (
+	"acid/internal/problem"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters read by FromQuery
+const (
+	SortParam   = "sort"
+	FilterParam = "filter"
+)
+
+// Direction is a sort order
+type Direction string
+
+const (
+	Asc  Direction = "asc"
+	Desc Direction = "desc"
+)
+
+// Sort orders a list by one field
+type Sort struct {
+	Field     string
+	Direction Direction
+}
+
+func (s Sort) String() string {
+	return s.Field + ":" + string(s.Direction)
+}
+
+// Query is a parsed ?sort= and ?filter=; Filters maps each field to the value it must equal
+type Query struct {
+	Sort    *Sort
+	Filters map[string]string
+}
+
+// Kind is the type of a field's filter values
+type Kind int
+
+const (
+	String Kind = iota
+	Bool
+)
+
+// Field is a field that may appear in a query
+type Field struct {
+	Kind Kind
+
+	// Values restricts a String field to these values when set
+	Values []string
+
+	// Normalize rewrites a String filter value to the form the index stores, e.g. an E.164 phone
+	// number; an error rejects the query
+	Normalize func(value string) (string, error)
+}
+
+// Index is one way an endpoint can read its list
+type Index struct {
+	Name string
+
+	// Filters are the fields the index is keyed by; a query must filter on exactly these
+	Filters []string
+
+	// Sorts are the orders the index can return; a query without a sort gets the index's own
+	Sorts []Sort
+
+	// Unique indexes return at most one row, so any sort is satisfied
+	Unique bool
+}
+
+// Schema describes what an endpoint can be queried by
+type Schema struct {
+	Fields  map[string]Field
+	Indexes []Index
+}
+
+// Plan is a query matched to the index that answers it
+type Plan struct {
+	Index *Index
+	Query Query
+}
+
+// Key identifies the index and order of a plan, e.g. for binding cursors to the query that made
+// them
+func (p Plan) Key() string {
+	if p.Query.Sort == nil {
+		return p.Index.Name
+	}
+	return p.Index.Name + ":" + p.Query.Sort.String()
+}
+
+// Error is a query that can't be parsed or answered; Param names the offending parameter
+type Error struct {
+	Param   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Param + ": " + e.Message
+}
+
+// Parse reads sort, "field" or "field:asc|desc", and filter, comma-separated "field:value" pairs.
+// Filter values can't contain commas
+func Parse(sortParam, filterParam string) (Query, error) {
+	var query Query
+
+	if sortParam != "" {
+		if strings.Contains(sortParam, ",") {
+			return Query{}, &Error{SortParam, "sorting by more than one field is not supported"}
+		}
+		field, direction, _ := strings.Cut(sortParam, ":")
+		s := Sort{Field: field, Direction: Direction(strings.ToLower(direction))}
+		if s.Direction == "" {
+			s.Direction = Asc
+		}
+		if s.Field == "" || (s.Direction != Asc && s.Direction != Desc) {
+			return Query{}, &Error{SortParam, fmt.Sprintf("%q is not field, field:asc or field:desc", sortParam)}
+		}
+		query.Sort = &s
+	}
+
+	if filterParam != "" {
+		query.Filters = make(map[string]string)
+		for _, term := range strings.Split(filterParam, ",") {
+			field, value, ok := strings.Cut(term, ":")
+			if !ok || field == "" || value == "" {
+				return Query{}, &Error{FilterParam, fmt.Sprintf("%q is not field:value", term)}
+			}
+			if _, dup := query.Filters[field]; dup {
+				return Query{}, &Error{FilterParam, fmt.Sprintf("%s is filtered more than once", field)}
+			}
+			query.Filters[field] = value
+		}
+	}
+	return query, nil
+}
+
+// Plan checks the query's fields and values and picks the index that answers it
+func (s *Schema) Plan(query Query) (Plan, error) {
+	if query.Sort != nil {
+		if _, ok := s.Fields[query.Sort.Field]; !ok {
+			return Plan{}, &Error{SortParam, s.unknown(query.Sort.Field)}
+		}
+	}
+	for field, value := range query.Filters {
+		def, ok := s.Fields[field]
+		if !ok {
+			return Plan{}, &Error{FilterParam, s.unknown(field)}
+		}
+		normalized, err := def.normalize(value)
+		if err != nil {
+			return Plan{}, &Error{FilterParam, fmt.Sprintf("%s: %v", field, err)}
+		}
+		query.Filters[field] = normalized
+	}
+
+	for i := range s.Indexes {
+		if index := &s.Indexes[i]; index.answers(query) {
+			return Plan{Index: index, Query: query}, nil
+		}
+	}
+
+	param := FilterParam
+	if len(query.Filters) == 0 {
+		param = SortParam
+	}
+	return Plan{}, &Error{param, fmt.Sprintf("%s is not indexed; supported: %s", describe(query), s.supported())}
+}
+
+func (i *Index) answers(query Query) bool {
+	if len(query.Filters) != len(i.Filters) {
+		return false
+	}
+	for _, field := range i.Filters {
+		if _, ok := query.Filters[field]; !ok {
+			return false
+		}
+	}
+	return query.Sort == nil || i.Unique || slices.Contains(i.Sorts, *query.Sort)
+}
+
+func (f Field) normalize(value string) (string, error) {
+	switch {
+	case f.Kind == Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not true or false", value)
+		}
+		return strconv.FormatBool(b), nil
+	case len(f.Values) > 0 && !slices.Contains(f.Values, value):
+		return "", fmt.Errorf("%q is not one of %s", value, strings.Join(f.Values, ", "))
+	case f.Normalize != nil:
+		return f.Normalize(value)
+	}
+	return value, nil
+}
+
+func (s *Schema) unknown(field string) string {
+	fields := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("unknown field %q, want one of %s", field, strings.Join(fields, ", "))
+}
+
+// supported lists the sort and filter combinations of every index, e.g. "sort=created_at:desc"
+func (s *Schema) supported() string {
+	var combinations []string
+	for _, index := range s.Indexes {
+		var filter string
+		if len(index.Filters) > 0 {
+			terms := make([]string, len(index.Filters))
+			for i, field := range index.Filters {
+				terms[i] = field + ":<value>"
+			}
+			filter = "filter=" + strings.Join(terms, ",")
+		}
+		if len(index.Sorts) == 0 && filter != "" {
+			combinations = append(combinations, filter)
+		}
+		for _, sort := range index.Sorts {
+			combination := "sort=" + sort.String()
+			if filter != "" {
+				combination = filter + "&" + combination
+			}
+			combinations = append(combinations, combination)
+		}
+	}
+	return strings.Join(combinations, ", ")
+}
+
+func describe(query Query) string {
+	var parts []string
+	if len(query.Filters) > 0 {
+		fields := make([]string, 0, len(query.Filters))
+		for field := range query.Filters {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		parts = append(parts, "filtering on "+strings.Join(fields, " and "))
+	}
+	if query.Sort != nil {
+		parts = append(parts, "sorting by "+query.Sort.String())
+	}
+	return strings.Join(parts, " while ")
+}
+
+// FromQuery parses ?sort= and ?filter= and plans them against schema; on an unsupported query it
+// aborts with a validation problem and returns false
+func FromQuery(c *gin.Context, schema *Schema) (Plan, bool) {
+	query, err := Parse(c.Query(SortParam), c.Query(FilterParam))
+	if err != nil {
+		abort(c, err)
+		return Plan{}, false
+	}
+	plan, err := schema.Plan(query)
+	if err != nil {
+		abort(c, err)
+		return Plan{}, false
+	}
+	return plan, true
+}
+
+func abort(c *gin.Context, err error) {
+	var listErr *Error
+	if errors.As(err, &listErr) {
+		problem.Abort(c, problem.FieldProblem(listErr.Param, listErr.Message))
+		return
+	}
+	problem.Abort(c, problem.FieldProblem(FilterParam, err.Error()))
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"acid/internal/safego"
+	"bytes"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy decides what an AsyncWriter does with an entry when its queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes the logging goroutine wait for room: nothing is lost, but a stalled
+	// output stalls the callers again once the queue is full
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropOldest discards the oldest queued entry to make room, so callers never wait
+	// and the entries kept during a spike are the most recent ones
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+)
+
+// ParseOverflowPolicy parses "block" or "drop-oldest"
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(s); policy {
+	case OverflowBlock, OverflowDropOldest:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown log overflow policy %q, expected block or drop-oldest", s)
+}
+
+// asyncBatchBytes bounds how much the writer coalesces into one write to the output
+const asyncBatchBytes = 64 << 10
+
+// AsyncWriter moves log output off the request path: Write copies the encoded entry into a
+// bounded queue and returns, and one goroutine writes queued entries to the output in batches.
+// Sync waits until everything queued so far is written and synced, so the logger's Sync on
+// shutdown flushes it. The goroutine lives as long as the process, as logging may go on while
+// other components stop
+type AsyncWriter struct {
+	out    zapcore.WriteSyncer
+	queue  chan []byte
+	flush  chan chan error
+	policy OverflowPolicy
+
+	written atomic.Int64
+	dropped atomic.Int64
+	errors  atomic.Int64
+}
+
+// NewAsyncWriter starts an AsyncWriter holding up to size entries for out
+func NewAsyncWriter(out zapcore.WriteSyncer, size int, policy OverflowPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = 1
+	}
+	w := &AsyncWriter{
+		out:    out,
+		queue:  make(chan []byte, size),
+		flush:  make(chan chan error),
+		policy: policy,
+	}
+	safego.Go("logger.async_writer", w.run, safego.Restart(nil))
+	return w
+}
+
+// Write queues a copy of p; zap reuses its buffer once Write returns
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	entry := bytes.Clone(p)
+	if w.policy != OverflowDropOldest {
+		w.queue <- entry
+		return len(p), nil
+	}
+
+	for {
+		select {
+		case w.queue <- entry:
+			return len(p), nil
+		default:
+		}
+		// Full: drop the oldest entry and retry; the writer may have drained it meanwhile
+		select {
+		case <-w.queue:
+			w.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Sync blocks until every entry queued before it is written, then syncs the output
+func (w *AsyncWriter) Sync() error {
+	done := make(chan error, 1)
+	w.flush <- done
+	return <-done
+}
+
+// GetMetrics returns entries written, dropped on overflow and failed writes, plus the queue depth
+func (w *AsyncWriter) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"written":  w.written.Load(),
+		"dropped":  w.dropped.Load(),
+		"errors":   w.errors.Load(),
+		"queued":   int64(len(w.queue)),
+		"capacity": int64(cap(w.queue)),
+	}
+}
+
+func (w *AsyncWriter) run() {
+	var batch bytes.Buffer
+	for {
+		select {
+		case entry := <-w.queue:
+			w.add(&batch, entry)
+			// Coalesce whatever else is queued into the same write
+			for batch.Len() < asyncBatchBytes && w.next(&batch) {
+			}
+			w.writeBatch(&batch)
+		case done := <-w.flush:
+			// Only what was queued when Sync was called, so constant logging can't starve it
+			for n := len(w.queue); n > 0 && w.next(&batch); n-- {
+				if batch.Len() >= asyncBatchBytes {
+					w.writeBatch(&batch)
+				}
+			}
+			w.writeBatch(&batch)
+			done <- w.out.Sync()
+		}
+	}
+}
+
+// next moves one queued entry into batch without waiting, reporting whether there was one
+func (w *AsyncWriter) next(batch *bytes.Buffer) bool {
+	select {
+	case entry := <-w.queue:
+		w.add(batch, entry)
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *AsyncWriter) add(batch *bytes.Buffer, entry []byte) {
+	batch.Write(entry)
+	w.written.Add(1)
+}
+
+func (w *AsyncWriter) writeBatch(batch *bytes.Buffer) {
+	if batch.Len() == 0 {
+		return
+	}
+	if _, err := w.out.Write(batch.Bytes()); err != nil {
+		w.errors.Add(1)
+	}
+	batch.Reset()
+}
@@ -1,17 +1,160 @@
 package logger
 
 import (
+	"acid/internal/authz"
+	"acid/internal/clientip"
+	"acid/internal/correlation"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var Logger *zap.Logger
 
+// Config tunes log sampling: each SampleTick the first SampleInitial entries with the same level
+// and message are logged, then every SampleThereafter-th. Errors and above are never sampled
+type Config struct {
+	// SampleInitial entries per message per tick are always logged; 0 disables sampling
+	SampleInitial int
+
+	// SampleThereafter logs every Nth further entry in the tick; 0 drops them all
+	SampleThereafter int
+
+	// SampleTick is the sampling window
+	SampleTick time.Duration
+
+	// Async writes entries from a bounded queue on a background goroutine instead of on the
+	// logging goroutine, see AsyncWriter
+	Async bool
+
+	// AsyncQueueSize is the number of entries the queue holds
+	AsyncQueueSize int
+
+	// AsyncOverflow is what happens to entries logged while the queue is full
+	AsyncOverflow OverflowPolicy
+}
+
+// DefaultConfig matches zap's production sampling, writing synchronously
+func DefaultConfig() *Config {
+	return &Config{
+		SampleInitial:    100,
+		SampleThereafter: 100,
+		SampleTick:       time.Second,
+		AsyncQueueSize:   8192,
+		AsyncOverflow:    OverflowBlock,
+	}
+}
+
+// asyncWriter is the writer of the async logger InitLoggerWithConfig built, if any
+var asyncWriter *AsyncWriter
+
+// AsyncMetrics returns the metrics of the async log writer, or nil when logging is synchronous
+func AsyncMetrics() map[string]int64 {
+	if asyncWriter == nil {
+		return nil
+	}
+	return asyncWriter.GetMetrics()
+}
+
 func InitLogger() (*zap.Logger, error) {
-	var err error
-	Logger, err = zap.NewProduction()
-	defer Logger.Sync()
+	return InitLoggerWithConfig(DefaultConfig())
+}
+
+// InitLoggerWithConfig builds the production JSON logger with the given sampling
+func InitLoggerWithConfig(config *Config) (*zap.Logger, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Sampling = nil // Applied below so errors can bypass it
+
+	var options []zap.Option
+	if config.Async {
+		// Same output, encoding and level as the production core, written through the queue
+		out, _, err := zap.Open(zapConfig.OutputPaths...)
+		if err != nil {
+			return nil, err
+		}
+		writer := NewAsyncWriter(out, config.AsyncQueueSize, config.AsyncOverflow)
+		encoder := zapcore.NewJSONEncoder(zapConfig.EncoderConfig)
+		options = append(options, zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return zapcore.NewCore(encoder, writer, zapConfig.Level)
+		}))
+		asyncWriter = writer
+	}
+	if config.SampleInitial > 0 {
+		options = append(options, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			sampled := zapcore.NewSamplerWithOptions(belowLevelCore{Core: core, level: zapcore.ErrorLevel},
+				config.SampleTick, config.SampleInitial, config.SampleThereafter)
+			errors, err := zapcore.NewIncreaseLevelCore(core, zapcore.ErrorLevel)
+			if err != nil {
+				return core
+			}
+			return zapcore.NewTee(sampled, errors)
+		}))
+	}
+
+	logger, err := zapConfig.Build(options...)
 	if err != nil {
 		return nil, err
 	}
-	return Logger, nil
+	Logger = logger
+	return logger, nil
+}
+
+// For returns base annotated with the request behind ctx: request_id, client_ip, auth_subject (the
+// authenticated caller), plus trace_id and span_id of the active OpenTelemetry span so log lines
+// can be joined with traces (Grafana/Tempo). With no tracer SDK installed the span is the
+// caller's, taken from its traceparent
+func For(ctx context.Context, base *zap.Logger) *zap.Logger {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return base
+	}
+	return base.With(fields...)
+}
+
+// ContextFields returns the correlation fields For adds, for callers building their own entries
+func ContextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if requestID := correlation.RequestID(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	if clientAddr := clientip.FromContext(ctx); clientAddr.IsValid() {
+		fields = append(fields, zap.String("client_ip", clientAddr.String()))
+	}
+	if grant, ok := authz.FromContext(ctx); ok {
+		fields = append(fields, zap.String("auth_subject", grant.Subject))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()))
+	}
+	return fields
+}
+
+// belowLevelCore passes only entries below level, so the sampler never sees errors
+type belowLevelCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c belowLevelCore) Enabled(level zapcore.Level) bool {
+	return level < c.level && c.Core.Enabled(level)
+}
+
+func (c belowLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return belowLevelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c belowLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level >= c.level {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
 }
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// DryRunSender keeps the most recent messages in memory instead of delivering them, so tests and
+// staging environments can inspect what would have been sent
+type DryRunSender struct {
+	limit int
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewDryRunSender keeps up to limit messages, dropping the oldest first
+func NewDryRunSender(limit int) *DryRunSender {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &DryRunSender{limit: limit}
+}
+
+func (s *DryRunSender) Send(ctx context.Context, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sent) >= s.limit {
+		s.sent = s.sent[1:]
+	}
+	s.sent = append(s.sent, *msg)
+	return nil
+}
+
+// Sent returns a copy of the kept messages, oldest first
+func (s *DryRunSender) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.sent...)
+}
+
+// Reset forgets the kept messages
+func (s *DryRunSender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = nil
+}
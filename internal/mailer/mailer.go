@@ -0,0 +1,266 @@
+// Package mailer renders the service's emails from html/template files and hands them to a Sender:
+// SMTP, Amazon SES, the log, or an in-memory dry run
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Template names available in templates/. Verification and password reset emails take a
+// Username, Email, Link and ExpiresIn
+const (
+	TemplateWelcome       = "welcome"
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Message is a rendered email ready to send
+type Message struct {
+	From     string
+	To       string
+	Subject  string
+	HTMLBody string
+	TextBody string // Plain-text alternative; empty when the template defines no "text" block
+
+	// Template and Locale record what the message was rendered from, for logs and dry runs
+	Template string
+	Locale   string
+}
+
+// Sender delivers rendered messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// BounceError is returned by a Sender when the provider refused the recipient for good, e.g. an
+// SMTP 550 for a mailbox that doesn't exist. Retrying won't help
+type BounceError struct {
+	Recipient string
+	Reason    string
+}
+
+func (e *BounceError) Error() string {
+	return fmt.Sprintf("email to %s bounced: %s", e.Recipient, e.Reason)
+}
+
+// LogSender logs messages instead of delivering them; used when no provider is configured
+type LogSender struct {
+	logger *zap.Logger
+}
+
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg *Message) error {
+	s.logger.Info("Email send (log only)",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("template", msg.Template),
+		zap.String("locale", msg.Locale),
+		zap.Int("body_bytes", len(msg.HTMLBody)))
+	return nil
+}
+
+// Translator looks up the text of key in locale for the templates' t function, returning false
+// when it has none; the template's own default text is used then
+type Translator func(locale, key string) (string, bool)
+
+// Config holds mailer configuration
+type Config struct {
+	// From is the sender address of every email, e.g. "Acid <no-reply@example.com>"
+	From string
+
+	// DefaultLocale is used for recipients without a locale, and its templates are the fallback
+	// for locales without their own
+	DefaultLocale string
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() *Config {
+	return &Config{
+		From:          "Acid <no-reply@localhost>",
+		DefaultLocale: "en",
+	}
+}
+
+// Metrics tracks email delivery for observability
+type Metrics struct {
+	Sent    atomic.Int64
+	Failed  atomic.Int64 // Send errors other than bounces; the caller may retry
+	Bounced atomic.Int64 // Refused recipients, at send time or reported later by RecordBounce
+}
+
+// Mailer renders html/template emails and hands them to a Sender
+// Each template file defines a "subject" and a "body" block, and optionally a plain-text "text"
+// block. templates/<name>.html is the default; templates/<name>.<locale>.html (e.g.
+// welcome.de.html) is used for that locale
+type Mailer struct {
+	sender     Sender
+	config     *Config
+	templates  map[string]map[string]*template.Template // Template name -> locale ("" default) -> template
+	translator Translator
+	metrics    *Metrics
+}
+
+// New parses all embedded templates; a malformed template fails startup
+func New(sender Sender, config *Config) (*Mailer, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	m := &Mailer{
+		sender:    sender,
+		config:    config,
+		templates: make(map[string]map[string]*template.Template),
+		metrics:   &Metrics{},
+	}
+
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+	for _, entry := range entries {
+		name, locale, _ := strings.Cut(strings.TrimSuffix(entry.Name(), ".html"), ".")
+		// t is rebound to the recipient's locale on every render
+		tmpl, err := template.New(entry.Name()).Funcs(m.funcs("")).ParseFS(templateFS, "templates/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", entry.Name(), err)
+		}
+		if m.templates[name] == nil {
+			m.templates[name] = make(map[string]*template.Template)
+		}
+		m.templates[name][strings.ToLower(locale)] = tmpl
+	}
+	for name, locales := range m.templates {
+		if locales[""] == nil {
+			return nil, fmt.Errorf("email template %s has locale variants but no default %s.html", name, name)
+		}
+	}
+	return m, nil
+}
+
+// SetTranslator installs the lookup behind the templates' t function, e.g. backed by message
+// catalogs. Without one t returns its default text. Must be called during startup
+func (m *Mailer) SetTranslator(translator Translator) {
+	m.translator = translator
+}
+
+// funcs returns the template functions bound to locale. {{t "key" "default text"}} translates
+func (m *Mailer) funcs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key, fallback string) string {
+			if m.translator != nil {
+				if text, ok := m.translator(locale, key); ok {
+					return text
+				}
+			}
+			return fallback
+		},
+		"locale": func() string { return locale },
+	}
+}
+
+// Render executes a template against data in locale ("de-AT" falls back to "de", then to the
+// default template); an empty locale is the default one
+func (m *Mailer) Render(name, locale, to string, data any) (*Message, error) {
+	locales, ok := m.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	if locale == "" {
+		locale = m.config.DefaultLocale
+	}
+
+	base, err := m.localized(locales, locale).Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare template %s: %w", name, err)
+	}
+	tmpl := base.Funcs(m.funcs(locale))
+
+	var subject, body, text bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, fmt.Errorf("failed to render body of %s: %w", name, err)
+	}
+	if tmpl.Lookup("text") != nil {
+		if err := tmpl.ExecuteTemplate(&text, "text", data); err != nil {
+			return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+		}
+	}
+
+	return &Message{
+		From: m.config.From,
+		To:   to,
+		// Subjects and text bodies are plain text; undo the HTML escaping applied by html/template
+		Subject:  html.UnescapeString(strings.TrimSpace(subject.String())),
+		HTMLBody: body.String(),
+		TextBody: html.UnescapeString(strings.TrimSpace(text.String())),
+		Template: name,
+		Locale:   locale,
+	}, nil
+}
+
+// localized picks the variant of a template for locale, trying the language without its region
+// next and the default template last
+func (m *Mailer) localized(locales map[string]*template.Template, locale string) *template.Template {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if tmpl, ok := locales[locale]; ok {
+		return tmpl
+	}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		if tmpl, ok := locales[language]; ok {
+			return tmpl
+		}
+	}
+	return locales[""]
+}
+
+// Send renders a template in the recipient's locale and delivers it
+func (m *Mailer) Send(ctx context.Context, name, locale, to string, data any) error {
+	msg, err := m.Render(name, locale, to, data)
+	if err != nil {
+		return err
+	}
+
+	err = m.sender.Send(ctx, msg)
+	var bounce *BounceError
+	switch {
+	case err == nil:
+		m.metrics.Sent.Add(1)
+	case errors.As(err, &bounce):
+		m.metrics.Bounced.Add(1)
+	default:
+		m.metrics.Failed.Add(1)
+	}
+	return err
+}
+
+// RecordBounce counts a bounce the provider reported after accepting the email, e.g. an SES
+// bounce notification
+func (m *Mailer) RecordBounce() {
+	m.metrics.Bounced.Add(1)
+}
+
+// GetMetrics returns email delivery counts of this instance
+func (m *Mailer) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"sent":    m.metrics.Sent.Load(),
+		"failed":  m.metrics.Failed.Load(),
+		"bounced": m.metrics.Bounced.Load(),
+	}
+}
@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"acid/internal/httpclient"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// SESConfig holds Amazon SES configuration. Credentials come from the default AWS chain
+type SESConfig struct {
+	// Region overrides the region of the AWS config, e.g. "eu-west-1"
+	Region string
+
+	// ConfigurationSet routes SES events (bounces, complaints, deliveries) to their destinations
+	ConfigurationSet string
+}
+
+// SESSender delivers messages through the Amazon SES v2 API
+type SESSender struct {
+	client *sesv2.Client
+	config *SESConfig
+}
+
+func NewSESSender(ctx context.Context, sesConfig *SESConfig) (*SESSender, error) {
+	if sesConfig == nil {
+		sesConfig = &SESConfig{}
+	}
+	// The SDK retries by itself
+	clientConfig := httpclient.DefaultConfig("ses")
+	clientConfig.MaxAttempts = 1
+	options := []func(*config.LoadOptions) error{
+		config.WithHTTPClient(httpclient.New(clientConfig).StandardClient()),
+	}
+	if sesConfig.Region != "" {
+		options = append(options, config.WithRegion(sesConfig.Region))
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &SESSender{client: sesv2.NewFromConfig(awsConfig), config: sesConfig}, nil
+}
+
+func (s *SESSender) Send(ctx context.Context, msg *Message) error {
+	body := &types.Body{Html: &types.Content{Data: aws.String(msg.HTMLBody), Charset: aws.String("UTF-8")}}
+	if msg.TextBody != "" {
+		body.Text = &types.Content{Data: aws.String(msg.TextBody), Charset: aws.String("UTF-8")}
+	}
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination:      &types.Destination{ToAddresses: []string{msg.To}},
+		Content: &types.EmailContent{Simple: &types.Message{
+			Subject: &types.Content{Data: aws.String(msg.Subject), Charset: aws.String("UTF-8")},
+			Body:    body,
+		}},
+	}
+	if s.config.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.config.ConfigurationSet)
+	}
+	if msg.Template != "" {
+		input.EmailTags = []types.MessageTag{{Name: aws.String("template"), Value: aws.String(msg.Template)}}
+	}
+
+	if _, err := s.client.SendEmail(ctx, input); err != nil {
+		// SES accepts mail for bad mailboxes and reports the bounce later through the
+		// configuration set; only a message it refuses outright is known to be undeliverable now
+		var rejected *types.MessageRejected
+		if errors.As(err, &rejected) {
+			return &BounceError{Recipient: msg.To, Reason: rejected.ErrorMessage()}
+		}
+		return fmt.Errorf("SES delivery to %s failed: %w", msg.To, err)
+	}
+	return nil
+}
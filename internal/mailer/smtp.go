@@ -0,0 +1,184 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig holds SMTP relay configuration
+type SMTPConfig struct {
+	Host string
+	Port int
+
+	// Username and Password authenticate with PLAIN auth when set; the server must offer STARTTLS
+	Username string
+	Password string
+
+	// Timeout bounds the whole delivery of one message
+	Timeout time.Duration
+}
+
+// DefaultSMTPConfig returns sensible defaults
+func DefaultSMTPConfig() *SMTPConfig {
+	return &SMTPConfig{
+		Host:    "localhost",
+		Port:    587,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// SMTPSender delivers messages through an SMTP relay, upgrading to TLS when the server offers it
+type SMTPSender struct {
+	config *SMTPConfig
+}
+
+func NewSMTPSender(config *SMTPConfig) *SMTPSender {
+	if config == nil {
+		config = DefaultSMTPConfig()
+	}
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		// An address that can't be parsed will never be delivered
+		return &BounceError{Recipient: msg.To, Reason: err.Error()}
+	}
+	body, err := buildMIME(msg, from, to)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	// net/smtp takes no context; the deadline bounds the conversation instead
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake with %s failed: %w", addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection to a remote host
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM refused: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return smtpError(to.Address, err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return smtpError(to.Address, err)
+	}
+	if _, err := writer.Write(body); err != nil {
+		return fmt.Errorf("failed to write SMTP message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return smtpError(to.Address, err)
+	}
+	return client.Quit()
+}
+
+// smtpError turns a permanent 5xx rejection of the recipient or message into a BounceError;
+// 4xx replies are temporary and left for a retry
+func smtpError(recipient string, err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 && reply.Code < 600 {
+		return &BounceError{Recipient: recipient, Reason: fmt.Sprintf("%d %s", reply.Code, reply.Msg)}
+	}
+	return fmt.Errorf("SMTP delivery to %s failed: %w", recipient, err)
+}
+
+// buildMIME encodes msg as multipart/alternative with the text part first, as RFC 2046 orders
+// them from plainest to richest; messages without a text body are sent as HTML only
+func buildMIME(msg *Message, from, to *mail.Address) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	if msg.Locale != "" {
+		header("Content-Language", msg.Locale)
+	}
+
+	if msg.TextBody == "" {
+		header("Content-Type", `text/html; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.HTMLBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var random [12]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+	boundary := "acid-" + hex.EncodeToString(random[:])
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.TextBody},
+		{"text/html", msg.HTMLBody},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=\"utf-8\"\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, part.body); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	writer := quotedprintable.NewWriter(buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return err
+	}
+	return writer.Close()
+}
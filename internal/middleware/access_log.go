@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogConfig samples the access log of successful requests; responses with status >= 400
+// are always logged
+type AccessLogConfig struct {
+	// SampleRate is the fraction of successful requests logged (1 = all, 0 = none)
+	SampleRate float64
+
+	// PathSampleRates overrides SampleRate per route, keyed by route template (e.g. "/livez")
+	PathSampleRates map[string]float64
+}
+
+// DefaultAccessLogConfig logs every request except 99% of successful liveness/readiness probes,
+// which otherwise make up most of the log volume
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		SampleRate: 1,
+		PathSampleRates: map[string]float64{
+			"/livez":  0.01,
+			"/readyz": 0.01,
+		},
+	}
+}
+
+// ParsePathSampleRates parses "path=rate" pairs separated by commas, e.g. "/livez=0.01,/readyz=0";
+// entries that don't parse are skipped
+func ParsePathSampleRates(raw string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(raw, ",") {
+		path, rateText, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || path == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateText), 64)
+		if err != nil {
+			continue
+		}
+		rates[strings.TrimSpace(path)] = rate
+	}
+	return rates
+}
+
+// AccessLog is gin's access logger with per-route sampling of successful requests
+func AccessLog(config *AccessLogConfig) gin.HandlerFunc {
+	if config == nil {
+		config = DefaultAccessLogConfig()
+	}
+	return gin.LoggerWithConfig(gin.LoggerConfig{Skip: config.skip})
+}
+
+// skip runs after the handler, so the response status is known
+func (config *AccessLogConfig) skip(c *gin.Context) bool {
+	if c.Writer.Status() >= 400 {
+		return false
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	rate, ok := config.PathSampleRates[route]
+	if !ok {
+		rate = config.SampleRate
+	}
+	return rate < 1 && rand.Float64() >= rate
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"acid/internal/authz"
+	"acid/internal/problem"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth protects admin routes with a static bearer token (ADMIN_TOKEN)
+// An empty token disables the admin API entirely rather than leaving it open
+// Browsers can't set headers on WebSocket upgrades, so those may pass ?access_token= instead
+// Requests ServiceAuth or APIKeyAuth granted the route's permission are let through too
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			problem.Abort(c, problem.New(http.StatusForbidden, "admin API is disabled"))
+			return
+		}
+		if _, ok := c.Get(GrantKey); ok {
+			c.Next()
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if provided == "" && c.IsWebsocket() {
+			provided = c.Query("access_token")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "invalid admin token"))
+			return
+		}
+
+		setGrant(c, authz.AdminGrant)
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"acid/internal/authz"
+	"acid/internal/problem"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// publicPathPrefixes are the public API, whose writes need users:write
+var publicPathPrefixes = []string{"/api/", "/graphql"}
+
+// APIKeyAuth grants requests carrying a registered API key in X-API-Key the permissions of the
+// key's roles, checked like ServiceAuth checks scopes. Unregistered keys stay plain quota keys.
+// It runs last of the credentials, so it also settles requests none of them authorized: the
+// admin token is granted admin on the public API, and writes to it are refused unless the
+// policy serves them anonymously. Reads, writes to signedPaths (whose handlers check a signed
+// link instead, e.g. unsubscribe) and requests another credential already authorized pass
+func APIKeyAuth(policy *authz.Policy, adminToken string, signedPaths []string, readPaths ...string) gin.HandlerFunc {
+	reads := pathSet(readPaths)
+	signed := pathSet(signedPaths)
+
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(HeaderAPIKey)
+		if _, authorized := c.Get(GrantKey); authorized {
+			c.Next()
+			return
+		}
+		if apiKey == "" {
+			anonymous(c, policy, adminToken, signed, reads)
+			return
+		}
+
+		grant, err := policy.AuthenticateKey(c.Request.Context(), apiKey)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusServiceUnavailable, "failed to verify API key"))
+			return
+		}
+		if grant == nil {
+			anonymous(c, policy, adminToken, signed, reads)
+			return
+		}
+		if permission, ok := authorize(c, policy, grant, reads); !ok {
+			problem.Abort(c, problem.New(http.StatusForbidden, "API key lacks permission "+permission).
+				With("permission", permission))
+			return
+		}
+
+		setGrant(c, grant)
+		c.Next()
+	}
+}
+
+// anonymous serves a request no access token or registered API key authorized. Admin paths are
+// left to AdminAuth
+func anonymous(c *gin.Context, policy *authz.Policy, adminToken string, signed, reads map[string]bool) {
+	if !publicPath(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+		setGrant(c, authz.AdminGrant)
+		c.Next()
+		return
+	}
+	if requiredPermission(c, reads) == authz.UsersWrite && !signed[c.FullPath()] && !policy.AllowAnonymousWrite() {
+		c.Header("WWW-Authenticate", `Bearer scope="`+authz.UsersWrite+`"`)
+		problem.Abort(c, problem.New(http.StatusUnauthorized,
+			"authentication required: send an access token or admin token in Authorization, or a registered API key in X-API-Key").
+			With("permission", authz.UsersWrite))
+		return
+	}
+	c.Next()
+}
+
+func publicPath(path string) bool {
+	for _, prefix := range publicPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission refuses requests whose credentials weren't granted permission, even where
+// the policy serves anonymous writes: 401 without credentials, 403 with a grant lacking it
+func RequirePermission(permission authz.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		grant, ok := authz.FromContext(c.Request.Context())
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer scope="`+permission+`"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "authentication required").
+				With("permission", permission))
+			return
+		}
+		if !grant.Allows(permission) {
+			problem.Abort(c, problem.New(http.StatusForbidden, "caller lacks permission "+permission).
+				With("permission", permission))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"acid/internal/capture"
+	"acid/internal/correlation"
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Capture records the request and response bodies of the recorder's routes for the admin API.
+// The request body is read up to the size cap and handed on unchanged; the response is copied as
+// it is written. Register it before Problems so rendered errors are captured too. Other routes
+// pass through untouched
+func Capture(recorder *capture.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !recorder.Captures(c.Request.Method, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		limit := recorder.MaxBodyBytes()
+
+		var requestRaw []byte
+		counter := &countingReader{}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			requestRaw, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+			counter.Reader = io.MultiReader(bytes.NewReader(requestRaw), c.Request.Body)
+			c.Request.Body = readCloser{Reader: counter, Closer: c.Request.Body}
+		}
+
+		writer := &captureWriter{ResponseWriter: c.Writer, limit: limit}
+		c.Writer = writer
+
+		c.Next()
+
+		recorder.Record(capture.Exchange{
+			Time:       start,
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Query:      recorder.RedactQuery(c.Request.URL.RawQuery),
+			Status:     writer.Status(),
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			RequestID:  correlation.RequestID(c.Request.Context()),
+			ClientIP:   ClientAddr(c).String(),
+			Request:    recorder.NewBody(c.GetHeader("Content-Type"), requestRaw, max(len(requestRaw), counter.n)),
+			Response:   recorder.NewBody(writer.Header().Get("Content-Type"), writer.body.Bytes(), writer.size),
+		})
+	}
+}
+
+// countingReader counts what the handler read, so a body larger than the cap reports its size
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter copies up to limit+1 bytes of the response while counting all of it
+type captureWriter struct {
+	gin.ResponseWriter
+	limit int
+	body  bytes.Buffer
+	size  int
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.copy(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.copy([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) copy(data []byte) {
+	w.size += len(data)
+	if room := w.limit + 1 - w.body.Len(); room > 0 {
+		w.body.Write(data[:min(room, len(data))])
+	}
+}
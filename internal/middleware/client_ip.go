@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"acid/internal/clientip"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP stores the request's client address in its context, so context loggers and services
+// see the same address as the access log, rate limiter and IP filter. The engine must have been
+// set up with clientip.Resolver.Configure
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(clientip.NewContext(c.Request.Context(), clientip.ParseAddr(c.ClientIP())))
+		c.Next()
+	}
+}
+
+// ClientAddr returns the request's client address, resolved through the trusted proxies
+func ClientAddr(c *gin.Context) netip.Addr {
+	if addr := clientip.FromContext(c.Request.Context()); addr.IsValid() {
+		return addr
+	}
+	return clientip.ParseAddr(c.ClientIP())
+}
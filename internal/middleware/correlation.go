@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"acid/internal/correlation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key holding the request ID
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength bounds client-supplied IDs so they can't bloat logs and headers
+const maxRequestIDLength = 128
+
+// Correlation stores the request ID, trace context and credentials on the request context so
+// outbound calls made by handlers propagate them (see correlation.NewHTTPClient / DialOptions)
+// The request ID is taken from X-Request-ID or generated, and echoed on the response
+func Correlation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		md := correlation.Metadata{
+			RequestID:     c.GetHeader(correlation.HeaderRequestID),
+			TraceParent:   c.GetHeader(correlation.HeaderTraceParent),
+			TraceState:    c.GetHeader(correlation.HeaderTraceState),
+			Authorization: c.GetHeader(correlation.HeaderAuthorization),
+		}
+		if md.RequestID == "" || len(md.RequestID) > maxRequestIDLength {
+			md.RequestID = correlation.NewRequestID()
+		}
+
+		c.Request = c.Request.WithContext(correlation.WithMetadata(c.Request.Context(), md))
+		c.Set(RequestIDKey, md.RequestID)
+		c.Header(correlation.HeaderRequestID, md.RequestID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomMethodParam is the route parameter holding a custom method, e.g. "/users:method"
+const CustomMethodParam = "method"
+
+// CustomMethods dispatches collection custom methods such as POST /users:batchGet, registered as
+// "/users:method". gin only routes an escaped literal colon ("/users\\:batchGet") after
+// Engine.Run has rewritten its trees, which a server built on http.Server never calls, so the
+// method name is matched here instead; unknown names answer like an unknown route
+func CustomMethods(methods map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, ok := strings.CutPrefix(c.Param(CustomMethodParam), ":")
+		if handler := methods[name]; ok && handler != nil {
+			handler(c)
+			return
+		}
+		NoRoute(c)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"acid/internal/correlation"
+	"acid/internal/problem"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline bounds each request's context by the deadline its caller sent in X-Request-Deadline
+// or X-Request-Timeout-Ms, clamped by config, so an upstream budget also cuts short the cache and
+// database calls made for the request. A request whose deadline has passed is refused with 504.
+// Exempt paths are long-lived streams (SSE, WebSocket) that must not inherit a request deadline
+func Deadline(config *correlation.DeadlineConfig, exemptPaths ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		deadline, ok, err := config.Resolve(now, time.Time{}, c.GetHeader)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+		if !deadline.After(now) {
+			problem.Abort(c, problem.New(http.StatusGatewayTimeout, "the request deadline has already passed"))
+			return
+		}
+
+		ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"acid/internal/debugtrace"
+	"acid/internal/jsoncodec"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DebugTokenHeader turns on debug mode for one request; its value must be the admin token
+	DebugTokenHeader = "X-Debug-Token"
+
+	// DebugTraceHeader carries the request's debugtrace.Summary as JSON
+	DebugTraceHeader = "X-Debug-Trace"
+)
+
+// DebugTrace annotates responses to requests carrying the admin token in X-Debug-Token with what
+// the request did: cache lookups and the tier that answered, every CQL statement attempt with its
+// latency, and repository retries. The trace goes into X-Debug-Trace, with totals in a
+// Server-Timing header for browser dev tools. Requests without the header, or with a wrong token,
+// are served as usual; an empty token disables debug mode
+func DebugTrace(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(DebugTokenHeader)
+		if adminToken == "" || provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			c.Next()
+			return
+		}
+
+		ctx, trace := debugtrace.WithTrace(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		writer := &debugWriter{ResponseWriter: c.Writer, trace: trace}
+		c.Writer = writer
+
+		c.Next()
+
+		// Bodiless responses never went through the writer
+		writer.annotate()
+	}
+}
+
+// debugWriter adds the trace headers right before the headers are sent
+type debugWriter struct {
+	gin.ResponseWriter
+	trace     *debugtrace.Trace
+	annotated bool
+}
+
+func (w *debugWriter) annotate() {
+	if w.annotated || w.ResponseWriter.Written() {
+		return
+	}
+	w.annotated = true
+
+	summary := w.trace.Summary()
+	if encoded, err := jsoncodec.Marshal(summary); err == nil {
+		w.Header().Set(DebugTraceHeader, string(encoded))
+	}
+	w.Header().Set("Server-Timing", strings.Join([]string{
+		fmt.Sprintf("cache;desc=\"%d lookups\";dur=%.3f", len(summary.Cache), summary.CacheMs),
+		fmt.Sprintf("cql;desc=\"%d statements\";dur=%.3f", len(summary.Statements), summary.CQLMs),
+		fmt.Sprintf("total;dur=%.3f", summary.TotalMs),
+	}, ", "))
+}
+
+func (w *debugWriter) WriteHeaderNow() {
+	w.annotate()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *debugWriter) Write(data []byte) (int, error) {
+	w.annotate()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *debugWriter) WriteString(s string) (int, error) {
+	w.annotate()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *debugWriter) Flush() {
+	w.annotate()
+	w.ResponseWriter.Flush()
+}
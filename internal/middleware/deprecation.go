@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"acid/internal/deprecation"
+	"acid/internal/problem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecations marks responses from routes deprecated in the registry with the Deprecation,
+// Sunset and Link headers and records the call by caller. Routes past the sunset of a hard
+// notice are answered with 410 Gone
+func Deprecations(registry *deprecation.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		endpoint := c.Request.Method + " " + route
+		notice, ok := registry.Lookup(endpoint)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		notice.SetHeaders(c.Header)
+		if registry.Record(endpoint, ClientIPKey(c)) {
+			detail := fmt.Sprintf("%s was retired on %s", endpoint, notice.Sunset.UTC().Format(time.RFC3339))
+			if notice.Replacement != "" {
+				detail += "; use " + notice.Replacement
+			}
+			problem.Abort(c, problem.Typed(http.StatusGone, problem.TypeRetired, "Endpoint retired", detail))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Encoding is a response body encoding selected from the Accept header
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingMsgPack  Encoding = "msgpack"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Binary media types accepted on read endpoints
+const (
+	MediaTypeMsgPack  = "application/x-msgpack"
+	MediaTypeProtobuf = "application/x-protobuf"
+)
+
+// EncodingKey is the gin context key holding the negotiated Encoding
+const EncodingKey = "response_encoding"
+
+// binaryMediaTypes maps accepted media types (including common aliases) to encodings
+var binaryMediaTypes = map[string]Encoding{
+	MediaTypeMsgPack:          EncodingMsgPack,
+	"application/msgpack":     EncodingMsgPack,
+	"application/vnd.msgpack": EncodingMsgPack,
+	MediaTypeProtobuf:         EncodingProtobuf,
+	"application/protobuf":    EncodingProtobuf,
+}
+
+// NegotiateEncoding lets internal consumers opt out of JSON on read endpoints: the first
+// msgpack or protobuf media type listed in Accept selects that encoding, anything else keeps
+// JSON. Handlers render through ResponseEncoding; routes without it always answer JSON
+func NegotiateEncoding() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+
+		encoding := EncodingJSON
+		for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err != nil {
+				continue
+			}
+			if binary, ok := binaryMediaTypes[mediaType]; ok {
+				encoding = binary
+				break
+			}
+		}
+
+		c.Set(EncodingKey, encoding)
+		c.Next()
+	}
+}
+
+// ResponseEncoding returns the encoding chosen by NegotiateEncoding, JSON if it didn't run
+func ResponseEncoding(c *gin.Context) Encoding {
+	if encoding, ok := c.Get(EncodingKey); ok {
+		return encoding.(Encoding)
+	}
+	return EncodingJSON
+}
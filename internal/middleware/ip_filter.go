@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"acid/internal/ipfilter"
+	"acid/internal/problem"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPFilter rejects requests from addresses the route's scope doesn't allow with 403: /admin,
+// /debug and /ws are the admin scope, everything else the api scope. Health probes are exempt so
+// load balancers aren't locked out
+func IPFilter(filter *ipfilter.Filter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if path == "/livez" || path == "/readyz" {
+			c.Next()
+			return
+		}
+
+		scope := ipfilter.ScopeAPI
+		if IsAdminPath(path) {
+			scope = ipfilter.ScopeAdmin
+		}
+		if !filter.Allowed(scope, ClientAddr(c)) {
+			problem.Abort(c, problem.New(http.StatusForbidden, "client address is not allowed"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdminPath reports whether path is an operational endpoint rather than part of the API
+func IsAdminPath(path string) bool {
+	for _, prefix := range []string{"/admin", "/debug", "/ws"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"acid/internal/problem"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problems renders errors attached with c.Error as application/problem+json once the chain
+// returns. *problem.Problem errors are rendered as-is; anything else becomes an opaque 500 so
+// internal messages never reach clients. Register it right after Correlation so the request ID
+// is available as the problem instance and errors from later middleware are covered
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var p *problem.Problem
+		if !errors.As(err, &p) {
+			log.Printf("[Problems] Unhandled error on %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			p = problem.New(http.StatusInternalServerError, "")
+		}
+		if p.Instance == "" {
+			p.Instance = c.GetString(RequestIDKey)
+		}
+
+		problem.Write(c, p)
+	}
+}
+
+// NoRoute answers unknown paths with a 404 problem instead of gin's plain-text default
+func NoRoute(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path))
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"acid/internal/problem"
+	"acid/internal/quota"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderAPIKey carries the caller's API key; keys are opaque here and only identify the quota subject
+const HeaderAPIKey = "X-API-Key"
+
+// quotaHeaderSuffix names each period in the X-Quota-* headers
+var quotaHeaderSuffix = map[quota.Period]string{
+	quota.Daily:   "Day",
+	quota.Monthly: "Month",
+}
+
+// QuotaSubject bills requests to their API key (hashed), else the authenticated subject, else the client IP
+func QuotaSubject(c *gin.Context) string {
+	if apiKey := c.GetHeader(HeaderAPIKey); apiKey != "" {
+		return quota.APIKeySubject(apiKey)
+	}
+	if subject := c.GetString(AuthSubjectKey); subject != "" {
+		return "user:" + subject
+	}
+	return "ip:" + ClientAddr(c).String()
+}
+
+// Quota enforces daily and monthly quotas, reporting each limited period in X-Quota-Limit-*,
+// X-Quota-Remaining-* and X-Quota-Reset-* (unix seconds) headers, and rejects exhausted subjects
+// with 429 until the period resets. Requests of a tenant (see Tenant) are also metered against
+// the tenant's shared quota, once their own subject had room
+func Quota(manager *quota.Manager, keyFunc KeyFunc) gin.HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = QuotaSubject
+	}
+
+	return func(c *gin.Context) {
+		decision := manager.Consume(c.Request.Context(), keyFunc(c))
+		for _, usage := range decision.Usage {
+			suffix := quotaHeaderSuffix[usage.Period]
+			c.Header("X-Quota-Limit-"+suffix, strconv.FormatInt(usage.Limit, 10))
+			c.Header("X-Quota-Remaining-"+suffix, strconv.FormatInt(usage.Remaining, 10))
+			c.Header("X-Quota-Reset-"+suffix, strconv.FormatInt(usage.ResetAt.Unix(), 10))
+		}
+		if !decision.Allowed && decision.Exceeded != nil {
+			abortQuotaExceeded(c, decision.Exceeded, "")
+			return
+		}
+
+		if tenantID := c.GetString(TenantKey); tenantID != "" {
+			tenantDecision := manager.Consume(c.Request.Context(), quota.TenantSubject(tenantID))
+			if !tenantDecision.Allowed && tenantDecision.Exceeded != nil {
+				abortQuotaExceeded(c, tenantDecision.Exceeded, tenantID)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// abortQuotaExceeded rejects a request whose quota, or its tenant's when tenantID is set, ran out
+func abortQuotaExceeded(c *gin.Context, exceeded *quota.Usage, tenantID string) {
+	retryAfter := int64(math.Ceil(time.Until(exceeded.ResetAt).Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	detail := fmt.Sprintf("%s quota of %d requests reached", exceeded.Period, exceeded.Limit)
+	if tenantID != "" {
+		detail = fmt.Sprintf("%s quota of %d requests of tenant %s reached", exceeded.Period, exceeded.Limit, tenantID)
+	}
+	exceededProblem := problem.Typed(http.StatusTooManyRequests, problem.TypeQuotaExceeded, "Quota exceeded", detail).
+		With("period", exceeded.Period).
+		With("limit", exceeded.Limit).
+		With("reset_at", exceeded.ResetAt).
+		With("retry_after", retryAfter)
+	if tenantID != "" {
+		exceededProblem.With("tenant", tenantID)
+	}
+	problem.Abort(c, exceededProblem)
+}
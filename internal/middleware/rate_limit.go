@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"acid/internal/cache"
+	"acid/internal/problem"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc derives the rate limit bucket for a request
+type KeyFunc func(c *gin.Context) string
+
+// ClientIPKey buckets requests by client IP, or by authenticated subject when present
+func ClientIPKey(c *gin.Context) string {
+	if subject := c.GetString(AuthSubjectKey); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + ClientAddr(c).String()
+}
+
+// RateLimit rejects requests over limit per window with 429 and standard rate limit headers
+func RateLimit(limiter *cache.RateLimiter, limit int64, window time.Duration, keyFunc KeyFunc) gin.HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = ClientIPKey
+	}
+
+	return func(c *gin.Context) {
+		result, err := limiter.Allow(c.Request.Context(), c.FullPath()+":"+keyFunc(c), limit, window)
+		if err != nil && result == nil {
+			log.Printf("[RateLimit] Limiter error for %s: %v", c.FullPath(), err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+
+		if !result.Allowed {
+			retryAfter := int64(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			problem.Abort(c, problem.Typed(http.StatusTooManyRequests, problem.TypeRateLimited,
+				"Rate limit exceeded", fmt.Sprintf("limit of %d requests per %s reached", result.Limit, window)).
+				With("retry_after", retryAfter))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"acid/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly rejects mutating requests (anything but GET, HEAD and OPTIONS) with 503 on a
+// read-only instance. Exempt paths carry reads over POST (e.g. /graphql) and rely on the
+// service layer rejecting the actual mutations
+func ReadOnly(exemptPaths ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		problem.Abort(c, problem.Typed(http.StatusServiceUnavailable, problem.TypeReadOnly,
+			"Instance is read-only", "this instance does not accept writes"))
+	}
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"acid/internal/cache"
+	"acid/internal/jsoncodec"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthSubjectKey is the gin context key holding the authenticated subject
+// Auth middleware sets it; the response cache uses it to keep entries per caller
+const AuthSubjectKey = "auth_subject"
+
+// responseCacheGenTTL outlives any sensible response TTL so a scope never
+// falls back to an older generation while entries from it are still cached
+const responseCacheGenTTL = 24 * time.Hour
+
+// ScopeFunc resolves the invalidation scope for a request (e.g. "user:<id>")
+type ScopeFunc func(c *gin.Context) string
+
+// ResponseCache caches full HTTP responses of idempotent GET routes in the CacheManager
+// Entries are grouped by scope; invalidating a scope bumps its generation so every
+// response cached under the previous generation is never served again
+type ResponseCache struct {
+	cache *cache.CacheManager
+	name  string
+}
+
+// cachedResponse is the serialized form of a captured response
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// NewResponseCache creates a response cache backed by the given cache manager
+func NewResponseCache(cacheManager *cache.CacheManager, name string) *ResponseCache {
+	return &ResponseCache{
+		cache: cacheManager,
+		name:  name,
+	}
+}
+
+// RouteScope scopes entries by the matched route template (the default)
+func RouteScope(c *gin.Context) string {
+	return "route:" + c.FullPath()
+}
+
+// ParamScope scopes entries by a resource prefix and a path parameter, e.g. ParamScope("user", "id")
+func ParamScope(resource, param string) ScopeFunc {
+	return func(c *gin.Context) string {
+		return ResourceScope(resource, c.Param(param))
+	}
+}
+
+// ResourceScope is the scope ParamScope gives a resource, for invalidating it from outside a request
+func ResourceScope(resource, id string) string {
+	return resource + ":" + id
+}
+
+// Cache returns an opt-in middleware caching successful GET responses for ttl
+// Pass a nil scope to scope entries by route template
+func (rc *ResponseCache) Cache(ttl time.Duration, scope ScopeFunc) gin.HandlerFunc {
+	if scope == nil {
+		scope = RouteScope
+	}
+
+	return func(c *gin.Context) {
+		if rc == nil || rc.cache == nil || c.Request.Method != http.MethodGet ||
+			c.GetHeader("Cache-Control") == "no-cache" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := rc.buildKey(ctx, scope(c), c)
+
+		// Serve from cache if we have a valid entry
+		var cached cachedResponse
+		source, err := rc.cache.GetJSON(ctx, key, &cached)
+		if err == nil {
+			c.Header("X-Cache", "HIT")
+			c.Header("X-Cache-Source", source)
+			c.Data(cached.Status, cached.ContentType, cached.Body)
+			c.Abort()
+			return
+		}
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			log.Printf("[ResponseCache:%s] Cache lookup failed for key '%s': %v", rc.name, key, err)
+		}
+
+		// Cache miss - capture the handler's response
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Header("X-Cache", "MISS")
+
+		c.Next()
+
+		// Only cache successful, complete responses; stale or degraded answers (X-Served-From)
+		// must not outlive the outage that produced them
+		if writer.Status() != http.StatusOK || len(c.Errors) > 0 || writer.Header().Get("X-Served-From") != "" {
+			return
+		}
+
+		data, err := jsoncodec.Marshal(cachedResponse{
+			Status:      writer.Status(),
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("[ResponseCache:%s] Failed to marshal response for key '%s': %v", rc.name, key, err)
+			return
+		}
+
+		if err := rc.cache.SetWithTTL(ctx, key, string(data), ttl, ttl); err != nil {
+			log.Printf("[ResponseCache:%s] Failed to cache response for key '%s': %v", rc.name, key, err)
+		}
+	}
+}
+
+// Invalidate drops every cached response in the given scope by bumping its generation
+// Intended to be registered as a service-layer invalidation hook
+func (rc *ResponseCache) Invalidate(ctx context.Context, scope string) error {
+	if rc == nil || rc.cache == nil {
+		return nil
+	}
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return rc.cache.SetWithTTL(ctx, rc.cache.Keys().Key(cache.EntityResponseGeneration, scope), generation, responseCacheGenTTL, responseCacheGenTTL)
+}
+
+// buildKey derives the cache key from method, path, query, Accept, auth subject and scope generation
+func (rc *ResponseCache) buildKey(ctx context.Context, scope string, c *gin.Context) string {
+	generation, _, err := rc.cache.Get(ctx, rc.cache.Keys().Key(cache.EntityResponseGeneration, scope))
+	if err != nil {
+		generation = "0"
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Request.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Request.URL.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.GetHeader("Accept"))) // Negotiated routes vary the body by Accept
+	hash.Write([]byte{0})
+	hash.Write([]byte(requestSubject(c)))
+
+	var sum [sha256.Size]byte
+	buf := cache.AcquireKeyBuffer()
+	defer cache.ReleaseKeyBuffer(buf)
+	key := rc.cache.Keys().AppendKey(*buf, cache.EntityResponse, scope, generation)
+	key = append(key, ':')
+	key = hex.AppendEncode(key, hash.Sum(sum[:0]))
+	*buf = key
+	return string(key)
+}
+
+// requestSubject identifies the caller so cached responses are never shared across callers
+func requestSubject(c *gin.Context) string {
+	if subject := c.GetString(AuthSubjectKey); subject != "" {
+		return "subject:" + subject
+	}
+
+	// Unauthenticated routes that still carry credentials are keyed by the credential itself
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+		return "authorization:" + hex.EncodeToString(sum[:])
+	}
+
+	return "anonymous"
+}
+
+// capturingWriter tees the response body so it can be stored after the handler runs
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
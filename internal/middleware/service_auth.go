@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"acid/internal/authz"
+	"acid/internal/problem"
+	"acid/internal/serviceaccount"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GrantKey is the gin context key holding the *authz.Grant of a request authenticated with an
+// access token, a registered API key or the admin token. It is only set once the grant was
+// checked against the route, so AdminAuth lets such requests through
+const GrantKey = "grant"
+
+// adminPathPrefixes need the permission annotated on their route, admin when there is none
+var adminPathPrefixes = []string{"/admin", "/ws", "/debug"}
+
+// ServiceAuth authenticates requests carrying a service account access token in
+// Authorization: Bearer and checks its scopes with authorize. Requests without one pass on to
+// APIKeyAuth, and other bearer tokens (the admin token) to APIKeyAuth and AdminAuth
+func ServiceAuth(manager *serviceaccount.Manager, policy *authz.Policy, readPaths ...string) gin.HandlerFunc {
+	reads := pathSet(readPaths)
+
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok && c.IsWebsocket() {
+			token = c.Query("access_token")
+		}
+		if !serviceaccount.IsAccessToken(token) {
+			c.Next()
+			return
+		}
+
+		principal, err := manager.Verify(c.Request.Context(), token)
+		if errors.Is(err, serviceaccount.ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, err.Error()))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusServiceUnavailable, "failed to verify access token"))
+			return
+		}
+
+		grant := principal.Grant()
+		if permission, ok := authorize(c, policy, grant, reads); !ok {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+permission+`"`)
+			problem.Abort(c, problem.New(http.StatusForbidden, "token lacks scope "+permission))
+			return
+		}
+
+		c.Request = c.Request.WithContext(serviceaccount.WithPrincipal(c.Request.Context(), principal))
+		setGrant(c, grant)
+		c.Next()
+	}
+}
+
+// authorize checks grant against the permission the request needs: the one annotated on its
+// route for admin paths, users:read for reads (GET, HEAD, OPTIONS and reads, which read over
+// POST) and users:write for anything else. It returns the permission and whether it was granted
+func authorize(c *gin.Context, policy *authz.Policy, grant *authz.Grant, reads map[string]bool) (authz.Permission, bool) {
+	permission := requiredPermission(c, reads)
+	return permission, policy.Authorize(grant, permission)
+}
+
+func requiredPermission(c *gin.Context, reads map[string]bool) authz.Permission {
+	for _, prefix := range adminPathPrefixes {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			return authz.RoutePermission(c.Request.Method, c.FullPath())
+		}
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return authz.UsersRead
+	}
+	if reads[c.FullPath()] {
+		return authz.UsersRead
+	}
+	return authz.UsersWrite
+}
+
+// setGrant records an authorized grant on the request
+func setGrant(c *gin.Context, grant *authz.Grant) {
+	c.Set(GrantKey, grant)
+	c.Set(AuthSubjectKey, grant.Subject)
+	c.Request = c.Request.WithContext(authz.WithGrant(c.Request.Context(), grant))
+}
+
+func pathSet(paths []string) map[string]bool {
+	set := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		set[path] = true
+	}
+	return set
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"acid/internal/slo"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SLO records each request's latency and outcome under "METHOD /route"; 5xx responses count as
+// failures. Requests that matched no route are not recorded
+func SLO(tracker *slo.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		tracker.Record(c.Request.Method+" "+route, time.Since(start), c.Writer.Status() >= http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"acid/internal/authz"
+	"acid/internal/cache"
+	"acid/internal/problem"
+	"acid/internal/tenant"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantKey is the gin context key holding the tenant a request is made for
+const TenantKey = "tenant"
+
+// Tenant resolves the tenant of a request: the tenant its API key is bound to, else the value of
+// header when one is configured. The header is only trusted behind a gateway that sets it, so it
+// is off by default; a credential bound to a tenant always wins over it. Requests without a
+// tenant are limited per client only, as before tenants
+func Tenant(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var id string
+		if grant, ok := c.Get(GrantKey); ok {
+			id = grant.(*authz.Grant).Tenant
+		}
+		if id == "" && header != "" {
+			id = c.GetHeader(header)
+			if id != "" && !tenant.Valid(id) {
+				problem.Abort(c, problem.New(http.StatusBadRequest,
+					header+" must be lowercase letters, digits, - or _, up to 64 characters"))
+				return
+			}
+		}
+
+		if id != "" {
+			c.Set(TenantKey, id)
+			c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), id))
+		}
+		c.Next()
+	}
+}
+
+// TenantRateLimit caps the requests of each tenant across all its callers and routes, with the
+// limit tenants has in effect for it, and rejects requests over it with 429. It runs in addition
+// to the per-client RateLimit
+func TenantRateLimit(limiter *cache.RateLimiter, tenants *tenant.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetString(TenantKey)
+		if id == "" {
+			c.Next()
+			return
+		}
+		limit := tenants.RateLimit(c.Request.Context(), id)
+		if limit.Requests <= 0 || limit.Window <= 0 {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), id, limit.Requests, limit.Window)
+		if err != nil && result == nil {
+			log.Printf("[TenantRateLimit] Limiter error for tenant %s: %v", id, err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-Tenant-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Header("X-Tenant-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+
+		if !result.Allowed {
+			retryAfter := int64(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			problem.Abort(c, problem.Typed(http.StatusTooManyRequests, problem.TypeRateLimited,
+				"Rate limit exceeded", fmt.Sprintf("limit of %d requests per %s of tenant %s reached", result.Limit, limit.Window, id)).
+				With("tenant", id).
+				With("retry_after", retryAfter))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"acid/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocql/gocql"
+)
+
+// uuidParamKeyPrefix namespaces parsed UUIDs stored on the gin context
+const uuidParamKeyPrefix = "uuid_param:"
+
+// ValidateUUIDParams validates the named path parameters as UUIDs before the handler runs
+// Invalid values are rejected with 400 so they never reach the repository layer
+func ValidateUUIDParams(params ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range params {
+			value := c.Param(name)
+
+			parsed, err := gocql.ParseUUID(value)
+			if err != nil {
+				problem.Abort(c, problem.FieldProblem(name, "must be a valid UUID"))
+				return
+			}
+
+			// Store the parsed value so handlers don't need to parse again
+			c.Set(uuidParamKeyPrefix+name, parsed)
+		}
+
+		c.Next()
+	}
+}
+
+// UUIDParam returns a path parameter previously validated by ValidateUUIDParams
+// Returns false if the middleware did not run for this parameter
+func UUIDParam(c *gin.Context, name string) (gocql.UUID, bool) {
+	value, exists := c.Get(uuidParamKeyPrefix + name)
+	if !exists {
+		return gocql.UUID{}, false
+	}
+
+	parsed, ok := value.(gocql.UUID)
+	return parsed, ok
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaTypeV2 selects the v2 representation on any versioned route
+const MediaTypeV2 = "application/vnd.acid.v2+json"
+
+// APIVersionKey is the gin context key holding the API version serving the request
+const APIVersionKey = "api_version"
+
+// HeaderAPIVersion reports the API version that produced the response
+const HeaderAPIVersion = "X-API-Version"
+
+// AcceptsV2 reports whether the Accept header lists MediaTypeV2
+func AcceptsV2(c *gin.Context) bool {
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == MediaTypeV2 {
+			return true
+		}
+	}
+	return false
+}
+
+// APIVersion pins the version for a route group, e.g. router.Group("/api/v2", APIVersion(2))
+func APIVersion(version int) gin.HandlerFunc {
+	header := strconv.Itoa(version)
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Header(HeaderAPIVersion, header)
+		c.Next()
+	}
+}
+
+// Negotiate serves a v1 route with its v2 handler when the client sends Accept: MediaTypeV2,
+// so clients can adopt the v2 representation before moving to /api/v2 URLs
+func Negotiate(v1, v2 gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+		if AcceptsV2(c) {
+			c.Set(APIVersionKey, 2)
+			c.Header(HeaderAPIVersion, "2")
+			v2(c)
+			return
+		}
+		v1(c)
+	}
+}
+
+// DeprecationConfig describes the sunset schedule of a deprecated API version
+type DeprecationConfig struct {
+	// Since is when the version was deprecated; zero disables the deprecation headers
+	Since time.Time
+
+	// Sunset is when the version stops being served; zero omits the Sunset header
+	Sunset time.Time
+
+	// Link points at migration docs or the successor version
+	Link string
+}
+
+// Deprecation marks responses from a deprecated route group with the Deprecation (RFC 9745),
+// Sunset (RFC 8594) and Link headers. Requests negotiated to v2 via Accept are not marked, and
+// routes deprecated on their own (Deprecations) keep their own headers.
+// DeprecatedCalls counts marked responses so remaining v1 traffic can be tracked
+func Deprecation(config DeprecationConfig) gin.HandlerFunc {
+	if config.Since.IsZero() {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	deprecation := "@" + strconv.FormatInt(config.Since.Unix(), 10)
+	var sunset string
+	if !config.Sunset.IsZero() {
+		sunset = config.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var link string
+	if config.Link != "" {
+		link = "<" + config.Link + `>; rel="deprecation"; type="text/html"`
+	}
+
+	return func(c *gin.Context) {
+		if AcceptsV2(c) {
+			c.Next()
+			return
+		}
+
+		deprecatedCalls.Add(1)
+		if c.Writer.Header().Get("Deprecation") != "" {
+			c.Next()
+			return
+		}
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		if link != "" {
+			c.Header("Link", link)
+		}
+		c.Next()
+	}
+}
+
+var deprecatedCalls atomic.Int64
+
+// DeprecatedCalls returns the number of requests served by deprecated routes since startup
+func DeprecatedCalls() int64 {
+	return deprecatedCalls.Load()
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidAttributes is returned for attributes rejected by the attribute schema
+var ErrInvalidAttributes = errors.New("invalid attributes")
+
+// Attributes are free-form user fields defined by an admin-managed JSON Schema rather than a
+// migration. Each value is stored as compact JSON text in a map<text, text> column and rendered
+// back as the JSON value it holds
+type Attributes map[string]string
+
+// MarshalJSON renders the attributes as an object of their JSON values; a value that isn't valid
+// JSON (e.g. written outside the API) is rendered as a string
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+	values := make(map[string]json.RawMessage, len(a))
+	for name, value := range a {
+		if json.Valid([]byte(value)) {
+			values[name] = json.RawMessage(value)
+			continue
+		}
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = quoted
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON accepts an object of any JSON values
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if values == nil {
+		*a = nil
+		return nil
+	}
+
+	attributes := make(Attributes, len(values))
+	for name, value := range values {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return fmt.Errorf("attribute %q: %w", name, err)
+		}
+		attributes[name] = compact.String()
+	}
+	*a = attributes
+	return nil
+}
+
+// Merge applies a JSON merge patch (RFC 7396) to a copy of the attributes: each patched value
+// replaces the stored one and null removes it
+func (a Attributes) Merge(patch map[string]json.RawMessage) (Attributes, error) {
+	merged := make(Attributes, len(a)+len(patch))
+	for name, value := range a {
+		merged[name] = value
+	}
+	for name, value := range patch {
+		if name == "" {
+			return nil, fmt.Errorf("%w: attribute names must not be empty", ErrInvalidAttributes)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, fmt.Errorf("%w: attribute %q: %v", ErrInvalidAttributes, name, err)
+		}
+		if compact.String() == "null" {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = compact.String()
+	}
+	return merged, nil
+}
+
+// StringValue returns the attribute name when it holds a JSON string, e.g. the "locale" of a user
+func (a Attributes) StringValue(name string) (string, bool) {
+	var value string
+	if err := json.Unmarshal([]byte(a[name]), &value); err != nil {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,20 @@
+package models
+
+// BatchGetUsersRequest lists the users to read in one call, at most 500
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=500"`
+}
+
+// BatchGetError is an ID a batchGet couldn't answer for; retrying it later may succeed unless
+// the error says the ID is invalid
+type BatchGetError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// BatchGetUsersV2 is the /api/v2 batchGet result: every requested ID is in exactly one list
+type BatchGetUsersV2 struct {
+	Users    []*UserV2       `json:"users"`
+	NotFound []string        `json:"not_found"`
+	Errors   []BatchGetError `json:"errors"`
+}
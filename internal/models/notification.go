@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Notification kinds
+const (
+	NotificationWelcomeEmail = "welcome_email"
+)
+
+// Notification delivery statuses
+const (
+	NotificationQueued   = "queued"
+	NotificationRetrying = "retrying"
+	NotificationSent     = "sent"
+	NotificationFailed   = "failed"
+	// NotificationSuppressed means the user opted out of the category
+	NotificationSuppressed = "suppressed"
+)
+
+// Notification records the delivery status of a message sent to a user
+type Notification struct {
+	UserID    gocql.UUID `db:"user_id" json:"user_id"`
+	ID        gocql.UUID `db:"id" json:"id"`
+	Kind      string     `db:"kind" json:"kind"`
+	Channel   string     `db:"channel" json:"channel"`
+	Status    string     `db:"status" json:"status"`
+	Attempts  int        `db:"attempts" json:"attempts"`
+	LastError string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func NewNotification(userID gocql.UUID, kind string, channel string) *Notification {
+	now := time.Now()
+	return &Notification{
+		UserID:    userID,
+		ID:        gocql.TimeUUID(),
+		Kind:      kind,
+		Channel:   channel,
+		Status:    NotificationQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhone is returned for a phone number that can't be read as an E.164 number
+var ErrInvalidPhone = errors.New("phone must be an international number, e.g. +14155550100")
+
+// NormalizePhone returns phone in E.164 form: "+", the country code and the subscriber number,
+// up to 15 digits. Spaces, dots, dashes and parentheses are dropped, and a leading international
+// prefix "00" is read as "+". Numbers without a country code are rejected rather than guessed.
+// Only the shape is checked; whether the country code and length match is the carrier's call
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		phone = phone[2:]
+	default:
+		return "", ErrInvalidPhone
+	}
+
+	digits := make([]byte, 0, len(phone))
+	for i := 0; i < len(phone); i++ {
+		switch c := phone[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	// Country codes don't start with 0, and the shortest numbers in use have 8 digits
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + string(digits), nil
+}
+
+// MaskPhone hides all but the country code's first digit and the last 4 digits, for responses and
+// logs that confirm where a code went without revealing the number
+func MaskPhone(phone string) string {
+	if len(phone) <= 6 {
+		return strings.Repeat("*", len(phone))
+	}
+	return phone[:2] + strings.Repeat("*", len(phone)-6) + phone[len(phone)-4:]
+}
@@ -0,0 +1,40 @@
+package models
+
+import "sync"
+
+// Pools for the structs allocated on every user read and write. A pooled value must not be used
+// after it is released: release it only once the response using it has been written
+
+var (
+	userPool        = sync.Pool{New: func() any { return new(User) }}
+	userRequestPool = sync.Pool{New: func() any { return new(UserRequest) }}
+)
+
+// AcquireUser returns a zeroed User from the pool
+func AcquireUser() *User {
+	return userPool.Get().(*User)
+}
+
+// ReleaseUser zeroes user and returns it to the pool. Maps it holds are dropped, not cleared,
+// since callers may have handed them on
+func ReleaseUser(user *User) {
+	if user == nil {
+		return
+	}
+	*user = User{}
+	userPool.Put(user)
+}
+
+// AcquireUserRequest returns a zeroed UserRequest from the pool
+func AcquireUserRequest() *UserRequest {
+	return userRequestPool.Get().(*UserRequest)
+}
+
+// ReleaseUserRequest zeroes req and returns it to the pool
+func ReleaseUserRequest(req *UserRequest) {
+	if req == nil {
+		return
+	}
+	*req = UserRequest{}
+	userRequestPool.Put(req)
+}
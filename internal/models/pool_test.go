@@ -0,0 +1,75 @@
+package models
+
+import (
+	"acid/internal/jsoncodec"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// pooledDecodeBudget is the most allocations decoding a cached user into a pooled User may make
+// with encoding/json: the strings and the preferences map. Other engines only report
+const pooledDecodeBudget = 5
+
+func cachedUser(tb testing.TB) []byte {
+	data, err := jsoncodec.Marshal(&User{
+		ID:          gocql.TimeUUID(),
+		Username:    "user_000001",
+		Email:       "user_000001@example.com",
+		CreatedAt:   time.Date(2025, 10, 22, 8, 15, 47, 123000000, time.UTC),
+		Preferences: map[string]bool{"email.marketing": false, "email.product_updates": true},
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestReleaseUserZeroes(t *testing.T) {
+	user := AcquireUser()
+	if err := jsoncodec.Unmarshal(cachedUser(t), user); err != nil {
+		t.Fatal(err)
+	}
+	ReleaseUser(user)
+	if user.Username != "" || user.Preferences != nil {
+		t.Errorf("released user not zeroed: %+v", user)
+	}
+}
+
+func TestPooledUserDecodeAllocations(t *testing.T) {
+	if jsoncodec.Engine != "encoding/json" {
+		t.Skipf("allocation budget is set for encoding/json, not %s", jsoncodec.Engine)
+	}
+	data := cachedUser(t)
+	if allocs := testing.AllocsPerRun(100, func() {
+		user := AcquireUser()
+		_ = jsoncodec.Unmarshal(data, user)
+		ReleaseUser(user)
+	}); allocs > pooledDecodeBudget {
+		t.Errorf("decoding into a pooled User allocates %v times, budget %d", allocs, pooledDecodeBudget)
+	}
+}
+
+// Sink keeps the compiler from optimizing the benchmarked work away
+var sinkUser *User
+
+func BenchmarkDecodeUser(b *testing.B) {
+	data := cachedUser(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		user := new(User)
+		_ = jsoncodec.Unmarshal(data, user)
+		sinkUser = user
+	}
+}
+
+func BenchmarkDecodePooledUser(b *testing.B) {
+	data := cachedUser(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		user := AcquireUser()
+		_ = jsoncodec.Unmarshal(data, user)
+		ReleaseUser(user)
+	}
+}
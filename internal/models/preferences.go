@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPreferences is returned for unknown or non-optional categories
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// Email notification categories users can opt in or out of
+const (
+	EmailCategoryWelcome        = "welcome"
+	EmailCategoryProductUpdates = "product_updates"
+	EmailCategoryMarketing      = "marketing"
+	EmailCategorySecurity       = "security"
+)
+
+// EmailCategories lists every known email category
+var EmailCategories = []string{
+	EmailCategoryWelcome,
+	EmailCategoryProductUpdates,
+	EmailCategoryMarketing,
+	EmailCategorySecurity,
+}
+
+// mandatoryEmailCategories cannot be disabled (transactional/security mail)
+var mandatoryEmailCategories = map[string]bool{
+	EmailCategorySecurity: true,
+}
+
+const emailPreferencePrefix = "email."
+
+// PreferencesRequest updates a subset of a user's notification preferences
+type PreferencesRequest struct {
+	Email map[string]bool `json:"email" binding:"required"`
+}
+
+// PreferencesResponse is the effective preference set, defaults included
+type PreferencesResponse struct {
+	Email map[string]bool `json:"email"`
+}
+
+// Validate rejects unknown categories and attempts to disable mandatory ones
+func (p *PreferencesRequest) Validate() error {
+	for category, enabled := range p.Email {
+		if !IsEmailCategory(category) {
+			return fmt.Errorf("%w: unknown email category %q", ErrInvalidPreferences, category)
+		}
+		if !enabled && mandatoryEmailCategories[category] {
+			return fmt.Errorf("%w: email category %q cannot be disabled", ErrInvalidPreferences, category)
+		}
+	}
+	return nil
+}
+
+// ToColumn converts the request to the preferences column representation
+func (p *PreferencesRequest) ToColumn() map[string]bool {
+	column := make(map[string]bool, len(p.Email))
+	for category, enabled := range p.Email {
+		column[emailPreferencePrefix+category] = enabled
+	}
+	return column
+}
+
+// OptionalEmailCategories lists the categories a user may opt out of
+func OptionalEmailCategories() []string {
+	categories := make([]string, 0, len(EmailCategories))
+	for _, category := range EmailCategories {
+		if !mandatoryEmailCategories[category] {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// IsEmailCategory reports whether a category is known
+func IsEmailCategory(category string) bool {
+	for _, known := range EmailCategories {
+		if known == category {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailAllowed reports whether the user accepts email of the given category
+// Categories are opt-out: anything not explicitly disabled is allowed
+func (u *User) EmailAllowed(category string) bool {
+	if mandatoryEmailCategories[category] {
+		return true
+	}
+	enabled, ok := u.Preferences[emailPreferencePrefix+category]
+	return !ok || enabled
+}
+
+// EffectivePreferences returns every category with its effective value
+func (u *User) EffectivePreferences() PreferencesResponse {
+	email := make(map[string]bool, len(EmailCategories))
+	for _, category := range EmailCategories {
+		email[category] = u.EmailAllowed(category)
+	}
+
+	// Preserve categories stored by newer versions we don't know about yet
+	for key, enabled := range u.Preferences {
+		if category, ok := strings.CutPrefix(key, emailPreferencePrefix); ok {
+			if _, known := email[category]; !known {
+				email[category] = enabled
+			}
+		}
+	}
+
+	return PreferencesResponse{Email: email}
+}
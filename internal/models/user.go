@@ -1,6 +1,8 @@
 package models
 
 import (
+	"acid/internal/ids"
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,15 +10,32 @@ import (
 )
 
 type User struct {
-	ID        gocql.UUID `db:"id"`
-	Username  string     `db:"username"`
-	Email     string     `db:"email"`
-	CreatedAt time.Time  `db:"created_at"`
+	ID          gocql.UUID      `db:"id"`
+	Username    string          `db:"username"`
+	Email       string          `db:"email"`
+	Phone       string          `db:"phone" json:",omitempty"` // E.164, see NormalizePhone
+	CreatedAt   time.Time       `db:"created_at"`
+	Preferences map[string]bool `db:"preferences"`
+	Attributes  Attributes      `db:"attributes" json:",omitempty"`
 }
 
+// ErrUnsupportedUserID is returned for a client-supplied ID that isn't a random (v4) or
+// time-ordered (v7) UUID
+var ErrUnsupportedUserID = errors.New("id must be a UUIDv4 or UUIDv7")
+
 type UserRequest struct {
+	// ID is optional: systems migrating their users keep their identifiers by supplying them,
+	// otherwise a TimeUUID is generated. See ParseClientUserID
+	ID string `json:"id,omitempty"`
+
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
+
+	// Phone is optional and normalized to E.164, see NormalizePhone
+	Phone string `json:"phone,omitempty"`
+
+	// Attributes are validated against the attribute schema when present
+	Attributes Attributes `json:"attributes,omitempty"`
 }
 
 func (u *UserRequest) Validate() error {
@@ -29,13 +48,32 @@ func (u *UserRequest) Validate() error {
 	return nil
 }
 
+// ParseClientUserID validates a client-supplied user ID. Only v4 and v7 UUIDs are accepted:
+// both are unlikely to collide with the TimeUUIDs generated here or with each other
+func ParseClientUserID(id string) (gocql.UUID, error) {
+	uuid, err := gocql.ParseUUID(id)
+	if err != nil {
+		return gocql.UUID{}, fmt.Errorf("%w: %v", ErrUnsupportedUserID, err)
+	}
+	if strategy := ids.Detect(uuid); strategy != ids.UUIDv4 && strategy != ids.UUIDv7 {
+		return gocql.UUID{}, ErrUnsupportedUserID
+	}
+	return uuid, nil
+}
+
+// NewUser builds a user without an ID: UserService.CreateUser assigns one from the configured
+// ID strategy
 func NewUser(username string, email string) (*User, error) {
-	uuid := gocql.TimeUUID()
+	return NewUserWithID(gocql.UUID{}, username, email), nil
+}
+
+// NewUserWithID builds a user with a client-supplied ID, see ParseClientUserID
+func NewUserWithID(uuid gocql.UUID, username string, email string) *User {
 	return &User{
 		ID:        uuid,
 		Username:  username,
 		Email:     email,
 		CreatedAt: time.Now(),
-	}, nil
+	}
 }
 
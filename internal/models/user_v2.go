@@ -0,0 +1,70 @@
+package models
+
+import (
+	"acid/internal/pagination"
+	"time"
+)
+
+// UserV2 is the /api/v2 representation of a user. Unlike v1, which serializes User directly,
+// field names are snake_case and stable independently of the storage model
+type UserV2 struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Attributes Attributes `json:"attributes,omitempty"`
+}
+
+func NewUserV2(user *User) *UserV2 {
+	return &UserV2{
+		ID:        user.ID.String(),
+		Username:  user.Username,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		CreatedAt: user.CreatedAt,
+
+		Attributes: user.Attributes,
+	}
+}
+
+// UserV2Fields are the field names accepted by ?fields= on v2 user reads
+var UserV2Fields = []string{"id", "username", "email", "phone", "created_at", "attributes"}
+
+// Select returns only the named fields, keyed by their JSON names, for partial responses
+func (u *UserV2) Select(fields []string) map[string]any {
+	selected := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected[field] = u.ID
+		case "username":
+			selected[field] = u.Username
+		case "email":
+			selected[field] = u.Email
+		case "phone":
+			selected[field] = u.Phone
+		case "created_at":
+			selected[field] = u.CreatedAt
+		case "attributes":
+			selected[field] = u.Attributes
+		}
+	}
+	return selected
+}
+
+// Envelope wraps every /api/v2 success response; errors are problem+json documents instead
+type Envelope struct {
+	Data any   `json:"data"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// Meta carries response metadata that isn't part of the resource
+type Meta struct {
+	RequestID string `json:"request_id,omitempty"`
+	Source    string `json:"source,omitempty"` // Cache tier or database the resource was read from
+
+	// Pagination is set on list responses; its fields are inlined into meta
+	*pagination.Meta
+}
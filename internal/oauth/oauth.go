@@ -0,0 +1,294 @@
+// Package oauth signs users in with external identity providers (Google over OpenID Connect,
+// GitHub over OAuth2) using the authorization code flow with PKCE, and stores the provider
+// identities linked to users
+package oauth
+
+import (
+	"acid/internal/httpclient"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider names, as used in routes and the identities table
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// ErrUnknownProvider is returned for a provider that isn't configured
+var ErrUnknownProvider = errors.New("unknown sign-in provider")
+
+// ErrExchangeFailed is returned when the provider refused the authorization code or the profile
+// couldn't be read
+var ErrExchangeFailed = errors.New("sign-in with the provider failed")
+
+// ProviderConfig holds the client registration and endpoints of one provider
+type ProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the callback registered with the provider, e.g.
+	// https://api.example.com/auth/google/callback
+	RedirectURL string
+
+	Scopes []string
+
+	// AuthURL, TokenURL and UserInfoURL are the provider's endpoints, overridden to point at a mock
+	// in development. EmailsURL lists a GitHub user's addresses, which the profile may hide
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	EmailsURL   string
+
+	// Timeout bounds each call to the provider
+	Timeout time.Duration
+}
+
+// DefaultGoogleConfig returns Google's OpenID Connect endpoints and scopes
+func DefaultGoogleConfig() *ProviderConfig {
+	return &ProviderConfig{
+		Scopes:      []string{"openid", "email", "profile"},
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+		Timeout:     10 * time.Second,
+	}
+}
+
+// DefaultGitHubConfig returns GitHub's OAuth endpoints and the scopes needed to read the user's
+// verified emails
+func DefaultGitHubConfig() *ProviderConfig {
+	return &ProviderConfig{
+		Scopes:      []string{"read:user", "user:email"},
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Profile is who the provider says signed in
+type Profile struct {
+	// Subject is the provider's stable ID of the user; emails and logins can change
+	Subject       string
+	Email         string
+	EmailVerified bool
+
+	// Username is the login at the provider (GitHub) or the local part of the email (Google)
+	Username string
+	Name     string
+}
+
+// Provider runs the authorization code flow with one provider
+type Provider struct {
+	name    string
+	config  *ProviderConfig
+	client  *httpclient.Client
+	profile func(ctx context.Context, p *Provider, accessToken string) (*Profile, error)
+}
+
+// NewProvider creates the provider name, google or github
+func NewProvider(name string, config *ProviderConfig) (*Provider, error) {
+	p := &Provider{name: name, config: config}
+	switch name {
+	case ProviderGoogle:
+		p.profile = googleProfile
+	case ProviderGitHub:
+		p.profile = githubProfile
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	clientConfig := httpclient.DefaultConfig("oauth-" + name)
+	clientConfig.Timeout = config.Timeout
+	p.client = httpclient.New(clientConfig)
+	return p, nil
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL returns where to send the user to sign in: state comes back on the callback, and
+// challenge is the S256 hash of the PKCE verifier Exchange is given
+func (p *Provider) AuthCodeURL(state, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.config.AuthURL + separator + query.Encode()
+}
+
+// Exchange trades the authorization code of a callback for the profile of the user who signed
+// in. The code is single use, so the token request is not retried
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Profile, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers form-encoded unless asked for JSON
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token request: %w", ErrExchangeFailed, err)
+	}
+	// GitHub reports a refused code with 200 and an error field
+	if token.Error != "" || token.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token request answered %d: %s %s", ErrExchangeFailed, status, token.Error, token.ErrorDescription)
+	}
+
+	profile, err := p.profile(ctx, p, token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExchangeFailed, err)
+	}
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("%w: profile has no subject", ErrExchangeFailed)
+	}
+	return profile, nil
+}
+
+// get reads a JSON resource of the provider's API with the user's access token
+func (p *Provider) get(ctx context.Context, endpoint, accessToken string, dest any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	status, err := p.do(req, dest)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("%s answered %d", endpoint, status)
+	}
+	return nil
+}
+
+// do sends req and decodes a JSON response body into dest; a body that isn't JSON is only an
+// error for a successful status
+func (p *Provider) do(req *http.Request, dest any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest)
+	if err != nil && resp.StatusCode < 300 {
+		return resp.StatusCode, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// googleProfile reads the OpenID Connect userinfo of a Google account
+func googleProfile(ctx context.Context, p *Provider, accessToken string) (*Profile, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := p.get(ctx, p.config.UserInfoURL, accessToken, &info); err != nil {
+		return nil, err
+	}
+	username, _, _ := strings.Cut(info.Email, "@")
+	return &Profile{
+		Subject:       info.Sub,
+		Email:         strings.ToLower(info.Email),
+		EmailVerified: info.EmailVerified,
+		Username:      username,
+		Name:          info.Name,
+	}, nil
+}
+
+// githubProfile reads a GitHub user and its primary verified email, which /user leaves out when
+// the user keeps it private
+func githubProfile(ctx context.Context, p *Provider, accessToken string) (*Profile, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, p.config.UserInfoURL, accessToken, &user); err != nil {
+		return nil, err
+	}
+	profile := &Profile{Username: user.Login, Name: user.Name}
+	if user.ID != 0 {
+		profile.Subject = fmt.Sprint(user.ID)
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, p.config.EmailsURL, accessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			profile.Email = strings.ToLower(email.Email)
+			profile.EmailVerified = true
+		}
+	}
+	return profile, nil
+}
+
+// NewState returns a random state parameter, tying a callback to the sign-in that started it
+func NewState() (string, error) {
+	return randomString(24)
+}
+
+// NewVerifier returns a random PKCE code verifier (RFC 7636: 43 to 128 characters)
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// Challenge returns the S256 code challenge of a verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// IdentitiesTable maps a provider identity to the user it signs in as
+var IdentitiesTable = table.New(table.Metadata{
+	Name:    "identities",
+	Columns: []string{"provider", "subject", "user_id", "email", "linked_at"},
+	PartKey: []string{"provider", "subject"},
+	SortKey: []string{},
+})
+
+// IdentitiesByUserTable lists the identities linked to a user
+var IdentitiesByUserTable = table.New(table.Metadata{
+	Name:    "identities_by_user",
+	Columns: []string{"user_id", "provider", "subject", "email", "linked_at"},
+	PartKey: []string{"user_id"},
+	SortKey: []string{"provider", "subject"},
+})
+
+// ColumnTypes are the CQL types Identity is marshaled to, in both tables
+var ColumnTypes = map[string]string{
+	"provider":  "text",
+	"subject":   "text",
+	"user_id":   "uuid",
+	"email":     "text",
+	"linked_at": "timestamp",
+}
+
+// ErrIdentityLinked is returned by Link when the identity belongs to another user
+var ErrIdentityLinked = errors.New("identity is linked to another user")
+
+// Identity is a provider account linked to a user. Email is the one the provider reported when
+// it was linked, kept for support; the user's own email may differ
+type Identity struct {
+	Provider string     `db:"provider" json:"provider"`
+	Subject  string     `db:"subject" json:"subject"`
+	UserID   gocql.UUID `db:"user_id" json:"user_id"`
+	Email    string     `db:"email" json:"email,omitempty"`
+	LinkedAt time.Time  `db:"linked_at" json:"linked_at"`
+}
+
+// Repository stores linked identities in ScyllaDB
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Get returns the identity of a provider account, or nil when it isn't linked
+func (r *Repository) Get(ctx context.Context, provider, subject string) (*Identity, error) {
+	var identity Identity
+	err := IdentitiesTable.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{
+		"provider": provider,
+		"subject":  subject,
+	}).GetRelease(&identity)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// Link links an identity to its user with IF NOT EXISTS, failing with ErrIdentityLinked when
+// another user has it. Linking an identity to the user it is linked to already succeeds
+func (r *Repository) Link(ctx context.Context, identity *Identity) error {
+	stmt, names := IdentitiesTable.InsertBuilder().Unique().ToCql()
+	existing := make(map[string]interface{})
+	applied, err := r.session.ContextQuery(ctx, stmt, names).BindStruct(identity).MapScanCAS(existing)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	if owner, _ := existing["user_id"].(gocql.UUID); !applied && owner != identity.UserID {
+		return ErrIdentityLinked
+	}
+
+	// A lost write only hides the identity from ListByUser; sign-ins read identities
+	if err := IdentitiesByUserTable.InsertQueryContext(ctx, r.session).BindStruct(identity).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to list identity under its user: %w", err)
+	}
+	return nil
+}
+
+// Unlink removes an identity from its user; the identity row is only deleted while it still
+// belongs to that user
+func (r *Repository) Unlink(ctx context.Context, identity *Identity) error {
+	stmt, names := qb.Delete(IdentitiesTable.Name()).
+		Where(qb.Eq("provider"), qb.Eq("subject")).
+		If(qb.Eq("user_id")).
+		ToCql()
+	if _, err := r.session.ContextQuery(ctx, stmt, names).BindStruct(identity).MapScanCAS(make(map[string]interface{})); err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if err := IdentitiesByUserTable.DeleteQueryContext(ctx, r.session).BindStruct(identity).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to unlist identity: %w", err)
+	}
+	return nil
+}
+
+// ListByUser returns the identities linked to a user
+func (r *Repository) ListByUser(ctx context.Context, userID gocql.UUID) ([]Identity, error) {
+	stmt, names := qb.Select(IdentitiesByUserTable.Name()).Where(qb.Eq("user_id")).ToCql()
+	var identities []Identity
+	err := r.session.ContextQuery(ctx, stmt, names).BindMap(map[string]interface{}{
+		"user_id": userID,
+	}).SelectRelease(&identities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
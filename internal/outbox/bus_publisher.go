@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"acid/internal/events"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// BusPublisher hands relayed events to the event bus, which publishes them to its backend (the
+// configured sink) and, once it accepts them, to this instance's handlers and subscribers
+type BusPublisher struct {
+	bus *events.Bus
+}
+
+func NewBusPublisher(bus *events.Bus) *BusPublisher {
+	return &BusPublisher{bus: bus}
+}
+
+func (p *BusPublisher) Publish(ctx context.Context, event *Event) error {
+	return p.bus.Publish(ctx, events.Event{
+		ID:          event.ID.String(),
+		Type:        event.EventType,
+		AggregateID: event.AggregateID,
+		Payload:     json.RawMessage(event.Payload),
+		OccurredAt:  event.CreatedAt,
+	})
+}
+
+func (p *BusPublisher) Close() error {
+	return p.bus.Close()
+}
+
+// SinkBackend makes an outbox sink (log or NATS) the bus's durable backend
+type SinkBackend struct {
+	sink Publisher
+}
+
+func NewSinkBackend(sink Publisher) *SinkBackend {
+	return &SinkBackend{sink: sink}
+}
+
+func (b *SinkBackend) Publish(ctx context.Context, event events.Event) error {
+	// Sinks deduplicate by event ID, so it must be the outbox ID the event was relayed with
+	id, err := gocql.ParseUUID(event.ID)
+	if err != nil {
+		return fmt.Errorf("event ID %q is not an outbox ID: %w", event.ID, err)
+	}
+	return b.sink.Publish(ctx, &Event{
+		Shard:       shardFor(event.AggregateID),
+		ID:          id,
+		EventType:   event.Type,
+		AggregateID: event.AggregateID,
+		Payload:     string(event.Payload),
+		CreatedAt:   event.OccurredAt,
+	})
+}
+
+func (b *SinkBackend) Close() error {
+	return b.sink.Close()
+}
@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// NumShards is the number of outbox partitions; events are spread by aggregate ID
+const NumShards = 16
+
+// Event types emitted by the user service
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserLoggedIn = "user.logged_in"
+	EventUserMerged   = "user.merged"
+)
+
+// Event is a pending domain event stored in the outbox (or the DLQ) until published
+type Event struct {
+	Shard       int        `db:"shard" json:"shard"`
+	ID          gocql.UUID `db:"id" json:"id"`
+	EventType   string     `db:"event_type" json:"event_type"`
+	AggregateID string     `db:"aggregate_id" json:"aggregate_id"`
+	Payload     string     `db:"payload" json:"payload"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	LastError   string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	FailedAt    time.Time  `db:"failed_at" json:"failed_at,omitempty"`
+}
+
+// NewEvent builds an outbox event with a JSON-encoded payload
+func NewEvent(eventType, aggregateID string, payload any) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	return &Event{
+		Shard:       shardFor(aggregateID),
+		ID:          gocql.TimeUUID(),
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		Payload:     string(data),
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// shardFor keeps all events of one aggregate in the same partition (and thus ordered)
+func shardFor(aggregateID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(aggregateID))
+	return int(h.Sum32() % NumShards)
+}
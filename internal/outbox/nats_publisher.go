@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// NATSConfig holds NATS JetStream publisher configuration
+type NATSConfig struct {
+	// URL is the NATS server URL (comma-separated for a cluster)
+	URL string
+
+	// Stream is the JetStream stream capturing all event subjects
+	Stream string
+
+	// SubjectPrefix is prepended to the event type, e.g. "acid.events" -> "acid.events.user.created"
+	SubjectPrefix string
+
+	// DuplicateWindow is how long JetStream remembers message IDs for deduplication
+	DuplicateWindow time.Duration
+
+	// ConnectTimeout bounds the initial connection and stream setup
+	ConnectTimeout time.Duration
+
+	// Encoding is how payloads go on the wire: EncodingJSON as stored, or EncodingProtobuf using
+	// the messages of proto/events/events.proto
+	Encoding string
+}
+
+// DefaultNATSConfig returns sensible production defaults
+func DefaultNATSConfig() *NATSConfig {
+	return &NATSConfig{
+		URL:             nats.DefaultURL,
+		Stream:          "ACID_EVENTS",
+		SubjectPrefix:   "acid.events",
+		DuplicateWindow: 2 * time.Minute,
+		ConnectTimeout:  5 * time.Second,
+		Encoding:        EncodingJSON,
+	}
+}
+
+// NATSPublisher publishes outbox events to NATS JetStream, one subject per event type
+// The outbox event ID is used as the message ID so relay retries are deduplicated by the server
+type NATSPublisher struct {
+	conn     *nats.Conn
+	js       jetstream.JetStream
+	config   *NATSConfig
+	registry *SchemaRegistry
+	logger   *zap.Logger
+}
+
+// NewNATSPublisher connects to NATS and ensures the event stream exists
+func NewNATSPublisher(config *NATSConfig, logger *zap.Logger) (*NATSPublisher, error) {
+	if config == nil {
+		config = DefaultNATSConfig()
+	}
+	if config.Encoding != EncodingJSON && config.Encoding != EncodingProtobuf {
+		return nil, fmt.Errorf("unknown event encoding %q (expected %q or %q)", config.Encoding, EncodingJSON, EncodingProtobuf)
+	}
+
+	conn, err := nats.Connect(config.URL,
+		nats.Name("acid-outbox-relay"),
+		nats.Timeout(config.ConnectTimeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", config.URL, err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       config.Stream,
+		Subjects:   []string{config.SubjectPrefix + ".>"},
+		Duplicates: config.DuplicateWindow,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ensure JetStream stream %s: %w", config.Stream, err)
+	}
+
+	logger.Info("NATS JetStream publisher initialized",
+		zap.String("url", config.URL),
+		zap.String("stream", config.Stream),
+		zap.String("subject_prefix", config.SubjectPrefix),
+		zap.String("encoding", config.Encoding))
+
+	return &NATSPublisher{
+		conn:   conn,
+		js:     js,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// Subject returns the subject an event type is published on
+func (p *NATSPublisher) Subject(eventType string) string {
+	return p.config.SubjectPrefix + "." + eventType
+}
+
+// SetSchemaRegistry frames protobuf payloads in the Confluent wire format, registering the schema
+// under "<subject>-value" first. Must be called during startup
+func (p *NATSPublisher) SetSchemaRegistry(registry *SchemaRegistry) {
+	p.registry = registry
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event *Event) error {
+	msg := &nats.Msg{
+		Subject: p.Subject(event.EventType),
+		Header:  nats.Header{},
+	}
+	msg.Header.Set("Event-Type", event.EventType)
+	msg.Header.Set("Aggregate-Id", event.AggregateID)
+	if err := p.encode(ctx, msg, event); err != nil {
+		return err
+	}
+
+	ack, err := p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID.String()))
+	if err != nil {
+		return fmt.Errorf("jetstream publish failed: %w", err)
+	}
+
+	if ack.Duplicate {
+		p.logger.Debug("Duplicate outbox event ignored by JetStream",
+			zap.String("event_id", event.ID.String()))
+	}
+
+	return nil
+}
+
+// encode sets the payload of msg in the configured encoding. Event types without a protobuf
+// schema stay JSON; Content-Type tells consumers which they got
+func (p *NATSPublisher) encode(ctx context.Context, msg *nats.Msg, event *Event) error {
+	if p.config.Encoding != EncodingProtobuf {
+		msg.Data = []byte(event.Payload)
+		msg.Header.Set("Content-Type", "application/json")
+		return nil
+	}
+
+	message, err := ProtoPayload(event)
+	if errors.Is(err, ErrNoSchema) {
+		msg.Data = []byte(event.Payload)
+		msg.Header.Set("Content-Type", "application/json")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	msg.Header.Set("Content-Type", "application/x-protobuf")
+	msg.Header.Set("Proto-Message", string(message.ProtoReflect().Descriptor().FullName()))
+
+	if p.registry == nil {
+		msg.Data, err = proto.Marshal(message)
+		return err
+	}
+	data, schemaID, err := p.registry.Frame(ctx, msg.Subject+"-value", message)
+	if err != nil {
+		return err
+	}
+	msg.Data = data
+	msg.Header.Set("Schema-Id", strconv.Itoa(schemaID))
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Publisher delivers outbox events to an event sink
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+	Close() error
+}
+
+// LogPublisher writes events to the application log; used when no broker is configured
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event *Event) error {
+	p.logger.Info("Outbox event published",
+		zap.String("event_id", event.ID.String()),
+		zap.String("event_type", event.EventType),
+		zap.String("aggregate_id", event.AggregateID))
+	return nil
+}
+
+func (p *LogPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,315 @@
+package outbox
+
+import (
+	"acid/internal/safego"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// RelayConfig holds outbox relay configuration
+type RelayConfig struct {
+	// PollInterval is how often the outbox is scanned for pending events
+	PollInterval time.Duration
+
+	// BatchSize is the max number of events fetched per shard per poll
+	BatchSize uint
+
+	// MaxAttempts is the number of failed publishes before an event is dead-lettered
+	MaxAttempts int
+
+	// PublishTimeout bounds a single publish call
+	PublishTimeout time.Duration
+}
+
+// DefaultRelayConfig returns sensible production defaults
+func DefaultRelayConfig() *RelayConfig {
+	return &RelayConfig{
+		PollInterval:   1 * time.Second,
+		BatchSize:      100,
+		MaxAttempts:    5,
+		PublishTimeout: 5 * time.Second,
+	}
+}
+
+// RelayMetrics tracks relay throughput and backlog for observability
+type RelayMetrics struct {
+	Published    atomic.Int64
+	Failed       atomic.Int64
+	DeadLettered atomic.Int64
+	Replayed     atomic.Int64
+	LagMillis    atomic.Int64 // Age of the oldest pending event
+	DLQDepth     atomic.Int64
+}
+
+// Relay publishes outbox events and dead-letters the ones that keep failing
+// Delivery is at-least-once: consumers must deduplicate by event ID
+type Relay struct {
+	repo      *Repository
+	publisher Publisher
+	config    *RelayConfig
+	logger    *zap.Logger
+	metrics   *RelayMetrics
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewRelay(repo *Repository, publisher Publisher, config *RelayConfig, logger *zap.Logger) *Relay {
+	if config == nil {
+		config = DefaultRelayConfig()
+	}
+
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		config:    config,
+		logger:    logger,
+		metrics:   &RelayMetrics{},
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start runs the relay loop in the background until Stop is called
+func (r *Relay) Start() {
+	r.refreshDLQDepth()
+
+	r.wg.Add(1)
+	safego.Go("outbox.relay", func() {
+		ticker := time.NewTicker(r.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.poll()
+			}
+		}
+	}, safego.Restart(r.stop), safego.OnExit(r.wg.Done))
+
+	r.logger.Info("Outbox relay started",
+		zap.Duration("poll_interval", r.config.PollInterval),
+		zap.Int("max_attempts", r.config.MaxAttempts))
+}
+
+// Stop waits for the current poll to finish and stops the relay
+func (r *Relay) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+	r.logger.Info("Outbox relay stopped")
+}
+
+// poll drains one batch from every shard
+func (r *Relay) poll() {
+	var oldest time.Time
+
+	for shard := 0; shard < NumShards; shard++ {
+		events, err := r.repo.FetchPending(shard, r.config.BatchSize)
+		if err != nil {
+			r.logger.Error("Failed to fetch pending outbox events", zap.Int("shard", shard), zap.Error(err))
+			continue
+		}
+
+		if len(events) > 0 && (oldest.IsZero() || events[0].CreatedAt.Before(oldest)) {
+			oldest = events[0].CreatedAt
+		}
+
+		for i := range events {
+			r.process(&events[i])
+		}
+	}
+
+	if oldest.IsZero() {
+		r.metrics.LagMillis.Store(0)
+	} else {
+		r.metrics.LagMillis.Store(time.Since(oldest).Milliseconds())
+	}
+}
+
+// process publishes a single event, recording failures and dead-lettering when exhausted
+func (r *Relay) process(event *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.PublishTimeout)
+	defer cancel()
+
+	err := r.publisher.Publish(ctx, event)
+	if err == nil {
+		r.metrics.Published.Add(1)
+		if delErr := r.repo.Delete(event); delErr != nil {
+			r.logger.Error("Failed to delete published outbox event",
+				zap.String("event_id", event.ID.String()), zap.Error(delErr))
+		}
+		return
+	}
+
+	r.metrics.Failed.Add(1)
+	event.Attempts++
+	event.LastError = err.Error()
+
+	if event.Attempts >= r.config.MaxAttempts {
+		if dlqErr := r.repo.MoveToDLQ(event); dlqErr != nil {
+			r.logger.Error("Failed to move outbox event to DLQ",
+				zap.String("event_id", event.ID.String()), zap.Error(dlqErr))
+			return
+		}
+		r.metrics.DeadLettered.Add(1)
+		r.metrics.DLQDepth.Add(1)
+		r.logger.Warn("Outbox event dead-lettered",
+			zap.String("event_id", event.ID.String()),
+			zap.String("event_type", event.EventType),
+			zap.Int("attempts", event.Attempts),
+			zap.Error(err))
+		return
+	}
+
+	if recErr := r.repo.RecordFailure(event); recErr != nil {
+		r.logger.Error("Failed to record outbox publish failure",
+			zap.String("event_id", event.ID.String()), zap.Error(recErr))
+	}
+	r.logger.Warn("Outbox publish failed, will retry",
+		zap.String("event_id", event.ID.String()),
+		zap.Int("attempt", event.Attempts),
+		zap.Error(err))
+}
+
+// ListDLQ returns up to limit dead-lettered events across all shards
+func (r *Relay) ListDLQ(limit int) ([]Event, error) {
+	events := make([]Event, 0)
+
+	for shard := 0; shard < NumShards && len(events) < limit; shard++ {
+		shardEvents, err := r.repo.FetchDLQ(shard, uint(limit-len(events)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list DLQ shard %d: %w", shard, err)
+		}
+		events = append(events, shardEvents...)
+	}
+
+	return events, nil
+}
+
+// DLQPosition is where a DLQ listing continues: after event After of shard Shard, or at the
+// start of Shard when After is empty
+type DLQPosition struct {
+	Shard int        `json:"s"`
+	After gocql.UUID `json:"a"`
+}
+
+// ListDLQPage returns up to limit dead-lettered events from position on, walking the shards in
+// order, and the position of the next page (nil once every shard is exhausted)
+func (r *Relay) ListDLQPage(from DLQPosition, limit int) ([]Event, *DLQPosition, error) {
+	events := make([]Event, 0, limit)
+
+	position := from
+	for position.Shard < NumShards {
+		// One extra row tells whether the shard continues after this page
+		want := uint(limit - len(events) + 1)
+		var shardEvents []Event
+		var err error
+		if position.After.IsEmpty() {
+			shardEvents, err = r.repo.FetchDLQ(position.Shard, want)
+		} else {
+			shardEvents, err = r.repo.FetchDLQAfter(position.Shard, position.After, want)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list DLQ shard %d: %w", position.Shard, err)
+		}
+
+		if len(events)+len(shardEvents) > limit {
+			events = append(events, shardEvents[:limit-len(events)]...)
+			return events, &DLQPosition{Shard: position.Shard, After: events[len(events)-1].ID}, nil
+		}
+		events = append(events, shardEvents...)
+		position = DLQPosition{Shard: position.Shard + 1}
+		if len(events) == limit && position.Shard < NumShards {
+			return events, &position, nil
+		}
+	}
+
+	return events, nil, nil
+}
+
+// DLQDepth returns the last counted number of dead-lettered events
+func (r *Relay) DLQDepth() int64 {
+	return r.metrics.DLQDepth.Load()
+}
+
+// Replay re-queues the given dead-lettered events, or up to limit events when ids is empty
+// Returns the number of events moved back to the outbox
+func (r *Relay) Replay(ids []gocql.UUID, limit int) (int, error) {
+	var events []Event
+
+	if len(ids) == 0 {
+		all, err := r.ListDLQ(limit)
+		if err != nil {
+			return 0, err
+		}
+		events = all
+	} else {
+		for _, id := range ids {
+			event, err := r.findDLQ(id)
+			if err != nil {
+				return 0, err
+			}
+			events = append(events, *event)
+		}
+	}
+
+	replayed := 0
+	for i := range events {
+		if err := r.repo.Requeue(&events[i]); err != nil {
+			r.refreshDLQDepth()
+			return replayed, fmt.Errorf("failed to requeue event %s: %w", events[i].ID, err)
+		}
+		replayed++
+	}
+
+	r.metrics.Replayed.Add(int64(replayed))
+	r.refreshDLQDepth()
+
+	r.logger.Info("Outbox DLQ replayed", zap.Int("events", replayed))
+	return replayed, nil
+}
+
+// findDLQ locates a dead-lettered event by ID; the shard is unknown to callers
+func (r *Relay) findDLQ(id gocql.UUID) (*Event, error) {
+	for shard := 0; shard < NumShards; shard++ {
+		if event, err := r.repo.GetDLQ(shard, id); err == nil {
+			return event, nil
+		}
+	}
+	return nil, fmt.Errorf("event %s not found in DLQ", id)
+}
+
+// refreshDLQDepth recounts the DLQ, which may be modified by other instances
+func (r *Relay) refreshDLQDepth() {
+	var total int64
+	for shard := 0; shard < NumShards; shard++ {
+		count, err := r.repo.CountDLQ(shard)
+		if err != nil {
+			r.logger.Warn("Failed to count DLQ shard", zap.Int("shard", shard), zap.Error(err))
+			return
+		}
+		total += count
+	}
+	r.metrics.DLQDepth.Store(total)
+}
+
+// GetMetrics returns current relay metrics
+func (r *Relay) GetMetrics() map[string]int64 {
+	r.refreshDLQDepth()
+
+	return map[string]int64{
+		"published":     r.metrics.Published.Load(),
+		"failed":        r.metrics.Failed.Load(),
+		"dead_lettered": r.metrics.DeadLettered.Load(),
+		"replayed":      r.metrics.Replayed.Load(),
+		"lag_ms":        r.metrics.LagMillis.Load(),
+		"dlq_depth":     r.metrics.DLQDepth.Load(),
+	}
+}
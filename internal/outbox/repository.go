@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"acid/internal/budget"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+var outboxColumns = []string{
+	"shard", "id", "event_type", "aggregate_id", "payload",
+	"attempts", "last_error", "created_at", "failed_at",
+}
+
+// ColumnTypes are the CQL types Event is marshaled to, in both the outbox and the DLQ table
+var ColumnTypes = map[string]string{
+	"shard":        "int",
+	"id":           "timeuuid",
+	"event_type":   "text",
+	"aggregate_id": "text",
+	"payload":      "text",
+	"attempts":     "int",
+	"last_error":   "text",
+	"created_at":   "timestamp",
+	"failed_at":    "timestamp",
+}
+
+var OutboxTable = table.New(table.Metadata{
+	Name:    "outbox",
+	Columns: outboxColumns,
+	PartKey: []string{"shard"},
+	SortKey: []string{"id"},
+})
+
+var DLQTable = table.New(table.Metadata{
+	Name:    "outbox_dlq",
+	Columns: outboxColumns,
+	PartKey: []string{"shard"},
+	SortKey: []string{"id"},
+})
+
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Enqueue stores a new event in the outbox. Like other writes it isn't abandoned when ctx is
+// canceled, but ends at the deadline of the caller's latency budget
+func (r *Repository) Enqueue(ctx context.Context, event *Event) error {
+	ctx, cancel := budget.Detach(ctx)
+	defer cancel()
+	return r.session.Query(OutboxTable.Insert()).WithContext(ctx).BindStruct(event).ExecRelease()
+}
+
+// FetchPending returns the oldest pending events of a shard
+func (r *Repository) FetchPending(shard int, limit uint) ([]Event, error) {
+	return r.selectShard(OutboxTable, shard, limit)
+}
+
+// FetchDLQ returns dead-lettered events of a shard
+func (r *Repository) FetchDLQ(shard int, limit uint) ([]Event, error) {
+	return r.selectShard(DLQTable, shard, limit)
+}
+
+// FetchDLQAfter returns dead-lettered events of a shard with IDs after after, in ID order
+func (r *Repository) FetchDLQAfter(shard int, after gocql.UUID, limit uint) ([]Event, error) {
+	stmt, names := qb.Select(DLQTable.Name()).
+		Columns(outboxColumns...).
+		Where(qb.Eq("shard"), qb.Gt("id")).
+		Limit(limit).
+		ToCql()
+
+	var events []Event
+	q := r.session.Query(stmt, names).BindMap(map[string]interface{}{"shard": shard, "id": after})
+	if err := q.SelectRelease(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetDLQ fetches a single dead-lettered event by ID
+func (r *Repository) GetDLQ(shard int, id gocql.UUID) (*Event, error) {
+	var event Event
+	q := r.session.Query(DLQTable.Get()).BindMap(map[string]interface{}{
+		"shard": shard,
+		"id":    id,
+	})
+	if err := q.GetRelease(&event); err != nil {
+		return nil, fmt.Errorf("dead-lettered event not found: %w", err)
+	}
+	return &event, nil
+}
+
+// Delete removes a published event from the outbox
+func (r *Repository) Delete(event *Event) error {
+	return r.session.Query(OutboxTable.Delete()).BindStruct(event).ExecRelease()
+}
+
+// RecordFailure stores the attempt count and last error of a failed publish
+func (r *Repository) RecordFailure(event *Event) error {
+	stmt, names := OutboxTable.Update("attempts", "last_error")
+	return r.session.Query(stmt, names).BindStruct(event).ExecRelease()
+}
+
+// MoveToDLQ atomically moves an event from the outbox to the dead-letter table
+func (r *Repository) MoveToDLQ(event *Event) error {
+	event.FailedAt = time.Now()
+	return r.move(event, DLQTable, OutboxTable)
+}
+
+// Requeue moves a dead-lettered event back to the outbox with a fresh attempt budget
+func (r *Repository) Requeue(event *Event) error {
+	event.Attempts = 0
+	event.FailedAt = time.Time{}
+	return r.move(event, OutboxTable, DLQTable)
+}
+
+// CountDLQ counts dead-lettered events in a shard
+func (r *Repository) CountDLQ(shard int) (int64, error) {
+	stmt, names := qb.Select(DLQTable.Name()).CountAll().Where(qb.Eq("shard")).ToCql()
+
+	var count int64
+	q := r.session.Query(stmt, names).BindMap(map[string]interface{}{"shard": shard})
+	if err := q.GetRelease(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *Repository) selectShard(t *table.Table, shard int, limit uint) ([]Event, error) {
+	stmt, names := qb.Select(t.Name()).
+		Columns(outboxColumns...).
+		Where(qb.Eq("shard")).
+		Limit(limit).
+		ToCql()
+
+	var events []Event
+	q := r.session.Query(stmt, names).BindMap(map[string]interface{}{"shard": shard})
+	if err := q.SelectRelease(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// move inserts into one table and deletes from the other in a single logged batch
+func (r *Repository) move(event *Event, to, from *table.Table) error {
+	insertStmt, insertNames := to.Insert()
+	deleteStmt, deleteNames := from.Delete()
+
+	batch := r.session.Batch(gocql.LoggedBatch)
+	if err := batch.BindStruct(r.session.Query(insertStmt, insertNames), event); err != nil {
+		return err
+	}
+	if err := batch.BindStruct(r.session.Query(deleteStmt, deleteNames), event); err != nil {
+		return err
+	}
+
+	return r.session.ExecuteBatch(batch)
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	eventspb "acid/proto/events"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event encodings a publisher can put on the wire. Events are stored as JSON either way
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+)
+
+// ErrNoSchema is returned by ProtoPayload for an event type without a protobuf schema
+var ErrNoSchema = errors.New("event type has no protobuf schema")
+
+// payloadSchemas maps each event type to its message in proto/events/events.proto. An event type
+// added here needs a message there first
+var payloadSchemas = map[string]func() proto.Message{
+	EventUserCreated:  func() proto.Message { return &eventspb.UserCreated{} },
+	EventUserUpdated:  func() proto.Message { return &eventspb.UserUpdated{} },
+	EventUserLoggedIn: func() proto.Message { return &eventspb.UserLoggedIn{} },
+	EventUserMerged:   func() proto.Message { return &eventspb.UserMerged{} },
+}
+
+// ProtoPayload converts the JSON payload of an event to its protobuf message. Fields the schema
+// doesn't know are dropped, so a payload may grow before its schema does
+func ProtoPayload(event *Event) (proto.Message, error) {
+	newMessage, ok := payloadSchemas[event.EventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSchema, event.EventType)
+	}
+	message := newMessage()
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal([]byte(event.Payload), message); err != nil {
+		return nil, fmt.Errorf("payload of %s doesn't match %s: %w", event.EventType, message.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return message, nil
+}
+
+// MarshalProto returns the protobuf encoding of an event's payload
+func MarshalProto(event *Event) ([]byte, error) {
+	message, err := ProtoPayload(event)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(message)
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"acid/internal/httpclient"
+	eventspb "acid/proto/events"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SchemaRegistryConfig holds Confluent Schema Registry configuration
+type SchemaRegistryConfig struct {
+	// URL is the registry's base URL, e.g. http://schema-registry:8081
+	URL string
+
+	// Username and Password are sent as basic auth when set
+	Username string
+	Password string
+
+	// Timeout bounds each registry request
+	Timeout time.Duration
+}
+
+// DefaultSchemaRegistryConfig returns sensible defaults
+func DefaultSchemaRegistryConfig() *SchemaRegistryConfig {
+	return &SchemaRegistryConfig{
+		URL:     "http://localhost:8081",
+		Timeout: 5 * time.Second,
+	}
+}
+
+// SchemaRegistry registers the event schema with a Confluent Schema Registry and frames payloads
+// in its wire format, so consumers using Confluent deserializers decode them. Registering is also
+// the compatibility check: a schema change the subject's compatibility level forbids is refused,
+// and so is every publish using it
+type SchemaRegistry struct {
+	config *SchemaRegistryConfig
+	client *httpclient.Client
+
+	mu  sync.Mutex
+	ids map[string]int // Schema ID per subject, registered once per process
+}
+
+func NewSchemaRegistry(config *SchemaRegistryConfig) *SchemaRegistry {
+	if config == nil {
+		config = DefaultSchemaRegistryConfig()
+	}
+	clientConfig := httpclient.DefaultConfig("schema-registry")
+	clientConfig.Timeout = config.Timeout
+	return &SchemaRegistry{
+		config: config,
+		client: httpclient.New(clientConfig),
+		ids:    make(map[string]int),
+	}
+}
+
+// Frame returns message in the Confluent wire format: a zero byte, the big-endian schema ID of
+// subject, the message's index in events.proto and the protobuf encoding
+func (r *SchemaRegistry) Frame(ctx context.Context, subject string, message proto.Message) ([]byte, int, error) {
+	id, err := r.Register(ctx, subject)
+	if err != nil {
+		return nil, 0, err
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	framed := make([]byte, 5, 5+2+len(payload))
+	binary.BigEndian.PutUint32(framed[1:], uint32(id))
+	// Message indexes are a zigzag varint count then zigzag varint indexes; the first message
+	// of the file is written as a lone 0
+	if index := message.ProtoReflect().Descriptor().Index(); index == 0 {
+		framed = append(framed, 0)
+	} else {
+		framed = binary.AppendVarint(framed, 1)
+		framed = binary.AppendVarint(framed, int64(index))
+	}
+	return append(framed, payload...), id, nil
+}
+
+// Register registers events.proto under subject and returns its schema ID. The registry returns
+// the existing ID when the schema is already registered
+func (r *SchemaRegistry) Register(ctx context.Context, subject string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.ids[subject]; ok {
+		return id, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"schemaType": "PROTOBUF", "schema": eventspb.Schema})
+	if err != nil {
+		return 0, err
+	}
+	endpoint := strings.TrimSuffix(r.config.URL, "/") + "/subjects/" + url.PathEscape(subject) + "/versions"
+	// Registering a schema twice returns the same ID, so the POST is safe to retry
+	req, err := http.NewRequestWithContext(httpclient.Idempotent(ctx), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.config.Username != "" {
+		req.SetBasicAuth(r.config.Username, r.config.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID        int    `json:"id"`
+		ErrorCode int    `json:"error_code"`
+		Message   string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("unreadable schema registry response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// 409 is an incompatible schema, 422 an invalid one
+		return 0, fmt.Errorf("schema registry refused schema for %s (%s): %s", subject, resp.Status, result.Message)
+	}
+
+	r.ids[subject] = result.ID
+	return result.ID, nil
+}
@@ -0,0 +1,126 @@
+// Package pagination is the one way list endpoints page: ?limit= and an opaque ?cursor= in, a
+// meta object (page size, next cursor, total estimate) and an RFC 5988 Link: rel="next" header
+// out. Endpoints only decide what their cursor holds
+package pagination
+
+import (
+	"acid/internal/problem"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters read by FromQuery and written into Link URLs
+const (
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't produced by EncodeCursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Config bounds the page size of one endpoint
+type Config struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// DefaultConfig returns sensible defaults: 50 items per page, at most 500
+func DefaultConfig() Config {
+	return Config{DefaultLimit: 50, MaxLimit: 500}
+}
+
+// Page is the page a request asked for
+type Page struct {
+	Limit int
+
+	// Cursor is the raw cursor, empty for the first page
+	Cursor string
+}
+
+// First reports whether the request asked for the first page
+func (p Page) First() bool {
+	return p.Cursor == ""
+}
+
+// Meta describes a returned page. TotalEstimate is omitted when counting would cost too much;
+// it may lag concurrent writes
+type Meta struct {
+	PageSize      int    `json:"page_size"`
+	NextCursor    string `json:"next_cursor,omitempty"`
+	TotalEstimate *int64 `json:"total_estimate,omitempty"`
+}
+
+// FromQuery reads ?limit= and ?cursor=, decoding the cursor into position (a pointer to the
+// endpoint's position type, untouched on the first page). On a bad value it aborts with a
+// validation problem and returns false
+func FromQuery(c *gin.Context, config Config, position any) (Page, bool) {
+	page := Page{Limit: config.DefaultLimit, Cursor: c.Query(CursorParam)}
+	if raw := c.Query(LimitParam); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > config.MaxLimit {
+			problem.Abort(c, problem.FieldProblem(LimitParam, "must be an integer between 1 and "+strconv.Itoa(config.MaxLimit)))
+			return Page{}, false
+		}
+		page.Limit = limit
+	}
+	if !page.First() {
+		if err := DecodeCursor(page.Cursor, position); err != nil {
+			problem.Abort(c, problem.FieldProblem(CursorParam, err.Error()))
+			return Page{}, false
+		}
+	}
+	return page, true
+}
+
+// NewMeta describes a page of count items; next is the position of the following page, nil on
+// the last one
+func NewMeta[P any](count int, next *P, total *int64) Meta {
+	meta := Meta{PageSize: count, TotalEstimate: total}
+	if next != nil {
+		meta.NextCursor = EncodeCursor(next)
+	}
+	return meta
+}
+
+// Total is a helper for Meta.TotalEstimate
+func Total(n int64) *int64 {
+	return &n
+}
+
+// SetLink adds the Link: rel="next" header pointing at the request's URL with the next cursor
+// (and the same limit and filters); nothing is added on the last page
+func SetLink(c *gin.Context, page Page, meta Meta) {
+	if meta.NextCursor == "" {
+		return
+	}
+	next := *c.Request.URL
+	query := next.Query()
+	query.Set(CursorParam, meta.NextCursor)
+	query.Set(LimitParam, strconv.Itoa(page.Limit))
+	next.RawQuery = query.Encode()
+	next.Scheme, next.Host = "", ""
+	c.Header("Link", "<"+next.RequestURI()+`>; rel="next"`)
+}
+
+// EncodeCursor packs an endpoint's position, a small struct of IDs and offsets, into an opaque,
+// URL-safe cursor
+func EncodeCursor(position any) string {
+	raw, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor unpacks a cursor made by EncodeCursor into dest
+func DecodeCursor(cursor string, dest any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType is the RFC 7807 media type for error responses
+const ContentType = "application/problem+json"
+
+// Problem types. TypeBlank means the status code says it all; the others are relative URIs
+// documented in the README so clients can branch on them without parsing detail
+const (
+	TypeBlank         = "about:blank"
+	TypeValidation    = "/problems/validation"
+	TypeReadOnly      = "/problems/read-only"
+	TypeDegraded      = "/problems/degraded"
+	TypeRateLimited   = "/problems/rate-limited"
+	TypeQuotaExceeded = "/problems/quota-exceeded"
+	TypeRetired       = "/problems/retired"
+)
+
+// FieldError points at one invalid request field by its JSON name
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object. It implements error so handlers and middleware
+// can attach it with c.Error and leave rendering to middleware.Problems
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	// Extensions are extra members serialized alongside the standard ones (e.g. retry_after)
+	Extensions map[string]any `json:"-"`
+}
+
+// New returns an about:blank problem titled after the status code
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   TypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Typed returns a problem with a specific type and title
+func Typed(status int, problemType, title, detail string) *Problem {
+	return &Problem{
+		Type:   problemType,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With sets an extension member and returns the problem for chaining
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+	}
+	return fmt.Sprintf("%d %s", p.Status, p.Title)
+}
+
+// MarshalJSON flattens Extensions next to the standard members, which take precedence
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	if len(p.Extensions) == 0 {
+		return json.Marshal((*plain)(p))
+	}
+
+	base, err := json.Marshal((*plain)(p))
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]any, len(p.Extensions)+6)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	var standard map[string]json.RawMessage
+	if err := json.Unmarshal(base, &standard); err != nil {
+		return nil, err
+	}
+	for key, value := range standard {
+		members[key] = value
+	}
+	return json.Marshal(members)
+}
+
+// Abort stops the handler chain and records p for middleware.Problems to render
+func Abort(c *gin.Context, p *Problem) {
+	c.Abort()
+	_ = c.Error(p)
+}
+
+// Write renders p immediately; use it outside a chain that has middleware.Problems installed
+func Write(c *gin.Context, p *Problem) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// FromBindError converts a ShouldBind* error into a 400 validation problem with field errors
+func FromBindError(err error) *Problem {
+	p := Typed(http.StatusBadRequest, TypeValidation, "Invalid request", "")
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		p.Detail = "one or more fields are invalid"
+		for _, fe := range validationErrs {
+			p.Errors = append(p.Errors, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+	case errors.As(err, &typeErr):
+		p.Detail = "one or more fields have the wrong type"
+		p.Errors = []FieldError{{Field: typeErr.Field, Message: "must be " + typeErr.Type.String()}}
+	case errors.As(err, &syntaxErr):
+		p.Detail = fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = "request body is empty or truncated"
+	default:
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// FieldProblem is a 400 validation problem for a single field
+func FieldProblem(field, message string) *Problem {
+	p := Typed(http.StatusBadRequest, TypeValidation, "Invalid request", "one or more fields are invalid")
+	p.Errors = []FieldError{{Field: field, Message: message}}
+	return p
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a valid UUID"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
+
+// Report field errors by their JSON name rather than the Go struct field name
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
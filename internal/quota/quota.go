@@ -0,0 +1,528 @@
+// Package quota enforces daily and monthly request quotas per subject (API key, user, client
+// IP or tenant). Redis counters answer every check in one script call; ScyllaDB counters are the durable
+// record, written in the background and used to re-seed Redis when a counter is missing
+package quota
+
+import (
+	"acid/internal/cache"
+	"acid/internal/safego"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Period is a quota window aligned to the UTC calendar
+type Period string
+
+const (
+	Daily   Period = "day"
+	Monthly Period = "month"
+)
+
+// Periods lists every supported period, shortest first
+var Periods = []Period{Daily, Monthly}
+
+// Valid reports whether p is a supported period
+func (p Period) Valid() bool {
+	return p == Daily || p == Monthly
+}
+
+// Bucket returns the usage bucket containing t (e.g. "day:2024-06-01") and when it ends
+func (p Period) Bucket(t time.Time) (string, time.Time) {
+	t = t.UTC()
+	if p == Monthly {
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return string(p) + ":" + t.Format("2006-01"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return string(p) + ":" + t.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+// Limits are requests allowed per period; zero means unlimited
+type Limits struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// For returns the limit of one period
+func (l Limits) For(p Period) int64 {
+	if p == Monthly {
+		return l.Monthly
+	}
+	return l.Daily
+}
+
+func (l *Limits) set(p Period, quota int64) {
+	if p == Monthly {
+		l.Monthly = quota
+	} else {
+		l.Daily = quota
+	}
+}
+
+// Usage is a subject's consumption in the current bucket of one period
+type Usage struct {
+	Period Period `json:"period"`
+	// Limit is zero when the period is unlimited
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// Decision is the outcome of Consume
+type Decision struct {
+	Allowed bool
+	Subject string
+	// Usage covers the limited periods; empty when the subject is unlimited or the check failed open
+	Usage []Usage
+	// Exceeded is the first period whose limit was reached when the request was rejected
+	Exceeded *Usage
+}
+
+// Config holds quota configuration
+type Config struct {
+	// Defaults apply to subjects without overrides
+	Defaults Limits
+
+	// TenantDefaults apply to tenant subjects without overrides instead of Defaults; a tenant's
+	// quota is shared by all its callers, so it is typically far larger
+	TenantDefaults Limits
+
+	// FlushInterval is how often usage is written to ScyllaDB; the Redis fast path is exact, but
+	// a counter re-seeded from ScyllaDB misses up to this much usage from other instances
+	FlushInterval time.Duration
+
+	// LimitCacheTTL is how long overrides are cached per instance; admin changes made on another
+	// instance take effect within it
+	LimitCacheTTL time.Duration
+
+	// Timeout bounds a check, including re-seeding from ScyllaDB
+	Timeout time.Duration
+
+	// Prefix namespaces counters in Redis
+	Prefix string
+}
+
+// DefaultConfig returns sensible defaults: no default limits, 5s flushes, 1m override cache
+func DefaultConfig() *Config {
+	return &Config{
+		FlushInterval: 5 * time.Second,
+		LimitCacheTTL: 1 * time.Minute,
+		Timeout:       500 * time.Millisecond,
+		Prefix:        "quota:",
+	}
+}
+
+// Metrics tracks quota decisions and durable writes
+type Metrics struct {
+	Allowed     atomic.Int64
+	Denied      atomic.Int64
+	FailedOpen  atomic.Int64
+	Flushed     atomic.Int64
+	FlushErrors atomic.Int64
+}
+
+type pendingKey struct {
+	subject string
+	bucket  string
+}
+
+type cachedLimits struct {
+	limits    Limits
+	expiresAt time.Time
+}
+
+type window struct {
+	period  Period
+	bucket  string
+	limit   int64
+	resetAt time.Time
+}
+
+var errNoFastPath = errors.New("quota fast path unavailable: redis not configured")
+
+// Manager checks and records quota usage. Checks fail open when Redis can't answer: a quota
+// outage must not take the API down, and the usage is still recorded durably
+type Manager struct {
+	repo    *Repository
+	counter *cache.QuotaCounter
+	config  *Config
+	logger  *zap.Logger
+
+	limitsMu sync.Mutex
+	limits   map[string]cachedLimits
+
+	pendingMu sync.Mutex
+	pending   map[pendingKey]int64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	metrics Metrics
+}
+
+// NewManager creates a quota manager; a nil counter makes every check fail open
+func NewManager(repo *Repository, counter *cache.QuotaCounter, config *Config, logger *zap.Logger) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Manager{
+		repo:    repo,
+		counter: counter,
+		config:  config,
+		logger:  logger,
+		limits:  make(map[string]cachedLimits),
+		pending: make(map[pendingKey]int64),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// APIKeySubject is the subject of requests made with an API key; keys are hashed so raw keys are
+// never stored or logged
+func APIKeySubject(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// TenantSubject is the subject a tenant's requests are metered under, on top of their own
+func TenantSubject(tenant string) string {
+	return tenantSubjectPrefix + tenant
+}
+
+const tenantSubjectPrefix = "tenant:"
+
+// Start launches the background flush of usage to ScyllaDB
+func (m *Manager) Start() {
+	safego.Go("quota.flush", m.run, safego.Restart(m.stop), safego.OnExit(func() { close(m.done) }))
+	m.logger.Info("Quota manager started",
+		zap.Int64("default_daily", m.config.Defaults.Daily),
+		zap.Int64("default_monthly", m.config.Defaults.Monthly),
+		zap.Duration("flush_interval", m.config.FlushInterval))
+}
+
+// Stop halts the flush loop and writes the remaining usage
+func (m *Manager) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+		<-m.done
+		m.logger.Info("Quota manager stopped")
+	})
+}
+
+// Consume checks subject's quotas and, if every limited period has room, counts one request
+func (m *Manager) Consume(ctx context.Context, subject string) *Decision {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	limits, err := m.Limits(ctx, subject)
+	if err != nil {
+		m.logger.Warn("Failed to load quota overrides, using defaults", zap.String("subject", subject), zap.Error(err))
+	}
+
+	windows := m.windows(limits, time.Now())
+	if len(windows) == 0 {
+		m.metrics.Allowed.Add(1)
+		return &Decision{Allowed: true, Subject: subject}
+	}
+
+	allowed, used, err := m.consume(ctx, subject, windows)
+	if err != nil {
+		m.metrics.FailedOpen.Add(1)
+		m.logger.Warn("Quota check failed, allowing request", zap.String("subject", subject), zap.Error(err))
+		m.record(subject, windows)
+		return &Decision{Allowed: true, Subject: subject}
+	}
+
+	decision := &Decision{Allowed: allowed, Subject: subject, Usage: make([]Usage, len(windows))}
+	for i, w := range windows {
+		decision.Usage[i] = Usage{
+			Period:    w.period,
+			Limit:     w.limit,
+			Used:      used[i],
+			Remaining: max(w.limit-used[i], 0),
+			ResetAt:   w.resetAt,
+		}
+		if !allowed && decision.Exceeded == nil && used[i] >= w.limit {
+			decision.Exceeded = &decision.Usage[i]
+		}
+	}
+
+	if allowed {
+		m.metrics.Allowed.Add(1)
+		m.record(subject, windows)
+	} else {
+		m.metrics.Denied.Add(1)
+	}
+	return decision
+}
+
+// consume runs the Redis check, re-seeding missing counters from durable usage
+func (m *Manager) consume(ctx context.Context, subject string, windows []window) (bool, []int64, error) {
+	if m.counter == nil {
+		return false, nil, errNoFastPath
+	}
+
+	quotaWindows := make([]cache.QuotaWindow, len(windows))
+	for i, w := range windows {
+		quotaWindows[i] = cache.QuotaWindow{
+			Key:      counterKey(subject, w.bucket),
+			Limit:    w.limit,
+			ExpireAt: w.resetAt.Add(time.Hour), // Outlive the bucket a little for clock skew
+			Seed:     -1,
+		}
+	}
+
+	allowed, used, err := m.counter.Consume(ctx, quotaWindows)
+	if !errors.Is(err, cache.ErrQuotaSeedRequired) {
+		return allowed, used, err
+	}
+
+	durable, err := m.durableUsage(ctx, subject, windows)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to seed quota counters: %w", err)
+	}
+	for i, w := range windows {
+		quotaWindows[i].Seed = durable[w.bucket]
+	}
+	return m.counter.Consume(ctx, quotaWindows)
+}
+
+// Limits returns the limits in effect for subject: its overrides on top of the defaults
+// On error the defaults are returned along with it
+func (m *Manager) Limits(ctx context.Context, subject string) (Limits, error) {
+	m.limitsMu.Lock()
+	cached, ok := m.limits[subject]
+	m.limitsMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.limits, nil
+	}
+
+	limits := m.config.Defaults
+	if strings.HasPrefix(subject, tenantSubjectPrefix) {
+		limits = m.config.TenantDefaults
+	}
+	overrides, err := m.repo.Limits(ctx, subject)
+	if err != nil {
+		return limits, err
+	}
+	for period, quota := range overrides {
+		if period.Valid() {
+			limits.set(period, quota)
+		}
+	}
+
+	m.limitsMu.Lock()
+	m.limits[subject] = cachedLimits{limits: limits, expiresAt: time.Now().Add(m.config.LimitCacheTTL)}
+	m.limitsMu.Unlock()
+	return limits, nil
+}
+
+// Usage returns subject's limits and its usage in the current bucket of every period. Usage not
+// yet flushed by other instances is not included
+func (m *Manager) Usage(ctx context.Context, subject string) (Limits, []Usage, error) {
+	limits, err := m.Limits(ctx, subject)
+	if err != nil {
+		return limits, nil, err
+	}
+
+	windows := m.allWindows(limits, time.Now())
+	durable, err := m.durableUsage(ctx, subject, windows)
+	if err != nil {
+		return limits, nil, err
+	}
+
+	usage := make([]Usage, len(windows))
+	for i, w := range windows {
+		usage[i] = Usage{Period: w.period, Limit: w.limit, Used: durable[w.bucket], ResetAt: w.resetAt}
+		if w.limit > 0 {
+			usage[i].Remaining = max(w.limit-usage[i].Used, 0)
+		}
+	}
+	return limits, usage, nil
+}
+
+// SetLimit overrides one period's limit for subject (zero means unlimited)
+func (m *Manager) SetLimit(ctx context.Context, subject string, period Period, quota int64) error {
+	if !period.Valid() {
+		return fmt.Errorf("unknown quota period %q", period)
+	}
+	if quota < 0 {
+		return fmt.Errorf("quota must not be negative")
+	}
+	if err := m.repo.SetLimit(ctx, subject, period, quota); err != nil {
+		return err
+	}
+	m.forgetLimits(subject)
+	return nil
+}
+
+// ClearLimits removes subject's overrides so the defaults apply again
+func (m *Manager) ClearLimits(ctx context.Context, subject string) error {
+	for _, period := range Periods {
+		if err := m.repo.DeleteLimit(ctx, subject, period); err != nil {
+			return err
+		}
+	}
+	m.forgetLimits(subject)
+	return nil
+}
+
+// ResetUsage zeroes subject's usage in the current buckets, e.g. after a billing adjustment
+func (m *Manager) ResetUsage(ctx context.Context, subject string) error {
+	m.flush()
+
+	windows := m.allWindows(m.config.Defaults, time.Now())
+	durable, err := m.durableUsage(ctx, subject, windows)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(windows))
+	for _, w := range windows {
+		if used := durable[w.bucket]; used != 0 {
+			if err := m.repo.AddUsage(ctx, subject, w.bucket, -used); err != nil {
+				return err
+			}
+		}
+		keys = append(keys, counterKey(subject, w.bucket))
+	}
+	return m.counter.Reset(ctx, keys...)
+}
+
+// GetMetrics returns current quota metrics
+func (m *Manager) GetMetrics() map[string]int64 {
+	m.pendingMu.Lock()
+	pending := len(m.pending)
+	m.pendingMu.Unlock()
+
+	return map[string]int64{
+		"allowed":        m.metrics.Allowed.Load(),
+		"denied":         m.metrics.Denied.Load(),
+		"failed_open":    m.metrics.FailedOpen.Load(),
+		"flushed":        m.metrics.Flushed.Load(),
+		"flush_errors":   m.metrics.FlushErrors.Load(),
+		"pending_counts": int64(pending),
+	}
+}
+
+// windows returns the current bucket of every limited period
+func (m *Manager) windows(limits Limits, now time.Time) []window {
+	windows := make([]window, 0, len(Periods))
+	for _, w := range m.allWindows(limits, now) {
+		if w.limit > 0 {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+func (m *Manager) allWindows(limits Limits, now time.Time) []window {
+	windows := make([]window, len(Periods))
+	for i, period := range Periods {
+		bucket, resetAt := period.Bucket(now)
+		windows[i] = window{period: period, bucket: bucket, limit: limits.For(period), resetAt: resetAt}
+	}
+	return windows
+}
+
+// durableUsage is the ScyllaDB usage plus what this instance hasn't flushed yet
+func (m *Manager) durableUsage(ctx context.Context, subject string, windows []window) (map[string]int64, error) {
+	buckets := make([]string, len(windows))
+	for i, w := range windows {
+		buckets[i] = w.bucket
+	}
+
+	usage, err := m.repo.Usage(ctx, subject, buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	m.pendingMu.Lock()
+	for _, bucket := range buckets {
+		usage[bucket] += m.pending[pendingKey{subject: subject, bucket: bucket}]
+	}
+	m.pendingMu.Unlock()
+	return usage, nil
+}
+
+func (m *Manager) record(subject string, windows []window) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	for _, w := range windows {
+		m.pending[pendingKey{subject: subject, bucket: w.bucket}]++
+	}
+}
+
+func (m *Manager) forgetLimits(subject string) {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	delete(m.limits, subject)
+}
+
+func (m *Manager) run() {
+	ticker := time.NewTicker(m.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			m.flush()
+			return
+		case <-ticker.C:
+			m.flush()
+			m.pruneLimits()
+		}
+	}
+}
+
+// flush writes pending usage to ScyllaDB; failed increments are kept for the next flush
+func (m *Manager) flush() {
+	m.pendingMu.Lock()
+	pending := m.pending
+	m.pending = make(map[pendingKey]int64)
+	m.pendingMu.Unlock()
+
+	for key, delta := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := m.repo.AddUsage(ctx, key.subject, key.bucket, delta)
+		cancel()
+		if err != nil {
+			m.metrics.FlushErrors.Add(1)
+			m.logger.Warn("Failed to flush quota usage",
+				zap.String("subject", key.subject), zap.String("bucket", key.bucket), zap.Error(err))
+			m.pendingMu.Lock()
+			m.pending[key] += delta
+			m.pendingMu.Unlock()
+			continue
+		}
+		m.metrics.Flushed.Add(delta)
+	}
+}
+
+// pruneLimits drops expired override cache entries so one-off subjects (client IPs) don't pile up
+func (m *Manager) pruneLimits() {
+	now := time.Now()
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	for subject, cached := range m.limits {
+		if now.After(cached.expiresAt) {
+			delete(m.limits, subject)
+		}
+	}
+}
+
+// counterKey hash-tags the subject so all of its counters land in one Redis Cluster slot
+func counterKey(subject, bucket string) string {
+	return "{" + subject + "}:" + bucket
+}
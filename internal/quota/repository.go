@@ -0,0 +1,132 @@
+package quota
+
+import (
+	"context"
+	"time"
+
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// UsageTable holds durable usage counters, one row per subject and period bucket
+var UsageTable = table.New(table.Metadata{
+	Name:    "quota_usage",
+	Columns: []string{"subject", "bucket", "used"},
+	PartKey: []string{"subject"},
+	SortKey: []string{"bucket"},
+})
+
+// LimitsTable holds per-subject limit overrides; subjects without a row get the defaults
+var LimitsTable = table.New(table.Metadata{
+	Name:    "quota_limits",
+	Columns: []string{"subject", "period", "quota", "updated_at"},
+	PartKey: []string{"subject"},
+	SortKey: []string{"period"},
+})
+
+// UsageColumnTypes and LimitsColumnTypes are the CQL types usageRow and limitRow are marshaled to
+var (
+	UsageColumnTypes = map[string]string{
+		"subject": "text",
+		"bucket":  "text",
+		"used":    "counter",
+	}
+	LimitsColumnTypes = map[string]string{
+		"subject":    "text",
+		"period":     "text",
+		"quota":      "bigint",
+		"updated_at": "timestamp",
+	}
+)
+
+type usageRow struct {
+	Subject string
+	Bucket  string
+	Used    int64
+}
+
+type limitRow struct {
+	Subject   string
+	Period    Period
+	Quota     int64
+	UpdatedAt time.Time
+}
+
+// Repository stores quota usage in ScyllaDB counters and limit overrides in a regular table
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Usage returns the durable usage of a subject's buckets; buckets without a row are omitted
+func (r *Repository) Usage(ctx context.Context, subject string, buckets []string) (map[string]int64, error) {
+	stmt, names := qb.Select(UsageTable.Name()).
+		Columns("bucket", "used").
+		Where(qb.Eq("subject"), qb.In("bucket")).
+		ToCql()
+
+	var rows []usageRow
+	q := r.session.ContextQuery(ctx, stmt, names).BindMap(map[string]interface{}{
+		"subject": subject,
+		"bucket":  buckets,
+	})
+	if err := q.SelectRelease(&rows); err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		usage[row.Bucket] = row.Used
+	}
+	return usage, nil
+}
+
+// AddUsage increments a usage counter by delta (negative to give quota back)
+// Counter updates are not idempotent, so they are never retried
+func (r *Repository) AddUsage(ctx context.Context, subject, bucket string, delta int64) error {
+	stmt, names := qb.Update(UsageTable.Name()).
+		AddNamed("used", "delta").
+		Where(qb.Eq("subject"), qb.Eq("bucket")).
+		ToCql()
+
+	return r.session.ContextQuery(ctx, stmt, names).BindMap(map[string]interface{}{
+		"delta":   delta,
+		"subject": subject,
+		"bucket":  bucket,
+	}).ExecRelease()
+}
+
+// Limits returns a subject's limit overrides by period
+func (r *Repository) Limits(ctx context.Context, subject string) (map[Period]int64, error) {
+	var rows []limitRow
+	q := LimitsTable.SelectQueryContext(ctx, r.session).BindMap(map[string]interface{}{
+		"subject": subject,
+	})
+	if err := q.SelectRelease(&rows); err != nil {
+		return nil, err
+	}
+
+	limits := make(map[Period]int64, len(rows))
+	for _, row := range rows {
+		limits[row.Period] = row.Quota
+	}
+	return limits, nil
+}
+
+// SetLimit overrides one period's limit for a subject
+func (r *Repository) SetLimit(ctx context.Context, subject string, period Period, quota int64) error {
+	row := limitRow{Subject: subject, Period: period, Quota: quota, UpdatedAt: time.Now()}
+	return LimitsTable.InsertQueryContext(ctx, r.session).BindStruct(row).ExecRelease()
+}
+
+// DeleteLimit removes one period's override so the default applies again
+func (r *Repository) DeleteLimit(ctx context.Context, subject string, period Period) error {
+	return LimitsTable.DeleteQueryContext(ctx, r.session).BindMap(map[string]interface{}{
+		"subject": subject,
+		"period":  period,
+	}).ExecRelease()
+}
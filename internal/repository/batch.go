@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"acid/internal/models"
+	"acid/internal/workerpool"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// maxBatchPartitions is the hard ceiling on partitions per batch, whatever the config says
+const maxBatchPartitions = 10
+
+// ErrCrossPartitionBatch is returned for batch configs that would spread one batch over many partitions
+var ErrCrossPartitionBatch = errors.New("cross-partition batch not allowed")
+
+// BatchConfig holds bulk write configuration
+type BatchConfig struct {
+	// Size is the max number of statements per BATCH
+	Size int
+
+	// Logged makes batches atomic across partitions (batchlog write), at roughly twice the cost
+	Logged bool
+
+	// MaxPartitions is the max number of distinct partitions in one batch. Unlogged batches must
+	// stay at 1: a multi-partition unlogged batch only moves fan-out work onto the coordinator
+	MaxPartitions int
+
+	// Concurrency is the number of batches in flight
+	Concurrency int
+}
+
+// DefaultBatchConfig returns single-partition unlogged batches, the cheap and safe default
+func DefaultBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		Size:          50,
+		Logged:        false,
+		MaxPartitions: 1,
+		Concurrency:   4,
+	}
+}
+
+func (c *BatchConfig) Validate() error {
+	if c.Size <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("batch concurrency must be positive")
+	}
+	if c.MaxPartitions <= 0 {
+		return fmt.Errorf("batch max partitions must be positive")
+	}
+	if c.MaxPartitions > 1 && !c.Logged {
+		return fmt.Errorf("%w: unlogged batches must target a single partition", ErrCrossPartitionBatch)
+	}
+	if c.MaxPartitions > maxBatchPartitions {
+		return fmt.Errorf("%w: at most %d partitions per batch", ErrCrossPartitionBatch, maxBatchPartitions)
+	}
+	return nil
+}
+
+// SetBatchConfig replaces the bulk write configuration
+func (r *UserRepository) SetBatchConfig(config *BatchConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	r.batch = config
+	return nil
+}
+
+// CreateUsersBatch inserts users with BATCH statements grouped by partition and returns how
+// many were written. Batches run concurrently; the first failure stops scheduling new ones.
+// Inserts are not retried, matching CreateUser
+func (r *UserRepository) CreateUsersBatch(ctx context.Context, users []*models.User) (int, error) {
+	batches := planBatches(users, func(u *models.User) string { return u.ID.String() }, r.batch)
+	if len(batches) == 0 {
+		return 0, nil
+	}
+
+	batchType := gocql.UnloggedBatch
+	if r.batch.Logged {
+		batchType = gocql.LoggedBatch
+	}
+
+	var written atomic.Int64
+	pool := workerpool.New(ctx, &workerpool.Config{Workers: r.batch.Concurrency, StopOnError: true})
+	for _, group := range batches {
+		if pool.Submit(func(ctx context.Context) error {
+			if err := r.execUserBatch(ctx, batchType, group); err != nil {
+				return err
+			}
+			written.Add(int64(len(group)))
+			return nil
+		}) != nil {
+			break
+		}
+	}
+
+	err := pool.Wait()
+	return int(written.Load()), err
+}
+
+func (r *UserRepository) execUserBatch(ctx context.Context, batchType gocql.BatchType, users []*models.User) error {
+	query := r.session.Query(UserTable.Insert())
+	defer query.Release()
+
+	batch := r.session.ContextBatch(ctx, batchType)
+	for _, user := range users {
+		if err := batch.BindStruct(query, user); err != nil {
+			return fmt.Errorf("failed to bind user %s: %w", user.ID, err)
+		}
+	}
+
+	return r.retry.Do(ctx, "CreateUsersBatch", false, func() error {
+		// Unlike single writes, a batch stops with ctx: the pool cancels it after another batch fails
+		batchCtx, cancel := withTimeout(ctx, r.timeouts.Write)
+		defer cancel()
+		return r.session.ExecuteBatch(batch.WithContext(batchCtx))
+	})
+}
+
+// planBatches groups items by partition key and packs the groups into batches of at most
+// config.Size statements spanning at most config.MaxPartitions partitions. A partition with
+// more than Size items is split over several batches, each still single-partition
+func planBatches[T any](items []T, partitionKey func(T) string, config *BatchConfig) [][]T {
+	order := make([]string, 0, len(items))
+	groups := make(map[string][]T)
+	for _, item := range items {
+		key := partitionKey(item)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	var batches [][]T
+	var current []T
+	partitions := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			batches = append(batches, current)
+		}
+		current, partitions = nil, 0
+	}
+
+	for _, key := range order {
+		group := groups[key]
+
+		// Oversized partitions go out on their own, in Size chunks
+		if len(group) > config.Size {
+			flush()
+			for start := 0; start < len(group); start += config.Size {
+				end := min(start+config.Size, len(group))
+				batches = append(batches, group[start:end])
+			}
+			continue
+		}
+
+		if partitions >= config.MaxPartitions || len(current)+len(group) > config.Size {
+			flush()
+		}
+		current = append(current, group...)
+		partitions++
+	}
+	flush()
+
+	return batches
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// DowngradeMetrics counts reads retried at a lower consistency level
+type DowngradeMetrics struct {
+	Downgrades atomic.Int64
+	ByLevel    map[gocql.Consistency]*atomic.Int64 // Fixed at construction, so reads need no lock
+}
+
+// DowngradingRetryPolicy is gocql's DowngradingConsistencyRetryPolicy with metrics: when a read
+// times out, or replicas are unavailable while at least one is alive, it is retried at the next
+// lower level instead of failing. Reads may then miss the latest writes, so it's opt-in and
+// applied to user-facing reads only
+type DowngradingRetryPolicy struct {
+	policy  *gocql.DowngradingConsistencyRetryPolicy
+	metrics *DowngradeMetrics
+}
+
+// NewDowngradingRetryPolicy tries levels in order after the configured consistency fails;
+// LOCAL_ONE alone is the usual choice
+func NewDowngradingRetryPolicy(levels []gocql.Consistency) *DowngradingRetryPolicy {
+	if len(levels) == 0 {
+		levels = []gocql.Consistency{gocql.LocalOne}
+	}
+
+	metrics := &DowngradeMetrics{ByLevel: make(map[gocql.Consistency]*atomic.Int64, len(levels))}
+	for _, level := range levels {
+		metrics.ByLevel[level] = &atomic.Int64{}
+	}
+
+	return &DowngradingRetryPolicy{
+		policy:  &gocql.DowngradingConsistencyRetryPolicy{ConsistencyLevelsToTry: levels},
+		metrics: metrics,
+	}
+}
+
+// Attempt lowers the query's consistency for the next attempt, counting every downgrade
+func (p *DowngradingRetryPolicy) Attempt(q gocql.RetryableQuery) bool {
+	before := q.GetConsistency()
+	if !p.policy.Attempt(q) {
+		return false
+	}
+	if after := q.GetConsistency(); after != before {
+		p.metrics.Downgrades.Add(1)
+		p.metrics.ByLevel[after].Add(1)
+	}
+	return true
+}
+
+func (p *DowngradingRetryPolicy) GetRetryType(err error) gocql.RetryType {
+	return p.policy.GetRetryType(err)
+}
+
+// GetMetrics reports the total and "downgrades:<level>" per target level
+func (p *DowngradingRetryPolicy) GetMetrics() map[string]int64 {
+	metrics := map[string]int64{
+		"downgrades": p.metrics.Downgrades.Load(),
+	}
+	for level, count := range p.metrics.ByLevel {
+		metrics["downgrades:"+level.String()] = count.Load()
+	}
+	return metrics
+}
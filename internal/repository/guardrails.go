@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrAllowFiltering is returned for a statement with ALLOW FILTERING, which makes the
+// coordinator read whole partitions or tables to throw most rows away
+var ErrAllowFiltering = errors.New("ALLOW FILTERING statements are refused")
+
+// ErrPageTooLarge is returned for a listing or scan asking for more rows per page than
+// GuardrailConfig.MaxPageSize
+var ErrPageTooLarge = errors.New("page size exceeds the guardrail")
+
+// ErrPartitionTooLarge is returned by whole-partition reads of more than
+// GuardrailConfig.MaxPartitionRows rows when DenyLargePartitions is set
+var ErrPartitionTooLarge = errors.New("partition exceeds the row guardrail")
+
+// GuardrailConfig bounds what a single repository statement may read, so a bug in a handler
+// can't turn one request into a scan that takes the cluster down
+type GuardrailConfig struct {
+	// MaxPageSize caps the LIMIT of listings and the page size of token-range scans
+	MaxPageSize int
+
+	// MaxPartitionRows is the most rows a read of a whole partition (a user's notifications)
+	// should return. Larger partitions are logged; with DenyLargePartitions the read stops just
+	// past the threshold and fails instead
+	MaxPartitionRows    int
+	DenyLargePartitions bool
+}
+
+// DefaultGuardrailConfig allows pages of up to 5000 rows and logs partitions over 10000 rows
+func DefaultGuardrailConfig() *GuardrailConfig {
+	return &GuardrailConfig{
+		MaxPageSize:      5000,
+		MaxPartitionRows: 10000,
+	}
+}
+
+// GuardrailMetrics counts the statements the guardrails stopped or flagged
+type GuardrailMetrics struct {
+	FilteringRefused atomic.Int64
+	PagesRefused     atomic.Int64
+	LargePartitions  atomic.Int64 // Whole-partition reads over MaxPartitionRows, denied or not
+	PartitionsDenied atomic.Int64
+}
+
+// Guardrails checks statements and page sizes before they reach the cluster; it is shared by
+// the repositories like the Retryer
+type Guardrails struct {
+	config  *GuardrailConfig
+	metrics *GuardrailMetrics
+}
+
+func NewGuardrails(config *GuardrailConfig) *Guardrails {
+	if config == nil {
+		config = DefaultGuardrailConfig()
+	}
+	return &Guardrails{
+		config:  config,
+		metrics: &GuardrailMetrics{},
+	}
+}
+
+// Statement refuses a statement with ALLOW FILTERING. Repositories check every SELECT they
+// build, so filtering can't be added to one by accident
+func (g *Guardrails) Statement(op, stmt string) error {
+	if !strings.Contains(strings.ToUpper(stmt), "ALLOW FILTERING") {
+		return nil
+	}
+	g.metrics.FilteringRefused.Add(1)
+	log.Printf("[Repository] %s refused: statement uses ALLOW FILTERING: %s", op, stmt)
+	return fmt.Errorf("%s: %w", op, ErrAllowFiltering)
+}
+
+// PageSize refuses a page size that isn't positive or exceeds MaxPageSize
+func (g *Guardrails) PageSize(op string, size int) error {
+	if size > 0 && (g.config.MaxPageSize <= 0 || size <= g.config.MaxPageSize) {
+		return nil
+	}
+	g.metrics.PagesRefused.Add(1)
+	log.Printf("[Repository] %s refused: page size %d outside 1..%d", op, size, g.config.MaxPageSize)
+	return fmt.Errorf("%s: page size %d: %w", op, size, ErrPageTooLarge)
+}
+
+// partitionLimit is the LIMIT of whole-partition reads: one row past MaxPartitionRows when large
+// partitions are denied, so the read stops there, and 0 for no LIMIT otherwise
+func (g *Guardrails) partitionLimit() uint {
+	if !g.config.DenyLargePartitions || g.config.MaxPartitionRows <= 0 {
+		return 0
+	}
+	return uint(g.config.MaxPartitionRows) + 1
+}
+
+// Partition checks the rows a whole-partition read returned against MaxPartitionRows
+func (g *Guardrails) Partition(op string, key any, rows int) error {
+	if g.config.MaxPartitionRows <= 0 || rows <= g.config.MaxPartitionRows {
+		return nil
+	}
+	g.metrics.LargePartitions.Add(1)
+	if !g.config.DenyLargePartitions {
+		log.Printf("[Repository] %s read %d rows from partition %v, over the guardrail of %d", op, rows, key, g.config.MaxPartitionRows)
+		return nil
+	}
+	g.metrics.PartitionsDenied.Add(1)
+	log.Printf("[Repository] %s refused: partition %v has more than %d rows", op, key, g.config.MaxPartitionRows)
+	return fmt.Errorf("%s: partition %v: %w", op, key, ErrPartitionTooLarge)
+}
+
+// GetMetrics returns the guardrail counters
+func (g *Guardrails) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"filtering_refused": g.metrics.FilteringRefused.Load(),
+		"pages_refused":     g.metrics.PagesRefused.Load(),
+		"large_partitions":  g.metrics.LargePartitions.Load(),
+		"partitions_denied": g.metrics.PartitionsDenied.Load(),
+	}
+}
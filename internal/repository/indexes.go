@@ -0,0 +1,12 @@
+package repository
+
+import "acid/db"
+
+// Indexes and Views are the secondary indexes and materialized views the repositories query
+// through. None are needed yet: every read is served by a table's primary key or a lookup table.
+// Declare one here before querying through it; `acidctl indexes -write db/migration` then writes
+// its migration and startup warns on clusters that lack it
+var (
+	Indexes []db.IndexSchema
+	Views   []db.ViewSchema
+)
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// UserLoginsTable counts logins per user; counters can't share a table with regular columns, so
+// the count lives here while last_login_at is a column of users
+var UserLoginsTable = table.New(table.Metadata{
+	Name:    "user_logins",
+	Columns: []string{"id", "login_count"},
+	PartKey: []string{"id"},
+	SortKey: []string{},
+})
+
+// UserLoginsColumnTypes are the CQL types of user_logins
+var UserLoginsColumnTypes = map[string]string{
+	"id":          "uuid",
+	"login_count": "counter",
+}
+
+// UserLoginColumns is the part of users written by RecordLogin; models.User doesn't carry it, so
+// it is checked at startup on its own
+var UserLoginColumns = table.Metadata{
+	Name:    "users",
+	Columns: []string{"id", "last_login_at"},
+	PartKey: []string{"id"},
+	SortKey: []string{},
+}
+
+// UserLoginColumnTypes are the CQL types of UserLoginColumns
+var UserLoginColumnTypes = map[string]string{
+	"id":            "uuid",
+	"last_login_at": "timestamp",
+}
+
+// LoginStats are a user's login totals; LastLoginAt is zero and LoginCount 0 before the first login
+type LoginStats struct {
+	LastLoginAt time.Time `json:"last_login_at"`
+	LoginCount  int64     `json:"login_count"`
+}
+
+// RecordLogin sets last_login_at and increments login_count. The caller checks the user exists:
+// the plain UPDATE would otherwise create a partial row. The timestamp write is retried; the
+// counter increment is not, since counter updates are not idempotent
+func (r *UserRepository) RecordLogin(ctx context.Context, id gocql.UUID, at time.Time) error {
+	stmt, names := qb.Update(UserTable.Name()).Set("last_login_at").Where(qb.Eq("id")).ToCql()
+	err := r.retry.Do(ctx, "RecordLogin", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		return r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id":            id,
+			"last_login_at": at,
+		}).WithContext(ctx).Idempotent(true).ExecRelease()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record last login: %w", err)
+	}
+
+	stmt, names = qb.Update(UserLoginsTable.Name()).Add("login_count").Where(qb.Eq("id")).ToCql()
+	err = r.retry.Do(ctx, "IncrementLoginCount", false, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		return r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id":          id,
+			"login_count": int64(1),
+		}).WithContext(ctx).ExecRelease()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count login: %w", err)
+	}
+	return nil
+}
+
+// LoginStats reads a user's login totals; a user who never logged in gets zero values
+func (r *UserRepository) LoginStats(ctx context.Context, id gocql.UUID) (*LoginStats, error) {
+	return r.loginStats(ctx, r.reader(), id)
+}
+
+// LoginStatsForWrite is LoginStats on the session writes go to, for totals shown back right
+// after RecordLogin
+func (r *UserRepository) LoginStatsForWrite(ctx context.Context, id gocql.UUID) (*LoginStats, error) {
+	return r.loginStats(ctx, r.session, id)
+}
+
+func (r *UserRepository) loginStats(ctx context.Context, session gocqlx.Session, id gocql.UUID) (*LoginStats, error) {
+	var stats LoginStats
+
+	stmt, names := qb.Select(UserTable.Name()).Columns("last_login_at").Where(qb.Eq("id")).ToCql()
+	if err := r.guard.Statement("GetLastLogin", stmt); err != nil {
+		return nil, err
+	}
+	err := r.retry.Do(ctx, "GetLastLogin", true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		return session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id": id,
+		}).WithContext(ctx).Idempotent(true).Scan(&stats.LastLoginAt)
+	})
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last login: %w", err)
+	}
+
+	stmt, names = qb.Select(UserLoginsTable.Name()).Columns("login_count").Where(qb.Eq("id")).ToCql()
+	if err := r.guard.Statement("GetLoginCount", stmt); err != nil {
+		return nil, err
+	}
+	err = r.retry.Do(ctx, "GetLoginCount", true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		return session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id": id,
+		}).WithContext(ctx).Idempotent(true).Scan(&stats.LoginCount)
+	})
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return nil, fmt.Errorf("failed to read login count: %w", err)
+	}
+	return &stats, nil
+}
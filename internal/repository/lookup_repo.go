@@ -0,0 +1,492 @@
+package repository
+
+import (
+	"acid/internal/models"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// CreatedShards is the number of users_by_created partitions; users are spread by ID so no single
+// partition takes every signup
+const CreatedShards = 16
+
+// UsersByCreatedTable lists user IDs newest first, for listings sorted by creation time
+var UsersByCreatedTable = table.New(table.Metadata{
+	Name:    "users_by_created",
+	Columns: []string{"shard", "created_at", "id"},
+	PartKey: []string{"shard"},
+	SortKey: []string{"created_at", "id"},
+})
+
+// UsersByCreatedColumnTypes are the CQL types of users_by_created
+var UsersByCreatedColumnTypes = map[string]string{
+	"shard":      "int",
+	"created_at": "timestamp",
+	"id":         "uuid",
+}
+
+// UsersByEmailTable maps an email to the user ID that registered it
+var UsersByEmailTable = table.New(table.Metadata{
+	Name:    "users_by_email",
+	Columns: []string{"email", "id"},
+	PartKey: []string{"email"},
+	SortKey: []string{},
+})
+
+// UsersByEmailColumnTypes are the CQL types of users_by_email
+var UsersByEmailColumnTypes = map[string]string{
+	"email": "text",
+	"id":    "uuid",
+}
+
+// UsersByUsernameTable maps a username to its user ID
+var UsersByUsernameTable = table.New(table.Metadata{
+	Name:    "users_by_username",
+	Columns: []string{"username", "id"},
+	PartKey: []string{"username"},
+	SortKey: []string{},
+})
+
+// UsersByUsernameColumnTypes are the CQL types of users_by_username
+var UsersByUsernameColumnTypes = map[string]string{
+	"username": "text",
+	"id":       "uuid",
+}
+
+// UsersByPhoneTable maps an E.164 phone number to the user ID that registered it
+var UsersByPhoneTable = table.New(table.Metadata{
+	Name:    "users_by_phone",
+	Columns: []string{"phone", "id"},
+	PartKey: []string{"phone"},
+	SortKey: []string{},
+})
+
+// UsersByPhoneColumnTypes are the CQL types of users_by_phone
+var UsersByPhoneColumnTypes = map[string]string{
+	"phone": "text",
+	"id":    "uuid",
+}
+
+// UserCreatedKey is a users_by_created row: the position of a user in creation order
+type UserCreatedKey struct {
+	CreatedAt time.Time  `db:"created_at" json:"c"`
+	ID        gocql.UUID `db:"id" json:"i"`
+}
+
+// Before reports whether k sorts before other newest first, the clustering order of
+// users_by_created, so pages merged across shards continue where the slice of each shard does
+func (k UserCreatedKey) Before(other UserCreatedKey) bool {
+	if !k.CreatedAt.Equal(other.CreatedAt) {
+		return k.CreatedAt.After(other.CreatedAt)
+	}
+	return compareUUIDs(k.ID, other.ID) > 0
+}
+
+// compareUUIDs orders UUIDs like ScyllaDB's uuid type: by version, version 1 UUIDs by their
+// timestamp, then bytewise
+func compareUUIDs(a, b gocql.UUID) int {
+	if c := cmp.Compare(a.Version(), b.Version()); c != 0 {
+		return c
+	}
+	if a.Version() == 1 {
+		if c := cmp.Compare(a.Timestamp(), b.Timestamp()); c != 0 {
+			return c
+		}
+	}
+	return bytes.Compare(a[:], b[:])
+}
+
+// CreatedShard is the users_by_created partition of a user
+func CreatedShard(id gocql.UUID) int {
+	h := fnv.New32a()
+	h.Write(id[:])
+	return int(h.Sum32() % CreatedShards)
+}
+
+// ErrEmailTaken, ErrUsernameTaken and ErrPhoneTaken are returned by StageUser when another user
+// holds the email, username or phone number
+var (
+	ErrEmailTaken    = errors.New("email already registered")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrPhoneTaken    = errors.New("phone number already registered")
+)
+
+// claimStaleAfter is how old a lookup row of a missing user must be before a create takes it
+// over: a younger one may belong to a create whose batch is still in flight
+const claimStaleAfter = time.Minute
+
+// StageUser adds a new user to uow. The users and users_by_created rows go in its batch, while
+// the email, username and phone are claimed in users_by_email, users_by_username and
+// users_by_phone right away with lightweight transactions, failing with ErrEmailTaken,
+// ErrUsernameTaken or ErrPhoneTaken. With ifNotExists the users row is claimed the same way,
+// failing with ErrUserExists. On error everything claimed so far has been released
+func (r *UserRepository) StageUser(ctx context.Context, uow *UnitOfWork, user *models.User, ifNotExists bool) error {
+	if ifNotExists {
+		if err := r.CreateUserIfNotExists(ctx, user); err != nil {
+			return err
+		}
+		uow.Compensate("users", func(ctx context.Context) error {
+			return r.deleteUserIfCreatedAt(ctx, user)
+		})
+	} else {
+		uow.Insert(UserTable, user)
+	}
+
+	// Lookup keys can't be empty, and users without an email, username or phone have nothing to claim
+	if user.Email != "" {
+		if err := r.claimLookup(ctx, uow, UsersByEmailTable, user.Email, user.ID, ErrEmailTaken,
+			func(owner *models.User) string { return owner.Email }); err != nil {
+			uow.Rollback(ctx)
+			return err
+		}
+	}
+	if user.Username != "" {
+		if err := r.claimLookup(ctx, uow, UsersByUsernameTable, user.Username, user.ID, ErrUsernameTaken,
+			func(owner *models.User) string { return owner.Username }); err != nil {
+			uow.Rollback(ctx)
+			return err
+		}
+	}
+	if user.Phone != "" {
+		if err := r.claimLookup(ctx, uow, UsersByPhoneTable, user.Phone, user.ID, ErrPhoneTaken,
+			func(owner *models.User) string { return owner.Phone }); err != nil {
+			uow.Rollback(ctx)
+			return err
+		}
+	}
+
+	uow.Insert(UsersByCreatedTable, map[string]interface{}{
+		"shard":      CreatedShard(user.ID),
+		"created_at": user.CreatedAt,
+		"id":         user.ID,
+	})
+	return nil
+}
+
+// DeleteNewUser undoes a committed StageUser, e.g. when a later step of creating the user failed:
+// the lookup claims are released and the users_by_created and users rows deleted. Claims and the
+// users row are only removed while they still belong to user, so nothing written since is lost
+func (r *UserRepository) DeleteNewUser(ctx context.Context, user *models.User) error {
+	var errs []error
+	if user.Phone != "" {
+		errs = append(errs, r.releaseLookup(ctx, UsersByPhoneTable, user.Phone, user.ID))
+	}
+	if user.Username != "" {
+		errs = append(errs, r.releaseLookup(ctx, UsersByUsernameTable, user.Username, user.ID))
+	}
+	if user.Email != "" {
+		errs = append(errs, r.releaseLookup(ctx, UsersByEmailTable, user.Email, user.ID))
+	}
+
+	stmt, names := UsersByCreatedTable.Delete()
+	errs = append(errs, r.retry.Do(ctx, "DeleteNewUser", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		return r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"shard":      CreatedShard(user.ID),
+			"created_at": user.CreatedAt,
+			"id":         user.ID,
+		}).WithContext(ctx).Idempotent(true).ExecRelease()
+	}))
+
+	errs = append(errs, r.deleteUserIfCreatedAt(ctx, user))
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to delete new user %s: %w", user.ID, err)
+	}
+	return nil
+}
+
+// claimLookup points a lookup row at id with INSERT IF NOT EXISTS. A row held by another user is
+// taken over with UPDATE IF when it is stale: older than claimStaleAfter and its user is gone or
+// no longer has value (field reads it), as left by a create that failed before its batch or by
+// a change of email. Retrying is safe: a timed-out attempt that was applied finds its own id
+func (r *UserRepository) claimLookup(ctx context.Context, uow *UnitOfWork, lookup *table.Table, value string, id gocql.UUID, taken error, field func(*models.User) string) error {
+	key := lookup.Metadata().PartKey[0]
+	stmt, names := lookup.InsertBuilder().Unique().ToCql()
+
+	existing := make(map[string]interface{})
+	var applied bool
+	err := r.retry.Do(ctx, "ClaimLookup", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		clear(existing)
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			key:  value,
+			"id": id,
+		}).WithContext(ctx).Idempotent(true).MapScanCAS(existing)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to claim %s: %w", lookup.Name(), err)
+	}
+
+	owner, _ := existing["id"].(gocql.UUID)
+	if !applied && owner != id {
+		stale, err := r.staleLookup(ctx, lookup, value, owner, field)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			return taken
+		}
+		tookOver, err := r.takeOverLookup(ctx, lookup, value, owner, id)
+		if err != nil {
+			return err
+		}
+		if !tookOver {
+			return taken
+		}
+	}
+
+	uow.Compensate(lookup.Name(), func(ctx context.Context) error {
+		return r.releaseLookup(ctx, lookup, value, id)
+	})
+	return nil
+}
+
+// staleLookup reports whether the lookup row of value, held by owner, may be taken over
+func (r *UserRepository) staleLookup(ctx context.Context, lookup *table.Table, value string, owner gocql.UUID, field func(*models.User) string) (bool, error) {
+	key := lookup.Metadata().PartKey[0]
+	stmt := fmt.Sprintf("SELECT WRITETIME(id) FROM %s WHERE %s = ?", lookup.Name(), key)
+	if err := r.guard.Statement("StaleLookup", stmt); err != nil {
+		return false, err
+	}
+
+	var written int64
+	err := r.retry.Do(ctx, "StaleLookup", true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		return r.session.Session.Query(stmt, value).WithContext(ctx).Idempotent(true).Scan(&written)
+	})
+	if errors.Is(err, gocql.ErrNotFound) {
+		// Released since the claim failed; stale, and the takeover's condition fails if it's back
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", lookup.Name(), err)
+	}
+	if time.Since(time.UnixMicro(written)) < claimStaleAfter {
+		return false, nil
+	}
+
+	user, err := r.GetUserForWrite(ctx, owner.String())
+	if errors.Is(err, gocql.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return field(user) != value, nil
+}
+
+// takeOverLookup moves a stale lookup row from owner to id and reports false when another create
+// took it first
+func (r *UserRepository) takeOverLookup(ctx context.Context, lookup *table.Table, value string, owner, id gocql.UUID) (bool, error) {
+	key := lookup.Metadata().PartKey[0]
+	stmt, names := qb.Update(lookup.Name()).Set("id").Where(qb.Eq(key)).If(qb.EqNamed("id", "owner")).ToCql()
+
+	existing := make(map[string]interface{})
+	var applied bool
+	err := r.retry.Do(ctx, "TakeOverLookup", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		clear(existing)
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id":    id,
+			key:     value,
+			"owner": owner,
+		}).WithContext(ctx).Idempotent(true).MapScanCAS(existing)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to take over %s: %w", lookup.Name(), err)
+	}
+	current, _ := existing["id"].(gocql.UUID)
+	return applied || current == id, nil
+}
+
+// releaseLookup deletes a lookup row if id still holds it
+func (r *UserRepository) releaseLookup(ctx context.Context, lookup *table.Table, value string, id gocql.UUID) error {
+	key := lookup.Metadata().PartKey[0]
+	stmt, names := qb.Delete(lookup.Name()).Where(qb.Eq(key)).If(qb.Eq("id")).ToCql()
+	return r.retry.Do(ctx, "ReleaseLookup", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		_, err := r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			key:  value,
+			"id": id,
+		}).WithContext(ctx).Idempotent(true).MapScanCAS(make(map[string]interface{}))
+		return err
+	})
+}
+
+// IndexUser writes the lookup rows of a user in one logged batch, so they are all written or
+// none. Rewriting the same rows is harmless, so it is retried and safe to run from a backfill.
+// Unlike StageUser it doesn't claim: a row held by another user is overwritten
+func (r *UserRepository) IndexUser(ctx context.Context, user *models.User) error {
+	createdStmt, createdNames := UsersByCreatedTable.Insert()
+	emailStmt, emailNames := UsersByEmailTable.Insert()
+	usernameStmt, usernameNames := UsersByUsernameTable.Insert()
+	phoneStmt, phoneNames := UsersByPhoneTable.Insert()
+	row := map[string]interface{}{
+		"shard":      CreatedShard(user.ID),
+		"created_at": user.CreatedAt,
+		"id":         user.ID,
+		"email":      user.Email,
+		"username":   user.Username,
+		"phone":      user.Phone,
+	}
+
+	queries := []*gocqlx.Queryx{r.session.Query(createdStmt, createdNames)}
+	// Lookup keys can't be empty, and users without an email, username or phone have nothing to find
+	if user.Email != "" {
+		queries = append(queries, r.session.Query(emailStmt, emailNames))
+	}
+	if user.Username != "" {
+		queries = append(queries, r.session.Query(usernameStmt, usernameNames))
+	}
+	if user.Phone != "" {
+		queries = append(queries, r.session.Query(phoneStmt, phoneNames))
+	}
+
+	batch := r.session.ContextBatch(ctx, gocql.LoggedBatch)
+	for _, query := range queries {
+		defer query.Release()
+		if err := batch.BindMap(query, row); err != nil {
+			return fmt.Errorf("failed to bind lookups of user %s: %w", user.ID, err)
+		}
+	}
+
+	return r.retry.Do(ctx, "IndexUser", true, func() error {
+		batchCtx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		return r.session.ExecuteBatch(batch.WithContext(batchCtx))
+	})
+}
+
+// ListCreated returns up to limit users_by_created rows of one shard, newest first (oldest first
+// with ascending), after the row after when set
+func (r *UserRepository) ListCreated(ctx context.Context, shard int, ascending bool, after *UserCreatedKey, limit int) ([]UserCreatedKey, error) {
+	builder := qb.Select(UsersByCreatedTable.Name()).Columns("created_at", "id").Where(qb.Eq("shard"))
+	values := map[string]interface{}{"shard": shard}
+	if after != nil {
+		// A clustering slice on (created_at, id), so rows sharing a timestamp aren't skipped
+		if ascending {
+			builder.Where(qb.GtTupleNamed("(created_at,id)", 2, "after"))
+		} else {
+			builder.Where(qb.LtTupleNamed("(created_at,id)", 2, "after"))
+		}
+		values["after[0]"], values["after[1]"] = after.CreatedAt, after.ID
+	}
+	if ascending {
+		builder.OrderBy("created_at", qb.ASC).OrderBy("id", qb.ASC)
+	}
+	if err := r.guard.PageSize("ListCreated", limit); err != nil {
+		return nil, err
+	}
+	stmt, names := builder.Limit(uint(limit)).ToCql()
+	if err := r.guard.Statement("ListCreated", stmt); err != nil {
+		return nil, err
+	}
+
+	var keys []UserCreatedKey
+	err := r.retry.Do(ctx, "ListCreated", true, func() error {
+		keys = nil
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := r.reader().Query(stmt, names).BindMap(values).
+			WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.SelectRelease(&keys)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users_by_created shard %d: %w", shard, err)
+	}
+	return keys, nil
+}
+
+// LookupUserID returns the user ID a users_by_email, users_by_username or users_by_phone row maps
+// value to, and false when there is none
+func (r *UserRepository) LookupUserID(ctx context.Context, lookup *table.Table, value string) (gocql.UUID, bool, error) {
+	var row struct {
+		ID gocql.UUID `db:"id"`
+	}
+	stmt, names := qb.Select(lookup.Name()).Columns("id").Where(qb.Eq(lookup.Metadata().PartKey[0])).ToCql()
+	if err := r.guard.Statement("LookupUserID", stmt); err != nil {
+		return gocql.UUID{}, false, err
+	}
+	err := r.retry.Do(ctx, "LookupUserID", true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := r.reader().Query(stmt, names).BindMap(map[string]interface{}{
+			lookup.Metadata().PartKey[0]: value,
+		}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.GetRelease(&row)
+	})
+	if errors.Is(err, gocql.ErrNotFound) {
+		return gocql.UUID{}, false, nil
+	}
+	if err != nil {
+		return gocql.UUID{}, false, fmt.Errorf("failed to read %s: %w", lookup.Name(), err)
+	}
+	return row.ID, true, nil
+}
+
+// listUsersStmt pages through the whole table in token order
+const listUsersStmt = `SELECT token(id), id, username, email, phone, created_at, preferences, attributes FROM users WHERE token(id) > ? LIMIT ?`
+
+// ListUsers returns up to limit users in token order after token after (math.MinInt64 for the
+// first page), with the token of the last one. Token order is stable but unrelated to IDs or
+// creation time
+func (r *UserRepository) ListUsers(ctx context.Context, after int64, limit int) ([]*models.User, int64, error) {
+	if err := r.guard.PageSize("ListUsers", limit); err != nil {
+		return nil, after, err
+	}
+	if err := r.guard.Statement("ListUsers", listUsersStmt); err != nil {
+		return nil, after, err
+	}
+
+	var users []*models.User
+	last := after
+	err := r.retry.Do(ctx, "ListUsers", true, func() error {
+		users, last = nil, after
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+
+		iter := r.reader().Session.Query(listUsersStmt, after, limit).
+			WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy).Iter()
+		for {
+			var token int64
+			user := &models.User{}
+			if !iter.Scan(&token, &user.ID, &user.Username, &user.Email, &user.Phone, &user.CreatedAt, &user.Preferences, &user.Attributes) {
+				break
+			}
+			users = append(users, user)
+			last = token
+		}
+		return iter.Close()
+	})
+	if err != nil {
+		return nil, after, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, last, nil
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"acid/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// UserAliasesTable maps the ID of a user merged into another to the ID of the user it became, so
+// the old ID keeps resolving
+var UserAliasesTable = table.New(table.Metadata{
+	Name:    "user_aliases",
+	Columns: []string{"alias_id", "user_id", "merged_at"},
+	PartKey: []string{"alias_id"},
+	SortKey: []string{},
+})
+
+// UserAliasesColumnTypes are the CQL types of user_aliases
+var UserAliasesColumnTypes = map[string]string{
+	"alias_id":  "uuid",
+	"user_id":   "uuid",
+	"merged_at": "timestamp",
+}
+
+// UserMergesTable is the audit trail of merges, per surviving user, newest first
+var UserMergesTable = table.New(table.Metadata{
+	Name: "user_merges",
+	Columns: []string{"user_id", "id", "source_id", "source_username", "source_email", "source_phone",
+		"moved", "released", "reason", "merged_at"},
+	PartKey: []string{"user_id"},
+	SortKey: []string{"id"},
+})
+
+// UserMergesColumnTypes are the CQL types of user_merges
+var UserMergesColumnTypes = map[string]string{
+	"user_id":         "uuid",
+	"id":              "timeuuid",
+	"source_id":       "uuid",
+	"source_username": "text",
+	"source_email":    "text",
+	"source_phone":    "text",
+	"moved":           "set<text>",
+	"released":        "set<text>",
+	"reason":          "text",
+	"merged_at":       "timestamp",
+}
+
+// ErrAlreadyMerged is returned by MergeUser when the source was merged into another user already
+var ErrAlreadyMerged = errors.New("user already merged into another user")
+
+// maxAliasHops bounds how many merges ResolveAlias follows: a user merged into one merged later
+// resolves through both
+const maxAliasHops = 8
+
+// UserMerge is the audit record of one merge: the source as it was, and which of its lookups
+// now find the surviving user (Moved) or were freed because that user has its own (Released)
+type UserMerge struct {
+	UserID         gocql.UUID `db:"user_id" json:"user_id"`
+	ID             gocql.UUID `db:"id" json:"id"`
+	SourceID       gocql.UUID `db:"source_id" json:"source_id"`
+	SourceUsername string     `db:"source_username" json:"source_username,omitempty"`
+	SourceEmail    string     `db:"source_email" json:"source_email,omitempty"`
+	SourcePhone    string     `db:"source_phone" json:"source_phone,omitempty"`
+	Moved          []string   `db:"moved" json:"moved,omitempty"`
+	Released       []string   `db:"released" json:"released,omitempty"`
+	Reason         string     `db:"reason" json:"reason,omitempty"`
+	MergedAt       time.Time  `db:"merged_at" json:"merged_at"`
+}
+
+// mergeLookup is a lookup a merge moves: its table and the field of models.User it indexes
+type mergeLookup struct {
+	name  string
+	table *table.Table
+	taken error
+	field func(*models.User) *string
+}
+
+var mergeLookups = []mergeLookup{
+	{"email", UsersByEmailTable, ErrEmailTaken, func(u *models.User) *string { return &u.Email }},
+	{"username", UsersByUsernameTable, ErrUsernameTaken, func(u *models.User) *string { return &u.Username }},
+	{"phone", UsersByPhoneTable, ErrPhoneTaken, func(u *models.User) *string { return &u.Phone }},
+}
+
+// MergeUser folds source, typically a duplicate created by another sign-in method, into target:
+//   - source's ID becomes an alias of target's, failing with ErrAlreadyMerged when it is one of
+//     another user's already
+//   - each lookup of source that target lacks (or shares) is moved to target, and target adopts
+//     the value; the lookups target has its own value for are released after the merge
+//   - source's users and users_by_created rows are deleted and the merge recorded in user_merges,
+//     in one logged batch
+//
+// Steps before the batch are undone when a later one fails. Source's preferences, attributes and
+// login stats are not carried over; target's are kept
+func (r *UserRepository) MergeUser(ctx context.Context, source, target *models.User, reason string) (*UserMerge, error) {
+	now := time.Now()
+	merge := &UserMerge{
+		UserID:         target.ID,
+		ID:             gocql.UUIDFromTime(now),
+		SourceID:       source.ID,
+		SourceUsername: source.Username,
+		SourceEmail:    source.Email,
+		SourcePhone:    source.Phone,
+		Reason:         reason,
+		MergedAt:       now,
+	}
+	uow := r.NewUnitOfWork()
+
+	if err := r.createAlias(ctx, source.ID, target.ID, now); err != nil {
+		return nil, err
+	}
+	uow.Compensate(UserAliasesTable.Name(), func(ctx context.Context) error {
+		return r.deleteAlias(ctx, source.ID, target.ID)
+	})
+
+	merged := *target
+	var release []mergeLookup
+	for _, lookup := range mergeLookups {
+		value := *lookup.field(source)
+		if value == "" {
+			continue
+		}
+		if current := *lookup.field(target); current != "" && current != value {
+			release = append(release, lookup)
+			continue
+		}
+		moved, err := r.moveLookup(ctx, uow, lookup, value, source.ID, target.ID)
+		if err != nil {
+			uow.Rollback(ctx)
+			return nil, err
+		}
+		if moved {
+			*lookup.field(&merged) = value
+			merge.Moved = append(merge.Moved, lookup.name)
+		}
+	}
+
+	if merged.Email != target.Email || merged.Username != target.Username || merged.Phone != target.Phone {
+		if err := r.setIdentity(ctx, &merged); err != nil {
+			uow.Rollback(ctx)
+			return nil, err
+		}
+		uow.Compensate(UserTable.Name(), func(ctx context.Context) error {
+			return r.setIdentity(ctx, target)
+		})
+	}
+
+	for _, lookup := range release {
+		merge.Released = append(merge.Released, lookup.name)
+	}
+	uow.Delete(UserTable, map[string]interface{}{"id": source.ID})
+	uow.Delete(UsersByCreatedTable, map[string]interface{}{
+		"shard":      CreatedShard(source.ID),
+		"created_at": source.CreatedAt,
+		"id":         source.ID,
+	})
+	uow.Insert(UserMergesTable, merge)
+	if err := uow.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to merge user %s into %s: %w", source.ID, target.ID, err)
+	}
+
+	// Best effort: a lookup left behind points at a deleted user, so the next claim takes it over
+	for _, lookup := range release {
+		if err := r.releaseLookup(ctx, lookup.table, *lookup.field(source), source.ID); err != nil {
+			log.Printf("[Repository] failed to release %s of merged user %s: %v", lookup.table.Name(), source.ID, err)
+		}
+	}
+
+	*target = merged
+	return merge, nil
+}
+
+// moveLookup points the lookup row of value from source to target. A row that is missing, e.g.
+// for a user created before lookups were indexed, is claimed instead; false means a third user
+// holds value
+func (r *UserRepository) moveLookup(ctx context.Context, uow *UnitOfWork, lookup mergeLookup, value string, source, target gocql.UUID) (bool, error) {
+	moved, err := r.takeOverLookup(ctx, lookup.table, value, source, target)
+	if err != nil {
+		return false, err
+	}
+	if moved {
+		uow.Compensate(lookup.table.Name(), func(ctx context.Context) error {
+			_, err := r.takeOverLookup(ctx, lookup.table, value, target, source)
+			return err
+		})
+		return true, nil
+	}
+
+	err = r.claimLookup(ctx, uow, lookup.table, value, target, lookup.taken,
+		func(owner *models.User) string { return *lookup.field(owner) })
+	if errors.Is(err, lookup.taken) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// setIdentity writes the email, username and phone of user, as long as its row still exists with
+// the same created_at. Writing the same values twice is harmless, so it is retried
+func (r *UserRepository) setIdentity(ctx context.Context, user *models.User) error {
+	stmt, names := qb.Update(UserTable.Name()).
+		Set("email", "username", "phone").
+		Where(qb.Eq("id")).
+		If(qb.Eq("created_at")).
+		ToCql()
+
+	var applied bool
+	err := r.retry.Do(ctx, "SetIdentity", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindStruct(user).
+			WithContext(ctx).Idempotent(true).ExecCASRelease()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update user %s: %w", user.ID, err)
+	}
+	if !applied {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// createAlias makes alias resolve to id. Retrying is safe: an attempt that was applied finds its
+// own row
+func (r *UserRepository) createAlias(ctx context.Context, alias, id gocql.UUID, at time.Time) error {
+	stmt, names := UserAliasesTable.InsertBuilder().Unique().ToCql()
+
+	existing := make(map[string]interface{})
+	var applied bool
+	err := r.retry.Do(ctx, "CreateAlias", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		clear(existing)
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"alias_id":  alias,
+			"user_id":   id,
+			"merged_at": at,
+		}).WithContext(ctx).Idempotent(true).MapScanCAS(existing)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create alias: %w", err)
+	}
+	if owner, _ := existing["user_id"].(gocql.UUID); !applied && owner != id {
+		return ErrAlreadyMerged
+	}
+	return nil
+}
+
+// deleteAlias removes the alias if it still resolves to id
+func (r *UserRepository) deleteAlias(ctx context.Context, alias, id gocql.UUID) error {
+	stmt, names := qb.Delete(UserAliasesTable.Name()).Where(qb.Eq("alias_id")).If(qb.Eq("user_id")).ToCql()
+	return r.retry.Do(ctx, "DeleteAlias", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		_, err := r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"alias_id": alias,
+			"user_id":  id,
+		}).WithContext(ctx).Idempotent(true).MapScanCAS(make(map[string]interface{}))
+		return err
+	})
+}
+
+// ResolveAlias returns the user a merged ID now belongs to, following later merges, and false
+// when id is no alias
+func (r *UserRepository) ResolveAlias(ctx context.Context, id gocql.UUID) (gocql.UUID, bool, error) {
+	stmt, names := qb.Select(UserAliasesTable.Name()).Columns("user_id").Where(qb.Eq("alias_id")).ToCql()
+	if err := r.guard.Statement("ResolveAlias", stmt); err != nil {
+		return id, false, err
+	}
+
+	resolved := id
+	for hop := 0; ; hop++ {
+		var next gocql.UUID
+		err := r.retry.Do(ctx, "ResolveAlias", true, func() error {
+			ctx, cancel := r.timeouts.readContext(ctx)
+			defer cancel()
+			return r.reader().Query(stmt, names).BindMap(map[string]interface{}{
+				"alias_id": resolved,
+			}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy).Scan(&next)
+		})
+		if errors.Is(err, gocql.ErrNotFound) {
+			return resolved, hop > 0, nil
+		}
+		if err != nil {
+			return id, false, fmt.Errorf("failed to resolve alias %s: %w", resolved, err)
+		}
+		if hop == maxAliasHops {
+			return id, false, fmt.Errorf("alias %s is merged more than %d times", id, maxAliasHops)
+		}
+		resolved = next
+	}
+}
+
+// ListMerges returns up to limit merges into a user, newest first
+func (r *UserRepository) ListMerges(ctx context.Context, id gocql.UUID, limit int) ([]UserMerge, error) {
+	if err := r.guard.PageSize("ListMerges", limit); err != nil {
+		return nil, err
+	}
+	stmt, names := qb.Select(UserMergesTable.Name()).Where(qb.Eq("user_id")).Limit(uint(limit)).ToCql()
+	if err := r.guard.Statement("ListMerges", stmt); err != nil {
+		return nil, err
+	}
+
+	var merges []UserMerge
+	err := r.retry.Do(ctx, "ListMerges", true, func() error {
+		merges = nil
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		return r.reader().Query(stmt, names).BindMap(map[string]interface{}{
+			"user_id": id,
+		}).WithContext(ctx).Idempotent(true).SelectRelease(&merges)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merges of user %s: %w", id, err)
+	}
+	return merges, nil
+}
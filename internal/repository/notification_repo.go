@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"acid/internal/models"
+	"context"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+var NotificationTable = table.New(table.Metadata{
+	Name: "user_notifications",
+	Columns: []string{
+		"user_id", "id", "kind", "channel", "status",
+		"attempts", "last_error", "created_at", "updated_at",
+	},
+	PartKey: []string{"user_id"},
+	SortKey: []string{"id"},
+})
+
+// NotificationColumnTypes are the CQL types models.Notification is marshaled to
+var NotificationColumnTypes = map[string]string{
+	"user_id":    "uuid",
+	"id":         "timeuuid",
+	"kind":       "text",
+	"channel":    "text",
+	"status":     "text",
+	"attempts":   "int",
+	"last_error": "text",
+	"created_at": "timestamp",
+	"updated_at": "timestamp",
+}
+
+type NotificationRepository struct {
+	session gocqlx.Session
+	retry   *Retryer
+	guard   *Guardrails
+
+	// readSession returns the session of reads when set; see UserRepository.SetReadSession
+	readSession func() gocqlx.Session
+
+	// readPolicy is applied to idempotent reads; non-speculative by default
+	readPolicy gocql.SpeculativeExecutionPolicy
+
+	// readRetryPolicy replaces the cluster's retry policy on reads when set
+	readRetryPolicy gocql.RetryPolicy
+
+	timeouts *QueryTimeouts
+
+	// ttl is written as USING TTL when set, overriding the table's default_time_to_live
+	ttl time.Duration
+}
+
+func NewNotificationRepository(session gocqlx.Session) *NotificationRepository {
+	return &NotificationRepository{
+		session:    session,
+		retry:      NewRetryer(nil),
+		guard:      NewGuardrails(nil),
+		readPolicy: &gocql.NonSpeculativeExecution{},
+		timeouts:   &QueryTimeouts{},
+	}
+}
+
+// SetRetryer replaces the default retryer, e.g. to share config and metrics across repositories
+func (r *NotificationRepository) SetRetryer(retry *Retryer) {
+	r.retry = retry
+}
+
+// SetGuardrails replaces the default guardrails, e.g. to share config and metrics across repositories
+func (r *NotificationRepository) SetGuardrails(guard *Guardrails) {
+	r.guard = guard
+}
+
+// SetReadSpeculativeExecution sets the speculative execution policy used for reads
+func (r *NotificationRepository) SetReadSpeculativeExecution(policy gocql.SpeculativeExecutionPolicy) {
+	r.readPolicy = policy
+}
+
+// SetReadRetryPolicy sets the driver retry policy used for reads, e.g. a DowngradingRetryPolicy
+func (r *NotificationRepository) SetReadRetryPolicy(policy gocql.RetryPolicy) {
+	r.readRetryPolicy = policy
+}
+
+// SetReadSession routes reads through session, e.g. db.ScyllaDB.ReadSession
+func (r *NotificationRepository) SetReadSession(session func() gocqlx.Session) {
+	r.readSession = session
+}
+
+func (r *NotificationRepository) reader() gocqlx.Session {
+	if r.readSession != nil {
+		return r.readSession()
+	}
+	return r.session
+}
+
+// SetQueryTimeouts sets per-statement timeouts; each retry attempt gets the full timeout
+func (r *NotificationRepository) SetQueryTimeouts(timeouts *QueryTimeouts) {
+	r.timeouts = timeouts
+}
+
+// SetTTL overrides the default_time_to_live of user_notifications for the rows this repository
+// writes; zero keeps the table default
+func (r *NotificationRepository) SetTTL(ttl time.Duration) {
+	r.ttl = ttl
+}
+
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	stmt, names := NotificationTable.Insert()
+	if r.ttl > 0 {
+		stmt, names = NotificationTable.InsertBuilder().TTL(r.ttl).ToCql()
+	}
+	return r.retry.Do(ctx, "CreateNotification", false, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		q := r.session.Query(stmt, names).WithContext(ctx).BindStruct(notification)
+		return q.ExecRelease()
+	})
+}
+
+// UpdateStatus records the outcome of a delivery attempt
+func (r *NotificationRepository) UpdateStatus(ctx context.Context, notification *models.Notification) error {
+	notification.UpdatedAt = time.Now()
+	stmt, names := NotificationTable.Update("status", "attempts", "last_error", "updated_at")
+	if r.ttl > 0 {
+		// Updated cells get a TTL of their own; without it they would take the table default
+		stmt, names = NotificationTable.UpdateBuilder("status", "attempts", "last_error", "updated_at").TTL(r.ttl).ToCql()
+	}
+	return r.retry.Do(ctx, "UpdateStatus", false, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		q := r.session.Query(stmt, names).WithContext(ctx).BindStruct(notification)
+		return q.ExecRelease()
+	})
+}
+
+// ListByUser returns a user's notifications, newest first. It reads the user's whole partition,
+// so it is checked against the partition guardrail
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID gocql.UUID) ([]models.Notification, error) {
+	stmt, names := NotificationTable.Select()
+	if limit := r.guard.partitionLimit(); limit > 0 {
+		stmt, names = NotificationTable.SelectBuilder().Limit(limit).ToCql()
+	}
+	if err := r.guard.Statement("ListByUser", stmt); err != nil {
+		return nil, err
+	}
+
+	var notifications []models.Notification
+	err := r.retry.Do(ctx, "ListByUser", true, func() error {
+		notifications = nil
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := r.reader().Query(stmt, names).BindMap(map[string]interface{}{
+			"user_id": userID,
+		}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.SelectRelease(&notifications)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.guard.Partition("ListByUser", userID, len(notifications)); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
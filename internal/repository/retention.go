@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"acid/db"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NotificationRetention is the retention migration 000010 gives user_notifications: 90 days in
+// 3-day windows. Rows only expire and are never deleted, so tombstones are kept one day
+var NotificationRetention = db.Retention{
+	Window:     3 * 24 * time.Hour,
+	DefaultTTL: 90 * 24 * time.Hour,
+	GCGrace:    24 * time.Hour,
+}
+
+// Retentions lists the append-only tables by name; only these take a TTL override
+var Retentions = map[string]db.Retention{
+	NotificationTable.Name(): NotificationRetention,
+}
+
+// maxTTL is the largest TTL ScyllaDB accepts, 20 years
+const maxTTL = 630720000 * time.Second
+
+// TableTTLs overrides the default_time_to_live of append-only tables, written as USING TTL on each
+// insert. Keep an override near the table default: TWCS only drops a window's SSTable once all of
+// its rows have expired, so mixing very different TTLs in one table keeps the short-lived rows on
+// disk as long as the long-lived ones
+type TableTTLs map[string]time.Duration
+
+// ParseTableTTLs parses "table=duration" pairs separated by commas, e.g.
+// "user_notifications=720h". Unlike sampling rates a bad entry is an error: a mistyped TTL
+// silently keeping data for the wrong time is worse than a failed start
+func ParseTableTTLs(raw string) (TableTTLs, error) {
+	ttls := make(TableTTLs)
+	for _, entry := range strings.Split(raw, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, ttlText, found := strings.Cut(strings.TrimSpace(entry), "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("%q is not table=duration", entry)
+		}
+		if _, ok := Retentions[name]; !ok {
+			return nil, fmt.Errorf("%s is not an append-only table, want one of %s", name, strings.Join(retentionTables(), ", "))
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(ttlText))
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTL of %s: %w", name, err)
+		}
+		if ttl < time.Second || ttl > maxTTL {
+			return nil, fmt.Errorf("TTL of %s must be between 1s and %s", name, maxTTL)
+		}
+		ttls[name] = ttl
+	}
+	return ttls, nil
+}
+
+// For returns the override of a table, zero to use its default_time_to_live
+func (t TableTTLs) For(table string) time.Duration {
+	return t[table]
+}
+
+func retentionTables() []string {
+	names := make([]string, 0, len(Retentions))
+	for name := range Retentions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
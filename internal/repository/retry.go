@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"acid/internal/debugtrace"
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// RetryConfig holds repository-level retry configuration
+type RetryConfig struct {
+	// MaxAttempts includes the first try; 1 disables retries
+	MaxAttempts int
+
+	// InitialBackoff is the base delay before the first retry, doubled on each further retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryConfig returns sensible production defaults
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     1 * time.Second,
+	}
+}
+
+// RetryMetrics tracks attempts and outcomes for observability
+type RetryMetrics struct {
+	Attempts    atomic.Int64 // Every execution, including first tries
+	Retries     atomic.Int64 // Attempts after the first
+	Timeouts    atomic.Int64 // Attempts that failed with a read/write timeout
+	Unavailable atomic.Int64 // Attempts that failed because replicas or connections were unavailable
+	Recovered   atomic.Int64 // Operations that succeeded after at least one retry
+	Exhausted   atomic.Int64 // Operations that still failed after MaxAttempts
+	Skipped     atomic.Int64 // Retryable failures not retried because the statement isn't idempotent
+}
+
+// Retryer re-runs idempotent statements that failed with a transient error
+// Non-idempotent statements run once: after a timeout the write may already have been applied
+type Retryer struct {
+	config  *RetryConfig
+	metrics *RetryMetrics
+}
+
+func NewRetryer(config *RetryConfig) *Retryer {
+	if config == nil {
+		config = DefaultRetryConfig()
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	return &Retryer{
+		config:  config,
+		metrics: &RetryMetrics{},
+	}
+}
+
+// Do runs fn, retrying with jittered exponential backoff when idempotent is true and the
+// error is a timeout or unavailable error. fn must build a fresh query on every call; ctx only
+// carries the request's debug trace, fn applies its own deadline
+func (r *Retryer) Do(ctx context.Context, op string, idempotent bool, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			r.metrics.Retries.Add(1)
+			debugtrace.FromContext(ctx).AddRetry()
+			time.Sleep(r.backoff(attempt - 1))
+		}
+
+		r.metrics.Attempts.Add(1)
+		err = fn()
+		if err == nil {
+			if attempt > 1 {
+				r.metrics.Recovered.Add(1)
+			}
+			return nil
+		}
+
+		if !r.classify(err) {
+			return err
+		}
+		if !idempotent {
+			r.metrics.Skipped.Add(1)
+			return err
+		}
+	}
+
+	r.metrics.Exhausted.Add(1)
+	log.Printf("[Repository] %s failed after %d attempts: %v", op, r.config.MaxAttempts, err)
+	return err
+}
+
+// classify records the failure and reports whether it is transient
+func (r *Retryer) classify(err error) bool {
+	switch {
+	case isTimeout(err):
+		r.metrics.Timeouts.Add(1)
+		return true
+	case isUnavailable(err):
+		r.metrics.Unavailable.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+// IsUnavailable reports whether err means ScyllaDB couldn't answer (timeouts, missing replicas,
+// no connections) rather than that the query failed or found nothing
+func IsUnavailable(err error) bool {
+	return isTimeout(err) || isUnavailable(err)
+}
+
+func isTimeout(err error) bool {
+	var readTimeout *gocql.RequestErrReadTimeout
+	var writeTimeout *gocql.RequestErrWriteTimeout
+	return errors.As(err, &readTimeout) || errors.As(err, &writeTimeout) ||
+		errors.Is(err, gocql.ErrTimeoutNoResponse) ||
+		errors.Is(err, context.DeadlineExceeded) // A per-statement QueryTimeouts deadline
+}
+
+func isUnavailable(err error) bool {
+	var unavailable *gocql.RequestErrUnavailable
+	return errors.As(err, &unavailable) ||
+		errors.Is(err, gocql.ErrNoConnections) ||
+		errors.Is(err, gocql.ErrConnectionClosed) ||
+		errors.Is(err, gocql.ErrNoStreams) ||
+		errors.Is(err, gocql.ErrHostDown)
+}
+
+// backoff returns a full-jitter delay for the given retry number
+func (r *Retryer) backoff(retry int) time.Duration {
+	delay := r.config.InitialBackoff << (retry - 1)
+	if delay <= 0 || delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(delay))) + 1
+}
+
+func (r *Retryer) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"attempts":    r.metrics.Attempts.Load(),
+		"retries":     r.metrics.Retries.Load(),
+		"timeouts":    r.metrics.Timeouts.Load(),
+		"unavailable": r.metrics.Unavailable.Load(),
+		"recovered":   r.metrics.Recovered.Load(),
+		"exhausted":   r.metrics.Exhausted.Load(),
+		"skipped":     r.metrics.Skipped.Load(),
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"acid/internal/models"
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// TokenRange is the half-open slice (Start, End] of the Murmur3 token ring
+type TokenRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// SplitTokenRing divides the full Murmur3 ring into n contiguous ranges of (nearly) equal width
+// Scanning each range separately lets full-table jobs run in parallel and resume per range
+func SplitTokenRing(n int) []TokenRange {
+	if n <= 0 {
+		n = 1
+	}
+
+	lo, hi := big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)
+	width := new(big.Int).Sub(hi, lo)
+	width.Div(width, big.NewInt(int64(n)))
+
+	ranges := make([]TokenRange, n)
+	start := new(big.Int).Set(lo)
+	for i := range ranges {
+		end := new(big.Int).Add(start, width)
+		if i == n-1 {
+			end.Set(hi)
+		}
+		ranges[i] = TokenRange{Start: start.Int64(), End: end.Int64()}
+		start = end
+	}
+	return ranges
+}
+
+// scanUsersStmt pages through one token range; token(id) is returned so callers can checkpoint
+const scanUsersStmt = `SELECT token(id), id, username, email, phone, created_at, preferences, attributes FROM users WHERE token(id) > ? AND token(id) <= ?`
+
+// ScanUsers calls fn for every user whose token falls in rng, in token order, with that token
+// A non-nil error from fn stops the scan and is returned as is. Pages are fetched one at a time
+// so the scan timeout bounds each page rather than the whole range
+func (r *UserRepository) ScanUsers(ctx context.Context, rng TokenRange, pageSize int, fn func(token int64, user *models.User) error) error {
+	if err := r.guard.PageSize("ScanUsers", pageSize); err != nil {
+		return err
+	}
+	if err := r.guard.Statement("ScanUsers", scanUsersStmt); err != nil {
+		return err
+	}
+	var pageState []byte
+	for {
+		next, err := r.scanUsersPage(ctx, rng, pageSize, pageState, fn)
+		if err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		pageState = next
+	}
+}
+
+// scanUsersPage scans one page and returns the state of the next, empty after the last page
+func (r *UserRepository) scanUsersPage(ctx context.Context, rng TokenRange, pageSize int, pageState []byte, fn func(token int64, user *models.User) error) ([]byte, error) {
+	pageCtx, cancel := r.timeouts.scanContext(ctx)
+	defer cancel()
+
+	iter := r.session.Session.Query(scanUsersStmt, rng.Start, rng.End).
+		WithContext(pageCtx).
+		PageSize(pageSize).
+		PageState(pageState).
+		Idempotent(true).
+		Iter()
+	next := iter.PageState()
+
+	for {
+		var token int64
+		user := &models.User{}
+		if !iter.Scan(&token, &user.ID, &user.Username, &user.Email, &user.Phone, &user.CreatedAt, &user.Preferences, &user.Attributes) {
+			break
+		}
+		if err := fn(token, user); err != nil {
+			iter.Close()
+			return nil, err
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to scan token range (%d, %d]: %w", rng.Start, rng.End, err)
+	}
+	return next, nil
+}
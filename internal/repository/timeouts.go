@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"acid/internal/budget"
+	"context"
+	"time"
+)
+
+// QueryTimeouts bound each statement through its context, separately from the driver's
+// connection-level timeout. Zero leaves a kind of statement to the driver timeout alone.
+// Reads and scans also end when the caller's context does
+type QueryTimeouts struct {
+	// Read applies to point reads (GetUserByID, UserExists, ListByUser)
+	Read time.Duration
+
+	// Write applies to inserts, updates and each batch
+	Write time.Duration
+
+	// Scan applies to each page of a token-range scan, not to the whole range
+	Scan time.Duration
+}
+
+func (t *QueryTimeouts) readContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(parent, t.Read)
+}
+
+// writeContext detaches writes from the caller's cancellation, as before statements took a
+// context: a client hanging up must not abandon a write whose follow-up steps (outbox event, cache
+// purge) the caller still runs. Values such as the debug trace are kept, and so is the deadline of
+// a latency budget the service layer set
+func (t *QueryTimeouts) writeContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, release := budget.Detach(parent)
+	ctx, cancel := withTimeout(ctx, t.Write)
+	return ctx, func() {
+		cancel()
+		release()
+	}
+}
+
+func (t *QueryTimeouts) scanContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(parent, t.Scan)
+}
+
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// UnitOfWork groups the writes of one operation, e.g. a user row with its lookup claims, into a
+// single batch. The outbox event of a created user isn't part of it: UserService publishes that
+// in a later step of its saga. Writes spanning partitions go in a logged batch, so they are all
+// applied or none; writes to one partition use an unlogged batch, atomic there without the
+// batchlog round trip.
+//
+// Conditional writes can't join a batch spanning partitions, so uniqueness is checked before
+// Commit by claims: lightweight transactions run right away, each registering a compensation
+// that undoes it. Rollback runs them when a later claim or the batch itself fails
+type UnitOfWork struct {
+	session  gocqlx.Session
+	retry    *Retryer
+	timeouts *QueryTimeouts
+
+	batch         *gocqlx.Batch
+	ops           []string
+	partitions    map[string]struct{}
+	compensations []compensation
+	err           error
+}
+
+type compensation struct {
+	op   string
+	undo func(context.Context) error
+}
+
+// NewUnitOfWork starts an empty unit of work on the repository's session
+func (r *UserRepository) NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{
+		session:    r.session,
+		retry:      r.retry,
+		timeouts:   r.timeouts,
+		batch:      r.session.Batch(gocql.LoggedBatch),
+		partitions: make(map[string]struct{}),
+	}
+}
+
+// Insert adds an insert into t to the batch; arg is a struct bound like BindStruct or a
+// map[string]interface{} bound like BindMap. A bind error is returned by Commit
+func (u *UnitOfWork) Insert(t *table.Table, arg interface{}) {
+	if u.err != nil {
+		return
+	}
+	stmt, names := t.Insert()
+	u.add(t, stmt, names, arg)
+}
+
+// Delete adds a delete of the row of t whose primary key arg holds, bound like Insert
+func (u *UnitOfWork) Delete(t *table.Table, arg interface{}) {
+	if u.err != nil {
+		return
+	}
+	stmt, names := t.Delete()
+	u.add(t, stmt, names, arg)
+}
+
+func (u *UnitOfWork) add(t *table.Table, stmt string, names []string, arg interface{}) {
+	query := u.session.Query(stmt, names)
+	defer query.Release()
+
+	var err error
+	if row, ok := arg.(map[string]interface{}); ok {
+		err = u.batch.BindMap(query, row)
+	} else {
+		err = u.batch.BindStruct(query, arg)
+	}
+	if err != nil {
+		u.err = fmt.Errorf("failed to bind %s: %w", t.Name(), err)
+		return
+	}
+
+	args := u.batch.Entries[len(u.batch.Entries)-1].Args
+	partition := t.Name()
+	for _, column := range t.Metadata().PartKey {
+		partition += fmt.Sprintf("/%v", args[slices.Index(names, column)])
+	}
+	u.partitions[partition] = struct{}{}
+	u.ops = append(u.ops, t.Name())
+}
+
+// Compensate registers how to undo a write already applied outside the batch, such as a claim.
+// Compensations run newest first
+func (u *UnitOfWork) Compensate(op string, undo func(context.Context) error) {
+	u.compensations = append(u.compensations, compensation{op: op, undo: undo})
+}
+
+// Commit executes the batch. Every write in it is an insert of a row no client has seen yet or a
+// delete, so re-applying it is harmless and it is retried. When it definitely failed the claims
+// are rolled back; after a timeout they are kept, since a logged batch may still be replayed from
+// the batchlog and its rows would then lack their claims
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	if u.err != nil {
+		u.Rollback(ctx)
+		return u.err
+	}
+	if len(u.batch.Entries) == 0 {
+		return nil
+	}
+	if len(u.partitions) == 1 {
+		u.batch.Type = gocql.UnloggedBatch
+	}
+
+	err := u.retry.Do(ctx, "UnitOfWork", true, func() error {
+		batchCtx, cancel := u.timeouts.writeContext(ctx)
+		defer cancel()
+		return u.session.ExecuteBatch(u.batch.WithContext(batchCtx))
+	})
+	if err == nil {
+		return nil
+	}
+	if !isTimeout(err) {
+		u.Rollback(ctx)
+	}
+	return fmt.Errorf("failed to write %v: %w", u.ops, err)
+}
+
+// Rollback undoes what was claimed so far. Compensations run even when ctx is canceled, and a
+// failing one is logged and skipped: the claim it leaves is taken over once stale
+func (u *UnitOfWork) Rollback(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(u.compensations) - 1; i >= 0; i-- {
+		c := u.compensations[i]
+		if err := c.undo(ctx); err != nil {
+			log.Printf("[Repository] compensation %s failed: %v", c.op, err)
+		}
+	}
+	u.compensations = nil
+}
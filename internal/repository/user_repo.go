@@ -2,37 +2,174 @@ package repository
 
 import (
 	"acid/internal/models"
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
 	"github.com/scylladb/gocqlx/v3/table"
 )
 
+// ErrUserNotFound is returned when a write targets a user that doesn't exist
+var ErrUserNotFound = errors.New("user not found")
+
 var UserTable = table.New(table.Metadata{
 	Name:    "users",
-	Columns: []string{"id", "username", "email", "created_at"},
+	Columns: []string{"id", "username", "email", "phone", "created_at", "preferences", "attributes"},
 	PartKey: []string{"id"},
 	SortKey: []string{},
 })
 
+// UserColumnTypes are the CQL types models.User is marshaled to, checked against the live table at startup
+var UserColumnTypes = map[string]string{
+	"id":          "uuid",
+	"username":    "text",
+	"email":       "text",
+	"phone":       "text",
+	"created_at":  "timestamp",
+	"preferences": "map<text, boolean>",
+	"attributes":  "map<text, text>",
+}
+
 type UserRepository struct {
 	session gocqlx.Session
+	retry   *Retryer
+	guard   *Guardrails
+
+	// readSession returns the session of reads when set, e.g. a standby cluster's while the
+	// primary is down
+	readSession func() gocqlx.Session
+
+	// readPolicy is applied to idempotent reads; non-speculative by default
+	readPolicy gocql.SpeculativeExecutionPolicy
+
+	// readRetryPolicy replaces the cluster's retry policy on reads when set
+	readRetryPolicy gocql.RetryPolicy
+
+	timeouts *QueryTimeouts
+
+	batch *BatchConfig
 }
 
 func NewUserRepository(session gocqlx.Session) *UserRepository {
-	return &UserRepository{session: session}
+	return &UserRepository{
+		session:    session,
+		retry:      NewRetryer(nil),
+		guard:      NewGuardrails(nil),
+		readPolicy: &gocql.NonSpeculativeExecution{},
+		timeouts:   &QueryTimeouts{},
+		batch:      DefaultBatchConfig(),
+	}
+}
+
+// SetRetryer replaces the default retryer, e.g. to share config and metrics across repositories
+func (r *UserRepository) SetRetryer(retry *Retryer) {
+	r.retry = retry
+}
+
+// SetGuardrails replaces the default guardrails, e.g. to share config and metrics across repositories
+func (r *UserRepository) SetGuardrails(guard *Guardrails) {
+	r.guard = guard
+}
+
+// SetReadSpeculativeExecution sets the speculative execution policy used for reads
+func (r *UserRepository) SetReadSpeculativeExecution(policy gocql.SpeculativeExecutionPolicy) {
+	r.readPolicy = policy
+}
+
+// SetReadRetryPolicy sets the driver retry policy used for reads, e.g. a DowngradingRetryPolicy
+func (r *UserRepository) SetReadRetryPolicy(policy gocql.RetryPolicy) {
+	r.readRetryPolicy = policy
+}
+
+// SetReadSession routes reads serving requests through session, e.g. db.ScyllaDB.ReadSession so
+// they use the read consistency and pool, or fail over to a standby cluster. Writes, and reads
+// made while writing (which must see the latest write), keep the session the repository was
+// created with
+func (r *UserRepository) SetReadSession(session func() gocqlx.Session) {
+	r.readSession = session
+}
+
+// reader returns the session of reads serving requests
+func (r *UserRepository) reader() gocqlx.Session {
+	if r.readSession != nil {
+		return r.readSession()
+	}
+	return r.session
+}
+
+// SetQueryTimeouts sets per-statement timeouts; each retry attempt gets the full timeout
+func (r *UserRepository) SetQueryTimeouts(timeouts *QueryTimeouts) {
+	r.timeouts = timeouts
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
-	q := r.session.Query(UserTable.Insert()).BindStruct(user)
-	if err := q.ExecRelease(); err != nil {
+// CreateUser is not retried: a plain INSERT that timed out may already have been applied
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	return r.retry.Do(ctx, "CreateUser", false, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		q := r.session.Query(UserTable.Insert()).WithContext(ctx).BindStruct(user)
+		return q.ExecRelease()
+	})
+}
+
+// ErrUserExists is returned by CreateUserIfNotExists when the ID is taken
+var ErrUserExists = errors.New("user already exists")
+
+// CreateUserIfNotExists inserts a user whose ID was supplied by the client, with IF NOT EXISTS so
+// an existing user is never overwritten. Unlike CreateUser it is retried: when a timed-out attempt
+// was applied, the retry finds the row it wrote (same created_at) and reports success
+func (r *UserRepository) CreateUserIfNotExists(ctx context.Context, user *models.User) error {
+	stmt, names := UserTable.InsertBuilder().Unique().ToCql()
+
+	var applied bool
+	var existing models.User
+	err := r.retry.Do(ctx, "CreateUserIfNotExists", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindStruct(user).
+			WithContext(ctx).Idempotent(true).GetCASRelease(&existing)
 		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	// Timestamps are stored with millisecond precision
+	if !applied && !existing.CreatedAt.Equal(user.CreatedAt.Truncate(time.Millisecond)) {
+		return ErrUserExists
 	}
 	return nil
 }
 
-func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
+// deleteUserIfCreatedAt undoes CreateUserIfNotExists: the row is only deleted while it still has
+// the user's created_at, so a user created by someone else is never removed
+func (r *UserRepository) deleteUserIfCreatedAt(ctx context.Context, user *models.User) error {
+	stmt, names := UserTable.DeleteBuilder().If(qb.Eq("created_at")).ToCql()
+	return r.retry.Do(ctx, "DeleteUserIfCreatedAt", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+		_, err := r.session.Query(stmt, names).BindStruct(user).
+			WithContext(ctx).Idempotent(true).MapScanCAS(make(map[string]interface{}))
+		return err
+	})
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return r.getUser(ctx, "GetUserByID", r.reader(), id)
+}
+
+// GetUserForWrite reads a user from the session writes go to, for reads made while writing
+// that must see the latest write, e.g. the row a conditional update compares against
+func (r *UserRepository) GetUserForWrite(ctx context.Context, id string) (*models.User, error) {
+	return r.getUser(ctx, "GetUserForWrite", r.session, id)
+}
+
+func (r *UserRepository) getUser(ctx context.Context, op string, session gocqlx.Session, id string) (*models.User, error) {
 	var user models.User
 
 	// Convert string ID to UUID
@@ -41,13 +178,175 @@ func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 		return nil, fmt.Errorf("invalid UUID format: %w", err)
 	}
 
-	q := r.session.Query(UserTable.Get()).BindMap(map[string]interface{}{
-		"id": uuid,
+	err = r.retry.Do(ctx, op, true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := session.Query(UserTable.Get()).BindMap(map[string]interface{}{
+			"id": uuid,
+		}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.GetRelease(&user)
 	})
-
-	if err := q.GetRelease(&user); err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	return &user, nil
 }
+
+// UserExists reports whether a user row exists, reading only its partition key
+func (r *UserRepository) UserExists(ctx context.Context, id string) (bool, error) {
+	return r.userExists(ctx, "UserExists", r.reader(), id)
+}
+
+// UserExistsForWrite is UserExists on the session writes go to, for checks guarding a write
+func (r *UserRepository) UserExistsForWrite(ctx context.Context, id string) (bool, error) {
+	return r.userExists(ctx, "UserExistsForWrite", r.session, id)
+}
+
+func (r *UserRepository) userExists(ctx context.Context, op string, session gocqlx.Session, id string) (bool, error) {
+	uuid, err := gocql.ParseUUID(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid UUID format: %w", err)
+	}
+
+	stmt, names := qb.Select(UserTable.Name()).Columns("id").Where(qb.Eq("id")).ToCql()
+	if err := r.guard.Statement(op, stmt); err != nil {
+		return false, err
+	}
+	var user models.User
+	err = r.retry.Do(ctx, op, true, func() error {
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id": uuid,
+		}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.GetRelease(&user)
+	})
+	if errors.Is(err, gocql.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MaxMultiGet is the most IDs GetUsersByIDs reads per query, below Scylla's default
+// max_partition_key_restrictions_per_query of 100
+const MaxMultiGet = 100
+
+// GetUsersByIDs reads up to MaxMultiGet users with one IN query; IDs without a row are simply
+// missing from the result. The coordinator fans out to the replicas, so callers reading more
+// should split the IDs and run the queries concurrently
+func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []gocql.UUID) ([]*models.User, error) {
+	if len(ids) > MaxMultiGet {
+		return nil, fmt.Errorf("multi-get of %d users exceeds %d", len(ids), MaxMultiGet)
+	}
+
+	stmt, names := qb.Select(UserTable.Name()).Columns(UserTable.Metadata().Columns...).Where(qb.In("id")).ToCql()
+	if err := r.guard.Statement("GetUsersByIDs", stmt); err != nil {
+		return nil, err
+	}
+	var users []*models.User
+	err := r.retry.Do(ctx, "GetUsersByIDs", true, func() error {
+		users = nil
+		ctx, cancel := r.timeouts.readContext(ctx)
+		defer cancel()
+		q := r.reader().Query(stmt, names).BindMap(map[string]interface{}{
+			"id": ids,
+		}).WithContext(ctx).Idempotent(true).SetSpeculativeExecutionPolicy(r.readPolicy)
+		if r.readRetryPolicy != nil {
+			q.RetryPolicy(r.readRetryPolicy)
+		}
+		return q.SelectRelease(&users)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %d users: %w", len(ids), err)
+	}
+	return users, nil
+}
+
+// MergePreferences merges entries into the user's preferences map
+// Uses IF EXISTS so updating an unknown ID never creates a partial row; merging the same
+// entries twice gives the same map, so the LWT is safe to retry
+func (r *UserRepository) MergePreferences(ctx context.Context, id gocql.UUID, preferences map[string]bool) error {
+	stmt, names := qb.Update(UserTable.Name()).
+		Add("preferences").
+		Where(qb.Eq("id")).
+		Existing().
+		ToCql()
+
+	var applied bool
+	err := r.retry.Do(ctx, "MergePreferences", true, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id":          id,
+			"preferences": preferences,
+		}).WithContext(ctx).Idempotent(true).ExecCASRelease()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+	if !applied {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ErrAttributesChanged is returned by ReplaceAttributes when the stored attributes are no longer
+// the ones the new value was computed from
+var ErrAttributesChanged = errors.New("attributes changed concurrently")
+
+// ReplaceAttributes stores attributes if the user still has previous, so a merge computed and
+// validated from previous can't overwrite a concurrent one. The created_at condition keeps a
+// missing user from being created as a partial row. Not retried: a timed-out LWT may have been
+// applied, and repeating it would then report a conflict
+func (r *UserRepository) ReplaceAttributes(ctx context.Context, user *models.User, previous, attributes models.Attributes) error {
+	stmt, names := qb.Update(UserTable.Name()).
+		Set("attributes").
+		Where(qb.Eq("id")).
+		If(qb.Eq("created_at"), qb.EqNamed("attributes", "previous")).
+		ToCql()
+
+	// Empty maps are stored as null, so compare and write them as null
+	if len(previous) == 0 {
+		previous = nil
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+
+	var applied bool
+	err := r.retry.Do(ctx, "ReplaceAttributes", false, func() error {
+		ctx, cancel := r.timeouts.writeContext(ctx)
+		defer cancel()
+
+		var err error
+		applied, err = r.session.Query(stmt, names).BindMap(map[string]interface{}{
+			"id":         user.ID,
+			"created_at": user.CreatedAt,
+			"previous":   previous,
+			"attributes": attributes,
+		}).WithContext(ctx).ExecCASRelease()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update attributes: %w", err)
+	}
+	if !applied {
+		return ErrAttributesChanged
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package safego
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHandlers is the name panics in gRPC handlers are counted under
+const grpcHandlers = "grpc.handler"
+
+// UnaryServerInterceptor turns a panic in a handler into an INTERNAL error, logged and counted
+// like a goroutine's. gRPC runs handlers on goroutines of its own, so without it a handler's
+// panic takes the process down
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				statsFor(grpcHandlers).recordPanic(grpcHandlers, r, zap.String("method", info.FullMethod))
+				resp, err = nil, status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				statsFor(grpcHandlers).recordPanic(grpcHandlers, r, zap.String("method", info.FullMethod))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,263 @@
+// Package safego starts background goroutines that survive their own panics. A panic is
+// recovered, logged with its stack and counted under the goroutine's name; goroutines started
+// with Restart run again after a backoff, the others end as if they had returned. The gRPC
+// interceptors do the same for handlers, which run on gRPC's goroutines
+package safego
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config is the restart policy of goroutines started with Restart
+type Config struct {
+	// InitialBackoff is the wait before the first restart; it doubles with every panic in a row,
+	// up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// StableAfter is how long a run must last without panicking for the backoff to start over
+	StableAfter time.Duration
+}
+
+// DefaultConfig restarts after 1s, backing off to 30s for a goroutine that keeps panicking
+func DefaultConfig() *Config {
+	return &Config{
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     30 * time.Second,
+		StableAfter:    1 * time.Minute,
+	}
+}
+
+var (
+	config atomic.Pointer[Config]
+	logger atomic.Pointer[zap.Logger]
+
+	mu    sync.Mutex
+	stats = make(map[string]*goroutineStats)
+)
+
+func init() {
+	config.Store(DefaultConfig())
+}
+
+// Configure sets the restart policy and the logger panics are reported to. Until it is called
+// panics go to the standard logger
+func Configure(c *Config, l *zap.Logger) {
+	if c == nil {
+		c = DefaultConfig()
+	}
+	config.Store(c)
+	logger.Store(l)
+}
+
+type goroutineStats struct {
+	started  atomic.Int64
+	running  atomic.Int64
+	panics   atomic.Int64
+	restarts atomic.Int64
+
+	mu          sync.Mutex
+	lastPanic   string
+	lastPanicAt time.Time
+	lastStack   string
+}
+
+// statsFor returns the counters of name; names are fixed strings, so the map stays small
+func statsFor(name string) *goroutineStats {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := stats[name]
+	if !ok {
+		s = &goroutineStats{}
+		stats[name] = s
+	}
+	return s
+}
+
+type options struct {
+	restart bool
+	stop    <-chan struct{}
+	onPanic func(recovered any)
+	onExit  func()
+}
+
+// Option changes how Go runs a goroutine
+type Option func(*options)
+
+// Restart runs fn again after a panic, following the configured backoff, until fn returns or
+// stop is closed; a nil stop restarts for the life of the process. fn must be safe to run
+// again: signals that it has ended (closing a done channel, WaitGroup.Done) belong in OnExit
+func Restart(stop <-chan struct{}) Option {
+	return func(o *options) {
+		o.restart, o.stop = true, stop
+	}
+}
+
+// OnPanic calls handle with the recovered value after the panic is logged, e.g. to fail the
+// request a goroutine was working for, or to shut down when the process can't do without it
+func OnPanic(handle func(recovered any)) Option {
+	return func(o *options) {
+		o.onPanic = handle
+	}
+}
+
+// OnExit calls fn once when the goroutine ends for good: fn returned, or panicked and won't be
+// restarted. It runs after OnPanic
+func OnExit(fn func()) Option {
+	return func(o *options) {
+		o.onExit = fn
+	}
+}
+
+// Go runs fn in a new goroutine named name. A panic in fn is recovered and logged with its stack
+// instead of crashing the process
+func Go(name string, fn func(), opts ...Option) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := statsFor(name)
+	s.started.Add(1)
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Add(-1)
+		if o.onExit != nil {
+			defer o.onExit()
+		}
+
+		var backoff time.Duration
+		for {
+			start := time.Now()
+			if !run(name, s, fn, o.onPanic) || !o.restart {
+				return
+			}
+
+			c := config.Load()
+			if time.Since(start) >= c.StableAfter || backoff == 0 {
+				backoff = c.InitialBackoff
+			} else {
+				backoff = min(backoff*2, c.MaxBackoff)
+			}
+			timer := time.NewTimer(backoff)
+			select {
+			case <-o.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			s.restarts.Add(1)
+			report(func(l *zap.Logger) {
+				l.Info("Restarting goroutine after panic", zap.String("goroutine", name), zap.Duration("backoff", backoff))
+			}, "[safego] Restarting goroutine %s after panic (backoff %v)", name, backoff)
+		}
+	}()
+}
+
+// run calls fn and reports whether it panicked
+func run(name string, s *goroutineStats, fn func(), onPanic func(any)) (panicked bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		panicked = true
+		s.recordPanic(name, r)
+		if onPanic != nil {
+			onPanic(r)
+		}
+	}()
+	fn()
+	return false
+}
+
+// recordPanic counts and logs a panic recovered from the goroutine name
+func (s *goroutineStats) recordPanic(name string, r any, fields ...zap.Field) {
+	stack := string(debug.Stack())
+	s.panics.Add(1)
+	s.mu.Lock()
+	s.lastPanic, s.lastPanicAt, s.lastStack = fmt.Sprint(r), time.Now(), stack
+	s.mu.Unlock()
+	report(func(l *zap.Logger) {
+		l.Error("Goroutine panicked", append(fields, zap.String("goroutine", name), zap.Any("panic", r), zap.String("stack", stack))...)
+	}, "[safego] Goroutine %s panicked: %v\n%s", name, r, stack)
+}
+
+// report logs through the configured logger, or the standard one before Configure
+func report(entry func(*zap.Logger), format string, args ...any) {
+	if l := logger.Load(); l != nil {
+		entry(l)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Status is what happened to the goroutines started under one name
+type Status struct {
+	Name        string     `json:"name"`
+	Started     int64      `json:"started"`
+	Running     int64      `json:"running"`
+	Panics      int64      `json:"panics"`
+	Restarts    int64      `json:"restarts"`
+	LastPanic   string     `json:"last_panic,omitempty"`
+	LastPanicAt *time.Time `json:"last_panic_at,omitempty"`
+	LastStack   string     `json:"last_stack,omitempty"`
+}
+
+// Statuses lists every goroutine name started since the process began, sorted by name
+func Statuses() []Status {
+	mu.Lock()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	mu.Unlock()
+	sort.Strings(names)
+
+	statuses := make([]Status, 0, len(names))
+	for _, name := range names {
+		s := statsFor(name)
+		status := Status{
+			Name:     name,
+			Started:  s.started.Load(),
+			Running:  s.running.Load(),
+			Panics:   s.panics.Load(),
+			Restarts: s.restarts.Load(),
+		}
+		s.mu.Lock()
+		if !s.lastPanicAt.IsZero() {
+			lastPanicAt := s.lastPanicAt
+			status.LastPanic, status.LastPanicAt, status.LastStack = s.lastPanic, &lastPanicAt, s.lastStack
+		}
+		s.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// GetMetrics returns the goroutines running now and the panics and restarts of all of them
+func GetMetrics() map[string]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	var started, running, panics, restarts int64
+	for _, s := range stats {
+		started += s.started.Load()
+		running += s.running.Load()
+		panics += s.panics.Load()
+		restarts += s.restarts.Load()
+	}
+	return map[string]int64{
+		"started":  started,
+		"running":  running,
+		"panics":   panics,
+		"restarts": restarts,
+	}
+}
@@ -0,0 +1,105 @@
+package saga
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// NumShards spreads sagas over partitions; listing walks them in order
+const NumShards = 16
+
+// Statuses of a recorded saga. Sagas that complete or are fully compensated are deleted, so
+// every row is one in flight or one an operator has to look at
+const (
+	StatusRunning      = "running"
+	StatusCompensating = "compensating"
+	StatusFailed       = "failed"
+)
+
+// Table holds the state of sagas in flight and of failed ones. Rows expire through the table's
+// default_time_to_live, so a saga that crashed mid-run doesn't stay forever
+var Table = table.New(table.Metadata{
+	Name: "sagas",
+	Columns: []string{
+		"shard", "id", "kind", "aggregate_id", "step", "status",
+		"last_error", "started_at", "updated_at",
+	},
+	PartKey: []string{"shard"},
+	SortKey: []string{"id"},
+})
+
+// ColumnTypes are the CQL types State is marshaled to
+var ColumnTypes = map[string]string{
+	"shard":        "int",
+	"id":           "timeuuid",
+	"kind":         "text",
+	"aggregate_id": "text",
+	"step":         "text",
+	"status":       "text",
+	"last_error":   "text",
+	"started_at":   "timestamp",
+	"updated_at":   "timestamp",
+}
+
+// State is the persisted progress of a saga: the step it is running or compensating
+type State struct {
+	Shard       int        `db:"shard" json:"-"`
+	ID          gocql.UUID `db:"id" json:"id"`
+	Kind        string     `db:"kind" json:"kind"`
+	AggregateID string     `db:"aggregate_id" json:"aggregate_id"`
+	Step        string     `db:"step" json:"step"`
+	Status      string     `db:"status" json:"status"`
+	LastError   string     `db:"last_error" json:"last_error,omitempty"`
+	StartedAt   time.Time  `db:"started_at" json:"started_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Repository persists saga state
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Save upserts the state of a saga
+func (r *Repository) Save(ctx context.Context, state *State) error {
+	return r.session.Query(Table.Insert()).WithContext(ctx).BindStruct(state).ExecRelease()
+}
+
+// Delete removes a saga that no longer needs attention
+func (r *Repository) Delete(ctx context.Context, state *State) error {
+	return r.session.Query(Table.Delete()).WithContext(ctx).BindStruct(state).ExecRelease()
+}
+
+// FetchAfter returns up to limit sagas of a shard with IDs after after (all when empty), in ID
+// order
+func (r *Repository) FetchAfter(ctx context.Context, shard int, after gocql.UUID, limit uint) ([]State, error) {
+	builder := qb.Select(Table.Name()).Columns(Table.Metadata().Columns...).Where(qb.Eq("shard"))
+	values := map[string]interface{}{"shard": shard}
+	if !after.IsEmpty() {
+		builder.Where(qb.Gt("id"))
+		values["id"] = after
+	}
+	stmt, names := builder.Limit(limit).ToCql()
+
+	var states []State
+	q := r.session.Query(stmt, names).WithContext(ctx).BindMap(values)
+	if err := q.SelectRelease(&states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func shardFor(id gocql.UUID) int {
+	h := fnv.New32a()
+	h.Write(id[:])
+	return int(h.Sum32() % NumShards)
+}
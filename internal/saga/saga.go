@@ -0,0 +1,221 @@
+// Package saga runs operations spanning stores that can't share a transaction, such as a user
+// row, the cache and the event outbox, as a sequence of steps. The step being run is persisted,
+// so a process that dies mid-way leaves a visible record, and when a step fails the completed
+// ones are undone newest first
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// Step is one action of a saga. A failing Do cleans up after itself; Undo reverts a Do that
+// succeeded and is nil when there is nothing to revert, e.g. on the last step
+type Step struct {
+	Name string
+	Do   func(ctx context.Context) error
+	Undo func(ctx context.Context) error
+}
+
+// Config holds saga runner configuration
+type Config struct {
+	// StuckAfter is how long a saga may run or compensate before the admin view lists it
+	StuckAfter time.Duration
+}
+
+// DefaultConfig returns sensible production defaults
+func DefaultConfig() *Config {
+	return &Config{StuckAfter: 1 * time.Minute}
+}
+
+// Metrics counts saga outcomes for observability
+type Metrics struct {
+	Started     atomic.Int64
+	Completed   atomic.Int64
+	Compensated atomic.Int64 // A step failed and every completed step was undone
+	Failed      atomic.Int64 // An undo failed too; the saga is left for an operator
+}
+
+// Runner runs sagas and records their state. A nil repository runs them without recording
+type Runner struct {
+	repo    *Repository
+	config  *Config
+	logger  *zap.Logger
+	metrics *Metrics
+}
+
+func NewRunner(repo *Repository, config *Config, logger *zap.Logger) *Runner {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Runner{
+		repo:    repo,
+		config:  config,
+		logger:  logger,
+		metrics: &Metrics{},
+	}
+}
+
+// Run executes steps in order for an aggregate. If one fails, the completed steps are undone
+// and its error returned. Undos run even when ctx is canceled. A saga that completes or is
+// compensated is deleted; one whose undo failed stays as failed for the admin view
+func (r *Runner) Run(ctx context.Context, kind, aggregateID string, steps ...Step) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	r.metrics.Started.Add(1)
+
+	now := time.Now()
+	state := &State{
+		ID:          gocql.TimeUUID(),
+		Kind:        kind,
+		AggregateID: aggregateID,
+		Step:        steps[0].Name,
+		Status:      StatusRunning,
+		StartedAt:   now,
+		UpdatedAt:   now,
+	}
+	state.Shard = shardFor(state.ID)
+	// Without a record a crash would go unnoticed, so a saga that can't be recorded doesn't start
+	if r.repo != nil {
+		if err := r.repo.Save(ctx, state); err != nil {
+			return fmt.Errorf("failed to record %s saga: %w", kind, err)
+		}
+	}
+
+	for i, step := range steps {
+		if i > 0 {
+			state.Step = step.Name
+			r.save(ctx, state)
+		}
+		if err := step.Do(ctx); err != nil {
+			return r.compensate(context.WithoutCancel(ctx), state, steps[:i], fmt.Errorf("%s: %w", step.Name, err))
+		}
+	}
+
+	r.metrics.Completed.Add(1)
+	r.delete(ctx, state)
+	return nil
+}
+
+// compensate undoes the completed steps newest first and returns cause
+func (r *Runner) compensate(ctx context.Context, state *State, done []Step, cause error) error {
+	log := r.logger.With(zap.String("saga", state.Kind), zap.String("saga_id", state.ID.String()),
+		zap.String("aggregate_id", state.AggregateID))
+	log.Warn("Saga step failed, compensating", zap.String("step", state.Step), zap.Error(cause))
+
+	state.Status = StatusCompensating
+	state.LastError = cause.Error()
+	for i := len(done) - 1; i >= 0; i-- {
+		if done[i].Undo == nil {
+			continue
+		}
+		state.Step = done[i].Name
+		r.save(ctx, state)
+		if err := done[i].Undo(ctx); err != nil {
+			state.Status = StatusFailed
+			state.LastError = fmt.Sprintf("undo %s: %v (after %v)", done[i].Name, err, cause)
+			r.save(ctx, state)
+			r.metrics.Failed.Add(1)
+			log.Error("Saga compensation failed, left for an operator", zap.String("step", done[i].Name), zap.Error(err))
+			return errors.Join(cause, err)
+		}
+	}
+
+	r.metrics.Compensated.Add(1)
+	r.delete(ctx, state)
+	return cause
+}
+
+// save records progress; a failure only loses visibility, so it is logged rather than aborting
+// steps that already ran
+func (r *Runner) save(ctx context.Context, state *State) {
+	if r.repo == nil {
+		return
+	}
+	state.UpdatedAt = time.Now()
+	if err := r.repo.Save(ctx, state); err != nil {
+		r.logger.Warn("Failed to record saga state", zap.String("saga_id", state.ID.String()),
+			zap.String("step", state.Step), zap.Error(err))
+	}
+}
+
+func (r *Runner) delete(ctx context.Context, state *State) {
+	if r.repo == nil {
+		return
+	}
+	if err := r.repo.Delete(context.WithoutCancel(ctx), state); err != nil {
+		// The row expires with the table's TTL; until then it is listed as stuck
+		r.logger.Warn("Failed to delete finished saga", zap.String("saga_id", state.ID.String()), zap.Error(err))
+	}
+}
+
+// Position is where a listing of stuck sagas continues: after saga After of shard Shard, or at
+// the start of Shard when After is empty
+type Position struct {
+	Shard int        `json:"s"`
+	After gocql.UUID `json:"a"`
+}
+
+// scanPage is the number of rows read per query while looking for stuck sagas
+const scanPage = 100
+
+// ListStuck returns up to limit sagas that failed or have run or compensated for longer than
+// StuckAfter, from position on, walking the shards in order, and the position of the next page
+// (nil once every shard is exhausted)
+func (r *Runner) ListStuck(ctx context.Context, from Position, limit int) ([]State, *Position, error) {
+	stuck := make([]State, 0, limit+1)
+	if r.repo == nil {
+		return stuck, nil, nil
+	}
+
+	cutoff := time.Now().Add(-r.config.StuckAfter)
+	for shard := from.Shard; shard < NumShards; shard++ {
+		after := gocql.UUID{}
+		if shard == from.Shard {
+			after = from.After
+		}
+		for {
+			states, err := r.repo.FetchAfter(ctx, shard, after, scanPage)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list sagas of shard %d: %w", shard, err)
+			}
+			for _, state := range states {
+				if state.Status == StatusFailed || state.UpdatedAt.Before(cutoff) {
+					stuck = append(stuck, state)
+				}
+				// One extra saga tells whether another page follows
+				if len(stuck) > limit {
+					last := stuck[limit-1]
+					return stuck[:limit], &Position{Shard: last.Shard, After: last.ID}, nil
+				}
+			}
+			if len(states) < scanPage {
+				break
+			}
+			after = states[len(states)-1].ID
+		}
+	}
+	return stuck, nil, nil
+}
+
+// StuckAfter returns the configured age at which a saga is listed as stuck
+func (r *Runner) StuckAfter() time.Duration {
+	return r.config.StuckAfter
+}
+
+// GetMetrics returns saga outcome counts of this instance
+func (r *Runner) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"started":     r.metrics.Started.Load(),
+		"completed":   r.metrics.Completed.Load(),
+		"compensated": r.metrics.Compensated.Load(),
+		"failed":      r.metrics.Failed.Load(),
+	}
+}
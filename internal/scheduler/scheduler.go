@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"acid/internal/safego"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// Task is the work performed by a scheduled job
+type Task func(ctx context.Context) error
+
+// Locker grants a run to a single instance across the fleet
+type Locker interface {
+	// TryLock returns true if this owner acquired the key for ttl
+	TryLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+}
+
+// Job describes a recurring task
+type Job struct {
+	// Name uniquely identifies the job
+	Name string
+
+	// Spec is a standard 5-field cron expression or descriptor (e.g. "@every 5m", "0 3 * * *")
+	Spec string
+
+	// Task is the work to run
+	Task Task
+
+	// Timeout bounds a single run
+	Timeout time.Duration
+
+	// Jitter delays each run by a random duration in [0, Jitter) to spread load across instances
+	Jitter time.Duration
+
+	// SingleInstance guards the run with the leader lock so only one instance executes it per tick
+	SingleInstance bool
+}
+
+// JobStatus is a point-in-time view of a job for the admin API
+type JobStatus struct {
+	Name           string        `json:"name"`
+	Schedule       string        `json:"schedule"`
+	SingleInstance bool          `json:"single_instance"`
+	Running        bool          `json:"running"`
+	NextRun        time.Time     `json:"next_run"`
+	LastRun        time.Time     `json:"last_run,omitempty"`
+	LastDuration   time.Duration `json:"last_duration_ns"`
+	LastError      string        `json:"last_error,omitempty"`
+	Runs           int64         `json:"runs"`
+	Failures       int64         `json:"failures"`
+	Skipped        int64         `json:"skipped"` // Ticks another instance ran
+}
+
+type entry struct {
+	job      Job
+	schedule cron.Schedule
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// Scheduler runs registered jobs on cron schedules inside the service
+type Scheduler struct {
+	locker     Locker
+	instanceID string
+	logger     *zap.Logger
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a scheduler; a nil locker runs every job on every instance
+func New(locker Locker, logger *zap.Logger) *Scheduler {
+	hostname, _ := os.Hostname()
+
+	return &Scheduler{
+		locker:     locker,
+		instanceID: hostname + ":" + strconv.Itoa(os.Getpid()),
+		logger:     logger,
+		entries:    make(map[string]*entry),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Register adds a job; must be called before Start
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := cron.ParseStandard(job.Spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for job %s: %w", job.Spec, job.Name, err)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = 1 * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[job.Name]; exists {
+		return fmt.Errorf("job %s already registered", job.Name)
+	}
+
+	s.entries[job.Name] = &entry{
+		job:      job,
+		schedule: schedule,
+		status: JobStatus{
+			Name:           job.Name,
+			Schedule:       job.Spec,
+			SingleInstance: job.SingleInstance,
+		},
+	}
+	return nil
+}
+
+// Start launches one loop per registered job
+func (s *Scheduler) Start() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		safego.Go("scheduler.loop", func() { s.loop(e) }, safego.Restart(s.stop), safego.OnExit(s.wg.Done))
+	}
+
+	s.logger.Info("Scheduler started",
+		zap.Int("jobs", len(s.entries)),
+		zap.String("instance_id", s.instanceID))
+}
+
+// Stop cancels pending ticks and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) loop(e *entry) {
+	for {
+		tick := e.schedule.Next(time.Now())
+		e.mu.Lock()
+		e.status.NextRun = tick
+		e.mu.Unlock()
+
+		delay := time.Until(tick)
+		if e.job.Jitter > 0 {
+			delay += rand.N(e.job.Jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.run(e, tick)
+		}
+	}
+}
+
+// run executes one tick of a job, honoring the leader guard
+func (s *Scheduler) run(e *entry, tick time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.job.Timeout)
+	defer cancel()
+
+	if e.job.SingleInstance && s.locker != nil {
+		// The key is unique per tick, so the lock never needs renewing or releasing
+		key := "scheduler:" + e.job.Name + ":" + strconv.FormatInt(tick.Unix(), 10)
+		acquired, err := s.locker.TryLock(ctx, key, s.instanceID, e.job.Timeout+time.Minute)
+		if err != nil {
+			s.logger.Warn("Scheduler lock unavailable, skipping run",
+				zap.String("job", e.job.Name), zap.Error(err))
+			s.recordSkip(e)
+			return
+		}
+		if !acquired {
+			s.recordSkip(e)
+			return
+		}
+	}
+
+	e.mu.Lock()
+	e.status.Running = true
+	e.mu.Unlock()
+
+	start := time.Now()
+	err := s.execute(ctx, e)
+	duration := time.Since(start)
+
+	e.mu.Lock()
+	e.status.Running = false
+	e.status.LastRun = start
+	e.status.LastDuration = duration
+	e.status.Runs++
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	} else {
+		e.status.LastError = ""
+	}
+	e.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Scheduled job failed",
+			zap.String("job", e.job.Name),
+			zap.Duration("duration", duration),
+			zap.Error(err))
+		return
+	}
+	s.logger.Info("Scheduled job completed",
+		zap.String("job", e.job.Name),
+		zap.Duration("duration", duration))
+}
+
+// execute runs the task, converting panics into errors so one bad job can't kill the process
+func (s *Scheduler) execute(ctx context.Context, e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return e.job.Task(ctx)
+}
+
+func (s *Scheduler) recordSkip(e *entry) {
+	e.mu.Lock()
+	e.status.Skipped++
+	e.mu.Unlock()
+}
+
+// Status returns the status of every job, sorted by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.mu.Lock()
+		statuses = append(statuses, e.status)
+		e.mu.Unlock()
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
@@ -1,20 +1,248 @@
 package server
 
 import (
+	"acid/internal/graph"
 	"acid/internal/handlers"
+	"acid/internal/middleware"
+	"acid/internal/ws"
+	"net/http/pprof"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler) {
+// SetupRoutes registers /api/v1. Its response shapes are frozen; new fields and endpoints go to
+// v2. User routes (UserRoutes) also serve the v2 representation to clients sending
+// Accept: application/vnd.acid.v2+json, and keep answering on their deprecated pre-REST paths
+func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, deprecation middleware.DeprecationConfig) {
 	// Define your HTTP routes here
 	gin.SetMode(gin.ReleaseMode)
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", middleware.Deprecation(deprecation))
 	{
 		api.GET("/health", userHandler.HealthCheck)
-		api.POST("/create/user", userHandler.CreateUser)
-		api.GET("/get/user/:id", userHandler.GetUser)
 		api.GET("/cache/metrics", userHandler.GetCacheMetrics) // Cache metrics endpoint
+
+		// Multi-get replacing client-side fan-out; requested for v1 clients, so it is the one
+		// endpoint added to v1 after the freeze
+		api.POST("/users:method", middleware.CustomMethods(map[string]gin.HandlerFunc{
+			"batchGet": middleware.Negotiate(userHandler.BatchGetUsers, userHandler.BatchGetUsersV2),
+		}))
+
+		registerUserRoutes(api, userHandler, 1)
+	}
+
+}
+
+// SetupV2Routes registers /api/v2: resource-oriented paths, snake_case DTOs and a data/meta envelope
+func SetupV2Routes(router *gin.Engine, userHandler *handlers.UserHandler) {
+	api := router.Group("/api/v2", middleware.APIVersion(2))
+	{
+		api.POST("/users:method", middleware.CustomMethods(map[string]gin.HandlerFunc{
+			"batchGet": userHandler.BatchGetUsersV2,
+		}))
+		registerUserRoutes(api, userHandler, 2)
+	}
+}
+
+func SetupEventRoutes(router *gin.Engine, eventsHandler *handlers.EventsHandler) {
+	api := router.Group("/api/v1")
+	{
+		api.GET("/users/events", eventsHandler.StreamUserEvents) // Server-sent events
+	}
+}
+
+// SetupWebSocketRoutes exposes real-time admin updates; each connection authenticates with the admin token
+func SetupWebSocketRoutes(router *gin.Engine, hub *ws.Hub, adminToken string) {
+	router.GET("/ws", middleware.AdminAuth(adminToken), hub.Serve)
+}
+
+func SetupAdminRoutes(router *gin.Engine, adminHandler *handlers.AdminHandler, adminToken string) {
+	admin := router.Group("/admin", middleware.AdminAuth(adminToken))
+	{
+		admin.GET("/outbox/dlq", adminHandler.ListOutboxDLQ)
+		admin.POST("/outbox/replay", adminHandler.ReplayOutbox)
+		admin.GET("/outbox/metrics", adminHandler.GetOutboxMetrics)
+		admin.GET("/sagas", adminHandler.ListStuckSagas)
+		admin.GET("/jobs", adminHandler.ListJobs)
+		admin.GET("/config", adminHandler.GetConfig)
+		admin.GET("/cache/metrics", adminHandler.GetCacheMetrics)
+		admin.GET("/cache/tiers", adminHandler.GetCacheTiers)
+		admin.PUT("/cache/tiers/:tier", adminHandler.SetCacheTier)
+		admin.GET("/users/:id", adminHandler.GetUser)
+		admin.POST("/users/:id/evict", adminHandler.EvictUser)
+		admin.POST("/users/:id/logins", adminHandler.RecordLogin)
+		admin.POST("/users/:id/merge", adminHandler.MergeUser)
+		admin.GET("/users/:id/merges", adminHandler.ListMerges)
+	}
+}
+
+// SetupSLORoutes registers the SLO summary and the per-endpoint latency histograms
+func SetupSLORoutes(router *gin.Engine, sloHandler *handlers.SLOHandler, adminToken string) {
+	slo := router.Group("/admin/slo", middleware.AdminAuth(adminToken))
+	{
+		slo.GET("", sloHandler.GetSLO)
+		slo.GET("/histograms", sloHandler.GetSLOHistograms)
+	}
+}
+
+// SetupQuotaRoutes registers the quota admin API; subjects are "key:<hash>", "user:<id>", "ip:<addr>"
+// or "tenant:<id>"
+func SetupQuotaRoutes(router *gin.Engine, quotaHandler *handlers.QuotaHandler, adminToken string) {
+	quotas := router.Group("/admin/quotas", middleware.AdminAuth(adminToken))
+	{
+		quotas.GET("/metrics", quotaHandler.GetQuotaMetrics)
+		quotas.GET("/:subject", quotaHandler.GetQuota)
+		quotas.PUT("/:subject", quotaHandler.UpdateQuota)
+		quotas.DELETE("/:subject/limits", quotaHandler.ClearQuotaLimits)
+		quotas.DELETE("/:subject/usage", quotaHandler.ResetQuotaUsage)
+	}
+}
+
+// SetupAttributeRoutes registers the admin API managing the user attribute schema
+func SetupAttributeRoutes(router *gin.Engine, attributesHandler *handlers.AttributesHandler, adminToken string) {
+	attrs := router.Group("/admin/attributes", middleware.AdminAuth(adminToken))
+	{
+		attrs.GET("/schema", attributesHandler.GetAttributeSchema)
+		attrs.PUT("/schema", attributesHandler.UpdateAttributeSchema)
+		attrs.GET("/metrics", attributesHandler.GetAttributeMetrics)
+	}
+}
+
+// SetupOTPRoutes registers the SMS login code API used by the authenticating service
+func SetupOTPRoutes(router *gin.Engine, otpHandler *handlers.OTPHandler, adminToken string) {
+	otp := router.Group("/admin/users/:id/otp", middleware.AdminAuth(adminToken))
+	{
+		otp.POST("", otpHandler.SendLoginCode)
+		otp.POST("/verify", otpHandler.VerifyLoginCode)
+	}
+}
+
+// SetupOAuthRoutes registers social sign-in on the public router and the identities admin API;
+// provider is google or github
+func SetupOAuthRoutes(router, adminRouter *gin.Engine, oauthHandler *handlers.OAuthHandler, adminToken string) {
+	auth := router.Group("/auth/:provider")
+	{
+		auth.GET("", oauthHandler.BeginSignIn)
+		auth.GET("/callback", oauthHandler.CompleteSignIn)
 	}
 
+	identities := adminRouter.Group("/admin/users/:id/identities", middleware.AdminAuth(adminToken))
+	{
+		identities.GET("", oauthHandler.ListIdentities)
+		identities.DELETE("/:provider/:subject", oauthHandler.UnlinkIdentity)
+	}
+	adminRouter.GET("/admin/oauth/metrics", middleware.AdminAuth(adminToken), oauthHandler.GetOAuthMetrics)
+}
+
+// SetupServiceAccountRoutes registers the OAuth2 token endpoint on the public router and the
+// service account admin API
+func SetupServiceAccountRoutes(router, adminRouter *gin.Engine, serviceAccountHandler *handlers.ServiceAccountHandler, adminToken string) {
+	router.POST("/auth/token", serviceAccountHandler.IssueToken)
+
+	accounts := adminRouter.Group("/admin/service-accounts", middleware.AdminAuth(adminToken))
+	{
+		accounts.POST("", serviceAccountHandler.CreateServiceAccount)
+		accounts.GET("", serviceAccountHandler.ListServiceAccounts)
+		accounts.GET("/metrics", serviceAccountHandler.GetServiceAccountMetrics)
+		accounts.GET("/:id", serviceAccountHandler.GetServiceAccount)
+		accounts.PATCH("/:id", serviceAccountHandler.UpdateServiceAccount)
+		accounts.DELETE("/:id", serviceAccountHandler.DeleteServiceAccount)
+		accounts.POST("/:id/secret", serviceAccountHandler.RotateServiceAccountSecret)
+		accounts.PUT("/:id/key", serviceAccountHandler.SetServiceAccountKey)
+		accounts.DELETE("/:id/key", serviceAccountHandler.DeleteServiceAccountKey)
+	}
+}
+
+// SetupAuthzRoutes registers the admin API managing roles and the API keys holding them
+func SetupAuthzRoutes(router *gin.Engine, authzHandler *handlers.AuthzHandler, adminToken string) {
+	admin := router.Group("/admin", middleware.AdminAuth(adminToken))
+	{
+		admin.GET("/roles", authzHandler.ListRoles)
+		admin.PUT("/roles/:name", authzHandler.PutRole)
+		admin.DELETE("/roles/:name", authzHandler.DeleteRole)
+		admin.GET("/api-keys", authzHandler.ListAPIKeys)
+		admin.POST("/api-keys", authzHandler.CreateAPIKey)
+		admin.DELETE("/api-keys/:id", authzHandler.DeleteAPIKey)
+		admin.GET("/authz/metrics", authzHandler.GetAuthzMetrics)
+	}
+}
+
+// SetupTenantRoutes registers the admin API managing per-tenant rate limits; tenant quotas are
+// managed with the quota API under the subject tenant:<id>
+func SetupTenantRoutes(router *gin.Engine, tenantHandler *handlers.TenantHandler, adminToken string) {
+	tenants := router.Group("/admin/tenants", middleware.AdminAuth(adminToken))
+	{
+		tenants.GET("", tenantHandler.ListTenants)
+		tenants.GET("/metrics", tenantHandler.GetTenantMetrics)
+		tenants.GET("/:tenant", tenantHandler.GetTenant)
+		tenants.PUT("/:tenant/rate-limit", tenantHandler.SetTenantRateLimit)
+		tenants.DELETE("/:tenant/rate-limit", tenantHandler.DeleteTenantRateLimit)
+	}
+}
+
+// SetupIPRuleRoutes registers the admin API managing runtime IP rules; scope is admin, api or
+// grpc and list is allow or deny
+func SetupIPRuleRoutes(router *gin.Engine, ipRulesHandler *handlers.IPRulesHandler, adminToken string) {
+	rules := router.Group("/admin/ip-rules", middleware.AdminAuth(adminToken))
+	{
+		rules.GET("", ipRulesHandler.ListIPRules)
+		rules.GET("/metrics", ipRulesHandler.GetIPFilterMetrics)
+		rules.POST("/:scope/:list", ipRulesHandler.AddIPRule)
+		rules.DELETE("/:scope/:list", ipRulesHandler.RemoveIPRule)
+	}
+}
+
+// SetupCaptureRoutes registers the admin API reading captured request/response payloads
+func SetupCaptureRoutes(router *gin.Engine, captureHandler *handlers.CaptureHandler, adminToken string) {
+	captures := router.Group("/admin/captures", middleware.AdminAuth(adminToken))
+	{
+		captures.GET("", captureHandler.ListCaptures)
+		captures.GET("/metrics", captureHandler.GetCaptureMetrics)
+		captures.GET("/:id", captureHandler.GetCapture)
+		captures.DELETE("", captureHandler.ClearCaptures)
+	}
+}
+
+// SetupCDCRoutes registers the CDC consumer's admin metrics
+func SetupCDCRoutes(router *gin.Engine, cdcHandler *handlers.CDCHandler, adminToken string) {
+	router.GET("/admin/cdc/metrics", middleware.AdminAuth(adminToken), cdcHandler.GetCDCMetrics)
+}
+
+// SetupRuntimeRoutes registers the runtime watchdog's admin API
+func SetupRuntimeRoutes(router *gin.Engine, runtimeHandler *handlers.RuntimeHandler, adminToken string) {
+	runtime := router.Group("/admin/runtime", middleware.AdminAuth(adminToken))
+	{
+		runtime.GET("", runtimeHandler.GetRuntime)
+		runtime.GET("/goroutines", runtimeHandler.GetGoroutines)
+		runtime.GET("/supervised", runtimeHandler.GetSupervised)
+	}
+}
+
+// SetupDeprecationRoutes registers the deprecated endpoint traffic report
+func SetupDeprecationRoutes(router *gin.Engine, deprecationHandler *handlers.DeprecationHandler, adminToken string) {
+	router.GET("/admin/deprecations", middleware.AdminAuth(adminToken), deprecationHandler.GetDeprecations)
+}
+
+func SetupGraphQLRoutes(router *gin.Engine, graphHandler *graph.Handler) {
+	router.POST("/graphql", graphHandler.Serve)
+}
+
+// SetupHealthRoutes registers the liveness and readiness probes used by load balancers
+func SetupHealthRoutes(router *gin.Engine, healthHandler *handlers.HealthHandler) {
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+}
+
+// SetupProfilingRoutes exposes net/http/pprof under /debug/pprof. It is unauthenticated (go tool
+// pprof can't send a token), so it is only mounted on the cluster-internal admin listener
+func SetupProfilingRoutes(router *gin.Engine) {
+	debug := router.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		debug.GET("/:profile", gin.WrapF(pprof.Index)) // heap, goroutine, allocs, block, mutex, ...
+	}
 }
@@ -0,0 +1,43 @@
+package server
+
+import "acid/internal/authz"
+
+// RoutePermissions annotate admin routes with the permission a service account or API key needs
+// for them; routes missing here need admin. Reads support staff may see are admin:read, while
+// config and captured payloads stay admin-only
+var RoutePermissions = map[string]authz.Permission{
+	"GET /admin/outbox/dlq":               authz.AdminRead,
+	"GET /admin/outbox/metrics":           authz.AdminRead,
+	"GET /admin/sagas":                    authz.AdminRead,
+	"GET /admin/jobs":                     authz.AdminRead,
+	"GET /admin/users/:id":                authz.AdminRead,
+	"GET /admin/users/:id/merges":         authz.AdminRead,
+	"GET /admin/users/:id/identities":     authz.AdminRead,
+	"GET /admin/oauth/metrics":            authz.AdminRead,
+	"GET /admin/slo":                      authz.AdminRead,
+	"GET /admin/slo/histograms":           authz.AdminRead,
+	"GET /admin/quotas/metrics":           authz.AdminRead,
+	"GET /admin/quotas/:subject":          authz.AdminRead,
+	"GET /admin/attributes/schema":        authz.AdminRead,
+	"GET /admin/attributes/metrics":       authz.AdminRead,
+	"GET /admin/service-accounts":         authz.AdminRead,
+	"GET /admin/service-accounts/metrics": authz.AdminRead,
+	"GET /admin/service-accounts/:id":     authz.AdminRead,
+	"GET /admin/ip-rules":                 authz.AdminRead,
+	"GET /admin/ip-rules/metrics":         authz.AdminRead,
+	"GET /admin/cdc/metrics":              authz.AdminRead,
+	"GET /admin/runtime":                  authz.AdminRead,
+	"GET /admin/runtime/goroutines":       authz.AdminRead,
+	"GET /admin/runtime/supervised":       authz.AdminRead,
+	"GET /admin/deprecations":             authz.AdminRead,
+	"GET /admin/roles":                    authz.AdminRead,
+	"GET /admin/api-keys":                 authz.AdminRead,
+	"GET /admin/authz/metrics":            authz.AdminRead,
+	"GET /admin/tenants":                  authz.AdminRead,
+	"GET /admin/tenants/metrics":          authz.AdminRead,
+	"GET /admin/tenants/:tenant":          authz.AdminRead,
+	"GET /admin/cache/metrics":            authz.CacheAdmin,
+	"GET /admin/cache/tiers":              authz.CacheAdmin,
+	"PUT /admin/cache/tiers/:tier":        authz.CacheAdmin,
+	"POST /admin/users/:id/evict":         authz.CacheAdmin,
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"acid/internal/authz"
+	"acid/internal/deprecation"
+	"acid/internal/handlers"
+	"acid/internal/middleware"
+	pb "acid/proto/acid"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// UserRoute is one operation on the users resource. /api/v1, /api/v2 and the gRPC service are
+// all checked against UserRoutes, so an operation is added or renamed in one place
+type UserRoute struct {
+	// Name identifies the operation, e.g. in deprecation notices
+	Name string
+
+	// Method and Path are the same under /api/v1 and /api/v2
+	Method string
+	Path   string
+
+	// Middleware runs before the handler, e.g. path parameter validation
+	Middleware []gin.HandlerFunc
+
+	// V1 and V2 render the v1 and v2 representations; a nil V1 makes the route v2-only. v1
+	// routes serve V2 to clients sending Accept: application/vnd.acid.v2+json
+	V1 func(*handlers.UserHandler, *gin.Context)
+	V2 func(*handlers.UserHandler, *gin.Context)
+
+	// Legacy is the pre-REST v1 path still served as a deprecated alias, e.g. /create/user
+	Legacy string
+
+	// RPC is the gRPC method serving the same operation, if any
+	RPC string
+}
+
+// legacyDeprecatedAt is when the pre-REST v1 paths were deprecated in favor of /api/v1/users
+var legacyDeprecatedAt = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+// UserRoutes lists every operation on users, in resource order
+var UserRoutes = []UserRoute{
+	{
+		Name: "create_user", Method: http.MethodPost, Path: "/users",
+		V1: (*handlers.UserHandler).CreateUser, V2: (*handlers.UserHandler).CreateUserV2,
+		Legacy: "/create/user", RPC: pb.Acid_CreateUser_FullMethodName,
+	},
+	{
+		Name: "list_users", Method: http.MethodGet, Path: "/users",
+		V2: (*handlers.UserHandler).ListUsersV2,
+	},
+	{
+		Name: "get_user", Method: http.MethodGet, Path: "/users/:id",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id"), middleware.NegotiateEncoding()},
+		V1:         (*handlers.UserHandler).GetUser, V2: (*handlers.UserHandler).GetUserV2,
+		Legacy: "/get/user/:id", RPC: pb.Acid_FetchUser_FullMethodName,
+	},
+	{
+		Name: "user_exists", Method: http.MethodHead, Path: "/users/:id",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id")},
+		V1:         (*handlers.UserHandler).HeadUser, V2: (*handlers.UserHandler).HeadUser,
+		RPC: pb.Acid_UserExists_FullMethodName,
+	},
+	{
+		Name: "get_preferences", Method: http.MethodGet, Path: "/users/:id/preferences",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id"), middleware.NegotiateEncoding()},
+		V1:         (*handlers.UserHandler).GetPreferences, V2: (*handlers.UserHandler).GetPreferencesV2,
+	},
+	{
+		Name: "update_preferences", Method: http.MethodPatch, Path: "/users/:id/preferences",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id"), middleware.RequirePermission(authz.UsersWrite)},
+		V1:         (*handlers.UserHandler).UpdatePreferences, V2: (*handlers.UserHandler).UpdatePreferencesV2,
+	},
+	{
+		Name: "update_attributes", Method: http.MethodPatch, Path: "/users/:id/attributes",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id"), middleware.RequirePermission(authz.UsersWrite)},
+		V2:         (*handlers.UserHandler).UpdateAttributesV2,
+	},
+	{
+		Name: "unsubscribe", Method: http.MethodPost, Path: "/users/:id/unsubscribe",
+		Middleware: []gin.HandlerFunc{middleware.ValidateUUIDParams("id")},
+		V1:         (*handlers.UserHandler).Unsubscribe, V2: (*handlers.UserHandler).UnsubscribeV2,
+	},
+}
+
+// registerUserRoutes registers UserRoutes on a version group, each v1 route also under its
+// legacy path
+func registerUserRoutes(api *gin.RouterGroup, userHandler *handlers.UserHandler, version int) {
+	for _, route := range UserRoutes {
+		handler := route.handler(userHandler, version)
+		if handler == nil {
+			continue
+		}
+		chain := append(append([]gin.HandlerFunc{}, route.Middleware...), handler)
+		api.Handle(route.Method, route.Path, chain...)
+		if version == 1 && route.Legacy != "" {
+			api.Handle(route.Method, route.Legacy, chain...)
+		}
+	}
+}
+
+func (r *UserRoute) handler(userHandler *handlers.UserHandler, version int) gin.HandlerFunc {
+	bind := func(method func(*handlers.UserHandler, *gin.Context)) gin.HandlerFunc {
+		return func(c *gin.Context) { method(userHandler, c) }
+	}
+	switch {
+	case version == 2 && r.V2 != nil:
+		return bind(r.V2)
+	case version == 1 && r.V1 != nil && r.V2 != nil:
+		return middleware.Negotiate(bind(r.V1), bind(r.V2))
+	case version == 1 && r.V1 != nil:
+		return bind(r.V1)
+	}
+	return nil
+}
+
+// LegacyNotices deprecates the legacy path of every v1 route in favor of its resource path. A
+// notice is named after its operation, e.g. create_user_v1 for POST /api/v1/create/user
+func LegacyNotices() []deprecation.Notice {
+	var notices []deprecation.Notice
+	for _, route := range UserRoutes {
+		if route.Legacy == "" {
+			continue
+		}
+		notices = append(notices, deprecation.Notice{
+			Name:        route.Name + "_v1",
+			Endpoints:   []string{route.Method + " /api/v1" + route.Legacy},
+			Since:       legacyDeprecatedAt,
+			Replacement: route.Method + " /api/v1" + route.Path,
+			Mode:        deprecation.Soft,
+		})
+	}
+	return notices
+}
+
+// CheckRPCs verifies that the gRPC service and UserRoutes describe the same operations: every
+// method of service is served over REST by a route naming it, every route's RPC exists, and both
+// transports require the same permission (permissions being the gRPC method permissions)
+func CheckRPCs(service *grpc.ServiceDesc, permissions map[string]authz.Permission) error {
+	methods := make(map[string]bool, len(service.Methods)+len(service.Streams))
+	for _, method := range service.Methods {
+		methods["/"+service.ServiceName+"/"+method.MethodName] = true
+	}
+	for _, stream := range service.Streams {
+		methods["/"+service.ServiceName+"/"+stream.StreamName] = true
+	}
+
+	var errs []error
+	for _, route := range UserRoutes {
+		if route.RPC == "" {
+			continue
+		}
+		if !methods[route.RPC] {
+			errs = append(errs, fmt.Errorf("route %s %s names unknown RPC %s", route.Method, route.Path, route.RPC))
+			continue
+		}
+		delete(methods, route.RPC)
+
+		permission := authz.UsersWrite
+		switch route.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			permission = authz.UsersRead
+		}
+		if permissions[route.RPC] != permission {
+			errs = append(errs, fmt.Errorf("RPC %s requires %q but %s %s requires %q",
+				route.RPC, permissions[route.RPC], route.Method, route.Path, permission))
+		}
+	}
+	for method := range methods {
+		errs = append(errs, fmt.Errorf("RPC %s has no REST route in server.UserRoutes", method))
+	}
+	return errors.Join(errs...)
+}
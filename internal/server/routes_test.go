@@ -0,0 +1,37 @@
+package server
+
+import (
+	"acid/internal/authz"
+	"acid/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testUserID = "0192b7a4-3c1e-7d2a-9f4b-6a8e1c5d2f30"
+
+// TestUserWriteRoutesRequireUsersWrite checks that a caller granted only users:read can't change
+// a user, whether or not the policy serves anonymous writes
+func TestUserWriteRoutesRequireUsersWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(), func(c *gin.Context) {
+		grant := &authz.Grant{Subject: "key:reader", Permissions: []authz.Permission{authz.UsersRead}}
+		c.Request = c.Request.WithContext(authz.WithGrant(c.Request.Context(), grant))
+	})
+	// The handlers are never reached, so they can be bound to a nil UserHandler
+	registerUserRoutes(router.Group("/api/v2"), nil, 2)
+
+	for _, path := range []string{"/users/" + testUserID + "/attributes", "/users/" + testUserID + "/preferences"} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v2"+path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("PATCH %s with users:read = %d, want %d", path, rec.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package serviceaccount
+
+import (
+	"acid/internal/authz"
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// healthServicePrefix is exempt so load balancer health checks never need a token
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// UnaryServerInterceptor authenticates calls carrying "authorization: Bearer <access token>"
+// metadata and requires the permission methodPermissions maps their method to; methods missing
+// from it require admin. Calls without a token pass on, for authz.AuthenticateUnaryServerInterceptor
+// to settle
+func UnaryServerInterceptor(manager *Manager, policy *authz.Policy, methodPermissions map[string]authz.Permission) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := manager.authorizeCall(ctx, policy, info.FullMethod, methodPermissions)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(manager *Manager, policy *authz.Policy, methodPermissions map[string]authz.Permission) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := manager.authorizeCall(ss.Context(), policy, info.FullMethod, methodPermissions)
+		if err != nil {
+			return err
+		}
+		return handler(srv, authz.StreamWithContext(ss, ctx))
+	}
+}
+
+func (m *Manager) authorizeCall(ctx context.Context, policy *authz.Policy, method string, methodPermissions map[string]authz.Permission) (context.Context, error) {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+	}
+	if !IsAccessToken(token) {
+		// Other bearer tokens (the admin token) are left to authz.AuthenticateUnaryServerInterceptor
+		return ctx, nil
+	}
+
+	principal, err := m.Verify(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return ctx, status.Error(codes.Unavailable, "failed to verify access token")
+	}
+	grant := principal.Grant()
+	if err := policy.AuthorizeMethod(grant, method, methodPermissions); err != nil {
+		return ctx, err
+	}
+	return authz.WithGrant(WithPrincipal(ctx, principal), grant), nil
+}
@@ -0,0 +1,105 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// Table holds service accounts, one row each
+var Table = table.New(table.Metadata{
+	Name: "service_accounts",
+	Columns: []string{"id", "name", "description", "scopes", "secret_hash", "public_key", "disabled",
+		"created_at", "updated_at"},
+	PartKey: []string{"id"},
+	SortKey: []string{},
+})
+
+// ColumnTypes are the CQL types Account is marshaled to
+var ColumnTypes = map[string]string{
+	"id":          "uuid",
+	"name":        "text",
+	"description": "text",
+	"scopes":      "set<text>",
+	"secret_hash": "text",
+	"public_key":  "text",
+	"disabled":    "boolean",
+	"created_at":  "timestamp",
+	"updated_at":  "timestamp",
+}
+
+// maxListed bounds List: accounts are a handful of internal jobs, read with a full table scan
+const maxListed = 1000
+
+// Repository stores service accounts in ScyllaDB
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Get returns an account, failing with ErrNotFound
+func (r *Repository) Get(ctx context.Context, id gocql.UUID) (*Account, error) {
+	var account Account
+	err := Table.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{"id": id}).GetRelease(&account)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account: %w", err)
+	}
+	return &account, nil
+}
+
+// Insert stores a new account
+func (r *Repository) Insert(ctx context.Context, account *Account) error {
+	if err := Table.InsertQueryContext(ctx, r.session).BindStruct(account).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to create service account: %w", err)
+	}
+	return nil
+}
+
+// Update writes the mutable columns of an existing account, failing with ErrNotFound when it was
+// deleted meanwhile
+func (r *Repository) Update(ctx context.Context, account *Account) error {
+	account.UpdatedAt = time.Now()
+	stmt, names := qb.Update(Table.Name()).
+		Set("description", "scopes", "secret_hash", "public_key", "disabled", "updated_at").
+		Where(qb.Eq("id")).
+		Existing().
+		ToCql()
+	applied, err := r.session.ContextQuery(ctx, stmt, names).BindStruct(account).ExecCASRelease()
+	if err != nil {
+		return fmt.Errorf("failed to update service account: %w", err)
+	}
+	if !applied {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes an account
+func (r *Repository) Delete(ctx context.Context, id gocql.UUID) error {
+	if err := Table.DeleteQueryContext(ctx, r.session).BindMap(map[string]interface{}{"id": id}).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to delete service account: %w", err)
+	}
+	return nil
+}
+
+// List returns every account, up to maxListed
+func (r *Repository) List(ctx context.Context) ([]Account, error) {
+	stmt, names := qb.Select(Table.Name()).Limit(maxListed).ToCql()
+	var accounts []Account
+	if err := r.session.ContextQuery(ctx, stmt, names).SelectRelease(&accounts); err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	return accounts, nil
+}
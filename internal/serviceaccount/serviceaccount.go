@@ -0,0 +1,541 @@
+// Package serviceaccount gives internal jobs and services an identity of their own instead of a
+// human user's. An account authenticates with a client secret or, when it registered a public
+// key, a signed client assertion, and receives a short-lived access token carrying its scopes.
+// Scopes are authz permissions. The token is accepted on both the HTTP and gRPC APIs
+package serviceaccount
+
+import (
+	"acid/internal/authz"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// Scopes an account can be granted; any other authz permission, like cache:admin, is one too
+const (
+	ScopeUsersRead  = authz.UsersRead  // Read users on the public HTTP and gRPC APIs
+	ScopeUsersWrite = authz.UsersWrite // Create and change users on the public HTTP and gRPC APIs
+	ScopeAdmin      = authz.Admin      // The admin API, like ADMIN_TOKEN
+)
+
+// secretPrefix marks client secrets, so they are recognizable in leaked-credential scans
+const secretPrefix = "sas_"
+
+var (
+	// ErrNotFound is returned for an account that doesn't exist
+	ErrNotFound = errors.New("service account not found")
+
+	// ErrInvalidScope is returned for a scope that doesn't exist, or one a token request asked for
+	// that the account doesn't have
+	ErrInvalidScope = errors.New("invalid scope")
+
+	// ErrInvalidPublicKey is returned for a key that isn't a PEM-encoded Ed25519 or P-256 key
+	ErrInvalidPublicKey = errors.New("invalid public key")
+
+	// ErrInvalidClient is returned by IssueToken when the credentials are wrong, or the account is
+	// disabled or unknown; which of them is not told
+	ErrInvalidClient = errors.New("invalid client credentials")
+
+	// ErrInvalidToken is returned by Verify for a token that is malformed, forged, expired, or of an
+	// account that was disabled or deleted since
+	ErrInvalidToken = errors.New("invalid or expired access token")
+
+	// ErrTokensDisabled is returned by IssueToken when no signing key is configured
+	ErrTokensDisabled = errors.New("service account tokens are disabled")
+)
+
+// Account is a machine identity. Credentials are never returned: SecretHash only verifies the
+// client secret, and PublicKey verifies client assertions
+type Account struct {
+	ID          gocql.UUID `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	Description string     `db:"description" json:"description,omitempty"`
+	Scopes      []string   `db:"scopes" json:"scopes"`
+	SecretHash  string     `db:"secret_hash" json:"-"`
+	PublicKey   string     `db:"public_key" json:"public_key,omitempty"`
+	Disabled    bool       `db:"disabled" json:"disabled"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// AccountUpdate changes an account; nil fields are left as they are
+type AccountUpdate struct {
+	Description *string
+	Scopes      []string
+	Disabled    *bool
+}
+
+// TokenRequest is a client credentials grant: either ClientID and ClientSecret, or a client
+// Assertion (ClientID is then optional). Scopes narrows the token; empty grants all the
+// account's scopes
+type TokenRequest struct {
+	ClientID     string
+	ClientSecret string
+	Assertion    string
+	Scopes       []string
+}
+
+// Token is an issued access token
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresIn   int64     `json:"expires_in"`
+	Scope       string    `json:"scope"`
+	ExpiresAt   time.Time `json:"-"`
+}
+
+// Principal is the service account a request authenticated as
+type Principal struct {
+	AccountID gocql.UUID
+	Name      string
+	Scopes    []string
+}
+
+// Has reports whether the principal was granted scope; admin includes every scope
+func (p *Principal) Has(scope string) bool {
+	return authz.Allows(p.Scopes, scope)
+}
+
+// Grant returns what the principal may do
+func (p *Principal) Grant() *authz.Grant {
+	return &authz.Grant{Subject: p.Subject(), Permissions: p.Scopes}
+}
+
+// Subject identifies the principal in logs, rate limits and quotas
+func (p *Principal) Subject() string {
+	return "service:" + p.AccountID.String()
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal a request authenticated as, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// Config holds service account settings
+type Config struct {
+	// SigningKey signs access tokens (HMAC-SHA256); every instance must share it. Empty disables
+	// token issuance, and every token is rejected
+	SigningKey []byte
+
+	// Issuer is the iss of access tokens
+	Issuer string
+
+	// TokenTTL is how long an access token is valid
+	TokenTTL time.Duration
+
+	// Audience is the aud client assertions must be addressed to, typically the token URL
+	Audience string
+
+	// AssertionMaxAge bounds how far in the future a client assertion may expire
+	AssertionMaxAge time.Duration
+
+	// CacheTTL is how long verified accounts are cached per instance; an account disabled or
+	// deleted on another instance stops being accepted here within it
+	CacheTTL time.Duration
+}
+
+// DefaultConfig returns sensible defaults: 15 minute tokens and 30 second account cache
+func DefaultConfig() *Config {
+	return &Config{
+		Issuer:          "acid",
+		TokenTTL:        15 * time.Minute,
+		Audience:        "acid",
+		AssertionMaxAge: 5 * time.Minute,
+		CacheTTL:        30 * time.Second,
+	}
+}
+
+// Metrics tracks token issuance and verification
+type Metrics struct {
+	Issued   atomic.Int64
+	Rejected atomic.Int64 // Token requests with bad credentials
+	Verified atomic.Int64
+	Invalid  atomic.Int64 // Requests with a bad or expired token
+}
+
+type cachedAccount struct {
+	account   *Account
+	expiresAt time.Time
+}
+
+// Manager manages service accounts, issues their access tokens and verifies them
+type Manager struct {
+	repo   *Repository
+	config *Config
+	logger *zap.Logger
+
+	cacheMu sync.Mutex
+	cache   map[gocql.UUID]cachedAccount
+
+	metrics Metrics
+}
+
+// NewManager creates the service account manager
+func NewManager(repo *Repository, config *Config, logger *zap.Logger) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Manager{
+		repo:   repo,
+		config: config,
+		logger: logger,
+		cache:  make(map[gocql.UUID]cachedAccount),
+	}
+}
+
+// Create creates an account and returns it with its client secret, which is only shown now
+func (m *Manager) Create(ctx context.Context, name, description string, scopes []string) (*Account, string, error) {
+	if err := validateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	secret, secretHash, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	account := &Account{
+		ID:          gocql.MustRandomUUID(),
+		Name:        name,
+		Description: description,
+		Scopes:      normalizeScopes(scopes),
+		SecretHash:  secretHash,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := m.repo.Insert(ctx, account); err != nil {
+		return nil, "", err
+	}
+	m.logger.Info("Service account created", zap.String("id", account.ID.String()),
+		zap.String("name", name), zap.Strings("scopes", account.Scopes))
+	return account, secret, nil
+}
+
+// Get returns an account, failing with ErrNotFound
+func (m *Manager) Get(ctx context.Context, id gocql.UUID) (*Account, error) {
+	return m.repo.Get(ctx, id)
+}
+
+// List returns every account
+func (m *Manager) List(ctx context.Context) ([]Account, error) {
+	return m.repo.List(ctx)
+}
+
+// Update changes an account's description, scopes or disabled flag. Tokens issued before keep
+// working only with the scopes the account still has, and not at all once it is disabled
+func (m *Manager) Update(ctx context.Context, id gocql.UUID, update AccountUpdate) (*Account, error) {
+	if update.Scopes != nil {
+		if err := validateScopes(update.Scopes); err != nil {
+			return nil, err
+		}
+	}
+	return m.modify(ctx, id, func(account *Account) error {
+		if update.Description != nil {
+			account.Description = *update.Description
+		}
+		if update.Scopes != nil {
+			account.Scopes = normalizeScopes(update.Scopes)
+		}
+		if update.Disabled != nil {
+			account.Disabled = *update.Disabled
+		}
+		return nil
+	})
+}
+
+// RotateSecret replaces an account's client secret and returns the new one; the old one stops
+// working at once, tokens issued with it keep working until they expire
+func (m *Manager) RotateSecret(ctx context.Context, id gocql.UUID) (string, error) {
+	secret, secretHash, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+	_, err = m.modify(ctx, id, func(account *Account) error {
+		account.SecretHash = secretHash
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// SetPublicKey registers the PEM-encoded key client assertions are verified with; an empty key
+// removes it
+func (m *Manager) SetPublicKey(ctx context.Context, id gocql.UUID, publicKey string) (*Account, error) {
+	if publicKey != "" {
+		if _, err := ParsePublicKey(publicKey); err != nil {
+			return nil, err
+		}
+	}
+	return m.modify(ctx, id, func(account *Account) error {
+		account.PublicKey = publicKey
+		return nil
+	})
+}
+
+// Delete removes an account; its tokens are rejected within CacheTTL on every instance
+func (m *Manager) Delete(ctx context.Context, id gocql.UUID) error {
+	if _, err := m.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := m.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	m.forget(id)
+	m.logger.Info("Service account deleted", zap.String("id", id.String()))
+	return nil
+}
+
+func (m *Manager) modify(ctx context.Context, id gocql.UUID, change func(*Account) error) (*Account, error) {
+	account, err := m.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(account); err != nil {
+		return nil, err
+	}
+	if err := m.repo.Update(ctx, account); err != nil {
+		return nil, err
+	}
+	m.forget(id)
+	return account, nil
+}
+
+// IssueToken runs a client credentials grant and returns an access token for the account
+func (m *Manager) IssueToken(ctx context.Context, req TokenRequest) (*Token, error) {
+	if len(m.config.SigningKey) == 0 {
+		return nil, ErrTokensDisabled
+	}
+	account, err := m.authenticate(ctx, req)
+	if err != nil {
+		m.metrics.Rejected.Add(1)
+		return nil, err
+	}
+
+	scopes := account.Scopes
+	if len(req.Scopes) > 0 {
+		for _, scope := range req.Scopes {
+			if !authz.Allows(account.Scopes, scope) {
+				m.metrics.Rejected.Add(1)
+				return nil, fmt.Errorf("%w: account lacks %q", ErrInvalidScope, scope)
+			}
+		}
+		scopes = normalizeScopes(req.Scopes)
+	}
+
+	now := time.Now()
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, err
+	}
+	claims := accessClaims{
+		Issuer:    m.config.Issuer,
+		Subject:   account.ID.String(),
+		Scope:     strings.Join(scopes, " "),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.config.TokenTTL).Unix(),
+		ID:        hex.EncodeToString(jti),
+	}
+	accessToken, err := signAccessToken(m.config.SigningKey, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	m.metrics.Issued.Add(1)
+	return &Token{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(m.config.TokenTTL.Seconds()),
+		Scope:       claims.Scope,
+		ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// authenticate checks the credentials of a token request. Every failure is ErrInvalidClient; the
+// reason is only logged
+func (m *Manager) authenticate(ctx context.Context, req TokenRequest) (*Account, error) {
+	clientID := req.ClientID
+	if req.Assertion != "" {
+		subject, err := unverifiedAssertionSubject(req.Assertion)
+		if err != nil || (clientID != "" && clientID != subject) {
+			return nil, m.rejectClient(clientID, "malformed assertion", err)
+		}
+		clientID = subject
+	}
+	id, err := gocql.ParseUUID(clientID)
+	if err != nil {
+		return nil, m.rejectClient(clientID, "client ID is not a UUID", nil)
+	}
+	account, err := m.repo.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, m.rejectClient(clientID, "unknown account", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.Disabled {
+		return nil, m.rejectClient(clientID, "account disabled", nil)
+	}
+
+	if req.Assertion != "" {
+		if account.PublicKey == "" {
+			return nil, m.rejectClient(clientID, "no public key registered", nil)
+		}
+		if err := verifyAssertion(req.Assertion, account.PublicKey, clientID, m.config.Audience, m.config.AssertionMaxAge, time.Now()); err != nil {
+			return nil, m.rejectClient(clientID, "assertion rejected", err)
+		}
+		return account, nil
+	}
+
+	if account.SecretHash == "" || subtle.ConstantTimeCompare([]byte(hashSecret(req.ClientSecret)), []byte(account.SecretHash)) != 1 {
+		return nil, m.rejectClient(clientID, "wrong client secret", nil)
+	}
+	return account, nil
+}
+
+func (m *Manager) rejectClient(clientID, reason string, err error) error {
+	m.logger.Warn("Service account token request rejected", zap.String("client_id", clientID),
+		zap.String("reason", reason), zap.Error(err))
+	return ErrInvalidClient
+}
+
+// Verify checks an access token and returns the principal it authenticates. The token's scopes
+// are narrowed to those the account still has
+func (m *Manager) Verify(ctx context.Context, token string) (*Principal, error) {
+	principal, err := m.verify(ctx, token)
+	if err != nil {
+		m.metrics.Invalid.Add(1)
+		return nil, err
+	}
+	m.metrics.Verified.Add(1)
+	return principal, nil
+}
+
+func (m *Manager) verify(ctx context.Context, token string) (*Principal, error) {
+	if len(m.config.SigningKey) == 0 {
+		return nil, ErrInvalidToken
+	}
+	claims, err := parseAccessToken(m.config.SigningKey, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if claims.Issuer != m.config.Issuer || !time.Unix(claims.ExpiresAt, 0).After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+	id, err := gocql.ParseUUID(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	account, err := m.cachedAccount(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if account.Disabled {
+		return nil, ErrInvalidToken
+	}
+
+	principal := &Principal{AccountID: account.ID, Name: account.Name}
+	for _, scope := range strings.Fields(claims.Scope) {
+		if authz.Allows(account.Scopes, scope) {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+	return principal, nil
+}
+
+// cachedAccount reads an account through the per-instance cache
+func (m *Manager) cachedAccount(ctx context.Context, id gocql.UUID) (*Account, error) {
+	now := time.Now()
+	m.cacheMu.Lock()
+	cached, ok := m.cache[id]
+	m.cacheMu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.account, nil
+	}
+
+	account, err := m.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m.cacheMu.Lock()
+	m.cache[id] = cachedAccount{account: account, expiresAt: now.Add(m.config.CacheTTL)}
+	m.cacheMu.Unlock()
+	return account, nil
+}
+
+func (m *Manager) forget(id gocql.UUID) {
+	m.cacheMu.Lock()
+	delete(m.cache, id)
+	m.cacheMu.Unlock()
+}
+
+// GetMetrics returns token counts of this instance
+func (m *Manager) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"issued":   m.metrics.Issued.Load(),
+		"rejected": m.metrics.Rejected.Load(),
+		"verified": m.metrics.Verified.Load(),
+		"invalid":  m.metrics.Invalid.Load(),
+	}
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !authz.Valid(scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// normalizeScopes sorts and deduplicates scopes
+func normalizeScopes(scopes []string) []string {
+	normalized := slices.Clone(scopes)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// newSecret returns a client secret and the hash stored for it. Secrets are 32 random bytes, so
+// a plain SHA-256 is as good as a slow password hash
+func newSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := secretPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
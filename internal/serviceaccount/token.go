@@ -0,0 +1,239 @@
+package serviceaccount
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// accessTokenType is the JWT typ of access tokens (RFC 9068), which tells them apart from the
+// admin token sent in the same header
+const accessTokenType = "at+jwt"
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// accessClaims are the claims of an access token; scope is space-separated as in OAuth2
+type accessClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// assertionClaims are the claims of a client assertion (RFC 7523): the account signs a JWT
+// issued by and about itself, addressed to this API
+type assertionClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// audience accepts the aud claim as a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, aud := range a {
+		if aud == value {
+			return true
+		}
+	}
+	return false
+}
+
+// signAccessToken encodes claims as an HS256 JWT
+func signAccessToken(key []byte, claims accessClaims) (string, error) {
+	header, err := encodeSegment(jwtHeader{Alg: "HS256", Typ: accessTokenType})
+	if err != nil {
+		return "", err
+	}
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + payload
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(hmacSHA256(key, signingInput)), nil
+}
+
+// parseAccessToken checks the signature of an HS256 access token and returns its claims; expiry
+// is left to the caller
+func parseAccessToken(key []byte, token string) (*accessClaims, error) {
+	header, payload, signature, signingInput, err := splitJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if header.Alg != "HS256" || header.Typ != accessTokenType {
+		return nil, fmt.Errorf("unexpected token type %s/%s", header.Typ, header.Alg)
+	}
+	if !hmac.Equal(signature, hmacSHA256(key, signingInput)) {
+		return nil, errors.New("bad signature")
+	}
+	var claims accessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	return &claims, nil
+}
+
+// IsAccessToken reports whether a bearer token is shaped like an access token, without checking
+// it; other bearer tokens (the admin token) are left to their own checks
+func IsAccessToken(token string) bool {
+	if strings.Count(token, ".") != 2 {
+		return false
+	}
+	encoded, _, _ := strings.Cut(token, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	var header jwtHeader
+	return json.Unmarshal(raw, &header) == nil && header.Typ == accessTokenType
+}
+
+// unverifiedAssertionSubject returns who a client assertion claims to be, to look up the key that
+// must have signed it
+func unverifiedAssertionSubject(assertion string) (string, error) {
+	_, payload, _, _, err := splitJWT(assertion)
+	if err != nil {
+		return "", err
+	}
+	var claims assertionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("invalid claims: %w", err)
+	}
+	return claims.Subject, nil
+}
+
+// verifyAssertion checks a client assertion signed with EdDSA (Ed25519) or ES256 by publicKey:
+// issuer and subject must be clientID, the audience must include aud, and it must expire within
+// maxAge so a leaked assertion is of little use
+func verifyAssertion(assertion, publicKey, clientID, aud string, maxAge time.Duration, now time.Time) error {
+	header, payload, signature, signingInput, err := splitJWT(assertion)
+	if err != nil {
+		return err
+	}
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if header.Alg != "EdDSA" || !ed25519.Verify(k, []byte(signingInput), signature) {
+			return errors.New("bad EdDSA signature")
+		}
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		// JWS carries ES256 signatures as r||s, 32 bytes each
+		if header.Alg != "ES256" || len(signature) != 64 {
+			return errors.New("bad ES256 signature")
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return errors.New("bad ES256 signature")
+		}
+	}
+
+	var claims assertionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("invalid claims: %w", err)
+	}
+	switch expiresAt := time.Unix(claims.ExpiresAt, 0); {
+	case claims.Issuer != clientID || claims.Subject != clientID:
+		return errors.New("iss and sub must be the client ID")
+	case !claims.Audience.contains(aud):
+		return fmt.Errorf("aud must include %q", aud)
+	case !expiresAt.After(now):
+		return errors.New("assertion expired")
+	case expiresAt.Sub(now) > maxAge:
+		return fmt.Errorf("assertion must expire within %s", maxAge)
+	}
+	return nil
+}
+
+// ParsePublicKey parses a PEM-encoded (PKIX) Ed25519 or P-256 public key
+func ParsePublicKey(encoded string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, fmt.Errorf("%w: not PEM encoded", ErrInvalidPublicKey)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
+	}
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: want an Ed25519 or P-256 key, got %T", ErrInvalidPublicKey, key)
+}
+
+func splitJWT(token string) (header jwtHeader, payload, signature []byte, signingInput string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, nil, nil, "", errors.New("not a JWT")
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, nil, "", fmt.Errorf("invalid header: %w", err)
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return header, nil, nil, "", fmt.Errorf("invalid header: %w", err)
+	}
+	if payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return header, nil, nil, "", fmt.Errorf("invalid payload: %w", err)
+	}
+	if signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return header, nil, nil, "", fmt.Errorf("invalid signature: %w", err)
+	}
+	return header, payload, signature, parts[0] + "." + parts[1], nil
+}
+
+func encodeSegment(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hmacSHA256(key []byte, input string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
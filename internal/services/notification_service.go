@@ -0,0 +1,152 @@
+package services
+
+import (
+	"acid/internal/jobs"
+	"acid/internal/logger"
+	"acid/internal/mailer"
+	"acid/internal/models"
+	"acid/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// welcomeEmailData is the data passed to the welcome template
+type welcomeEmailData struct {
+	Username string
+	Email    string
+
+	// UnsubscribeURL opts the user out of welcome emails; empty when links aren't signed
+	UnsubscribeURL string
+}
+
+// NotificationService sends user notifications through the background queue
+// and records their delivery status in user_notifications
+// Users are consulted for notification preferences before anything is sent
+type NotificationService struct {
+	Repo   *repository.NotificationRepository
+	Users  *repository.UserRepository
+	Mailer *mailer.Mailer
+	Queue  *jobs.Queue
+	Logger *zap.Logger
+
+	unsubscribe *UnsubscribeLinks
+}
+
+func NewNotificationService(repo *repository.NotificationRepository, users *repository.UserRepository, mailer *mailer.Mailer, queue *jobs.Queue, logger *zap.Logger) *NotificationService {
+	return &NotificationService{
+		Repo:   repo,
+		Users:  users,
+		Mailer: mailer,
+		Queue:  queue,
+		Logger: logger,
+	}
+}
+
+// SetUnsubscribeLinks sets the signer of the unsubscribe links put in emails. Must be called
+// during startup
+func (s *NotificationService) SetUnsubscribeLinks(links *UnsubscribeLinks) {
+	s.unsubscribe = links
+}
+
+// SendWelcomeEmail records a queued notification and enqueues the email job
+// Users who opted out of welcome emails get a suppressed record instead
+func (s *NotificationService) SendWelcomeEmail(ctx context.Context, user *models.User) error {
+	notification := models.NewNotification(user.ID, models.NotificationWelcomeEmail, "email")
+	if !user.EmailAllowed(models.EmailCategoryWelcome) {
+		notification.Status = models.NotificationSuppressed
+	}
+	if err := s.Repo.CreateNotification(ctx, notification); err != nil {
+		return fmt.Errorf("failed to record notification: %w", err)
+	}
+	if notification.Status == models.NotificationSuppressed {
+		return nil
+	}
+
+	data := welcomeEmailData{
+		Username:       user.Username,
+		Email:          user.Email,
+		UnsubscribeURL: s.unsubscribe.URL(user.ID, models.EmailCategoryWelcome),
+	}
+
+	// Users pick their language with a "locale" attribute; the mailer's default applies otherwise
+	locale, _ := user.Attributes.StringValue("locale")
+
+	job := jobs.Job{
+		Name: "send_welcome_email",
+		Run: func(ctx context.Context, attempt int) error {
+			notification.Attempts = attempt
+
+			// Preferences may have changed while the job was queued
+			if !s.emailAllowed(ctx, user, models.EmailCategoryWelcome) {
+				notification.Status = models.NotificationSuppressed
+				s.recordStatus(ctx, notification)
+				return nil
+			}
+
+			err := s.Mailer.Send(ctx, mailer.TemplateWelcome, locale, user.Email, data)
+			var bounce *mailer.BounceError
+			if errors.As(err, &bounce) {
+				// The address won't start working on a retry
+				notification.Status = models.NotificationFailed
+				notification.LastError = err.Error()
+				s.recordStatus(ctx, notification)
+				return nil
+			}
+			if err != nil {
+				notification.Status = models.NotificationRetrying
+				notification.LastError = err.Error()
+				s.recordStatus(ctx, notification)
+				return err
+			}
+
+			notification.Status = models.NotificationSent
+			notification.LastError = ""
+			s.recordStatus(ctx, notification)
+			return nil
+		},
+		OnFailure: func(err error) {
+			notification.Status = models.NotificationFailed
+			notification.LastError = err.Error()
+			s.recordStatus(context.Background(), notification)
+		},
+	}
+
+	if err := s.Queue.Enqueue(job); err != nil {
+		notification.Status = models.NotificationFailed
+		notification.LastError = err.Error()
+		s.recordStatus(ctx, notification)
+		return fmt.Errorf("failed to enqueue welcome email: %w", err)
+	}
+
+	return nil
+}
+
+// emailAllowed re-reads the user's preferences, falling back to the given copy on lookup errors
+func (s *NotificationService) emailAllowed(ctx context.Context, user *models.User, category string) bool {
+	log := logger.For(ctx, s.Logger)
+	if s.Users == nil {
+		return user.EmailAllowed(category)
+	}
+
+	current, err := s.Users.GetUserByID(ctx, user.ID.String())
+	if err != nil {
+		log.Warn("Failed to reload notification preferences, using cached copy",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err))
+		return user.EmailAllowed(category)
+	}
+	return current.EmailAllowed(category)
+}
+
+func (s *NotificationService) recordStatus(ctx context.Context, notification *models.Notification) {
+	log := logger.For(ctx, s.Logger)
+	if err := s.Repo.UpdateStatus(ctx, notification); err != nil {
+		log.Error("Failed to update notification status",
+			zap.String("notification_id", notification.ID.String()),
+			zap.String("status", notification.Status),
+			zap.Error(err))
+	}
+}
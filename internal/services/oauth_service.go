@@ -0,0 +1,337 @@
+package services
+
+import (
+	"acid/internal/cache"
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/oauth"
+	"acid/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// ErrNoVerifiedEmail is returned for a sign-in whose provider didn't vouch for an email: users
+// are provisioned with one, and an unverified one could take over someone else's account
+var ErrNoVerifiedEmail = errors.New("provider reported no verified email")
+
+// ErrIdentityNotFound is returned by UnlinkIdentity for an identity the user doesn't have
+var ErrIdentityNotFound = errors.New("identity not linked to this user")
+
+// LoginMethodOAuthPrefix prefixes the provider in the login method recorded for a social
+// sign-in, e.g. "oauth_google"
+const LoginMethodOAuthPrefix = "oauth_"
+
+// oauthProvisionAttempts bounds the usernames tried for a new user before giving up
+const oauthProvisionAttempts = 3
+
+// OAuthConfig holds social sign-in settings
+type OAuthConfig struct {
+	// LinkVerifiedEmail signs a first-time identity in as the existing user with its email, if the
+	// provider verified the email. Off, such a sign-in fails with repository.ErrEmailTaken
+	LinkVerifiedEmail bool
+}
+
+// DefaultOAuthConfig returns sensible defaults
+func DefaultOAuthConfig() *OAuthConfig {
+	return &OAuthConfig{LinkVerifiedEmail: true}
+}
+
+// OAuthMetrics tracks social sign-ins for observability
+type OAuthMetrics struct {
+	Started     atomic.Int64
+	SignedIn    atomic.Int64
+	Provisioned atomic.Int64 // Sign-ins that created their user
+	Linked      atomic.Int64 // Sign-ins that linked their identity to a user, new or existing
+	Failed      atomic.Int64
+}
+
+// OAuthStart is where to send a user to sign in with a provider
+type OAuthStart struct {
+	URL   string
+	State string
+}
+
+// OAuthLogin is the outcome of a social sign-in
+type OAuthLogin struct {
+	User     *models.User
+	Identity *oauth.Identity
+	Created  bool // The user was provisioned by this sign-in
+	Linked   bool // The identity was linked by this sign-in
+	Logins   *repository.LoginStats
+}
+
+// oauthState is what a sign-in started with, kept until its callback
+type oauthState struct {
+	Provider string `json:"p"`
+	Verifier string `json:"v"`
+}
+
+// OAuthService signs users in with Google or GitHub. A known identity signs in as its user; a new
+// one is linked to the user with the same verified email, or a user is provisioned for it just in
+// time. Every sign-in is recorded as a login
+type OAuthService struct {
+	Users      *UserService
+	Identities *oauth.Repository
+	States     *cache.OAuthStateStore
+	Logger     *zap.Logger
+
+	providers map[string]*oauth.Provider
+	config    *OAuthConfig
+	metrics   *OAuthMetrics
+}
+
+// NewOAuthService creates the service for the configured providers; states is nil when running
+// without cache, and sign-ins then fail with cache.ErrOAuthStateUnavailable
+func NewOAuthService(users *UserService, identities *oauth.Repository, states *cache.OAuthStateStore, providers []*oauth.Provider, config *OAuthConfig, logger *zap.Logger) *OAuthService {
+	if config == nil {
+		config = DefaultOAuthConfig()
+	}
+	byName := make(map[string]*oauth.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OAuthService{
+		Users:      users,
+		Identities: identities,
+		States:     states,
+		Logger:     logger,
+		providers:  byName,
+		config:     config,
+		metrics:    &OAuthMetrics{},
+	}
+}
+
+// Begin starts a sign-in: it stores a new state with its PKCE verifier and returns the provider
+// URL to redirect to. It fails with oauth.ErrUnknownProvider for a provider that isn't configured
+func (s *OAuthService) Begin(ctx context.Context, provider string) (*OAuthStart, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, oauth.ErrUnknownProvider
+	}
+	if s.States == nil {
+		return nil, cache.ErrOAuthStateUnavailable
+	}
+
+	state, err := oauth.NewState()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oauth.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(oauthState{Provider: provider, Verifier: verifier})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.States.Save(ctx, state, string(value)); err != nil {
+		return nil, err
+	}
+
+	s.metrics.Started.Add(1)
+	return &OAuthStart{URL: p.AuthCodeURL(state, oauth.Challenge(verifier)), State: state}, nil
+}
+
+// Complete finishes a sign-in from the provider's callback. It fails with
+// cache.ErrOAuthStateNotFound for a state that wasn't issued for provider or was used already,
+// oauth.ErrExchangeFailed when the provider refused the code, ErrNoVerifiedEmail, or
+// repository.ErrEmailTaken when the email belongs to a user it may not be linked to
+func (s *OAuthService) Complete(ctx context.Context, provider, state, code string) (*OAuthLogin, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, oauth.ErrUnknownProvider
+	}
+	if s.States == nil {
+		return nil, cache.ErrOAuthStateUnavailable
+	}
+	if s.Users.ReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	raw, err := s.States.Take(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	var started oauthState
+	if err := json.Unmarshal([]byte(raw), &started); err != nil || started.Provider != provider {
+		return nil, cache.ErrOAuthStateNotFound
+	}
+
+	profile, err := p.Exchange(ctx, code, started.Verifier)
+	if err != nil {
+		s.metrics.Failed.Add(1)
+		return nil, err
+	}
+	login, err := s.signIn(ctx, provider, profile)
+	if err != nil {
+		s.metrics.Failed.Add(1)
+		return nil, err
+	}
+
+	s.metrics.SignedIn.Add(1)
+	if login.Created {
+		s.metrics.Provisioned.Add(1)
+	}
+	if login.Linked {
+		s.metrics.Linked.Add(1)
+	}
+	if login.Logins, err = s.Users.RecordLogin(ctx, login.User.ID, LoginMethodOAuthPrefix+provider); err != nil {
+		// The sign-in stands; only the login stats miss it
+		logger.For(ctx, s.Logger).Warn("Failed to record social sign-in", zap.String("id", login.User.ID.String()), zap.Error(err))
+	}
+	return login, nil
+}
+
+// signIn finds or provisions the user of a provider profile
+func (s *OAuthService) signIn(ctx context.Context, provider string, profile *oauth.Profile) (*OAuthLogin, error) {
+	log := logger.For(ctx, s.Logger)
+
+	identity, err := s.Identities.Get(ctx, provider, profile.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		// A merged user is found under the ID it was merged into
+		user, _, err := s.Users.GetUser(ctx, identity.UserID.String())
+		if err == nil {
+			return &OAuthLogin{User: user, Identity: identity}, nil
+		}
+		if !errors.Is(err, gocql.ErrNotFound) {
+			return nil, err
+		}
+		log.Info("Identity of a deleted user, signing in anew", zap.String("provider", provider),
+			zap.String("user_id", identity.UserID.String()))
+		if err := s.Identities.Unlink(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if profile.Email == "" || !profile.EmailVerified {
+		return nil, ErrNoVerifiedEmail
+	}
+	user, created, err := s.findOrProvision(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	identity = &oauth.Identity{
+		Provider: provider,
+		Subject:  profile.Subject,
+		UserID:   user.ID,
+		Email:    profile.Email,
+		LinkedAt: time.Now(),
+	}
+	if err := s.Identities.Link(ctx, identity); err != nil {
+		return nil, err
+	}
+	log.Info("Identity linked", zap.String("provider", provider), zap.String("id", user.ID.String()), zap.Bool("created", created))
+	return &OAuthLogin{User: user, Identity: identity, Created: created, Linked: true}, nil
+}
+
+// findOrProvision returns the user with the profile's email when linking by email is on, or
+// creates one, trying other usernames while the profile's is taken
+func (s *OAuthService) findOrProvision(ctx context.Context, profile *oauth.Profile) (*models.User, bool, error) {
+	if s.config.LinkVerifiedEmail {
+		id, found, err := s.Users.Repo.LookupUserID(ctx, repository.UsersByEmailTable, profile.Email)
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			user, err := s.Users.Repo.GetUserByID(ctx, id.String())
+			if err == nil && user.Email == profile.Email {
+				return user, false, nil
+			}
+			if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+				return nil, false, err
+			}
+		}
+	}
+
+	for attempt := 0; attempt < oauthProvisionAttempts; attempt++ {
+		user, err := models.NewUser(oauthUsername(profile.Username, attempt), profile.Email)
+		if err != nil {
+			return nil, false, err
+		}
+		err = s.Users.CreateUser(ctx, user)
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return user, true, nil
+	}
+	return nil, false, fmt.Errorf("no free username for %q: %w", profile.Username, repository.ErrUsernameTaken)
+}
+
+// oauthUsername derives a username from the provider's: lowercased, limited to letters, digits,
+// dots, dashes and underscores, and suffixed with random digits after the first attempt
+func oauthUsername(base string, attempt int) string {
+	username := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return -1
+		}
+	}, strings.ToLower(base))
+	if username == "" {
+		username = "user"
+	}
+	if attempt > 0 {
+		username = fmt.Sprintf("%s-%04d", username, rand.IntN(10000))
+	}
+	return username
+}
+
+// Providers returns the names of the configured providers
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListIdentities returns the identities linked to a user
+func (s *OAuthService) ListIdentities(ctx context.Context, id gocql.UUID) ([]oauth.Identity, error) {
+	return s.Identities.ListByUser(ctx, id)
+}
+
+// UnlinkIdentity removes a provider identity from a user, who can then no longer sign in with it.
+// It fails with ErrIdentityNotFound when the user doesn't have it
+func (s *OAuthService) UnlinkIdentity(ctx context.Context, id gocql.UUID, provider, subject string) error {
+	if s.Users.ReadOnly() {
+		return ErrReadOnly
+	}
+	identity, err := s.Identities.Get(ctx, provider, subject)
+	if err != nil {
+		return err
+	}
+	if identity == nil || identity.UserID != id {
+		return ErrIdentityNotFound
+	}
+	return s.Identities.Unlink(ctx, identity)
+}
+
+// GetMetrics returns social sign-in counts of this instance
+func (s *OAuthService) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"started":     s.metrics.Started.Load(),
+		"signed_in":   s.metrics.SignedIn.Load(),
+		"provisioned": s.metrics.Provisioned.Load(),
+		"linked":      s.metrics.Linked.Load(),
+		"failed":      s.metrics.Failed.Load(),
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"acid/internal/cache"
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/repository"
+	"acid/internal/sms"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// ErrNoPhone is returned for an SMS code requested for a user without a phone number
+var ErrNoPhone = errors.New("user has no phone number")
+
+// ErrCodeNotDelivered is returned when the SMS provider didn't take a login code
+var ErrCodeNotDelivered = errors.New("failed to send code")
+
+// LoginMethodSMSOTP is the login method recorded for a login confirmed with a texted code
+const LoginMethodSMSOTP = "sms_otp"
+
+// otpDigits is the length of login codes
+const otpDigits = 6
+
+// OTPChallenge describes a code that was sent
+type OTPChallenge struct {
+	Phone     string    `json:"phone"` // Masked, see models.MaskPhone
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OTPMetrics tracks login codes for observability
+type OTPMetrics struct {
+	Sent     atomic.Int64
+	Verified atomic.Int64
+	Rejected atomic.Int64 // Wrong or expired codes
+	Failed   atomic.Int64 // Codes that couldn't be stored or delivered
+}
+
+// OTPService sends one-time login codes by SMS and checks them, as a second factor the
+// authenticating service can offer besides its own. A confirmed code is recorded as a login
+type OTPService struct {
+	Users  *UserService
+	SMS    sms.Sender
+	Codes  *cache.OTPStore
+	Logger *zap.Logger
+
+	metrics *OTPMetrics
+}
+
+// NewOTPService creates the service; codes is nil when running without cache, and every request
+// then fails with cache.ErrOTPUnavailable
+func NewOTPService(users *UserService, sender sms.Sender, codes *cache.OTPStore, logger *zap.Logger) *OTPService {
+	return &OTPService{
+		Users:   users,
+		SMS:     sender,
+		Codes:   codes,
+		Logger:  logger,
+		metrics: &OTPMetrics{},
+	}
+}
+
+// SendLoginCode texts a new code to the user's phone, replacing any pending one. It fails with
+// repository.ErrUserNotFound, ErrNoPhone, cache.ErrOTPResendTooSoon with the wait when the last
+// code is too recent, or ErrCodeNotDelivered, wrapping sms.ErrInvalidRecipient when the number
+// can't receive texts
+func (s *OTPService) SendLoginCode(ctx context.Context, id gocql.UUID) (*OTPChallenge, time.Duration, error) {
+	log := logger.For(ctx, s.Logger)
+	if s.Codes == nil {
+		return nil, 0, cache.ErrOTPUnavailable
+	}
+	user, _, err := s.Users.GetUser(ctx, id.String())
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, 0, repository.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if user.Phone == "" {
+		return nil, 0, ErrNoPhone
+	}
+
+	code, err := newOTPCode()
+	if err != nil {
+		return nil, 0, err
+	}
+	if wait, err := s.Codes.Issue(ctx, id.String(), code); err != nil {
+		if !errors.Is(err, cache.ErrOTPResendTooSoon) {
+			s.metrics.Failed.Add(1)
+		}
+		return nil, wait, err
+	}
+
+	ttl := s.Codes.TTL()
+	err = s.SMS.Send(ctx, &sms.Message{
+		To:   user.Phone,
+		Body: fmt.Sprintf("Your Acid login code is %s. It expires in %d minutes.", code, max(1, int(ttl.Round(time.Minute).Minutes()))),
+	})
+	if err != nil {
+		s.metrics.Failed.Add(1)
+		// An undelivered code can't be used; drop it so the user can ask again right away
+		if revokeErr := s.Codes.Revoke(context.WithoutCancel(ctx), id.String()); revokeErr != nil {
+			log.Warn("Failed to revoke undelivered code", zap.String("id", id.String()), zap.Error(revokeErr))
+		}
+		return nil, 0, fmt.Errorf("%w: %w", ErrCodeNotDelivered, err)
+	}
+
+	s.metrics.Sent.Add(1)
+	log.Info("Login code sent", zap.String("id", id.String()), zap.String("phone", models.MaskPhone(user.Phone)))
+	return &OTPChallenge{Phone: models.MaskPhone(user.Phone), ExpiresAt: time.Now().Add(ttl)}, 0, nil
+}
+
+// VerifyLoginCode checks a code sent by SendLoginCode and records the login when it matches. It
+// fails with cache.ErrOTPInvalid or cache.ErrOTPExpired otherwise
+func (s *OTPService) VerifyLoginCode(ctx context.Context, id gocql.UUID, code string) (*repository.LoginStats, error) {
+	if s.Codes == nil {
+		return nil, cache.ErrOTPUnavailable
+	}
+	if err := s.Codes.Verify(ctx, id.String(), code); err != nil {
+		if errors.Is(err, cache.ErrOTPInvalid) || errors.Is(err, cache.ErrOTPExpired) {
+			s.metrics.Rejected.Add(1)
+		}
+		return nil, err
+	}
+	s.metrics.Verified.Add(1)
+	return s.Users.RecordLogin(ctx, id, LoginMethodSMSOTP)
+}
+
+// GetMetrics returns login code counts of this instance
+func (s *OTPService) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"sent":     s.metrics.Sent.Load(),
+		"verified": s.metrics.Verified.Load(),
+		"rejected": s.metrics.Rejected.Load(),
+		"failed":   s.metrics.Failed.Load(),
+	}
+}
+
+// newOTPCode returns a uniformly random numeric code of otpDigits digits
+func newOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(math.Pow10(otpDigits))))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n.Int64()), nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gocql/gocql"
+)
+
+// ErrInvalidUnsubscribeToken is returned for an unsubscribe request whose token wasn't signed for
+// its user and category
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeLinks signs the unsubscribe links put in emails, so a recipient can opt out without
+// credentials while nobody else can opt them out by guessing their ID. A token is the HMAC of the
+// user ID and category; an empty category stands for every optional one. Tokens don't expire:
+// an old email must keep working. Rotating the key invalidates every link sent
+type UnsubscribeLinks struct {
+	key []byte
+
+	// url is the link template; {id} is replaced with the user ID, and category and token are
+	// added to its query
+	url string
+}
+
+// NewUnsubscribeLinks signs links with key; without one no link is made and every token is
+// refused
+func NewUnsubscribeLinks(key []byte, linkURL string) *UnsubscribeLinks {
+	return &UnsubscribeLinks{key: key, url: linkURL}
+}
+
+// Enabled reports whether links are signed
+func (l *UnsubscribeLinks) Enabled() bool {
+	return l != nil && len(l.key) > 0
+}
+
+// Token returns the token of id and category
+func (l *UnsubscribeLinks) Token(id gocql.UUID, category string) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte(id.String()))
+	mac.Write([]byte{0})
+	mac.Write([]byte(category))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether token was signed for id and category
+func (l *UnsubscribeLinks) Verify(id gocql.UUID, category, token string) bool {
+	if !l.Enabled() || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(l.Token(id, category)))
+}
+
+// URL returns the signed link unsubscribing id from category, or "" when links are disabled
+func (l *UnsubscribeLinks) URL(id gocql.UUID, category string) string {
+	if !l.Enabled() || l.url == "" {
+		return ""
+	}
+	query := url.Values{"token": {l.Token(id, category)}}
+	if category != "" {
+		query.Set("category", category)
+	}
+	link := strings.ReplaceAll(l.url, "{id}", id.String())
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+	return link + separator + query.Encode()
+}
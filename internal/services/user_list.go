@@ -0,0 +1,186 @@
+package services
+
+import (
+	"acid/internal/listquery"
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/pagination"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/scylladb/gocqlx/v3/table"
+	"go.uber.org/zap"
+)
+
+// Indexes of UserListSchema
+const (
+	UserIndexTable    = "users"
+	UserIndexCreated  = "users_by_created"
+	UserIndexEmail    = "users_by_email"
+	UserIndexUsername = "users_by_username"
+	UserIndexPhone    = "users_by_phone"
+)
+
+// UserListSchema is what ListUsers can sort and filter by. Without a sort, users come in token
+// order: stable across pages but unrelated to IDs or creation time
+var UserListSchema = &listquery.Schema{
+	Fields: map[string]listquery.Field{
+		"created_at": {},
+		"email":      {},
+		"username":   {},
+		"phone":      {Normalize: models.NormalizePhone}, // Encode the "+" as %2B, or write 00 instead
+	},
+	Indexes: []listquery.Index{
+		{Name: UserIndexTable},
+		{Name: UserIndexCreated, Sorts: []listquery.Sort{
+			{Field: "created_at", Direction: listquery.Desc},
+			{Field: "created_at", Direction: listquery.Asc},
+		}},
+		{Name: UserIndexEmail, Filters: []string{"email"}, Unique: true},
+		{Name: UserIndexUsername, Filters: []string{"username"}, Unique: true},
+		{Name: UserIndexPhone, Filters: []string{"phone"}, Unique: true},
+	},
+}
+
+// UserListPosition is where a user listing continues; Plan binds it to the query it came from
+type UserListPosition struct {
+	Plan    string                     `json:"p"`
+	Token   int64                      `json:"t,omitempty"`
+	Created *repository.UserCreatedKey `json:"k,omitempty"`
+}
+
+// ListUsers returns up to limit users answering plan from position on (the zero position for the
+// first page), and the position of the next page, nil on the last one. Lists read the database,
+// so in degraded mode it returns ErrDegraded
+func (s *UserService) ListUsers(ctx context.Context, plan listquery.Plan, from UserListPosition, limit int) ([]*models.User, *UserListPosition, error) {
+	if from.Plan != "" && from.Plan != plan.Key() {
+		return nil, nil, pagination.ErrInvalidCursor
+	}
+	if s.Degraded() {
+		return nil, nil, ErrDegraded
+	}
+
+	switch plan.Index.Name {
+	case UserIndexCreated:
+		ascending := plan.Query.Sort.Direction == listquery.Asc
+		users, next, err := s.listUsersByCreated(ctx, ascending, from.Created, limit)
+		if next == nil || err != nil {
+			return users, nil, err
+		}
+		return users, &UserListPosition{Plan: plan.Key(), Created: next}, nil
+	case UserIndexEmail:
+		return s.findUser(ctx, repository.UsersByEmailTable, plan.Query.Filters["email"], func(user *models.User) string { return user.Email })
+	case UserIndexUsername:
+		return s.findUser(ctx, repository.UsersByUsernameTable, plan.Query.Filters["username"], func(user *models.User) string { return user.Username })
+	case UserIndexPhone:
+		return s.findUser(ctx, repository.UsersByPhoneTable, plan.Query.Filters["phone"], func(user *models.User) string { return user.Phone })
+	}
+
+	after := int64(math.MinInt64)
+	if from.Plan != "" {
+		after = from.Token
+	}
+	users, last, err := s.Repo.ListUsers(ctx, after, limit)
+	if err != nil || len(users) < limit {
+		return users, nil, err
+	}
+	return users, &UserListPosition{Plan: plan.Key(), Token: last}, nil
+}
+
+// listUsersByCreated merges the users_by_created shards: each is read one row past limit, so a
+// longer merged list means more pages follow. The users are then read through the cache
+func (s *UserService) listUsersByCreated(ctx context.Context, ascending bool, after *repository.UserCreatedKey, limit int) ([]*models.User, *repository.UserCreatedKey, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var keys []repository.UserCreatedKey
+	var firstErr error
+	for shard := 0; shard < repository.CreatedShards; shard++ {
+		// A shard that panics fails the listing like one whose query failed
+		failShard := safego.OnPanic(func(r any) {
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("listing shard %d panicked: %v", shard, r)
+			}
+		})
+		wg.Add(1)
+		safego.Go("users.list_shard", func() {
+			shardKeys, err := s.Repo.ListCreated(ctx, shard, ascending, after, limit+1)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			keys = append(keys, shardKeys...)
+		}, failShard, safego.OnExit(wg.Done))
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	slices.SortFunc(keys, func(a, b repository.UserCreatedKey) int {
+		if ascending {
+			a, b = b, a
+		}
+		switch {
+		case a.Before(b):
+			return -1
+		case b.Before(a):
+			return 1
+		}
+		return 0
+	})
+	var next *repository.UserCreatedKey
+	if len(keys) > limit {
+		keys = keys[:limit]
+		next = &keys[limit-1]
+	}
+
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID.String()
+	}
+	batch := s.GetUsers(ctx, ids)
+	if len(batch.Failed) > 0 {
+		return nil, nil, batch.Failed[0].Err
+	}
+	if len(batch.NotFound) > 0 {
+		// Lookup rows are only written for inserted users, so their user was removed outside the API
+		logger.For(ctx, s.Logger).Warn("Skipping users_by_created rows without a user", zap.Strings("ids", batch.NotFound))
+	}
+
+	byID := make(map[string]*models.User, len(batch.Users))
+	for _, user := range batch.Users {
+		byID[user.ID.String()] = user
+	}
+	users := make([]*models.User, 0, len(keys))
+	for _, id := range ids {
+		if user := byID[id]; user != nil {
+			users = append(users, user)
+		}
+	}
+	return users, next, nil
+}
+
+// findUser reads the user a lookup table maps value to. The user is read through the cache and
+// must still have value, so a stale lookup row finds nothing
+func (s *UserService) findUser(ctx context.Context, lookup *table.Table, value string, field func(*models.User) string) ([]*models.User, *UserListPosition, error) {
+	id, found, err := s.Repo.LookupUserID(ctx, lookup, value)
+	if err != nil || !found {
+		return []*models.User{}, nil, err
+	}
+	batch := s.GetUsers(ctx, []string{id.String()})
+	if len(batch.Failed) > 0 {
+		return nil, nil, batch.Failed[0].Err
+	}
+	if len(batch.Users) == 0 || field(batch.Users[0]) != value {
+		return []*models.User{}, nil, nil
+	}
+	return batch.Users, nil, nil
+}
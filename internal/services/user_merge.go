@@ -0,0 +1,108 @@
+package services
+
+import (
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/outbox"
+	"acid/internal/repository"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// ErrMergeSelf is returned by MergeUsers when both IDs are the same user
+var ErrMergeSelf = errors.New("cannot merge a user into itself")
+
+// mergeEventPayload is the event contract of user.merged
+type mergeEventPayload struct {
+	ID       string    `json:"id"`
+	MergedID string    `json:"merged_id"`
+	Moved    []string  `json:"moved,omitempty"`
+	MergedAt time.Time `json:"merged_at"`
+}
+
+// MergeUsers folds a duplicate, e.g. an account created by an OAuth sign-in, into the user with
+// id (see repository.UserRepository.MergeUser). The duplicate's ID keeps resolving to the user
+// in GetUser, both users are purged from every cache tier, and user.merged is recorded. It fails
+// with repository.ErrUserNotFound when either user is missing, and repository.ErrAlreadyMerged
+// when the duplicate was merged before
+func (s *UserService) MergeUsers(ctx context.Context, id, duplicateID gocql.UUID, reason string) (*models.User, *repository.UserMerge, error) {
+	log := logger.For(ctx, s.Logger)
+	if s.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	if id == duplicateID {
+		return nil, nil, ErrMergeSelf
+	}
+
+	target, err := s.Repo.GetUserForWrite(ctx, id.String())
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, nil, repository.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	duplicate, err := s.Repo.GetUserForWrite(ctx, duplicateID.String())
+	if errors.Is(err, gocql.ErrNotFound) {
+		if _, aliased, aliasErr := s.Repo.ResolveAlias(ctx, duplicateID); aliasErr == nil && aliased {
+			return nil, nil, repository.ErrAlreadyMerged
+		}
+		return nil, nil, repository.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merge, err := s.Repo.MergeUser(ctx, duplicate, target, reason)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The duplicate is gone and the user may have taken over its email; a failed purge leaves
+	// entries that expire with the cache TTL
+	for _, user := range []*models.User{duplicate, target} {
+		if err := s.InvalidateUser(ctx, user.ID.String(), []string{user.Email}); err != nil {
+			log.Warn("Failed to purge merged user from cache", zap.String("id", user.ID.String()), zap.Error(err))
+		}
+	}
+
+	s.enqueue(outbox.EventUserMerged, id.String(), mergeEventPayload{
+		ID:       id.String(),
+		MergedID: duplicateID.String(),
+		Moved:    merge.Moved,
+		MergedAt: merge.MergedAt,
+	})
+	if len(merge.Moved) > 0 {
+		s.enqueueEvent(outbox.EventUserUpdated, target)
+	}
+
+	log.Info("Users merged",
+		zap.String("id", id.String()),
+		zap.String("merged_id", duplicateID.String()),
+		zap.Strings("moved", merge.Moved),
+		zap.Strings("released", merge.Released))
+	return target, merge, nil
+}
+
+// ListMerges returns up to limit merges into a user, newest first
+func (s *UserService) ListMerges(ctx context.Context, id gocql.UUID, limit int) ([]repository.UserMerge, error) {
+	return s.Repo.ListMerges(ctx, id, limit)
+}
+
+// resolveAlias returns the user a merged ID now belongs to; a failed lookup is logged and
+// reported as no alias, so the caller answers not found as before merges existed
+func (s *UserService) resolveAlias(ctx context.Context, id string) (string, bool) {
+	uuid, err := gocql.ParseUUID(id)
+	if err != nil {
+		return "", false
+	}
+	resolved, aliased, err := s.Repo.ResolveAlias(ctx, uuid)
+	if err != nil {
+		logger.For(ctx, s.Logger).Warn("Failed to resolve user alias", zap.String("id", id), zap.Error(err))
+		return "", false
+	}
+	return resolved.String(), aliased
+}
@@ -1,21 +1,780 @@
 package services
 
 import (
-	"acid/internal/repository"
+	"acid/internal/budget"
 	"acid/internal/cache"
+	"acid/internal/ids"
+	"acid/internal/jsoncodec"
+	"acid/internal/logger"
+	"acid/internal/models"
+	"acid/internal/outbox"
+	"acid/internal/repository"
+	"acid/internal/safego"
+	"acid/internal/saga"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
 	"go.uber.org/zap"
 )
 
+// SourceCacheDegraded is the read source reported when the database is down and only the cache was consulted
+const SourceCacheDegraded = "cache-degraded"
+
+// ErrDegraded is returned for reads that miss the cache while the database is marked unhealthy
+var ErrDegraded = errors.New("database unavailable, serving cached data only")
+
+// ErrReadOnly is returned for writes on an instance started in read-only mode
+var ErrReadOnly = errors.New("instance is read-only")
+
+// ErrCacheDisabled is returned by cache administration on an instance running without cache
+var ErrCacheDisabled = errors.New("cache is disabled on this instance")
+
+// attributeUpdateAttempts bounds UpdateAttributes retries when a concurrent update wins
+const attributeUpdateAttempts = 3
+
+// AttributeValidator checks user attributes against the attribute schema, returning an error
+// wrapping models.ErrInvalidAttributes for a rejection
+type AttributeValidator func(ctx context.Context, attributes models.Attributes) error
+
+// InvalidationHook is called after a user is written so dependent caches can be purged
+type InvalidationHook func(ctx context.Context, userID string)
+
 type UserService struct {
-	Repo        *repository.UserRepository
-	Logger      *zap.Logger
+	Repo         *repository.UserRepository
+	Logger       *zap.Logger
 	CacheManager *cache.CacheManager
+	Outbox        *outbox.Repository
+	Notifications *NotificationService
+
+	ids                ids.Generator
+	invalidationHooks  []InvalidationHook
+	degraded           func() bool
+	readOnly           bool
+	validateAttributes AttributeValidator
+	sagas              *saga.Runner
+	budgets            *budget.Budgets
+	unsubscribe        *UnsubscribeLinks
 }
 
-func NewUserService(repo *repository.UserRepository, logger *zap.Logger, cacheManager *cache.CacheManager) *UserService {
+// userEventPayload is the stable event contract for user lifecycle events
+type userEventPayload struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// loginEventPayload is the event contract of user.logged_in
+type loginEventPayload struct {
+	ID         string    `json:"id"`
+	Method     string    `json:"method,omitempty"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+}
+
+func NewUserService(repo *repository.UserRepository, logger *zap.Logger, cacheManager *cache.CacheManager, outboxRepo *outbox.Repository, notifications *NotificationService) *UserService {
 	return &UserService{
-		Repo:        repo,
-		Logger:      logger,
-		CacheManager: cacheManager,
+		Repo:          repo,
+		Logger:        logger,
+		CacheManager:  cacheManager,
+		Outbox:        outboxRepo,
+		Notifications: notifications,
+		ids:           defaultIDs,
+		sagas:         saga.NewRunner(nil, nil, logger),
+	}
+}
+
+// defaultIDs is the ID strategy used unless SetIDGenerator picks another
+var defaultIDs, _ = ids.New(ids.UUIDv7, 0)
+
+// SetIDGenerator sets the strategy generating the IDs of new users (UUIDv7 by default)
+// Must be called during startup, before the service handles requests
+func (s *UserService) SetIDGenerator(generator ids.Generator) {
+	s.ids = generator
+}
+
+// SetSagaRunner sets the runner recording create-user sagas; by default they run unrecorded
+// Must be called during startup, before the service handles requests
+func (s *UserService) SetSagaRunner(runner *saga.Runner) {
+	s.sagas = runner
+}
+
+// Operations with a latency budget and the stages it is split into
+const (
+	BudgetCreateUser = "create_user"
+	BudgetFetchUser  = "fetch_user"
+
+	StageCache  = "cache"
+	StageDB     = "db"
+	StageEvents = "events"
+)
+
+// BudgetStages lists the stages each budgeted operation runs; fetch_user's cache reads are
+// bounded by the cache's ReadBudget instead
+var BudgetStages = map[string][]string{
+	BudgetCreateUser: {StageCache, StageDB, StageEvents},
+	BudgetFetchUser:  {StageDB},
+}
+
+// SetBudgets sets the latency budgets of CreateUser and GetUser; by default they are unbounded
+// Must be called during startup, before the service handles requests
+func (s *UserService) SetBudgets(budgets *budget.Budgets) {
+	s.budgets = budgets
+}
+
+// RegisterInvalidationHook adds a hook fired after every successful user write
+// Hooks must be registered during startup, before the service handles requests
+func (s *UserService) RegisterInvalidationHook(hook InvalidationHook) {
+	s.invalidationHooks = append(s.invalidationHooks, hook)
+}
+
+// SetDegradedCheck installs the database health signal used to switch reads to cache-only
+// Must be called during startup, before the service handles requests
+func (s *UserService) SetDegradedCheck(degraded func() bool) {
+	s.degraded = degraded
+}
+
+// Degraded reports whether the database is currently marked unhealthy
+func (s *UserService) Degraded() bool {
+	return s.degraded != nil && s.degraded()
+}
+
+// SetReadOnly makes every write fail with ErrReadOnly; reads and caching are unaffected
+// Must be called during startup, before the service handles requests
+func (s *UserService) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// SetAttributeValidator installs the attribute schema check; without one, writes carrying
+// attributes are rejected. Must be called during startup, before the service handles requests
+func (s *UserService) SetAttributeValidator(validate AttributeValidator) {
+	s.validateAttributes = validate
+}
+
+// ReadOnly reports whether the instance rejects writes
+func (s *UserService) ReadOnly() bool {
+	return s.readOnly
+}
+
+// CreateUser persists a new user, records a user.created event and notifies invalidation hooks
+// A user without an ID gets one from the ID generator. It fails with repository.ErrEmailTaken,
+// repository.ErrUsernameTaken or repository.ErrPhoneTaken when another user holds the email,
+// username or phone number
+func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
+	if user.ID.IsEmpty() {
+		user.ID = s.ids.NewID()
+	}
+	return s.createUser(ctx, user, false)
+}
+
+// CreateUserWithID is CreateUser for an ID supplied by the client: it fails with
+// repository.ErrUserExists when the ID is taken instead of overwriting that user
+func (s *UserService) CreateUserWithID(ctx context.Context, user *models.User) error {
+	return s.createUser(ctx, user, true)
+}
+
+// SagaCreateUser is the saga kind of user creation
+const SagaCreateUser = "create_user"
+
+// createUser runs user creation as a saga: the user with its lookup rows (one unit of work),
+// then the cache, then the user.created event. A failing step undoes the earlier ones, so a
+// user is never left half-created, and the event is only recorded for a user that exists
+func (s *UserService) createUser(ctx context.Context, user *models.User, ifNotExists bool) error {
+	log := logger.For(ctx, s.Logger)
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if len(user.Attributes) > 0 {
+		if err := s.checkAttributes(ctx, user.Attributes); err != nil {
+			return err
+		}
+	}
+
+	// Steps past their share of the budget fail and are undone; what follows the saga isn't budgeted
+	budgetCtx, run := s.budgets.Start(ctx, BudgetCreateUser)
+	defer run.End()
+	err := s.sagas.Run(budgetCtx, SagaCreateUser, user.ID.String(),
+		saga.Step{
+			Name: "user",
+			Do: func(ctx context.Context) error {
+				ctx, done := run.Stage(ctx, StageDB)
+				defer done()
+				uow := s.Repo.NewUnitOfWork()
+				if err := s.Repo.StageUser(ctx, uow, user, ifNotExists); err != nil {
+					return err
+				}
+				return uow.Commit(ctx)
+			},
+			Undo: func(ctx context.Context) error {
+				return s.Repo.DeleteNewUser(ctx, user)
+			},
+		},
+		saga.Step{
+			Name: "cache",
+			Do: func(ctx context.Context) error {
+				if s.CacheManager == nil {
+					return nil
+				}
+				ctx, done := run.Stage(ctx, StageCache)
+				defer done()
+				return s.CacheManager.SetJSON(ctx, s.CacheManager.Keys().Key(cache.EntityUser, user.ID.String()), user)
+			},
+			Undo: func(ctx context.Context) error {
+				s.purgeCachedUser(ctx, user.ID.String())
+				return nil
+			},
+		},
+		saga.Step{
+			Name: "publish",
+			Do: func(ctx context.Context) error {
+				if s.Outbox == nil {
+					return nil
+				}
+				event, err := outbox.NewEvent(outbox.EventUserCreated, user.ID.String(), newUserEventPayload(user))
+				if err != nil {
+					return err
+				}
+				ctx, done := run.Stage(ctx, StageEvents)
+				defer done()
+				return s.Outbox.Enqueue(ctx, event)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.notifyInvalidation(ctx, user.ID.String())
+
+	// Welcome email is best effort: a queueing failure must not fail the signup
+	if s.Notifications != nil {
+		if err := s.Notifications.SendWelcomeEmail(ctx, user); err != nil {
+			log.Warn("Failed to schedule welcome email",
+				zap.String("user_id", user.ID.String()),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetUser returns a user through the cache, loading it from the database on a miss
+// The returned source is "local", "redis" or "database", or cache.SourceStale when the database
+// was unreachable and the cache's stale copy answered; in degraded mode the database is
+// skipped, the source is SourceCacheDegraded and a cache miss returns ErrDegraded. The ID of a
+// user merged into another returns that user (see MergeUsers)
+func (s *UserService) GetUser(ctx context.Context, id string) (*models.User, string, error) {
+	user := &models.User{}
+	source, err := s.GetUserInto(ctx, id, user)
+	if err != nil {
+		return nil, source, err
+	}
+	return user, source, nil
+}
+
+// GetUserInto is GetUser decoding into user, so hot callers can pass one from models.AcquireUser.
+// user is only valid when the error is nil
+func (s *UserService) GetUserInto(ctx context.Context, id string, user *models.User) (string, error) {
+	if s.Degraded() {
+		if _, err := s.CacheManager.GetJSON(ctx, s.CacheManager.Keys().Key(cache.EntityUser, id), user); err != nil {
+			return SourceCacheDegraded, ErrDegraded
+		}
+		return SourceCacheDegraded, nil
+	}
+
+	ctx, run := s.budgets.Start(ctx, BudgetFetchUser)
+	defer run.End()
+	source, err := s.CacheManager.GetOrSetJSON(ctx, s.CacheManager.Keys().Key(cache.EntityUser, id), user, func() (interface{}, error) {
+		// This function is only called on cache miss
+		log := logger.For(ctx, s.Logger)
+		log.Info("Fetching user from database", zap.String("id", id))
+		dbCtx, done := run.Stage(ctx, StageDB)
+		defer done()
+		fetchedUser, dbErr := s.Repo.GetUserByID(dbCtx, id)
+		if dbErr != nil {
+			log.Error("Database fetch failed",
+				zap.String("id", id),
+				zap.Error(dbErr))
+			if repository.IsUnavailable(dbErr) {
+				// Lets the cache answer from its stale copy instead (CACHE_STALE_TTL)
+				return nil, fmt.Errorf("%w: %w", cache.ErrSourceUnavailable, dbErr)
+			}
+			return nil, dbErr
+		}
+		log.Info("User fetched from database successfully",
+			zap.String("id", id),
+			zap.String("username", fetchedUser.Username))
+		return fetchedUser, nil
+	})
+	if errors.Is(err, gocql.ErrNotFound) {
+		// A user merged into another is found under the ID it was merged into; the alias itself
+		// isn't cached, so merging later never leaves a stale entry behind
+		if target, ok := s.resolveAlias(ctx, id); ok {
+			*user = models.User{}
+			return s.GetUserInto(ctx, target, user)
+		}
+	}
+	return source, err
+}
+
+// UserExists reports whether a user exists without loading it: a cached user answers from the
+// cache tiers, otherwise only the partition key is read from the database. Sources are as in
+// GetUser; in degraded mode a cache miss returns ErrDegraded
+func (s *UserService) UserExists(ctx context.Context, id string) (bool, string, error) {
+	if source, err := s.CacheManager.Locate(ctx, s.CacheManager.Keys().Key(cache.EntityUser, id)); err == nil {
+		return true, source, nil
+	}
+
+	if s.Degraded() {
+		return false, SourceCacheDegraded, ErrDegraded
+	}
+
+	exists, err := s.Repo.UserExists(ctx, id)
+	if err != nil {
+		return false, "database", err
+	}
+	return exists, "database", nil
+}
+
+// ErrInvalidUserID is reported by GetUsers for an ID that isn't a UUID
+var ErrInvalidUserID = errors.New("invalid user ID")
+
+// UserLookupError is an ID GetUsers couldn't answer for
+type UserLookupError struct {
+	ID  string
+	Err error
+}
+
+// UserBatch is the outcome of GetUsers: every requested ID lands in exactly one of the lists, in
+// request order
+type UserBatch struct {
+	Users    []*models.User
+	NotFound []string
+	Failed   []UserLookupError
+}
+
+// GetUsers loads several users at once: one cache MGet for all of them, then the misses from the
+// database in concurrent multi-get queries of up to repository.MaxMultiGet IDs, which are cached.
+// Failures are per ID rather than for the whole batch: an invalid ID fails with ErrInvalidUserID,
+// a failed query fails its IDs with the query's error, and in degraded mode cache misses fail
+// with ErrDegraded. Duplicate IDs are answered once
+func (s *UserService) GetUsers(ctx context.Context, ids []string) *UserBatch {
+	log := logger.For(ctx, s.Logger)
+	batch := &UserBatch{}
+	failed := make(map[string]error)
+
+	requested := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			requested = append(requested, id)
+		}
+	}
+
+	users := make(map[string]*models.User, len(requested))
+	var keys []string
+	if s.CacheManager != nil {
+		keys = make([]string, 0, len(requested))
+		for _, id := range requested {
+			keys = append(keys, s.CacheManager.Keys().Key(cache.EntityUser, id))
+		}
+		cached, err := s.CacheManager.MGet(ctx, keys)
+		if err != nil {
+			log.Warn("Cache multi-get failed, reading all users from the database", zap.Int("ids", len(keys)), zap.Error(err))
+		}
+		for i, id := range requested {
+			value, ok := cached[keys[i]]
+			if !ok {
+				continue
+			}
+			var user models.User
+			if err := jsoncodec.Unmarshal([]byte(value), &user); err != nil {
+				log.Warn("Ignoring undecodable cached user", zap.String("id", id), zap.Error(err))
+				continue
+			}
+			users[id] = &user
+		}
+	}
+
+	var missing []gocql.UUID
+	for _, id := range requested {
+		if users[id] != nil {
+			continue
+		}
+		uuid, err := gocql.ParseUUID(id)
+		switch {
+		case err != nil:
+			failed[id] = ErrInvalidUserID
+		case s.Degraded():
+			failed[id] = ErrDegraded
+		default:
+			missing = append(missing, uuid)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded := s.loadUsers(ctx, missing, failed)
+		if s.CacheManager != nil && len(loaded) > 0 {
+			values := make(map[string]any, len(loaded))
+			for _, user := range loaded {
+				values[s.CacheManager.Keys().Key(cache.EntityUser, user.ID.String())] = user
+			}
+			if err := s.CacheManager.SetMany(ctx, values); err != nil {
+				log.Warn("Failed to cache loaded users", zap.Int("users", len(values)), zap.Error(err))
+			}
+		}
+		for _, user := range loaded {
+			users[user.ID.String()] = user
+		}
+	}
+
+	for _, id := range requested {
+		switch {
+		case users[id] != nil:
+			batch.Users = append(batch.Users, users[id])
+		case failed[id] != nil:
+			batch.Failed = append(batch.Failed, UserLookupError{ID: id, Err: failed[id]})
+		default:
+			batch.NotFound = append(batch.NotFound, id)
+		}
+	}
+	return batch
+}
+
+// loadUsers reads ids from the database in concurrent chunks, recording the IDs of failed chunks
+// in failed
+func (s *UserService) loadUsers(ctx context.Context, ids []gocql.UUID, failed map[string]error) []*models.User {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		loaded []*models.User
+	)
+	for start := 0; start < len(ids); start += repository.MaxMultiGet {
+		chunk := ids[start:min(start+repository.MaxMultiGet, len(ids))]
+		// A chunk that panics fails like one whose query failed
+		failChunk := safego.OnPanic(func(r any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range chunk {
+				failed[id.String()] = fmt.Errorf("multi-get panicked: %v", r)
+			}
+		})
+		wg.Add(1)
+		safego.Go("users.multi_get", func() {
+			users, err := s.Repo.GetUsersByIDs(ctx, chunk)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.For(ctx, s.Logger).Error("Database multi-get failed", zap.Int("ids", len(chunk)), zap.Error(err))
+				for _, id := range chunk {
+					failed[id.String()] = err
+				}
+				return
+			}
+			loaded = append(loaded, users...)
+		}, failChunk, safego.OnExit(wg.Done))
+	}
+	wg.Wait()
+	return loaded
+}
+
+// SetUnsubscribeLinks sets the signer of unsubscribe links, whose tokens CheckUnsubscribeToken
+// accepts. Must be called during startup
+func (s *UserService) SetUnsubscribeLinks(links *UnsubscribeLinks) {
+	s.unsubscribe = links
+}
+
+// CheckUnsubscribeToken fails with ErrInvalidUnsubscribeToken unless token is the one of the
+// link unsubscribing id from category ("" for every optional category)
+func (s *UserService) CheckUnsubscribeToken(id gocql.UUID, category, token string) error {
+	if !s.unsubscribe.Verify(id, category, token) {
+		return ErrInvalidUnsubscribeToken
+	}
+	return nil
+}
+
+// GetPreferences returns a user's effective notification preferences, read from the database
+func (s *UserService) GetPreferences(ctx context.Context, id gocql.UUID) (*models.PreferencesResponse, error) {
+	user, err := s.Repo.GetUserByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := user.EffectivePreferences()
+	return &preferences, nil
+}
+
+// UpdatePreferences merges preference changes, purges the cached user and records a user.updated event
+func (s *UserService) UpdatePreferences(ctx context.Context, id gocql.UUID, req *models.PreferencesRequest) (*models.PreferencesResponse, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.MergePreferences(ctx, id, req.ToColumn()); err != nil {
+		return nil, err
+	}
+
+	s.purgeCachedUser(ctx, id.String())
+	s.notifyInvalidation(ctx, id.String())
+
+	user, err := s.Repo.GetUserForWrite(ctx, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("preferences updated but reload failed: %w", err)
+	}
+	s.enqueueEvent(outbox.EventUserUpdated, user)
+
+	preferences := user.EffectivePreferences()
+	return &preferences, nil
+}
+
+// RecordLogin records a successful login: it sets last_login_at, increments login_count and
+// records a user.logged_in event for analytics. Method is free-form ("password", "sso", ...)
+func (s *UserService) RecordLogin(ctx context.Context, id gocql.UUID, method string) (*repository.LoginStats, error) {
+	log := logger.For(ctx, s.Logger)
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	exists, err := s.Repo.UserExistsForWrite(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, repository.ErrUserNotFound
+	}
+
+	at := time.Now()
+	if err := s.Repo.RecordLogin(ctx, id, at); err != nil {
+		return nil, err
+	}
+	s.enqueue(outbox.EventUserLoggedIn, id.String(), loginEventPayload{
+		ID:         id.String(),
+		Method:     method,
+		LoggedInAt: at,
+	})
+
+	stats, err := s.Repo.LoginStatsForWrite(ctx, id)
+	if err != nil {
+		// The login is recorded; only the totals shown back are missing
+		log.Warn("Failed to reload login stats", zap.String("id", id.String()), zap.Error(err))
+		return &repository.LoginStats{LastLoginAt: at}, nil
+	}
+	log.Info("Login recorded", zap.String("id", id.String()), zap.String("method", method), zap.Int64("login_count", stats.LoginCount))
+	return stats, nil
+}
+
+// LoginStats returns a user's last login time and login count, read from the database
+func (s *UserService) LoginStats(ctx context.Context, id gocql.UUID) (*repository.LoginStats, error) {
+	return s.Repo.LoginStats(ctx, id)
+}
+
+// UpdateAttributes applies a JSON merge patch to a user's attributes (null removes one) and
+// validates the result as a whole, so a patch can't leave a required attribute missing. The
+// write only applies if no other update landed since the attributes were read; a lost race is
+// retried from a fresh read
+func (s *UserService) UpdateAttributes(ctx context.Context, id gocql.UUID, patch map[string]json.RawMessage) (models.Attributes, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	var user *models.User
+	var merged models.Attributes
+	var err error
+	for attempt := 0; attempt < attributeUpdateAttempts; attempt++ {
+		user, err = s.Repo.GetUserForWrite(ctx, id.String())
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, repository.ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if merged, err = user.Attributes.Merge(patch); err != nil {
+			return nil, err
+		}
+		if err = s.checkAttributes(ctx, merged); err != nil {
+			return nil, err
+		}
+
+		err = s.Repo.ReplaceAttributes(ctx, user, user.Attributes, merged)
+		if !errors.Is(err, repository.ErrAttributesChanged) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.purgeCachedUser(ctx, id.String())
+	s.notifyInvalidation(ctx, id.String())
+
+	user.Attributes = merged
+	s.enqueueEvent(outbox.EventUserUpdated, user)
+	return merged, nil
+}
+
+func (s *UserService) checkAttributes(ctx context.Context, attributes models.Attributes) error {
+	if s.validateAttributes == nil {
+		return fmt.Errorf("%w: attribute validation is not configured", models.ErrInvalidAttributes)
+	}
+	return s.validateAttributes(ctx, attributes)
+}
+
+// enqueueEvent stores a user lifecycle event in the outbox for the relay to publish
+// Failures are logged, not returned: the user write has already succeeded
+func (s *UserService) enqueueEvent(eventType string, user *models.User) {
+	s.enqueue(eventType, user.ID.String(), newUserEventPayload(user))
+}
+
+func newUserEventPayload(user *models.User) userEventPayload {
+	return userEventPayload{
+		ID:        user.ID.String(),
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func (s *UserService) enqueue(eventType, userID string, payload any) {
+	if s.Outbox == nil {
+		return
+	}
+
+	event, err := outbox.NewEvent(eventType, userID, payload)
+	if err != nil {
+		s.Logger.Error("Failed to build outbox event", zap.String("event_type", eventType), zap.Error(err))
+		return
+	}
+
+	if err := s.Outbox.Enqueue(context.Background(), event); err != nil {
+		s.Logger.Error("Failed to enqueue outbox event",
+			zap.String("event_type", eventType),
+			zap.String("user_id", userID),
+			zap.Error(err))
+	}
+}
+
+// purgeCachedUser drops every cached encoding of a user: the JSON entry read by GetUser and the
+// protobuf entry read by gRPC FetchUser
+func (s *UserService) purgeCachedUser(ctx context.Context, id string) {
+	log := logger.For(ctx, s.Logger)
+	if s.CacheManager == nil {
+		return
+	}
+	keys := s.CacheManager.Keys()
+	for _, key := range []string{keys.Key(cache.EntityUser, id), keys.Key(cache.EntityUserProto, id)} {
+		if err := s.CacheManager.Delete(ctx, key); err != nil {
+			log.Warn("Failed to purge cached user", zap.String("id", id), zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// UserEviction reports what EvictUser found before dropping it: each key with the tier that held
+// it ("local", "redis" or "miss"), and whether other instances were told to drop their copies
+type UserEviction struct {
+	UserID    string            `json:"user_id"`
+	Keys      map[string]string `json:"keys"`
+	Broadcast bool              `json:"broadcast"`
+}
+
+// EvictUser drops a user from every cache tier: the JSON and protobuf entries, the email mapping
+// of both the stored and the cached email, and responses cached for the user. Other instances are
+// told to drop their local copies; a failed broadcast is logged and reported, not returned
+func (s *UserService) EvictUser(ctx context.Context, id string) (*UserEviction, error) {
+	if s.CacheManager == nil {
+		return nil, ErrCacheDisabled
+	}
+	log := logger.For(ctx, s.Logger)
+	keys := s.CacheManager.Keys()
+
+	userKey := keys.Key(cache.EntityUser, id)
+	evicted := []string{userKey, keys.Key(cache.EntityUserProto, id)}
+
+	// The cached entry may hold an email the user no longer has, so both mappings go
+	emails := make(map[string]bool)
+	var cached models.User
+	if _, err := s.CacheManager.GetJSON(ctx, userKey, &cached); err == nil && cached.Email != "" {
+		emails[cached.Email] = true
+	}
+	stored, err := s.Repo.GetUserForWrite(ctx, id)
+	switch {
+	case err == nil:
+		emails[stored.Email] = true
+	case !errors.Is(err, gocql.ErrNotFound):
+		// Evicting must still work while the database is down; only the stored email is unknown
+		log.Warn("Failed to load user for eviction, evicting cached email only", zap.String("id", id), zap.Error(err))
+	}
+	for email := range emails {
+		evicted = append(evicted, s.ownedEmailKeys(ctx, id, email)...)
+	}
+
+	eviction := &UserEviction{UserID: id, Keys: make(map[string]string, len(evicted))}
+	for _, key := range evicted {
+		eviction.Keys[key], _ = s.CacheManager.Locate(ctx, key)
+		if err := s.CacheManager.Delete(ctx, key); err != nil {
+			return nil, fmt.Errorf("failed to evict %s: %w", key, err)
+		}
+	}
+	s.notifyInvalidation(ctx, id)
+
+	if err := s.CacheManager.PublishInvalidation(ctx, evicted...); err != nil {
+		log.Warn("Failed to broadcast user eviction", zap.String("id", id), zap.Error(err))
+	} else {
+		eviction.Broadcast = true
+	}
+
+	log.Info("User evicted from cache", zap.String("id", id), zap.Any("keys", eviction.Keys))
+	return eviction, nil
+}
+
+// InvalidateUser purges a user changed outside the API (seen through CDC) from every cache tier:
+// the JSON and protobuf entries, the mappings of emails, and responses cached for the user. Other
+// instances are told to drop their local copies. Unlike EvictUser it neither reads the database
+// nor reports where each key was, since it runs for every changed row of a bulk write
+func (s *UserService) InvalidateUser(ctx context.Context, id string, emails []string) error {
+	if s.CacheManager == nil {
+		return nil
+	}
+	keys := s.CacheManager.Keys()
+
+	invalidated := []string{keys.Key(cache.EntityUser, id), keys.Key(cache.EntityUserProto, id)}
+	for _, email := range emails {
+		invalidated = append(invalidated, s.ownedEmailKeys(ctx, id, email)...)
+	}
+	for _, key := range invalidated {
+		if err := s.CacheManager.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to invalidate %s: %w", key, err)
+		}
+	}
+	s.notifyInvalidation(ctx, id)
+
+	if err := s.CacheManager.PublishInvalidation(ctx, invalidated...); err != nil {
+		logger.For(ctx, s.Logger).Warn("Failed to broadcast user invalidation", zap.String("id", id), zap.Error(err))
+	}
+	return nil
+}
+
+// ownedEmailKeys returns the email mapping key of email unless it points at another user: the
+// mapping is only this user's while it points at them
+func (s *UserService) ownedEmailKeys(ctx context.Context, id, email string) []string {
+	emailKey := s.CacheManager.Keys().Key(cache.EntityEmail, email)
+	if owner, _, err := s.CacheManager.Get(ctx, emailKey); err == nil && owner != id {
+		return nil
+	}
+	return []string{emailKey}
+}
+
+// notifyInvalidation runs all registered invalidation hooks for a user
+func (s *UserService) notifyInvalidation(ctx context.Context, userID string) {
+	for _, hook := range s.invalidationHooks {
+		hook(ctx, userID)
 	}
 }
@@ -0,0 +1,31 @@
+package slo
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor records every unary call under its full method name
+func UnaryServerInterceptor(tracker *Tracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		tracker.Record(info.FullMethod, time.Since(start), ServerFailure(status.Code(err)))
+		return resp, err
+	}
+}
+
+// ServerFailure reports whether a gRPC code is the server's fault and spends availability budget;
+// client errors (NotFound, InvalidArgument, ...) and deliberate rejections do not
+func ServerFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
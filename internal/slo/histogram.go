@@ -0,0 +1,93 @@
+package slo
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Buckets follow the Prometheus native histogram layout with schema 2: bucket i holds
+// observations in (2^((i-1)/4), 2^(i/4)] microseconds, so each bucket is ~19% wider than the one
+// before and quantiles read from bucket bounds are within ~19% of the true value. Bucket 0 also
+// holds everything at or below 1µs; the last one everything above ~1h
+const (
+	histogramSchema  = 2
+	histogramBuckets = 128
+)
+
+// Histogram is a lock-free latency histogram with exponential buckets
+type Histogram struct {
+	count   atomic.Int64
+	sumUs   atomic.Int64
+	buckets [histogramBuckets]atomic.Int64
+}
+
+// Bucket is a non-empty histogram bucket
+type Bucket struct {
+	// UpperBound is the bucket's inclusive upper bound in seconds
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
+
+// HistogramSnapshot is a point-in-time copy of a histogram, cumulative since start
+type HistogramSnapshot struct {
+	Schema  int      `json:"schema"`
+	Count   int64    `json:"count"`
+	Sum     float64  `json:"sum_seconds"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Observe records one latency
+func (h *Histogram) Observe(latency time.Duration) {
+	us := latency.Microseconds()
+	h.buckets[bucketIndex(us)].Add(1)
+	h.sumUs.Add(us)
+	h.count.Add(1)
+}
+
+// Quantile estimates the q-quantile (0 < q <= 1) as the upper bound of the bucket containing it
+func (h *Histogram) Quantile(q float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i := range h.buckets {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			return time.Duration(math.Ceil(bucketUpperBound(i))) * time.Microsecond
+		}
+	}
+	return time.Duration(math.Ceil(bucketUpperBound(histogramBuckets-1))) * time.Microsecond
+}
+
+// Snapshot copies the non-empty buckets
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{
+		Schema:  histogramSchema,
+		Count:   h.count.Load(),
+		Sum:     float64(h.sumUs.Load()) / 1e6,
+		Buckets: []Bucket{},
+	}
+	for i := range h.buckets {
+		if count := h.buckets[i].Load(); count > 0 {
+			snapshot.Buckets = append(snapshot.Buckets, Bucket{UpperBound: bucketUpperBound(i) / 1e6, Count: count})
+		}
+	}
+	return snapshot
+}
+
+func bucketIndex(us int64) int {
+	if us <= 1 {
+		return 0
+	}
+	index := int(math.Ceil(math.Log2(float64(us)) * (1 << histogramSchema)))
+	return min(index, histogramBuckets-1)
+}
+
+// bucketUpperBound returns bucket i's upper bound in microseconds
+func bucketUpperBound(i int) float64 {
+	return math.Exp2(float64(i) / (1 << histogramSchema))
+}
@@ -0,0 +1,282 @@
+// Package slo measures request latency and availability per HTTP route and gRPC method, and
+// tracks error budgets for the service level objectives defined on top of them. Everything is
+// per instance and in memory; /admin/slo reports it
+package slo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// slotWidth is the resolution of the error budget window
+const slotWidth = time.Minute
+
+// Objective is an SLO over one or more endpoints (HTTP "METHOD /route" or gRPC full method names)
+type Objective struct {
+	Name      string
+	Endpoints []string
+
+	// LatencyThreshold is the latency a request must not exceed to count as fast
+	LatencyThreshold time.Duration
+
+	// LatencyTarget is the fraction of requests that must be fast, e.g. 0.99
+	LatencyTarget float64
+
+	// AvailabilityTarget is the fraction of requests that must not fail server-side, e.g. 0.999
+	AvailabilityTarget float64
+}
+
+// Config holds SLO tracking configuration
+type Config struct {
+	// Window is the rolling window error budgets are computed over
+	Window time.Duration
+
+	Objectives []Objective
+}
+
+// DefaultConfig defines the CreateUser and FetchUser objectives over REST (v1 and v2) and gRPC
+func DefaultConfig() *Config {
+	return &Config{
+		Window: 1 * time.Hour,
+		Objectives: []Objective{
+			{
+				Name:               "create_user",
+				Endpoints:          []string{"POST /api/v1/users", "POST /api/v1/create/user", "POST /api/v2/users", "/acid.Acid/createUser"},
+				LatencyThreshold:   200 * time.Millisecond,
+				LatencyTarget:      0.99,
+				AvailabilityTarget: 0.999,
+			},
+			{
+				Name:               "fetch_user",
+				Endpoints:          []string{"GET /api/v1/users/:id", "GET /api/v1/get/user/:id", "GET /api/v2/users/:id", "/acid.Acid/fetchUser"},
+				LatencyThreshold:   50 * time.Millisecond,
+				LatencyTarget:      0.99,
+				AvailabilityTarget: 0.999,
+			},
+		},
+	}
+}
+
+// Tracker records every request into a per-endpoint histogram and, for endpoints covered by an
+// objective, into that objective's rolling window
+type Tracker struct {
+	config     *Config
+	endpoints  sync.Map // endpoint -> *endpointStats
+	objectives map[string][]*objectiveWindow
+	windows    []*objectiveWindow
+}
+
+type endpointStats struct {
+	latency  Histogram
+	requests atomic.Int64
+	errors   atomic.Int64
+}
+
+// objectiveWindow counts requests per minute slot over the window
+type objectiveWindow struct {
+	objective Objective
+	mu        sync.Mutex
+	slots     []windowSlot
+}
+
+type windowSlot struct {
+	minute   int64
+	requests int64
+	errors   int64
+	slow     int64
+}
+
+// NewTracker creates a tracker for the configured objectives
+func NewTracker(config *Config) *Tracker {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Window < slotWidth {
+		config.Window = slotWidth
+	}
+
+	t := &Tracker{
+		config:     config,
+		objectives: make(map[string][]*objectiveWindow),
+	}
+	for _, objective := range config.Objectives {
+		window := &objectiveWindow{
+			objective: objective,
+			slots:     make([]windowSlot, int(config.Window/slotWidth)),
+		}
+		t.windows = append(t.windows, window)
+		for _, endpoint := range objective.Endpoints {
+			t.objectives[endpoint] = append(t.objectives[endpoint], window)
+		}
+	}
+	return t
+}
+
+// Record adds one request; failed means a server-side failure (5xx, or a gRPC server error code)
+func (t *Tracker) Record(endpoint string, latency time.Duration, failed bool) {
+	stats, ok := t.endpoints.Load(endpoint)
+	if !ok {
+		stats, _ = t.endpoints.LoadOrStore(endpoint, &endpointStats{})
+	}
+	s := stats.(*endpointStats)
+	s.latency.Observe(latency)
+	s.requests.Add(1)
+	if failed {
+		s.errors.Add(1)
+	}
+
+	for _, window := range t.objectives[endpoint] {
+		window.record(time.Now(), latency, failed)
+	}
+}
+
+func (w *objectiveWindow) record(now time.Time, latency time.Duration, failed bool) {
+	minute := now.Unix() / int64(slotWidth/time.Second)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	slot := &w.slots[minute%int64(len(w.slots))]
+	if slot.minute != minute {
+		*slot = windowSlot{minute: minute}
+	}
+	slot.requests++
+	if failed {
+		slot.errors++
+	} else if latency > w.objective.LatencyThreshold {
+		slot.slow++ // Failed requests already spend availability budget
+	}
+}
+
+// ObjectiveStatus is an objective's state over the rolling window
+type ObjectiveStatus struct {
+	Name             string  `json:"name"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	Slow             int64   `json:"slow"`
+	Availability     float64 `json:"availability"`
+	LatencyRatio     float64 `json:"latency_ratio"` // Fraction of successful requests within the threshold
+	LatencyThreshold string  `json:"latency_threshold"`
+
+	AvailabilityTarget          float64 `json:"availability_target"`
+	LatencyTarget               float64 `json:"latency_target"`
+	AvailabilityBudgetRemaining float64 `json:"availability_budget_remaining"`
+	LatencyBudgetRemaining      float64 `json:"latency_budget_remaining"`
+	AvailabilityBurnRate        float64 `json:"availability_burn_rate"` // 1 spends the budget exactly over the window
+	LatencyBurnRate             float64 `json:"latency_burn_rate"`
+}
+
+func (w *objectiveWindow) status(now time.Time) ObjectiveStatus {
+	oldest := now.Unix()/int64(slotWidth/time.Second) - int64(len(w.slots)) + 1
+
+	var requests, errors, slow int64
+	w.mu.Lock()
+	for _, slot := range w.slots {
+		if slot.minute >= oldest {
+			requests += slot.requests
+			errors += slot.errors
+			slow += slot.slow
+		}
+	}
+	w.mu.Unlock()
+
+	status := ObjectiveStatus{
+		Name:               w.objective.Name,
+		Requests:           requests,
+		Errors:             errors,
+		Slow:               slow,
+		Availability:       1,
+		LatencyRatio:       1,
+		LatencyThreshold:   w.objective.LatencyThreshold.String(),
+		AvailabilityTarget: w.objective.AvailabilityTarget,
+		LatencyTarget:      w.objective.LatencyTarget,
+	}
+	if requests > 0 {
+		status.Availability = 1 - float64(errors)/float64(requests)
+	}
+	if succeeded := requests - errors; succeeded > 0 {
+		status.LatencyRatio = 1 - float64(slow)/float64(succeeded)
+	}
+	status.AvailabilityBurnRate = burnRate(status.Availability, w.objective.AvailabilityTarget)
+	status.LatencyBurnRate = burnRate(status.LatencyRatio, w.objective.LatencyTarget)
+	status.AvailabilityBudgetRemaining = 1 - status.AvailabilityBurnRate
+	status.LatencyBudgetRemaining = 1 - status.LatencyBurnRate
+	return status
+}
+
+// burnRate is the share of the error budget (1 - target) spent: above 1 the objective is missed
+func burnRate(ratio, target float64) float64 {
+	if target >= 1 {
+		if ratio < 1 {
+			return 1
+		}
+		return 0
+	}
+	return (1 - ratio) / (1 - target)
+}
+
+// EndpointStatus is an endpoint's latency and availability since start
+type EndpointStatus struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	Availability float64 `json:"availability"`
+	P50          string  `json:"p50"`
+	P90          string  `json:"p90"`
+	P99          string  `json:"p99"`
+}
+
+// Summary reports every objective over the window and every endpoint since start
+type Summary struct {
+	Window     string                    `json:"window"`
+	Objectives []ObjectiveStatus         `json:"objectives"`
+	Endpoints  map[string]EndpointStatus `json:"endpoints"`
+}
+
+// Summary computes the current SLO state
+func (t *Tracker) Summary() Summary {
+	summary := Summary{
+		Window:     t.config.Window.String(),
+		Objectives: t.Objectives(),
+		Endpoints:  make(map[string]EndpointStatus),
+	}
+
+	t.endpoints.Range(func(key, value any) bool {
+		s := value.(*endpointStats)
+		requests, errors := s.requests.Load(), s.errors.Load()
+		status := EndpointStatus{
+			Requests:     requests,
+			Errors:       errors,
+			Availability: 1,
+			P50:          s.latency.Quantile(0.5).String(),
+			P90:          s.latency.Quantile(0.9).String(),
+			P99:          s.latency.Quantile(0.99).String(),
+		}
+		if requests > 0 {
+			status.Availability = 1 - float64(errors)/float64(requests)
+		}
+		summary.Endpoints[key.(string)] = status
+		return true
+	})
+	return summary
+}
+
+// Objectives reports only the objectives, e.g. for periodic metrics snapshots
+func (t *Tracker) Objectives() []ObjectiveStatus {
+	now := time.Now()
+	statuses := make([]ObjectiveStatus, 0, len(t.windows))
+	for _, window := range t.windows {
+		statuses = append(statuses, window.status(now))
+	}
+	return statuses
+}
+
+// Histograms returns the cumulative latency histogram of every endpoint
+func (t *Tracker) Histograms() map[string]HistogramSnapshot {
+	histograms := make(map[string]HistogramSnapshot)
+	t.endpoints.Range(func(key, value any) bool {
+		histograms[key.(string)] = value.(*endpointStats).latency.Snapshot()
+		return true
+	})
+	return histograms
+}
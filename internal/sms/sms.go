@@ -0,0 +1,43 @@
+// Package sms sends text messages, such as one-time login codes, through a Sender: Twilio or the
+// log
+package sms
+
+import (
+	"acid/internal/models"
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// Message is a text message to one E.164 number
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender delivers text messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// ErrInvalidRecipient is returned when the provider refuses the number itself, e.g. it can't
+// receive SMS; retrying won't help
+var ErrInvalidRecipient = errors.New("phone number cannot receive text messages")
+
+// LogSender logs messages instead of delivering them; used when no provider is configured.
+// Bodies carry one-time codes, so only the masked number and the length are logged
+type LogSender struct {
+	logger *zap.Logger
+}
+
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg *Message) error {
+	s.logger.Info("SMS send (log only)",
+		zap.String("to", models.MaskPhone(msg.To)),
+		zap.Int("body_length", len(msg.Body)))
+	return nil
+}
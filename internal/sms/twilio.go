@@ -0,0 +1,95 @@
+package sms
+
+import (
+	"acid/internal/httpclient"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TwilioConfig holds Twilio Programmable Messaging configuration
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+
+	// From is the sending number in E.164; MessagingServiceSID is used instead when set
+	From                string
+	MessagingServiceSID string
+
+	// BaseURL is the API's base URL, overridden to point at a mock in development
+	BaseURL string
+
+	// Timeout bounds each API request
+	Timeout time.Duration
+}
+
+// DefaultTwilioConfig returns sensible defaults
+func DefaultTwilioConfig() *TwilioConfig {
+	return &TwilioConfig{
+		BaseURL: "https://api.twilio.com",
+		Timeout: 10 * time.Second,
+	}
+}
+
+// twilioInvalidRecipient lists Twilio error codes about the destination number: invalid, not
+// SMS-capable, unsubscribed or in an unsupported region
+var twilioInvalidRecipient = map[int]bool{21211: true, 21408: true, 21610: true, 21612: true, 21614: true}
+
+// TwilioSender sends messages with the Twilio Messages API
+type TwilioSender struct {
+	config *TwilioConfig
+	client *httpclient.Client
+}
+
+func NewTwilioSender(config *TwilioConfig) *TwilioSender {
+	if config == nil {
+		config = DefaultTwilioConfig()
+	}
+	clientConfig := httpclient.DefaultConfig("twilio")
+	clientConfig.Timeout = config.Timeout
+	return &TwilioSender{config: config, client: httpclient.New(clientConfig)}
+}
+
+// Send creates a message. The POST isn't idempotent, so it is not retried: a retry after a lost
+// response would text the code twice
+func (s *TwilioSender) Send(ctx context.Context, msg *Message) error {
+	form := url.Values{"To": {msg.To}, "Body": {msg.Body}}
+	if s.config.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", s.config.MessagingServiceSID)
+	} else {
+		form.Set("From", s.config.From)
+	}
+
+	endpoint := strings.TrimSuffix(s.config.BaseURL, "/") + "/2010-04-01/Accounts/" + url.PathEscape(s.config.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.config.AccountSID, s.config.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result)
+	if twilioInvalidRecipient[result.Code] {
+		return fmt.Errorf("%w: twilio error %d: %s", ErrInvalidRecipient, result.Code, result.Message)
+	}
+	return fmt.Errorf("twilio refused message (%s): error %d: %s", resp.Status, result.Code, result.Message)
+}
@@ -0,0 +1,110 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v3"
+	"github.com/scylladb/gocqlx/v3/qb"
+	"github.com/scylladb/gocqlx/v3/table"
+)
+
+// LimitsTable holds per-tenant rate limit overrides, one row each
+var LimitsTable = table.New(table.Metadata{
+	Name:    "tenant_limits",
+	Columns: []string{"tenant", "rate_limit", "rate_window_ms", "updated_at"},
+	PartKey: []string{"tenant"},
+	SortKey: []string{},
+})
+
+// LimitsColumnTypes are the CQL types limitsRow is marshaled to
+var LimitsColumnTypes = map[string]string{
+	"tenant":         "text",
+	"rate_limit":     "bigint",
+	"rate_window_ms": "bigint",
+	"updated_at":     "timestamp",
+}
+
+// maxListed bounds List: overrides exist for a few noisy or premium tenants, read with a full
+// table scan
+const maxListed = 1000
+
+type limitsRow struct {
+	Tenant       string    `db:"tenant"`
+	RateLimit    int64     `db:"rate_limit"`
+	RateWindowMs int64     `db:"rate_window_ms"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+func (r limitsRow) overrides() Overrides {
+	return Overrides{
+		Tenant: r.Tenant,
+		RateLimit: RateLimit{
+			Requests: r.RateLimit,
+			Window:   time.Duration(r.RateWindowMs) * time.Millisecond,
+		},
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// Repository stores tenant overrides in ScyllaDB
+type Repository struct {
+	session gocqlx.Session
+}
+
+func NewRepository(session gocqlx.Session) *Repository {
+	return &Repository{session: session}
+}
+
+// Get returns a tenant's overrides, failing with ErrNotFound
+func (r *Repository) Get(ctx context.Context, id string) (*Overrides, error) {
+	var row limitsRow
+	err := LimitsTable.GetQueryContext(ctx, r.session).BindMap(map[string]interface{}{"tenant": id}).GetRelease(&row)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant limits: %w", err)
+	}
+	overrides := row.overrides()
+	return &overrides, nil
+}
+
+// Put creates or replaces a tenant's overrides
+func (r *Repository) Put(ctx context.Context, overrides *Overrides) error {
+	row := limitsRow{
+		Tenant:       overrides.Tenant,
+		RateLimit:    overrides.RateLimit.Requests,
+		RateWindowMs: overrides.RateLimit.Window.Milliseconds(),
+		UpdatedAt:    overrides.UpdatedAt,
+	}
+	if err := LimitsTable.InsertQueryContext(ctx, r.session).BindStruct(&row).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to store tenant limits: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a tenant's overrides
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	if err := LimitsTable.DeleteQueryContext(ctx, r.session).BindMap(map[string]interface{}{"tenant": id}).ExecRelease(); err != nil {
+		return fmt.Errorf("failed to delete tenant limits: %w", err)
+	}
+	return nil
+}
+
+// List returns every tenant's overrides, up to maxListed
+func (r *Repository) List(ctx context.Context) ([]Overrides, error) {
+	stmt, names := qb.Select(LimitsTable.Name()).Limit(maxListed).ToCql()
+	var rows []limitsRow
+	if err := r.session.ContextQuery(ctx, stmt, names).SelectRelease(&rows); err != nil {
+		return nil, fmt.Errorf("failed to list tenant limits: %w", err)
+	}
+	overrides := make([]Overrides, len(rows))
+	for i, row := range rows {
+		overrides[i] = row.overrides()
+	}
+	return overrides, nil
+}
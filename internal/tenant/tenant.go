@@ -0,0 +1,223 @@
+// Package tenant identifies which customer of a shared deployment a request is made for, and
+// holds the per-tenant rate limits that keep one noisy customer from starving the others. A
+// tenant's quotas live with every other quota, under the subject quota.TenantSubject
+package tenant
+
+import (
+	"acid/internal/cache"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned for a tenant without rate limit overrides
+var ErrNotFound = errors.New("tenant has no overrides")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Valid reports whether id is a well-formed tenant ID: lowercase letters, digits, "-" and "_",
+// up to 64 characters
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant of a request
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of a request, or "" when it has none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey{}).(string)
+	return id
+}
+
+// RateLimit is a tenant's request budget, shared by all its callers; zero Requests means
+// unlimited
+type RateLimit struct {
+	Requests int64         `json:"requests"`
+	Window   time.Duration `json:"window_ns"`
+}
+
+// Overrides are a tenant's stored rate limit, replacing the default
+type Overrides struct {
+	Tenant    string    `json:"tenant"`
+	RateLimit RateLimit `json:"rate_limit"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Config holds tenant limit settings
+type Config struct {
+	// DefaultRateLimit applies to tenants without overrides
+	DefaultRateLimit RateLimit
+
+	// CacheTTL is how long a tenant's limit is cached. Changes made through the Manager are
+	// invalidated on every instance at once; the TTL bounds how long a missed invalidation (Redis
+	// down) is honored
+	CacheTTL time.Duration
+}
+
+// DefaultConfig returns sensible defaults: no default tenant limit, limits cached for a minute
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultRateLimit: RateLimit{Window: time.Minute},
+		CacheTTL:         time.Minute,
+	}
+}
+
+// Metrics tracks limit lookups
+type Metrics struct {
+	CacheHits    atomic.Int64
+	CacheMisses  atomic.Int64
+	LookupErrors atomic.Int64
+}
+
+// Manager stores per-tenant rate limit overrides and serves the limit in effect from cache
+type Manager struct {
+	repo   *Repository
+	cache  *cache.CacheManager
+	config *Config
+	logger *zap.Logger
+
+	metrics Metrics
+}
+
+// NewManager creates the tenant limit manager; a nil CacheManager reads every lookup from
+// ScyllaDB
+func NewManager(repo *Repository, cm *cache.CacheManager, config *Config, logger *zap.Logger) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Manager{
+		repo:   repo,
+		cache:  cm,
+		config: config,
+		logger: logger,
+	}
+}
+
+// RateLimit returns the rate limit in effect for a tenant: its override, else the default. A
+// failed lookup falls back to the default, so a database outage doesn't lift every limit
+func (m *Manager) RateLimit(ctx context.Context, id string) RateLimit {
+	limit := m.config.DefaultRateLimit
+	var cached cachedLimit
+	if m.getCached(ctx, id, &cached) {
+		if cached.Overridden {
+			limit = cached.RateLimit
+		}
+		return limit
+	}
+
+	overrides, err := m.repo.Get(ctx, id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		m.metrics.LookupErrors.Add(1)
+		m.logger.Warn("Failed to load tenant limits, using defaults", zap.String("tenant", id), zap.Error(err))
+		return limit
+	default:
+		cached = cachedLimit{Overridden: true, RateLimit: overrides.RateLimit}
+		limit = overrides.RateLimit
+	}
+	m.setCached(ctx, id, cached)
+	return limit
+}
+
+// Get returns a tenant's overrides, failing with ErrNotFound
+func (m *Manager) Get(ctx context.Context, id string) (*Overrides, error) {
+	return m.repo.Get(ctx, id)
+}
+
+// List returns the overrides of every tenant that has some
+func (m *Manager) List(ctx context.Context) ([]Overrides, error) {
+	return m.repo.List(ctx)
+}
+
+// SetRateLimit overrides a tenant's rate limit; every instance applies it at once
+func (m *Manager) SetRateLimit(ctx context.Context, id string, limit RateLimit) (*Overrides, error) {
+	overrides := &Overrides{Tenant: id, RateLimit: limit, UpdatedAt: time.Now()}
+	if err := m.repo.Put(ctx, overrides); err != nil {
+		return nil, err
+	}
+	m.invalidate(ctx, id)
+	m.logger.Info("Tenant rate limit set", zap.String("tenant", id),
+		zap.Int64("requests", limit.Requests), zap.Duration("window", limit.Window))
+	return overrides, nil
+}
+
+// DeleteRateLimit removes a tenant's override so the default applies again
+func (m *Manager) DeleteRateLimit(ctx context.Context, id string) error {
+	if err := m.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	m.invalidate(ctx, id)
+	m.logger.Info("Tenant rate limit cleared", zap.String("tenant", id))
+	return nil
+}
+
+// DefaultRateLimit returns the limit of tenants without overrides
+func (m *Manager) DefaultRateLimit() RateLimit {
+	return m.config.DefaultRateLimit
+}
+
+// GetMetrics returns lookup counts of this instance
+func (m *Manager) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"cache_hits":    m.metrics.CacheHits.Load(),
+		"cache_misses":  m.metrics.CacheMisses.Load(),
+		"lookup_errors": m.metrics.LookupErrors.Load(),
+	}
+}
+
+// cachedLimit is the cached lookup of a tenant; tenants without overrides are cached too, as
+// most tenants have none
+type cachedLimit struct {
+	Overridden bool      `json:"overridden"`
+	RateLimit  RateLimit `json:"rate_limit"`
+}
+
+func (m *Manager) getCached(ctx context.Context, id string, dest *cachedLimit) bool {
+	if m.cache == nil {
+		return false
+	}
+	if _, err := m.cache.GetJSON(ctx, m.cache.Keys().Key(cache.EntityTenantLimits, id), dest); err != nil {
+		m.metrics.CacheMisses.Add(1)
+		return false
+	}
+	m.metrics.CacheHits.Add(1)
+	return true
+}
+
+func (m *Manager) setCached(ctx context.Context, id string, value cachedLimit) {
+	if m.cache == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := m.cache.SetWithTTL(ctx, m.cache.Keys().Key(cache.EntityTenantLimits, id), string(data), m.config.CacheTTL, m.config.CacheTTL); err != nil {
+		m.logger.Debug("Failed to cache tenant limits", zap.String("tenant", id), zap.Error(err))
+	}
+}
+
+// invalidate drops a cached limit here and tells other instances to drop their local copies
+func (m *Manager) invalidate(ctx context.Context, id string) {
+	if m.cache == nil {
+		return
+	}
+	key := m.cache.Keys().Key(cache.EntityTenantLimits, id)
+	if err := m.cache.Delete(ctx, key); err != nil {
+		m.logger.Warn("Failed to invalidate tenant limits", zap.String("tenant", id), zap.Error(err))
+	}
+	if err := m.cache.PublishInvalidation(ctx, key); err != nil {
+		m.logger.Warn("Failed to broadcast tenant limit invalidation", zap.String("tenant", id), zap.Error(err))
+	}
+}
@@ -2,12 +2,60 @@ package utils
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 // GetEnv fetches the value of an environment variable or returns a default value
 func GetEnv(key, defaultValue string) string {
+	return lookupEnv(key, defaultValue, func(value string) (string, error) { return value, nil },
+		func(value string) string { return value })
+}
+
+// GetEnvInt fetches an integer environment variable or returns a default value
+func GetEnvInt(key string, defaultValue int) int {
+	return lookupEnv(key, defaultValue, strconv.Atoi, strconv.Itoa)
+}
+
+// GetEnvBool fetches a boolean environment variable or returns a default value
+func GetEnvBool(key string, defaultValue bool) bool {
+	return lookupEnv(key, defaultValue, strconv.ParseBool, strconv.FormatBool)
+}
+
+// GetEnvDuration fetches a duration environment variable (e.g. "5s") or returns a default value
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	return lookupEnv(key, defaultValue, time.ParseDuration, time.Duration.String)
+}
+
+// GetEnvFloat fetches a float environment variable or returns a default value
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	return lookupEnv(key, defaultValue,
+		func(value string) (float64, error) { return strconv.ParseFloat(value, 64) },
+		func(value float64) string { return strconv.FormatFloat(value, 'g', -1, 64) })
+}
+
+// GetEnvTime fetches an RFC 3339 timestamp environment variable (e.g. "2025-06-30T00:00:00Z") or returns a default value
+func GetEnvTime(key string, defaultValue time.Time) time.Time {
+	return lookupEnv(key, defaultValue,
+		func(value string) (time.Time, error) { return time.Parse(time.RFC3339, value) },
+		func(value time.Time) string {
+			if value.IsZero() {
+				return ""
+			}
+			return value.Format(time.RFC3339)
+		})
+}
+
+// lookupEnv parses key, falling back to defaultValue when it is unset or doesn't parse, and
+// records the outcome for EffectiveConfig
+func lookupEnv[T any](key string, defaultValue T, parse func(string) (T, error), format func(T) string) T {
+	result, source := defaultValue, SourceDefault
 	if value, exists := os.LookupEnv(key); exists {
-		return value
+		source = SourceInvalid
+		if parsed, err := parse(value); err == nil {
+			result, source = parsed, SourceEnv
+		}
 	}
-	return defaultValue
+	record(key, format(result), format(defaultValue), source)
+	return result
 }
@@ -0,0 +1,27 @@
+//go:build linux
+
+package watchdog
+
+import (
+	"os"
+	"syscall"
+)
+
+// openFDs counts the process's open file descriptors, or returns -1 when /proc can't be read
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// Less the descriptor ReadDir held open on the directory itself
+	return len(entries) - 1
+}
+
+// fdLimit returns the soft RLIMIT_NOFILE, or 0 when unknown
+func fdLimit() int {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil || limit.Cur > 1<<31 {
+		return 0
+	}
+	return int(limit.Cur)
+}
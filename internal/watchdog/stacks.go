@@ -0,0 +1,120 @@
+package watchdog
+
+import (
+	"bufio"
+	"bytes"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StackGroup is a set of goroutines with the same stack
+type StackGroup struct {
+	Count int `json:"count"`
+
+	// Delta is the change in Count since the reference summary, when compared against one
+	Delta int `json:"delta,omitempty"`
+
+	// Top is the innermost frame outside the runtime and standard sync/syscall packages, i.e.
+	// where the goroutines wait, as function and file:line
+	Top string `json:"top"`
+
+	// Entry is the outermost frame: the function the goroutines were started with
+	Entry string `json:"entry"`
+}
+
+func (g StackGroup) key() string {
+	return g.Top + " <- " + g.Entry
+}
+
+// summarizeGoroutines groups the running goroutines by stack, largest groups first. Groups whose
+// stacks differ only below Top and above Entry are merged
+func summarizeGoroutines() []StackGroup {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	merged := make(map[string]*StackGroup)
+	var current *StackGroup
+	var frames []string
+	flush := func() {
+		if current == nil || len(frames) == 0 {
+			return
+		}
+		current.Top, current.Entry = topFrame(frames), frames[len(frames)-1]
+		if group, ok := merged[current.key()]; ok {
+			group.Count += current.Count
+		} else {
+			merged[current.key()] = current
+		}
+	}
+
+	// The debug=1 format is a "<count> @ <pcs>" line per stack followed by its frames, one
+	// "#\t<pc>\t<function>+<offset>\t<file>:<line>" line each
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if count, _, ok := strings.Cut(line, " @ "); ok {
+			flush()
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				current, frames = nil, nil
+				continue
+			}
+			current, frames = &StackGroup{Count: n}, frames[:0]
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 || fields[0] != "#" {
+			continue
+		}
+		// Function names are padded to a common width
+		function, _, _ := strings.Cut(strings.TrimSpace(fields[2]), "+")
+		frames = append(frames, function+" "+fields[len(fields)-1])
+	}
+	flush()
+
+	groups := make([]StackGroup, 0, len(merged))
+	for _, group := range merged {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+	return groups
+}
+
+// topFrame skips the frames every blocked goroutine shares, so the group names the caller
+func topFrame(frames []string) string {
+	for _, frame := range frames {
+		if !isRuntimeFrame(frame) {
+			return frame
+		}
+	}
+	return frames[0]
+}
+
+func isRuntimeFrame(frame string) bool {
+	for _, prefix := range []string{"runtime.", "runtime/", "internal/", "sync.", "sync/", "syscall."} {
+		if strings.HasPrefix(frame, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// growth compares groups with a reference summary, largest growth first
+func growth(groups, reference []StackGroup) []StackGroup {
+	before := make(map[string]int, len(reference))
+	for _, group := range reference {
+		before[group.key()] = group.Count
+	}
+
+	grown := make([]StackGroup, len(groups))
+	for i, group := range groups {
+		group.Delta = group.Count - before[group.key()]
+		grown[i] = group
+	}
+	sort.SliceStable(grown, func(i, j int) bool { return grown[i].Delta > grown[j].Delta })
+	return grown
+}
@@ -0,0 +1,296 @@
+// Package watchdog samples the process's goroutines, open file descriptors and heap, so slow leaks
+// show up in logs and metrics with the stacks behind them before they take the process down
+package watchdog
+
+import (
+	"acid/internal/safego"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config holds the sampling interval and alert thresholds
+type Config struct {
+	// Interval between samples
+	Interval time.Duration
+
+	// MaxGoroutines alerts when the goroutine count reaches it; 0 disables
+	MaxGoroutines int
+
+	// GrowthWindow is the number of samples the goroutine floor is taken over. Load makes the
+	// count swing, but a leak raises its minimum: the floor is the lowest count in the window
+	GrowthWindow int
+
+	// A leak is suspected when the floor exceeds the lowest floor seen (the reference) by both
+	// GrowthRatio of it and GrowthMin goroutines; after an alert the reference moves up to the
+	// floor, so continued growth alerts again
+	GrowthRatio float64
+	GrowthMin   int
+
+	// MaxOpenFDs alerts when open file descriptors reach it; 0 uses FDRatio of the soft
+	// RLIMIT_NOFILE instead
+	MaxOpenFDs int
+	FDRatio    float64
+
+	// MaxHeapBytes alerts when live heap objects reach it; 0 disables
+	MaxHeapBytes uint64
+
+	// StackGroups is how many goroutine groups a goroutine alert logs
+	StackGroups int
+}
+
+// DefaultConfig returns sensible production defaults: a sample every 30s, with the goroutine
+// floor taken over 5 minutes
+func DefaultConfig() *Config {
+	return &Config{
+		Interval:      30 * time.Second,
+		MaxGoroutines: 10000,
+		GrowthWindow:  10,
+		GrowthRatio:   0.5,
+		GrowthMin:     200,
+		FDRatio:       0.8,
+		StackGroups:   10,
+	}
+}
+
+// Metrics holds the gauges of the last sample and alert counters
+type Metrics struct {
+	Samples         atomic.Int64
+	Goroutines      atomic.Int64
+	GoroutineFloor  atomic.Int64
+	OpenFDs         atomic.Int64 // -1 where unsupported
+	HeapBytes       atomic.Int64
+	GoroutineAlerts atomic.Int64
+	GrowthAlerts    atomic.Int64
+	FDAlerts        atomic.Int64
+	HeapAlerts      atomic.Int64
+}
+
+// Watchdog samples the runtime every Interval and logs a warning when a threshold is crossed and
+// again when it is back below it. Goroutine alerts include the largest stack groups, and growth
+// alerts the groups that grew most since the reference, which is what attributes a slow leak
+type Watchdog struct {
+	config  *Config
+	logger  *zap.Logger
+	metrics *Metrics
+	fdLimit int
+
+	// Only touched by the sampling goroutine
+	window         []int
+	reference      int
+	refStacks      []StackGroup
+	overGoroutines bool
+	overFDs        bool
+	overHeap       bool
+	heapSample     []metrics.Sample
+
+	mu         sync.Mutex
+	lastStacks []StackGroup // Groups logged with the last goroutine alert
+	lastAlert  time.Time
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewWatchdog(config *Config, logger *zap.Logger) *Watchdog {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.GrowthWindow <= 0 {
+		config.GrowthWindow = 1
+	}
+
+	limit := config.MaxOpenFDs
+	if limit <= 0 && config.FDRatio > 0 {
+		limit = int(float64(fdLimit()) * config.FDRatio)
+	}
+	return &Watchdog{
+		config:     config,
+		logger:     logger,
+		metrics:    &Metrics{},
+		fdLimit:    limit,
+		heapSample: []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start samples every Interval until Stop is called
+func (w *Watchdog) Start() {
+	safego.Go("watchdog", w.run, safego.Restart(w.stop), safego.OnExit(func() { close(w.done) }))
+	w.logger.Info("Runtime watchdog started",
+		zap.Duration("interval", w.config.Interval),
+		zap.Int("max_goroutines", w.config.MaxGoroutines),
+		zap.Int("fd_limit", w.fdLimit),
+		zap.Uint64("max_heap_bytes", w.config.MaxHeapBytes))
+}
+
+// Stop halts sampling
+func (w *Watchdog) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+func (w *Watchdog) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"samples":          w.metrics.Samples.Load(),
+		"goroutines":       w.metrics.Goroutines.Load(),
+		"goroutine_floor":  w.metrics.GoroutineFloor.Load(),
+		"open_fds":         w.metrics.OpenFDs.Load(),
+		"fd_limit":         int64(w.fdLimit),
+		"heap_bytes":       w.metrics.HeapBytes.Load(),
+		"goroutine_alerts": w.metrics.GoroutineAlerts.Load(),
+		"growth_alerts":    w.metrics.GrowthAlerts.Load(),
+		"fd_alerts":        w.metrics.FDAlerts.Load(),
+		"heap_alerts":      w.metrics.HeapAlerts.Load(),
+	}
+}
+
+// Status returns the metrics with the stack groups of the last goroutine alert, for the admin API
+func (w *Watchdog) Status() map[string]any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := map[string]any{"metrics": w.GetMetrics()}
+	if w.lastStacks != nil {
+		status["last_alert"] = w.lastAlert
+		status["stacks"] = w.lastStacks
+	}
+	return status
+}
+
+// Stacks groups the goroutines running now, largest groups first, at most limit of them
+func (w *Watchdog) Stacks(limit int) []StackGroup {
+	groups := summarizeGoroutines()
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+func (w *Watchdog) run() {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.sample()
+		}
+	}
+}
+
+func (w *Watchdog) sample() {
+	w.metrics.Samples.Add(1)
+
+	goroutines := runtime.NumGoroutine()
+	w.metrics.Goroutines.Store(int64(goroutines))
+	w.checkGoroutines(goroutines)
+	w.checkGrowth(goroutines)
+
+	if fds := openFDs(); fds >= 0 {
+		w.metrics.OpenFDs.Store(int64(fds))
+		if w.fdLimit > 0 {
+			w.overFDs = w.crossed(w.overFDs, fds >= w.fdLimit, &w.metrics.FDAlerts,
+				"Open file descriptors over threshold", "Open file descriptors back under threshold",
+				zap.Int("open_fds", fds), zap.Int("threshold", w.fdLimit))
+		}
+	} else {
+		w.metrics.OpenFDs.Store(-1)
+	}
+
+	metrics.Read(w.heapSample)
+	if w.heapSample[0].Value.Kind() == metrics.KindUint64 {
+		heap := w.heapSample[0].Value.Uint64()
+		w.metrics.HeapBytes.Store(int64(heap))
+		if w.config.MaxHeapBytes > 0 {
+			w.overHeap = w.crossed(w.overHeap, heap >= w.config.MaxHeapBytes, &w.metrics.HeapAlerts,
+				"Heap over threshold", "Heap back under threshold",
+				zap.Uint64("heap_bytes", heap), zap.Uint64("threshold", w.config.MaxHeapBytes))
+		}
+	}
+}
+
+func (w *Watchdog) checkGoroutines(goroutines int) {
+	if w.config.MaxGoroutines <= 0 {
+		return
+	}
+	over := goroutines >= w.config.MaxGoroutines
+	if over && !w.overGoroutines {
+		w.metrics.GoroutineAlerts.Add(1)
+		stacks := w.recordStacks(summarizeGoroutines())
+		w.logger.Warn("Goroutines over threshold",
+			zap.Int("goroutines", goroutines),
+			zap.Int("threshold", w.config.MaxGoroutines),
+			zap.Any("stacks", stacks))
+	} else if !over && w.overGoroutines {
+		w.logger.Info("Goroutines back under threshold", zap.Int("goroutines", goroutines))
+	}
+	w.overGoroutines = over
+}
+
+// checkGrowth tracks the goroutine floor over the last GrowthWindow samples against the reference
+func (w *Watchdog) checkGrowth(goroutines int) {
+	if len(w.window) == w.config.GrowthWindow {
+		w.window = w.window[:copy(w.window, w.window[1:])]
+	}
+	w.window = append(w.window, goroutines)
+	if len(w.window) < w.config.GrowthWindow {
+		return
+	}
+
+	floor := w.window[0]
+	for _, n := range w.window[1:] {
+		floor = min(floor, n)
+	}
+	w.metrics.GoroutineFloor.Store(int64(floor))
+
+	if w.reference == 0 || floor < w.reference {
+		w.reference, w.refStacks = floor, summarizeGoroutines()
+		return
+	}
+	if floor < w.reference+max(w.config.GrowthMin, int(float64(w.reference)*w.config.GrowthRatio)) {
+		return
+	}
+
+	w.metrics.GrowthAlerts.Add(1)
+	current := summarizeGoroutines()
+	stacks := w.recordStacks(growth(current, w.refStacks))
+	w.logger.Warn("Goroutine floor keeps growing, possible leak",
+		zap.Int("floor", floor),
+		zap.Int("reference", w.reference),
+		zap.Int("window_samples", w.config.GrowthWindow),
+		zap.Any("stacks", stacks))
+	w.reference, w.refStacks = floor, current
+}
+
+// recordStacks keeps the first StackGroups groups for Status and returns them
+func (w *Watchdog) recordStacks(groups []StackGroup) []StackGroup {
+	if w.config.StackGroups > 0 && len(groups) > w.config.StackGroups {
+		groups = groups[:w.config.StackGroups]
+	}
+	w.mu.Lock()
+	w.lastStacks, w.lastAlert = groups, time.Now()
+	w.mu.Unlock()
+	return groups
+}
+
+// crossed logs and counts a threshold crossing in either direction and returns the new state
+func (w *Watchdog) crossed(was, over bool, alerts *atomic.Int64, overMsg, underMsg string, fields ...zap.Field) bool {
+	if over && !was {
+		alerts.Add(1)
+		w.logger.Warn(overMsg, fields...)
+	} else if !over && was {
+		w.logger.Info(underMsg, fields...)
+	}
+	return over
+}
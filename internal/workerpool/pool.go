@@ -0,0 +1,254 @@
+package workerpool
+
+import (
+	"acid/internal/safego"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPoolClosed is returned when submitting after Wait
+	ErrPoolClosed = errors.New("worker pool is closed")
+	// ErrTaskTimeout wraps the error of a task that ran past Config.TaskTimeout
+	ErrTaskTimeout = errors.New("task timed out")
+)
+
+// Task is a unit of work; it must return promptly once ctx is cancelled
+type Task func(ctx context.Context) error
+
+// Config holds worker pool configuration
+type Config struct {
+	// Workers bounds the number of tasks running at once
+	Workers int
+
+	// TaskTimeout bounds a single task; zero means only the pool context applies
+	TaskTimeout time.Duration
+
+	// StopOnError cancels the remaining tasks after the first failure and makes Submit
+	// return that failure; otherwise every task runs and failures are aggregated
+	StopOnError bool
+
+	// MaxErrors caps the failures kept for the aggregated error; the rest are only counted
+	MaxErrors int
+}
+
+// DefaultConfig returns sensible defaults: 4 workers, fail fast, no per-task timeout
+func DefaultConfig() *Config {
+	return &Config{
+		Workers:     4,
+		StopOnError: true,
+		MaxErrors:   10,
+	}
+}
+
+// Metrics tracks pool activity
+type Metrics struct {
+	Submitted atomic.Int64
+	Completed atomic.Int64
+	Failed    atomic.Int64
+	TimedOut  atomic.Int64
+	Panicked  atomic.Int64
+	Running   atomic.Int64
+}
+
+// Pool runs submitted tasks on at most Config.Workers goroutines. Submit blocks while every
+// worker is busy, so producers are throttled instead of queueing unbounded work. Wait drains
+// in-flight tasks and returns the aggregated error. A pool is single-use
+type Pool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	config *Config
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	errs    []error
+	dropped int
+	closed  bool
+
+	metrics Metrics
+}
+
+// New creates a pool whose tasks run under ctx; cancelling ctx stops the pool, and Wait then
+// returns once running tasks have observed the cancellation
+func New(ctx context.Context, config *Config) *Pool {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxErrors <= 0 {
+		config.MaxErrors = 10
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pool{
+		ctx:    ctx,
+		cancel: cancel,
+		config: config,
+		sem:    make(chan struct{}, config.Workers),
+	}
+}
+
+// Context is cancelled when the pool stops (first error with StopOnError, or parent cancelled)
+func (p *Pool) Context() context.Context {
+	return p.ctx
+}
+
+// Submit blocks until a worker is free, then runs task. It returns the pool's stop cause
+// (first failure with StopOnError, or the parent context's error) once the pool has stopped
+func (p *Pool) Submit(task Task) error {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return p.stopCause()
+	}
+	if p.ctx.Err() != nil {
+		<-p.sem
+		return p.stopCause()
+	}
+
+	p.metrics.Submitted.Add(1)
+	p.wg.Add(1)
+	safego.Go("workerpool.task", func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+
+		p.metrics.Running.Add(1)
+		err := p.run(task)
+		p.metrics.Running.Add(-1)
+
+		if err == nil {
+			p.metrics.Completed.Add(1)
+			return
+		}
+		p.fail(err)
+	})
+	return nil
+}
+
+// Wait closes the pool to new tasks, waits for running ones to finish and returns the
+// aggregated failures, or the parent context's error if it was cancelled first
+func (p *Pool) Wait() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	defer p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.errs) > 0 {
+		return &Errors{Errs: p.errs, Dropped: p.dropped}
+	}
+	return context.Cause(p.ctx)
+}
+
+// GetMetrics returns a snapshot of pool metrics
+func (p *Pool) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"submitted": p.metrics.Submitted.Load(),
+		"completed": p.metrics.Completed.Load(),
+		"failed":    p.metrics.Failed.Load(),
+		"timed_out": p.metrics.TimedOut.Load(),
+		"panicked":  p.metrics.Panicked.Load(),
+		"running":   p.metrics.Running.Load(),
+	}
+}
+
+// run executes task with the per-task timeout, converting panics into errors
+func (p *Pool) run(task Task) (err error) {
+	ctx := p.ctx
+	if p.config.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.TaskTimeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.metrics.Panicked.Add(1)
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+
+	err = task(ctx)
+	if err != nil && p.config.TaskTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) && p.ctx.Err() == nil {
+		p.metrics.TimedOut.Add(1)
+		err = fmt.Errorf("%w after %s: %w", ErrTaskTimeout, p.config.TaskTimeout, err)
+	}
+	return err
+}
+
+func (p *Pool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Once stopped, the remaining failures are just tasks observing the cancellation;
+	// Wait reports the stop cause instead
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	p.metrics.Failed.Add(1)
+	if len(p.errs) < p.config.MaxErrors {
+		p.errs = append(p.errs, err)
+	} else {
+		p.dropped++
+	}
+	if p.config.StopOnError {
+		p.cancel()
+	}
+}
+
+// stopCause is the first recorded failure, else the parent context's error
+func (p *Pool) stopCause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.errs) > 0 {
+		return p.errs[0]
+	}
+	return context.Cause(p.ctx)
+}
+
+// Errors aggregates task failures
+type Errors struct {
+	Errs []error
+	// Dropped counts failures beyond Config.MaxErrors
+	Dropped int
+}
+
+func (e *Errors) Error() string {
+	if len(e.Errs) == 1 && e.Dropped == 0 {
+		return e.Errs[0].Error()
+	}
+
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+	message := fmt.Sprintf("%d tasks failed: %s", len(e.Errs)+e.Dropped, strings.Join(messages, "; "))
+	if e.Dropped > 0 {
+		message += fmt.Sprintf(" (and %d more)", e.Dropped)
+	}
+	return message
+}
+
+// Unwrap lets errors.Is and errors.As match any kept failure
+func (e *Errors) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,158 @@
+package ws
+
+import (
+	"acid/internal/events"
+	"acid/internal/jsoncodec"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// Client actions
+const (
+	actionSubscribe   = "subscribe"
+	actionUnsubscribe = "unsubscribe"
+)
+
+// Server message types
+const (
+	messageEvent        = "event"
+	messageSubscribed   = "subscribed"
+	messageUnsubscribed = "unsubscribed"
+	messageError        = "error"
+)
+
+// clientMessage is sent by clients, e.g. {"action":"subscribe","topics":["user.created"]}
+type clientMessage struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// serverMessage is sent to clients
+type serverMessage struct {
+	Type   string        `json:"type"`
+	Topic  string        `json:"topic,omitempty"`
+	Topics []string      `json:"topics,omitempty"`
+	Event  *events.Event `json:"event,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// client is one authenticated WebSocket connection
+type client struct {
+	hub     *Hub
+	conn    *websocket.Conn
+	subject string
+	send    chan []byte
+
+	mu     sync.RWMutex
+	topics map[string]bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newClient(hub *Hub, conn *websocket.Conn, subject string) *client {
+	return &client{
+		hub:     hub,
+		conn:    conn,
+		subject: subject,
+		send:    make(chan []byte, hub.config.SendBuffer),
+		topics:  make(map[string]bool),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *client) subscribed(topic string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.topics[topic]
+}
+
+// close sends a close frame and tears the connection down; safe to call more than once
+func (c *client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		deadline := time.Now().Add(c.hub.config.WriteWait)
+		_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// readPump handles subscription requests and pongs until the connection fails
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.close(websocket.CloseNormalClosure, "")
+	}()
+
+	c.conn.SetReadLimit(c.hub.config.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	})
+
+	for {
+		var msg clientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.hub.logger.Info("WebSocket connection closed", zap.String("subject", c.subject), zap.Error(err))
+			}
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+
+		switch msg.Action {
+		case actionSubscribe:
+			c.mu.Lock()
+			for _, topic := range msg.Topics {
+				c.topics[topic] = true
+			}
+			c.mu.Unlock()
+			c.reply(serverMessage{Type: messageSubscribed, Topics: msg.Topics})
+		case actionUnsubscribe:
+			c.mu.Lock()
+			for _, topic := range msg.Topics {
+				delete(c.topics, topic)
+			}
+			c.mu.Unlock()
+			c.reply(serverMessage{Type: messageUnsubscribed, Topics: msg.Topics})
+		default:
+			c.reply(serverMessage{Type: messageError, Error: "unknown action: " + msg.Action})
+		}
+	}
+}
+
+// writePump is the only writer of data frames; it also pings the peer to detect dead connections
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case data := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+func (c *client) reply(msg serverMessage) {
+	data, err := jsoncodec.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.hub.deliver(c, data)
+}
@@ -0,0 +1,208 @@
+package ws
+
+import (
+	"acid/internal/events"
+	"acid/internal/jsoncodec"
+	"acid/internal/middleware"
+	"acid/internal/safego"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// HubConfig holds WebSocket hub configuration
+type HubConfig struct {
+	// SendBuffer is the per-connection outbound queue; a client that fills it is disconnected
+	SendBuffer int
+
+	// PingInterval is how often the server pings idle connections
+	PingInterval time.Duration
+
+	// PongWait is how long a connection may stay silent (no pong or message) before it is dropped
+	PongWait time.Duration
+
+	// WriteWait bounds a single write to the socket
+	WriteWait time.Duration
+
+	// MaxMessageSize limits inbound client messages in bytes
+	MaxMessageSize int64
+
+	// AllowedOrigins lists browser origins allowed to connect; empty allows same-origin only
+	AllowedOrigins []string
+}
+
+// DefaultHubConfig returns sensible production defaults
+func DefaultHubConfig() *HubConfig {
+	return &HubConfig{
+		SendBuffer:     64,
+		PingInterval:   30 * time.Second,
+		PongWait:       60 * time.Second,
+		WriteWait:      10 * time.Second,
+		MaxMessageSize: 4096,
+	}
+}
+
+// HubMetrics tracks connection and delivery counts for observability
+type HubMetrics struct {
+	Connections     atomic.Int64
+	MessagesSent    atomic.Int64
+	SlowDisconnects atomic.Int64
+}
+
+// Hub tracks WebSocket connections and routes bus events to the ones subscribed to each topic
+type Hub struct {
+	bus     *events.Bus
+	config  *HubConfig
+	logger  *zap.Logger
+	metrics *HubMetrics
+
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+
+	sub  *events.Subscription
+	done chan struct{}
+}
+
+func NewHub(bus *events.Bus, config *HubConfig, logger *zap.Logger) *Hub {
+	if config == nil {
+		config = DefaultHubConfig()
+	}
+
+	return &Hub{
+		bus:     bus,
+		config:  config,
+		logger:  logger,
+		metrics: &HubMetrics{},
+		clients: make(map[*client]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start subscribes to the event bus and begins dispatching to connected clients
+func (h *Hub) Start() {
+	h.sub = h.bus.Subscribe()
+	safego.Go("ws.hub", h.run, safego.Restart(nil), safego.OnExit(func() { close(h.done) }))
+	h.logger.Info("WebSocket hub started")
+}
+
+// Stop unsubscribes from the bus and closes every connection
+func (h *Hub) Stop() {
+	if h.sub != nil {
+		h.sub.Close()
+		<-h.done
+	}
+
+	h.mu.Lock()
+	for c := range h.clients {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	h.mu.Unlock()
+
+	h.logger.Info("WebSocket hub stopped")
+}
+
+// GetMetrics returns current hub metrics
+func (h *Hub) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"connections":      h.metrics.Connections.Load(),
+		"messages_sent":    h.metrics.MessagesSent.Load(),
+		"slow_disconnects": h.metrics.SlowDisconnects.Load(),
+	}
+}
+
+func (h *Hub) run() {
+	for event := range h.sub.Events() {
+		data, err := jsoncodec.Marshal(serverMessage{Type: messageEvent, Topic: event.Type, Event: &event})
+		if err != nil {
+			h.logger.Error("Failed to encode WebSocket event", zap.String("event_id", event.ID), zap.Error(err))
+			continue
+		}
+
+		h.mu.RLock()
+		for c := range h.clients {
+			if c.subscribed(event.Type) {
+				h.deliver(c, data)
+			}
+		}
+		h.mu.RUnlock()
+	}
+}
+
+// deliver queues a message without blocking; a full queue means the client can't keep up
+// and it is disconnected rather than stalling every other subscriber
+func (h *Hub) deliver(c *client, data []byte) {
+	select {
+	case c.send <- data:
+		h.metrics.MessagesSent.Add(1)
+	default:
+		h.metrics.SlowDisconnects.Add(1)
+		h.logger.Warn("WebSocket client too slow, disconnecting", zap.String("subject", c.subject))
+		// Don't block dispatch on a stalled socket
+		safego.Go("ws.close", func() { c.close(websocket.CloseTryAgainLater, "client too slow") })
+	}
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	h.metrics.Connections.Add(1)
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		h.metrics.Connections.Add(-1)
+	}
+	h.mu.Unlock()
+}
+
+// Serve upgrades an authenticated request to a WebSocket connection
+// Authentication runs before this handler (route middleware); the subject is taken from the context
+func (h *Hub) Serve(c *gin.Context) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error response
+		h.logger.Warn("WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+
+	client := newClient(h, conn, c.GetString(middleware.AuthSubjectKey))
+	h.register(client)
+
+	// A pump that panics closes the connection, which ends the other one and unregisters the client
+	closeOnPanic := safego.OnPanic(func(any) { client.close(websocket.CloseInternalServerErr, "internal error") })
+	safego.Go("ws.write_pump", client.writePump, closeOnPanic)
+	safego.Go("ws.read_pump", client.readPump, closeOnPanic)
+}
+
+// checkOrigin allows same-origin requests, non-browser clients and configured origins
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range h.config.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -5,7 +5,7 @@ Target: 5,000-10,000 concurrent users for realistic performance testing
 Expected: 2,000-5,000 RPS with <100ms latency
 
 Routes tested:
-- GET /api/v1/get/user/:id
+- GET /api/v1/users/:id
 
 Run examples:
   Basic test (recommended):
@@ -64,8 +64,8 @@ class ApiUser(FastHttpUser):
         - P99: <500ms
         """
         with self.client.get(
-            f"/api/v1/get/user/{TEST_USER_IDS[0]}",
-            name="GET /api/v1/user/[id]",
+            f"/api/v1/users/{TEST_USER_IDS[0]}",
+            name="GET /api/v1/users/[id]",
             headers=self.headers,
             catch_response=True,
         ) as resp:
@@ -0,0 +1,380 @@
+// Package client is the Go SDK for the acid user service REST and gRPC APIs
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthFunc decorates outgoing requests with credentials (bearer token, signed header, ...)
+type AuthFunc func(req *http.Request) error
+
+// Config holds client configuration
+type Config struct {
+	// BaseURL of the HTTP API, e.g. http://localhost:8000
+	BaseURL string
+
+	// HTTPClient performs requests; defaults to a client with Timeout
+	HTTPClient *http.Client
+
+	// Timeout bounds each attempt when HTTPClient is not set
+	Timeout time.Duration
+
+	// Token is sent as "Authorization: Bearer <token>" when Auth is not set
+	Token string
+
+	// Auth overrides Token for custom authentication schemes
+	Auth AuthFunc
+
+	// MaxRetries is the number of retries after the first attempt for retryable failures
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry, doubled on each further retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+
+	// HedgeDelay makes gRPC FetchUser send a second request when the first hasn't answered in
+	// this long, taking whichever answers first; 0 disables hedging. Set it around the p95
+	// latency: lower sends many extra requests, higher rarely helps
+	HedgeDelay time.Duration
+
+	// UserAgent identifies the calling service
+	UserAgent string
+}
+
+// DefaultConfig returns sensible defaults for service-to-service calls
+func DefaultConfig() *Config {
+	return &Config{
+		BaseURL:        "http://localhost:8000",
+		Timeout:        10 * time.Second,
+		MaxRetries:     3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		UserAgent:      "acid-go-client",
+	}
+}
+
+// Client is a typed REST client; it is safe for concurrent use
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+	config  *Config
+}
+
+// New creates a REST client
+func New(config *Config) (*Client, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	baseURL, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", config.BaseURL, err)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: config.Timeout}
+	}
+
+	return &Client{
+		baseURL: baseURL,
+		http:    httpClient,
+		config:  config,
+	}, nil
+}
+
+// APIError is returned for non-2xx responses, decoded from the server's problem+json body
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string // Problem type URI, e.g. /problems/validation
+	RequestID  string // Problem instance; quote it when reporting server errors
+	Fields     []FieldError
+}
+
+// FieldError is a per-field validation failure
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("acid API error %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 from the API, e.g. a user ID that is already taken
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// User is a user as returned by the API (field names mirror the server's JSON encoding)
+type User struct {
+	ID          string
+	Username    string
+	Email       string
+	CreatedAt   time.Time
+	Preferences map[string]bool
+}
+
+// Preferences is a user's effective notification preferences
+type Preferences struct {
+	Email map[string]bool `json:"email"`
+}
+
+// Health checks that the API is up
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/api/v1/health", nil, nil, true)
+}
+
+// CreateUser registers a new user
+// Not retried on server errors: the user may already have been created
+func (c *Client) CreateUser(ctx context.Context, username, email string) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	body := map[string]string{"username": username, "email": email}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/users", body, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// CreateUserWithID registers a new user under an ID of the caller's (a UUIDv4 or UUIDv7), so
+// migrated users keep their identifiers. A taken ID fails with a 409 (see IsConflict)
+// Not retried on server errors: a retry of a create that was applied would report the conflict
+func (c *Client) CreateUserWithID(ctx context.Context, id, username, email string) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	body := map[string]string{"id": id, "username": username, "email": email}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/users", body, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// GetUser fetches a user by ID
+func (c *Client) GetUser(ctx context.Context, id string) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(id), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// UserExists reports whether a user exists without fetching it (HEAD /api/v2/users/:id)
+func (c *Client) UserExists(ctx context.Context, id string) (bool, error) {
+	err := c.do(ctx, http.MethodHead, "/api/v2/users/"+url.PathEscape(id), nil, nil, true)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPreferences returns a user's effective notification preferences
+func (c *Client) GetPreferences(ctx context.Context, id string) (*Preferences, error) {
+	var resp struct {
+		Preferences Preferences `json:"preferences"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(id)+"/preferences", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp.Preferences, nil
+}
+
+// UpdatePreferences merges email category toggles into a user's preferences
+func (c *Client) UpdatePreferences(ctx context.Context, id string, email map[string]bool) (*Preferences, error) {
+	var resp struct {
+		Preferences Preferences `json:"preferences"`
+	}
+	body := Preferences{Email: email}
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/users/"+url.PathEscape(id)+"/preferences", body, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp.Preferences, nil
+}
+
+// Unsubscribe opts a user out of one email category, or all optional ones when category is empty.
+// The client's credentials need users:write
+func (c *Client) Unsubscribe(ctx context.Context, id, category string) (*Preferences, error) {
+	path := "/api/v1/users/" + url.PathEscape(id) + "/unsubscribe"
+	if category != "" {
+		path += "?category=" + url.QueryEscape(category)
+	}
+
+	var resp struct {
+		Preferences Preferences `json:"preferences"`
+	}
+	if err := c.do(ctx, http.MethodPost, path, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp.Preferences, nil
+}
+
+// do sends a JSON request, retrying retryable failures with exponential backoff and jitter
+// idempotent=false limits retries to failures where the server cannot have acted (429)
+func (c *Client) do(ctx context.Context, method, path string, body, out any, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	backoff := c.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.attempt(ctx, method, path, payload, out)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= c.config.MaxRetries || !retryable(err, idempotent) {
+			return err
+		}
+
+		delay := backoff/2 + rand.N(backoff/2+1) // Jitter avoids synchronized retries
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+// attempt performs one request and returns the server's Retry-After hint, if any
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, out any) (time.Duration, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+
+	switch {
+	case c.config.Auth != nil:
+		if err := c.config.Auth(req); err != nil {
+			return 0, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+	case c.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, &transportError{err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, &transportError{err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var problem struct {
+			Type     string       `json:"type"`
+			Title    string       `json:"title"`
+			Detail   string       `json:"detail"`
+			Instance string       `json:"instance"`
+			Errors   []FieldError `json:"errors"`
+		}
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if json.Unmarshal(data, &problem) == nil {
+			apiErr.Type = problem.Type
+			apiErr.RequestID = problem.Instance
+			apiErr.Fields = problem.Errors
+			if problem.Detail != "" {
+				apiErr.Message = problem.Detail
+			} else if problem.Title != "" {
+				apiErr.Message = problem.Title
+			}
+		}
+		return retryAfterHint(resp), apiErr
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return 0, nil
+}
+
+// transportError marks network-level failures (no HTTP response received)
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return true
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return idempotent
+		}
+		return false
+	}
+
+	var transportErr *transportError
+	return errors.As(err, &transportErr) && idempotent
+}
+
+func retryAfterHint(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	pb "acid/proto/acid"
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// DefaultServiceConfig is the gRPC service config DialGRPC applies: a 10s deadline for calls
+// without one, and for fetchUser and userExists up to 3 retries of UNAVAILABLE,
+// DEADLINE_EXCEEDED and RESOURCE_EXHAUSTED with exponential backoff. createUser is not retried:
+// without a request_id a retry may create the user twice. Retries are throttled once more than
+// 10% of calls fail, so an outage isn't met with a retry storm
+//
+//go:embed service_config.json
+var DefaultServiceConfig string
+
+// HedgingServiceConfig is DefaultServiceConfig with fetchUser hedged instead of retried: a second
+// attempt goes out when the first hasn't answered within 50ms. It is for consumers whose gRPC
+// library implements hedging (Java, C++); grpc-go ignores hedgingPolicy, so Go clients hedge
+// with Config.HedgeDelay instead
+//
+//go:embed service_config_hedging.json
+var HedgingServiceConfig string
+
+// GRPCClient wraps the generated Acid stub with auth metadata, retries for idempotent calls and
+// optional hedging of FetchUser
+type GRPCClient struct {
+	stub   pb.AcidClient
+	config *Config
+	conn   *grpc.ClientConn
+
+	// channelRetries is set when the connection's service config retries, so calls aren't
+	// retried twice
+	channelRetries bool
+}
+
+// NewGRPC creates a gRPC client on an existing connection; BaseURL and HTTP settings are ignored.
+// Calls are retried by the client itself; prefer DialGRPC, which leaves retries to gRPC
+func NewGRPC(conn grpc.ClientConnInterface, config *Config) *GRPCClient {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	return &GRPCClient{
+		stub:   pb.NewAcidClient(conn),
+		config: config,
+	}
+}
+
+// DialGRPC connects to target with DefaultServiceConfig, so retries and deadlines are handled by
+// gRPC. opts must include transport credentials; a grpc.WithDefaultServiceConfig among them
+// replaces DefaultServiceConfig, and a service config published by the name resolver takes
+// precedence over both
+func DialGRPC(target string, config *Config, opts ...grpc.DialOption) (*GRPCClient, error) {
+	conn, err := grpc.NewClient(target, append([]grpc.DialOption{grpc.WithDefaultServiceConfig(DefaultServiceConfig)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	client := NewGRPC(conn, config)
+	client.conn = conn
+	client.channelRetries = true
+	return client, nil
+}
+
+// Close closes the connection opened by DialGRPC; clients from NewGRPC leave theirs open
+func (g *GRPCClient) Close() error {
+	if g.conn == nil {
+		return nil
+	}
+	return g.conn.Close()
+}
+
+// RegisterUser creates a user; not retried on server errors since it is not idempotent
+func (g *GRPCClient) RegisterUser(ctx context.Context, name, email string) error {
+	resp, err := g.stub.CreateUser(g.outgoing(ctx), &pb.RegisterUserRequest{Name: name, Email: email})
+	if err != nil {
+		return err
+	}
+	if resp.Response != pb.RegisterUserResponse_SUCCESS {
+		return fmt.Errorf("register user failed: %s", resp.Response)
+	}
+	return nil
+}
+
+// FetchUser returns a user's name and email, retrying transient failures and hedging with
+// HedgeDelay. Passing fields ("id", "name", "email") fetches only those; the others are left empty
+func (g *GRPCClient) FetchUser(ctx context.Context, id string, fields ...string) (*User, error) {
+	req := &pb.FetchUserRequest{UserId: id}
+	if len(fields) > 0 {
+		req.FieldMask = &fieldmaskpb.FieldMask{Paths: fields}
+	}
+
+	resp, err := hedge(ctx, g.config.HedgeDelay, func(ctx context.Context) (*pb.FetchUserResponse, error) {
+		var resp *pb.FetchUserResponse
+		err := g.retry(ctx, func() error {
+			var err error
+			resp, err = g.stub.FetchUser(g.outgoing(ctx), req)
+			return err
+		})
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &User{
+		ID:       id,
+		Username: resp.Name,
+		Email:    resp.Email,
+	}, nil
+}
+
+// UserExists reports whether a user exists without fetching it, retrying transient failures
+func (g *GRPCClient) UserExists(ctx context.Context, id string) (bool, error) {
+	var resp *pb.UserExistsResponse
+	err := g.retry(ctx, func() error {
+		var err error
+		resp, err = g.stub.UserExists(g.outgoing(ctx), &pb.UserExistsRequest{UserId: id})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Exists, nil
+}
+
+// retry runs an idempotent call, retrying Unavailable and ResourceExhausted with backoff, unless
+// the connection's service config already retries
+func (g *GRPCClient) retry(ctx context.Context, call func() error) error {
+	if g.channelRetries {
+		return call()
+	}
+
+	backoff := g.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		code := status.Code(err)
+		if attempt >= g.config.MaxRetries || (code != codes.Unavailable && code != codes.ResourceExhausted) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, g.config.MaxBackoff)
+	}
+}
+
+// hedge runs call and, when it hasn't returned after delay, a second copy of it. The first success
+// or definitive failure wins and cancels the other; a transient failure waits for the other
+// attempt. A delay of 0 runs call alone
+func hedge[T any](ctx context.Context, delay time.Duration, call func(context.Context) (T, error)) (T, error) {
+	if delay <= 0 {
+		return call(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	results := make(chan result, 2)
+	attempt := func() {
+		value, err := call(ctx)
+		results <- result{value, err}
+	}
+	go attempt()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			go attempt()
+		case r := <-results:
+			pending--
+			if r.err == nil || pending == 0 || !transient(status.Code(r.err)) {
+				return r.value, r.err
+			}
+		}
+	}
+}
+
+// transient reports whether another attempt of a call failing with code may succeed
+func transient(code codes.Code) bool {
+	return code == codes.Unavailable || code == codes.ResourceExhausted || code == codes.DeadlineExceeded
+}
+
+// outgoing attaches the bearer token as gRPC metadata
+func (g *GRPCClient) outgoing(ctx context.Context) context.Context {
+	if g.config.Token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+g.config.Token)
+}
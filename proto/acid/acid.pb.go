@@ -9,6 +9,7 @@ package __
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -71,6 +72,7 @@ type RegisterUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	RequestId     string                 `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Retries with the same ID get the first call's result
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -119,6 +121,13 @@ func (x *RegisterUserRequest) GetEmail() string {
 	return ""
 }
 
+func (x *RegisterUserRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
 type RegisterUserResponse struct {
 	state         protoimpl.MessageState      `protogen:"open.v1"`
 	Response      RegisterUserResponse_Status `protobuf:"varint,3,opt,name=response,proto3,enum=acid.RegisterUserResponse_Status" json:"response,omitempty"`
@@ -166,6 +175,7 @@ func (x *RegisterUserResponse) GetResponse() RegisterUserResponse_Status {
 type FetchUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FieldMask     *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=field_mask,json=fieldMask,proto3" json:"field_mask,omitempty"` // Response fields to return; all when unset
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -207,10 +217,18 @@ func (x *FetchUserRequest) GetUserId() string {
 	return ""
 }
 
+func (x *FetchUserRequest) GetFieldMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.FieldMask
+	}
+	return nil
+}
+
 type FetchUserResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Id            string                 `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -259,28 +277,239 @@ func (x *FetchUserResponse) GetEmail() string {
 	return ""
 }
 
+func (x *FetchUserResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// UserResponse is the protobuf encoding of a user served by REST read endpoints
+// (Accept: application/x-protobuf)
+type UserResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Username        string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Email           string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	CreatedAtUnixMs int64                  `protobuf:"varint,4,opt,name=created_at_unix_ms,json=createdAtUnixMs,proto3" json:"created_at_unix_ms,omitempty"`
+	Source          string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"` // Cache tier or database the user was read from
+	Preferences     map[string]bool        `protobuf:"bytes,6,rep,name=preferences,proto3" json:"preferences,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UserResponse) Reset() {
+	*x = UserResponse{}
+	mi := &file_proto_acid_acid_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserResponse) ProtoMessage() {}
+
+func (x *UserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_acid_acid_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserResponse.ProtoReflect.Descriptor instead.
+func (*UserResponse) Descriptor() ([]byte, []int) {
+	return file_proto_acid_acid_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UserResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UserResponse) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *UserResponse) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UserResponse) GetCreatedAtUnixMs() int64 {
+	if x != nil {
+		return x.CreatedAtUnixMs
+	}
+	return 0
+}
+
+func (x *UserResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *UserResponse) GetPreferences() map[string]bool {
+	if x != nil {
+		return x.Preferences
+	}
+	return nil
+}
+
+type UserExistsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserExistsRequest) Reset() {
+	*x = UserExistsRequest{}
+	mi := &file_proto_acid_acid_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserExistsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserExistsRequest) ProtoMessage() {}
+
+func (x *UserExistsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_acid_acid_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserExistsRequest.ProtoReflect.Descriptor instead.
+func (*UserExistsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_acid_acid_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UserExistsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type UserExistsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"` // Cache tier or database that answered
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserExistsResponse) Reset() {
+	*x = UserExistsResponse{}
+	mi := &file_proto_acid_acid_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserExistsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserExistsResponse) ProtoMessage() {}
+
+func (x *UserExistsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_acid_acid_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserExistsResponse.ProtoReflect.Descriptor instead.
+func (*UserExistsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_acid_acid_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UserExistsResponse) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *UserExistsResponse) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
 var File_proto_acid_acid_proto protoreflect.FileDescriptor
 
 const file_proto_acid_acid_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/acid/acid.proto\x12\x04acid\"?\n" +
+	"\x15proto/acid/acid.proto\x12\x04acid\x1a google/protobuf/field_mask.proto\"^\n" +
 	"\x13RegisterUserRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
-	"\x05email\x18\x02 \x01(\tR\x05email\"y\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\"y\n" +
 	"\x14RegisterUserResponse\x12=\n" +
 	"\bresponse\x18\x03 \x01(\x0e2!.acid.RegisterUserResponse.StatusR\bresponse\"\"\n" +
 	"\x06Status\x12\v\n" +
 	"\aSUCCESS\x10\x00\x12\v\n" +
-	"\aFAILURE\x10\x01\"+\n" +
+	"\aFAILURE\x10\x01\"f\n" +
 	"\x10FetchUserRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"=\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x129\n" +
+	"\n" +
+	"field_mask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskR\tfieldMask\"M\n" +
 	"\x11FetchUserResponse\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
-	"\x05email\x18\x02 \x01(\tR\x05email2\x89\x01\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\tR\x02id\"\x9c\x02\n" +
+	"\fUserResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12+\n" +
+	"\x12created_at_unix_ms\x18\x04 \x01(\x03R\x0fcreatedAtUnixMs\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12E\n" +
+	"\vpreferences\x18\x06 \x03(\v2#.acid.UserResponse.PreferencesEntryR\vpreferences\x1a>\n" +
+	"\x10PreferencesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\",\n" +
+	"\x11UserExistsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"D\n" +
+	"\x12UserExistsResponse\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source2\xca\x01\n" +
 	"\x04Acid\x12C\n" +
 	"\n" +
 	"createUser\x12\x19.acid.RegisterUserRequest\x1a\x1a.acid.RegisterUserResponse\x12<\n" +
-	"\tfetchUser\x12\x16.acid.FetchUserRequest\x1a\x17.acid.FetchUserResponseB\x03Z\x01.b\x06proto3"
+	"\tfetchUser\x12\x16.acid.FetchUserRequest\x1a\x17.acid.FetchUserResponse\x12?\n" +
+	"\n" +
+	"userExists\x12\x17.acid.UserExistsRequest\x1a\x18.acid.UserExistsResponseB\x03Z\x01.b\x06proto3"
 
 var (
 	file_proto_acid_acid_proto_rawDescOnce sync.Once
@@ -295,25 +524,34 @@ func file_proto_acid_acid_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_acid_acid_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_acid_acid_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_acid_acid_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_proto_acid_acid_proto_goTypes = []any{
 	(RegisterUserResponse_Status)(0), // 0: acid.RegisterUserResponse.Status
 	(*RegisterUserRequest)(nil),      // 1: acid.RegisterUserRequest
 	(*RegisterUserResponse)(nil),     // 2: acid.RegisterUserResponse
 	(*FetchUserRequest)(nil),         // 3: acid.FetchUserRequest
 	(*FetchUserResponse)(nil),        // 4: acid.FetchUserResponse
+	(*UserResponse)(nil),             // 5: acid.UserResponse
+	(*UserExistsRequest)(nil),        // 6: acid.UserExistsRequest
+	(*UserExistsResponse)(nil),       // 7: acid.UserExistsResponse
+	nil,                              // 8: acid.UserResponse.PreferencesEntry
+	(*fieldmaskpb.FieldMask)(nil),    // 9: google.protobuf.FieldMask
 }
 var file_proto_acid_acid_proto_depIdxs = []int32{
 	0, // 0: acid.RegisterUserResponse.response:type_name -> acid.RegisterUserResponse.Status
-	1, // 1: acid.Acid.createUser:input_type -> acid.RegisterUserRequest
-	3, // 2: acid.Acid.fetchUser:input_type -> acid.FetchUserRequest
-	2, // 3: acid.Acid.createUser:output_type -> acid.RegisterUserResponse
-	4, // 4: acid.Acid.fetchUser:output_type -> acid.FetchUserResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	9, // 1: acid.FetchUserRequest.field_mask:type_name -> google.protobuf.FieldMask
+	8, // 2: acid.UserResponse.preferences:type_name -> acid.UserResponse.PreferencesEntry
+	1, // 3: acid.Acid.createUser:input_type -> acid.RegisterUserRequest
+	3, // 4: acid.Acid.fetchUser:input_type -> acid.FetchUserRequest
+	6, // 5: acid.Acid.userExists:input_type -> acid.UserExistsRequest
+	2, // 6: acid.Acid.createUser:output_type -> acid.RegisterUserResponse
+	4, // 7: acid.Acid.fetchUser:output_type -> acid.FetchUserResponse
+	7, // 8: acid.Acid.userExists:output_type -> acid.UserExistsResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_acid_acid_proto_init() }
@@ -327,7 +565,7 @@ func file_proto_acid_acid_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_acid_acid_proto_rawDesc), len(file_proto_acid_acid_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   4,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	Acid_CreateUser_FullMethodName = "/acid.Acid/createUser"
 	Acid_FetchUser_FullMethodName  = "/acid.Acid/fetchUser"
+	Acid_UserExists_FullMethodName = "/acid.Acid/userExists"
 )
 
 // AcidClient is the client API for Acid service.
@@ -29,6 +30,7 @@ const (
 type AcidClient interface {
 	CreateUser(ctx context.Context, in *RegisterUserRequest, opts ...grpc.CallOption) (*RegisterUserResponse, error)
 	FetchUser(ctx context.Context, in *FetchUserRequest, opts ...grpc.CallOption) (*FetchUserResponse, error)
+	UserExists(ctx context.Context, in *UserExistsRequest, opts ...grpc.CallOption) (*UserExistsResponse, error)
 }
 
 type acidClient struct {
@@ -59,12 +61,23 @@ func (c *acidClient) FetchUser(ctx context.Context, in *FetchUserRequest, opts .
 	return out, nil
 }
 
+func (c *acidClient) UserExists(ctx context.Context, in *UserExistsRequest, opts ...grpc.CallOption) (*UserExistsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserExistsResponse)
+	err := c.cc.Invoke(ctx, Acid_UserExists_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AcidServer is the server API for Acid service.
 // All implementations must embed UnimplementedAcidServer
 // for forward compatibility.
 type AcidServer interface {
 	CreateUser(context.Context, *RegisterUserRequest) (*RegisterUserResponse, error)
 	FetchUser(context.Context, *FetchUserRequest) (*FetchUserResponse, error)
+	UserExists(context.Context, *UserExistsRequest) (*UserExistsResponse, error)
 	mustEmbedUnimplementedAcidServer()
 }
 
@@ -81,6 +94,9 @@ func (UnimplementedAcidServer) CreateUser(context.Context, *RegisterUserRequest)
 func (UnimplementedAcidServer) FetchUser(context.Context, *FetchUserRequest) (*FetchUserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FetchUser not implemented")
 }
+func (UnimplementedAcidServer) UserExists(context.Context, *UserExistsRequest) (*UserExistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UserExists not implemented")
+}
 func (UnimplementedAcidServer) mustEmbedUnimplementedAcidServer() {}
 func (UnimplementedAcidServer) testEmbeddedByValue()              {}
 
@@ -138,6 +154,24 @@ func _Acid_FetchUser_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Acid_UserExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserExistsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AcidServer).UserExists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Acid_UserExists_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AcidServer).UserExists(ctx, req.(*UserExistsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Acid_ServiceDesc is the grpc.ServiceDesc for Acid service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +187,10 @@ var Acid_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "fetchUser",
 			Handler:    _Acid_FetchUser_Handler,
 		},
+		{
+			MethodName: "userExists",
+			Handler:    _Acid_UserExists_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/acid/acid.proto",
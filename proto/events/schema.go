@@ -0,0 +1,8 @@
+package events
+
+import _ "embed"
+
+// Schema is the source of events.proto, as registered with a schema registry
+//
+//go:embed events.proto
+var Schema string